@@ -1,10 +1,20 @@
 package main
 
 import (
+	"context"
 	"log"
+	"net"
 	"net/http"
+	"os"
+	"token-transfer-api/internal/buildinfo"
 	"token-transfer-api/internal/db"
+	"token-transfer-api/internal/graph"
+	"token-transfer-api/internal/tracing"
 	"token-transfer-api/pkg/graphql"
+	"token-transfer-api/pkg/grpcserver"
+	"token-transfer-api/pkg/outbox"
+	"token-transfer-api/pkg/rest"
+	"token-transfer-api/pkg/scheduler"
 
 	"github.com/joho/godotenv"
 )
@@ -15,16 +25,80 @@ func main() {
 		log.Println("No .env file found, using environment variables")
 	}
 
+	// Export spans when an OTLP endpoint is configured
+	tracingConfig, err := tracing.ConfigFromEnv()
+	if err != nil {
+		log.Fatalf("Failed to load tracing configuration: %v", err)
+	}
+	shutdownTracing, err := tracing.Setup(context.Background(), tracingConfig)
+	if err != nil {
+		log.Fatalf("Failed to set up tracing: %v", err)
+	}
+	defer shutdownTracing(context.Background())
+
 	// Initialize database
 	if err := db.InitDB(); err != nil {
 		log.Fatalf("Failed to initialize database: %v", err)
 	}
 	defer db.CloseDB()
 
-	// Setup GraphQL handler
-	handler := graphql.NewHandler()
+	authConfig, err := graphql.AuthConfigFromEnv()
+	if err != nil {
+		log.Fatalf("Failed to load auth configuration: %v", err)
+	}
+
+	accessLogConfig, err := graphql.AccessLogConfigFromEnv()
+	if err != nil {
+		log.Fatalf("Failed to load access log configuration: %v", err)
+	}
+
+	// All APIs share one resolver, so a sender's rate limit, duplicate
+	// detection and queue are the same whichever API it uses
+	resolver, err := graph.NewResolver()
+	if err != nil {
+		log.Fatalf("Failed to create resolver: %v", err)
+	}
+
+	// Setup REST endpoints under /api/, build info at /version and GraphQL
+	// everywhere else. GraphQL requests are logged before authentication so
+	// refused ones show up too.
+	mux := http.NewServeMux()
+	mux.Handle("/version", buildinfo.Handler())
+	mux.Handle("/api/", rest.WithAuth(rest.NewHandlerFor(resolver), authConfig))
+	mux.Handle("/", graphql.WithAccessLog(graphql.WithAuth(graphql.NewHandlerFor(resolver), authConfig), accessLogConfig))
+
+	// Start the gRPC listener when a port is configured
+	if port := os.Getenv("GRPC_PORT"); port != "" {
+		grpcServer := grpcserver.New(resolver, authConfig)
+		lis, err := net.Listen("tcp", ":"+port)
+		if err != nil {
+			log.Fatalf("Failed to listen for gRPC on :%s: %v", port, err)
+		}
+		log.Printf("gRPC server starting on :%s", port)
+		go func() {
+			if err := grpcServer.Serve(lis); err != nil {
+				log.Fatalf("gRPC server stopped: %v", err)
+			}
+		}()
+	}
+
+	// Execute scheduled transfers as they fall due
+	schedulerConfig, err := scheduler.ConfigFromEnv()
+	if err != nil {
+		log.Fatalf("Failed to load scheduler configuration: %v", err)
+	}
+	go scheduler.Run(context.Background(), schedulerConfig)
+
+	// Deliver transfer events when a webhook is configured
+	outboxConfig, err := outbox.ConfigFromEnv()
+	if err != nil {
+		log.Fatalf("Failed to load transfer events configuration: %v", err)
+	}
+	if outboxConfig.URL != "" {
+		go outbox.Run(context.Background(), outboxConfig)
+	}
 
 	// Start server
-	log.Println("Server starting on :8080")
-	log.Fatal(http.ListenAndServe(":8080", handler))
+	log.Printf("Server %s (%s) starting on :8080", buildinfo.Version, buildinfo.Commit)
+	log.Fatal(http.ListenAndServe(":8080", mux))
 }
@@ -0,0 +1,85 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"log"
+	"net/http"
+	"sync"
+	"sync/atomic"
+	"time"
+	"token-transfer-api/pkg/ramp"
+)
+
+type graphQLResponse struct {
+	Errors []map[string]interface{} `json:"errors,omitempty"`
+}
+
+func main() {
+	url := flag.String("url", "http://localhost:8080/query", "GraphQL endpoint")
+	from := flag.String("from", "0x0000000000000000000000000000000000000000", "sender address")
+	to := flag.String("to", "0x0000000000000000000000000000000000000001", "receiver address")
+	amount := flag.String("amount", "1", "amount per transfer")
+	requests := flag.Int("requests", 1000, "total number of transfers to send")
+	concurrency := flag.Int("concurrency", 50, "maximum number of concurrent requests")
+	rampInitial := flag.Int("ramp-initial", 0, "concurrency at start (0 disables the slow start)")
+	rampPeriod := flag.Duration("ramp-period", 10*time.Second, "time to ramp from -ramp-initial to -concurrency")
+	flag.Parse()
+
+	r := ramp.Ramp{Initial: *concurrency, Max: *concurrency}
+	if *rampInitial > 0 {
+		r = ramp.Ramp{Initial: *rampInitial, Max: *concurrency, Period: *rampPeriod}
+	}
+	gate := ramp.NewGate(r)
+
+	mutation := fmt.Sprintf(`mutation { transfer(from_address: "%s", to_address: "%s", amount: "%s") { balance } }`,
+		*from, *to, *amount)
+	body, _ := json.Marshal(map[string]string{"query": mutation})
+
+	var succeeded, failed atomic.Int64
+	var wg sync.WaitGroup
+	start := time.Now()
+
+	for i := 0; i < *requests; i++ {
+		if err := gate.Acquire(context.Background()); err != nil {
+			log.Fatalf("Failed to acquire slot: %v", err)
+		}
+
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			defer gate.Release()
+
+			if err := send(*url, body); err != nil {
+				failed.Add(1)
+				return
+			}
+			succeeded.Add(1)
+		}()
+	}
+	wg.Wait()
+
+	elapsed := time.Since(start)
+	log.Printf("Sent %d transfers in %s (%.1f req/s): %d succeeded, %d failed",
+		*requests, elapsed, float64(*requests)/elapsed.Seconds(), succeeded.Load(), failed.Load())
+}
+
+func send(url string, body []byte) error {
+	resp, err := http.Post(url, "application/json", bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	var result graphQLResponse
+	if err := json.NewDecoder(resp.Body).Decode(&result); err != nil {
+		return err
+	}
+	if len(result.Errors) > 0 {
+		return fmt.Errorf("%v", result.Errors[0]["message"])
+	}
+	return nil
+}
@@ -0,0 +1,27 @@
+package main
+
+import (
+	"context"
+	"log"
+	"token-transfer-api/internal/db"
+
+	"github.com/joho/godotenv"
+)
+
+func main() {
+	// Load environment variables
+	if err := godotenv.Load(); err != nil {
+		log.Println("No .env file found, using environment variables")
+	}
+
+	if err := db.InitDB(); err != nil {
+		log.Fatalf("Failed to initialize database: %v", err)
+	}
+	defer db.CloseDB()
+
+	// InitDB already migrated unless DB_AUTO_MIGRATE is false
+	if err := db.Migrate(context.Background(), db.DB); err != nil {
+		log.Fatalf("Migration failed: %v", err)
+	}
+	log.Println("Database schema is up to date")
+}
@@ -0,0 +1,17 @@
+package main
+
+import (
+	"fmt"
+	"log"
+	"token-transfer-api/pkg/graphql"
+)
+
+// main writes the served schema in SDL to stdout; make schema stores it in
+// pkg/graphql/schema.graphql.
+func main() {
+	sdl, err := graphql.SchemaSDL()
+	if err != nil {
+		log.Fatalf("Failed to build schema: %v", err)
+	}
+	fmt.Print(sdl)
+}
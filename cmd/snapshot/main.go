@@ -0,0 +1,38 @@
+package main
+
+import (
+	"fmt"
+	"log"
+	"os"
+	"token-transfer-api/internal/db"
+
+	"github.com/joho/godotenv"
+)
+
+func main() {
+	if len(os.Args) != 2 || (os.Args[1] != "export" && os.Args[1] != "import") {
+		fmt.Fprintln(os.Stderr, "usage: snapshot export > snapshot.ndjson")
+		fmt.Fprintln(os.Stderr, "       snapshot import < snapshot.ndjson")
+		os.Exit(2)
+	}
+
+	// Load environment variables
+	if err := godotenv.Load(); err != nil {
+		log.Println("No .env file found, using environment variables")
+	}
+
+	if err := db.InitDB(); err != nil {
+		log.Fatalf("Failed to initialize database: %v", err)
+	}
+	defer db.CloseDB()
+
+	var err error
+	if os.Args[1] == "export" {
+		err = db.ExportSnapshot(os.Stdout)
+	} else {
+		err = db.ImportSnapshot(os.Stdin)
+	}
+	if err != nil {
+		log.Fatalf("Snapshot %s failed: %v", os.Args[1], err)
+	}
+}
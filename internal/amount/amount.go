@@ -0,0 +1,82 @@
+// Package amount converts between human token amounts such as "1.5" and the
+// integer base units stored in the database.
+package amount
+
+import (
+	"errors"
+	"math/big"
+	"strings"
+)
+
+var (
+	// ErrSyntax is returned for input that is not a plain decimal number.
+	ErrSyntax = errors.New("amount is not a decimal number")
+	// ErrTooPrecise is returned for input with more significant fractional
+	// digits than the token has decimals.
+	ErrTooPrecise = errors.New("amount has more fractional digits than the token supports")
+)
+
+// Parse converts a human amount into base units for a token with the given
+// number of decimals, e.g. "1.5" with 18 decimals becomes
+// "1500000000000000000". Trailing fractional zeros are ignored, so "1.50" is
+// accepted with one decimal. Signs, whitespace, exponents, leading zeros such
+// as "007" and a bare "." or ".5" are not accepted.
+func Parse(human string, decimals int) (string, error) {
+	whole, frac, hasPoint := strings.Cut(human, ".")
+	if whole == "" || (hasPoint && frac == "") || !digitsOnly(whole) || !digitsOnly(frac) {
+		return "", ErrSyntax
+	}
+	if len(whole) > 1 && whole[0] == '0' {
+		return "", ErrSyntax
+	}
+
+	frac = strings.TrimRight(frac, "0")
+	if len(frac) > decimals {
+		return "", ErrTooPrecise
+	}
+
+	base := strings.TrimLeft(whole+frac+strings.Repeat("0", decimals-len(frac)), "0")
+	if base == "" {
+		return "0", nil
+	}
+	return base, nil
+}
+
+// ParseBase parses a positive amount of base units. Only the canonical form
+// is accepted, digits without leading zeros, so every amount has exactly one
+// spelling.
+func ParseBase(s string) (*big.Int, error) {
+	if s == "" || s[0] == '0' || !digitsOnly(s) {
+		return nil, ErrSyntax
+	}
+	v, _ := new(big.Int).SetString(s, 10)
+	return v, nil
+}
+
+// Format renders base units in human units for a token with the given number
+// of decimals, dropping trailing fractional zeros.
+func Format(base string, decimals int) string {
+	if decimals == 0 {
+		return base
+	}
+
+	if len(base) <= decimals {
+		base = strings.Repeat("0", decimals-len(base)+1) + base
+	}
+
+	whole := base[:len(base)-decimals]
+	frac := strings.TrimRight(base[len(base)-decimals:], "0")
+	if frac == "" {
+		return whole
+	}
+	return whole + "." + frac
+}
+
+func digitsOnly(s string) bool {
+	for _, c := range s {
+		if c < '0' || c > '9' {
+			return false
+		}
+	}
+	return true
+}
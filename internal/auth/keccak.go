@@ -0,0 +1,98 @@
+package auth
+
+import (
+	"encoding/binary"
+	"math/bits"
+)
+
+// keccakRate is the number of bytes absorbed per permutation by Keccak-256.
+const keccakRate = 136
+
+var keccakRoundConstants = [24]uint64{
+	0x0000000000000001, 0x0000000000008082, 0x800000000000808a, 0x8000000080008000,
+	0x000000000000808b, 0x0000000080000001, 0x8000000080008081, 0x8000000000008009,
+	0x000000000000008a, 0x0000000000000088, 0x0000000080008009, 0x000000008000000a,
+	0x000000008000808b, 0x800000000000008b, 0x8000000000008089, 0x8000000000008003,
+	0x8000000000008002, 0x8000000000000080, 0x000000000000800a, 0x800000008000000a,
+	0x8000000080008081, 0x8000000000008080, 0x0000000080000001, 0x8000000080008008,
+}
+
+// keccakRotations are the rho offsets, indexed by x + 5y.
+var keccakRotations = [25]int{
+	0, 1, 62, 28, 27,
+	36, 44, 6, 55, 20,
+	3, 10, 43, 25, 39,
+	41, 45, 15, 21, 8,
+	18, 2, 61, 56, 14,
+}
+
+// Keccak256 returns the Keccak-256 hash of the concatenated data as Ethereum
+// uses it. It is the original Keccak padding, not the FIPS 202 SHA3-256 one,
+// so the two give different hashes for the same input.
+func Keccak256(data ...[]byte) []byte {
+	var msg []byte
+	for _, d := range data {
+		msg = append(msg, d...)
+	}
+
+	var state [25]uint64
+	for len(msg) >= keccakRate {
+		absorb(&state, msg[:keccakRate])
+		msg = msg[keccakRate:]
+	}
+
+	var last [keccakRate]byte
+	copy(last[:], msg)
+	last[len(msg)] ^= 0x01
+	last[keccakRate-1] ^= 0x80
+	absorb(&state, last[:])
+
+	out := make([]byte, 32)
+	for i := 0; i < 4; i++ {
+		binary.LittleEndian.PutUint64(out[i*8:], state[i])
+	}
+	return out
+}
+
+// absorb XORs one block into the state and permutes it.
+func absorb(state *[25]uint64, block []byte) {
+	for i := 0; i < keccakRate/8; i++ {
+		state[i] ^= binary.LittleEndian.Uint64(block[i*8:])
+	}
+	keccakF(state)
+}
+
+// keccakF is the Keccak-f[1600] permutation.
+func keccakF(a *[25]uint64) {
+	for round := 0; round < 24; round++ {
+		// theta
+		var c [5]uint64
+		for x := 0; x < 5; x++ {
+			c[x] = a[x] ^ a[x+5] ^ a[x+10] ^ a[x+15] ^ a[x+20]
+		}
+		for x := 0; x < 5; x++ {
+			d := c[(x+4)%5] ^ bits.RotateLeft64(c[(x+1)%5], 1)
+			for y := 0; y < 25; y += 5 {
+				a[x+y] ^= d
+			}
+		}
+
+		// rho and pi
+		var b [25]uint64
+		for x := 0; x < 5; x++ {
+			for y := 0; y < 5; y++ {
+				b[y+5*((2*x+3*y)%5)] = bits.RotateLeft64(a[x+5*y], keccakRotations[x+5*y])
+			}
+		}
+
+		// chi
+		for y := 0; y < 25; y += 5 {
+			for x := 0; x < 5; x++ {
+				a[x+y] = b[x+y] ^ (^b[(x+1)%5+y] & b[(x+2)%5+y])
+			}
+		}
+
+		// iota
+		a[0] ^= keccakRoundConstants[round]
+	}
+}
@@ -0,0 +1,152 @@
+package auth
+
+import (
+	"crypto/rand"
+	"errors"
+	"math/big"
+)
+
+// The secp256k1 curve y² = x³ + 7 over the field of order p, with base point
+// (gx, gy) of order n.
+var (
+	curveP, _  = new(big.Int).SetString("fffffffffffffffffffffffffffffffffffffffffffffffffffffffefffffc2f", 16)
+	curveN, _  = new(big.Int).SetString("fffffffffffffffffffffffffffffffebaaedce6af48a03bbfd25e8cd0364141", 16)
+	curveGx, _ = new(big.Int).SetString("79be667ef9dcbbac55a06295ce870b07029bfcdb2dce28d959f2815b16f81798", 16)
+	curveGy, _ = new(big.Int).SetString("483ada7726a3c4655da4fbfc0e1108a8fd17b448a68554199c47d08ffb10d4b8", 16)
+	curveB     = big.NewInt(7)
+	halfN      = new(big.Int).Rsh(curveN, 1)
+)
+
+// errInvalidSignature is returned for signatures no public key can be
+// recovered from.
+var errInvalidSignature = errors.New("invalid signature")
+
+// point is an affine curve point; the point at infinity has a nil x.
+type point struct {
+	x, y *big.Int
+}
+
+func (pt point) infinity() bool {
+	return pt.x == nil
+}
+
+// add returns a + b.
+func add(a, b point) point {
+	if a.infinity() {
+		return b
+	}
+	if b.infinity() {
+		return a
+	}
+
+	var slope *big.Int
+	if a.x.Cmp(b.x) == 0 {
+		if a.y.Cmp(b.y) != 0 || a.y.Sign() == 0 {
+			return point{}
+		}
+		// Doubling: (3x²) / (2y)
+		num := new(big.Int).Mul(a.x, a.x)
+		num.Mul(num, big.NewInt(3))
+		den := new(big.Int).Lsh(a.y, 1)
+		slope = num.Mul(num, den.ModInverse(den.Mod(den, curveP), curveP))
+	} else {
+		num := new(big.Int).Sub(b.y, a.y)
+		den := new(big.Int).Sub(b.x, a.x)
+		slope = num.Mul(num, den.ModInverse(den.Mod(den, curveP), curveP))
+	}
+	slope.Mod(slope, curveP)
+
+	x := new(big.Int).Mul(slope, slope)
+	x.Sub(x, a.x).Sub(x, b.x).Mod(x, curveP)
+	y := new(big.Int).Sub(a.x, x)
+	y.Mul(y, slope).Sub(y, a.y).Mod(y, curveP)
+	return point{x, y}
+}
+
+// mul returns k·pt by double-and-add. It runs in time that depends on k, so
+// it must not be given secret scalars outside tests and tools.
+func mul(pt point, k *big.Int) point {
+	var result point
+	for i := k.BitLen() - 1; i >= 0; i-- {
+		result = add(result, result)
+		if k.Bit(i) == 1 {
+			result = add(result, pt)
+		}
+	}
+	return result
+}
+
+var generator = point{curveGx, curveGy}
+
+// recoverPublicKey returns the public key that made the signature (r, s)
+// over hash, where recid is the parity of the y coordinate of the point r
+// was taken from.
+func recoverPublicKey(hash []byte, r, s *big.Int, recid byte) (point, error) {
+	if r.Sign() <= 0 || r.Cmp(curveN) >= 0 || s.Sign() <= 0 || s.Cmp(curveN) >= 0 || recid > 1 {
+		return point{}, errInvalidSignature
+	}
+
+	// y² = x³ + 7 for x = r; p ≡ 3 (mod 4), so a root is (y²)^((p+1)/4)
+	ySquared := new(big.Int).Exp(r, big.NewInt(3), curveP)
+	ySquared.Add(ySquared, curveB).Mod(ySquared, curveP)
+	exp := new(big.Int).Add(curveP, big.NewInt(1))
+	y := new(big.Int).Exp(ySquared, exp.Rsh(exp, 2), curveP)
+	if new(big.Int).Exp(y, big.NewInt(2), curveP).Cmp(ySquared) != 0 {
+		return point{}, errInvalidSignature
+	}
+	if y.Bit(0) != uint(recid) {
+		y.Sub(curveP, y)
+	}
+
+	// Q = r⁻¹(sR − eG)
+	e := new(big.Int).SetBytes(hash)
+	rInv := new(big.Int).ModInverse(r, curveN)
+	u1 := new(big.Int).Mul(e, rInv)
+	u1.Neg(u1).Mod(u1, curveN)
+	u2 := new(big.Int).Mul(s, rInv)
+	u2.Mod(u2, curveN)
+
+	q := add(mul(generator, u1), mul(point{new(big.Int).Set(r), y}, u2))
+	if q.infinity() {
+		return point{}, errInvalidSignature
+	}
+	return q, nil
+}
+
+// sign signs hash with the private key d and returns r, s and the recovery
+// id, with s in the lower half of the order as Ethereum requires.
+func sign(hash []byte, d *big.Int) (*big.Int, *big.Int, byte, error) {
+	e := new(big.Int).SetBytes(hash)
+	for {
+		k, err := rand.Int(rand.Reader, curveN)
+		if err != nil {
+			return nil, nil, 0, err
+		}
+		if k.Sign() == 0 {
+			continue
+		}
+
+		rp := mul(generator, k)
+		// A point whose x is at least n would need a recovery id above 1,
+		// which Ethereum signatures cannot carry.
+		if rp.x.Cmp(curveN) >= 0 {
+			continue
+		}
+		r := new(big.Int).Set(rp.x)
+		if r.Sign() == 0 {
+			continue
+		}
+		recid := byte(rp.y.Bit(0))
+
+		s := new(big.Int).Mul(r, d)
+		s.Add(s, e).Mul(s, new(big.Int).ModInverse(k, curveN)).Mod(s, curveN)
+		if s.Sign() == 0 {
+			continue
+		}
+		if s.Cmp(halfN) > 0 {
+			s.Sub(curveN, s)
+			recid ^= 1
+		}
+		return r, s, recid, nil
+	}
+}
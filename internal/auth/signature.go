@@ -0,0 +1,129 @@
+// Package auth verifies Ethereum-style signatures authorizing transfers. A
+// transfer is signed as an EIP-191 personal message, so any Ethereum wallet
+// can produce the signature, and the signer is recovered from it with
+// secp256k1 and Keccak-256 like ecrecover does.
+package auth
+
+import (
+	"encoding/hex"
+	"errors"
+	"math/big"
+	"strconv"
+	"strings"
+)
+
+var (
+	// ErrMalformedSignature is returned for signatures that are not 65 hex
+	// encoded bytes or from which no signer can be recovered.
+	ErrMalformedSignature = errors.New("malformed signature")
+	// ErrWrongSigner is returned when a valid signature was made by another
+	// address than the sender.
+	ErrWrongSigner = errors.New("signature was not made by the sender")
+)
+
+// TransferMessage is the canonical message a sender signs to authorize a
+// transfer: the lowercased addresses, the amount in base units and the
+// sender's nonce, separated by "|".
+func TransferMessage(fromAddress, toAddress, amount string, nonce int64) []byte {
+	return []byte(strings.ToLower(fromAddress) + "|" + strings.ToLower(toAddress) + "|" + amount + "|" + strconv.FormatInt(nonce, 10))
+}
+
+// SweepMessage is the canonical message a sender signs to authorize a
+// sweep. It is the transfer message with "sweep" in place of the amount,
+// which no amount can be, so neither signature passes for the other.
+func SweepMessage(fromAddress, toAddress string, nonce int64) []byte {
+	return TransferMessage(fromAddress, toAddress, "sweep", nonce)
+}
+
+// HashMessage returns the EIP-191 hash of a personal message, the hash
+// Ethereum wallets sign for personal_sign.
+func HashMessage(message []byte) []byte {
+	prefix := "\x19Ethereum Signed Message:\n" + strconv.Itoa(len(message))
+	return Keccak256([]byte(prefix), message)
+}
+
+// RecoverAddress returns the lowercase address that signed message. The
+// signature is 0x followed by the 65 bytes r, s and v in hex, with v either
+// 27 or 28 or the bare recovery id 0 or 1. Signatures with s in the upper
+// half of the curve order are rejected, as Ethereum does, so each message
+// has only one valid signature per key.
+func RecoverAddress(message []byte, signature string) (string, error) {
+	raw, err := hex.DecodeString(strings.TrimPrefix(signature, "0x"))
+	if err != nil || len(raw) != 65 || !strings.HasPrefix(signature, "0x") {
+		return "", ErrMalformedSignature
+	}
+
+	r := new(big.Int).SetBytes(raw[:32])
+	s := new(big.Int).SetBytes(raw[32:64])
+	v := raw[64]
+	if v >= 27 {
+		v -= 27
+	}
+	if s.Cmp(halfN) > 0 {
+		return "", ErrMalformedSignature
+	}
+
+	pub, err := recoverPublicKey(HashMessage(message), r, s, v)
+	if err != nil {
+		return "", ErrMalformedSignature
+	}
+	return publicKeyAddress(pub), nil
+}
+
+// VerifyTransfer checks that signature signs the transfer message of the
+// given transfer and was made by the sender.
+func VerifyTransfer(signature, fromAddress, toAddress, amount string, nonce int64) error {
+	return verify(TransferMessage(fromAddress, toAddress, amount, nonce), signature, fromAddress)
+}
+
+// VerifySweep checks that signature signs the sweep message of the given
+// sweep and was made by the sender.
+func VerifySweep(signature, fromAddress, toAddress string, nonce int64) error {
+	return verify(SweepMessage(fromAddress, toAddress, nonce), signature, fromAddress)
+}
+
+// verify checks that signature signs message and was made by signer.
+func verify(message []byte, signature, signer string) error {
+	recovered, err := RecoverAddress(message, signature)
+	if err != nil {
+		return err
+	}
+	if recovered != strings.ToLower(signer) {
+		return ErrWrongSigner
+	}
+	return nil
+}
+
+// publicKeyAddress is the last 20 bytes of the Keccak-256 hash of the
+// public key's coordinates, in lowercase hex.
+func publicKeyAddress(pub point) string {
+	var coords [64]byte
+	pub.x.FillBytes(coords[:32])
+	pub.y.FillBytes(coords[32:])
+	return "0x" + hex.EncodeToString(Keccak256(coords[:])[12:])
+}
+
+// Address returns the lowercase address of a private key.
+func Address(key *big.Int) string {
+	return publicKeyAddress(mul(generator, key))
+}
+
+// Sign signs message as a personal message with the private key and returns
+// the signature in the form RecoverAddress reads, with v 27 or 28. It is
+// meant for tests and tools: the arithmetic is not constant time, so keys
+// that guard real funds should be signed with in a wallet instead.
+func Sign(message []byte, key *big.Int) (string, error) {
+	if key.Sign() <= 0 || key.Cmp(curveN) >= 0 {
+		return "", errors.New("private key out of range")
+	}
+	r, s, recid, err := sign(HashMessage(message), key)
+	if err != nil {
+		return "", err
+	}
+
+	var raw [65]byte
+	r.FillBytes(raw[:32])
+	s.FillBytes(raw[32:64])
+	raw[64] = 27 + recid
+	return "0x" + hex.EncodeToString(raw[:]), nil
+}
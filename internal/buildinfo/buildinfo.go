@@ -0,0 +1,48 @@
+// Package buildinfo reports the version of the running binary. The variables
+// are set at build time, e.g.
+//
+//	go build -ldflags "-X token-transfer-api/internal/buildinfo.Version=v1.2.0 \
+//		-X token-transfer-api/internal/buildinfo.Commit=$(git rev-parse HEAD) \
+//		-X token-transfer-api/internal/buildinfo.BuildTime=$(date -u +%Y-%m-%dT%H:%M:%SZ)" ./cmd/api
+//
+// and are "dev" when they are not.
+package buildinfo
+
+import (
+	"encoding/json"
+	"net/http"
+)
+
+var (
+	// Version is the release the binary was built from.
+	Version = "dev"
+	// Commit is the git commit the binary was built from.
+	Commit = "dev"
+	// BuildTime is when the binary was built.
+	BuildTime = "dev"
+)
+
+// Info is the build information served by Handler.
+type Info struct {
+	Version   string `json:"version"`
+	Commit    string `json:"commit"`
+	BuildTime string `json:"build_time"`
+}
+
+// Get returns the build information of the running binary.
+func Get() Info {
+	return Info{Version: Version, Commit: Commit, BuildTime: BuildTime}
+}
+
+// Handler serves the build information as JSON to GET requests.
+func Handler() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodGet && r.Method != http.MethodHead {
+			w.Header().Set("Allow", "GET, HEAD")
+			http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(Get())
+	})
+}
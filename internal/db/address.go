@@ -0,0 +1,10 @@
+package db
+
+import "regexp"
+
+var addressPattern = regexp.MustCompile(`^0x[0-9a-fA-F]{40}$`)
+
+// ValidAddress reports whether address is a 0x-prefixed, 20-byte hex address.
+func ValidAddress(address string) bool {
+	return addressPattern.MatchString(address)
+}
@@ -0,0 +1,18 @@
+package db
+
+import (
+	"math/big"
+	"token-transfer-api/internal/amount"
+)
+
+// parseAmount returns a transfer, mint or burn amount as a big.Int, or
+// ErrInvalidAmount unless it is a positive number of base units in canonical
+// form. Rejecting other spellings keeps the amounts stored in transfers
+// canonical.
+func parseAmount(s string) (*big.Int, error) {
+	v, err := amount.ParseBase(s)
+	if err != nil {
+		return nil, ErrInvalidAmount
+	}
+	return v, nil
+}
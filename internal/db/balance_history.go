@@ -0,0 +1,90 @@
+package db
+
+import (
+	"context"
+	"database/sql"
+	"time"
+	"token-transfer-api/internal/model"
+)
+
+// stmtRecordBalance adds a history row for every change.
+const stmtRecordBalance = "INSERT INTO balance_history (address, balance) VALUES ($1, $2)"
+
+// stmtRecordBalanceInterval keeps one row per wallet and interval of $3
+// seconds: the row of the current interval is overwritten, or added when it
+// does not exist yet. Writers of the same wallet hold its row lock, so they
+// never race for the interval's row.
+const stmtRecordBalanceInterval = `WITH bucket AS (
+	SELECT to_timestamp(floor(extract(epoch FROM NOW()) / $3::bigint) * $3::bigint) AS recorded_at
+), updated AS (
+	UPDATE balance_history SET balance = $2
+	WHERE address = $1 AND recorded_at = (SELECT recorded_at FROM bucket)
+	RETURNING id
+)
+INSERT INTO balance_history (address, balance, recorded_at)
+SELECT $1, $2, recorded_at FROM bucket WHERE NOT EXISTS (SELECT 1 FROM updated)`
+
+// recordBalance records balance as the new balance of the wallet at address
+// when the balance history is on. Callers that change a wallet more than
+// once in a transaction record it after each change, so the last row holds
+// the balance the transaction leaves.
+func recordBalance(tx txn, cfg Config, address, balance string) error {
+	if !cfg.BalanceHistory {
+		return nil
+	}
+	if cfg.BalanceHistoryInterval > 0 {
+		_, err := tx.Exec(stmtRecordBalanceInterval, address, balance, int64(cfg.BalanceHistoryInterval/time.Second))
+		return err
+	}
+	_, err := tx.Exec(stmtRecordBalance, address, balance)
+	return err
+}
+
+// GetBalanceHistory returns the recorded balances of the wallet at address,
+// oldest first, optionally only those recorded at or after since and before
+// until. It is empty unless BALANCE_HISTORY was on while the wallet changed.
+func GetBalanceHistory(address string, since, until *time.Time) ([]model.BalanceSnapshot, error) {
+	return GetBalanceHistoryContext(context.Background(), address, since, until)
+}
+
+func GetBalanceHistoryContext(ctx context.Context, address string, since, until *time.Time) (_ []model.BalanceSnapshot, err error) {
+	defer func() { err = ClassifyError(err) }()
+
+	address = Settings.NormalizeAddress(address)
+
+	var from, to sql.NullTime
+	if since != nil {
+		from = sql.NullTime{Time: *since, Valid: true}
+	}
+	if until != nil {
+		to = sql.NullTime{Time: *until, Valid: true}
+	}
+
+	q, err := readConn(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	rows, err := q.Query(`
+		SELECT address, balance::text, recorded_at
+		FROM balance_history
+		WHERE address = $1
+			AND ($2::timestamptz IS NULL OR recorded_at >= $2::timestamptz)
+			AND ($3::timestamptz IS NULL OR recorded_at < $3::timestamptz)
+		ORDER BY recorded_at, id`, address, from, to)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	history := []model.BalanceSnapshot{}
+	for rows.Next() {
+		var s model.BalanceSnapshot
+		if err := rows.Scan(&s.Address, &s.Balance, &s.RecordedAt); err != nil {
+			return nil, err
+		}
+		s.RecordedAt = s.RecordedAt.UTC()
+		history = append(history, s)
+	}
+	return history, rows.Err()
+}
@@ -0,0 +1,83 @@
+package db
+
+import "context"
+
+// BlockAddress adds address to the compliance blocklist. Transfers from or to
+// a blocked address are rejected. The wallet row, if any, is locked first so
+// the block waits for transfers already in flight and applies to all later
+// ones.
+func BlockAddress(address string) error {
+	return BlockAddressContext(context.Background(), address)
+}
+
+func BlockAddressContext(ctx context.Context, address string) (err error) {
+	defer func() { err = ClassifyError(err) }()
+
+	address = Settings.NormalizeAddress(address)
+
+	tx, err := begin(ctx)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	// Lock the wallet with a no-op update rather than SELECT FOR UPDATE: a
+	// transfer under REPEATABLE READ that waited for this lock then fails
+	// with a serialization error and is retried with a snapshot that
+	// includes the block, instead of missing it.
+	_, err = tx.Exec("UPDATE wallets SET balance = balance WHERE address = $1", address)
+	if err != nil {
+		return err
+	}
+
+	_, err = tx.Exec("INSERT INTO blocked_addresses (address) VALUES ($1) ON CONFLICT (address) DO NOTHING", address)
+	if err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
+
+// UnblockAddress removes address from the blocklist and reports whether it
+// was blocked.
+func UnblockAddress(address string) (bool, error) {
+	return UnblockAddressContext(context.Background(), address)
+}
+
+func UnblockAddressContext(ctx context.Context, address string) (_ bool, err error) {
+	defer func() { err = ClassifyError(err) }()
+
+	address = Settings.NormalizeAddress(address)
+
+	q, err := conn(ctx)
+	if err != nil {
+		return false, err
+	}
+
+	res, err := q.Exec("DELETE FROM blocked_addresses WHERE address = $1", address)
+	if err != nil {
+		return false, err
+	}
+	n, err := res.RowsAffected()
+	if err != nil {
+		return false, err
+	}
+	return n > 0, nil
+}
+
+// checkBlocked returns ErrBlockedAddress if any of the addresses is on the
+// blocklist. It runs inside the transfer transaction after the wallets are
+// locked, so a block committed before the locks were taken is always seen.
+func checkBlocked(tx querier, addresses ...string) error {
+	for _, address := range addresses {
+		var blocked bool
+		err := queryRow(tx, stmtAddressBlocked, address).Scan(&blocked)
+		if err != nil {
+			return err
+		}
+		if blocked {
+			return ErrBlockedAddress
+		}
+	}
+	return nil
+}
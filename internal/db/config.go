@@ -0,0 +1,405 @@
+package db
+
+import (
+	"database/sql"
+	"fmt"
+	"math/big"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Config holds the ledger rules read from the environment by InitDB.
+type Config struct {
+	// FeeFlat is charged on every transfer on top of FeeBPS.
+	FeeFlat *big.Int
+	// FeeBPS is the percentage fee in basis points (1/100 of a percent).
+	FeeBPS int64
+	// FeeWallet receives the fees. Fees are only charged when it is set.
+	FeeWallet string
+	// CaseInsensitiveAddresses lowercases every address before it is used,
+	// so 0xAbc and 0xabc refer to the same wallet.
+	CaseInsensitiveAddresses bool
+	// AllowedAmounts, when not empty, lists the only amounts a transfer may
+	// move, keyed by their decimal string.
+	AllowedAmounts map[string]bool
+	// DeniedAmounts lists amounts a transfer may not move.
+	DeniedAmounts map[string]bool
+	// DailyLimit caps the value, fees included, a wallet may send within
+	// DailyLimitWindow. Nil disables the limit.
+	DailyLimit *big.Int
+	// MaxTransferAmount caps the amount a single transfer may move, fees
+	// excluded. Nil leaves transfers unlimited.
+	MaxTransferAmount *big.Int
+	// ZeroAddressReserved makes transfers sent by ZeroAddress fail with
+	// ErrReservedAddress, so new tokens only enter circulation through Mint.
+	// It is set by ZERO_ADDRESS_SPENDABLE=false.
+	ZeroAddressReserved bool
+	// ReceiverMustExist makes transfers to an address without a wallet fail
+	// with ErrReceiverNotFound instead of creating the wallet. Mints still
+	// create wallets. It is set by AUTO_CREATE_RECEIVER=false.
+	ReceiverMustExist bool
+	// NewWalletGrant is credited on top of the amount to a receiver wallet
+	// a transfer of any token, a sweep or a hold release creates, and
+	// recorded as a mint to it. Nil grants nothing. Wallets that mints,
+	// credits and imports create get no grant.
+	NewWalletGrant *big.Int
+	// NewWalletStatus is the status of receiver wallets transfers, sweeps
+	// and hold releases create. Empty means WalletActive; wallets created
+	// otherwise are always active.
+	NewWalletStatus string
+	// PrimaryToken is the symbol of the token kept in wallets.balance, which
+	// every operation without a token works on.
+	PrimaryToken string
+	// Tokens lists the symbols of the other tokens wallets may hold. Their
+	// balances are kept in token_balances.
+	Tokens map[string]bool
+	// MaxRetries is how often a transfer that hit a serialization failure or
+	// deadlock is retried before ErrConflict is returned.
+	MaxRetries int
+	// Isolation is the isolation level of the transactions the db functions
+	// start.
+	Isolation sql.IsolationLevel
+	// PreparedStatements makes InitDB prepare the statements of the transfer
+	// path once. Turn it off behind poolers that do not support prepared
+	// statements, such as PgBouncer in transaction mode.
+	PreparedStatements bool
+	// AutoMigrate makes InitDB apply pending schema migrations before the
+	// statements are prepared.
+	AutoMigrate bool
+	// GenesisAddress is the treasury wallet InitDB seeds with GenesisSupply
+	// tokens when it does not exist yet.
+	GenesisAddress string
+	// GenesisSupply is the initial supply of GenesisAddress. Nil disables
+	// seeding.
+	GenesisSupply *big.Int
+	// TransferEvents makes every transfer write an event to the
+	// transfer_events outbox for the relay to deliver. It is on when
+	// TRANSFER_EVENTS_URL is set.
+	TransferEvents bool
+	// BalanceHistory makes every change to a wallet's balance record the new
+	// balance in balance_history, in the transaction that made it.
+	BalanceHistory bool
+	// BalanceHistoryInterval bounds the history to one row per wallet and
+	// interval, holding the balance at the interval's end. Zero keeps a row
+	// for every change.
+	BalanceHistoryInterval time.Duration
+	// ConnectRetries is how often InitDB pings the database again when it
+	// is not reachable yet, e.g. while Postgres starts alongside the server.
+	ConnectRetries int
+	// ConnectBackoff is the pause before InitDB's first reconnection
+	// attempt. It doubles with every further attempt.
+	ConnectBackoff time.Duration
+	// ReadAfterWrite is how long after this process commits a write the
+	// reads that may use the read replica go to the primary instead, so
+	// they see the write even while the replica lags.
+	ReadAfterWrite time.Duration
+	// SlowQuery makes every statement that runs longer be logged with its
+	// SQL and duration. Zero disables the log.
+	SlowQuery time.Duration
+}
+
+// Settings is the configuration in effect for the db functions.
+var Settings = Config{FeeFlat: new(big.Int), PrimaryToken: DefaultPrimaryToken, MaxRetries: DefaultMaxRetries, Isolation: DefaultIsolation, PreparedStatements: true, AutoMigrate: true}
+
+// DefaultConnectBackoff is the pause before the first reconnection attempt
+// when DB_CONNECT_BACKOFF is not set.
+const DefaultConnectBackoff = time.Second
+
+// DefaultReadAfterWrite is how long reads stay on the primary after a write
+// when DB_READ_AFTER_WRITE is not set.
+const DefaultReadAfterWrite = time.Second
+
+// DefaultGenesisSupply is the treasury's initial supply when GENESIS_SUPPLY
+// is not set.
+const DefaultGenesisSupply = 1000000
+
+// DefaultIsolation is the transaction isolation level used when DB_ISOLATION
+// is not set.
+const DefaultIsolation = sql.LevelRepeatableRead
+
+// isolationLevels maps the accepted DB_ISOLATION values to their levels.
+var isolationLevels = map[string]sql.IsolationLevel{
+	"READ COMMITTED":  sql.LevelReadCommitted,
+	"REPEATABLE READ": sql.LevelRepeatableRead,
+	"SERIALIZABLE":    sql.LevelSerializable,
+}
+
+// LoadConfig reads the ledger configuration from the environment.
+func LoadConfig() (Config, error) {
+	cfg := Config{
+		FeeFlat:            new(big.Int),
+		FeeWallet:          os.Getenv("FEE_WALLET_ADDRESS"),
+		PrimaryToken:       DefaultPrimaryToken,
+		MaxRetries:         DefaultMaxRetries,
+		TransferEvents:     os.Getenv("TRANSFER_EVENTS_URL") != "",
+		Isolation:          DefaultIsolation,
+		PreparedStatements: true,
+		AutoMigrate:        true,
+		GenesisAddress:     ZeroAddress,
+		GenesisSupply:      big.NewInt(DefaultGenesisSupply),
+		ConnectBackoff:     DefaultConnectBackoff,
+		ReadAfterWrite:     DefaultReadAfterWrite,
+	}
+
+	if v := os.Getenv("TRANSFER_FEE_FLAT"); v != "" {
+		flat, ok := new(big.Int).SetString(v, 10)
+		if !ok || flat.Sign() < 0 {
+			return Config{}, fmt.Errorf("invalid TRANSFER_FEE_FLAT %q", v)
+		}
+		cfg.FeeFlat = flat
+	}
+
+	if v := os.Getenv("TRANSFER_FEE_BPS"); v != "" {
+		bps, err := strconv.ParseInt(v, 10, 64)
+		if err != nil || bps < 0 || bps > 10000 {
+			return Config{}, fmt.Errorf("invalid TRANSFER_FEE_BPS %q", v)
+		}
+		cfg.FeeBPS = bps
+	}
+
+	if v := os.Getenv("ADDRESS_CASE_INSENSITIVE"); v != "" {
+		insensitive, err := strconv.ParseBool(v)
+		if err != nil {
+			return Config{}, fmt.Errorf("invalid ADDRESS_CASE_INSENSITIVE %q", v)
+		}
+		cfg.CaseInsensitiveAddresses = insensitive
+	}
+	cfg.FeeWallet = cfg.NormalizeAddress(cfg.FeeWallet)
+
+	if v := os.Getenv("TRANSFER_MAX_RETRIES"); v != "" {
+		retries, err := strconv.Atoi(v)
+		if err != nil || retries < 0 {
+			return Config{}, fmt.Errorf("invalid TRANSFER_MAX_RETRIES %q", v)
+		}
+		cfg.MaxRetries = retries
+	}
+
+	if v := os.Getenv("DB_ISOLATION"); v != "" {
+		level, ok := isolationLevels[strings.ToUpper(strings.TrimSpace(v))]
+		if !ok {
+			return Config{}, fmt.Errorf("invalid DB_ISOLATION %q", v)
+		}
+		cfg.Isolation = level
+	}
+
+	if v := os.Getenv("TRANSFER_DAILY_LIMIT"); v != "" {
+		limit, ok := new(big.Int).SetString(v, 10)
+		if !ok || limit.Sign() < 0 {
+			return Config{}, fmt.Errorf("invalid TRANSFER_DAILY_LIMIT %q", v)
+		}
+		if limit.Sign() > 0 {
+			cfg.DailyLimit = limit
+		}
+	}
+
+	if v := os.Getenv("MAX_TRANSFER_AMOUNT"); v != "" {
+		ceiling, ok := new(big.Int).SetString(v, 10)
+		if !ok || ceiling.Sign() <= 0 {
+			return Config{}, fmt.Errorf("invalid MAX_TRANSFER_AMOUNT %q", v)
+		}
+		cfg.MaxTransferAmount = ceiling
+	}
+
+	if v := os.Getenv("ZERO_ADDRESS_SPENDABLE"); v != "" {
+		spendable, err := strconv.ParseBool(v)
+		if err != nil {
+			return Config{}, fmt.Errorf("invalid ZERO_ADDRESS_SPENDABLE %q", v)
+		}
+		cfg.ZeroAddressReserved = !spendable
+	}
+
+	if v := os.Getenv("AUTO_CREATE_RECEIVER"); v != "" {
+		autoCreate, err := strconv.ParseBool(v)
+		if err != nil {
+			return Config{}, fmt.Errorf("invalid AUTO_CREATE_RECEIVER %q", v)
+		}
+		cfg.ReceiverMustExist = !autoCreate
+	}
+
+	if v := os.Getenv("NEW_WALLET_GRANT"); v != "" {
+		grant, ok := new(big.Int).SetString(v, 10)
+		if !ok || grant.Sign() < 0 {
+			return Config{}, fmt.Errorf("invalid NEW_WALLET_GRANT %q", v)
+		}
+		if grant.Sign() > 0 {
+			cfg.NewWalletGrant = grant
+		}
+	}
+
+	if v := os.Getenv("NEW_WALLET_DEFAULT_STATUS"); v != "" {
+		if v != WalletActive && walletStatusErrors[v] == nil {
+			return Config{}, fmt.Errorf("invalid NEW_WALLET_DEFAULT_STATUS %q", v)
+		}
+		if v != WalletActive {
+			cfg.NewWalletStatus = v
+		}
+	}
+
+	if v := os.Getenv("PRIMARY_TOKEN"); v != "" {
+		if !tokenPattern.MatchString(v) {
+			return Config{}, fmt.Errorf("invalid PRIMARY_TOKEN %q", v)
+		}
+		cfg.PrimaryToken = v
+	}
+
+	if v := os.Getenv("TOKENS"); v != "" {
+		cfg.Tokens = make(map[string]bool)
+		for _, item := range strings.Split(v, ",") {
+			token := strings.TrimSpace(item)
+			if !tokenPattern.MatchString(token) || token == cfg.PrimaryToken {
+				return Config{}, fmt.Errorf("invalid TOKENS entry %q", item)
+			}
+			cfg.Tokens[token] = true
+		}
+	}
+
+	if v := os.Getenv("DB_PREPARED_STATEMENTS"); v != "" {
+		enabled, err := strconv.ParseBool(v)
+		if err != nil {
+			return Config{}, fmt.Errorf("invalid DB_PREPARED_STATEMENTS %q", v)
+		}
+		cfg.PreparedStatements = enabled
+	}
+
+	if v := os.Getenv("DB_AUTO_MIGRATE"); v != "" {
+		enabled, err := strconv.ParseBool(v)
+		if err != nil {
+			return Config{}, fmt.Errorf("invalid DB_AUTO_MIGRATE %q", v)
+		}
+		cfg.AutoMigrate = enabled
+	}
+
+	if v := os.Getenv("GENESIS_ADDRESS"); v != "" {
+		if !ValidAddress(v) {
+			return Config{}, fmt.Errorf("invalid GENESIS_ADDRESS %q", v)
+		}
+		cfg.GenesisAddress = v
+	}
+
+	if v := os.Getenv("GENESIS_SUPPLY"); v != "" {
+		supply, ok := new(big.Int).SetString(v, 10)
+		if !ok || supply.Sign() < 0 {
+			return Config{}, fmt.Errorf("invalid GENESIS_SUPPLY %q", v)
+		}
+		cfg.GenesisSupply = supply
+		if supply.Sign() == 0 {
+			cfg.GenesisSupply = nil
+		}
+	}
+
+	if v := os.Getenv("DB_CONNECT_RETRIES"); v != "" {
+		retries, err := strconv.Atoi(v)
+		if err != nil || retries < 0 {
+			return Config{}, fmt.Errorf("invalid DB_CONNECT_RETRIES %q", v)
+		}
+		cfg.ConnectRetries = retries
+	}
+
+	if v := os.Getenv("DB_CONNECT_BACKOFF"); v != "" {
+		backoff, err := time.ParseDuration(v)
+		if err != nil || backoff < 0 {
+			return Config{}, fmt.Errorf("invalid DB_CONNECT_BACKOFF %q", v)
+		}
+		cfg.ConnectBackoff = backoff
+	}
+
+	if v := os.Getenv("DB_READ_AFTER_WRITE"); v != "" {
+		window, err := time.ParseDuration(v)
+		if err != nil || window < 0 {
+			return Config{}, fmt.Errorf("invalid DB_READ_AFTER_WRITE %q", v)
+		}
+		cfg.ReadAfterWrite = window
+	}
+
+	if v := os.Getenv("BALANCE_HISTORY"); v != "" {
+		enabled, err := strconv.ParseBool(v)
+		if err != nil {
+			return Config{}, fmt.Errorf("invalid BALANCE_HISTORY %q", v)
+		}
+		cfg.BalanceHistory = enabled
+	}
+
+	if v := os.Getenv("BALANCE_HISTORY_INTERVAL"); v != "" {
+		interval, err := time.ParseDuration(v)
+		if err != nil || interval < 0 || interval%time.Second != 0 {
+			return Config{}, fmt.Errorf("invalid BALANCE_HISTORY_INTERVAL %q", v)
+		}
+		cfg.BalanceHistoryInterval = interval
+	}
+
+	if v := os.Getenv("DB_SLOW_QUERY_MS"); v != "" {
+		ms, err := strconv.Atoi(v)
+		if err != nil || ms < 0 {
+			return Config{}, fmt.Errorf("invalid DB_SLOW_QUERY_MS %q", v)
+		}
+		cfg.SlowQuery = time.Duration(ms) * time.Millisecond
+	}
+
+	var err error
+	if cfg.AllowedAmounts, err = parseAmountSet("TRANSFER_AMOUNT_ALLOWLIST"); err != nil {
+		return Config{}, err
+	}
+	if cfg.DeniedAmounts, err = parseAmountSet("TRANSFER_AMOUNT_DENYLIST"); err != nil {
+		return Config{}, err
+	}
+
+	if cfg.FeeWallet == "" && (cfg.FeeFlat.Sign() > 0 || cfg.FeeBPS > 0) {
+		return Config{}, fmt.Errorf("FEE_WALLET_ADDRESS is required when transfer fees are configured")
+	}
+
+	return cfg, nil
+}
+
+// parseAmountSet reads a comma-separated list of positive amounts from the
+// named variable. An unset variable gives an empty set.
+func parseAmountSet(name string) (map[string]bool, error) {
+	v := os.Getenv(name)
+	if v == "" {
+		return nil, nil
+	}
+
+	set := make(map[string]bool)
+	for _, item := range strings.Split(v, ",") {
+		amount, ok := new(big.Int).SetString(strings.TrimSpace(item), 10)
+		if !ok || amount.Sign() <= 0 {
+			return nil, fmt.Errorf("invalid %s entry %q", name, item)
+		}
+		set[amount.String()] = true
+	}
+	return set, nil
+}
+
+// AmountAllowed reports whether a transfer may move amount under the
+// configured allowlist and denylist.
+func (c Config) AmountAllowed(amount *big.Int) bool {
+	key := amount.String()
+	if len(c.AllowedAmounts) > 0 && !c.AllowedAmounts[key] {
+		return false
+	}
+	return !c.DeniedAmounts[key]
+}
+
+// Fee returns the fee charged for transferring amount. The percentage part is
+// rounded down, so a fee is never overcharged by rounding.
+func (c Config) Fee(amount *big.Int) *big.Int {
+	if c.FeeWallet == "" {
+		return new(big.Int)
+	}
+
+	fee := new(big.Int).Mul(amount, big.NewInt(c.FeeBPS))
+	fee.Quo(fee, big.NewInt(10000))
+	if c.FeeFlat != nil {
+		fee.Add(fee, c.FeeFlat)
+	}
+	return fee
+}
+
+// NormalizeAddress returns the form of address stored in the database.
+func (c Config) NormalizeAddress(address string) string {
+	if c.CaseInsensitiveAddresses {
+		return strings.ToLower(address)
+	}
+	return address
+}
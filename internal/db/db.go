@@ -1,45 +1,143 @@
 package db
 
 import (
+	"context"
 	"database/sql"
 	"fmt"
 	"log"
-	"os"
+	"time"
 
 	_ "github.com/lib/pq"
 )
 
+// DB is the connection pool opened by InitDB. It is nil before InitDB
+// succeeds and again after CloseDB.
 var DB *sql.DB
 
+// DBRead is the pool wallet, history and stats reads may be served from. It
+// is a second pool to the replica named by DATABASE_READ_URL, or DB itself
+// when none is set. Writes always go to DB.
+var DBRead *sql.DB
+
+// InitDB loads Settings, opens the connection pool to the database named by
+// DATABASE_URL or the DB_* variables, waiting for it per DB_CONNECT_RETRIES,
+// and the one to the replica named by DATABASE_READ_URL, if any, applies
+// pending migrations to the primary and seeds the genesis wallet. It can be called
+// again after CloseDB to reopen the pool; a pool that is still open is closed
+// and replaced.
 func InitDB() error {
-	dbHost := os.Getenv("DB_HOST")
-	dbPort := os.Getenv("DB_PORT")
-	dbUser := os.Getenv("DB_USER")
-	dbPassword := os.Getenv("DB_PASSWORD")
-	dbName := os.Getenv("DB_NAME")
-	dbSSLMode := os.Getenv("DB_SSLMODE")
-
-	dsn := fmt.Sprintf("host=%s port=%s user=%s password=%s dbname=%s sslmode=%s",
-		dbHost, dbPort, dbUser, dbPassword, dbName, dbSSLMode)
-
-	var err error
-	DB, err = sql.Open("postgres", dsn)
+	cfg, err := LoadConfig()
+	if err != nil {
+		return fmt.Errorf("failed to load configuration: %w", err)
+	}
+	Settings = cfg
+
+	params, err := ConnParamsFromEnv()
+	if err != nil {
+		return err
+	}
+	if err := params.CheckTLS(); err != nil {
+		return err
+	}
+
+	pool, err := openPool(params.DSN(), cfg)
 	if err != nil {
 		return fmt.Errorf("failed to open database connection: %w", err)
 	}
 
-	if err := DB.Ping(); err != nil {
-		DB.Close()
-		return fmt.Errorf("failed to ping database: %w", err)
+	if err := ping(pool, cfg); err != nil {
+		pool.Close()
+		return err
+	}
+
+	if cfg.AutoMigrate {
+		if err := Migrate(context.Background(), pool); err != nil {
+			pool.Close()
+			return fmt.Errorf("failed to migrate database: %w", err)
+		}
+	}
+
+	stmts := map[string]*sql.Stmt{}
+	if cfg.PreparedStatements {
+		if stmts, err = prepareStatements(pool); err != nil {
+			pool.Close()
+			return fmt.Errorf("failed to prepare statements: %w", err)
+		}
+	}
+
+	readPool, err := openReadPool(cfg)
+	if err != nil {
+		closeStatements(stmts)
+		pool.Close()
+		return err
+	}
+	if readPool == nil {
+		readPool = pool
+	}
+
+	if err := CloseDB(); err != nil {
+		log.Printf("Failed to close previous database connection: %v", err)
+	}
+	DB = pool
+	DBRead = readPool
+	prepared = stmts
+
+	if cfg.GenesisSupply != nil {
+		if err := SeedGenesis(cfg.GenesisAddress, cfg.GenesisSupply.String()); err != nil {
+			CloseDB()
+			return fmt.Errorf("failed to seed genesis wallet: %w", err)
+		}
 	}
 
 	log.Println("Successfully connected to database")
 	return nil
 }
 
+// maxConnectBackoff caps the doubling pause between reconnection attempts.
+const maxConnectBackoff = 30 * time.Second
+
+// ping waits until pool reaches the database, trying again up to
+// cfg.ConnectRetries times with a backoff that starts at cfg.ConnectBackoff.
+func ping(pool *sql.DB, cfg Config) error {
+	delay := cfg.ConnectBackoff
+	for attempt := 1; ; attempt++ {
+		err := pool.Ping()
+		if err == nil {
+			return nil
+		}
+		if attempt > cfg.ConnectRetries {
+			return fmt.Errorf("failed to ping database after %d attempts: %w", attempt, err)
+		}
+
+		log.Printf("Database not reachable (attempt %d of %d), retrying in %s: %v", attempt, cfg.ConnectRetries+1, delay, err)
+		time.Sleep(delay)
+		delay = min(2*delay, maxConnectBackoff)
+	}
+}
+
+// CloseDB closes the connection pools and sets DB and DBRead to nil. Calling
+// it again, or before InitDB, does nothing.
 func CloseDB() error {
-	if DB != nil {
-		return DB.Close()
+	if DB == nil {
+		return nil
 	}
-	return nil
+	closeStatements(prepared)
+	prepared = map[string]*sql.Stmt{}
+	if DBRead != nil && DBRead != DB {
+		if err := DBRead.Close(); err != nil {
+			log.Printf("Failed to close read replica connection: %v", err)
+		}
+	}
+	DBRead = nil
+	err := DB.Close()
+	DB = nil
+	return err
+}
+
+// database returns DB, or ErrNotInitialized when the pool is not open.
+func database() (*sql.DB, error) {
+	if DB == nil {
+		return nil, ErrNotInitialized
+	}
+	return DB, nil
 }
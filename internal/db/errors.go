@@ -0,0 +1,230 @@
+package db
+
+import (
+	"database/sql/driver"
+	"errors"
+	"io"
+	"net"
+	"syscall"
+
+	"github.com/lib/pq"
+)
+
+// AppError is an error that is safe to return to API clients. Code is a
+// stable identifier clients can branch on; Err keeps the underlying cause
+// for logging.
+type AppError struct {
+	Code    string
+	Message string
+	Err     error
+	// Details are extra fields reported next to the code, such as how long
+	// to wait before retrying.
+	Details map[string]interface{}
+}
+
+func (e *AppError) Error() string {
+	return e.Message
+}
+
+func (e *AppError) Unwrap() error {
+	return e.Err
+}
+
+// Is matches any AppError with the same code, so classified errors carrying a
+// cause still compare equal to the sentinels below.
+func (e *AppError) Is(target error) bool {
+	t, ok := target.(*AppError)
+	return ok && t.Code == e.Code
+}
+
+// Retryable reports whether the same request may succeed if it is sent
+// again unchanged, because the error came from contention or from the
+// database being briefly unavailable rather than from the request itself.
+func (e *AppError) Retryable() bool {
+	return retryableCodes[e.Code]
+}
+
+// Extensions exposes the code, whether the error is retryable and the
+// details in the GraphQL error response.
+func (e *AppError) Extensions() map[string]interface{} {
+	ext := map[string]interface{}{"code": e.Code, "retryable": e.Retryable()}
+	for k, v := range e.Details {
+		ext[k] = v
+	}
+	return ext
+}
+
+// WithDetails returns a copy of e reporting the given details.
+func (e *AppError) WithDetails(details map[string]interface{}) *AppError {
+	return &AppError{Code: e.Code, Message: e.Message, Err: e.Err, Details: details}
+}
+
+func (e *AppError) wrap(err error) *AppError {
+	return &AppError{Code: e.Code, Message: e.Message, Err: err}
+}
+
+var (
+	ErrInvalidAmount         = &AppError{Code: "INVALID_AMOUNT", Message: "invalid amount"}
+	ErrAmountTooPrecise      = &AppError{Code: "AMOUNT_TOO_PRECISE", Message: "amount has more fractional digits than the token supports"}
+	ErrAmountNotAllowed      = &AppError{Code: "AMOUNT_NOT_ALLOWED", Message: "amount is not a permitted transfer amount"}
+	ErrAmountTooLarge        = &AppError{Code: "AMOUNT_TOO_LARGE", Message: "amount exceeds the maximum transfer amount"}
+	ErrSenderNotFound        = &AppError{Code: "SENDER_NOT_FOUND", Message: "sender wallet does not exist"}
+	ErrReceiverNotFound      = &AppError{Code: "RECEIVER_NOT_FOUND", Message: "receiver wallet does not exist"}
+	ErrInvalidSenderBalance  = &AppError{Code: "INVALID_SENDER_BALANCE", Message: "invalid sender balance format"}
+	ErrCorruptBalance        = &AppError{Code: "CORRUPT_BALANCE", Message: "stored wallet balance is not a valid amount"}
+	ErrInsufficientBalance   = &AppError{Code: "INSUFFICIENT_BALANCE", Message: "insufficient balance"}
+	ErrNothingToSweep        = &AppError{Code: "NOTHING_TO_SWEEP", Message: "wallet has no spendable balance to sweep"}
+	ErrBalanceOverflow       = &AppError{Code: "BALANCE_OVERFLOW", Message: "receiver balance would exceed the largest balance that can be stored"}
+	ErrTransferNotFound      = &AppError{Code: "TRANSFER_NOT_FOUND", Message: "transfer does not exist"}
+	ErrScheduledNotFound     = &AppError{Code: "SCHEDULED_TRANSFER_NOT_FOUND", Message: "scheduled transfer does not exist"}
+	ErrAlreadyRefunded       = &AppError{Code: "ALREADY_REFUNDED", Message: "transfer has already been refunded"}
+	ErrRefundOfRefund        = &AppError{Code: "REFUND_OF_REFUND", Message: "a refund cannot itself be refunded"}
+	ErrHoldNotFound          = &AppError{Code: "HOLD_NOT_FOUND", Message: "hold does not exist"}
+	ErrHoldSettled           = &AppError{Code: "HOLD_ALREADY_SETTLED", Message: "hold has already been released or cancelled"}
+	ErrReserveViolation      = &AppError{Code: "RESERVE_VIOLATION", Message: "transfer would breach the sender's reserved balance"}
+	ErrDailyLimitExceeded    = &AppError{Code: "DAILY_LIMIT_EXCEEDED", Message: "transfer would exceed the sender's daily limit"}
+	ErrWalletNotFound        = &AppError{Code: "WALLET_NOT_FOUND", Message: "wallet does not exist"}
+	ErrInvalidReserve        = &AppError{Code: "INVALID_RESERVE", Message: "reserved amount must be a non-negative integer"}
+	ErrReserveExceedsBalance = &AppError{Code: "RESERVE_EXCEEDS_BALANCE", Message: "reserved amount exceeds wallet balance"}
+	ErrWalletFrozen          = &AppError{Code: "WALLET_FROZEN", Message: "transfer involves a frozen wallet"}
+	ErrWalletClosed          = &AppError{Code: "WALLET_CLOSED", Message: "transfer involves a closed wallet"}
+	ErrWalletNotEmpty        = &AppError{Code: "WALLET_NOT_EMPTY", Message: "only wallets with a zero balance can be closed"}
+	ErrInvalidWalletStatus   = &AppError{Code: "INVALID_WALLET_STATUS", Message: "wallet status must be active, frozen or closed"}
+	ErrInvalidPagination     = &AppError{Code: "INVALID_PAGINATION", Message: "limit and offset must not be negative"}
+	ErrInvalidOrder          = &AppError{Code: "INVALID_ORDER", Message: "unknown sort order"}
+	ErrInvalidCursor         = &AppError{Code: "INVALID_CURSOR", Message: "invalid pagination cursor"}
+	ErrInvalidInterval       = &AppError{Code: "INVALID_INTERVAL", Message: "unknown volume interval"}
+	ErrInvalidAddress        = &AppError{Code: "INVALID_ADDRESS", Message: "address must be 0x followed by 40 hex digits"}
+	ErrTooManyAddresses      = &AppError{Code: "TOO_MANY_ADDRESSES", Message: "too many addresses requested at once"}
+	ErrMemoTooLong           = &AppError{Code: "MEMO_TOO_LONG", Message: "memo is longer than 256 characters"}
+	ErrBadSignature          = &AppError{Code: "BAD_SIGNATURE", Message: "transfer signature is missing, malformed or not made by the sender"}
+	ErrNonceMismatch         = &AppError{Code: "NONCE_MISMATCH", Message: "expected nonce does not match the sender's nonce"}
+	ErrConditionNotMet       = &AppError{Code: "CONDITION_NOT_MET", Message: "sender balance is below the transfer's required minimum balance"}
+	ErrSelfTransfer          = &AppError{Code: "SELF_TRANSFER", Message: "sender and receiver must be different wallets"}
+	ErrReservedAddress       = &AppError{Code: "RESERVED_ADDRESS", Message: "the zero address cannot send transfers; mint tokens instead"}
+	ErrBlockedAddress        = &AppError{Code: "BLOCKED_ADDRESS", Message: "transfer involves a blocked address"}
+	ErrInvalidSnapshot       = &AppError{Code: "INVALID_SNAPSHOT", Message: "invalid snapshot"}
+	ErrSnapshotNotEmpty      = &AppError{Code: "SNAPSHOT_TARGET_NOT_EMPTY", Message: "snapshots can only be imported into a database without transfers"}
+	ErrSupplyMismatch        = &AppError{Code: "SUPPLY_MISMATCH", Message: "imported total supply does not match the snapshot"}
+	ErrDuplicateImportRow    = &AppError{Code: "DUPLICATE_IMPORT_ROW", Message: "address appears more than once in the import"}
+	ErrImportRejected        = &AppError{Code: "IMPORT_REJECTED", Message: "import has rejected rows and nothing was imported"}
+	ErrInvalidImportPolicy   = &AppError{Code: "INVALID_IMPORT_POLICY", Message: "on_error must be abort or skip"}
+	ErrUnknownToken          = &AppError{Code: "UNKNOWN_TOKEN", Message: "token is not one of the configured tokens"}
+	ErrUnsupportedToken      = &AppError{Code: "UNSUPPORTED_TOKEN", Message: "operation only supports the primary token"}
+	ErrConflict              = &AppError{Code: "CONFLICT", Message: "transfer kept conflicting with concurrent updates, please retry"}
+	ErrNotInitialized        = &AppError{Code: "NOT_INITIALIZED", Message: "database connection is not initialized"}
+	ErrRateLimited           = &AppError{Code: "RATE_LIMITED", Message: "too many transfers from this wallet, please retry later"}
+	ErrReadOnly              = &AppError{Code: "READ_ONLY", Message: "server is in read-only mode, writes are disabled"}
+	ErrUnauthenticated       = &AppError{Code: "UNAUTHENTICATED", Message: "missing or invalid API key"}
+	ErrUnauthorized          = &AppError{Code: "UNAUTHORIZED", Message: "caller is not allowed to perform this operation"}
+	ErrQueryNotPersisted     = &AppError{Code: "PERSISTED_QUERY_NOT_FOUND", Message: "PersistedQueryNotFound"}
+	ErrQueryHashMismatch     = &AppError{Code: "PERSISTED_QUERY_HASH_MISMATCH", Message: "provided sha256Hash does not match query"}
+	ErrQueryTooDeep          = &AppError{Code: "QUERY_TOO_DEEP", Message: "query is nested too deeply"}
+	ErrQueryTooComplex       = &AppError{Code: "QUERY_TOO_COMPLEX", Message: "query selects too many fields"}
+	ErrIntrospectionDisabled = &AppError{Code: "INTROSPECTION_DISABLED", Message: "introspection is disabled"}
+	ErrRequestTooLarge       = &AppError{Code: "REQUEST_TOO_LARGE", Message: "request body is too large"}
+	ErrMutationOverGET       = &AppError{Code: "MUTATION_OVER_GET", Message: "mutations must be sent with POST"}
+	ErrMalformedRequest      = &AppError{Code: "BAD_REQUEST", Message: "request could not be parsed"}
+	ErrOperationNameRequired = &AppError{Code: "OPERATION_NAME_REQUIRED", Message: "document has several operations, operationName must pick one"}
+	ErrUnknownOperation      = &AppError{Code: "UNKNOWN_OPERATION", Message: "document has no operation with the given operationName"}
+	ErrRequestTimeout        = &AppError{Code: "REQUEST_TIMEOUT", Message: "request took longer than the server allows"}
+
+	ErrInternal             = &AppError{Code: "INTERNAL", Message: "internal server error"}
+	ErrDuplicate            = &AppError{Code: "DUPLICATE", Message: "record already exists"}
+	ErrConstraintViolation  = &AppError{Code: "CONSTRAINT_VIOLATION", Message: "operation violates a ledger constraint"}
+	ErrNumericOverflow      = &AppError{Code: "NUMERIC_OVERFLOW", Message: "numeric value out of range"}
+	ErrSerializationFailure = &AppError{Code: "SERIALIZATION_FAILURE", Message: "transaction conflicted with a concurrent update, please retry"}
+	ErrDeadlock             = &AppError{Code: "DEADLOCK_DETECTED", Message: "transaction deadlocked with a concurrent update, please retry"}
+	ErrLockTimeout          = &AppError{Code: "LOCK_TIMEOUT", Message: "timed out waiting for a lock, please retry"}
+	ErrQueryCanceled        = &AppError{Code: "QUERY_CANCELED", Message: "query was canceled"}
+	ErrRetryable            = &AppError{Code: "RETRYABLE", Message: "database is temporarily unavailable, please retry"}
+	ErrPermanent            = &AppError{Code: "PERMANENT", Message: "database rejected the operation"}
+)
+
+// retryableCodes are the codes of errors a client can resolve by retrying.
+var retryableCodes = map[string]bool{
+	ErrRetryable.Code:            true,
+	ErrSerializationFailure.Code: true,
+	ErrDeadlock.Code:             true,
+	ErrLockTimeout.Code:          true,
+	ErrConflict.Code:             true,
+	ErrRateLimited.Code:          true,
+	ErrNotInitialized.Code:       true,
+	ErrReadOnly.Code:             true,
+}
+
+// sqlStateErrors maps Postgres SQLSTATE codes to the app error reported for
+// them.
+var sqlStateErrors = map[pq.ErrorCode]*AppError{
+	"23505": ErrDuplicate,
+	"23514": ErrConstraintViolation,
+	"22003": ErrNumericOverflow,
+	"40001": ErrSerializationFailure,
+	"40P01": ErrDeadlock,
+	"55P03": ErrLockTimeout,
+	"57014": ErrQueryCanceled,
+}
+
+// retryableSQLStates are SQLSTATEs, other than those in sqlStateErrors,
+// reporting that the server could not run the statement for the time being.
+// Whole classes are keyed by their two-character class code.
+var retryableSQLStates = map[string]bool{
+	"08":    true, // connection_exception
+	"57P01": true, // admin_shutdown
+	"57P02": true, // crash_shutdown
+	"57P03": true, // cannot_connect_now
+	"53300": true, // too_many_connections
+}
+
+// permanentSQLClasses are the SQLSTATE classes of errors caused by the data
+// a statement was given, which fail the same way every time.
+var permanentSQLClasses = map[string]bool{
+	"22": true, // data_exception
+	"23": true, // integrity_constraint_violation
+}
+
+// ClassifyError converts driver errors into coded app errors: those with a
+// known SQLSTATE into their own error, and other ones into ErrRetryable when
+// the connection failed or the server was unavailable, or ErrPermanent when
+// the data was rejected. App errors and unknown errors are returned
+// unchanged.
+func ClassifyError(err error) error {
+	if err == nil {
+		return nil
+	}
+
+	var appErr *AppError
+	if errors.As(err, &appErr) {
+		return err
+	}
+
+	var pqErr *pq.Error
+	if errors.As(err, &pqErr) {
+		if mapped, ok := sqlStateErrors[pqErr.Code]; ok {
+			return mapped.wrap(err)
+		}
+		if retryableSQLStates[string(pqErr.Code)] || retryableSQLStates[string(pqErr.Code.Class())] {
+			return ErrRetryable.wrap(err)
+		}
+		if permanentSQLClasses[string(pqErr.Code.Class())] {
+			return ErrPermanent.wrap(err)
+		}
+		return err
+	}
+
+	if connectionLost(err) {
+		return ErrRetryable.wrap(err)
+	}
+	return err
+}
+
+// connectionLost reports whether err is the driver or the network failing
+// to reach the database or losing the connection to it.
+func connectionLost(err error) bool {
+	if errors.Is(err, driver.ErrBadConn) || errors.Is(err, io.EOF) || errors.Is(err, io.ErrUnexpectedEOF) ||
+		errors.Is(err, syscall.ECONNRESET) || errors.Is(err, syscall.ECONNREFUSED) || errors.Is(err, syscall.EPIPE) {
+		return true
+	}
+	var opErr *net.OpError
+	return errors.As(err, &opErr)
+}
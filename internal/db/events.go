@@ -0,0 +1,139 @@
+package db
+
+import (
+	"context"
+	"database/sql"
+	"time"
+	"token-transfer-api/internal/model"
+)
+
+const eventColumns = "id, transfer_id, payload, attempts, next_attempt_at, last_error, published_at, created_at"
+
+// stmtRecordEvent adds the outbox event of a recorded transfer. The payload
+// is built from the transfer row so it matches what was stored.
+const stmtRecordEvent = `INSERT INTO transfer_events (transfer_id, payload)
+SELECT id, jsonb_build_object(
+	'transfer_id', id,
+	'from_address', from_address,
+	'to_address', to_address,
+	'amount', amount::text,
+	'fee', $2::text,
+	'memo', memo,
+	'refund_of', refund_of,
+	'created_at', created_at
+) FROM transfers WHERE id = $1`
+
+const (
+	eventRetryBaseDelay = time.Second
+	eventRetryMaxDelay  = 5 * time.Minute
+)
+
+// recordEvent writes the outbox event of the transfer with id transferID in
+// tx, so the event exists exactly when the transfer does.
+func recordEvent(tx txn, transferID int64, fee string) error {
+	_, err := tx.Exec(stmtRecordEvent, transferID, fee)
+	return err
+}
+
+// PublishNextEvent claims the oldest unpublished transfer event that is due
+// and hands it to deliver. A delivered event is marked published; a failed
+// one is retried after a backoff that doubles with each attempt, up to five
+// minutes. The row stays locked while deliver runs and concurrent callers
+// skip it, so several relays can run at once without delivering an event
+// twice.
+//
+// It returns nil when no event is due. The error of a failed delivery is
+// recorded on the event, not returned.
+func PublishNextEvent(ctx context.Context, deliver func(context.Context, *model.TransferEvent) error) (_ *model.TransferEvent, err error) {
+	defer func() { err = ClassifyError(err) }()
+
+	tx, err := BeginTx(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	event, err := scanEvent(tx.QueryRow("SELECT " + eventColumns + " FROM transfer_events " +
+		"WHERE published_at IS NULL AND next_attempt_at <= NOW() ORDER BY next_attempt_at, id LIMIT 1 FOR UPDATE SKIP LOCKED"))
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, nil
+		}
+		return nil, err
+	}
+
+	if deliverErr := deliver(ctx, event); deliverErr != nil {
+		event, err = scanEvent(tx.QueryRow("UPDATE transfer_events SET attempts = attempts + 1, last_error = $1, next_attempt_at = NOW() + $2 * INTERVAL '1 millisecond' WHERE id = $3 RETURNING "+eventColumns,
+			deliverErr.Error(), eventRetryDelay(event.Attempts).Milliseconds(), event.ID))
+	} else {
+		event, err = scanEvent(tx.QueryRow("UPDATE transfer_events SET attempts = attempts + 1, last_error = NULL, published_at = NOW() WHERE id = $1 RETURNING "+eventColumns,
+			event.ID))
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	if err = tx.Commit(); err != nil {
+		return nil, err
+	}
+	return event, nil
+}
+
+// GetTransferEvents returns the outbox events of the transfer with id
+// transferID, oldest first.
+func GetTransferEvents(transferID int64) ([]*model.TransferEvent, error) {
+	return GetTransferEventsContext(context.Background(), transferID)
+}
+
+func GetTransferEventsContext(ctx context.Context, transferID int64) (_ []*model.TransferEvent, err error) {
+	defer func() { err = ClassifyError(err) }()
+
+	q, err := conn(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	rows, err := q.Query("SELECT "+eventColumns+" FROM transfer_events WHERE transfer_id = $1 ORDER BY id", transferID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var events []*model.TransferEvent
+	for rows.Next() {
+		event, err := scanEvent(rows)
+		if err != nil {
+			return nil, err
+		}
+		events = append(events, event)
+	}
+	return events, rows.Err()
+}
+
+// eventRetryDelay returns the pause before delivery attempt attempts+2.
+func eventRetryDelay(attempts int) time.Duration {
+	if attempts >= 16 {
+		return eventRetryMaxDelay
+	}
+	return min(eventRetryBaseDelay<<attempts, eventRetryMaxDelay)
+}
+
+// scanEvent reads a row selected with eventColumns.
+func scanEvent(row rowScanner) (*model.TransferEvent, error) {
+	var e model.TransferEvent
+	var payload []byte
+	var lastError sql.NullString
+	var publishedAt sql.NullTime
+	err := row.Scan(&e.ID, &e.TransferID, &payload, &e.Attempts, &e.NextAttemptAt, &lastError, &publishedAt, &e.CreatedAt)
+	if err != nil {
+		return nil, err
+	}
+	e.Payload = payload
+	if lastError.Valid {
+		e.LastError = &lastError.String
+	}
+	if publishedAt.Valid {
+		e.PublishedAt = &publishedAt.Time
+	}
+	return &e, nil
+}
@@ -0,0 +1,291 @@
+package db
+
+import (
+	"context"
+	"database/sql"
+	"math/big"
+	"token-transfer-api/internal/model"
+)
+
+// Statuses of an escrow hold.
+const (
+	HoldHeld      = "HELD"
+	HoldReleased  = "RELEASED"
+	HoldCancelled = "CANCELLED"
+)
+
+// holdColumns are the columns read by scanHold, in order.
+const holdColumns = "id, from_address, amount, status, to_address, transfer_id, settled_at, created_at"
+
+// scanHold reads a row selected with holdColumns.
+func scanHold(row rowScanner) (*model.Hold, error) {
+	var h model.Hold
+	var toAddress sql.NullString
+	var transferID sql.NullInt64
+	var settledAt sql.NullTime
+	err := row.Scan(&h.ID, &h.FromAddress, &h.Amount, &h.Status, &toAddress, &transferID, &settledAt, &h.CreatedAt)
+	if err != nil {
+		return nil, err
+	}
+	if toAddress.Valid {
+		h.ToAddress = &toAddress.String
+	}
+	if transferID.Valid {
+		h.TransferID = &transferID.Int64
+	}
+	if settledAt.Valid {
+		h.SettledAt = &settledAt.Time
+	}
+	return &h, nil
+}
+
+// GetHold returns the hold with the given id.
+func GetHold(holdID int64) (*model.Hold, error) {
+	return GetHoldContext(context.Background(), holdID)
+}
+
+func GetHoldContext(ctx context.Context, holdID int64) (_ *model.Hold, err error) {
+	defer func() { err = ClassifyError(err) }()
+
+	q, err := conn(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	hold, err := scanHold(q.QueryRow("SELECT "+holdColumns+" FROM holds WHERE id = $1", holdID))
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, ErrHoldNotFound
+		}
+		return nil, err
+	}
+	return hold, nil
+}
+
+// CreateHold puts amount of the sender's spendable balance in escrow. The
+// tokens stay in the balance but count against it like the reserve until
+// the hold is released or cancelled, so the sender cannot transfer them in
+// the meantime.
+func CreateHold(fromAddress, amount string) (*model.Hold, error) {
+	return CreateHoldContext(context.Background(), fromAddress, amount)
+}
+
+func CreateHoldContext(ctx context.Context, fromAddress, amount string) (_ *model.Hold, err error) {
+	defer func() { err = ClassifyError(err) }()
+
+	cfg := Settings
+	amountBig, err := parseAmount(amount)
+	if err != nil {
+		return nil, err
+	}
+	fromAddress = cfg.NormalizeAddress(fromAddress)
+	if cfg.ZeroAddressReserved && fromAddress == ZeroAddress {
+		return nil, ErrReservedAddress
+	}
+
+	var hold *model.Hold
+	err = retryConflicts(ctx, cfg.MaxRetries, func() error {
+		tx, err := begin(ctx)
+		if err != nil {
+			return err
+		}
+		defer tx.Rollback()
+
+		if _, err = lockSpendable(tx, fromAddress, amountBig); err != nil {
+			return err
+		}
+		if err = checkBlocked(tx, fromAddress); err != nil {
+			return err
+		}
+		if err = checkWalletStatus(tx, fromAddress); err != nil {
+			return err
+		}
+
+		_, err = tx.Exec("UPDATE wallets SET held = held + $1 WHERE address = $2", amountBig.String(), fromAddress)
+		if err != nil {
+			return err
+		}
+		hold, err = scanHold(tx.QueryRow("INSERT INTO holds (from_address, amount) VALUES ($1, $2) RETURNING "+holdColumns,
+			fromAddress, amountBig.String()))
+		if err != nil {
+			return err
+		}
+		return tx.Commit()
+	})
+	if err != nil {
+		return nil, err
+	}
+	return hold, nil
+}
+
+// ReleaseHold pays an open hold out to the receiver. The amount leaves the
+// sender's balance and held tokens together and is recorded as an ordinary
+// transfer, which the hold links to. Paying a hold out is a transfer of its
+// amount: it must pass the same amount rules, counts against the sender's
+// daily limit and is charged the fee, which is taken from the sender's
+// spendable balance on top of the held tokens. As for a refund, the sender
+// signed nothing, so its nonce stays as it is. A hold is settled only once:
+// releasing or cancelling it again fails with ErrHoldSettled.
+func ReleaseHold(holdID int64, toAddress string) (*model.Hold, error) {
+	return ReleaseHoldContext(context.Background(), holdID, toAddress)
+}
+
+func ReleaseHoldContext(ctx context.Context, holdID int64, toAddress string) (_ *model.Hold, err error) {
+	defer func() { err = ClassifyError(err) }()
+
+	cfg := Settings
+	var hold *model.Hold
+	err = retryConflicts(ctx, cfg.MaxRetries, func() error {
+		var err error
+		hold, err = applyRelease(ctx, cfg, holdID, toAddress)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+	return hold, nil
+}
+
+// applyRelease runs the transaction of a hold's release.
+func applyRelease(ctx context.Context, cfg Config, holdID int64, toAddress string) (*model.Hold, error) {
+	tx, err := begin(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	open, err := lockOpenHold(tx, holdID)
+	if err != nil {
+		return nil, err
+	}
+	fromAddress, toAddress, amountBig, err := checkTransfer(cfg, open.FromAddress, toAddress, open.Amount, "")
+	if err != nil {
+		return nil, err
+	}
+	fee := cfg.Fee(amountBig)
+	total := new(big.Int).Add(amountBig, fee)
+	amount := amountBig.String()
+
+	// The held tokens are freed first, so only the fee has to come out of
+	// what the sender can spend
+	_, err = tx.Exec("UPDATE wallets SET held = held - $1 WHERE address = $2", amount, fromAddress)
+	if err != nil {
+		return nil, err
+	}
+	newSenderBalance, err := withdraw(tx, fromAddress, total)
+	if err != nil {
+		return nil, err
+	}
+	if err = checkDailyLimit(tx, cfg, fromAddress, total); err != nil {
+		return nil, err
+	}
+
+	if err = checkReceiver(tx, cfg, toAddress); err != nil {
+		return nil, err
+	}
+	toAfter, created, err := creditReceiver(tx, cfg, toAddress, amountBig)
+	if err != nil {
+		return nil, err
+	}
+
+	if err = checkBlocked(tx, fromAddress, toAddress); err != nil {
+		return nil, err
+	}
+	// As for a transfer, a receiver the release created is only held to its
+	// status from the next transfer on
+	statusChecked := []string{fromAddress, toAddress}
+	if created {
+		statusChecked = statusChecked[:1]
+	}
+	if err = checkWalletStatus(tx, statusChecked...); err != nil {
+		return nil, err
+	}
+
+	fromAfter := new(big.Int).Add(newSenderBalance, fee).String()
+	var transferID int64
+	err = queryRow(tx, stmtRecordTransfer, fromAddress, toAddress, amount, fromAfter, toAfter, "").Scan(&transferID)
+	if err != nil {
+		return nil, err
+	}
+	if err = recordBalance(tx, cfg, fromAddress, newSenderBalance.String()); err != nil {
+		return nil, err
+	}
+	if err = recordBalance(tx, cfg, toAddress, toAfter); err != nil {
+		return nil, err
+	}
+	if cfg.TransferEvents {
+		if err = recordEvent(tx, transferID, fee.String()); err != nil {
+			return nil, err
+		}
+	}
+	if err = chargeFee(tx, cfg, fromAddress, newSenderBalance, fee); err != nil {
+		return nil, err
+	}
+
+	hold, err := scanHold(tx.QueryRow("UPDATE holds SET status = $1, to_address = $2, transfer_id = $3, settled_at = NOW() WHERE id = $4 RETURNING "+holdColumns,
+		HoldReleased, toAddress, transferID, holdID))
+	if err != nil {
+		return nil, err
+	}
+	if err = tx.Commit(); err != nil {
+		return nil, err
+	}
+	return hold, nil
+}
+
+// CancelHold closes an open hold without paying it out, so its tokens become
+// spendable by the sender again.
+func CancelHold(holdID int64) (*model.Hold, error) {
+	return CancelHoldContext(context.Background(), holdID)
+}
+
+func CancelHoldContext(ctx context.Context, holdID int64) (_ *model.Hold, err error) {
+	defer func() { err = ClassifyError(err) }()
+
+	var hold *model.Hold
+	err = retryConflicts(ctx, Settings.MaxRetries, func() error {
+		tx, err := begin(ctx)
+		if err != nil {
+			return err
+		}
+		defer tx.Rollback()
+
+		open, err := lockOpenHold(tx, holdID)
+		if err != nil {
+			return err
+		}
+
+		_, err = tx.Exec("UPDATE wallets SET held = held - $1 WHERE address = $2", open.Amount, open.FromAddress)
+		if err != nil {
+			return err
+		}
+
+		hold, err = scanHold(tx.QueryRow("UPDATE holds SET status = $1, settled_at = NOW() WHERE id = $2 RETURNING "+holdColumns,
+			HoldCancelled, holdID))
+		if err != nil {
+			return err
+		}
+		return tx.Commit()
+	})
+	if err != nil {
+		return nil, err
+	}
+	return hold, nil
+}
+
+// lockOpenHold locks the hold with the given id and checks it has not been
+// settled yet. Concurrent settlements of one hold queue on this lock, and
+// all but the first find it settled.
+func lockOpenHold(tx txn, holdID int64) (*model.Hold, error) {
+	hold, err := scanHold(tx.QueryRow("SELECT "+holdColumns+" FROM holds WHERE id = $1 FOR UPDATE", holdID))
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, ErrHoldNotFound
+		}
+		return nil, err
+	}
+	if hold.Status != HoldHeld {
+		return nil, ErrHoldSettled
+	}
+	return hold, nil
+}
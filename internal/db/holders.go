@@ -0,0 +1,69 @@
+package db
+
+import (
+	"context"
+	"token-transfer-api/internal/model"
+)
+
+const (
+	// DefaultHolderLimit is used when GetTopHolders is called without a limit.
+	DefaultHolderLimit = 10
+	// MaxHolderLimit caps the number of holders returned by GetTopHolders.
+	MaxHolderLimit = 100
+)
+
+// GetTopHolders returns the wallets with the largest balances, largest
+// first, and each one's share of the total supply. With excludeZeroAddress
+// ZeroAddress, which holds the genesis supply by default, is left out of
+// both the list and the supply the shares are taken of.
+func GetTopHolders(limit int, excludeZeroAddress bool) ([]model.Holder, error) {
+	return GetTopHoldersContext(context.Background(), limit, excludeZeroAddress)
+}
+
+func GetTopHoldersContext(ctx context.Context, limit int, excludeZeroAddress bool) (_ []model.Holder, err error) {
+	defer func() { err = ClassifyError(err) }()
+
+	if limit < 0 {
+		return nil, ErrInvalidPagination
+	}
+	if limit == 0 {
+		limit = DefaultHolderLimit
+	}
+	if limit > MaxHolderLimit {
+		limit = MaxHolderLimit
+	}
+
+	excluded := ""
+	if excludeZeroAddress {
+		excluded = ZeroAddress
+	}
+
+	q, err := readConn(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	// The window sum is taken before LIMIT applies, so it is the supply of
+	// every wallet listed or not.
+	rows, err := q.Query(`
+		SELECT address, balance::text,
+			COALESCE(floor(balance * 10000 / NULLIF(SUM(balance) OVER (), 0)), 0)::bigint
+		FROM wallets
+		WHERE address <> $1
+		ORDER BY balance DESC, address ASC
+		LIMIT $2`, excluded, limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	holders := []model.Holder{}
+	for rows.Next() {
+		var holder model.Holder
+		if err := rows.Scan(&holder.Address, &holder.Balance, &holder.ShareBps); err != nil {
+			return nil, err
+		}
+		holders = append(holders, holder)
+	}
+	return holders, rows.Err()
+}
@@ -0,0 +1,260 @@
+package db
+
+import (
+	"context"
+	"fmt"
+	"math/big"
+	"strings"
+	"token-transfer-api/internal/amount"
+	"token-transfer-api/internal/model"
+
+	"github.com/lib/pq"
+)
+
+// Statuses of the rows of a bulk wallet import.
+const (
+	ImportInserted = "INSERTED"
+	ImportUpdated  = "UPDATED"
+	ImportRejected = "REJECTED"
+	// ImportAborted marks valid rows that were not imported because another
+	// row was rejected and the import stops on errors.
+	ImportAborted = "ABORTED"
+)
+
+// importBatchSize is the number of rows written per multi-row INSERT.
+const importBatchSize = 500
+
+// errZeroAddressImport rejects rows for ZeroAddress, whose transfers stand
+// for mints and burns rather than a balance of its own.
+var errZeroAddressImport = &AppError{Code: ErrReservedAddress.Code, Message: "the zero address cannot be imported"}
+
+// importRow is a valid row of an import and what writing it changes.
+type importRow struct {
+	index   int
+	address string
+	balance *big.Int
+	// delta is the new balance minus the old one, recorded as a mint when
+	// positive and a burn when negative.
+	delta *big.Int
+}
+
+// ImportWallets sets the balances of the wallets in rows, creating the
+// wallets that do not exist yet, in one transaction. Rows with a malformed
+// address or balance, a repeated address, or a balance below what the
+// wallet has reserved or held are rejected. With skipInvalid the other rows
+// are imported anyway; otherwise nothing is and ErrImportRejected is
+// returned with the per-row results in its "results" detail.
+//
+// Balances are in base units and may be zero. Each change is recorded as a
+// mint or burn so CheckLedgerIntegrity still balances. Like Mint, it leaves
+// authorization to the caller.
+func ImportWallets(rows []model.WalletImportRow, skipInvalid bool) ([]model.WalletImportResult, error) {
+	return ImportWalletsContext(context.Background(), rows, skipInvalid)
+}
+
+func ImportWalletsContext(ctx context.Context, rows []model.WalletImportRow, skipInvalid bool) (_ []model.WalletImportResult, err error) {
+	defer func() { err = ClassifyError(err) }()
+
+	cfg := Settings
+
+	results := make([]model.WalletImportResult, len(rows))
+	var valid []importRow
+	seen := make(map[string]bool)
+	for i, row := range rows {
+		address := cfg.NormalizeAddress(row.Address)
+		results[i] = model.WalletImportResult{Row: i + 1, Address: address}
+
+		balance, rowErr := checkImportRow(address, row.Balance)
+		if rowErr == nil && seen[address] {
+			rowErr = ErrDuplicateImportRow
+		}
+		if rowErr != nil {
+			reject(&results[i], rowErr)
+			continue
+		}
+		seen[address] = true
+		valid = append(valid, importRow{index: i, address: address, balance: balance})
+	}
+
+	if len(valid) < len(rows) && !skipInvalid {
+		return nil, abortImport(results)
+	}
+	if len(valid) == 0 {
+		return results, nil
+	}
+
+	var imported []model.WalletImportResult
+	err = retryConflicts(ctx, cfg.MaxRetries, func() error {
+		attempt := append([]model.WalletImportResult(nil), results...)
+		if err := importWallets(ctx, cfg, valid, attempt, skipInvalid); err != nil {
+			return err
+		}
+		imported = attempt
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return imported, nil
+}
+
+// checkImportRow validates a row's address and balance and returns the
+// balance.
+func checkImportRow(address, balance string) (*big.Int, *AppError) {
+	if !ValidAddress(address) {
+		return nil, ErrInvalidAddress
+	}
+	if address == ZeroAddress {
+		return nil, errZeroAddressImport
+	}
+	if balance == "0" {
+		return new(big.Int), nil
+	}
+	v, parseErr := amount.ParseBase(balance)
+	if parseErr != nil {
+		return nil, ErrInvalidAmount
+	}
+	if len(balance) > len(maxBalance) {
+		return nil, ErrBalanceOverflow
+	}
+	return v, nil
+}
+
+func reject(result *model.WalletImportResult, err *AppError) {
+	result.Status = ImportRejected
+	result.Code = err.Code
+	result.Message = err.Message
+}
+
+// abortImport marks the rows that were not rejected as aborted and returns
+// the error reporting all results.
+func abortImport(results []model.WalletImportResult) error {
+	for i := range results {
+		if results[i].Status != ImportRejected {
+			results[i].Status = ImportAborted
+		}
+	}
+	return ErrImportRejected.WithDetails(map[string]interface{}{"results": results})
+}
+
+// importWallets writes rows in one transaction and fills in their results.
+// The existing wallets are locked first, in address order, so their
+// reserved and held amounts cannot change before they are overwritten.
+func importWallets(ctx context.Context, cfg Config, rows []importRow, results []model.WalletImportResult, skipInvalid bool) error {
+	tx, err := begin(ctx)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	addresses := make([]string, len(rows))
+	for i, row := range rows {
+		addresses[i] = row.address
+	}
+
+	type lockedWallet struct{ balance, locked *big.Int }
+	existing := make(map[string]lockedWallet)
+	dbRows, err := tx.Query("SELECT address, balance::text, (reserved + held)::text FROM wallets WHERE address = ANY($1) ORDER BY address FOR UPDATE",
+		pq.Array(addresses))
+	if err != nil {
+		return err
+	}
+	defer dbRows.Close()
+	for dbRows.Next() {
+		var address, balance, locked string
+		if err := dbRows.Scan(&address, &balance, &locked); err != nil {
+			return err
+		}
+		b, err := parseBalance(address, balance)
+		if err != nil {
+			return err
+		}
+		l, err := parseBalance(address, locked)
+		if err != nil {
+			return err
+		}
+		existing[address] = lockedWallet{balance: b, locked: l}
+	}
+	if err := dbRows.Err(); err != nil {
+		return err
+	}
+
+	var writes []importRow
+	for _, row := range rows {
+		wallet, ok := existing[row.address]
+		if !ok {
+			results[row.index].Status = ImportInserted
+			row.delta = row.balance
+			writes = append(writes, row)
+			continue
+		}
+		if row.balance.Cmp(wallet.locked) < 0 {
+			reject(&results[row.index], ErrReserveExceedsBalance)
+			continue
+		}
+		results[row.index].Status = ImportUpdated
+		row.delta = new(big.Int).Sub(row.balance, wallet.balance)
+		writes = append(writes, row)
+	}
+
+	if len(writes) < len(rows) && !skipInvalid {
+		return abortImport(results)
+	}
+
+	for start := 0; start < len(writes); start += importBatchSize {
+		batch := writes[start:min(start+importBatchSize, len(writes))]
+		if err := upsertWallets(tx, batch); err != nil {
+			return err
+		}
+		if err := recordImportTransfers(tx, batch); err != nil {
+			return err
+		}
+	}
+	for _, row := range writes {
+		if err := recordBalance(tx, cfg, row.address, row.balance.String()); err != nil {
+			return err
+		}
+	}
+
+	return tx.Commit()
+}
+
+// upsertWallets sets the balances of rows with one multi-row INSERT.
+func upsertWallets(tx txn, rows []importRow) error {
+	values := make([]string, len(rows))
+	args := make([]interface{}, 0, 2*len(rows))
+	for i, row := range rows {
+		values[i] = fmt.Sprintf("($%d, $%d)", 2*i+1, 2*i+2)
+		args = append(args, row.address, row.balance.String())
+	}
+
+	_, err := tx.Exec("INSERT INTO wallets (address, balance) VALUES "+strings.Join(values, ", ")+
+		" ON CONFLICT (address) DO UPDATE SET balance = EXCLUDED.balance", args...)
+	return err
+}
+
+// recordImportTransfers records the balance changes of rows as mints to, or
+// burns from, their wallets. Rows whose balance is unchanged record nothing.
+func recordImportTransfers(tx txn, rows []importRow) error {
+	var values []string
+	var args []interface{}
+	for _, row := range rows {
+		if row.delta.Sign() == 0 {
+			continue
+		}
+		n := len(args)
+		values = append(values, fmt.Sprintf("($%d, $%d, $%d, $%d, $%d)", n+1, n+2, n+3, n+4, n+5))
+		if row.delta.Sign() > 0 {
+			args = append(args, ZeroAddress, row.address, row.delta.String(), nil, row.balance.String())
+		} else {
+			args = append(args, row.address, ZeroAddress, new(big.Int).Neg(row.delta).String(), row.balance.String(), nil)
+		}
+	}
+	if len(values) == 0 {
+		return nil
+	}
+
+	_, err := tx.Exec("INSERT INTO transfers (from_address, to_address, amount, from_balance_after, to_balance_after) VALUES "+
+		strings.Join(values, ", "), args...)
+	return err
+}
@@ -0,0 +1,72 @@
+package db
+
+import (
+	"context"
+	"math/big"
+	"time"
+)
+
+// DailyLimitWindow is the rolling window Config.DailyLimit applies to.
+const DailyLimitWindow = 24 * time.Hour
+
+// GetSentInWindow returns the total value, in base units, the wallet has sent
+// since the given time. Fees count as sent value.
+func GetSentInWindow(address string, since time.Time) (string, error) {
+	return GetSentInWindowContext(context.Background(), address, since)
+}
+
+func GetSentInWindowContext(ctx context.Context, address string, since time.Time) (_ string, err error) {
+	defer func() { err = ClassifyError(err) }()
+
+	q, err := conn(ctx)
+	if err != nil {
+		return "", err
+	}
+
+	sent, err := sentSince(q, Settings.NormalizeAddress(address), since)
+	if err != nil {
+		return "", err
+	}
+	return sent.String(), nil
+}
+
+// sentSince sums the amounts of the transfers from address created since the
+// given time. created_at has no time zone, so it is compared in the session's
+// zone, the one it was written in.
+func sentSince(q querier, address string, since time.Time) (*big.Int, error) {
+	var sent string
+	err := queryRow(q, stmtSentSince, address, since).Scan(&sent)
+	if err != nil {
+		return nil, err
+	}
+
+	sentBig, ok := new(big.Int).SetString(sent, 10)
+	if !ok {
+		return nil, ErrInvalidAmount
+	}
+	return sentBig, nil
+}
+
+// checkDailyLimit rejects sending total from address when it would take the
+// value sent within DailyLimitWindow over cfg.DailyLimit. It must run after
+// the sender's row is locked, so concurrent transfers from the same wallet
+// see each other's transfers.
+func checkDailyLimit(tx txn, cfg Config, address string, total *big.Int) error {
+	if cfg.DailyLimit == nil {
+		return nil
+	}
+
+	sent, err := sentSince(tx, address, time.Now().Add(-DailyLimitWindow))
+	if err != nil {
+		return err
+	}
+
+	if new(big.Int).Add(sent, total).Cmp(cfg.DailyLimit) > 0 {
+		remaining := new(big.Int).Sub(cfg.DailyLimit, sent)
+		if remaining.Sign() < 0 {
+			remaining.SetInt64(0)
+		}
+		return ErrDailyLimitExceeded.WithDetails(map[string]interface{}{"remaining": remaining.String()})
+	}
+	return nil
+}
@@ -1,6 +1,11 @@
+-- Wallets and the transfers between them. Balances are DECIMAL(78, 0) so any
+-- uint256 amount fits. Statements use IF NOT EXISTS so databases created
+-- before migrations existed are adopted as they are.
 CREATE TABLE IF NOT EXISTS wallets (
     address VARCHAR(42) PRIMARY KEY,
     balance DECIMAL(78, 0) NOT NULL DEFAULT 0 CHECK (balance >= 0),
+    reserved DECIMAL(78, 0) NOT NULL DEFAULT 0 CHECK (reserved >= 0),
+    last_activity_at TIMESTAMP WITH TIME ZONE,
     created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
     updated_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP
 );
@@ -10,16 +15,16 @@ CREATE TABLE IF NOT EXISTS transfers (
     from_address VARCHAR(42) NOT NULL,
     to_address VARCHAR(42) NOT NULL,
     amount DECIMAL(78, 0) NOT NULL CHECK (amount > 0),
+    refund_of INTEGER UNIQUE,
+    from_balance_after DECIMAL(78, 0),
+    to_balance_after DECIMAL(78, 0),
+    memo TEXT,
     created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
     FOREIGN KEY (from_address) REFERENCES wallets(address),
-    FOREIGN KEY (to_address) REFERENCES wallets(address)
+    FOREIGN KEY (to_address) REFERENCES wallets(address),
+    FOREIGN KEY (refund_of) REFERENCES transfers(id)
 );
 
--- Insert initial wallet with 1,000,000 BTP tokens
-INSERT INTO wallets (address, balance) 
-VALUES ('0x0000000000000000000000000000000000000000', 1000000)
-ON CONFLICT (address) DO NOTHING;
-
 CREATE OR REPLACE FUNCTION update_updated_at_column()
 RETURNS TRIGGER AS $$
 BEGIN
@@ -28,5 +33,6 @@ BEGIN
 END;
 $$ language 'plpgsql';
 
+DROP TRIGGER IF EXISTS update_wallets_updated_at ON wallets;
 CREATE TRIGGER update_wallets_updated_at BEFORE UPDATE
-    ON wallets FOR EACH ROW EXECUTE FUNCTION update_updated_at_column();
\ No newline at end of file
+    ON wallets FOR EACH ROW EXECUTE FUNCTION update_updated_at_column();
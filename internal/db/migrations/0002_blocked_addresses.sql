@@ -0,0 +1,5 @@
+-- Compliance blocklist checked by every transfer
+CREATE TABLE IF NOT EXISTS blocked_addresses (
+    address VARCHAR(42) PRIMARY KEY,
+    created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
@@ -0,0 +1,6 @@
+-- Lookups by sender or receiver, such as wallet stats, neighbors and the
+-- daily limit, use these instead of scanning every transfer. The id column
+-- keeps an address's transfers in the keyset pagination order.
+CREATE INDEX IF NOT EXISTS idx_transfers_from_address ON transfers (from_address, id);
+CREATE INDEX IF NOT EXISTS idx_transfers_to_address ON transfers (to_address, id);
+CREATE INDEX IF NOT EXISTS idx_transfers_created_at ON transfers (created_at);
@@ -0,0 +1,16 @@
+-- Transfers to run once execute_at has passed. The times carry a time zone
+-- because they are set by clients and compared with NOW().
+CREATE TABLE IF NOT EXISTS scheduled_transfers (
+    id SERIAL PRIMARY KEY,
+    from_address VARCHAR(42) NOT NULL,
+    to_address VARCHAR(42) NOT NULL,
+    amount DECIMAL(78, 0) NOT NULL CHECK (amount > 0),
+    execute_at TIMESTAMPTZ NOT NULL,
+    status VARCHAR(16) NOT NULL DEFAULT 'PENDING' CHECK (status IN ('PENDING', 'EXECUTED', 'FAILED')),
+    failure_code VARCHAR(64),
+    failure_message TEXT,
+    executed_at TIMESTAMPTZ,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+
+CREATE INDEX IF NOT EXISTS idx_scheduled_transfers_due ON scheduled_transfers (execute_at) WHERE status = 'PENDING';
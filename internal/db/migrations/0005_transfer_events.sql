@@ -0,0 +1,17 @@
+-- Outbox of transfer events, written in the transaction of the transfer and
+-- delivered by the relay. Unpublished events are found through the partial
+-- index. transfer_id has no foreign key so published events never hold up
+-- archiving or truncating transfers.
+CREATE TABLE IF NOT EXISTS transfer_events (
+    id BIGSERIAL PRIMARY KEY,
+    transfer_id INTEGER NOT NULL,
+    payload JSONB NOT NULL,
+    attempts INTEGER NOT NULL DEFAULT 0,
+    next_attempt_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    last_error TEXT,
+    published_at TIMESTAMPTZ,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+
+CREATE INDEX IF NOT EXISTS idx_transfer_events_unpublished
+    ON transfer_events (next_attempt_at, id) WHERE published_at IS NULL;
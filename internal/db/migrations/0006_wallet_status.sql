@@ -0,0 +1,4 @@
+-- Lifecycle status of a wallet. Frozen and closed wallets can neither send
+-- nor receive; only empty wallets are closed.
+ALTER TABLE wallets ADD COLUMN IF NOT EXISTS status VARCHAR(10) NOT NULL DEFAULT 'active'
+    CHECK (status IN ('active', 'frozen', 'closed'));
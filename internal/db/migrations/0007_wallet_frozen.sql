@@ -0,0 +1,3 @@
+-- Risk hold on a wallet, independent of its status. A frozen wallet can
+-- neither send nor receive until it is unfrozen.
+ALTER TABLE wallets ADD COLUMN IF NOT EXISTS frozen BOOLEAN NOT NULL DEFAULT false;
@@ -0,0 +1,3 @@
+-- Links the second leg of a swap to the first, so the two transfers that
+-- exchanged tokens between a pair of wallets can be found from either one.
+ALTER TABLE transfers ADD COLUMN IF NOT EXISTS swap_of INTEGER UNIQUE REFERENCES transfers(id);
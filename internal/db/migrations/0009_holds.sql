@@ -0,0 +1,19 @@
+-- Escrow holds. Held tokens stay in the sender's balance but, like the
+-- reserve, cannot be transferred until the hold is released to a receiver
+-- or cancelled; wallets.held is the sum of the wallet's open holds. Like
+-- transfer_events, holds have no foreign keys so they never hold up
+-- truncating transfers.
+ALTER TABLE wallets ADD COLUMN IF NOT EXISTS held DECIMAL(78, 0) NOT NULL DEFAULT 0 CHECK (held >= 0);
+
+CREATE TABLE IF NOT EXISTS holds (
+    id SERIAL PRIMARY KEY,
+    from_address VARCHAR(42) NOT NULL,
+    amount DECIMAL(78, 0) NOT NULL CHECK (amount > 0),
+    status VARCHAR(16) NOT NULL DEFAULT 'HELD' CHECK (status IN ('HELD', 'RELEASED', 'CANCELLED')),
+    to_address VARCHAR(42),
+    transfer_id INTEGER,
+    settled_at TIMESTAMPTZ,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+
+CREATE INDEX IF NOT EXISTS idx_holds_from_address ON holds (from_address);
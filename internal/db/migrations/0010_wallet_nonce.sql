@@ -0,0 +1,4 @@
+-- Number of transfers a wallet has sent. It only ever grows, so a client can
+-- pin a transfer to the nonce it expects and a replayed or reordered
+-- transfer no longer matches.
+ALTER TABLE wallets ADD COLUMN IF NOT EXISTS nonce BIGINT NOT NULL DEFAULT 0;
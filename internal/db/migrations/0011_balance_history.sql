@@ -0,0 +1,14 @@
+-- Balances of wallets after the changes made to them, written in the same
+-- transaction while BALANCE_HISTORY is on. With BALANCE_HISTORY_INTERVAL a
+-- wallet has at most one row per interval, stamped with its start and
+-- holding the balance after the interval's last change. Like
+-- transfer_events, it has no foreign keys so it never holds up removing
+-- wallets or truncating transfers.
+CREATE TABLE IF NOT EXISTS balance_history (
+    id BIGSERIAL PRIMARY KEY,
+    address VARCHAR(42) NOT NULL,
+    balance DECIMAL(78, 0) NOT NULL,
+    recorded_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+
+CREATE INDEX IF NOT EXISTS idx_balance_history_address ON balance_history (address, recorded_at);
@@ -0,0 +1,13 @@
+-- Balances of the tokens other than the primary one, whose balances stay in
+-- wallets.balance. A wallet holds a row per token it has ever been credited;
+-- reserves and holds only apply to the primary token. Transfers of another
+-- token name it in transfers.token, which is NULL for the primary token so
+-- the rows written before tokens existed keep their meaning.
+CREATE TABLE IF NOT EXISTS token_balances (
+    address VARCHAR(42) NOT NULL REFERENCES wallets(address) ON DELETE CASCADE,
+    token VARCHAR(16) NOT NULL,
+    balance DECIMAL(78, 0) NOT NULL DEFAULT 0 CHECK (balance >= 0),
+    PRIMARY KEY (address, token)
+);
+
+ALTER TABLE transfers ADD COLUMN IF NOT EXISTS token VARCHAR(16);
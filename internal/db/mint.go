@@ -0,0 +1,167 @@
+package db
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"token-transfer-api/internal/model"
+)
+
+// ZeroAddress is the source recorded for minted tokens.
+const ZeroAddress = "0x0000000000000000000000000000000000000000"
+
+// Mint creates amount new tokens in the wallet at toAddress, creating the
+// wallet if needed, and records the mint as a transfer from ZeroAddress.
+// Callers are responsible for checking that the requester may mint.
+func Mint(toAddress, amount string) (*model.Wallet, error) {
+	return MintContext(context.Background(), toAddress, amount)
+}
+
+func MintContext(ctx context.Context, toAddress, amount string) (_ *model.Wallet, err error) {
+	defer func() { err = ClassifyError(err) }()
+
+	amountBig, err := parseAmount(amount)
+	if err != nil {
+		return nil, err
+	}
+
+	toAddress = Settings.NormalizeAddress(toAddress)
+
+	tx, err := begin(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	toAfter, err := credit(tx, toAddress, amountBig.String())
+	if err != nil {
+		return nil, err
+	}
+
+	if err = checkWalletStatus(tx, toAddress); err != nil {
+		return nil, err
+	}
+
+	_, err = tx.Exec("INSERT INTO transfers (from_address, to_address, amount, to_balance_after) VALUES ($1, $2, $3, $4)",
+		ZeroAddress, toAddress, amountBig.String(), toAfter)
+	if err != nil {
+		return nil, err
+	}
+	if err = recordBalance(tx, Settings, toAddress, toAfter); err != nil {
+		return nil, err
+	}
+
+	wallet, err := scanWallet(tx.QueryRow("SELECT "+walletColumns+" FROM wallets WHERE address = $1", toAddress))
+	if err != nil {
+		return nil, err
+	}
+
+	if err = tx.Commit(); err != nil {
+		return nil, err
+	}
+
+	return wallet, nil
+}
+
+// Burn destroys amount tokens from the wallet at fromAddress and records the
+// burn as a transfer to ZeroAddress, returning the new balance. Like Mint, it
+// leaves authorization to the caller. The wallet's nonce does not change, so
+// transfers its owner already signed stay valid.
+func Burn(fromAddress, amount string) (string, error) {
+	return BurnContext(context.Background(), fromAddress, amount)
+}
+
+func BurnContext(ctx context.Context, fromAddress, amount string) (_ string, err error) {
+	defer func() { err = ClassifyError(err) }()
+
+	amountBig, err := parseAmount(amount)
+	if err != nil {
+		return "", err
+	}
+
+	fromAddress = Settings.NormalizeAddress(fromAddress)
+
+	tx, err := begin(ctx)
+	if err != nil {
+		return "", err
+	}
+	defer tx.Rollback()
+
+	// The owner signed nothing, so its nonce is left alone
+	newBalance, err := withdraw(tx, fromAddress, amountBig)
+	if err != nil {
+		if errors.Is(err, ErrSenderNotFound) {
+			return "", ErrWalletNotFound
+		}
+		return "", err
+	}
+
+	if err = checkWalletStatus(tx, fromAddress); err != nil {
+		return "", err
+	}
+
+	_, err = tx.Exec("INSERT INTO transfers (from_address, to_address, amount, from_balance_after) VALUES ($1, $2, $3, $4)",
+		fromAddress, ZeroAddress, amountBig.String(), newBalance.String())
+	if err != nil {
+		return "", err
+	}
+	if err = recordBalance(tx, Settings, fromAddress, newBalance.String()); err != nil {
+		return "", err
+	}
+
+	if err = tx.Commit(); err != nil {
+		return "", err
+	}
+
+	return newBalance.String(), nil
+}
+
+// SeedGenesis creates the treasury wallet at address holding balance tokens
+// and records the allocation as a mint, so CheckLedgerIntegrity counts it as
+// supply. It does nothing when the wallet already exists, which makes it safe
+// to run on every start; InitDB calls it with GENESIS_ADDRESS and
+// GENESIS_SUPPLY.
+func SeedGenesis(address, balance string) error {
+	return SeedGenesisContext(context.Background(), address, balance)
+}
+
+func SeedGenesisContext(ctx context.Context, address, balance string) (err error) {
+	defer func() { err = ClassifyError(err) }()
+
+	if !ValidAddress(address) {
+		return ErrInvalidAddress
+	}
+	amountBig, err := parseAmount(balance)
+	if err != nil {
+		return err
+	}
+
+	address = Settings.NormalizeAddress(address)
+
+	tx, err := begin(ctx)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	var created string
+	err = tx.QueryRow("INSERT INTO wallets (address, balance) VALUES ($1, $2) ON CONFLICT (address) DO NOTHING RETURNING balance",
+		address, amountBig.String()).Scan(&created)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+
+	_, err = tx.Exec("INSERT INTO transfers (from_address, to_address, amount, to_balance_after) VALUES ($1, $2, $3, $4)",
+		ZeroAddress, address, amountBig.String(), created)
+	if err != nil {
+		return err
+	}
+	if err = recordBalance(tx, Settings, address, created); err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
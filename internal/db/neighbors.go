@@ -0,0 +1,67 @@
+package db
+
+import (
+	"context"
+	"token-transfer-api/internal/model"
+)
+
+const (
+	// DefaultNeighborLimit is used when GetNeighbors is called without a limit.
+	DefaultNeighborLimit = 20
+	// MaxNeighborLimit caps the number of neighbors returned by GetNeighbors.
+	MaxNeighborLimit = 100
+)
+
+// GetNeighbors returns the distinct counterparties of address with the total
+// amount of the primary token sent and received between them, largest total
+// first.
+func GetNeighbors(address string, limit int) ([]model.Neighbor, error) {
+	return GetNeighborsContext(context.Background(), address, limit)
+}
+
+func GetNeighborsContext(ctx context.Context, address string, limit int) (_ []model.Neighbor, err error) {
+	defer func() { err = ClassifyError(err) }()
+
+	if limit < 0 {
+		return nil, ErrInvalidPagination
+	}
+	if limit == 0 {
+		limit = DefaultNeighborLimit
+	}
+	if limit > MaxNeighborLimit {
+		limit = MaxNeighborLimit
+	}
+
+	address = Settings.NormalizeAddress(address)
+
+	q, err := readConn(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	rows, err := q.Query(`
+		SELECT counterparty, SUM(amount)::text
+		FROM (
+			SELECT to_address AS counterparty, amount FROM transfers WHERE from_address = $1 AND token IS NULL
+			UNION ALL
+			SELECT from_address AS counterparty, amount FROM transfers WHERE to_address = $1 AND token IS NULL
+		) exchanged
+		WHERE counterparty <> $1
+		GROUP BY counterparty
+		ORDER BY SUM(amount) DESC, counterparty ASC
+		LIMIT $2`, address, limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	neighbors := []model.Neighbor{}
+	for rows.Next() {
+		var neighbor model.Neighbor
+		if err := rows.Scan(&neighbor.Address, &neighbor.Total); err != nil {
+			return nil, err
+		}
+		neighbors = append(neighbors, neighbor)
+	}
+	return neighbors, rows.Err()
+}
@@ -0,0 +1,70 @@
+package db
+
+import (
+	"database/sql"
+	"math/big"
+)
+
+// stmtCreditReceiver is stmtCredit for a receiver that a new wallet is
+// initialized for: a wallet it inserts starts with $3, the amount plus the
+// grant, and status $4, while an existing wallet is only credited $2. The
+// last column tells whether the row was inserted.
+const stmtCreditReceiver = "INSERT INTO wallets (address, balance, status, last_activity_at) VALUES ($1, $3, $4, NOW()) ON CONFLICT (address) DO UPDATE SET balance = wallets.balance + $2::DECIMAL, last_activity_at = NOW() WHERE wallets.balance + $2::DECIMAL <= " + maxBalance + " RETURNING balance, xmax = 0"
+
+// initializesNewWallets reports whether receiver wallets transfers create
+// start with more than the amount or with another status than active.
+func (c Config) initializesNewWallets() bool {
+	return c.NewWalletGrant != nil || c.NewWalletStatus != ""
+}
+
+// creditReceiver credits a transfer's receiver like credit does. While
+// NEW_WALLET_GRANT or NEW_WALLET_DEFAULT_STATUS is set, a wallet it creates
+// also gets the grant and the status in the same statement, so a concurrent
+// transfer to the same address either creates the wallet itself or credits
+// the initialized one. It reports whether it created the wallet; the grant
+// is then recorded as a mint to it, whose balance after is the grant alone.
+func creditReceiver(q querier, cfg Config, address string, amount *big.Int) (string, bool, error) {
+	if !cfg.initializesNewWallets() {
+		balance, err := credit(q, address, amount.String())
+		return balance, false, err
+	}
+
+	grant := cfg.NewWalletGrant
+	if grant == nil {
+		grant = new(big.Int)
+	}
+	status := cfg.NewWalletStatus
+	if status == "" {
+		status = WalletActive
+	}
+
+	initial := new(big.Int).Add(amount, grant).String()
+	if len(initial) > len(maxBalance) {
+		return "", false, ErrBalanceOverflow
+	}
+
+	var balance string
+	var created bool
+	err := q.QueryRow(stmtCreditReceiver, address, amount.String(), initial, status).Scan(&balance, &created)
+	if err == sql.ErrNoRows {
+		if err := checkStoredBalance(q, address); err != nil {
+			return "", false, err
+		}
+		return "", false, ErrBalanceOverflow
+	}
+	if err != nil {
+		return "", false, err
+	}
+	if _, err := parseBalance(address, balance); err != nil {
+		return "", false, err
+	}
+
+	if created && grant.Sign() > 0 {
+		_, err = q.Exec("INSERT INTO transfers (from_address, to_address, amount, to_balance_after) VALUES ($1, $2, $3, $4)",
+			ZeroAddress, address, grant.String(), grant.String())
+		if err != nil {
+			return "", false, err
+		}
+	}
+	return balance, created, nil
+}
@@ -0,0 +1,71 @@
+package db
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"sync/atomic"
+	"time"
+)
+
+// lastWrite is when this process last committed a write, in Unix
+// nanoseconds.
+var lastWrite atomic.Int64
+
+// noteWrite records that a write was just committed on DB.
+func noteWrite() {
+	lastWrite.Store(time.Now().UnixNano())
+}
+
+// recentWrite reports whether a write was committed within
+// Settings.ReadAfterWrite, so the replica may not show it yet.
+func recentWrite() bool {
+	return time.Since(time.Unix(0, lastWrite.Load())) < Settings.ReadAfterWrite
+}
+
+// readConn returns the connection for reads that the replica may serve:
+// DBRead, unless ctx carries a transaction or a write was committed too
+// recently for the replica to have caught up, in which case the primary is
+// read like conn does.
+func readConn(ctx context.Context) (querier, error) {
+	if txFromContext(ctx) != nil || DBRead == nil || DBRead == DB || recentWrite() {
+		return conn(ctx)
+	}
+	return DBRead, nil
+}
+
+// primaryTx is a transaction begun on DB that notes the write when it
+// commits.
+type primaryTx struct {
+	*sql.Tx
+}
+
+func (t primaryTx) Commit() error {
+	if err := t.Tx.Commit(); err != nil {
+		return err
+	}
+	noteWrite()
+	return nil
+}
+
+// openReadPool opens the pool to the replica named by DATABASE_READ_URL and
+// waits for it like the primary. It returns nil when no replica is set.
+func openReadPool(cfg Config) (*sql.DB, error) {
+	params, ok, err := ReadConnParamsFromEnv()
+	if err != nil || !ok {
+		return nil, err
+	}
+	if err := params.CheckTLS(); err != nil {
+		return nil, fmt.Errorf("read replica: %w", err)
+	}
+
+	pool, err := openPool(params.DSN(), cfg)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open read replica connection: %w", err)
+	}
+	if err := ping(pool, cfg); err != nil {
+		pool.Close()
+		return nil, fmt.Errorf("read replica: %w", err)
+	}
+	return pool, nil
+}
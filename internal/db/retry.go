@@ -0,0 +1,67 @@
+package db
+
+import (
+	"context"
+	"errors"
+	"math/rand/v2"
+	"sync/atomic"
+	"time"
+)
+
+// DefaultMaxRetries is how often a conflicting transfer is retried when
+// TRANSFER_MAX_RETRIES is not set.
+const DefaultMaxRetries = 10
+
+const (
+	retryBaseDelay = 10 * time.Millisecond
+	retryMaxDelay  = 500 * time.Millisecond
+)
+
+// retries counts the conflicts retried by retryConflicts.
+var retries atomic.Int64
+
+// ConflictRetries returns how many times a transfer has been retried after a
+// serialization failure or deadlock since the process started.
+func ConflictRetries() int64 {
+	return retries.Load()
+}
+
+// retryConflicts runs fn until it succeeds, fails with an error other than a
+// serialization failure or deadlock, or has been retried maxRetries times.
+// Attempts are spaced by a jittered exponential backoff. A conflict that is
+// still failing at the end is reported as ErrConflict.
+//
+// Work inside a caller-supplied transaction is never retried: the conflict
+// aborted the caller's transaction, so only the caller can start over.
+func retryConflicts(ctx context.Context, maxRetries int, fn func() error) error {
+	if txFromContext(ctx) != nil {
+		maxRetries = 0
+	}
+
+	for attempt := 0; ; attempt++ {
+		err := ClassifyError(fn())
+		if !errors.Is(err, ErrSerializationFailure) && !errors.Is(err, ErrDeadlock) {
+			return err
+		}
+		if attempt >= maxRetries {
+			return ErrConflict.wrap(err)
+		}
+
+		retries.Add(1)
+		select {
+		case <-time.After(retryDelay(attempt)):
+		case <-ctx.Done():
+			return ErrConflict.wrap(err)
+		}
+	}
+}
+
+// retryDelay returns the pause before retry number attempt+1: a random
+// duration between half and all of an exponentially growing, capped delay.
+func retryDelay(attempt int) time.Duration {
+	delay := retryMaxDelay
+	if attempt < 16 {
+		delay = min(retryBaseDelay<<attempt, retryMaxDelay)
+	}
+	return delay/2 + rand.N(delay/2+1)
+}
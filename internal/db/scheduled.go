@@ -0,0 +1,155 @@
+package db
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"time"
+	"token-transfer-api/internal/model"
+)
+
+// Statuses of a scheduled transfer.
+const (
+	ScheduledPending  = "PENDING"
+	ScheduledExecuted = "EXECUTED"
+	ScheduledFailed   = "FAILED"
+)
+
+// scheduledColumns are the columns read by scanScheduled, in order.
+const scheduledColumns = "id, from_address, to_address, amount, execute_at, status, failure_code, failure_message, executed_at, created_at"
+
+// scanScheduled reads a row selected with scheduledColumns.
+func scanScheduled(row rowScanner) (*model.ScheduledTransfer, error) {
+	var s model.ScheduledTransfer
+	var failureCode, failureMessage sql.NullString
+	var executedAt sql.NullTime
+	err := row.Scan(&s.ID, &s.FromAddress, &s.ToAddress, &s.Amount, &s.ExecuteAt, &s.Status,
+		&failureCode, &failureMessage, &executedAt, &s.CreatedAt)
+	if err != nil {
+		return nil, err
+	}
+	if failureCode.Valid {
+		s.FailureCode = &failureCode.String
+	}
+	if failureMessage.Valid {
+		s.FailureMessage = &failureMessage.String
+	}
+	if executedAt.Valid {
+		s.ExecutedAt = &executedAt.Time
+	}
+	return &s, nil
+}
+
+// ScheduleTransfer records a transfer for the scheduler to execute once
+// executeAt has passed. The amount and addresses are checked now; balances,
+// reserves and the blocklist only when the transfer runs.
+func ScheduleTransfer(fromAddress, toAddress, amount string, executeAt time.Time) (*model.ScheduledTransfer, error) {
+	return ScheduleTransferContext(context.Background(), fromAddress, toAddress, amount, executeAt)
+}
+
+func ScheduleTransferContext(ctx context.Context, fromAddress, toAddress, amount string, executeAt time.Time) (_ *model.ScheduledTransfer, err error) {
+	defer func() { err = ClassifyError(err) }()
+
+	amountBig, err := parseAmount(amount)
+	if err != nil {
+		return nil, err
+	}
+
+	fromAddress = Settings.NormalizeAddress(fromAddress)
+	toAddress = Settings.NormalizeAddress(toAddress)
+	if fromAddress == toAddress {
+		return nil, ErrSelfTransfer
+	}
+
+	q, err := conn(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	return scanScheduled(q.QueryRow("INSERT INTO scheduled_transfers (from_address, to_address, amount, execute_at) VALUES ($1, $2, $3, $4) RETURNING "+scheduledColumns,
+		fromAddress, toAddress, amountBig.String(), executeAt))
+}
+
+// GetScheduledTransfer returns the scheduled transfer with the given id, or
+// ErrScheduledNotFound.
+func GetScheduledTransfer(id int64) (*model.ScheduledTransfer, error) {
+	return GetScheduledTransferContext(context.Background(), id)
+}
+
+func GetScheduledTransferContext(ctx context.Context, id int64) (_ *model.ScheduledTransfer, err error) {
+	defer func() { err = ClassifyError(err) }()
+
+	q, err := conn(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	scheduled, err := scanScheduled(q.QueryRow("SELECT "+scheduledColumns+" FROM scheduled_transfers WHERE id = $1", id))
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, ErrScheduledNotFound
+		}
+		return nil, err
+	}
+	return scheduled, nil
+}
+
+// ExecuteDueTransfer claims the earliest pending scheduled transfer whose time
+// has come, executes it with TransferTokensContext and records the outcome,
+// all in one transaction of its own. A transfer is therefore never executed
+// without being marked, or marked without being executed. Rows claimed by a
+// concurrent caller are skipped, so several schedulers can run at once and
+// each transfer is still executed only once.
+//
+// It returns nil when nothing is due. Transfers rejected by the ledger rules
+// are marked FAILED with the error. Conflicts and other transient errors are
+// returned and leave the transfer pending for the next attempt.
+func ExecuteDueTransfer(ctx context.Context) (_ *model.ScheduledTransfer, err error) {
+	defer func() { err = ClassifyError(err) }()
+
+	tx, err := BeginTx(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	scheduled, err := scanScheduled(tx.QueryRow("SELECT " + scheduledColumns + " FROM scheduled_transfers " +
+		"WHERE status = 'PENDING' AND execute_at <= NOW() ORDER BY execute_at, id LIMIT 1 FOR UPDATE SKIP LOCKED"))
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, nil
+		}
+		return nil, err
+	}
+
+	// The transfer runs in a savepoint of tx, so a rejected transfer leaves
+	// tx usable for recording the failure.
+	_, err = TransferTokensContext(WithTx(ctx, tx), scheduled.FromAddress, scheduled.ToAddress, scheduled.Amount)
+
+	var appErr *AppError
+	switch {
+	case err == nil:
+		scheduled, err = scanScheduled(tx.QueryRow("UPDATE scheduled_transfers SET status = $1, executed_at = NOW() WHERE id = $2 RETURNING "+scheduledColumns,
+			ScheduledExecuted, scheduled.ID))
+	case transient(err) || !errors.As(err, &appErr):
+		return nil, err
+	default:
+		scheduled, err = scanScheduled(tx.QueryRow("UPDATE scheduled_transfers SET status = $1, failure_code = $2, failure_message = $3, executed_at = NOW() WHERE id = $4 RETURNING "+scheduledColumns,
+			ScheduledFailed, appErr.Code, appErr.Message, scheduled.ID))
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	if err = tx.Commit(); err != nil {
+		return nil, err
+	}
+	return scheduled, nil
+}
+
+// transient reports whether err may not happen again when the same work is
+// retried later.
+func transient(err error) bool {
+	return errors.Is(err, ErrConflict) || errors.Is(err, ErrSerializationFailure) || errors.Is(err, ErrDeadlock) ||
+		errors.Is(err, ErrLockTimeout) || errors.Is(err, ErrQueryCanceled) || errors.Is(err, ErrNotInitialized)
+}
@@ -0,0 +1,152 @@
+package db
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"log"
+	"strings"
+	"time"
+
+	"github.com/lib/pq"
+)
+
+// openPool opens a connection pool to dsn. With cfg.SlowQuery set, every
+// statement run on it, prepared or not and inside transactions or not, is
+// timed, and those taking longer are logged.
+func openPool(dsn string, cfg Config) (*sql.DB, error) {
+	if cfg.SlowQuery <= 0 {
+		return sql.Open("postgres", dsn)
+	}
+	connector, err := pq.NewConnector(dsn)
+	if err != nil {
+		return nil, err
+	}
+	return sql.OpenDB(slowQueryConnector{Connector: connector, threshold: cfg.SlowQuery}), nil
+}
+
+// logIfSlow logs query when it ran for longer than threshold. Only the SQL
+// text is logged, with its $n placeholders; argument values never are, as
+// they can hold addresses and amounts.
+func logIfSlow(query string, start time.Time, threshold time.Duration) {
+	if elapsed := time.Since(start); elapsed > threshold {
+		log.Printf("Slow query (%s): %s", elapsed.Round(time.Millisecond), strings.Join(strings.Fields(query), " "))
+	}
+}
+
+type slowQueryConnector struct {
+	driver.Connector
+	threshold time.Duration
+}
+
+func (c slowQueryConnector) Connect(ctx context.Context) (driver.Conn, error) {
+	conn, err := c.Connector.Connect(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return &slowQueryConn{Conn: conn, threshold: c.threshold}, nil
+}
+
+// slowQueryConn times the statements run on a connection. Queries are timed
+// until their first row is available, not until the rows are read.
+type slowQueryConn struct {
+	driver.Conn
+	threshold time.Duration
+}
+
+func (c *slowQueryConn) ExecContext(ctx context.Context, query string, args []driver.NamedValue) (driver.Result, error) {
+	execer, ok := c.Conn.(driver.ExecerContext)
+	if !ok {
+		return nil, driver.ErrSkip
+	}
+	defer logIfSlow(query, time.Now(), c.threshold)
+	return execer.ExecContext(ctx, query, args)
+}
+
+func (c *slowQueryConn) QueryContext(ctx context.Context, query string, args []driver.NamedValue) (driver.Rows, error) {
+	queryer, ok := c.Conn.(driver.QueryerContext)
+	if !ok {
+		return nil, driver.ErrSkip
+	}
+	defer logIfSlow(query, time.Now(), c.threshold)
+	return queryer.QueryContext(ctx, query, args)
+}
+
+func (c *slowQueryConn) Prepare(query string) (driver.Stmt, error) {
+	return c.PrepareContext(context.Background(), query)
+}
+
+func (c *slowQueryConn) PrepareContext(ctx context.Context, query string) (driver.Stmt, error) {
+	var stmt driver.Stmt
+	var err error
+	if preparer, ok := c.Conn.(driver.ConnPrepareContext); ok {
+		stmt, err = preparer.PrepareContext(ctx, query)
+	} else {
+		stmt, err = c.Conn.Prepare(query)
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &slowQueryStmt{Stmt: stmt, query: query, threshold: c.threshold}, nil
+}
+
+func (c *slowQueryConn) BeginTx(ctx context.Context, opts driver.TxOptions) (driver.Tx, error) {
+	if beginner, ok := c.Conn.(driver.ConnBeginTx); ok {
+		return beginner.BeginTx(ctx, opts)
+	}
+	return c.Conn.Begin()
+}
+
+func (c *slowQueryConn) Ping(ctx context.Context) error {
+	if pinger, ok := c.Conn.(driver.Pinger); ok {
+		return pinger.Ping(ctx)
+	}
+	return nil
+}
+
+func (c *slowQueryConn) ResetSession(ctx context.Context) error {
+	if resetter, ok := c.Conn.(driver.SessionResetter); ok {
+		return resetter.ResetSession(ctx)
+	}
+	return nil
+}
+
+func (c *slowQueryConn) IsValid() bool {
+	if validator, ok := c.Conn.(driver.Validator); ok {
+		return validator.IsValid()
+	}
+	return true
+}
+
+// slowQueryStmt times the executions of a prepared statement.
+type slowQueryStmt struct {
+	driver.Stmt
+	query     string
+	threshold time.Duration
+}
+
+func (s *slowQueryStmt) ExecContext(ctx context.Context, args []driver.NamedValue) (driver.Result, error) {
+	defer logIfSlow(s.query, time.Now(), s.threshold)
+	if execer, ok := s.Stmt.(driver.StmtExecContext); ok {
+		return execer.ExecContext(ctx, args)
+	}
+	return s.Stmt.Exec(values(args))
+}
+
+func (s *slowQueryStmt) QueryContext(ctx context.Context, args []driver.NamedValue) (driver.Rows, error) {
+	defer logIfSlow(s.query, time.Now(), s.threshold)
+	if queryer, ok := s.Stmt.(driver.StmtQueryContext); ok {
+		return queryer.QueryContext(ctx, args)
+	}
+	return s.Stmt.Query(values(args))
+}
+
+// values drops the names of positional arguments for drivers that only
+// take plain values.
+func values(args []driver.NamedValue) []driver.Value {
+	vals := make([]driver.Value, len(args))
+	for i, arg := range args {
+		vals[i] = arg.Value
+	}
+	return vals
+}
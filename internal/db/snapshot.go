@@ -0,0 +1,304 @@
+package db
+
+import (
+	"bufio"
+	"context"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"io"
+	"math/big"
+	"time"
+)
+
+// SnapshotVersion is written in the header of every snapshot. ImportSnapshot
+// only accepts snapshots with this version.
+const SnapshotVersion = 1
+
+// snapshotRecord is one line of a snapshot. A snapshot is newline-delimited
+// JSON: a header, every wallet, every balance of a token other than the
+// primary one, every transfer in id order, and a footer with the counts and
+// total supply of the primary token used to validate an import.
+type snapshotRecord struct {
+	Type string `json:"type"`
+
+	// header
+	Version int `json:"version,omitempty"`
+
+	// wallet
+	Address        string     `json:"address,omitempty"`
+	Balance        string     `json:"balance,omitempty"`
+	Reserved       string     `json:"reserved,omitempty"`
+	Status         string     `json:"status,omitempty"`
+	Frozen         bool       `json:"frozen,omitempty"`
+	Nonce          int64      `json:"nonce,omitempty"`
+	LastActivityAt *time.Time `json:"last_activity_at,omitempty"`
+
+	// token balance, with Address and Balance
+	Token string `json:"token,omitempty"`
+
+	// transfer, with Token unless it moved the primary token
+	ID          int64   `json:"id,omitempty"`
+	FromAddress string  `json:"from_address,omitempty"`
+	ToAddress   string  `json:"to_address,omitempty"`
+	Amount      string  `json:"amount,omitempty"`
+	RefundOf    *int64  `json:"refund_of,omitempty"`
+	SwapOf      *int64  `json:"swap_of,omitempty"`
+	FromAfter   *string `json:"from_balance_after,omitempty"`
+	ToAfter     *string `json:"to_balance_after,omitempty"`
+	Memo        *string `json:"memo,omitempty"`
+
+	// wallet and transfer
+	CreatedAt *time.Time `json:"created_at,omitempty"`
+
+	// footer
+	Wallets       int64  `json:"wallets,omitempty"`
+	TokenBalances int64  `json:"token_balances,omitempty"`
+	Transfers     int64  `json:"transfers,omitempty"`
+	Supply        string `json:"supply,omitempty"`
+}
+
+// ExportSnapshot writes every wallet and transfer to w as a versioned NDJSON
+// stream. Rows are streamed, so memory use does not grow with the ledger, and
+// are read in one repeatable-read transaction so the snapshot is consistent.
+func ExportSnapshot(w io.Writer) error {
+	return ExportSnapshotContext(context.Background(), w)
+}
+
+func ExportSnapshotContext(ctx context.Context, w io.Writer) (err error) {
+	defer func() { err = ClassifyError(err) }()
+
+	var q querier
+	if tx := txFromContext(ctx); tx != nil {
+		q = tx
+	} else {
+		pool, err := database()
+		if err != nil {
+			return err
+		}
+		tx, err := pool.BeginTx(ctx, &sql.TxOptions{Isolation: sql.LevelRepeatableRead, ReadOnly: true})
+		if err != nil {
+			return err
+		}
+		defer tx.Rollback()
+		q = tx
+	}
+
+	buf := bufio.NewWriter(w)
+	enc := json.NewEncoder(buf)
+
+	if err := enc.Encode(snapshotRecord{Type: "header", Version: SnapshotVersion}); err != nil {
+		return err
+	}
+
+	footer := snapshotRecord{Type: "footer"}
+	supply := new(big.Int)
+
+	rows, err := q.Query("SELECT address, balance, reserved, status, frozen, nonce, last_activity_at, created_at FROM wallets ORDER BY address")
+	if err != nil {
+		return err
+	}
+	for rows.Next() {
+		rec := snapshotRecord{Type: "wallet"}
+		var lastActivity sql.NullTime
+		var createdAt time.Time
+		if err := rows.Scan(&rec.Address, &rec.Balance, &rec.Reserved, &rec.Status, &rec.Frozen, &rec.Nonce, &lastActivity, &createdAt); err != nil {
+			rows.Close()
+			return err
+		}
+		// Active is left out, so snapshots of ledgers that never froze or
+		// closed a wallet read as before
+		if rec.Status == WalletActive {
+			rec.Status = ""
+		}
+		if lastActivity.Valid {
+			rec.LastActivityAt = &lastActivity.Time
+		}
+		rec.CreatedAt = &createdAt
+
+		balance, ok := new(big.Int).SetString(rec.Balance, 10)
+		if !ok {
+			rows.Close()
+			return ErrInvalidSenderBalance
+		}
+		supply.Add(supply, balance)
+		footer.Wallets++
+
+		if err := enc.Encode(rec); err != nil {
+			rows.Close()
+			return err
+		}
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return err
+	}
+
+	rows, err = q.Query("SELECT address, token, balance::text FROM token_balances ORDER BY address, token")
+	if err != nil {
+		return err
+	}
+	for rows.Next() {
+		rec := snapshotRecord{Type: "token_balance"}
+		if err := rows.Scan(&rec.Address, &rec.Token, &rec.Balance); err != nil {
+			rows.Close()
+			return err
+		}
+		footer.TokenBalances++
+
+		if err := enc.Encode(rec); err != nil {
+			rows.Close()
+			return err
+		}
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return err
+	}
+
+	rows, err = q.Query("SELECT " + transferColumns + " FROM transfers ORDER BY id")
+	if err != nil {
+		return err
+	}
+	for rows.Next() {
+		t, err := scanTransfer(rows)
+		if err != nil {
+			rows.Close()
+			return err
+		}
+		rec := snapshotRecord{
+			Type:        "transfer",
+			ID:          t.ID,
+			FromAddress: t.FromAddress,
+			ToAddress:   t.ToAddress,
+			Amount:      t.Amount,
+			RefundOf:    t.RefundOf,
+			SwapOf:      t.SwapOf,
+			FromAfter:   t.FromBalanceAfter,
+			ToAfter:     t.ToBalanceAfter,
+			Memo:        t.Memo,
+			Token:       t.Token,
+			CreatedAt:   &t.CreatedAt,
+		}
+		footer.Transfers++
+
+		if err := enc.Encode(rec); err != nil {
+			rows.Close()
+			return err
+		}
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return err
+	}
+
+	footer.Supply = supply.String()
+	if err := enc.Encode(footer); err != nil {
+		return err
+	}
+	return buf.Flush()
+}
+
+// ImportSnapshot loads a snapshot written by ExportSnapshot in a single
+// transaction. The target must not have any transfers yet; wallets already
+// present, such as the genesis wallet, are overwritten. The import is
+// rolled back unless the counts and the total supply match the footer.
+func ImportSnapshot(r io.Reader) error {
+	return ImportSnapshotContext(context.Background(), r)
+}
+
+func ImportSnapshotContext(ctx context.Context, r io.Reader) (err error) {
+	defer func() { err = ClassifyError(err) }()
+
+	tx, err := begin(ctx)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	var hasTransfers bool
+	if err := tx.QueryRow("SELECT EXISTS(SELECT 1 FROM transfers)").Scan(&hasTransfers); err != nil {
+		return err
+	}
+	if hasTransfers {
+		return ErrSnapshotNotEmpty
+	}
+
+	dec := json.NewDecoder(bufio.NewReader(r))
+
+	var header snapshotRecord
+	if err := dec.Decode(&header); err != nil || header.Type != "header" {
+		return fmt.Errorf("%w: missing header", ErrInvalidSnapshot)
+	}
+	if header.Version != SnapshotVersion {
+		return fmt.Errorf("%w: unsupported version %d", ErrInvalidSnapshot, header.Version)
+	}
+
+	var wallets, tokenBalances, transfers int64
+	for {
+		var rec snapshotRecord
+		if err := dec.Decode(&rec); err != nil {
+			if err == io.EOF {
+				return fmt.Errorf("%w: missing footer", ErrInvalidSnapshot)
+			}
+			return fmt.Errorf("%w: %v", ErrInvalidSnapshot, err)
+		}
+
+		switch rec.Type {
+		case "wallet":
+			status := rec.Status
+			if status == "" {
+				status = WalletActive
+			}
+			_, err = tx.Exec(`INSERT INTO wallets (address, balance, reserved, status, frozen, nonce, last_activity_at, created_at) VALUES ($1, $2, $3, $4, $5, $6, $7, $8)
+				ON CONFLICT (address) DO UPDATE SET balance = $2, reserved = $3, status = $4, frozen = $5, nonce = $6, last_activity_at = $7, created_at = $8`,
+				rec.Address, rec.Balance, rec.Reserved, status, rec.Frozen, rec.Nonce, rec.LastActivityAt, rec.CreatedAt)
+			if err != nil {
+				return err
+			}
+			wallets++
+
+		case "token_balance":
+			_, err = tx.Exec(`INSERT INTO token_balances (address, token, balance) VALUES ($1, $2, $3)
+				ON CONFLICT (address, token) DO UPDATE SET balance = $3`,
+				rec.Address, rec.Token, rec.Balance)
+			if err != nil {
+				return err
+			}
+			tokenBalances++
+
+		case "transfer":
+			_, err = tx.Exec("INSERT INTO transfers (id, from_address, to_address, amount, refund_of, swap_of, from_balance_after, to_balance_after, memo, token, created_at) VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, NULLIF($10, ''), $11)",
+				rec.ID, rec.FromAddress, rec.ToAddress, rec.Amount, rec.RefundOf, rec.SwapOf, rec.FromAfter, rec.ToAfter, rec.Memo, rec.Token, rec.CreatedAt)
+			if err != nil {
+				return err
+			}
+			transfers++
+
+		case "footer":
+			if rec.Wallets != wallets || rec.TokenBalances != tokenBalances || rec.Transfers != transfers {
+				return fmt.Errorf("%w: expected %d wallets, %d token balances and %d transfers, read %d, %d and %d",
+					ErrInvalidSnapshot, rec.Wallets, rec.TokenBalances, rec.Transfers, wallets, tokenBalances, transfers)
+			}
+
+			// Later inserts must not reuse imported ids.
+			_, err = tx.Exec("SELECT setval(pg_get_serial_sequence('transfers', 'id'), COALESCE(MAX(id), 0) + 1, false) FROM transfers")
+			if err != nil {
+				return err
+			}
+
+			var supply string
+			if err := tx.QueryRow("SELECT COALESCE(SUM(balance), 0)::text FROM wallets").Scan(&supply); err != nil {
+				return err
+			}
+			if supply != rec.Supply {
+				return fmt.Errorf("%w: expected %s, found %s", ErrSupplyMismatch, rec.Supply, supply)
+			}
+
+			return tx.Commit()
+
+		default:
+			return fmt.Errorf("%w: unknown record type %q", ErrInvalidSnapshot, rec.Type)
+		}
+	}
+}
@@ -0,0 +1,106 @@
+package db
+
+import (
+	"database/sql"
+	"errors"
+	"token-transfer-api/internal/tracing"
+)
+
+// The statements every transfer runs. InitDB prepares them once so the
+// server does not parse and plan them again on each call.
+const (
+	stmtLockSender     = "SELECT balance, reserved + held FROM wallets WHERE address = $1 FOR UPDATE"
+	stmtDebit          = "UPDATE wallets SET balance = $1, nonce = nonce + 1, last_activity_at = NOW() WHERE address = $2"
+	stmtCredit         = "INSERT INTO wallets (address, balance, last_activity_at) VALUES ($1, $2, NOW()) ON CONFLICT (address) DO UPDATE SET balance = wallets.balance + EXCLUDED.balance, last_activity_at = NOW() WHERE wallets.balance + EXCLUDED.balance <= " + maxBalance + " RETURNING balance"
+	stmtAddressBlocked = "SELECT EXISTS(SELECT 1 FROM blocked_addresses WHERE address = $1)"
+	stmtWalletStatus   = "SELECT status, frozen FROM wallets WHERE address = $1"
+	stmtSentSince      = "SELECT COALESCE(SUM(amount), 0)::text FROM transfers WHERE from_address = $1 AND token IS NULL AND created_at >= $2::timestamptz"
+	stmtRecordTransfer = "INSERT INTO transfers (from_address, to_address, amount, from_balance_after, to_balance_after, memo) VALUES ($1, $2, $3, $4, $5, NULLIF($6, '')) RETURNING id"
+)
+
+var hotStatements = []string{
+	stmtLockSender,
+	stmtDebit,
+	stmtCredit,
+	stmtAddressBlocked,
+	stmtWalletStatus,
+	stmtSentSince,
+	stmtRecordTransfer,
+	stmtTransferCTE,
+}
+
+// prepared maps the text of each hot statement to its prepared form on DB.
+// It is empty when Settings.PreparedStatements is off or DB is closed.
+var prepared = map[string]*sql.Stmt{}
+
+// prepareStatements prepares the hot statements on pool.
+func prepareStatements(pool *sql.DB) (map[string]*sql.Stmt, error) {
+	stmts := make(map[string]*sql.Stmt, len(hotStatements))
+	for _, query := range hotStatements {
+		stmt, err := pool.Prepare(query)
+		if err != nil {
+			closeStatements(stmts)
+			return nil, err
+		}
+		stmts[query] = stmt
+	}
+	return stmts, nil
+}
+
+func closeStatements(stmts map[string]*sql.Stmt) error {
+	var errs []error
+	for _, stmt := range stmts {
+		errs = append(errs, stmt.Close())
+	}
+	return errors.Join(errs...)
+}
+
+// statement returns the prepared form of query for use on q, or nil when
+// query was not prepared. Inside a transaction the statement is bound to it,
+// which reuses the preparation on the transaction's connection. Statements
+// are prepared on DB only, so none is returned for the read replica.
+func statement(q querier, query string) *sql.Stmt {
+	stmt := prepared[query]
+	if stmt == nil {
+		return nil
+	}
+	switch q := q.(type) {
+	case *sql.DB:
+		if q != DB {
+			return nil
+		}
+		return stmt
+	case *sql.Tx:
+		return q.Stmt(stmt)
+	case primaryTx:
+		return q.Tx.Stmt(stmt)
+	case *savepoint:
+		return q.Tx.Stmt(stmt)
+	case tracedTx:
+		return statement(q.txn, query)
+	}
+	return nil
+}
+
+// queryRow is q.QueryRow using the prepared statement when there is one.
+func queryRow(q querier, query string, args ...interface{}) *sql.Row {
+	if stmt := statement(q, query); stmt != nil {
+		if t, ok := q.(tracedTx); ok {
+			defer t.startStatement(query).End()
+		}
+		return stmt.QueryRow(args...)
+	}
+	return q.QueryRow(query, args...)
+}
+
+// exec is q.Exec using the prepared statement when there is one.
+func exec(q querier, query string, args ...interface{}) (_ sql.Result, err error) {
+	if stmt := statement(q, query); stmt != nil {
+		if t, ok := q.(tracedTx); ok {
+			span := t.startStatement(query)
+			defer func() { tracing.End(span, err) }()
+		}
+		return stmt.Exec(args...)
+	}
+	return q.Exec(query, args...)
+}
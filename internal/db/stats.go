@@ -0,0 +1,104 @@
+package db
+
+import (
+	"context"
+	"database/sql"
+	"time"
+	"token-transfer-api/internal/model"
+)
+
+// GetWalletStats returns the totals and counts of the transfers of the
+// primary token sent and received by address. An address without transfers
+// gets zeros.
+func GetWalletStats(address string) (*model.WalletStats, error) {
+	return GetWalletStatsContext(context.Background(), address)
+}
+
+func GetWalletStatsContext(ctx context.Context, address string) (_ *model.WalletStats, err error) {
+	defer func() { err = ClassifyError(err) }()
+
+	address = Settings.NormalizeAddress(address)
+
+	q, err := readConn(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	stats := &model.WalletStats{Address: address}
+	err = q.QueryRow(`
+		SELECT
+			COALESCE(SUM(amount) FILTER (WHERE from_address = $1), 0)::text,
+			COALESCE(SUM(amount) FILTER (WHERE to_address = $1), 0)::text,
+			COUNT(*) FILTER (WHERE from_address = $1),
+			COUNT(*) FILTER (WHERE to_address = $1)
+		FROM transfers
+		WHERE (from_address = $1 OR to_address = $1) AND token IS NULL`, address).
+		Scan(&stats.TotalSent, &stats.TotalReceived, &stats.TransferCountOut, &stats.TransferCountIn)
+	if err != nil {
+		return nil, err
+	}
+	return stats, nil
+}
+
+// volumeIntervals maps the supported bucket sizes to their date_trunc fields.
+var volumeIntervals = map[string]string{
+	"HOUR": "hour",
+	"DAY":  "day",
+	"WEEK": "week",
+}
+
+// GetTransferVolume returns the number and total amount of transfers of the
+// primary token per HOUR, DAY or WEEK, oldest bucket first. Buckets are cut
+// in UTC and weeks start on Monday. Only transfers made at or after since
+// and before until count; a nil bound leaves that side open. Buckets without
+// transfers are left out, so a range without any, including one whose since
+// is not before until, gives an empty list.
+func GetTransferVolume(interval string, since, until *time.Time) ([]model.TransferVolume, error) {
+	return GetTransferVolumeContext(context.Background(), interval, since, until)
+}
+
+func GetTransferVolumeContext(ctx context.Context, interval string, since, until *time.Time) (_ []model.TransferVolume, err error) {
+	defer func() { err = ClassifyError(err) }()
+
+	field, ok := volumeIntervals[interval]
+	if !ok {
+		return nil, ErrInvalidInterval
+	}
+
+	var from, to sql.NullTime
+	if since != nil {
+		from = sql.NullTime{Time: *since, Valid: true}
+	}
+	if until != nil {
+		to = sql.NullTime{Time: *until, Valid: true}
+	}
+
+	q, err := readConn(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	rows, err := q.Query(`
+		SELECT date_trunc($1, created_at::timestamptz, 'UTC') AS bucket, SUM(amount)::text, COUNT(*)
+		FROM transfers
+		WHERE token IS NULL
+			AND ($2::timestamptz IS NULL OR created_at >= $2::timestamptz)
+			AND ($3::timestamptz IS NULL OR created_at < $3::timestamptz)
+		GROUP BY bucket
+		ORDER BY bucket`, field, from, to)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	volume := []model.TransferVolume{}
+	for rows.Next() {
+		var v model.TransferVolume
+		if err := rows.Scan(&v.Bucket, &v.TotalAmount, &v.Count); err != nil {
+			return nil, err
+		}
+		v.Bucket = v.Bucket.UTC()
+		volume = append(volume, v)
+	}
+	return volume, rows.Err()
+}
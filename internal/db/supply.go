@@ -0,0 +1,97 @@
+package db
+
+import (
+	"context"
+	"math/big"
+	"token-transfer-api/internal/model"
+)
+
+// GetTotalSupply returns the sum of all wallet balances. The sum is computed
+// and returned as a decimal string because it does not fit in an int64.
+func GetTotalSupply() (string, error) {
+	return GetTotalSupplyContext(context.Background())
+}
+
+func GetTotalSupplyContext(ctx context.Context) (_ string, err error) {
+	defer func() { err = ClassifyError(err) }()
+
+	q, err := conn(ctx)
+	if err != nil {
+		return "", err
+	}
+
+	var supply string
+	err = q.QueryRow("SELECT COALESCE(SUM(balance), 0)::text FROM wallets").Scan(&supply)
+	if err != nil {
+		return "", err
+	}
+	return supply, nil
+}
+
+// GetWalletCount returns the number of wallets.
+func GetWalletCount() (int64, error) {
+	return GetWalletCountContext(context.Background())
+}
+
+func GetWalletCountContext(ctx context.Context) (_ int64, err error) {
+	defer func() { err = ClassifyError(err) }()
+
+	q, err := conn(ctx)
+	if err != nil {
+		return 0, err
+	}
+
+	var count int64
+	err = q.QueryRow("SELECT COUNT(*) FROM wallets").Scan(&count)
+	if err != nil {
+		return 0, err
+	}
+	return count, nil
+}
+
+// CheckLedgerIntegrity compares the sum of all wallet balances with the
+// supply the transfer records account for: everything minted minus
+// everything burned. Mints are transfers from ZeroAddress without a sender
+// balance and burns transfers to ZeroAddress without a receiver balance;
+// ordinary transfers from or to the zero wallet record both balances and
+// cancel out like any other transfer. Only the primary token is checked.
+func CheckLedgerIntegrity() (*model.LedgerIntegrity, error) {
+	return CheckLedgerIntegrityContext(context.Background())
+}
+
+func CheckLedgerIntegrityContext(ctx context.Context) (_ *model.LedgerIntegrity, err error) {
+	defer func() { err = ClassifyError(err) }()
+
+	q, err := conn(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	// One statement sees one snapshot, so the sums agree with each other
+	// even while transfers commit.
+	var walletSum, minted, burned string
+	err = q.QueryRow(`SELECT
+		(SELECT COALESCE(SUM(balance), 0) FROM wallets)::text,
+		(SELECT COALESCE(SUM(amount), 0) FROM transfers WHERE from_address = $1 AND from_balance_after IS NULL AND token IS NULL)::text,
+		(SELECT COALESCE(SUM(amount), 0) FROM transfers WHERE to_address = $1 AND to_balance_after IS NULL AND token IS NULL)::text`,
+		ZeroAddress).Scan(&walletSum, &minted, &burned)
+	if err != nil {
+		return nil, err
+	}
+
+	sums := make([]*big.Int, 3)
+	for i, v := range []string{walletSum, minted, burned} {
+		n, ok := new(big.Int).SetString(v, 10)
+		if !ok {
+			return nil, ErrInvalidAmount
+		}
+		sums[i] = n
+	}
+	expected := new(big.Int).Sub(sums[1], sums[2])
+
+	return &model.LedgerIntegrity{
+		Consistent: sums[0].Cmp(expected) == 0,
+		WalletSum:  sums[0].String(),
+		Expected:   expected.String(),
+	}, nil
+}
@@ -0,0 +1,155 @@
+package db
+
+import (
+	"context"
+	"math/big"
+	"token-transfer-api/internal/model"
+)
+
+// stmtRecordSwapLeg records one leg of a swap; the second leg links back to
+// the first through swap_of.
+const stmtRecordSwapLeg = "INSERT INTO transfers (from_address, to_address, amount, swap_of, from_balance_after, to_balance_after) VALUES ($1, $2, $3, $4, $5, $6) RETURNING id, created_at"
+
+// Swap exchanges tokens between two wallets in one transaction: wallet A
+// sends amountA to wallet B and wallet B sends amountB to wallet A. Either
+// both legs are recorded or, when one side cannot pay, neither is. The two
+// transfers are recorded as if A's leg came first, and B's leg links back to
+// it through swap_of. Swaps are not charged fees.
+func Swap(walletA, walletB, amountA, amountB string) (*model.SwapResult, error) {
+	return SwapContext(context.Background(), walletA, walletB, amountA, amountB)
+}
+
+func SwapContext(ctx context.Context, walletA, walletB, amountA, amountB string) (_ *model.SwapResult, err error) {
+	defer func() { err = ClassifyError(err) }()
+
+	cfg := Settings
+	walletA, walletB, amountABig, err := checkTransfer(cfg, walletA, walletB, amountA, "")
+	if err != nil {
+		return nil, err
+	}
+	_, _, amountBBig, err := checkTransfer(cfg, walletB, walletA, amountB, "")
+	if err != nil {
+		return nil, err
+	}
+
+	var result *model.SwapResult
+	err = retryConflicts(ctx, cfg.MaxRetries, func() error {
+		var err error
+		result, err = applySwap(ctx, cfg, walletA, walletB, amountABig, amountBBig)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+	return result, nil
+}
+
+// applySwap runs the transaction of a validated swap.
+func applySwap(ctx context.Context, cfg Config, walletA, walletB string, amountA, amountB *big.Int) (*model.SwapResult, error) {
+	tx, err := begin(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	// Debit the wallets in address order, so two swaps between the same
+	// pair lock them in the same order whichever side each names first and
+	// cannot deadlock.
+	var debitedA, debitedB *big.Int
+	if walletA < walletB {
+		if debitedA, err = debit(tx, walletA, amountA); err != nil {
+			return nil, err
+		}
+		if debitedB, err = debit(tx, walletB, amountB); err != nil {
+			return nil, err
+		}
+	} else {
+		if debitedB, err = debit(tx, walletB, amountB); err != nil {
+			return nil, err
+		}
+		if debitedA, err = debit(tx, walletA, amountA); err != nil {
+			return nil, err
+		}
+	}
+
+	if err = checkDailyLimit(tx, cfg, walletA, amountA); err != nil {
+		return nil, err
+	}
+	if err = checkDailyLimit(tx, cfg, walletB, amountB); err != nil {
+		return nil, err
+	}
+
+	balanceB, err := credit(tx, walletB, amountA.String())
+	if err != nil {
+		return nil, err
+	}
+	balanceA, err := credit(tx, walletA, amountB.String())
+	if err != nil {
+		return nil, err
+	}
+
+	if err = checkBlocked(tx, walletA, walletB); err != nil {
+		return nil, err
+	}
+	if err = checkWalletStatus(tx, walletA, walletB); err != nil {
+		return nil, err
+	}
+
+	// Between the legs B holds what it had before plus amountA.
+	legAFromAfter := debitedA.String()
+	legAToAfter := new(big.Int).Add(debitedB, amountA)
+	legAToAfter.Add(legAToAfter, amountB)
+	legAToAfterStr := legAToAfter.String()
+	legA := model.Transfer{
+		FromAddress:      walletA,
+		ToAddress:        walletB,
+		Amount:           amountA.String(),
+		FromBalanceAfter: &legAFromAfter,
+		ToBalanceAfter:   &legAToAfterStr,
+	}
+	err = tx.QueryRow(stmtRecordSwapLeg, legA.FromAddress, legA.ToAddress, legA.Amount, nil, legAFromAfter, legAToAfterStr).
+		Scan(&legA.ID, &legA.CreatedAt)
+	if err != nil {
+		return nil, err
+	}
+
+	legB := model.Transfer{
+		FromAddress:      walletB,
+		ToAddress:        walletA,
+		Amount:           amountB.String(),
+		SwapOf:           &legA.ID,
+		FromBalanceAfter: &balanceB,
+		ToBalanceAfter:   &balanceA,
+	}
+	err = tx.QueryRow(stmtRecordSwapLeg, legB.FromAddress, legB.ToAddress, legB.Amount, legA.ID, balanceB, balanceA).
+		Scan(&legB.ID, &legB.CreatedAt)
+	if err != nil {
+		return nil, err
+	}
+
+	if err = recordBalance(tx, cfg, walletA, balanceA); err != nil {
+		return nil, err
+	}
+	if err = recordBalance(tx, cfg, walletB, balanceB); err != nil {
+		return nil, err
+	}
+
+	if cfg.TransferEvents {
+		for _, id := range []int64{legA.ID, legB.ID} {
+			if err = recordEvent(tx, id, "0"); err != nil {
+				return nil, err
+			}
+		}
+	}
+
+	if err = tx.Commit(); err != nil {
+		return nil, err
+	}
+
+	return &model.SwapResult{
+		TransferA: &legA,
+		TransferB: &legB,
+		BalanceA:  balanceA,
+		BalanceB:  balanceB,
+	}, nil
+}
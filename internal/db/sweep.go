@@ -0,0 +1,152 @@
+package db
+
+import (
+	"context"
+	"database/sql"
+	"math/big"
+	"token-transfer-api/internal/model"
+)
+
+// Sweep moves the whole spendable balance of fromAddress to toAddress as one
+// transfer. The balance is read under the sender's row lock, so a concurrent
+// transfer cannot change it between reading and moving it. Tokens reserved
+// or held stay behind; without any the sender is left at zero. A sender with
+// nothing to move fails with ErrNothingToSweep. Like swaps, sweeps are not
+// charged fees, but they count against the sender's daily limit and
+// MAX_TRANSFER_AMOUNT as any transfer does.
+func Sweep(fromAddress, toAddress string) (*model.SweepResult, error) {
+	return SweepContext(context.Background(), fromAddress, toAddress, nil)
+}
+
+// SweepContext is Sweep that, when expectedNonce is set, only goes ahead
+// while the sender's nonce still equals it, as for a transfer.
+func SweepContext(ctx context.Context, fromAddress, toAddress string, expectedNonce *int64) (_ *model.SweepResult, err error) {
+	defer func() { err = ClassifyError(err) }()
+
+	cfg := Settings
+	fromAddress = cfg.NormalizeAddress(fromAddress)
+	toAddress = cfg.NormalizeAddress(toAddress)
+	if fromAddress == toAddress {
+		return nil, ErrSelfTransfer
+	}
+	if cfg.ZeroAddressReserved && fromAddress == ZeroAddress {
+		return nil, ErrReservedAddress
+	}
+
+	var result *model.SweepResult
+	err = retryConflicts(ctx, cfg.MaxRetries, func() error {
+		var err error
+		result, err = applySweep(ctx, cfg, fromAddress, toAddress, expectedNonce)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+	return result, nil
+}
+
+// applySweep runs the transaction of a sweep.
+func applySweep(ctx context.Context, cfg Config, fromAddress, toAddress string, expectedNonce *int64) (*model.SweepResult, error) {
+	tx, err := begin(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	if expectedNonce != nil {
+		if err = checkNonce(tx, fromAddress, *expectedNonce); err != nil {
+			return nil, err
+		}
+	}
+
+	var balance, unspendable string
+	err = queryRow(tx, stmtLockSender, fromAddress).Scan(&balance, &unspendable)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, ErrSenderNotFound
+		}
+		return nil, err
+	}
+	balanceBig, err := parseBalance(fromAddress, balance)
+	if err != nil {
+		return nil, err
+	}
+	unspendableBig, err := parseBalance(fromAddress, unspendable)
+	if err != nil {
+		return nil, err
+	}
+
+	amountBig := new(big.Int).Sub(balanceBig, unspendableBig)
+	if amountBig.Sign() <= 0 {
+		return nil, ErrNothingToSweep
+	}
+	amount := amountBig.String()
+	if _, _, _, err = checkTransfer(cfg, fromAddress, toAddress, amount, ""); err != nil {
+		return nil, err
+	}
+
+	fromBalance, err := debit(tx, fromAddress, amountBig)
+	if err != nil {
+		return nil, err
+	}
+	if err = checkDailyLimit(tx, cfg, fromAddress, amountBig); err != nil {
+		return nil, err
+	}
+
+	if err = checkReceiver(tx, cfg, toAddress); err != nil {
+		return nil, err
+	}
+	toBalance, created, err := creditReceiver(tx, cfg, toAddress, amountBig)
+	if err != nil {
+		return nil, err
+	}
+
+	if err = checkBlocked(tx, fromAddress, toAddress); err != nil {
+		return nil, err
+	}
+	// As for a transfer, a receiver the sweep created is only held to its
+	// status from the next transfer on
+	statusChecked := []string{fromAddress, toAddress}
+	if created {
+		statusChecked = statusChecked[:1]
+	}
+	if err = checkWalletStatus(tx, statusChecked...); err != nil {
+		return nil, err
+	}
+
+	fromAfter := fromBalance.String()
+	sweep := model.Transfer{
+		FromAddress:      fromAddress,
+		ToAddress:        toAddress,
+		Amount:           amount,
+		FromBalanceAfter: &fromAfter,
+		ToBalanceAfter:   &toBalance,
+	}
+	err = tx.QueryRow("INSERT INTO transfers (from_address, to_address, amount, from_balance_after, to_balance_after) VALUES ($1, $2, $3, $4, $5) RETURNING id, created_at",
+		fromAddress, toAddress, amount, fromAfter, toBalance).Scan(&sweep.ID, &sweep.CreatedAt)
+	if err != nil {
+		return nil, err
+	}
+	if err = recordBalance(tx, cfg, fromAddress, fromAfter); err != nil {
+		return nil, err
+	}
+	if err = recordBalance(tx, cfg, toAddress, toBalance); err != nil {
+		return nil, err
+	}
+
+	if cfg.TransferEvents {
+		if err = recordEvent(tx, sweep.ID, "0"); err != nil {
+			return nil, err
+		}
+	}
+
+	if err = tx.Commit(); err != nil {
+		return nil, err
+	}
+
+	return &model.SweepResult{
+		Transfer:    &sweep,
+		FromBalance: fromAfter,
+		ToBalance:   toBalance,
+	}, nil
+}
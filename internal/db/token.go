@@ -0,0 +1,387 @@
+package db
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"math/big"
+	"regexp"
+	"token-transfer-api/internal/model"
+	"token-transfer-api/internal/tracing"
+	"unicode/utf8"
+
+	"go.opentelemetry.io/otel/attribute"
+)
+
+// DefaultPrimaryToken is the symbol of the primary token when PRIMARY_TOKEN
+// is not set.
+const DefaultPrimaryToken = "TOKEN"
+
+// tokenPattern matches token symbols: an uppercase letter followed by up to
+// 15 uppercase letters or digits.
+var tokenPattern = regexp.MustCompile(`^[A-Z][A-Z0-9]{0,15}$`)
+
+// IsPrimaryToken reports whether token names the primary token. The empty
+// string does, so callers that name no token keep working on it.
+func (c Config) IsPrimaryToken(token string) bool {
+	return token == "" || token == c.PrimaryToken
+}
+
+// checkToken fails with ErrUnknownToken unless token is the primary token or
+// one of the configured other tokens.
+func (c Config) checkToken(token string) error {
+	if c.IsPrimaryToken(token) || c.Tokens[token] {
+		return nil
+	}
+	return ErrUnknownToken
+}
+
+// TransferToken moves amount of token from the sender to the receiver and
+// returns the sender's new balance of it. For the primary token it is
+// TransferTokens.
+func TransferToken(token, fromAddress, toAddress, amount string) (string, error) {
+	return TransferTokenContext(context.Background(), token, fromAddress, toAddress, amount)
+}
+
+func TransferTokenContext(ctx context.Context, token, fromAddress, toAddress, amount string) (string, error) {
+	result, err := ExecuteTokenTransfer(ctx, token, fromAddress, toAddress, amount, "", nil, "")
+	if err != nil {
+		return "", err
+	}
+	return result.Balance, nil
+}
+
+// ExecuteTokenTransfer is ExecuteTransfer for any token. Transfers of the
+// primary token go through ExecuteTransfer. Those of another token move its
+// balance in token_balances and are recorded as transfers naming it; they
+// are checked against the blocklist, the wallets' status, the receiver
+// policy, expectedNonce and minBalance like any transfer, but not charged
+// fees or held to the amount rules and limits, which are set in units of
+// the primary token. They write no transfer event and no balance history.
+func ExecuteTokenTransfer(ctx context.Context, token, fromAddress, toAddress, amount, memo string, expectedNonce *int64, minBalance string) (*model.TransferResult, error) {
+	if Settings.IsPrimaryToken(token) {
+		return ExecuteTransfer(ctx, fromAddress, toAddress, amount, memo, expectedNonce, minBalance)
+	}
+	result, err := runTokenTransfer(ctx, token, fromAddress, toAddress, amount, memo, expectedNonce, minBalance, true)
+	if err != nil {
+		return nil, err
+	}
+	result.WouldSucceed = true
+	return result, nil
+}
+
+// SimulateTokenTransfer is SimulateTransfer for any token.
+func SimulateTokenTransfer(ctx context.Context, token, fromAddress, toAddress, amount, memo string, expectedNonce *int64, minBalance string) (*model.TransferResult, error) {
+	if Settings.IsPrimaryToken(token) {
+		return SimulateTransfer(ctx, fromAddress, toAddress, amount, memo, expectedNonce, minBalance)
+	}
+	result, err := runTokenTransfer(ctx, token, fromAddress, toAddress, amount, memo, expectedNonce, minBalance, false)
+	if err != nil {
+		var appErr *AppError
+		if errors.As(err, &appErr) {
+			return &model.TransferResult{
+				FailureCode:    appErr.Code,
+				FailureMessage: appErr.Message,
+			}, nil
+		}
+		return nil, err
+	}
+	result.WouldSucceed = true
+	return result, nil
+}
+
+// runTokenTransfer performs a transfer of a token other than the primary
+// one and commits it, or rolls it back once all checks have passed when
+// commit is false.
+func runTokenTransfer(ctx context.Context, token, fromAddress, toAddress, amount, memo string, expectedNonce *int64, minBalance string, commit bool) (_ *model.TransferResult, err error) {
+	ctx, span := startTransferSpan(ctx, fromAddress, toAddress, amount, commit)
+	span.SetAttributes(attribute.String("transfer.token", token))
+	defer func() { tracing.End(span, err) }()
+	defer func() { err = ClassifyError(err) }()
+
+	cfg := Settings
+	if err = cfg.checkToken(token); err != nil {
+		return nil, err
+	}
+
+	amountBig, err := parseAmount(amount)
+	if err != nil {
+		return nil, err
+	}
+	if utf8.RuneCountInString(memo) > MaxMemoLength {
+		return nil, ErrMemoTooLong
+	}
+
+	fromAddress = cfg.NormalizeAddress(fromAddress)
+	toAddress = cfg.NormalizeAddress(toAddress)
+	if fromAddress == toAddress {
+		return nil, ErrSelfTransfer
+	}
+	if cfg.ZeroAddressReserved && fromAddress == ZeroAddress {
+		return nil, ErrReservedAddress
+	}
+
+	var minBalanceBig *big.Int
+	if minBalance != "" {
+		if minBalanceBig, err = parseAmount(minBalance); err != nil {
+			return nil, err
+		}
+	}
+
+	var result *model.TransferResult
+	err = retryConflicts(ctx, cfg.MaxRetries, func() error {
+		var err error
+		result, err = applyTokenTransfer(ctx, cfg, token, fromAddress, toAddress, memo, amountBig, expectedNonce, minBalanceBig, commit)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+	return result, nil
+}
+
+// applyTokenTransfer runs the transaction of a validated transfer of a token
+// other than the primary one.
+func applyTokenTransfer(ctx context.Context, cfg Config, token, fromAddress, toAddress, memo string, amountBig *big.Int, expectedNonce *int64, minBalance *big.Int, commit bool) (*model.TransferResult, error) {
+	amount := amountBig.String()
+
+	tx, err := begin(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	if expectedNonce != nil {
+		if err = checkNonce(tx, fromAddress, *expectedNonce); err != nil {
+			return nil, err
+		}
+	}
+
+	balance, err := lockTokenBalance(tx, fromAddress, token)
+	if err != nil {
+		return nil, err
+	}
+	if minBalance != nil && balance.Cmp(minBalance) < 0 {
+		return nil, ErrConditionNotMet.WithDetails(map[string]interface{}{"balance": balance.String()})
+	}
+	if balance.Cmp(amountBig) < 0 {
+		return nil, ErrInsufficientBalance
+	}
+
+	fromAfter := new(big.Int).Sub(balance, amountBig).String()
+	_, err = tx.Exec("UPDATE token_balances SET balance = $1 WHERE address = $2 AND token = $3", fromAfter, fromAddress, token)
+	if err != nil {
+		return nil, err
+	}
+	_, err = tx.Exec("UPDATE wallets SET nonce = nonce + 1, last_activity_at = NOW() WHERE address = $1", fromAddress)
+	if err != nil {
+		return nil, err
+	}
+
+	if err = checkReceiver(tx, cfg, toAddress); err != nil {
+		return nil, err
+	}
+	toAfter, created, err := creditToken(tx, cfg, toAddress, token, amount)
+	if err != nil {
+		return nil, err
+	}
+
+	if err = checkBlocked(tx, fromAddress, toAddress); err != nil {
+		return nil, err
+	}
+	// As for the primary token, a receiver this transfer created has
+	// NEW_WALLET_DEFAULT_STATUS from the next transfer on.
+	statusChecked := []string{fromAddress, toAddress}
+	if created {
+		statusChecked = statusChecked[:1]
+	}
+	if err = checkWalletStatus(tx, statusChecked...); err != nil {
+		return nil, err
+	}
+
+	_, err = tx.Exec("INSERT INTO transfers (from_address, to_address, amount, from_balance_after, to_balance_after, memo, token) VALUES ($1, $2, $3, $4, $5, NULLIF($6, ''), $7)",
+		fromAddress, toAddress, amount, fromAfter, toAfter, memo, token)
+	if err != nil {
+		return nil, err
+	}
+
+	result := &model.TransferResult{Balance: fromAfter, Fee: "0"}
+	if !commit {
+		return result, tx.Rollback()
+	}
+
+	if err = tx.Commit(); err != nil {
+		return nil, err
+	}
+	return result, nil
+}
+
+// lockTokenBalance locks the sender's wallet and its balance of token and
+// returns that balance, which is zero when the wallet never held the token.
+// The wallet row is locked first, as for transfers of the primary token, so
+// the two kinds of transfer from one wallet lock in the same order.
+func lockTokenBalance(tx txn, address, token string) (*big.Int, error) {
+	var one int
+	err := tx.QueryRow("SELECT 1 FROM wallets WHERE address = $1 FOR UPDATE", address).Scan(&one)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, ErrSenderNotFound
+		}
+		return nil, err
+	}
+
+	var balance string
+	err = tx.QueryRow("SELECT balance FROM token_balances WHERE address = $1 AND token = $2 FOR UPDATE", address, token).Scan(&balance)
+	if err == sql.ErrNoRows {
+		return new(big.Int), nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return parseBalance(address, balance)
+}
+
+// creditToken adds amount of token to the receiver's balance of it and
+// returns the result. It creates the wallet when it does not exist yet
+// through creditReceiver, crediting it none of the primary token, so cfg
+// decides whether the new wallet gets NEW_WALLET_GRANT and
+// NEW_WALLET_DEFAULT_STATUS, and reports whether it did. Like credit it
+// fails with ErrBalanceOverflow past maxBalance.
+func creditToken(q querier, cfg Config, address, token, amount string) (string, bool, error) {
+	if len(amount) > len(maxBalance) {
+		return "", false, ErrBalanceOverflow
+	}
+
+	_, created, err := creditReceiver(q, cfg, address, new(big.Int))
+	if err != nil {
+		return "", false, err
+	}
+
+	var balance string
+	err = q.QueryRow("INSERT INTO token_balances (address, token, balance) VALUES ($1, $2, $3) "+
+		"ON CONFLICT (address, token) DO UPDATE SET balance = token_balances.balance + EXCLUDED.balance "+
+		"WHERE token_balances.balance + EXCLUDED.balance <= "+maxBalance+" RETURNING balance", address, token, amount).Scan(&balance)
+	if err == sql.ErrNoRows {
+		return "", false, ErrBalanceOverflow
+	}
+	if err != nil {
+		return "", false, err
+	}
+	if _, err := parseBalance(address, balance); err != nil {
+		return "", false, err
+	}
+	return balance, created, nil
+}
+
+// MintToken creates amount new units of token in the wallet at toAddress,
+// creating the wallet if needed, and records the mint as a transfer of the
+// token from ZeroAddress. For the primary token it is Mint. The wallet is
+// returned as GetTokenWallet does. Like Mint, it leaves authorization to the
+// caller.
+func MintToken(token, toAddress, amount string) (*model.Wallet, error) {
+	return MintTokenContext(context.Background(), token, toAddress, amount)
+}
+
+func MintTokenContext(ctx context.Context, token, toAddress, amount string) (_ *model.Wallet, err error) {
+	if Settings.IsPrimaryToken(token) {
+		return MintContext(ctx, toAddress, amount)
+	}
+
+	defer func() { err = ClassifyError(err) }()
+
+	if err = Settings.checkToken(token); err != nil {
+		return nil, err
+	}
+	amountBig, err := parseAmount(amount)
+	if err != nil {
+		return nil, err
+	}
+
+	toAddress = Settings.NormalizeAddress(toAddress)
+
+	tx, err := begin(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	// Wallets that mints create are not initialized, so the zero Config is passed
+	toAfter, _, err := creditToken(tx, Config{}, toAddress, token, amountBig.String())
+	if err != nil {
+		return nil, err
+	}
+
+	if err = checkWalletStatus(tx, toAddress); err != nil {
+		return nil, err
+	}
+
+	_, err = tx.Exec("INSERT INTO transfers (from_address, to_address, amount, to_balance_after, token) VALUES ($1, $2, $3, $4, $5)",
+		ZeroAddress, toAddress, amountBig.String(), toAfter, token)
+	if err != nil {
+		return nil, err
+	}
+
+	wallet, err := scanWallet(tx.QueryRow("SELECT "+walletColumns+" FROM wallets WHERE address = $1", toAddress))
+	if err != nil {
+		return nil, err
+	}
+
+	if err = tx.Commit(); err != nil {
+		return nil, err
+	}
+
+	tokenWallet(wallet, toAfter)
+	return wallet, nil
+}
+
+// GetTokenWallet returns the wallet at address with its balance of token,
+// or nil if there is no wallet. For the primary token it is GetWallet. For
+// another token Balance is the wallet's balance of it, zero when it never
+// held any, and Reserved and HeldBalance are zero since reserves and holds
+// only apply to the primary token.
+func GetTokenWallet(address, token string) (*model.Wallet, error) {
+	return GetTokenWalletContext(context.Background(), address, token)
+}
+
+func GetTokenWalletContext(ctx context.Context, address, token string) (_ *model.Wallet, err error) {
+	if Settings.IsPrimaryToken(token) {
+		return GetWalletContext(ctx, address)
+	}
+
+	defer func() { err = ClassifyError(err) }()
+
+	if err = Settings.checkToken(token); err != nil {
+		return nil, err
+	}
+
+	wallet, err := GetWalletContext(ctx, address)
+	if err != nil || wallet == nil {
+		return nil, err
+	}
+
+	q, err := readConn(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	var balance string
+	err = q.QueryRow("SELECT COALESCE((SELECT balance FROM token_balances WHERE address = $1 AND token = $2), 0)::text",
+		wallet.Address, token).Scan(&balance)
+	if err != nil {
+		return nil, err
+	}
+	if _, err := parseBalance(wallet.Address, balance); err != nil {
+		return nil, err
+	}
+
+	tokenWallet(wallet, balance)
+	return wallet, nil
+}
+
+// tokenWallet makes wallet show balance as its balance of a token other than
+// the primary one.
+func tokenWallet(wallet *model.Wallet, balance string) {
+	wallet.Balance = balance
+	wallet.Reserved = "0"
+	wallet.HeldBalance = "0"
+}
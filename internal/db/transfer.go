@@ -0,0 +1,301 @@
+package db
+
+import (
+	"context"
+	"database/sql"
+	"math/big"
+	"token-transfer-api/internal/model"
+)
+
+// RefundTransfer reverses the transfer with the given id by moving the same
+// amount back from the original receiver to the original sender. The new
+// transfer row links back to the original through refund_of, so a transfer
+// can only ever be refunded once, and refunds cannot be refunded in turn.
+// Only transfers of the primary token can be refunded; others fail with
+// ErrUnsupportedToken. The refund is checked against the blocklist and the
+// wallet statuses like a transfer, writes a transfer event when events are
+// on and is retried on conflicts. It leaves the original receiver's nonce
+// alone, since the receiver did not sign it. Callers are responsible for
+// checking that the requester may refund.
+func RefundTransfer(transferID int64) (*model.RefundResult, error) {
+	return RefundTransferContext(context.Background(), transferID)
+}
+
+func RefundTransferContext(ctx context.Context, transferID int64) (_ *model.RefundResult, err error) {
+	defer func() { err = ClassifyError(err) }()
+
+	cfg := Settings
+	var result *model.RefundResult
+	err = retryConflicts(ctx, cfg.MaxRetries, func() error {
+		var err error
+		result, err = applyRefund(ctx, cfg, transferID)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+	return result, nil
+}
+
+// applyRefund runs the transaction of a refund.
+func applyRefund(ctx context.Context, cfg Config, transferID int64) (*model.RefundResult, error) {
+	tx, err := begin(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	var original model.Transfer
+	var token sql.NullString
+	var refundOf sql.NullInt64
+	err = tx.QueryRow("SELECT id, from_address, to_address, amount, token, refund_of FROM transfers WHERE id = $1 FOR UPDATE", transferID).
+		Scan(&original.ID, &original.FromAddress, &original.ToAddress, &original.Amount, &token, &refundOf)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, ErrTransferNotFound
+		}
+		return nil, err
+	}
+	if token.Valid {
+		return nil, ErrUnsupportedToken
+	}
+	if refundOf.Valid {
+		return nil, ErrRefundOfRefund
+	}
+
+	var refunded bool
+	err = tx.QueryRow("SELECT EXISTS(SELECT 1 FROM transfers WHERE refund_of = $1)", original.ID).Scan(&refunded)
+	if err != nil {
+		return nil, err
+	}
+	if refunded {
+		return nil, ErrAlreadyRefunded
+	}
+
+	amountBig, ok := new(big.Int).SetString(original.Amount, 10)
+	if !ok {
+		return nil, ErrInvalidAmount
+	}
+
+	fromBalance, err := withdraw(tx, original.ToAddress, amountBig)
+	if err != nil {
+		return nil, err
+	}
+
+	toBalance, err := credit(tx, original.FromAddress, original.Amount)
+	if err != nil {
+		return nil, err
+	}
+
+	// Both wallets are locked now, as in a transfer
+	if err = checkBlocked(tx, original.ToAddress, original.FromAddress); err != nil {
+		return nil, err
+	}
+	if err = checkWalletStatus(tx, original.ToAddress, original.FromAddress); err != nil {
+		return nil, err
+	}
+
+	fromAfter := fromBalance.String()
+	refund := model.Transfer{
+		FromAddress:      original.ToAddress,
+		ToAddress:        original.FromAddress,
+		Amount:           original.Amount,
+		RefundOf:         &original.ID,
+		FromBalanceAfter: &fromAfter,
+		ToBalanceAfter:   &toBalance,
+	}
+	err = tx.QueryRow("INSERT INTO transfers (from_address, to_address, amount, refund_of, from_balance_after, to_balance_after) VALUES ($1, $2, $3, $4, $5, $6) RETURNING id, created_at",
+		refund.FromAddress, refund.ToAddress, refund.Amount, original.ID, fromAfter, toBalance).Scan(&refund.ID, &refund.CreatedAt)
+	if err != nil {
+		return nil, err
+	}
+	if err = recordBalance(tx, cfg, refund.FromAddress, fromAfter); err != nil {
+		return nil, err
+	}
+	if err = recordBalance(tx, cfg, refund.ToAddress, toBalance); err != nil {
+		return nil, err
+	}
+	if cfg.TransferEvents {
+		if err = recordEvent(tx, refund.ID, "0"); err != nil {
+			return nil, err
+		}
+	}
+
+	if err = tx.Commit(); err != nil {
+		return nil, err
+	}
+
+	return &model.RefundResult{
+		Transfer:    &refund,
+		FromBalance: fromBalance.String(),
+		ToBalance:   toBalance,
+	}, nil
+}
+
+// transferColumns are the transfer columns read by scanTransfer, in order.
+const transferColumns = "id, from_address, to_address, amount, refund_of, swap_of, from_balance_after, to_balance_after, memo, token, created_at"
+
+// scanTransfer reads a row selected with transferColumns.
+func scanTransfer(row rowScanner) (*model.Transfer, error) {
+	var t model.Transfer
+	var refundOf, swapOf sql.NullInt64
+	var fromAfter, toAfter, memo, token sql.NullString
+	if err := row.Scan(&t.ID, &t.FromAddress, &t.ToAddress, &t.Amount, &refundOf, &swapOf, &fromAfter, &toAfter, &memo, &token, &t.CreatedAt); err != nil {
+		return nil, err
+	}
+	if refundOf.Valid {
+		t.RefundOf = &refundOf.Int64
+	}
+	if swapOf.Valid {
+		t.SwapOf = &swapOf.Int64
+	}
+	if fromAfter.Valid {
+		t.FromBalanceAfter = &fromAfter.String
+	}
+	if toAfter.Valid {
+		t.ToBalanceAfter = &toAfter.String
+	}
+	if memo.Valid {
+		t.Memo = &memo.String
+	}
+	t.Token = token.String
+	return &t, nil
+}
+
+// GetTransferByID returns the transfer with the given id, or
+// ErrTransferNotFound.
+func GetTransferByID(id int64) (*model.Transfer, error) {
+	return GetTransferByIDContext(context.Background(), id)
+}
+
+func GetTransferByIDContext(ctx context.Context, id int64) (_ *model.Transfer, err error) {
+	defer func() { err = ClassifyError(err) }()
+
+	q, err := readConn(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	transfer, err := scanTransfer(q.QueryRow("SELECT "+transferColumns+" FROM transfers WHERE id = $1", id))
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, ErrTransferNotFound
+		}
+		return nil, err
+	}
+	return transfer, nil
+}
+
+const (
+	// DefaultTransferPageSize is used when ListTransfers is called without a
+	// page size.
+	DefaultTransferPageSize = 20
+	// MaxTransferPageSize caps the number of transfers returned by
+	// ListTransfers.
+	MaxTransferPageSize = 100
+)
+
+// ListTransfers returns up to first transfers, newest first, whose id is
+// below beforeID, and whether more transfers follow them. A zero beforeID
+// starts from the newest transfer. Paging by id rather than offset keeps
+// pages stable while new transfers are being inserted.
+func ListTransfers(first int, beforeID int64) ([]model.Transfer, bool, error) {
+	return ListTransfersContext(context.Background(), first, beforeID)
+}
+
+func ListTransfersContext(ctx context.Context, first int, beforeID int64) (_ []model.Transfer, _ bool, err error) {
+	defer func() { err = ClassifyError(err) }()
+
+	if first < 0 || beforeID < 0 {
+		return nil, false, ErrInvalidPagination
+	}
+	if first == 0 {
+		first = DefaultTransferPageSize
+	}
+	if first > MaxTransferPageSize {
+		first = MaxTransferPageSize
+	}
+
+	query := "SELECT " + transferColumns + " FROM transfers ORDER BY id DESC LIMIT $1"
+	args := []interface{}{first + 1}
+	if beforeID > 0 {
+		query = "SELECT " + transferColumns + " FROM transfers WHERE id < $2 ORDER BY id DESC LIMIT $1"
+		args = append(args, beforeID)
+	}
+
+	q, err := readConn(ctx)
+	if err != nil {
+		return nil, false, err
+	}
+
+	rows, err := q.Query(query, args...)
+	if err != nil {
+		return nil, false, err
+	}
+	defer rows.Close()
+
+	transfers := []model.Transfer{}
+	for rows.Next() {
+		t, err := scanTransfer(rows)
+		if err != nil {
+			return nil, false, err
+		}
+		transfers = append(transfers, *t)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, false, err
+	}
+
+	hasNext := len(transfers) > first
+	if hasNext {
+		transfers = transfers[:first]
+	}
+	return transfers, hasNext, nil
+}
+
+// GetTransfers returns up to limit transfers sent or received by address,
+// newest first, whatever token they moved. A zero limit returns
+// DefaultTransferPageSize transfers and larger limits are capped at
+// MaxTransferPageSize.
+func GetTransfers(address string, limit int) ([]model.Transfer, error) {
+	return GetTransfersContext(context.Background(), address, limit)
+}
+
+func GetTransfersContext(ctx context.Context, address string, limit int) (_ []model.Transfer, err error) {
+	defer func() { err = ClassifyError(err) }()
+
+	if limit < 0 {
+		return nil, ErrInvalidPagination
+	}
+	if limit == 0 {
+		limit = DefaultTransferPageSize
+	}
+	if limit > MaxTransferPageSize {
+		limit = MaxTransferPageSize
+	}
+
+	q, err := readConn(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	rows, err := q.Query("SELECT "+transferColumns+" FROM transfers WHERE from_address = $1 OR to_address = $1 ORDER BY id DESC LIMIT $2",
+		Settings.NormalizeAddress(address), limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	transfers := []model.Transfer{}
+	for rows.Next() {
+		t, err := scanTransfer(rows)
+		if err != nil {
+			return nil, err
+		}
+		transfers = append(transfers, *t)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return transfers, nil
+}
@@ -0,0 +1,137 @@
+package db
+
+import (
+	"context"
+	"database/sql"
+	"math/big"
+)
+
+// stmtTransferCTE debits the sender, credits or creates the receiver and
+// records the transfer in one statement. The sender's UPDATE only matches
+// when neither wallet is blocked, frozen or closed, the receiver's balance
+// stays within maxBalance and the balance left covers the reserve and the
+// held tokens, which also rules out overdrafts because neither is ever
+// negative. A corrupt NaN balance would pass those comparisons, since
+// Postgres orders NaN above every number, so it is ruled out explicitly.
+// The other two parts run on the rows it returns, so a rejected
+// transfer changes nothing and returns no row. The foreign keys on
+// transfers are checked at the end of the statement, when the receiver
+// exists.
+const stmtTransferCTE = `WITH debited AS (
+	UPDATE wallets SET balance = balance - $3::numeric, nonce = nonce + 1, last_activity_at = NOW()
+	WHERE address = $1 AND balance <> 'NaN' AND balance - $3::numeric >= reserved + held
+		AND NOT EXISTS (SELECT 1 FROM blocked_addresses WHERE address IN ($1, $2))
+		AND status = 'active' AND NOT frozen
+		AND NOT EXISTS (SELECT 1 FROM wallets WHERE address = $2 AND (status <> 'active' OR frozen))
+		AND NOT EXISTS (SELECT 1 FROM wallets WHERE address = $2 AND balance + $3::numeric > ` + maxBalance + `)
+	RETURNING balance
+), credited AS (
+	INSERT INTO wallets (address, balance, last_activity_at)
+	SELECT $2, $3::numeric, NOW() FROM debited
+	ON CONFLICT (address) DO UPDATE SET balance = wallets.balance + EXCLUDED.balance, last_activity_at = NOW()
+	RETURNING balance
+), recorded AS (
+	INSERT INTO transfers (from_address, to_address, amount, from_balance_after, to_balance_after, memo)
+	SELECT $1, $2, $3::numeric, debited.balance, credited.balance, NULLIF($4, '') FROM debited, credited
+)
+SELECT balance::text FROM debited`
+
+// TransferTokensCTE is TransferTokens in a single round trip: the debit, the
+// credit and the transfer record are one statement, and the database is only
+// asked again to find out why a transfer was rejected. It returns the same
+// errors and records the same rows as TransferTokens.
+//
+// Fees, the daily limit, transfer events, the balance history, receivers
+// that must exist and initialized new wallets need more than one statement,
+// so while any of them is configured it falls back to TransferTokens.
+func TransferTokensCTE(fromAddress, toAddress, amount string) (string, error) {
+	return TransferTokensCTEContext(context.Background(), fromAddress, toAddress, amount)
+}
+
+func TransferTokensCTEContext(ctx context.Context, fromAddress, toAddress, amount string) (_ string, err error) {
+	cfg := Settings
+	if cfg.FeeWallet != "" || cfg.DailyLimit != nil || cfg.TransferEvents || cfg.BalanceHistory || cfg.ReceiverMustExist || cfg.initializesNewWallets() {
+		return TransferTokensContext(ctx, fromAddress, toAddress, amount)
+	}
+
+	defer func() { err = ClassifyError(err) }()
+
+	fromAddress, toAddress, amountBig, err := checkTransfer(cfg, fromAddress, toAddress, amount, "")
+	if err != nil {
+		return "", err
+	}
+
+	q, err := conn(ctx)
+	if err != nil {
+		return "", err
+	}
+
+	// Outside a caller's transaction the statement commits on its own. Its
+	// UPDATE locks the sender and re-checks the balance against the latest
+	// version of the row, so concurrent transfers cannot overdraw it.
+	var balance string
+	err = retryConflicts(ctx, cfg.MaxRetries, func() error {
+		return queryRow(q, stmtTransferCTE, fromAddress, toAddress, amountBig.String(), "").Scan(&balance)
+	})
+	if err == sql.ErrNoRows {
+		return "", rejectionReason(q, fromAddress, toAddress, amountBig)
+	}
+	if err != nil {
+		return "", err
+	}
+	noteWrite()
+	return balance, nil
+}
+
+// rejectionReason works out why stmtTransferCTE did not transfer, checking in
+// the order applyTransfer does. It reads the state after the fact, so a
+// concurrent change can make it report a different reason than the one that
+// applied.
+func rejectionReason(q querier, fromAddress, toAddress string, amount *big.Int) error {
+	var balance, reserved string
+	err := q.QueryRow("SELECT balance, reserved + held FROM wallets WHERE address = $1", fromAddress).Scan(&balance, &reserved)
+	if err == sql.ErrNoRows {
+		return ErrSenderNotFound
+	}
+	if err != nil {
+		return err
+	}
+
+	balanceBig, err := parseBalance(fromAddress, balance)
+	if err != nil {
+		return err
+	}
+	if balanceBig.Cmp(amount) < 0 {
+		return ErrInsufficientBalance
+	}
+	reservedBig, err := parseBalance(fromAddress, reserved)
+	if err != nil {
+		return err
+	}
+	if new(big.Int).Sub(balanceBig, amount).Cmp(reservedBig) < 0 {
+		return ErrReserveViolation
+	}
+
+	var fits bool
+	err = q.QueryRow("SELECT NOT EXISTS (SELECT 1 FROM wallets WHERE address = $1 AND balance + $2::numeric > "+maxBalance+")", toAddress, amount.String()).Scan(&fits)
+	if err != nil {
+		return err
+	}
+	if !fits {
+		if err := checkStoredBalance(q, toAddress); err != nil {
+			return err
+		}
+		return ErrBalanceOverflow
+	}
+
+	if err := checkBlocked(q, fromAddress, toAddress); err != nil {
+		return err
+	}
+	if err := checkWalletStatus(q, fromAddress, toAddress); err != nil {
+		return err
+	}
+
+	// The transfer would go through now, so the state it was rejected for
+	// has already changed.
+	return ErrConflict
+}
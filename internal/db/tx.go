@@ -0,0 +1,157 @@
+package db
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"strings"
+	"sync/atomic"
+	"token-transfer-api/internal/tracing"
+
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// querier is the part of *sql.DB and *sql.Tx used to run statements.
+type querier interface {
+	Exec(query string, args ...interface{}) (sql.Result, error)
+	Query(query string, args ...interface{}) (*sql.Rows, error)
+	QueryRow(query string, args ...interface{}) *sql.Row
+}
+
+// txn is a unit of work started by begin: either a real transaction or a
+// savepoint inside a transaction supplied by the caller.
+type txn interface {
+	querier
+	Commit() error
+	Rollback() error
+}
+
+type txKey struct{}
+
+// WithTx returns a context that makes every db function called with it run
+// inside tx instead of opening its own transaction on DB. The caller owns tx
+// and decides whether it is committed or rolled back.
+func WithTx(ctx context.Context, tx *sql.Tx) context.Context {
+	return context.WithValue(ctx, txKey{}, tx)
+}
+
+// InTx reports whether ctx carries a transaction set with WithTx, in which
+// case db functions leave committing to the caller.
+func InTx(ctx context.Context) bool {
+	return txFromContext(ctx) != nil
+}
+
+func txFromContext(ctx context.Context) *sql.Tx {
+	tx, _ := ctx.Value(txKey{}).(*sql.Tx)
+	return tx
+}
+
+// conn returns the connection statements outside a transaction should use.
+func conn(ctx context.Context) (querier, error) {
+	if tx := txFromContext(ctx); tx != nil {
+		return tx, nil
+	}
+	pool, err := database()
+	if err != nil {
+		return nil, err
+	}
+	return pool, nil
+}
+
+// BeginTx starts a transaction on DB at the isolation level configured in
+// Settings.
+func BeginTx(ctx context.Context) (*sql.Tx, error) {
+	pool, err := database()
+	if err != nil {
+		return nil, err
+	}
+	return pool.BeginTx(ctx, &sql.TxOptions{Isolation: Settings.Isolation})
+}
+
+var savepointSeq atomic.Uint64
+
+// begin starts a transaction, or a savepoint when ctx already carries one so
+// that the outer transaction keeps the final say. Each statement run on it
+// gets a span under the one in ctx.
+func begin(ctx context.Context) (txn, error) {
+	outer := txFromContext(ctx)
+	if outer == nil {
+		tx, err := BeginTx(ctx)
+		if err != nil {
+			return nil, err
+		}
+		return tracedTx{txn: primaryTx{tx}, ctx: ctx}, nil
+	}
+
+	name := fmt.Sprintf("sp_%d", savepointSeq.Add(1))
+	if _, err := outer.Exec("SAVEPOINT " + name); err != nil {
+		return nil, err
+	}
+	return tracedTx{txn: &savepoint{Tx: outer, name: name}, ctx: ctx}, nil
+}
+
+// tracedTx is a txn whose statements each get a span under the one in ctx.
+// The spans hold the SQL text with its $n placeholders but never the
+// argument values, which can be addresses and amounts.
+type tracedTx struct {
+	txn
+	ctx context.Context
+}
+
+// startStatement starts the span of running query.
+func (t tracedTx) startStatement(query string) trace.Span {
+	query = strings.Join(strings.Fields(query), " ")
+	operation, _, _ := strings.Cut(query, " ")
+	_, span := tracing.Start(t.ctx, "db."+strings.ToLower(operation),
+		attribute.String("db.system", "postgresql"),
+		attribute.String("db.statement", query),
+	)
+	return span
+}
+
+func (t tracedTx) Exec(query string, args ...interface{}) (sql.Result, error) {
+	span := t.startStatement(query)
+	result, err := t.txn.Exec(query, args...)
+	tracing.End(span, err)
+	return result, err
+}
+
+func (t tracedTx) Query(query string, args ...interface{}) (*sql.Rows, error) {
+	span := t.startStatement(query)
+	rows, err := t.txn.Query(query, args...)
+	tracing.End(span, err)
+	return rows, err
+}
+
+// QueryRow runs the query before it returns, so the span covers it, but
+// errors only surface when the row is scanned and are not recorded.
+func (t tracedTx) QueryRow(query string, args ...interface{}) *sql.Row {
+	span := t.startStatement(query)
+	defer span.End()
+	return t.txn.QueryRow(query, args...)
+}
+
+type savepoint struct {
+	*sql.Tx
+	name string
+	done bool
+}
+
+func (s *savepoint) Commit() error {
+	if s.done {
+		return sql.ErrTxDone
+	}
+	s.done = true
+	_, err := s.Tx.Exec("RELEASE SAVEPOINT " + s.name)
+	return err
+}
+
+func (s *savepoint) Rollback() error {
+	if s.done {
+		return sql.ErrTxDone
+	}
+	s.done = true
+	_, err := s.Tx.Exec("ROLLBACK TO SAVEPOINT " + s.name)
+	return err
+}
@@ -1,87 +1,850 @@
 package db
 
 import (
+	"context"
 	"database/sql"
 	"errors"
+	"fmt"
 	"math/big"
+	"time"
 	"token-transfer-api/internal/model"
+	"token-transfer-api/internal/tracing"
+	"unicode/utf8"
+
+	"github.com/lib/pq"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
 )
 
-func GetWallet(address string) (*model.Wallet, error) {
+// walletColumns are the columns read by scanWallet, in order.
+const walletColumns = "address, balance, reserved, held, status, frozen, nonce, last_activity_at"
+
+type rowScanner interface {
+	Scan(dest ...interface{}) error
+}
+
+// scanWallet reads a row selected with walletColumns, followed by any extra
+// columns scanned into extra.
+func scanWallet(row rowScanner, extra ...interface{}) (*model.Wallet, error) {
 	var wallet model.Wallet
-	err := DB.QueryRow("SELECT address, balance FROM wallets WHERE address = $1", address).Scan(&wallet.Address, &wallet.Balance)
+	var lastActivity sql.NullTime
+	dest := append([]interface{}{&wallet.Address, &wallet.Balance, &wallet.Reserved, &wallet.HeldBalance, &wallet.Status, &wallet.Frozen, &wallet.Nonce, &lastActivity}, extra...)
+	if err := row.Scan(dest...); err != nil {
+		return nil, err
+	}
+	if lastActivity.Valid {
+		wallet.LastActivityAt = &lastActivity.Time
+	}
+	for _, balance := range []string{wallet.Balance, wallet.Reserved, wallet.HeldBalance} {
+		if _, err := parseBalance(wallet.Address, balance); err != nil {
+			return nil, err
+		}
+	}
+	return &wallet, nil
+}
+
+// parseBalance parses a balance, reserve or held amount read from the wallet
+// at address. The DECIMAL columns only ever read back as non-negative
+// integers unless a row was edited by hand to something like 'NaN', which
+// fails with ErrCorruptBalance naming the wallet.
+func parseBalance(address, balance string) (*big.Int, error) {
+	v, ok := new(big.Int).SetString(balance, 10)
+	if !ok || v.Sign() < 0 {
+		return nil, &AppError{
+			Code:    ErrCorruptBalance.Code,
+			Message: ErrCorruptBalance.Message,
+			Err:     fmt.Errorf("wallet %s has balance %q", address, balance),
+			Details: map[string]interface{}{"address": address},
+		}
+	}
+	return v, nil
+}
+
+// checkStoredBalance returns ErrCorruptBalance if the wallet at address
+// exists and its balance is corrupt. Statements that compare the balance in
+// SQL call it to tell a corrupt balance apart from one that legitimately
+// failed the comparison.
+func checkStoredBalance(q querier, address string) error {
+	var balance string
+	err := q.QueryRow("SELECT balance::text FROM wallets WHERE address = $1", address).Scan(&balance)
+	if err == sql.ErrNoRows {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	_, err = parseBalance(address, balance)
+	return err
+}
+
+func GetWallet(address string) (*model.Wallet, error) {
+	return GetWalletContext(context.Background(), address)
+}
+
+func GetWalletContext(ctx context.Context, address string) (_ *model.Wallet, err error) {
+	defer func() { err = ClassifyError(err) }()
+
+	address = Settings.NormalizeAddress(address)
+
+	q, err := readConn(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	wallet, err := scanWallet(q.QueryRow("SELECT "+walletColumns+" FROM wallets WHERE address = $1", address))
 	if err != nil {
 		if err == sql.ErrNoRows {
 			return nil, nil
 		}
 		return nil, err
 	}
-	return &wallet, nil
+	return wallet, nil
 }
 
-func TransferTokens(fromAddress, toAddress, amount string) (string, error) {
-	amountBig := new(big.Int)
-	_, ok := amountBig.SetString(amount, 10)
-	if !ok || amountBig.Cmp(big.NewInt(0)) <= 0 {
-		return "", errors.New("invalid amount")
+// MaxBulkAddresses caps the number of addresses GetWallets accepts.
+const MaxBulkAddresses = 100
+
+// GetWallets returns the wallets at the given addresses with a single query,
+// in the order the addresses were given. Unknown addresses are left out and
+// repeated addresses returned once.
+func GetWallets(addresses []string) ([]model.Wallet, error) {
+	return GetWalletsContext(context.Background(), addresses)
+}
+
+func GetWalletsContext(ctx context.Context, addresses []string) (_ []model.Wallet, err error) {
+	defer func() { err = ClassifyError(err) }()
+
+	if len(addresses) > MaxBulkAddresses {
+		return nil, ErrTooManyAddresses.WithDetails(map[string]interface{}{"max_addresses": MaxBulkAddresses})
+	}
+
+	normalized := make([]string, len(addresses))
+	for i, address := range addresses {
+		normalized[i] = Settings.NormalizeAddress(address)
+	}
+
+	q, err := readConn(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	rows, err := q.Query("SELECT "+walletColumns+" FROM wallets WHERE address = ANY($1)", pq.Array(normalized))
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	found := make(map[string]*model.Wallet)
+	for rows.Next() {
+		wallet, err := scanWallet(rows)
+		if err != nil {
+			return nil, err
+		}
+		found[wallet.Address] = wallet
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	wallets := []model.Wallet{}
+	for _, address := range normalized {
+		if wallet, ok := found[address]; ok {
+			wallets = append(wallets, *wallet)
+			delete(found, address)
+		}
+	}
+	return wallets, nil
+}
+
+const (
+	// DefaultWalletPageSize is used when ListWallets is called without a limit.
+	DefaultWalletPageSize = 20
+	// MaxWalletPageSize caps the number of wallets returned by ListWallets.
+	MaxWalletPageSize = 100
+)
+
+// walletOrders maps the supported sort orders to their ORDER BY clauses. The
+// balance column is DECIMAL, so it sorts numerically rather than by digits.
+var walletOrders = map[string]string{
+	"BALANCE_DESC": "balance DESC, address ASC",
+	"BALANCE_ASC":  "balance ASC, address ASC",
+	"ADDRESS_ASC":  "address ASC",
+}
+
+// walletPageSize validates the paging arguments of the wallet listings and
+// returns the page size to use.
+func walletPageSize(limit, offset int) (int, error) {
+	if limit < 0 || offset < 0 {
+		return 0, ErrInvalidPagination
+	}
+	if limit == 0 {
+		return DefaultWalletPageSize, nil
+	}
+	if limit > MaxWalletPageSize {
+		return MaxWalletPageSize, nil
+	}
+	return limit, nil
+}
+
+// ListWallets returns a page of wallets in the given order. A zero limit
+// selects the default page size and larger limits are capped.
+func ListWallets(limit, offset int, order string) ([]model.Wallet, error) {
+	return ListWalletsContext(context.Background(), limit, offset, order)
+}
+
+func ListWalletsContext(ctx context.Context, limit, offset int, order string) (_ []model.Wallet, err error) {
+	defer func() { err = ClassifyError(err) }()
+
+	limit, err = walletPageSize(limit, offset)
+	if err != nil {
+		return nil, err
+	}
+
+	orderBy, ok := walletOrders[order]
+	if !ok {
+		return nil, ErrInvalidOrder
+	}
+
+	q, err := readConn(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	rows, err := q.Query("SELECT "+walletColumns+" FROM wallets ORDER BY "+orderBy+" LIMIT $1 OFFSET $2",
+		limit, offset)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	wallets := []model.Wallet{}
+	for rows.Next() {
+		wallet, err := scanWallet(rows)
+		if err != nil {
+			return nil, err
+		}
+		wallets = append(wallets, *wallet)
+	}
+	return wallets, rows.Err()
+}
+
+// ListWalletsWithCount returns a page of wallets like ListWallets together
+// with the total number of wallets, so a client can show its position in the
+// full list without a second request.
+func ListWalletsWithCount(limit, offset int, order string) (*model.WalletConnection, error) {
+	return ListWalletsWithCountContext(context.Background(), limit, offset, order)
+}
+
+func ListWalletsWithCountContext(ctx context.Context, limit, offset int, order string) (_ *model.WalletConnection, err error) {
+	defer func() { err = ClassifyError(err) }()
+
+	limit, err = walletPageSize(limit, offset)
+	if err != nil {
+		return nil, err
+	}
+
+	orderBy, ok := walletOrders[order]
+	if !ok {
+		return nil, ErrInvalidOrder
+	}
+
+	q, err := readConn(ctx)
+	if err != nil {
+		return nil, err
 	}
 
-	tx, err := DB.Begin()
+	// The window count is computed before LIMIT applies, so every row
+	// carries the size of the whole table.
+	rows, err := q.Query("SELECT "+walletColumns+", COUNT(*) OVER () FROM wallets ORDER BY "+orderBy+" LIMIT $1 OFFSET $2",
+		limit, offset)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	page := &model.WalletConnection{Nodes: []model.Wallet{}}
+	for rows.Next() {
+		wallet, err := scanWallet(rows, &page.TotalCount)
+		if err != nil {
+			return nil, err
+		}
+		page.Nodes = append(page.Nodes, *wallet)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	// A page past the end has no rows to carry the count.
+	if len(page.Nodes) == 0 {
+		if err := q.QueryRow("SELECT COUNT(*) FROM wallets").Scan(&page.TotalCount); err != nil {
+			return nil, err
+		}
+	}
+
+	page.PageInfo.HasNextPage = int64(offset+len(page.Nodes)) < page.TotalCount
+	return page, nil
+}
+
+// ListDormantWallets returns a page of wallets that have neither sent nor
+// received tokens since the cutoff, least recently active first. Wallets
+// that were never active are included.
+func ListDormantWallets(inactiveSince time.Time, limit, offset int) ([]model.Wallet, error) {
+	return ListDormantWalletsContext(context.Background(), inactiveSince, limit, offset)
+}
+
+func ListDormantWalletsContext(ctx context.Context, inactiveSince time.Time, limit, offset int) (_ []model.Wallet, err error) {
+	defer func() { err = ClassifyError(err) }()
+
+	limit, err = walletPageSize(limit, offset)
+	if err != nil {
+		return nil, err
+	}
+
+	q, err := readConn(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	rows, err := q.Query("SELECT "+walletColumns+" FROM wallets WHERE last_activity_at IS NULL OR last_activity_at < $1 "+
+		"ORDER BY last_activity_at ASC NULLS FIRST, address ASC LIMIT $2 OFFSET $3", inactiveSince, limit, offset)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	wallets := []model.Wallet{}
+	for rows.Next() {
+		wallet, err := scanWallet(rows)
+		if err != nil {
+			return nil, err
+		}
+		wallets = append(wallets, *wallet)
+	}
+	return wallets, rows.Err()
+}
+
+// MaxMemoLength is the longest memo, in characters, a transfer may carry.
+const MaxMemoLength = 256
+
+func TransferTokens(fromAddress, toAddress, amount string) (string, error) {
+	return TransferTokensContext(context.Background(), fromAddress, toAddress, amount)
+}
+
+func TransferTokensContext(ctx context.Context, fromAddress, toAddress, amount string) (string, error) {
+	result, err := ExecuteTransfer(ctx, fromAddress, toAddress, amount, "", nil, "")
 	if err != nil {
 		return "", err
 	}
+	return result.Balance, nil
+}
+
+// ExecuteTransfer moves amount from the sender to the receiver and charges
+// the configured fee to the sender on top of it. The fee is credited to the
+// fee wallet and recorded as a separate transfer in the same transaction.
+// A non-empty memo of at most MaxMemoLength characters is stored with the
+// transfer as it is; the fee transfer has none.
+//
+// Every transfer a wallet sends increments its nonce. With a non-nil
+// expectedNonce the transfer only goes ahead while the sender's nonce still
+// equals it and fails with ErrNonceMismatch otherwise, so a replayed or
+// reordered transfer is refused.
+//
+// A non-empty minBalance makes the transfer conditional: it only goes ahead
+// while the sender's balance, read under the same lock, is at least
+// minBalance and fails with ErrConditionNotMet otherwise. Unlike the amount,
+// the threshold may be more than the transfer moves.
+func ExecuteTransfer(ctx context.Context, fromAddress, toAddress, amount, memo string, expectedNonce *int64, minBalance string) (*model.TransferResult, error) {
+	result, err := runTransfer(ctx, fromAddress, toAddress, amount, memo, expectedNonce, minBalance, true)
+	if err != nil {
+		return nil, err
+	}
+	result.WouldSucceed = true
+	return result, nil
+}
+
+// SimulateTransfer runs every step of ExecuteTransfer inside a transaction
+// that is always rolled back, so nothing is recorded. A transfer that would
+// be rejected is reported through WouldSucceed and the failure fields rather
+// than as an error; only unexpected failures are returned as errors.
+func SimulateTransfer(ctx context.Context, fromAddress, toAddress, amount, memo string, expectedNonce *int64, minBalance string) (*model.TransferResult, error) {
+	result, err := runTransfer(ctx, fromAddress, toAddress, amount, memo, expectedNonce, minBalance, false)
+	if err != nil {
+		var appErr *AppError
+		if errors.As(err, &appErr) {
+			return &model.TransferResult{
+				FailureCode:    appErr.Code,
+				FailureMessage: appErr.Message,
+			}, nil
+		}
+		return nil, err
+	}
+	result.WouldSucceed = true
+	return result, nil
+}
+
+// runTransfer performs a transfer and commits it, or rolls it back once all
+// checks have passed when commit is false.
+func runTransfer(ctx context.Context, fromAddress, toAddress, amount, memo string, expectedNonce *int64, minBalance string, commit bool) (_ *model.TransferResult, err error) {
+	ctx, span := startTransferSpan(ctx, fromAddress, toAddress, amount, commit)
+	// Deferred first so it sees the classified error
+	defer func() { tracing.End(span, err) }()
+	defer func() { err = ClassifyError(err) }()
+
+	cfg := Settings
+	fromAddress, toAddress, amountBig, err := checkTransfer(cfg, fromAddress, toAddress, amount, memo)
+	if err != nil {
+		return nil, err
+	}
+
+	var minBalanceBig *big.Int
+	if minBalance != "" {
+		if minBalanceBig, err = parseAmount(minBalance); err != nil {
+			return nil, err
+		}
+	}
+
+	fee := cfg.Fee(amountBig)
+
+	var result *model.TransferResult
+	err = retryConflicts(ctx, cfg.MaxRetries, func() error {
+		var err error
+		result, err = applyTransfer(ctx, cfg, fromAddress, toAddress, memo, amountBig, fee, expectedNonce, minBalanceBig, commit)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+	return result, nil
+}
+
+// startTransferSpan starts the span of a transfer, which the spans of its
+// statements nest under. The addresses are hashed, and the amount is left
+// out while amounts are sensitive.
+func startTransferSpan(ctx context.Context, fromAddress, toAddress, amount string, commit bool) (context.Context, trace.Span) {
+	ctx, span := tracing.Start(ctx, "db.transfer",
+		tracing.Address("transfer.from", fromAddress),
+		tracing.Address("transfer.to", toAddress),
+		attribute.Bool("transfer.dry_run", !commit),
+	)
+	tracing.SetAmount(span, "transfer.amount", amount)
+	return ctx, span
+}
+
+// checkTransfer runs the checks that need no database access and returns the
+// normalized addresses and the parsed amount.
+func checkTransfer(cfg Config, fromAddress, toAddress, amount, memo string) (string, string, *big.Int, error) {
+	amountBig, err := parseAmount(amount)
+	if err != nil {
+		return "", "", nil, err
+	}
+
+	if utf8.RuneCountInString(memo) > MaxMemoLength {
+		return "", "", nil, ErrMemoTooLong
+	}
+
+	if !cfg.AmountAllowed(amountBig) {
+		return "", "", nil, ErrAmountNotAllowed
+	}
+
+	if cfg.MaxTransferAmount != nil && amountBig.Cmp(cfg.MaxTransferAmount) > 0 {
+		return "", "", nil, ErrAmountTooLarge.WithDetails(map[string]interface{}{"max": cfg.MaxTransferAmount.String()})
+	}
+
+	// Compare normalized addresses so case variants of one wallet count as a
+	// self-transfer.
+	fromAddress = cfg.NormalizeAddress(fromAddress)
+	toAddress = cfg.NormalizeAddress(toAddress)
+	if fromAddress == toAddress {
+		return "", "", nil, ErrSelfTransfer
+	}
+	if cfg.ZeroAddressReserved && fromAddress == ZeroAddress {
+		return "", "", nil, ErrReservedAddress
+	}
+
+	return fromAddress, toAddress, amountBig, nil
+}
+
+// CheckTransfer returns the error a transfer of amount base units from
+// fromAddress to toAddress fails with before it reads any balance: a
+// malformed address or amount, a self-transfer or an amount the transfer
+// rules refuse. It does not touch the database, so the resolver can refuse
+// such transfers before they use up the sender's rate limit.
+func CheckTransfer(fromAddress, toAddress, amount string) error {
+	if !ValidAddress(fromAddress) || !ValidAddress(toAddress) {
+		return ErrInvalidAddress
+	}
+	_, _, _, err := checkTransfer(Settings, fromAddress, toAddress, amount, "")
+	return err
+}
+
+// applyTransfer runs the transaction of a validated transfer. It is retried
+// as a whole when it conflicts with a concurrent transaction. Only the parsed
+// amount is used, so the credit and the record hold its canonical form.
+func applyTransfer(ctx context.Context, cfg Config, fromAddress, toAddress, memo string, amountBig, fee *big.Int, expectedNonce *int64, minBalance *big.Int, commit bool) (*model.TransferResult, error) {
+	total := new(big.Int).Add(amountBig, fee)
+	amount := amountBig.String()
+
+	tx, err := begin(ctx)
+	if err != nil {
+		return nil, err
+	}
 	defer tx.Rollback()
 
-	var senderBalance string
-	err = tx.QueryRow("SELECT balance FROM wallets WHERE address = $1", fromAddress).Scan(&senderBalance)
+	if expectedNonce != nil {
+		if err = checkNonce(tx, fromAddress, *expectedNonce); err != nil {
+			return nil, err
+		}
+	}
+	if minBalance != nil {
+		if err = checkMinBalance(tx, fromAddress, minBalance); err != nil {
+			return nil, err
+		}
+	}
+
+	newSenderBalance, err := debit(tx, fromAddress, total)
+	if err != nil {
+		return nil, err
+	}
+
+	// debit locked the sender, so transfers from the same wallet are counted
+	// against the limit one at a time.
+	if err = checkDailyLimit(tx, cfg, fromAddress, total); err != nil {
+		return nil, err
+	}
+
+	if err = checkReceiver(tx, cfg, toAddress); err != nil {
+		return nil, err
+	}
+	toAfter, created, err := creditReceiver(tx, cfg, toAddress, amountBig)
+	if err != nil {
+		return nil, err
+	}
+
+	// Both wallets are locked now, so a concurrently added block has either
+	// committed and is visible here or is waiting for this transfer.
+	if err = checkBlocked(tx, fromAddress, toAddress); err != nil {
+		return nil, err
+	}
+	// A receiver this transfer created has NEW_WALLET_DEFAULT_STATUS, which
+	// applies from the next transfer on.
+	statusChecked := []string{fromAddress, toAddress}
+	if created {
+		statusChecked = statusChecked[:1]
+	}
+	if err = checkWalletStatus(tx, statusChecked...); err != nil {
+		return nil, err
+	}
+
+	// The sender was debited the amount and fee together; record the
+	// balances as if the amount and the fee had moved one after another.
+	fromAfter := new(big.Int).Add(newSenderBalance, fee).String()
+	var transferID int64
+	err = queryRow(tx, stmtRecordTransfer, fromAddress, toAddress, amount, fromAfter, toAfter, memo).Scan(&transferID)
+	if err != nil {
+		return nil, err
+	}
+	if err = recordBalance(tx, cfg, fromAddress, newSenderBalance.String()); err != nil {
+		return nil, err
+	}
+	if err = recordBalance(tx, cfg, toAddress, toAfter); err != nil {
+		return nil, err
+	}
+
+	if cfg.TransferEvents {
+		if err = recordEvent(tx, transferID, fee.String()); err != nil {
+			return nil, err
+		}
+	}
+
+	if err = chargeFee(tx, cfg, fromAddress, newSenderBalance, fee); err != nil {
+		return nil, err
+	}
+
+	result := &model.TransferResult{
+		Balance: newSenderBalance.String(),
+		Fee:     fee.String(),
+	}
+	if !commit {
+		return result, tx.Rollback()
+	}
+
+	if err = tx.Commit(); err != nil {
+		return nil, err
+	}
+	return result, nil
+}
+
+// chargeFee credits fee, which was debited from the sender together with the
+// amount, to the fee wallet and records it as a transfer of its own.
+// senderAfter is the sender's balance once both left it.
+func chargeFee(tx txn, cfg Config, fromAddress string, senderAfter, fee *big.Int) error {
+	if fee.Sign() <= 0 {
+		return nil
+	}
+
+	feeWalletAfter, err := credit(tx, cfg.FeeWallet, fee.String())
+	if err != nil {
+		return err
+	}
+	if err = recordBalance(tx, cfg, cfg.FeeWallet, feeWalletAfter); err != nil {
+		return err
+	}
+
+	fromAfter := senderAfter.String()
+	if fromAddress == cfg.FeeWallet {
+		fromAfter = feeWalletAfter
+	}
+	_, err = exec(tx, stmtRecordTransfer, fromAddress, cfg.FeeWallet, fee.String(), fromAfter, feeWalletAfter, "")
+	return err
+}
+
+// debit locks the sender's row, checks it can cover amount without dropping
+// below its reserve and held tokens and writes the reduced balance,
+// returning it. The wallet's last activity time is bumped along with the
+// balance.
+func debit(tx txn, address string, amount *big.Int) (*big.Int, error) {
+	newBalance, err := lockSpendable(tx, address, amount)
+	if err != nil {
+		return nil, err
+	}
+
+	_, err = exec(tx, stmtDebit, newBalance.String(), address)
+	if err != nil {
+		return nil, err
+	}
+
+	return newBalance, nil
+}
+
+// withdraw is debit for debits the wallet's owner did not sign, such as
+// refunds. It leaves the nonce alone, so the owner's pending signed
+// transfers stay valid.
+func withdraw(tx txn, address string, amount *big.Int) (*big.Int, error) {
+	newBalance, err := lockSpendable(tx, address, amount)
+	if err != nil {
+		return nil, err
+	}
+
+	_, err = tx.Exec("UPDATE wallets SET balance = $1, last_activity_at = NOW() WHERE address = $2", newBalance.String(), address)
+	if err != nil {
+		return nil, err
+	}
+
+	return newBalance, nil
+}
+
+// checkNonce locks the sender's row and checks its nonce equals expected.
+// The lock is the one debit takes next, so the nonce cannot move in between.
+func checkNonce(tx txn, address string, expected int64) error {
+	var nonce int64
+	err := tx.QueryRow("SELECT nonce FROM wallets WHERE address = $1 FOR UPDATE", address).Scan(&nonce)
 	if err != nil {
 		if err == sql.ErrNoRows {
-			return "", errors.New("sender wallet does not exist")
+			return ErrSenderNotFound
 		}
-		return "", err
+		return err
 	}
+	if nonce != expected {
+		return ErrNonceMismatch.WithDetails(map[string]interface{}{"nonce": nonce})
+	}
+	return nil
+}
 
-	senderBalanceBig := new(big.Int)
-	_, ok = senderBalanceBig.SetString(senderBalance, 10)
-	if !ok {
-		return "", errors.New("invalid sender balance format")
+// checkMinBalance locks the sender's row and checks its balance is at least
+// min. Like checkNonce it takes the lock debit takes next, so the balance
+// cannot drop below min in between.
+func checkMinBalance(tx txn, address string, min *big.Int) error {
+	var balance string
+	err := tx.QueryRow("SELECT balance FROM wallets WHERE address = $1 FOR UPDATE", address).Scan(&balance)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return ErrSenderNotFound
+		}
+		return err
+	}
+	balanceBig, err := parseBalance(address, balance)
+	if err != nil {
+		return err
 	}
+	if balanceBig.Cmp(min) < 0 {
+		return ErrConditionNotMet.WithDetails(map[string]interface{}{"balance": balanceBig.String()})
+	}
+	return nil
+}
 
-	if senderBalanceBig.Cmp(amountBig) < 0 {
-		return "", errors.New("insufficient balance")
+// lockSpendable locks the sender's row and checks it can spend amount: the
+// balance must cover it and what remains must still cover the reserve and
+// the tokens in escrow. It returns the balance after amount is taken out
+// but changes nothing.
+func lockSpendable(tx txn, address string, amount *big.Int) (*big.Int, error) {
+	var balance, unspendable string
+	err := queryRow(tx, stmtLockSender, address).Scan(&balance, &unspendable)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, ErrSenderNotFound
+		}
+		return nil, err
+	}
+
+	balanceBig, err := parseBalance(address, balance)
+	if err != nil {
+		return nil, err
 	}
 
-	newSenderBalance := new(big.Int).Sub(senderBalanceBig, amountBig)
+	if balanceBig.Cmp(amount) < 0 {
+		return nil, ErrInsufficientBalance
+	}
 
-	_, err = tx.Exec("UPDATE wallets SET balance = $1 WHERE address = $2", newSenderBalance.String(), fromAddress)
+	newBalance := new(big.Int).Sub(balanceBig, amount)
+
+	unspendableBig, err := parseBalance(address, unspendable)
 	if err != nil {
-		return "", err
+		return nil, err
+	}
+	if newBalance.Cmp(unspendableBig) < 0 {
+		return nil, ErrReserveViolation
 	}
 
-	var receiverExists bool
-	err = tx.QueryRow("SELECT EXISTS(SELECT 1 FROM wallets WHERE address = $1)", toAddress).Scan(&receiverExists)
+	return newBalance, nil
+}
+
+// CreditWallet adds amount to the balance of the wallet at address, creating
+// the wallet if it does not exist yet, and returns the resulting balance. It
+// is the path every transfer, mint and refund credits the receiver through,
+// but on its own it records no transfer, so the credited tokens are not
+// accounted for by the ledger; use Mint to issue tokens.
+func CreditWallet(address, amount string) (string, error) {
+	return CreditWalletContext(context.Background(), address, amount)
+}
+
+func CreditWalletContext(ctx context.Context, address, amount string) (_ string, err error) {
+	defer func() { err = ClassifyError(err) }()
+
+	amountBig, err := parseAmount(amount)
 	if err != nil {
 		return "", err
 	}
+	if !ValidAddress(address) {
+		return "", ErrInvalidAddress
+	}
 
-	if receiverExists {
-		_, err = tx.Exec("UPDATE wallets SET balance = balance + $1 WHERE address = $2", amount, toAddress)
-	} else {
-		_, err = tx.Exec("INSERT INTO wallets (address, balance) VALUES ($1, $2)", toAddress, amount)
+	q, err := conn(ctx)
+	if err != nil {
+		return "", err
 	}
+	balance, err := credit(q, Settings.NormalizeAddress(address), amountBig.String())
 	if err != nil {
 		return "", err
 	}
+	noteWrite()
+	return balance, nil
+}
 
-	_, err = tx.Exec("INSERT INTO transfers (from_address, to_address, amount) VALUES ($1, $2, $3)",
-		fromAddress, toAddress, amount)
+// checkReceiver returns ErrReceiverNotFound when cfg requires receivers to
+// exist and there is no wallet at address, before credit would create one.
+func checkReceiver(q querier, cfg Config, address string) error {
+	if !cfg.ReceiverMustExist {
+		return nil
+	}
+	var exists bool
+	if err := q.QueryRow("SELECT EXISTS (SELECT 1 FROM wallets WHERE address = $1)", address).Scan(&exists); err != nil {
+		return err
+	}
+	if !exists {
+		return ErrReceiverNotFound
+	}
+	return nil
+}
+
+// maxBalance is the largest balance a DECIMAL(78, 0) column holds, 10^78-1.
+const maxBalance = "999999999999999999999999999999999999999999999999999999999999999999999999999999"
+
+// credit adds amount to the receiver's balance, creating the wallet if it
+// does not exist yet, and returns the resulting balance. Like debit, it
+// records the activity time. The upsert is a single statement, so two
+// transfers creating the same receiver at once both credit it: under READ
+// COMMITTED the second adds to the wallet the first inserted, and under
+// REPEATABLE READ and SERIALIZABLE it fails with a serialization failure,
+// which the transfer retries against the committed wallet.
+// A credit that would take the balance past maxBalance fails with
+// ErrBalanceOverflow and leaves the wallet unchanged.
+func credit(q querier, address, amount string) (string, error) {
+	if len(amount) > len(maxBalance) {
+		return "", ErrBalanceOverflow
+	}
+
+	var balance string
+	err := queryRow(q, stmtCredit, address, amount).Scan(&balance)
+	if err == sql.ErrNoRows {
+		// The update's condition left the existing wallet alone, which a
+		// corrupt balance does as well since NaN compares above any limit.
+		if err := checkStoredBalance(q, address); err != nil {
+			return "", err
+		}
+		return "", ErrBalanceOverflow
+	}
 	if err != nil {
 		return "", err
 	}
+	if _, err := parseBalance(address, balance); err != nil {
+		return "", err
+	}
+	return balance, nil
+}
+
+// SetReserve sets the part of a wallet's balance that cannot be transferred
+// out. The reserve must be non-negative and covered by the current balance.
+func SetReserve(address, amount string) (*model.Wallet, error) {
+	return SetReserveContext(context.Background(), address, amount)
+}
+
+func SetReserveContext(ctx context.Context, address, amount string) (_ *model.Wallet, err error) {
+	defer func() { err = ClassifyError(err) }()
+
+	reservedBig, ok := new(big.Int).SetString(amount, 10)
+	if !ok || reservedBig.Sign() < 0 {
+		return nil, ErrInvalidReserve
+	}
+
+	address = Settings.NormalizeAddress(address)
+
+	tx, err := begin(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	wallet, err := scanWallet(tx.QueryRow("SELECT "+walletColumns+" FROM wallets WHERE address = $1 FOR UPDATE", address))
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, ErrWalletNotFound
+		}
+		return nil, err
+	}
+
+	balanceBig, err := parseBalance(address, wallet.Balance)
+	if err != nil {
+		return nil, err
+	}
+	// Tokens in escrow are already spoken for, so the reserve can only
+	// cover the rest of the balance.
+	heldBig, err := parseBalance(address, wallet.HeldBalance)
+	if err != nil {
+		return nil, err
+	}
+	if new(big.Int).Add(reservedBig, heldBig).Cmp(balanceBig) > 0 {
+		return nil, ErrReserveExceedsBalance
+	}
+
+	_, err = tx.Exec("UPDATE wallets SET reserved = $1 WHERE address = $2", reservedBig.String(), address)
+	if err != nil {
+		return nil, err
+	}
 
 	if err = tx.Commit(); err != nil {
-		return "", err
+		return nil, err
 	}
 
-	return newSenderBalance.String(), nil
+	wallet.Reserved = reservedBig.String()
+	return wallet, nil
 }
@@ -0,0 +1,132 @@
+package db
+
+import (
+	"context"
+	"database/sql"
+	"token-transfer-api/internal/model"
+)
+
+// The lifecycle statuses of a wallet. Wallets are active until an admin
+// freezes or closes them; a wallet that does not exist yet counts as active.
+const (
+	WalletActive = "active"
+	WalletFrozen = "frozen"
+	WalletClosed = "closed"
+)
+
+// walletStatusErrors maps the statuses that stop a wallet from sending or
+// receiving to the error a transfer involving it fails with.
+var walletStatusErrors = map[string]*AppError{
+	WalletFrozen: ErrWalletFrozen,
+	WalletClosed: ErrWalletClosed,
+}
+
+// SetWalletStatus sets the status of the wallet at address to active, frozen
+// or closed and returns the updated wallet. Only a wallet with a zero
+// balance can be closed, so closing never strands tokens; move them out
+// before closing it. Callers are responsible for checking that the requester
+// may change statuses.
+func SetWalletStatus(address, status string) (*model.Wallet, error) {
+	return SetWalletStatusContext(context.Background(), address, status)
+}
+
+func SetWalletStatusContext(ctx context.Context, address, status string) (_ *model.Wallet, err error) {
+	defer func() { err = ClassifyError(err) }()
+
+	if status != WalletActive && walletStatusErrors[status] == nil {
+		return nil, ErrInvalidWalletStatus
+	}
+
+	address = Settings.NormalizeAddress(address)
+
+	tx, err := begin(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	// Locking the row makes transfers in flight finish first; later ones
+	// wait for this transaction and then see the new status.
+	wallet, err := scanWallet(tx.QueryRow("SELECT "+walletColumns+" FROM wallets WHERE address = $1 FOR UPDATE", address))
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, ErrWalletNotFound
+		}
+		return nil, err
+	}
+
+	if status == WalletClosed && wallet.Balance != "0" {
+		return nil, ErrWalletNotEmpty.WithDetails(map[string]interface{}{"balance": wallet.Balance})
+	}
+
+	_, err = tx.Exec("UPDATE wallets SET status = $1 WHERE address = $2", status, address)
+	if err != nil {
+		return nil, err
+	}
+
+	if err = tx.Commit(); err != nil {
+		return nil, err
+	}
+
+	wallet.Status = status
+	return wallet, nil
+}
+
+// SetWalletFrozen puts the wallet at address on hold or lifts the hold and
+// returns the updated wallet. The hold is separate from the wallet's status:
+// unfreezing a closed wallet leaves it closed. Callers are responsible for
+// checking that the requester may freeze wallets.
+func SetWalletFrozen(address string, frozen bool) (*model.Wallet, error) {
+	return SetWalletFrozenContext(context.Background(), address, frozen)
+}
+
+func SetWalletFrozenContext(ctx context.Context, address string, frozen bool) (_ *model.Wallet, err error) {
+	defer func() { err = ClassifyError(err) }()
+
+	address = Settings.NormalizeAddress(address)
+
+	tx, err := begin(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	// The UPDATE waits for transfers holding the wallet's row; once it
+	// commits, every transfer that locks the row afterwards sees the hold.
+	wallet, err := scanWallet(tx.QueryRow("UPDATE wallets SET frozen = $1 WHERE address = $2 RETURNING "+walletColumns, frozen, address))
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, ErrWalletNotFound
+		}
+		return nil, err
+	}
+
+	if err = tx.Commit(); err != nil {
+		return nil, err
+	}
+	return wallet, nil
+}
+
+// checkWalletStatus returns ErrWalletFrozen or ErrWalletClosed if any of the
+// addresses belongs to a wallet that may not send or receive, because of its
+// status or a hold. Like checkBlocked it runs after the wallets are locked.
+func checkWalletStatus(tx querier, addresses ...string) error {
+	for _, address := range addresses {
+		var status string
+		var frozen bool
+		err := queryRow(tx, stmtWalletStatus, address).Scan(&status, &frozen)
+		if err == sql.ErrNoRows {
+			continue
+		}
+		if err != nil {
+			return err
+		}
+		if frozen {
+			return ErrWalletFrozen
+		}
+		if err := walletStatusErrors[status]; err != nil {
+			return err
+		}
+	}
+	return nil
+}
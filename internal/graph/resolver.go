@@ -1,29 +1,911 @@
 package graph
 
 import (
+	"context"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"log"
+	"math"
+	"os"
+	"strconv"
+	"sync/atomic"
+	"time"
+	"token-transfer-api/internal/amount"
+	"token-transfer-api/internal/auth"
 	"token-transfer-api/internal/db"
 	"token-transfer-api/internal/model"
+	"token-transfer-api/pkg/dedup"
+	"token-transfer-api/pkg/ratelimit"
+	"token-transfer-api/pkg/serializer"
+	"token-transfer-api/pkg/webhook"
 )
 
-type Resolver struct{}
+// DefaultTransferRateLimit is the number of transfers a sender may make per
+// minute when TRANSFER_RATE_LIMIT is not set.
+const DefaultTransferRateLimit = 60
+
+// DefaultDedupWindow is how long a transfer's client_request_id is
+// remembered when TRANSFER_DEDUP_WINDOW is not set.
+const DefaultDedupWindow = 5 * time.Second
+
+type Resolver struct {
+	// Decimals is the number of fractional digits of the token. Amounts are
+	// accepted and balances presented in human units with this many
+	// decimals and stored in base units.
+	Decimals int
+
+	// MinterAddress is the only caller allowed to mint and burn. Both are
+	// disabled when it is empty.
+	MinterAddress string
+
+	// AdminAddress is the only caller allowed to run the admin operations:
+	// the blocklist, wallet statuses and freezes, imports, read-only mode
+	// and the ledger integrity check. They are disabled when it is empty.
+	AdminAddress string
+
+	// RequireSignatures rejects transfers without a valid signature by the
+	// sender. Without it only transfers that carry a signature are checked.
+	RequireSignatures bool
+
+	// recent catches transfers resubmitted with the same client_request_id.
+	recent *dedup.Cache
+
+	// limiter caps the transfers per sender. Nil disables the limit.
+	limiter *ratelimit.Limiter
+
+	// senders runs one transfer per sender at a time. Nil lets them run
+	// concurrently.
+	senders *serializer.Serializer
+
+	// notifier posts completed transfers to WEBHOOK_URL. Nil when no
+	// webhook is configured.
+	notifier *webhook.Notifier
+}
+
+// readOnly makes every mutation fail with ErrReadOnly. It is shared by all
+// resolvers, so the GraphQL, REST and gRPC APIs switch together.
+var readOnly atomic.Bool
+
+// ReadOnly reports whether read-only mode is on.
+func ReadOnly() bool {
+	return readOnly.Load()
+}
+
+// SetReadOnly turns read-only mode on or off.
+func SetReadOnly(enabled bool) {
+	readOnly.Store(enabled)
+}
+
+// writable fails with ErrReadOnly while read-only mode is on. Every mutation
+// checks it first, before validating its input or touching the database.
+func writable() error {
+	if readOnly.Load() {
+		return db.ErrReadOnly
+	}
+	return nil
+}
+
+// NewResolver creates a Resolver configured from the environment. READ_ONLY,
+// when set, turns read-only mode on or off for every resolver.
+func NewResolver() (*Resolver, error) {
+	r := &Resolver{}
+
+	if v := os.Getenv("READ_ONLY"); v != "" {
+		enabled, err := strconv.ParseBool(v)
+		if err != nil {
+			return nil, fmt.Errorf("invalid READ_ONLY %q", v)
+		}
+		SetReadOnly(enabled)
+	}
+
+	if v := os.Getenv("TOKEN_DECIMALS"); v != "" {
+		decimals, err := strconv.Atoi(v)
+		if err != nil || decimals < 0 {
+			return nil, fmt.Errorf("invalid TOKEN_DECIMALS %q", v)
+		}
+		r.Decimals = decimals
+	}
+
+	r.MinterAddress = os.Getenv("MINTER_ADDRESS")
+	r.AdminAddress = os.Getenv("ADMIN_ADDRESS")
+
+	switch v := os.Getenv("SIGNED_TRANSFERS"); v {
+	case "", "optional":
+	case "required":
+		r.RequireSignatures = true
+	default:
+		return nil, fmt.Errorf("invalid SIGNED_TRANSFERS %q", v)
+	}
+
+	window := DefaultDedupWindow
+	if v := os.Getenv("TRANSFER_DEDUP_WINDOW"); v != "" {
+		d, err := time.ParseDuration(v)
+		if err != nil || d < 0 {
+			return nil, fmt.Errorf("invalid TRANSFER_DEDUP_WINDOW %q", v)
+		}
+		window = d
+	}
+	if window > 0 {
+		r.recent = dedup.New(window)
+	}
+
+	rate := DefaultTransferRateLimit
+	if v := os.Getenv("TRANSFER_RATE_LIMIT"); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil || n < 0 {
+			return nil, fmt.Errorf("invalid TRANSFER_RATE_LIMIT %q", v)
+		}
+		rate = n
+	}
+	if rate > 0 {
+		r.limiter = ratelimit.PerMinute(rate)
+	}
+
+	if v := os.Getenv("TRANSFER_SERIALIZE_SENDERS"); v != "" {
+		serialize, err := strconv.ParseBool(v)
+		if err != nil {
+			return nil, fmt.Errorf("invalid TRANSFER_SERIALIZE_SENDERS %q", v)
+		}
+		if serialize {
+			r.senders = serializer.New()
+		}
+	}
+
+	webhookConfig, err := webhook.ConfigFromEnv()
+	if err != nil {
+		return nil, err
+	}
+	if webhookConfig.URL != "" {
+		r.notifier = webhook.NewNotifier(webhookConfig)
+	}
+
+	return r, nil
+}
+
+type callerKey struct{}
+
+// WithCaller returns a context carrying the address the request was made on
+// behalf of. The transports set it from the API key the request was
+// authenticated with, never from anything the client merely claims.
+func WithCaller(ctx context.Context, address string) context.Context {
+	return context.WithValue(ctx, callerKey{}, address)
+}
+
+// CallerFromContext returns the caller address stored by WithCaller.
+func CallerFromContext(ctx context.Context) string {
+	caller, _ := ctx.Value(callerKey{}).(string)
+	return caller
+}
+
+// requireAdmin fails with ErrUnauthorized unless the request acts on behalf
+// of AdminAddress.
+func (r *Resolver) requireAdmin(ctx context.Context) error {
+	return requireCaller(ctx, r.AdminAddress)
+}
+
+// requireMinter fails with ErrUnauthorized unless the request acts on behalf
+// of MinterAddress.
+func (r *Resolver) requireMinter(ctx context.Context) error {
+	return requireCaller(ctx, r.MinterAddress)
+}
+
+// requireOwnerOrAdmin fails with ErrUnauthorized unless the request acts on
+// behalf of address itself or of AdminAddress.
+func (r *Resolver) requireOwnerOrAdmin(ctx context.Context, address string) error {
+	if requireCaller(ctx, address) == nil {
+		return nil
+	}
+	return r.requireAdmin(ctx)
+}
+
+// rejectUnsigned fails with ErrBadSignature while SIGNED_TRANSFERS=required.
+// Mutations that debit a wallet without carrying its owner's signature call
+// it, so tokens cannot leave a wallet unsigned by any route but a transfer.
+func (r *Resolver) rejectUnsigned() error {
+	if r.RequireSignatures {
+		return db.ErrBadSignature
+	}
+	return nil
+}
+
+// requireCaller fails with ErrUnauthorized unless the authenticated caller is
+// address. Both are normalized, so with ADDRESS_CASE_INSENSITIVE the case
+// they are written in does not matter. An empty address authorizes no one.
+func requireCaller(ctx context.Context, address string) error {
+	caller := CallerFromContext(ctx)
+	if address == "" || caller == "" || db.Settings.NormalizeAddress(caller) != db.Settings.NormalizeAddress(address) {
+		return db.ErrUnauthorized
+	}
+	return nil
+}
 
 type TransferArgs struct {
-	FromAddress string `json:"from_address"`
-	ToAddress   string `json:"to_address"`
-	Amount      string `json:"amount"`
+	FromAddress     string `json:"from_address"`
+	ToAddress       string `json:"to_address"`
+	Amount          string `json:"amount"`
+	ClientRequestID string `json:"client_request_id"`
+	DryRun          bool   `json:"dry_run"`
+	Memo            string `json:"memo"`
+	// ExpectedNonce, when set, must equal the sender's nonce for the
+	// transfer to go ahead.
+	ExpectedNonce *int64 `json:"expected_nonce"`
+	// Signature is the sender's signature of the transfer message built
+	// from the addresses, the amount and ExpectedNonce.
+	Signature string `json:"signature"`
+	// RequireMinBalance, when set, is the balance the sender must at least
+	// have for the transfer to go ahead.
+	RequireMinBalance string `json:"require_min_balance"`
+	// Token is the token to transfer, or empty for the primary token.
+	Token string `json:"token"`
 }
 
-func (r *Resolver) Transfer(args TransferArgs) (*model.TransferResult, error) {
-	balance, err := db.TransferTokens(args.FromAddress, args.ToAddress, args.Amount)
+// Transfer executes a transfer, or only simulates it for a dry run. When the
+// client supplies a request id, a resubmission with the same id from the same
+// sender within the dedup window returns the first result instead of
+// transferring again. Dry runs are never remembered. With
+// TRANSFER_SERIALIZE_SENDERS, transfers from one sender run one at a time.
+func (r *Resolver) Transfer(ctx context.Context, args TransferArgs) (*model.TransferResult, error) {
+	if err := writable(); err != nil {
+		return nil, err
+	}
+
+	base, err := r.ParseAmount(args.Amount)
+	if err == nil && base == "0" {
+		// Rejected here rather than by the transfer so it costs no rate
+		// limit token
+		err = db.ErrInvalidAmount
+	}
+	if err == nil {
+		args.Amount = base
+		err = r.checkSignature(args)
+	}
+	if err == nil && args.RequireMinBalance != "" {
+		args.RequireMinBalance, err = r.ParseAmount(args.RequireMinBalance)
+	}
 	if err != nil {
+		var appErr *db.AppError
+		if args.DryRun && errors.As(err, &appErr) {
+			return &model.TransferResult{FailureCode: appErr.Code, FailureMessage: appErr.Message}, nil
+		}
 		return nil, err
 	}
 
-	return &model.TransferResult{
-		Balance: balance,
-	}, nil
+	if args.DryRun {
+		return db.SimulateTokenTransfer(ctx, args.Token, args.FromAddress, args.ToAddress, args.Amount, args.Memo, args.ExpectedNonce, args.RequireMinBalance)
+	}
+
+	// Only transfers that passed validation and will be made count against
+	// the sender's rate limit
+	if r.limiter != nil {
+		if ok, wait := r.limiter.Allow(db.Settings.NormalizeAddress(args.FromAddress)); !ok {
+			return nil, db.ErrRateLimited.WithDetails(map[string]interface{}{
+				"retry_after": int(math.Ceil(wait.Seconds())),
+			})
+		}
+	}
+
+	// Queue behind the sender's other transfers in this process instead of
+	// waiting on its row lock and retrying conflicts
+	if r.senders != nil {
+		release, err := r.senders.Acquire(ctx, db.Settings.NormalizeAddress(args.FromAddress))
+		if err != nil {
+			return nil, err
+		}
+		defer release()
+	}
+
+	if args.ClientRequestID == "" || r.recent == nil {
+		return r.executeTransfer(ctx, args)
+	}
+
+	key := db.Settings.NormalizeAddress(args.FromAddress) + "|" + args.ClientRequestID
+	result, duplicate, err := r.recent.Do(key, func() (interface{}, error) {
+		result, err := r.executeTransfer(ctx, args)
+		if err != nil {
+			return nil, err
+		}
+		result.ClientRequestID = args.ClientRequestID
+		return result, nil
+	})
+	if duplicate {
+		log.Printf("transfer client_request_id=%s from=%s duplicate=true", args.ClientRequestID, args.FromAddress)
+	}
+	if err != nil {
+		return nil, err
+	}
+	return result.(*model.TransferResult), nil
 }
 
-func (r *Resolver) GetWallet(address string) (*model.Wallet, error) {
-	return db.GetWallet(address)
+// checkSignature verifies the signature of a transfer whose amount is in
+// base units. The signed nonce is ExpectedNonce, which the transfer then has
+// to match, so a signed transfer cannot be replayed once it went through.
+// The token is not part of the signed message, so only transfers of the
+// primary token can be signed.
+func (r *Resolver) checkSignature(args TransferArgs) error {
+	if args.Signature == "" && !r.RequireSignatures {
+		return nil
+	}
+	if !db.Settings.IsPrimaryToken(args.Token) {
+		return db.ErrUnsupportedToken
+	}
+	if args.Signature == "" || args.ExpectedNonce == nil {
+		return db.ErrBadSignature
+	}
+	if err := auth.VerifyTransfer(args.Signature, args.FromAddress, args.ToAddress, args.Amount, *args.ExpectedNonce); err != nil {
+		return db.ErrBadSignature
+	}
+	return nil
+}
+
+// executeTransfer makes the transfer and, once it is committed, queues the
+// webhook notification. Transfers in a caller's transaction are not
+// notified since they may still be rolled back.
+func (r *Resolver) executeTransfer(ctx context.Context, args TransferArgs) (*model.TransferResult, error) {
+	result, err := db.ExecuteTokenTransfer(ctx, args.Token, args.FromAddress, args.ToAddress, args.Amount, args.Memo, args.ExpectedNonce, args.RequireMinBalance)
+	if err != nil {
+		return nil, err
+	}
+
+	if r.notifier != nil && !db.InTx(ctx) {
+		notification := model.TransferNotification{
+			From:             db.Settings.NormalizeAddress(args.FromAddress),
+			To:               db.Settings.NormalizeAddress(args.ToAddress),
+			Amount:           args.Amount,
+			FromBalanceAfter: result.Balance,
+			Timestamp:        time.Now().UTC(),
+		}
+		if !db.Settings.IsPrimaryToken(args.Token) {
+			notification.Token = args.Token
+		}
+		r.notifier.Notify(notification)
+	}
+	return result, nil
+}
+
+// ScheduleTransfer records a transfer to be executed by the scheduler at
+// executeAt. Scheduling counts against the sender's rate limit like a
+// transfer does, once the transfer passed validation.
+func (r *Resolver) ScheduleTransfer(ctx context.Context, fromAddress, toAddress, amount string, executeAt time.Time) (*model.ScheduledTransfer, error) {
+	if err := writable(); err != nil {
+		return nil, err
+	}
+	if err := r.rejectUnsigned(); err != nil {
+		return nil, err
+	}
+
+	base, err := r.ParseAmount(amount)
+	if err != nil {
+		return nil, err
+	}
+	if err := db.CheckTransfer(fromAddress, toAddress, base); err != nil {
+		return nil, err
+	}
+
+	if r.limiter != nil {
+		if ok, wait := r.limiter.Allow(db.Settings.NormalizeAddress(fromAddress)); !ok {
+			return nil, db.ErrRateLimited.WithDetails(map[string]interface{}{
+				"retry_after": int(math.Ceil(wait.Seconds())),
+			})
+		}
+	}
+	return db.ScheduleTransferContext(ctx, fromAddress, toAddress, base, executeAt)
+}
+
+func (r *Resolver) GetScheduledTransfer(ctx context.Context, id int64) (*model.ScheduledTransfer, error) {
+	return db.GetScheduledTransferContext(ctx, id)
+}
+
+// Mint creates new units of token, or of the primary token when it is
+// empty, in the receiver's wallet. Only the configured minter may call it.
+func (r *Resolver) Mint(ctx context.Context, toAddress, amount, token string) (*model.Wallet, error) {
+	if err := writable(); err != nil {
+		return nil, err
+	}
+	if err := r.requireMinter(ctx); err != nil {
+		return nil, err
+	}
+	base, err := r.ParseAmount(amount)
+	if err != nil {
+		return nil, err
+	}
+	return db.MintTokenContext(ctx, token, toAddress, base)
+}
+
+// Burn destroys tokens from a wallet. Only the configured minter may call it.
+func (r *Resolver) Burn(ctx context.Context, fromAddress, amount string) (string, error) {
+	if err := writable(); err != nil {
+		return "", err
+	}
+	if err := r.requireMinter(ctx); err != nil {
+		return "", err
+	}
+	if err := r.rejectUnsigned(); err != nil {
+		return "", err
+	}
+	base, err := r.ParseAmount(amount)
+	if err != nil {
+		return "", err
+	}
+	return db.BurnContext(ctx, fromAddress, base)
+}
+
+// BlockAddress adds an address to the compliance blocklist. Only the
+// configured admin may call it.
+func (r *Resolver) BlockAddress(ctx context.Context, address string) (bool, error) {
+	if err := writable(); err != nil {
+		return false, err
+	}
+	if err := r.requireAdmin(ctx); err != nil {
+		return false, err
+	}
+	if err := db.BlockAddressContext(ctx, address); err != nil {
+		return false, err
+	}
+	return true, nil
+}
+
+// UnblockAddress removes an address from the blocklist. Only the configured
+// admin may call it.
+func (r *Resolver) UnblockAddress(ctx context.Context, address string) (bool, error) {
+	if err := writable(); err != nil {
+		return false, err
+	}
+	if err := r.requireAdmin(ctx); err != nil {
+		return false, err
+	}
+	return db.UnblockAddressContext(ctx, address)
+}
+
+// SetWalletStatus freezes, closes or reactivates a wallet. Only the
+// configured admin may call it.
+func (r *Resolver) SetWalletStatus(ctx context.Context, address, status string) (*model.Wallet, error) {
+	if err := writable(); err != nil {
+		return nil, err
+	}
+	if err := r.requireAdmin(ctx); err != nil {
+		return nil, err
+	}
+	return db.SetWalletStatusContext(ctx, address, status)
+}
+
+// FreezeWallet puts a wallet on hold so it can neither send nor receive.
+// Only the configured admin may call it.
+func (r *Resolver) FreezeWallet(ctx context.Context, address string) (*model.Wallet, error) {
+	if err := writable(); err != nil {
+		return nil, err
+	}
+	if err := r.requireAdmin(ctx); err != nil {
+		return nil, err
+	}
+	return db.SetWalletFrozenContext(ctx, address, true)
+}
+
+// UnfreezeWallet lifts the hold set by FreezeWallet. Only the configured
+// admin may call it.
+func (r *Resolver) UnfreezeWallet(ctx context.Context, address string) (*model.Wallet, error) {
+	if err := writable(); err != nil {
+		return nil, err
+	}
+	if err := r.requireAdmin(ctx); err != nil {
+		return nil, err
+	}
+	return db.SetWalletFrozenContext(ctx, address, false)
+}
+
+// SetReadOnlyMode turns read-only mode on or off and returns the new state.
+// Only the configured admin may call it. Unlike other mutations it works
+// while read-only mode is on, so writes can be resumed.
+func (r *Resolver) SetReadOnlyMode(ctx context.Context, enabled bool) (bool, error) {
+	if err := r.requireAdmin(ctx); err != nil {
+		return false, err
+	}
+	SetReadOnly(enabled)
+	log.Printf("Read-only mode set to %t by %s", enabled, CallerFromContext(ctx))
+	return enabled, nil
+}
+
+// GetWallet returns the wallet at address, or nil without an error if the
+// address has never been seen. Malformed addresses fail with
+// ErrInvalidAddress before the database is asked.
+func (r *Resolver) GetWallet(ctx context.Context, address string) (*model.Wallet, error) {
+	if !db.ValidAddress(address) {
+		return nil, db.ErrInvalidAddress
+	}
+	return db.GetWalletContext(ctx, address)
+}
+
+// GetTokenWallet returns the wallet at address with its balance of token,
+// or of the primary token when it is empty. Like GetWallet it rejects
+// malformed addresses.
+func (r *Resolver) GetTokenWallet(ctx context.Context, address, token string) (*model.Wallet, error) {
+	if !db.ValidAddress(address) {
+		return nil, db.ErrInvalidAddress
+	}
+	return db.GetTokenWalletContext(ctx, address, token)
+}
+
+// GetWalletOrZero returns the wallet at address, or an empty wallet with a
+// zero balance if the address has never been seen. Like GetWallet it rejects
+// malformed addresses.
+func (r *Resolver) GetWalletOrZero(ctx context.Context, address string) (*model.Wallet, error) {
+	if !db.ValidAddress(address) {
+		return nil, db.ErrInvalidAddress
+	}
+
+	wallet, err := db.GetWalletContext(ctx, address)
+	if err != nil {
+		return nil, err
+	}
+	if wallet == nil {
+		wallet = &model.Wallet{
+			Address:     db.Settings.NormalizeAddress(address),
+			Balance:     "0",
+			Reserved:    "0",
+			HeldBalance: "0",
+			Status:      db.WalletActive,
+		}
+	}
+	return wallet, nil
+}
+
+// GetBalances returns the wallets at the given addresses in one round trip,
+// in the order requested. Unknown addresses get an empty wallet with a zero
+// balance when includeUnknown is set and are left out otherwise. Like
+// GetWalletOrZero it rejects malformed addresses.
+func (r *Resolver) GetBalances(ctx context.Context, addresses []string, includeUnknown bool) ([]model.Wallet, error) {
+	for _, address := range addresses {
+		if !db.ValidAddress(address) {
+			return nil, db.ErrInvalidAddress.WithDetails(map[string]interface{}{"address": address})
+		}
+	}
+
+	wallets, err := db.GetWalletsContext(ctx, addresses)
+	if err != nil || !includeUnknown {
+		return wallets, err
+	}
+
+	known := make(map[string]model.Wallet, len(wallets))
+	for _, wallet := range wallets {
+		known[wallet.Address] = wallet
+	}
+
+	all := make([]model.Wallet, 0, len(addresses))
+	seen := make(map[string]bool, len(addresses))
+	for _, address := range addresses {
+		address = db.Settings.NormalizeAddress(address)
+		if seen[address] {
+			continue
+		}
+		seen[address] = true
+
+		wallet, ok := known[address]
+		if !ok {
+			wallet = model.Wallet{Address: address, Balance: "0", Reserved: "0", HeldBalance: "0", Status: db.WalletActive}
+		}
+		all = append(all, wallet)
+	}
+	return all, nil
+}
+
+func (r *Resolver) ListWallets(ctx context.Context, limit, offset int, order string) ([]model.Wallet, error) {
+	return db.ListWalletsContext(ctx, limit, offset, order)
+}
+
+func (r *Resolver) ListWalletsWithCount(ctx context.Context, limit, offset int, order string) (*model.WalletConnection, error) {
+	return db.ListWalletsWithCountContext(ctx, limit, offset, order)
+}
+
+func (r *Resolver) ListDormantWallets(ctx context.Context, inactiveSince time.Time, limit, offset int) ([]model.Wallet, error) {
+	return db.ListDormantWalletsContext(ctx, inactiveSince, limit, offset)
+}
+
+func (r *Resolver) GetNeighbors(ctx context.Context, address string, limit int) ([]model.Neighbor, error) {
+	return db.GetNeighborsContext(ctx, address, limit)
+}
+
+func (r *Resolver) GetTopHolders(ctx context.Context, limit int, excludeZeroAddress bool) ([]model.Holder, error) {
+	return db.GetTopHoldersContext(ctx, limit, excludeZeroAddress)
+}
+
+func (r *Resolver) GetWalletStats(ctx context.Context, address string) (*model.WalletStats, error) {
+	return db.GetWalletStatsContext(ctx, address)
+}
+
+func (r *Resolver) GetTransferVolume(ctx context.Context, interval string, since, until *time.Time) ([]model.TransferVolume, error) {
+	return db.GetTransferVolumeContext(ctx, interval, since, until)
+}
+
+func (r *Resolver) GetBalanceHistory(ctx context.Context, address string, since, until *time.Time) ([]model.BalanceSnapshot, error) {
+	return db.GetBalanceHistoryContext(ctx, address, since, until)
+}
+
+func (r *Resolver) GetTotalSupply(ctx context.Context) (string, error) {
+	return db.GetTotalSupplyContext(ctx)
+}
+
+// LedgerIntegrity checks that wallet balances add up to the recorded supply.
+// Only the configured admin may call it.
+func (r *Resolver) LedgerIntegrity(ctx context.Context) (*model.LedgerIntegrity, error) {
+	if err := r.requireAdmin(ctx); err != nil {
+		return nil, err
+	}
+	return db.CheckLedgerIntegrityContext(ctx)
+}
+
+// ImportWallets sets the balances of many wallets at once, creating those
+// that do not exist. Balances are in human units like transfer amounts. Only
+// the configured admin may call it.
+func (r *Resolver) ImportWallets(ctx context.Context, rows []model.WalletImportRow, skipInvalid bool) ([]model.WalletImportResult, error) {
+	if err := writable(); err != nil {
+		return nil, err
+	}
+	if err := r.requireAdmin(ctx); err != nil {
+		return nil, err
+	}
+
+	// Rows whose balance does not parse go through with an empty balance,
+	// which the import rejects, and get the parse error reported instead.
+	base := make([]model.WalletImportRow, len(rows))
+	parseErrs := make(map[int]*db.AppError)
+	for i, row := range rows {
+		balance, err := r.ParseAmount(row.Balance)
+		if err != nil {
+			parseErrs[i] = err.(*db.AppError)
+		}
+		base[i] = model.WalletImportRow{Address: row.Address, Balance: balance}
+	}
+
+	results, err := db.ImportWalletsContext(ctx, base, skipInvalid)
+	var appErr *db.AppError
+	if errors.As(err, &appErr) && appErr.Code == db.ErrImportRejected.Code {
+		results, _ = appErr.Details["results"].([]model.WalletImportResult)
+	}
+	for i, parseErr := range parseErrs {
+		if i < len(results) && results[i].Code == db.ErrInvalidAmount.Code {
+			results[i].Code = parseErr.Code
+			results[i].Message = parseErr.Message
+		}
+	}
+	if err != nil {
+		return nil, err
+	}
+	return results, nil
+}
+
+func (r *Resolver) GetWalletCount(ctx context.Context) (int64, error) {
+	return db.GetWalletCountContext(ctx)
+}
+
+// SetReserve sets the part of a wallet's balance transfers may not touch.
+// Only the wallet itself or the configured admin may set it.
+func (r *Resolver) SetReserve(ctx context.Context, address, amount string) (*model.Wallet, error) {
+	if err := writable(); err != nil {
+		return nil, err
+	}
+	if err := r.requireOwnerOrAdmin(ctx, address); err != nil {
+		return nil, err
+	}
+	base, err := r.ParseAmount(amount)
+	if err != nil {
+		return nil, db.ErrInvalidReserve
+	}
+	return db.SetReserveContext(ctx, address, base)
+}
+
+// RefundTransfer moves the amount of a transfer back to its sender. Only
+// the configured admin may call it.
+func (r *Resolver) RefundTransfer(ctx context.Context, transferID int64) (*model.RefundResult, error) {
+	if err := writable(); err != nil {
+		return nil, err
+	}
+	if err := r.requireAdmin(ctx); err != nil {
+		return nil, err
+	}
+	if err := r.rejectUnsigned(); err != nil {
+		return nil, err
+	}
+	return db.RefundTransferContext(ctx, transferID)
+}
+
+// Swap exchanges amountA from wallet A for amountB from wallet B. Both
+// wallets send a transfer, so once both legs passed validation each counts
+// against its rate limit.
+func (r *Resolver) Swap(ctx context.Context, walletA, walletB, amountA, amountB string) (*model.SwapResult, error) {
+	if err := writable(); err != nil {
+		return nil, err
+	}
+	if err := r.rejectUnsigned(); err != nil {
+		return nil, err
+	}
+
+	baseA, err := r.ParseAmount(amountA)
+	if err != nil {
+		return nil, err
+	}
+	baseB, err := r.ParseAmount(amountB)
+	if err != nil {
+		return nil, err
+	}
+	if err := db.CheckTransfer(walletA, walletB, baseA); err != nil {
+		return nil, err
+	}
+	if err := db.CheckTransfer(walletB, walletA, baseB); err != nil {
+		return nil, err
+	}
+
+	if r.limiter != nil {
+		for _, address := range []string{walletA, walletB} {
+			if ok, wait := r.limiter.Allow(db.Settings.NormalizeAddress(address)); !ok {
+				return nil, db.ErrRateLimited.WithDetails(map[string]interface{}{
+					"retry_after": int(math.Ceil(wait.Seconds())),
+				})
+			}
+		}
+	}
+	return db.SwapContext(ctx, walletA, walletB, baseA, baseB)
+}
+
+// Sweep moves the whole spendable balance of fromAddress to toAddress. Since
+// it empties the wallet, it must always be signed by the sender over the
+// sweep message with expectedNonce, which the sweep then has to match like a
+// signed transfer. It sends a transfer, so it counts against the sender's
+// rate limit.
+func (r *Resolver) Sweep(ctx context.Context, fromAddress, toAddress string, expectedNonce *int64, signature string) (*model.SweepResult, error) {
+	if err := writable(); err != nil {
+		return nil, err
+	}
+	if signature == "" || expectedNonce == nil {
+		return nil, db.ErrBadSignature
+	}
+	if err := auth.VerifySweep(signature, fromAddress, toAddress, *expectedNonce); err != nil {
+		return nil, db.ErrBadSignature
+	}
+	if r.limiter != nil {
+		if ok, wait := r.limiter.Allow(db.Settings.NormalizeAddress(fromAddress)); !ok {
+			return nil, db.ErrRateLimited.WithDetails(map[string]interface{}{
+				"retry_after": int(math.Ceil(wait.Seconds())),
+			})
+		}
+	}
+	return db.SweepContext(ctx, fromAddress, toAddress, expectedNonce)
+}
+
+func (r *Resolver) CreateHold(ctx context.Context, fromAddress, amount string) (*model.Hold, error) {
+	if err := writable(); err != nil {
+		return nil, err
+	}
+	if err := r.rejectUnsigned(); err != nil {
+		return nil, err
+	}
+	base, err := r.ParseAmount(amount)
+	if err != nil {
+		return nil, err
+	}
+	return db.CreateHoldContext(ctx, fromAddress, base)
+}
+
+func (r *Resolver) ReleaseHold(ctx context.Context, holdID int64, toAddress string) (*model.Hold, error) {
+	if err := writable(); err != nil {
+		return nil, err
+	}
+	if err := r.rejectUnsigned(); err != nil {
+		return nil, err
+	}
+	if err := r.requireHoldOwnerOrAdmin(ctx, holdID); err != nil {
+		return nil, err
+	}
+	return db.ReleaseHoldContext(ctx, holdID, toAddress)
+}
+
+func (r *Resolver) CancelHold(ctx context.Context, holdID int64) (*model.Hold, error) {
+	if err := writable(); err != nil {
+		return nil, err
+	}
+	if err := r.requireHoldOwnerOrAdmin(ctx, holdID); err != nil {
+		return nil, err
+	}
+	return db.CancelHoldContext(ctx, holdID)
+}
+
+// requireHoldOwnerOrAdmin fails with ErrUnauthorized unless the request acts
+// on behalf of the wallet the hold takes its tokens from or of AdminAddress.
+// Requests acting for no one are refused before the hold is looked up, so
+// they cannot learn which holds exist.
+func (r *Resolver) requireHoldOwnerOrAdmin(ctx context.Context, holdID int64) error {
+	if CallerFromContext(ctx) == "" {
+		return db.ErrUnauthorized
+	}
+	if r.requireAdmin(ctx) == nil {
+		return nil
+	}
+	hold, err := db.GetHoldContext(ctx, holdID)
+	if err != nil {
+		return err
+	}
+	return requireCaller(ctx, hold.FromAddress)
+}
+
+func (r *Resolver) GetTransfer(ctx context.Context, id int64) (*model.Transfer, error) {
+	return db.GetTransferByIDContext(ctx, id)
+}
+
+// GetWalletTransfers returns the latest transfers sent or received by
+// address, newest first, for the transfers field of a wallet.
+func (r *Resolver) GetWalletTransfers(ctx context.Context, address string, limit int) ([]model.Transfer, error) {
+	return db.GetTransfersContext(ctx, address, limit)
+}
+
+// ListTransfers returns a page of transfers, newest first, starting after the
+// given cursor. An empty cursor starts from the newest transfer.
+func (r *Resolver) ListTransfers(ctx context.Context, first int, after string) (*model.TransferConnection, error) {
+	var beforeID int64
+	if after != "" {
+		id, err := DecodeCursor(after)
+		if err != nil {
+			return nil, err
+		}
+		beforeID = id
+	}
+
+	transfers, hasNext, err := db.ListTransfersContext(ctx, first, beforeID)
+	if err != nil {
+		return nil, err
+	}
+
+	conn := &model.TransferConnection{
+		Edges:    make([]model.TransferEdge, len(transfers)),
+		PageInfo: model.PageInfo{HasNextPage: hasNext},
+	}
+	for i := range transfers {
+		conn.Edges[i] = model.TransferEdge{
+			Cursor: EncodeCursor(transfers[i].ID),
+			Node:   &transfers[i],
+		}
+	}
+	if len(conn.Edges) > 0 {
+		conn.PageInfo.EndCursor = conn.Edges[len(conn.Edges)-1].Cursor
+	}
+	return conn, nil
+}
+
+// EncodeCursor turns a transfer id into an opaque pagination cursor.
+func EncodeCursor(id int64) string {
+	return base64.StdEncoding.EncodeToString([]byte(strconv.FormatInt(id, 10)))
+}
+
+// DecodeCursor returns the transfer id held by a cursor from EncodeCursor.
+func DecodeCursor(cursor string) (int64, error) {
+	raw, err := base64.StdEncoding.DecodeString(cursor)
+	if err != nil {
+		return 0, db.ErrInvalidCursor
+	}
+	id, err := strconv.ParseInt(string(raw), 10, 64)
+	if err != nil || id <= 0 {
+		return 0, db.ErrInvalidCursor
+	}
+	return id, nil
+}
+
+// ParseAmount converts a human amount from a client into base units. It
+// returns ErrInvalidAmount for malformed input and ErrAmountTooPrecise for
+// more fractional digits than Decimals.
+func (r *Resolver) ParseAmount(human string) (string, error) {
+	base, err := amount.Parse(human, r.Decimals)
+	if err == amount.ErrTooPrecise {
+		return "", db.ErrAmountTooPrecise
+	}
+	if err != nil {
+		return "", db.ErrInvalidAmount
+	}
+	return base, nil
+}
+
+// FormatBalance renders a stored base-unit balance in human units, or returns
+// it untouched when raw is set.
+func (r *Resolver) FormatBalance(balance string, raw bool) string {
+	if raw {
+		return balance
+	}
+	return amount.Format(balance, r.Decimals)
 }
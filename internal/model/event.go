@@ -0,0 +1,33 @@
+package model
+
+import (
+	"encoding/json"
+	"time"
+)
+
+// TransferEvent is an entry of the transfer outbox: a transfer waiting to be
+// delivered to the events webhook, or already delivered.
+type TransferEvent struct {
+	ID         int64           `json:"id"`
+	TransferID int64           `json:"transfer_id"`
+	Payload    json.RawMessage `json:"payload"`
+	// Attempts counts the deliveries tried so far. A failed attempt is
+	// retried at NextAttemptAt and LastError says why it failed.
+	Attempts      int        `json:"attempts"`
+	NextAttemptAt time.Time  `json:"next_attempt_at"`
+	LastError     *string    `json:"last_error"`
+	PublishedAt   *time.Time `json:"published_at"`
+	CreatedAt     time.Time  `json:"created_at"`
+}
+
+// TransferNotification is the body of the webhook posted after a transfer
+// committed. Amounts are in base units.
+type TransferNotification struct {
+	From             string `json:"from"`
+	To               string `json:"to"`
+	Amount           string `json:"amount"`
+	FromBalanceAfter string `json:"from_balance_after"`
+	// Token is the token moved, or empty for the primary token.
+	Token     string    `json:"token,omitempty"`
+	Timestamp time.Time `json:"timestamp"`
+}
@@ -0,0 +1,19 @@
+package model
+
+import "time"
+
+// Hold is an amount a wallet has put in escrow. The tokens stay in the
+// sender's balance but cannot be transferred until the hold is released to
+// a receiver or cancelled.
+type Hold struct {
+	ID          int64  `json:"id"`
+	FromAddress string `json:"from_address"`
+	Amount      string `json:"amount"`
+	// Status is HELD until the hold is settled, then RELEASED or CANCELLED.
+	// A released hold names its receiver and the transfer that paid it.
+	Status     string     `json:"status"`
+	ToAddress  *string    `json:"to_address"`
+	TransferID *int64     `json:"transfer_id"`
+	SettledAt  *time.Time `json:"settled_at"`
+	CreatedAt  time.Time  `json:"created_at"`
+}
@@ -0,0 +1,9 @@
+package model
+
+// Holder is a wallet in the list of largest holders with its share of the
+// supply in basis points, rounded down, so 2500 is 25%.
+type Holder struct {
+	Address  string `json:"address"`
+	Balance  string `json:"balance"`
+	ShareBps int64  `json:"share_bps"`
+}
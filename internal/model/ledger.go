@@ -0,0 +1,10 @@
+package model
+
+// LedgerIntegrity compares the tokens held by wallets with the supply implied
+// by the recorded mints and burns. Amounts are decimal strings because they
+// may not fit in an int64.
+type LedgerIntegrity struct {
+	Consistent bool   `json:"consistent"`
+	WalletSum  string `json:"wallet_sum"`
+	Expected   string `json:"expected"`
+}
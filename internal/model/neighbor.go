@@ -0,0 +1,8 @@
+package model
+
+// Neighbor is an address that has exchanged tokens with another address,
+// together with the total amount moved between the two in either direction.
+type Neighbor struct {
+	Address string `json:"address"`
+	Total   string `json:"total"`
+}
@@ -0,0 +1,21 @@
+package model
+
+import "time"
+
+// ScheduledTransfer is a transfer recorded ahead of time and executed by the
+// scheduler once ExecuteAt has passed.
+type ScheduledTransfer struct {
+	ID          int64     `json:"id"`
+	FromAddress string    `json:"from_address"`
+	ToAddress   string    `json:"to_address"`
+	Amount      string    `json:"amount"`
+	ExecuteAt   time.Time `json:"execute_at"`
+	// Status is PENDING until the scheduler has run the transfer, then
+	// EXECUTED or FAILED. FailureCode and FailureMessage say why a transfer
+	// failed.
+	Status         string     `json:"status"`
+	FailureCode    *string    `json:"failure_code"`
+	FailureMessage *string    `json:"failure_message"`
+	ExecutedAt     *time.Time `json:"executed_at"`
+	CreatedAt      time.Time  `json:"created_at"`
+}
@@ -0,0 +1,21 @@
+package model
+
+import "time"
+
+// WalletStats aggregates the transfers an address has sent and received.
+// Totals are decimal strings because they may not fit in an int64.
+type WalletStats struct {
+	Address          string `json:"address"`
+	TotalSent        string `json:"total_sent"`
+	TotalReceived    string `json:"total_received"`
+	TransferCountOut int64  `json:"transfer_count_out"`
+	TransferCountIn  int64  `json:"transfer_count_in"`
+}
+
+// TransferVolume is the number and total amount of the transfers made within
+// one time bucket, starting at Bucket.
+type TransferVolume struct {
+	Bucket      time.Time `json:"bucket"`
+	TotalAmount string    `json:"total_amount"`
+	Count       int64     `json:"count"`
+}
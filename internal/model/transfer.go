@@ -0,0 +1,64 @@
+package model
+
+import "time"
+
+type Transfer struct {
+	ID          int64  `json:"id"`
+	FromAddress string `json:"from_address"`
+	ToAddress   string `json:"to_address"`
+	Amount      string `json:"amount"`
+	RefundOf    *int64 `json:"refund_of"`
+	// SwapOf is the first leg of the swap this transfer is the second leg
+	// of, or nil.
+	SwapOf *int64 `json:"swap_of"`
+	// FromBalanceAfter and ToBalanceAfter are the two wallets' balances
+	// right after the transfer. They are nil for the zero address side of
+	// mints and burns.
+	FromBalanceAfter *string `json:"from_balance_after"`
+	ToBalanceAfter   *string `json:"to_balance_after"`
+	// Memo is the note the sender attached, or nil without one.
+	Memo *string `json:"memo"`
+	// Token is the token moved, or empty for the primary token.
+	Token     string    `json:"token,omitempty"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+type RefundResult struct {
+	Transfer    *Transfer `json:"transfer"`
+	FromBalance string    `json:"from_balance"`
+	ToBalance   string    `json:"to_balance"`
+}
+
+// SweepResult holds the transfer a sweep recorded and the two wallets'
+// balances after it.
+type SweepResult struct {
+	Transfer    *Transfer `json:"transfer"`
+	FromBalance string    `json:"from_balance"`
+	ToBalance   string    `json:"to_balance"`
+}
+
+// SwapResult holds both legs of a swap and the two wallets' balances after
+// it: BalanceA is wallet A's, which sent TransferA, and BalanceB wallet B's.
+type SwapResult struct {
+	TransferA *Transfer `json:"transfer_a"`
+	TransferB *Transfer `json:"transfer_b"`
+	BalanceA  string    `json:"balance_a"`
+	BalanceB  string    `json:"balance_b"`
+}
+
+// TransferConnection is a page of transfers following the Relay connection
+// convention.
+type TransferConnection struct {
+	Edges    []TransferEdge `json:"edges"`
+	PageInfo PageInfo       `json:"pageInfo"`
+}
+
+type TransferEdge struct {
+	Cursor string    `json:"cursor"`
+	Node   *Transfer `json:"node"`
+}
+
+type PageInfo struct {
+	HasNextPage bool   `json:"hasNextPage"`
+	EndCursor   string `json:"endCursor"`
+}
@@ -1,10 +1,60 @@
 package model
 
+import "time"
+
 type Wallet struct {
-	Address string `json:"address"`
-	Balance string `json:"balance"`
+	Address  string `json:"address"`
+	Balance  string `json:"balance"`
+	Reserved string `json:"reserved"`
+	// HeldBalance is the part of Balance locked in open escrow holds.
+	HeldBalance string `json:"held_balance"`
+	Status      string `json:"status"`
+	Frozen      bool   `json:"frozen"`
+	// Nonce is the number of transfers the wallet has sent.
+	Nonce          int64      `json:"nonce"`
+	LastActivityAt *time.Time `json:"last_activity_at"`
 }
 
 type TransferResult struct {
+	Balance         string `json:"balance"`
+	Fee             string `json:"fee"`
+	ClientRequestID string `json:"client_request_id"`
+	// WouldSucceed is false only for a dry run that would be rejected, in
+	// which case FailureCode and FailureMessage describe why.
+	WouldSucceed   bool   `json:"would_succeed"`
+	FailureCode    string `json:"failure_code"`
+	FailureMessage string `json:"failure_message"`
+}
+
+// WalletConnection is a page of wallets with the total number of wallets.
+type WalletConnection struct {
+	Nodes      []Wallet `json:"nodes"`
+	TotalCount int64    `json:"totalCount"`
+	PageInfo   PageInfo `json:"pageInfo"`
+}
+
+// BalanceSnapshot is a wallet's balance as of RecordedAt, an entry of its
+// balance history.
+type BalanceSnapshot struct {
+	Address    string    `json:"address"`
+	Balance    string    `json:"balance"`
+	RecordedAt time.Time `json:"recorded_at"`
+}
+
+// WalletImportRow is a wallet to create, or to set the balance of, in a bulk
+// import.
+type WalletImportRow struct {
+	Address string `json:"address"`
 	Balance string `json:"balance"`
 }
+
+// WalletImportResult reports what a bulk import did with one of its rows.
+// Row is the row's 1-based position in the import; Code and Message are set
+// only for rejected rows.
+type WalletImportResult struct {
+	Row     int    `json:"row"`
+	Address string `json:"address"`
+	Status  string `json:"status"`
+	Code    string `json:"code,omitempty"`
+	Message string `json:"message,omitempty"`
+}
@@ -0,0 +1,150 @@
+// Package tracing instruments the request path with OpenTelemetry spans:
+// one per GraphQL operation, one per transfer and one per SQL statement of
+// the transactions the db package runs. Spans are exported over OTLP as
+// configured by the standard OTEL_* variables; without an exporter they are
+// dropped as they end.
+package tracing
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"net/http"
+	"os"
+	"strconv"
+	"strings"
+	"sync/atomic"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/exporters/otlp/otlptrace/otlptracehttp"
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/sdk/resource"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// ServiceName is the service.name spans are exported under unless
+// OTEL_SERVICE_NAME says otherwise. It also names the tracer.
+const ServiceName = "token-transfer-api"
+
+// propagator reads the W3C traceparent and tracestate headers.
+var propagator = propagation.TraceContext{}
+
+var sensitiveAmounts atomic.Bool
+
+// Config controls what Setup exports.
+type Config struct {
+	// Export sends spans to the OTLP/HTTP endpoint configured by the
+	// OTEL_EXPORTER_OTLP_* variables.
+	Export bool
+	// SensitiveAmounts keeps amounts out of span attributes. Addresses are
+	// only ever recorded hashed.
+	SensitiveAmounts bool
+}
+
+// ConfigFromEnv reads whether spans are exported from OTEL_TRACES_EXPORTER:
+// "otlp" exports them and "none" does not. When it is not set they are
+// exported once OTEL_EXPORTER_OTLP_ENDPOINT or
+// OTEL_EXPORTER_OTLP_TRACES_ENDPOINT is, and OTEL_SDK_DISABLED=true turns
+// exporting off in any case. TRACE_SENSITIVE_AMOUNTS (default false) keeps
+// amounts out of spans.
+func ConfigFromEnv() (Config, error) {
+	var cfg Config
+
+	switch v := os.Getenv("OTEL_TRACES_EXPORTER"); v {
+	case "":
+		cfg.Export = os.Getenv("OTEL_EXPORTER_OTLP_ENDPOINT") != "" || os.Getenv("OTEL_EXPORTER_OTLP_TRACES_ENDPOINT") != ""
+	case "otlp":
+		cfg.Export = true
+	case "none":
+	default:
+		return Config{}, fmt.Errorf("unsupported OTEL_TRACES_EXPORTER %q", v)
+	}
+	if strings.EqualFold(os.Getenv("OTEL_SDK_DISABLED"), "true") {
+		cfg.Export = false
+	}
+
+	if v := os.Getenv("TRACE_SENSITIVE_AMOUNTS"); v != "" {
+		sensitive, err := strconv.ParseBool(v)
+		if err != nil {
+			return Config{}, fmt.Errorf("invalid TRACE_SENSITIVE_AMOUNTS %q", v)
+		}
+		cfg.SensitiveAmounts = sensitive
+	}
+	return cfg, nil
+}
+
+// Setup applies cfg and, when it exports, installs a tracer provider that
+// batches spans to the OTLP exporter. The returned function flushes the
+// spans still queued and stops the exporter.
+func Setup(ctx context.Context, cfg Config) (func(context.Context) error, error) {
+	SetSensitiveAmounts(cfg.SensitiveAmounts)
+	if !cfg.Export {
+		return func(context.Context) error { return nil }, nil
+	}
+
+	exporter, err := otlptracehttp.New(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("create OTLP exporter: %w", err)
+	}
+	// Detectors applied later win, so OTEL_SERVICE_NAME and
+	// OTEL_RESOURCE_ATTRIBUTES override the default service name
+	res, err := resource.New(ctx,
+		resource.WithAttributes(attribute.String("service.name", ServiceName)),
+		resource.WithTelemetrySDK(),
+		resource.WithFromEnv(),
+	)
+	if err != nil {
+		return nil, fmt.Errorf("build trace resource: %w", err)
+	}
+
+	provider := sdktrace.NewTracerProvider(sdktrace.WithBatcher(exporter), sdktrace.WithResource(res))
+	otel.SetTracerProvider(provider)
+	return provider.Shutdown, nil
+}
+
+// SetSensitiveAmounts turns recording amounts in spans off or on.
+func SetSensitiveAmounts(sensitive bool) {
+	sensitiveAmounts.Store(sensitive)
+}
+
+// Extract returns ctx carrying the remote span of the request's traceparent
+// header, if it has a valid one, so spans started with it join the caller's
+// trace.
+func Extract(ctx context.Context, header http.Header) context.Context {
+	return propagator.Extract(ctx, propagation.HeaderCarrier(header))
+}
+
+// Start starts a span as a child of the one in ctx. The tracer is looked up
+// on every call, so a provider installed later, as tests do, is used.
+func Start(ctx context.Context, name string, attrs ...attribute.KeyValue) (context.Context, trace.Span) {
+	return otel.Tracer(ServiceName).Start(ctx, name, trace.WithAttributes(attrs...))
+}
+
+// End ends span, marking it failed with err unless err is nil.
+func End(span trace.Span, err error) {
+	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+	}
+	span.End()
+}
+
+// Address is an attribute holding the SHA-256 of address in hex, so spans
+// of one wallet can be correlated without naming it. The address is
+// lowercased first, as addresses differing only in case are often the same
+// wallet.
+func Address(key, address string) attribute.KeyValue {
+	sum := sha256.Sum256([]byte(strings.ToLower(address)))
+	return attribute.String(key, hex.EncodeToString(sum[:]))
+}
+
+// SetAmount records amount on span, unless amounts are sensitive.
+func SetAmount(span trace.Span, key, amount string) {
+	if !sensitiveAmounts.Load() {
+		span.SetAttributes(attribute.String(key, amount))
+	}
+}
@@ -0,0 +1,95 @@
+package dedup
+
+import (
+	"sync"
+	"time"
+)
+
+// Cache remembers the outcome of recent calls by key so that a repeated call
+// within the window gets the earlier result instead of running again. It is
+// in-memory and per process, so it only catches accidental resubmissions
+// such as double clicks; it is not a substitute for durable idempotency.
+type Cache struct {
+	window time.Duration
+
+	mu        sync.Mutex
+	entries   map[string]*entry
+	lastEvict time.Time
+}
+
+type entry struct {
+	done    chan struct{}
+	expires time.Time
+	value   interface{}
+	err     error
+}
+
+// New creates a cache that keeps successful results for window.
+func New(window time.Duration) *Cache {
+	return &Cache{
+		window:  window,
+		entries: make(map[string]*entry),
+	}
+}
+
+// Do runs fn unless a call with the same key succeeded within the window or
+// is still running, in which case it waits for and returns that call's
+// result. The bool reports whether the result was reused. Failed calls are
+// forgotten so the client can retry them.
+func (c *Cache) Do(key string, fn func() (interface{}, error)) (interface{}, bool, error) {
+	c.mu.Lock()
+	now := time.Now()
+	if now.Sub(c.lastEvict) >= c.window {
+		c.evict(now)
+		c.lastEvict = now
+	}
+	if e, ok := c.entries[key]; ok && !e.expired(now) {
+		c.mu.Unlock()
+		<-e.done
+		if e.err == nil {
+			return e.value, true, nil
+		}
+		// The earlier call failed and has been forgotten; run again.
+		return c.Do(key, fn)
+	}
+
+	e := &entry{done: make(chan struct{})}
+	c.entries[key] = e
+	c.mu.Unlock()
+
+	e.value, e.err = fn()
+
+	c.mu.Lock()
+	if e.err != nil {
+		delete(c.entries, key)
+	} else {
+		e.expires = time.Now().Add(c.window)
+	}
+	c.mu.Unlock()
+	close(e.done)
+
+	return e.value, false, e.err
+}
+
+// Len returns the number of remembered or running calls.
+func (c *Cache) Len() int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.evict(time.Now())
+	return len(c.entries)
+}
+
+// evict drops finished entries whose window has passed. c.mu must be held.
+func (c *Cache) evict(now time.Time) {
+	for key, e := range c.entries {
+		if e.expired(now) {
+			delete(c.entries, key)
+		}
+	}
+}
+
+// expired reports whether a finished entry's window has passed. Running
+// calls never expire.
+func (e *entry) expired(now time.Time) bool {
+	return !e.expires.IsZero() && !now.Before(e.expires)
+}
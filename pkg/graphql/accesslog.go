@@ -0,0 +1,201 @@
+package graphql
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/graphql-go/graphql/language/ast"
+	"github.com/graphql-go/graphql/language/parser"
+)
+
+// DefaultAccessLogBodyBytes is how much of each body is logged when
+// ACCESS_LOG_BODY_BYTES is not set.
+const DefaultAccessLogBodyBytes = 2048
+
+// redactedHeaders are logged with their values replaced, since they carry
+// credentials.
+var redactedHeaders = map[string]bool{"Authorization": true, "Cookie": true}
+
+// AccessLogConfig controls the log WithAccessLog writes.
+type AccessLogConfig struct {
+	// Enabled writes a line per request with its method, operation names,
+	// status, duration and response size.
+	Enabled bool
+	// Bodies adds a line with the request headers and the request and
+	// response bodies, for debugging integrations. Credentials in the
+	// headers are redacted, but bodies are logged as sent.
+	Bodies bool
+	// MaxBodyBytes is how much of each body is logged. Longer bodies are
+	// cut off and marked as such.
+	MaxBodyBytes int
+}
+
+// AccessLogConfigFromEnv reads whether requests are logged from ACCESS_LOG
+// (default true), whether their bodies are from ACCESS_LOG_BODIES (default
+// false) and how much of each body from ACCESS_LOG_BODY_BYTES.
+func AccessLogConfigFromEnv() (AccessLogConfig, error) {
+	cfg := AccessLogConfig{Enabled: true, MaxBodyBytes: DefaultAccessLogBodyBytes}
+
+	if v := os.Getenv("ACCESS_LOG"); v != "" {
+		enabled, err := strconv.ParseBool(v)
+		if err != nil {
+			return AccessLogConfig{}, fmt.Errorf("invalid ACCESS_LOG %q", v)
+		}
+		cfg.Enabled = enabled
+	}
+
+	if v := os.Getenv("ACCESS_LOG_BODIES"); v != "" {
+		bodies, err := strconv.ParseBool(v)
+		if err != nil {
+			return AccessLogConfig{}, fmt.Errorf("invalid ACCESS_LOG_BODIES %q", v)
+		}
+		cfg.Bodies = bodies
+	}
+
+	if v := os.Getenv("ACCESS_LOG_BODY_BYTES"); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil || n < 1 {
+			return AccessLogConfig{}, fmt.Errorf("invalid ACCESS_LOG_BODY_BYTES %q", v)
+		}
+		cfg.MaxBodyBytes = n
+	}
+
+	return cfg, nil
+}
+
+// WithAccessLog logs every request next serves once it is answered. The
+// request body is read ahead to find the operation names, but never past
+// MAX_REQUEST_BYTES: a longer body is left for next to reject.
+func WithAccessLog(next http.Handler, cfg AccessLogConfig) http.Handler {
+	if !cfg.Enabled {
+		return next
+	}
+
+	maxBytes, err := MaxRequestBytesFromEnv()
+	if err != nil {
+		panic(err)
+	}
+
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		start := time.Now()
+
+		var body []byte
+		if r.Method != http.MethodGet && r.Body != nil {
+			// One byte past the limit is enough for next to notice it
+			body, _ = io.ReadAll(io.LimitReader(r.Body, maxBytes+1))
+			r.Body = io.NopCloser(bytes.NewReader(body))
+		}
+
+		operations := "-"
+		if int64(len(body)) <= maxBytes {
+			if reqs, _, err := decodeRequests(r, body); err == nil {
+				operations = operationNames(reqs)
+			}
+		}
+
+		rec := &accessLogWriter{ResponseWriter: w, status: http.StatusOK}
+		if cfg.Bodies {
+			rec.capture = cfg.MaxBodyBytes
+		}
+		next.ServeHTTP(rec, r)
+
+		id := w.Header().Get(RequestIDHeader)
+		if id == "" {
+			id = "-"
+		}
+		log.Printf("Access %s %s operation=%s status=%d duration=%s bytes=%d request_id=%s",
+			r.Method, r.URL.Path, operations, rec.status, time.Since(start).Round(time.Microsecond), rec.bytes, id)
+
+		if cfg.Bodies {
+			log.Printf("Access bodies request_id=%s headers=%s request=%q response=%q",
+				id, loggedHeaders(r.Header), truncateBody(body, cfg.MaxBodyBytes), truncateBody(rec.body.Bytes(), cfg.MaxBodyBytes))
+		}
+	})
+}
+
+// operationNames lists the operations of reqs by name, separated by commas.
+// An operation without an operationName is named after the only operation
+// of its document, or "-" when that has none or several.
+func operationNames(reqs []GraphQLRequest) string {
+	names := make([]string, len(reqs))
+	for i, req := range reqs {
+		names[i] = req.OperationName
+		if names[i] == "" {
+			names[i] = soleOperationName(req.Query)
+		}
+	}
+	return strings.Join(names, ",")
+}
+
+func soleOperationName(query string) string {
+	doc, err := parser.Parse(parser.ParseParams{Source: query})
+	if err != nil {
+		return "-"
+	}
+
+	name := "-"
+	count := 0
+	for _, def := range doc.Definitions {
+		if op, ok := def.(*ast.OperationDefinition); ok {
+			count++
+			if op.Name != nil {
+				name = op.Name.Value
+			}
+		}
+	}
+	if count != 1 {
+		return "-"
+	}
+	return name
+}
+
+// loggedHeaders renders h for the log with credentials redacted.
+func loggedHeaders(h http.Header) string {
+	logged := make(http.Header, len(h))
+	for name, values := range h {
+		if redactedHeaders[http.CanonicalHeaderKey(name)] {
+			values = []string{"[REDACTED]"}
+		}
+		logged[name] = values
+	}
+	return fmt.Sprint(map[string][]string(logged))
+}
+
+// truncateBody returns body cut to max bytes, marking the cut.
+func truncateBody(body []byte, max int) string {
+	if len(body) <= max {
+		return string(body)
+	}
+	return string(body[:max]) + "...(truncated)"
+}
+
+// accessLogWriter records the status and size of a response, and its first
+// capture bytes plus one so truncateBody can tell it was cut.
+type accessLogWriter struct {
+	http.ResponseWriter
+	status  int
+	bytes   int
+	capture int
+	body    bytes.Buffer
+}
+
+func (w *accessLogWriter) WriteHeader(status int) {
+	w.status = status
+	w.ResponseWriter.WriteHeader(status)
+}
+
+func (w *accessLogWriter) Write(b []byte) (int, error) {
+	if room := w.capture + 1 - w.body.Len(); w.capture > 0 && room > 0 {
+		w.body.Write(b[:min(room, len(b))])
+	}
+	n, err := w.ResponseWriter.Write(b)
+	w.bytes += n
+	return n, err
+}
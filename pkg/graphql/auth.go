@@ -0,0 +1,258 @@
+package graphql
+
+import (
+	"bytes"
+	"crypto/subtle"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"strconv"
+	"strings"
+	"token-transfer-api/internal/db"
+	"token-transfer-api/internal/graph"
+
+	"github.com/graphql-go/graphql"
+	"github.com/graphql-go/graphql/gqlerrors"
+	"github.com/graphql-go/graphql/language/ast"
+	"github.com/graphql-go/graphql/language/location"
+	"github.com/graphql-go/graphql/language/parser"
+)
+
+// AuthConfig controls which requests must carry an API key.
+type AuthConfig struct {
+	// APIKeys are the accepted bearer tokens. Authentication is disabled
+	// when it is empty.
+	APIKeys []string
+	// Callers maps keys to the address requests made with them act on
+	// behalf of, which the mint, burn and admin operations check. Requests
+	// with other keys, or without one, act on behalf of no one.
+	Callers map[string]string
+	// PublicQueries lets queries, including introspection, through without
+	// a key. Mutations always need one.
+	PublicQueries bool
+}
+
+// AuthConfigFromEnv reads the accepted keys from API_KEYS (comma-separated)
+// and whether queries are public from AUTH_PUBLIC_QUERIES (default true).
+// A key written as key=address is bound to that address.
+func AuthConfigFromEnv() (AuthConfig, error) {
+	cfg := AuthConfig{PublicQueries: true}
+
+	for _, entry := range strings.Split(os.Getenv("API_KEYS"), ",") {
+		entry = strings.TrimSpace(entry)
+		if entry == "" {
+			continue
+		}
+		key, caller, bound := strings.Cut(entry, "=")
+		key = strings.TrimSpace(key)
+		if key == "" {
+			return AuthConfig{}, fmt.Errorf("invalid API_KEYS entry %q", entry)
+		}
+		cfg.APIKeys = append(cfg.APIKeys, key)
+		if bound {
+			caller = strings.TrimSpace(caller)
+			if !db.ValidAddress(caller) {
+				return AuthConfig{}, fmt.Errorf("invalid API_KEYS caller %q", caller)
+			}
+			if cfg.Callers == nil {
+				cfg.Callers = make(map[string]string)
+			}
+			cfg.Callers[key] = caller
+		}
+	}
+
+	if v := os.Getenv("AUTH_PUBLIC_QUERIES"); v != "" {
+		public, err := strconv.ParseBool(v)
+		if err != nil {
+			return AuthConfig{}, fmt.Errorf("invalid AUTH_PUBLIC_QUERIES %q", v)
+		}
+		cfg.PublicQueries = public
+	}
+
+	return cfg, nil
+}
+
+// WithAuth rejects requests that need an API key but do not carry a valid
+// "Authorization: Bearer <key>" header, answering 401 with a GraphQL error.
+// Requests with a key bound to an address act on behalf of it. Bodies it
+// inspects are held to MAX_REQUEST_BYTES like in the handler.
+//
+// Persisted queries are resolved here, once, and the handler runs the
+// operations as resolved, so what was checked is what runs. A query sent with
+// its hash is only registered once its request passed, and a request sending
+// a hash the server does not know is refused without a key, since what it
+// stands for cannot be checked.
+func WithAuth(next http.Handler, cfg AuthConfig) http.Handler {
+	if len(cfg.APIKeys) == 0 {
+		return next
+	}
+
+	maxBytes, err := MaxRequestBytesFromEnv()
+	if err != nil {
+		panic(err)
+	}
+
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method == http.MethodOptions {
+			next.ServeHTTP(w, r)
+			return
+		}
+		authenticated := ValidKey(r, cfg.APIKeys)
+		if !authenticated && !cfg.PublicQueries {
+			writeError(w, http.StatusUnauthorized, db.ErrUnauthenticated)
+			return
+		}
+
+		var body []byte
+		if r.Method != http.MethodGet {
+			var ok bool
+			if body, ok = readBody(w, r, maxBytes); !ok {
+				return
+			}
+			r.Body = io.NopCloser(bytes.NewReader(body))
+		}
+
+		// Malformed requests are reported by the GraphQL handler, which
+		// decodes requests the same way and so cannot run anything
+		reqs, batch, err := decodeRequests(r, body)
+		if err != nil {
+			next.ServeHTTP(w, cfg.Authenticate(r))
+			return
+		}
+		resolved := resolveRequests(reqs, batch)
+
+		if !authenticated {
+			if resolved.unknownQuery() {
+				writeUnknownQuery(w, resolved)
+				return
+			}
+			if resolved.anyMutation() {
+				writeError(w, http.StatusUnauthorized, db.ErrUnauthenticated)
+				return
+			}
+		}
+
+		resolved.register()
+		next.ServeHTTP(w, withResolvedRequests(cfg.Authenticate(r), resolved))
+	})
+}
+
+// writeUnknownQuery refuses a request without a key that sent a hash the
+// server does not know. The operations with such a hash are answered with
+// PERSISTED_QUERY_NOT_FOUND, with 200 like the handler does, so clients send
+// the query again; the rest of a batch did not run and is answered with
+// UNAUTHENTICATED.
+func writeUnknownQuery(w http.ResponseWriter, resolved *resolvedRequests) {
+	results := make([]*graphql.Result, len(resolved.reqs))
+	for i, err := range resolved.errs {
+		if err == nil {
+			err = db.ErrUnauthenticated
+		}
+		results[i] = errorResult(err)
+	}
+
+	w.Header().Set("Access-Control-Allow-Origin", "*")
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	if resolved.batch {
+		json.NewEncoder(w).Encode(results)
+	} else {
+		json.NewEncoder(w).Encode(results[0])
+	}
+}
+
+// ValidKey reports whether the request carries one of the accepted keys.
+func ValidKey(r *http.Request, keys []string) bool {
+	return ValidAuthorization(r.Header.Get("Authorization"), keys)
+}
+
+// ValidAuthorization reports whether an Authorization value is
+// "Bearer <key>" with one of the accepted keys.
+func ValidAuthorization(header string, keys []string) bool {
+	_, ok := matchKey(header, keys)
+	return ok
+}
+
+// CallerFor returns the address the key in an Authorization value is bound
+// to, or "" when the key is not accepted or bound to no address.
+func (c AuthConfig) CallerFor(header string) string {
+	key, ok := matchKey(header, c.APIKeys)
+	if !ok {
+		return ""
+	}
+	return c.Callers[key]
+}
+
+// Authenticate returns r acting on behalf of the address its key is bound
+// to. Without such a key r is returned unchanged, acting for no one.
+func (c AuthConfig) Authenticate(r *http.Request) *http.Request {
+	caller := c.CallerFor(r.Header.Get("Authorization"))
+	if caller == "" {
+		return r
+	}
+	return r.WithContext(graph.WithCaller(r.Context(), caller))
+}
+
+// matchKey returns the accepted key an Authorization value of the form
+// "Bearer <key>" carries.
+func matchKey(header string, keys []string) (string, bool) {
+	token, ok := strings.CutPrefix(header, "Bearer ")
+	if !ok || token == "" {
+		return "", false
+	}
+
+	for _, key := range keys {
+		if subtle.ConstantTimeCompare([]byte(token), []byte(key)) == 1 {
+			return key, true
+		}
+	}
+	return "", false
+}
+
+// anyMutation reports whether any of the operations that resolved is a
+// mutation.
+func (r *resolvedRequests) anyMutation() bool {
+	for i, req := range r.reqs {
+		if r.errs[i] == nil && hasMutation(req.Query) {
+			return true
+		}
+	}
+	return false
+}
+
+// hasMutation reports whether the document defines any operation other than a
+// query. Unparsable documents are left to the executor, which rejects them.
+func hasMutation(query string) bool {
+	doc, err := parser.Parse(parser.ParseParams{Source: query})
+	if err != nil {
+		return false
+	}
+
+	for _, def := range doc.Definitions {
+		if op, ok := def.(*ast.OperationDefinition); ok && op.Operation != ast.OperationTypeQuery {
+			return true
+		}
+	}
+	return false
+}
+
+// writeError answers with a GraphQL error response carrying err's code.
+func writeError(w http.ResponseWriter, status int, err *db.AppError) {
+	w.Header().Set("Access-Control-Allow-Origin", "*")
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(errorResult(err))
+}
+
+// errorResult is a GraphQL response carrying only err.
+func errorResult(err *db.AppError) *graphql.Result {
+	return &graphql.Result{
+		Errors: []gqlerrors.FormattedError{{
+			Message:    err.Message,
+			Locations:  []location.SourceLocation{},
+			Extensions: err.Extensions(),
+		}},
+	}
+}
@@ -0,0 +1,56 @@
+package graphql
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"strconv"
+	"token-transfer-api/internal/db"
+)
+
+// DefaultMaxRequestBytes is the largest accepted request body when
+// MAX_REQUEST_BYTES is not set. It leaves room for thousands of transfer
+// mutations in one document.
+const DefaultMaxRequestBytes = 1 << 20
+
+// MaxRequestBytesFromEnv reads the largest accepted request body, in bytes,
+// from MAX_REQUEST_BYTES.
+func MaxRequestBytesFromEnv() (int64, error) {
+	v := os.Getenv("MAX_REQUEST_BYTES")
+	if v == "" {
+		return DefaultMaxRequestBytes, nil
+	}
+
+	n, err := strconv.ParseInt(v, 10, 64)
+	if err != nil || n < 1 {
+		return 0, fmt.Errorf("invalid MAX_REQUEST_BYTES %q", v)
+	}
+	return n, nil
+}
+
+// RequestTooLarge reports whether err came from reading a body past its
+// http.MaxBytesReader limit, and if so returns the error to answer with.
+func RequestTooLarge(err error) (*db.AppError, bool) {
+	var tooLarge *http.MaxBytesError
+	if !errors.As(err, &tooLarge) {
+		return nil, false
+	}
+	return db.ErrRequestTooLarge.WithDetails(map[string]interface{}{"max_bytes": tooLarge.Limit}), true
+}
+
+// readBody reads at most limit bytes of the request body. If the body is
+// larger or cannot be read, it answers the request and returns false.
+func readBody(w http.ResponseWriter, r *http.Request, limit int64) ([]byte, bool) {
+	body, err := io.ReadAll(http.MaxBytesReader(w, r.Body, limit))
+	if err != nil {
+		if appErr, ok := RequestTooLarge(err); ok {
+			writeError(w, http.StatusRequestEntityTooLarge, appErr)
+		} else {
+			writeError(w, http.StatusBadRequest, db.ErrMalformedRequest)
+		}
+		return nil, false
+	}
+	return body, true
+}
@@ -0,0 +1,92 @@
+package graphql
+
+import (
+	"errors"
+	"log"
+	"net/http"
+	"token-transfer-api/internal/db"
+
+	"github.com/graphql-go/graphql"
+	"github.com/graphql-go/graphql/gqlerrors"
+)
+
+// SanitizeErrors replaces resolver errors that are not app errors with a
+// generic internal error so driver messages, which can name tables,
+// columns and constraints, never reach clients. The original error is
+// logged. Syntax and validation errors are returned as they are.
+func SanitizeErrors(errs []gqlerrors.FormattedError) []gqlerrors.FormattedError {
+	for i, formatted := range errs {
+		var located *gqlerrors.Error
+		if !errors.As(formatted.OriginalError(), &located) || located.OriginalError == nil {
+			continue
+		}
+
+		cause := located.OriginalError
+		var appErr *db.AppError
+		if errors.As(cause, &appErr) {
+			continue
+		}
+
+		log.Printf("Internal error at %v: %v", formatted.Path, cause)
+		errs[i] = gqlerrors.FormattedError{
+			Message:    db.ErrInternal.Message,
+			Locations:  formatted.Locations,
+			Path:       formatted.Path,
+			Extensions: db.ErrInternal.Extensions(),
+		}
+	}
+	return errs
+}
+
+// resultStatus picks the HTTP status for the response to one operation. It
+// must see the errors before SanitizeErrors replaces them. Errors raised
+// while executing are field errors and keep 200, as the GraphQL spec asks.
+// An operation that never ran, because it could not be parsed or validated
+// or was refused by the query limits, is a bad request, unless a panic
+// stopped it, which is an internal error, or the request's deadline passed,
+// which is a gateway timeout. One that failed only for lack of
+// permission is forbidden. Persisted query errors stay 200 since clients
+// look for them in the errors array to resend the query text.
+func resultStatus(result *graphql.Result) int {
+	if len(result.Errors) == 0 {
+		return http.StatusOK
+	}
+
+	forbidden := true
+	for _, err := range result.Errors {
+		code := errorCode(err)
+		if code == db.ErrQueryNotPersisted.Code || code == db.ErrQueryHashMismatch.Code {
+			return http.StatusOK
+		}
+		if !executionError(err) {
+			if code == db.ErrInternal.Code {
+				return http.StatusInternalServerError
+			}
+			if code == db.ErrRequestTimeout.Code {
+				return http.StatusGatewayTimeout
+			}
+			return http.StatusBadRequest
+		}
+		if code != db.ErrUnauthorized.Code {
+			forbidden = false
+		}
+	}
+
+	if forbidden {
+		return http.StatusForbidden
+	}
+	return http.StatusOK
+}
+
+// executionError reports whether err was raised while resolving a field,
+// as opposed to while parsing, validating or preparing the operation.
+func executionError(err gqlerrors.FormattedError) bool {
+	var located *gqlerrors.Error
+	return errors.As(err.OriginalError(), &located) && located.OriginalError != nil
+}
+
+// errorCode returns the code in err's extensions, if any.
+func errorCode(err gqlerrors.FormattedError) string {
+	code, _ := err.Extensions["code"].(string)
+	return code
+}
@@ -0,0 +1,68 @@
+package graphql
+
+import (
+	"fmt"
+	"os"
+	"strconv"
+
+	"github.com/graphql-go/graphql/language/ast"
+	"github.com/graphql-go/graphql/language/parser"
+)
+
+// IntrospectionFromEnv reports whether __schema and __type may be queried.
+// GRAPHQL_INTROSPECTION overrides the default, which is to allow them
+// everywhere except ENV=production.
+func IntrospectionFromEnv() (bool, error) {
+	v := os.Getenv("GRAPHQL_INTROSPECTION")
+	if v == "" {
+		return os.Getenv("ENV") != "production", nil
+	}
+
+	enabled, err := strconv.ParseBool(v)
+	if err != nil {
+		return false, fmt.Errorf("invalid GRAPHQL_INTROSPECTION %q", v)
+	}
+	return enabled, nil
+}
+
+// hasIntrospection reports whether the document selects __schema or __type
+// anywhere. __typename stays available, since clients rely on it to tell
+// result types apart. Unparsable documents are left to the executor, which
+// rejects them.
+func hasIntrospection(query string) bool {
+	doc, err := parser.Parse(parser.ParseParams{Source: query})
+	if err != nil {
+		return false
+	}
+
+	found := false
+	for _, def := range doc.Definitions {
+		switch def := def.(type) {
+		case *ast.OperationDefinition:
+			found = found || selectsIntrospection(def.SelectionSet)
+		case *ast.FragmentDefinition:
+			found = found || selectsIntrospection(def.SelectionSet)
+		}
+	}
+	return found
+}
+
+func selectsIntrospection(set *ast.SelectionSet) bool {
+	if set == nil {
+		return false
+	}
+
+	for _, sel := range set.Selections {
+		switch sel := sel.(type) {
+		case *ast.Field:
+			if name := sel.Name.Value; name == "__schema" || name == "__type" || selectsIntrospection(sel.SelectionSet) {
+				return true
+			}
+		case *ast.InlineFragment:
+			if selectsIntrospection(sel.SelectionSet) {
+				return true
+			}
+		}
+	}
+	return false
+}
@@ -0,0 +1,352 @@
+package graphql
+
+import (
+	"fmt"
+	"math"
+	"os"
+	"strconv"
+	"strings"
+	"token-transfer-api/internal/db"
+
+	"github.com/graphql-go/graphql"
+	"github.com/graphql-go/graphql/language/ast"
+	"github.com/graphql-go/graphql/language/parser"
+)
+
+// QueryLimits bound the cost of an operation before it is executed. A zero
+// limit disables that check.
+type QueryLimits struct {
+	// MaxDepth is how deeply fields may be nested; a top-level field has
+	// depth 1.
+	MaxDepth int
+	// MaxComplexity caps the estimated number of fields resolved. Each field
+	// costs 1, and the fields below a list are counted as many times as it
+	// can have items: its first or limit argument, that argument's default
+	// when it is not given, or the number of elements of a list argument.
+	MaxComplexity int
+	// MaxIntrospectionDepth replaces MaxDepth for operations that only
+	// select __schema, __type or __typename. Introspection is not subject
+	// to MaxComplexity.
+	MaxIntrospectionDepth int
+}
+
+// DefaultQueryLimits are used for settings that are not configured. The
+// introspection depth leaves room for the usual TypeRef fragment.
+var DefaultQueryLimits = QueryLimits{
+	MaxDepth:              10,
+	MaxComplexity:         1000,
+	MaxIntrospectionDepth: 15,
+}
+
+// QueryLimitsFromEnv reads the limits from GRAPHQL_MAX_DEPTH,
+// GRAPHQL_MAX_COMPLEXITY and GRAPHQL_MAX_INTROSPECTION_DEPTH.
+func QueryLimitsFromEnv() (QueryLimits, error) {
+	cfg := DefaultQueryLimits
+
+	for _, setting := range []struct {
+		name  string
+		value *int
+	}{
+		{"GRAPHQL_MAX_DEPTH", &cfg.MaxDepth},
+		{"GRAPHQL_MAX_COMPLEXITY", &cfg.MaxComplexity},
+		{"GRAPHQL_MAX_INTROSPECTION_DEPTH", &cfg.MaxIntrospectionDepth},
+	} {
+		if v := os.Getenv(setting.name); v != "" {
+			n, err := strconv.Atoi(v)
+			if err != nil || n < 0 {
+				return QueryLimits{}, fmt.Errorf("invalid %s %q", setting.name, v)
+			}
+			*setting.value = n
+		}
+	}
+
+	return cfg, nil
+}
+
+// Check rejects documents with an operation that exceeds the limits.
+// Unparsable documents are left to the executor, which rejects them. The
+// argument defaults of schema's fields count as if they were given; without
+// a schema only the arguments in the document count.
+func (l QueryLimits) Check(schema *graphql.Schema, query string, variables map[string]interface{}) *db.AppError {
+	doc, err := parser.Parse(parser.ParseParams{Source: query})
+	if err != nil {
+		return nil
+	}
+
+	m := newMeasure(schema, doc, variables)
+	for _, def := range doc.Definitions {
+		op, ok := def.(*ast.OperationDefinition)
+		if !ok {
+			continue
+		}
+
+		maxDepth := l.MaxDepth
+		introspection := m.introspectionOnly(op.SelectionSet)
+		if introspection {
+			maxDepth = l.MaxIntrospectionDepth
+		}
+
+		if depth := m.depth(op.SelectionSet); maxDepth > 0 && depth > maxDepth {
+			return db.ErrQueryTooDeep.WithDetails(map[string]interface{}{"depth": depth, "max_depth": maxDepth})
+		}
+		if introspection {
+			continue
+		}
+		if complexity := m.complexity(op.SelectionSet, m.rootType(op.Operation)); l.MaxComplexity > 0 && complexity > l.MaxComplexity {
+			return db.ErrQueryTooComplex.WithDetails(map[string]interface{}{"complexity": complexity, "max_complexity": l.MaxComplexity})
+		}
+	}
+	return nil
+}
+
+// maxComplexity is where complexity estimates saturate, so huge list sizes
+// cannot overflow.
+const maxComplexity = math.MaxInt32
+
+// maxPageSize is the most items the db package returns for one page of any
+// list, however large a default the schema declares.
+const maxPageSize = max(db.MaxWalletPageSize, db.MaxTransferPageSize, db.MaxNeighborLimit, db.MaxHolderLimit)
+
+// measure walks the selections of one document. Each fragment is measured
+// once and the result reused, so documents that spread the same fragment
+// many times stay cheap to check. The fragments on the current path stop
+// cycles, which validation rejects later anyway.
+type measure struct {
+	schema    *graphql.Schema
+	fragments map[string]*ast.FragmentDefinition
+	variables map[string]interface{}
+
+	path           map[string]bool
+	fragmentDepths map[string]int
+	fragmentCosts  map[string]int
+}
+
+func newMeasure(schema *graphql.Schema, doc *ast.Document, variables map[string]interface{}) *measure {
+	m := &measure{
+		schema:         schema,
+		fragments:      map[string]*ast.FragmentDefinition{},
+		variables:      variables,
+		path:           map[string]bool{},
+		fragmentDepths: map[string]int{},
+		fragmentCosts:  map[string]int{},
+	}
+	for _, def := range doc.Definitions {
+		if frag, ok := def.(*ast.FragmentDefinition); ok && frag.Name != nil {
+			m.fragments[frag.Name.Value] = frag
+		}
+	}
+	return m
+}
+
+// spread measures a fragment with fn, or returns the earlier result. Unknown
+// fragments and cycles measure 0.
+func (m *measure) spread(name string, results map[string]int, fn func(*ast.FragmentDefinition) int) int {
+	if n, ok := results[name]; ok {
+		return n
+	}
+	frag, ok := m.fragments[name]
+	if !ok || m.path[name] {
+		return 0
+	}
+
+	m.path[name] = true
+	n := fn(frag)
+	delete(m.path, name)
+
+	results[name] = n
+	return n
+}
+
+// introspectionOnly reports whether every top-level field is __schema,
+// __type or __typename.
+func (m *measure) introspectionOnly(set *ast.SelectionSet) bool {
+	if set == nil {
+		return true
+	}
+
+	for _, sel := range set.Selections {
+		switch sel := sel.(type) {
+		case *ast.Field:
+			if !strings.HasPrefix(sel.Name.Value, "__") {
+				return false
+			}
+		case *ast.InlineFragment:
+			if !m.introspectionOnly(sel.SelectionSet) {
+				return false
+			}
+		case *ast.FragmentSpread:
+			name := sel.Name.Value
+			if frag, ok := m.fragments[name]; ok && !m.path[name] {
+				m.path[name] = true
+				only := m.introspectionOnly(frag.SelectionSet)
+				delete(m.path, name)
+				if !only {
+					return false
+				}
+			}
+		}
+	}
+	return true
+}
+
+// depth is how deeply fields are nested in set.
+func (m *measure) depth(set *ast.SelectionSet) int {
+	if set == nil {
+		return 0
+	}
+
+	deepest := 0
+	for _, sel := range set.Selections {
+		switch sel := sel.(type) {
+		case *ast.Field:
+			deepest = max(deepest, 1+m.depth(sel.SelectionSet))
+		case *ast.InlineFragment:
+			deepest = max(deepest, m.depth(sel.SelectionSet))
+		case *ast.FragmentSpread:
+			deepest = max(deepest, m.spread(sel.Name.Value, m.fragmentDepths, func(frag *ast.FragmentDefinition) int {
+				return m.depth(frag.SelectionSet)
+			}))
+		}
+	}
+	return deepest
+}
+
+// complexity estimates the number of fields resolved for set, selected on
+// parent. parent is nil when the schema does not know it.
+func (m *measure) complexity(set *ast.SelectionSet, parent graphql.Type) int {
+	if set == nil {
+		return 0
+	}
+
+	total := 0
+	for _, sel := range set.Selections {
+		cost := 0
+		switch sel := sel.(type) {
+		case *ast.Field:
+			def := fieldDefinition(parent, sel.Name.Value)
+			var fieldType graphql.Type
+			if def != nil {
+				fieldType, _ = graphql.GetNamed(def.Type).(graphql.Type)
+			}
+			size, below := m.listSize(sel, def), m.complexity(sel.SelectionSet, fieldType)
+			cost = maxComplexity
+			if below == 0 || size <= (maxComplexity-1)/below {
+				cost = 1 + size*below
+			}
+		case *ast.InlineFragment:
+			if sel.TypeCondition != nil {
+				cost = m.complexity(sel.SelectionSet, m.namedType(sel.TypeCondition))
+			} else {
+				cost = m.complexity(sel.SelectionSet, parent)
+			}
+		case *ast.FragmentSpread:
+			cost = m.spread(sel.Name.Value, m.fragmentCosts, func(frag *ast.FragmentDefinition) int {
+				return m.complexity(frag.SelectionSet, m.namedType(frag.TypeCondition))
+			})
+		}
+		total = min(total+cost, maxComplexity)
+	}
+	return total
+}
+
+// listSize is how many items a field can return: its first or limit
+// argument, or, when that is not given, its default capped at maxPageSize.
+// A list argument, such as the addresses of balances, allows as many items
+// as it has elements. Fields without such arguments count 1.
+func (m *measure) listSize(f *ast.Field, def *graphql.FieldDefinition) int {
+	size := 1
+	sized := false
+	for _, arg := range f.Arguments {
+		if arg.Name.Value == "first" || arg.Name.Value == "limit" {
+			if n := m.intValue(arg.Value); n >= 1 {
+				size = max(size, int(min(n, maxComplexity)))
+				sized = true
+			}
+		}
+		size = max(size, m.listLength(arg.Value))
+	}
+
+	if def != nil && !sized {
+		for _, arg := range def.Args {
+			if arg.Name() != "first" && arg.Name() != "limit" {
+				continue
+			}
+			if n, ok := arg.DefaultValue.(int); ok {
+				size = max(size, min(n, maxPageSize))
+			}
+		}
+	}
+	return size
+}
+
+// intValue is the number an argument is given, or 0 if it is not one.
+func (m *measure) intValue(value ast.Value) float64 {
+	var n float64
+	switch v := value.(type) {
+	case *ast.IntValue:
+		n, _ = strconv.ParseFloat(v.Value, 64)
+	case *ast.Variable:
+		switch value := m.variables[v.Name.Value].(type) {
+		case float64:
+			n = value
+		case int:
+			n = float64(value)
+		}
+	}
+	return n
+}
+
+// listLength is the number of elements of a list argument, or 0 if it is
+// not one.
+func (m *measure) listLength(value ast.Value) int {
+	switch v := value.(type) {
+	case *ast.ListValue:
+		return len(v.Values)
+	case *ast.Variable:
+		list, _ := m.variables[v.Name.Value].([]interface{})
+		return len(list)
+	}
+	return 0
+}
+
+// rootType is the type operation selects its fields on, or nil without a
+// schema.
+func (m *measure) rootType(operation string) graphql.Type {
+	if m.schema == nil {
+		return nil
+	}
+	var root *graphql.Object
+	switch operation {
+	case ast.OperationTypeQuery:
+		root = m.schema.QueryType()
+	case ast.OperationTypeMutation:
+		root = m.schema.MutationType()
+	case ast.OperationTypeSubscription:
+		root = m.schema.SubscriptionType()
+	}
+	if root == nil {
+		return nil
+	}
+	return root
+}
+
+// namedType is the schema's type called by a type condition, or nil when
+// there is no schema or no such type.
+func (m *measure) namedType(name *ast.Named) graphql.Type {
+	if m.schema == nil || name == nil || name.Name == nil {
+		return nil
+	}
+	return m.schema.Type(name.Name.Value)
+}
+
+// fieldDefinition is the field called name of parent, or nil when parent has
+// no fields or no such field.
+func fieldDefinition(parent graphql.Type, name string) *graphql.FieldDefinition {
+	switch parent := parent.(type) {
+	case *graphql.Object:
+		return parent.Fields()[name]
+	case *graphql.Interface:
+		return parent.Fields()[name]
+	}
+	return nil
+}
@@ -0,0 +1,111 @@
+package graphql
+
+import (
+	"context"
+	"sync"
+	"token-transfer-api/internal/db"
+	"token-transfer-api/internal/model"
+)
+
+// WalletFetchFunc looks up the wallets at up to db.MaxBulkAddresses
+// addresses, leaving out unknown ones, as db.GetWalletsContext does.
+type WalletFetchFunc func(ctx context.Context, addresses []string) ([]model.Wallet, error)
+
+// WalletLoader batches the wallet lookups of nested fields, such as the
+// sender and receiver of every transfer in a list. Load only queues the
+// address and returns a thunk; graphql-go calls the thunks once it has
+// resolved the fields around them, and the first one fetches every queued
+// address with one query. A batch is dropped once fetched, so later lookups,
+// such as those after a mutation, see fresh balances.
+type WalletLoader struct {
+	fetch WalletFetchFunc
+
+	mu    sync.Mutex
+	batch *walletBatch
+}
+
+// walletBatch is the set of addresses queued until one of their thunks runs.
+type walletBatch struct {
+	addresses []string
+	queued    map[string]bool
+
+	once    sync.Once
+	wallets map[string]*model.Wallet
+	err     error
+}
+
+// NewWalletLoader returns a loader fetching wallets with fetch.
+func NewWalletLoader(fetch WalletFetchFunc) *WalletLoader {
+	return &WalletLoader{fetch: fetch}
+}
+
+type walletLoaderKey struct{}
+
+// WithWalletLoader returns a context whose requests batch their wallet
+// lookups with loader.
+func WithWalletLoader(ctx context.Context, loader *WalletLoader) context.Context {
+	return context.WithValue(ctx, walletLoaderKey{}, loader)
+}
+
+// walletLoaderFromContext returns the loader stored by WithWalletLoader, or
+// nil without one.
+func walletLoaderFromContext(ctx context.Context) *WalletLoader {
+	if ctx == nil {
+		return nil
+	}
+	loader, _ := ctx.Value(walletLoaderKey{}).(*WalletLoader)
+	return loader
+}
+
+// Load queues address and returns a thunk giving its wallet, or nil if there
+// is none.
+func (l *WalletLoader) Load(ctx context.Context, address string) func() (interface{}, error) {
+	address = db.Settings.NormalizeAddress(address)
+
+	l.mu.Lock()
+	if l.batch == nil {
+		l.batch = &walletBatch{queued: make(map[string]bool)}
+	}
+	b := l.batch
+	if !b.queued[address] {
+		b.queued[address] = true
+		b.addresses = append(b.addresses, address)
+	}
+	l.mu.Unlock()
+
+	return func() (interface{}, error) {
+		b.once.Do(func() { l.run(ctx, b) })
+		if b.err != nil {
+			return nil, b.err
+		}
+		if wallet, ok := b.wallets[address]; ok {
+			return wallet, nil
+		}
+		// A nil *model.Wallet in an interface is not nil; return a plain
+		// nil so the field is null.
+		return nil, nil
+	}
+}
+
+// run closes b to new addresses and fetches its wallets, in chunks of
+// db.MaxBulkAddresses.
+func (l *WalletLoader) run(ctx context.Context, b *walletBatch) {
+	l.mu.Lock()
+	if l.batch == b {
+		l.batch = nil
+	}
+	l.mu.Unlock()
+
+	b.wallets = make(map[string]*model.Wallet, len(b.addresses))
+	for start := 0; start < len(b.addresses); start += db.MaxBulkAddresses {
+		end := min(start+db.MaxBulkAddresses, len(b.addresses))
+		wallets, err := l.fetch(ctx, b.addresses[start:end])
+		if err != nil {
+			b.err = err
+			return
+		}
+		for i := range wallets {
+			b.wallets[wallets[i].Address] = &wallets[i]
+		}
+	}
+}
@@ -0,0 +1,173 @@
+package graphql
+
+import (
+	"container/list"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"net/http"
+	"strings"
+	"sync"
+	"token-transfer-api/internal/db"
+)
+
+// PersistedQueryCacheSize is how many persisted queries are remembered. The
+// least recently used query is forgotten first; clients then register it
+// again.
+const PersistedQueryCacheSize = 1000
+
+// RequestExtensions are the protocol extensions a request can carry.
+type RequestExtensions struct {
+	PersistedQuery *PersistedQuery `json:"persistedQuery,omitempty"`
+}
+
+// PersistedQuery identifies a query by the hex SHA-256 of its text, as in
+// Apollo's Automatic Persisted Queries.
+type PersistedQuery struct {
+	Version    int    `json:"version"`
+	Sha256Hash string `json:"sha256Hash"`
+}
+
+// persistedQueries is shared by every handler and by WithAuth, which
+// resolves the requests it checks and hands them on. Entries are keyed by
+// the hash of their own text, so sharing them cannot mix up queries.
+var persistedQueries = newQueryCache(PersistedQueryCacheSize)
+
+// resolvePersistedQuery fills in the query of a request that only sends a
+// hash, and checks the hash of a request that sends both. Requests without a
+// persisted query are left alone. Nothing is registered; that waits for
+// registerPersistedQuery once the request may run.
+func resolvePersistedQuery(req *GraphQLRequest) *db.AppError {
+	if req.Extensions == nil || req.Extensions.PersistedQuery == nil {
+		return nil
+	}
+	hash := strings.ToLower(req.Extensions.PersistedQuery.Sha256Hash)
+
+	if req.Query == "" {
+		query, ok := persistedQueries.get(hash)
+		if !ok {
+			return db.ErrQueryNotPersisted
+		}
+		req.Query = query
+		return nil
+	}
+
+	sum := sha256.Sum256([]byte(req.Query))
+	if hex.EncodeToString(sum[:]) != hash {
+		return db.ErrQueryHashMismatch
+	}
+	return nil
+}
+
+// registerPersistedQuery remembers the query of a resolved request under its
+// hash, so later requests may send the hash alone.
+func registerPersistedQuery(req GraphQLRequest) {
+	if req.Extensions == nil || req.Extensions.PersistedQuery == nil {
+		return
+	}
+	persistedQueries.add(strings.ToLower(req.Extensions.PersistedQuery.Sha256Hash), req.Query)
+}
+
+// resolvedRequests are the operations of one HTTP request with their
+// persisted queries resolved. errs holds the error each operation failed to
+// resolve with, or nil.
+type resolvedRequests struct {
+	reqs  []GraphQLRequest
+	batch bool
+	errs  []*db.AppError
+}
+
+// resolveRequests resolves the persisted queries of reqs.
+func resolveRequests(reqs []GraphQLRequest, batch bool) *resolvedRequests {
+	resolved := &resolvedRequests{reqs: reqs, batch: batch, errs: make([]*db.AppError, len(reqs))}
+	for i := range reqs {
+		resolved.errs[i] = resolvePersistedQuery(&reqs[i])
+	}
+	return resolved
+}
+
+// register registers the persisted queries of the operations that resolved.
+func (r *resolvedRequests) register() {
+	for i, req := range r.reqs {
+		if r.errs[i] == nil {
+			registerPersistedQuery(req)
+		}
+	}
+}
+
+// unknownQuery reports whether any operation sent a hash the server does not
+// know, so that what it would run cannot be told.
+func (r *resolvedRequests) unknownQuery() bool {
+	for _, err := range r.errs {
+		if err == db.ErrQueryNotPersisted {
+			return true
+		}
+	}
+	return false
+}
+
+type resolvedRequestsKey struct{}
+
+// withResolvedRequests returns r carrying its resolved operations, which the
+// handler then runs instead of decoding and resolving the body again.
+func withResolvedRequests(r *http.Request, resolved *resolvedRequests) *http.Request {
+	return r.WithContext(context.WithValue(r.Context(), resolvedRequestsKey{}, resolved))
+}
+
+// resolvedRequestsFromContext returns the operations stored by
+// withResolvedRequests, or nil without them.
+func resolvedRequestsFromContext(ctx context.Context) *resolvedRequests {
+	resolved, _ := ctx.Value(resolvedRequestsKey{}).(*resolvedRequests)
+	return resolved
+}
+
+// queryCache is a fixed-size LRU map from hash to query text.
+type queryCache struct {
+	size int
+
+	mu      sync.Mutex
+	order   *list.List // of *queryEntry, most recently used first
+	entries map[string]*list.Element
+}
+
+type queryEntry struct {
+	hash  string
+	query string
+}
+
+func newQueryCache(size int) *queryCache {
+	return &queryCache{
+		size:    size,
+		order:   list.New(),
+		entries: make(map[string]*list.Element),
+	}
+}
+
+func (c *queryCache) get(hash string) (string, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	el, ok := c.entries[hash]
+	if !ok {
+		return "", false
+	}
+	c.order.MoveToFront(el)
+	return el.Value.(*queryEntry).query, true
+}
+
+func (c *queryCache) add(hash, query string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if el, ok := c.entries[hash]; ok {
+		c.order.MoveToFront(el)
+		return
+	}
+
+	c.entries[hash] = c.order.PushFront(&queryEntry{hash: hash, query: query})
+	if c.order.Len() > c.size {
+		oldest := c.order.Back()
+		c.order.Remove(oldest)
+		delete(c.entries, oldest.Value.(*queryEntry).hash)
+	}
+}
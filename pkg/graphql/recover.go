@@ -0,0 +1,85 @@
+package graphql
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"log"
+	"regexp"
+	"runtime/debug"
+	"strings"
+	"token-transfer-api/internal/db"
+
+	"github.com/graphql-go/graphql"
+)
+
+// RequestIDHeader carries the ID a request is logged under. A client may set
+// it to find its request in the server logs; otherwise one is generated. The
+// ID in use is returned in the response header of the same name.
+const RequestIDHeader = "X-Request-ID"
+
+// validRequestID limits client-chosen IDs to what is safe to log.
+var validRequestID = regexp.MustCompile(`^[A-Za-z0-9._-]{1,64}$`)
+
+type requestIDKey struct{}
+
+// WithRequestID returns a context carrying the ID of the request.
+func WithRequestID(ctx context.Context, id string) context.Context {
+	return context.WithValue(ctx, requestIDKey{}, id)
+}
+
+// RequestIDFromContext returns the request ID stored by WithRequestID.
+func RequestIDFromContext(ctx context.Context) string {
+	if ctx == nil {
+		return ""
+	}
+	id, _ := ctx.Value(requestIDKey{}).(string)
+	return id
+}
+
+// requestID returns the client's ID from header when it is safe to log, and
+// a new random one otherwise.
+func requestID(header string) string {
+	if validRequestID.MatchString(header) {
+		return header
+	}
+	var b [8]byte
+	rand.Read(b[:])
+	return hex.EncodeToString(b[:])
+}
+
+// RecoverResolvers wraps every resolver in schema so a panic, such as a
+// failed type assertion or a nil dereference, is logged with the request ID
+// and stack and reported to the client as ErrInternal. graphql-go recovers
+// resolver panics itself, but reports the panic value as the error message.
+func RecoverResolvers(schema graphql.Schema) {
+	for name, t := range schema.TypeMap() {
+		object, ok := t.(*graphql.Object)
+		if !ok || strings.HasPrefix(name, "__") {
+			continue
+		}
+		for _, field := range object.Fields() {
+			if field.Resolve != nil {
+				field.Resolve = recoverResolver(field.Resolve)
+			}
+		}
+	}
+}
+
+func recoverResolver(resolve graphql.FieldResolveFn) graphql.FieldResolveFn {
+	return func(p graphql.ResolveParams) (result interface{}, err error) {
+		defer func() {
+			if r := recover(); r != nil {
+				logPanic(p.Context, r)
+				result, err = nil, db.ErrInternal
+			}
+		}()
+		return resolve(p)
+	}
+}
+
+// logPanic logs a recovered panic with the stack of the goroutine that
+// raised it.
+func logPanic(ctx context.Context, r interface{}) {
+	log.Printf("Panic serving request %s: %v\n%s", RequestIDFromContext(ctx), r, debug.Stack())
+}
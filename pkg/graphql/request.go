@@ -0,0 +1,116 @@
+package graphql
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"mime"
+	"net/http"
+	"net/url"
+	"token-transfer-api/internal/db"
+
+	"github.com/graphql-go/graphql/language/ast"
+	"github.com/graphql-go/graphql/language/parser"
+)
+
+// MaxBatchOperations caps the operations in one batched request, since each
+// is checked against the query limits on its own.
+const MaxBatchOperations = 20
+
+type GraphQLRequest struct {
+	Query         string                 `json:"query"`
+	OperationName string                 `json:"operationName,omitempty"`
+	Variables     map[string]interface{} `json:"variables,omitempty"`
+	Extensions    *RequestExtensions     `json:"extensions,omitempty"`
+}
+
+// decodeRequests reads the operations of a request from the URL parameters
+// of a GET, the raw body of an application/graphql POST, or a JSON body
+// holding one request object or an array of them. batch reports whether
+// the operations came as an array and must be answered with one.
+func decodeRequests(r *http.Request, body []byte) (_ []GraphQLRequest, batch bool, _ error) {
+	if r.Method == http.MethodGet {
+		var req GraphQLRequest
+		if err := parseURLRequest(r.URL.Query(), &req); err != nil {
+			return nil, false, err
+		}
+		return []GraphQLRequest{req}, false, nil
+	}
+
+	if mediaType, _, err := mime.ParseMediaType(r.Header.Get("Content-Type")); err == nil && mediaType == "application/graphql" {
+		return []GraphQLRequest{{Query: string(body)}}, false, nil
+	}
+
+	if trimmed := bytes.TrimLeft(body, " \t\r\n"); len(trimmed) > 0 && trimmed[0] == '[' {
+		var reqs []GraphQLRequest
+		if err := json.Unmarshal(body, &reqs); err != nil {
+			return nil, true, err
+		}
+		if len(reqs) == 0 {
+			return nil, true, errors.New("empty batch")
+		}
+		if len(reqs) > MaxBatchOperations {
+			return nil, true, fmt.Errorf("batch has %d operations, at most %d are allowed", len(reqs), MaxBatchOperations)
+		}
+		return reqs, true, nil
+	}
+
+	var req GraphQLRequest
+	if err := json.Unmarshal(body, &req); err != nil {
+		return nil, false, err
+	}
+	return []GraphQLRequest{req}, false, nil
+}
+
+// parseURLRequest reads a GET request's query, operationName, and its
+// variables and extensions encoded as JSON, from the URL parameters.
+func parseURLRequest(params url.Values, req *GraphQLRequest) error {
+	req.Query = params.Get("query")
+	req.OperationName = params.Get("operationName")
+
+	if v := params.Get("variables"); v != "" {
+		if err := json.Unmarshal([]byte(v), &req.Variables); err != nil {
+			return fmt.Errorf("invalid variables: %w", err)
+		}
+	}
+	if v := params.Get("extensions"); v != "" {
+		if err := json.Unmarshal([]byte(v), &req.Extensions); err != nil {
+			return fmt.Errorf("invalid extensions: %w", err)
+		}
+	}
+	return nil
+}
+
+// checkOperation makes sure operationName picks exactly one operation of
+// query: it must be given when the document has several and must name one of
+// them when given. It returns the operation picked, or nil for unparsable
+// documents, which are left to the executor.
+func checkOperation(query, operationName string) (*ast.OperationDefinition, *db.AppError) {
+	doc, err := parser.Parse(parser.ParseParams{Source: query})
+	if err != nil {
+		return nil, nil
+	}
+
+	var last *ast.OperationDefinition
+	count := 0
+	for _, def := range doc.Definitions {
+		op, ok := def.(*ast.OperationDefinition)
+		if !ok {
+			continue
+		}
+		last = op
+		count++
+		if operationName != "" && op.Name != nil && op.Name.Value == operationName {
+			return op, nil
+		}
+	}
+
+	if operationName != "" {
+		return nil, db.ErrUnknownOperation.WithDetails(map[string]interface{}{"operationName": operationName})
+	}
+	if count > 1 {
+		return nil, db.ErrOperationNameRequired
+	}
+	return last, nil
+}
@@ -0,0 +1,107 @@
+package graphql
+
+import (
+	"strings"
+	"token-transfer-api/internal/amount"
+	"token-transfer-api/internal/db"
+
+	"github.com/graphql-go/graphql"
+	"github.com/graphql-go/graphql/language/ast"
+)
+
+// maxAmountDigits is the precision of the DECIMAL(78, 0) balance and amount
+// columns, enough for any uint256.
+const maxAmountDigits = 78
+
+// Amount is a token amount or balance. Without TOKEN_DECIMALS it is a whole
+// number of base units; with it, a human amount such as "1.5". Its name and
+// spelling do not depend on TOKEN_DECIMALS, so the schema clients see stays
+// the same. It travels as a decimal string without sign, whitespace, exponent
+// or leading zeros; query literals may also be written as integers. Whether
+// the token has enough decimals for an amount is checked by the resolver,
+// which rejects it with AMOUNT_TOO_PRECISE.
+var Amount = graphql.NewScalar(graphql.ScalarConfig{
+	Name:        "Amount",
+	Description: "A non-negative token amount sent as a decimal string, such as \"1000000\" or, with TOKEN_DECIMALS set, \"1.5\".",
+	Serialize: func(value interface{}) interface{} {
+		switch v := value.(type) {
+		case string:
+			return parseAmount(v)
+		case *string:
+			if v == nil {
+				return nil
+			}
+			return parseAmount(*v)
+		}
+		return nil
+	},
+	ParseValue: func(value interface{}) interface{} {
+		if s, ok := value.(string); ok {
+			return parseAmount(s)
+		}
+		return nil
+	},
+	ParseLiteral: func(valueAST ast.Value) interface{} {
+		switch v := valueAST.(type) {
+		case *ast.StringValue:
+			return parseAmount(v.Value)
+		case *ast.IntValue:
+			return parseAmount(v.Value)
+		}
+		return nil
+	},
+})
+
+// parseAmount returns s if it is an Amount, a decimal with at most
+// maxAmountDigits whole digits, and nil otherwise.
+func parseAmount(s string) interface{} {
+	whole, _, _ := strings.Cut(s, ".")
+	if len(whole) > maxAmountDigits {
+		return nil
+	}
+	if _, err := amount.Parse(s, len(s)); err != nil {
+		return nil
+	}
+	return s
+}
+
+// Address is a 0x-prefixed, 20-byte hex address. Malformed addresses are
+// refused while the request is validated, before any resolver runs. Any mix
+// of case is accepted and kept as sent; mixed case is not checked against an
+// EIP-55 checksum, and ADDRESS_CASE_INSENSITIVE decides whether it matters.
+var Address = graphql.NewScalar(graphql.ScalarConfig{
+	Name:        "Address",
+	Description: "An address, 0x followed by 40 hex digits.",
+	Serialize: func(value interface{}) interface{} {
+		switch v := value.(type) {
+		case string:
+			return parseAddress(v)
+		case *string:
+			if v == nil {
+				return nil
+			}
+			return parseAddress(*v)
+		}
+		return nil
+	},
+	ParseValue: func(value interface{}) interface{} {
+		if s, ok := value.(string); ok {
+			return parseAddress(s)
+		}
+		return nil
+	},
+	ParseLiteral: func(valueAST ast.Value) interface{} {
+		if v, ok := valueAST.(*ast.StringValue); ok {
+			return parseAddress(v.Value)
+		}
+		return nil
+	},
+})
+
+// parseAddress returns s if it is a valid address and nil otherwise.
+func parseAddress(s string) interface{} {
+	if !db.ValidAddress(s) {
+		return nil
+	}
+	return s
+}
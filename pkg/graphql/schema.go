@@ -1,100 +1,867 @@
 package graphql
 
 import (
+	"context"
 	"encoding/json"
-	"io"
+	"log"
 	"net/http"
+	"os"
+	"time"
+	"token-transfer-api/internal/db"
 	"token-transfer-api/internal/graph"
+	"token-transfer-api/internal/model"
+	"token-transfer-api/internal/tracing"
 
 	"github.com/graphql-go/graphql"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
 )
 
-type GraphQLRequest struct {
-	Query         string                 `json:"query"`
-	OperationName string                 `json:"operationName,omitempty"`
-	Variables     map[string]interface{} `json:"variables,omitempty"`
-}
+// TestRollbackHeader makes a request run inside a transaction that is rolled
+// back once the response is built. It is only honoured when ENV=test.
+const TestRollbackHeader = "X-Test-Rollback"
 
+// NewHandler returns the GraphQL handler with a resolver of its own,
+// configured from the environment.
 func NewHandler() http.Handler {
-	schema, err := createSchema()
+	resolver, err := graph.NewResolver()
+	if err != nil {
+		panic(err)
+	}
+	return NewHandlerFor(resolver)
+}
+
+// NewHandlerFor returns the GraphQL handler serving resolver. A server that
+// also serves REST or gRPC passes them the same resolver, so transfers share
+// one rate limit, dedup window and sender queue whichever API they come in
+// through.
+func NewHandlerFor(resolver *graph.Resolver) http.Handler {
+	schema, err := createSchema(resolver)
+	if err != nil {
+		panic(err)
+	}
+	RecoverResolvers(schema)
+
+	limits, err := QueryLimitsFromEnv()
+	if err != nil {
+		panic(err)
+	}
+
+	maxBytes, err := MaxRequestBytesFromEnv()
+	if err != nil {
+		panic(err)
+	}
+
+	introspection, err := IntrospectionFromEnv()
+	if err != nil {
+		panic(err)
+	}
+
+	timeout, err := RequestTimeoutFromEnv()
 	if err != nil {
 		panic(err)
 	}
 
+	testMode := os.Getenv("ENV") == "test"
+	debug := os.Getenv("DEBUG") == "true"
+
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 		if r.Method == http.MethodOptions {
 			w.Header().Set("Access-Control-Allow-Origin", "*")
 			w.Header().Set("Access-Control-Allow-Methods", "POST, GET, OPTIONS")
-			w.Header().Set("Access-Control-Allow-Headers", "Content-Type")
+			w.Header().Set("Access-Control-Allow-Headers", "Content-Type, Authorization, "+TestRollbackHeader+", "+RequestIDHeader)
 			w.WriteHeader(http.StatusOK)
 			return
 		}
 
+		id := requestID(r.Header.Get(RequestIDHeader))
 		w.Header().Set("Access-Control-Allow-Origin", "*")
+		w.Header().Set("Access-Control-Expose-Headers", RequestIDHeader)
 		w.Header().Set("Content-Type", "application/json")
+		w.Header().Set(RequestIDHeader, id)
 
-		body, err := io.ReadAll(r.Body)
-		if err != nil {
-			http.Error(w, "Error reading request body", http.StatusBadRequest)
-			return
+		// Requests WithAuth let through come with their persisted queries
+		// resolved, and run as they were checked. Others are decoded and
+		// resolved here, persisted queries first so every later check sees
+		// the query text.
+		resolved := resolvedRequestsFromContext(r.Context())
+		if resolved == nil {
+			var body []byte
+			if r.Method != http.MethodGet {
+				var ok bool
+				if body, ok = readBody(w, r, maxBytes); !ok {
+					return
+				}
+			}
+
+			reqs, batch, err := decodeRequests(r, body)
+			if err != nil {
+				writeError(w, http.StatusBadRequest, db.ErrMalformedRequest.WithDetails(map[string]interface{}{"reason": err.Error()}))
+				return
+			}
+			resolved = resolveRequests(reqs, batch)
+			resolved.register()
 		}
+		reqs, batch, resolveErrs := resolved.reqs, resolved.batch, resolved.errs
 
-		var req GraphQLRequest
-		if err := json.Unmarshal(body, &req); err != nil {
-			http.Error(w, "Error parsing request body", http.StatusBadRequest)
+		if r.Method == http.MethodGet && resolveErrs[0] == nil && hasMutation(reqs[0].Query) {
+			// GET requests may be cached, prefetched or replayed, so they
+			// must not change state
+			w.Header().Set("Allow", http.MethodPost)
+			writeError(w, http.StatusMethodNotAllowed, db.ErrMutationOverGET)
 			return
 		}
 
-		result := executeQuery(schema, req.Query, req.Variables)
-		json.NewEncoder(w).Encode(result)
+		// Spans of the request join the caller's trace when it sent a
+		// traceparent header
+		ctx := WithRequestID(tracing.Extract(r.Context(), r.Header), id)
+		if timeout > 0 {
+			var cancel context.CancelFunc
+			ctx, cancel = context.WithTimeout(ctx, timeout)
+			defer cancel()
+		}
+		// Nested wallet lookups are batched per request. A loader already
+		// in the request context, such as one a test counts queries with,
+		// is used instead.
+		if walletLoaderFromContext(ctx) == nil {
+			ctx = WithWalletLoader(ctx, NewWalletLoader(db.GetWalletsContext))
+		}
+		if testMode && r.Header.Get(TestRollbackHeader) == "true" {
+			tx, err := db.BeginTx(ctx)
+			if err != nil {
+				log.Printf("Failed to start test transaction: %v", err)
+				writeError(w, http.StatusInternalServerError, db.ErrInternal)
+				return
+			}
+			defer func() {
+				if err := tx.Rollback(); err != nil {
+					log.Printf("Failed to roll back test transaction: %v", err)
+				}
+			}()
+			ctx = db.WithTx(ctx, tx)
+		}
+
+		// Batched operations run one after another in the same context
+		results := make([]*graphql.Result, len(reqs))
+		for i, req := range reqs {
+			if resolveErrs[i] != nil {
+				results[i] = errorResult(resolveErrs[i])
+				continue
+			}
+			if err := limits.Check(&schema, req.Query, req.Variables); err != nil {
+				results[i] = errorResult(err)
+				continue
+			}
+
+			results[i] = executeQuery(ctx, schema, req, introspection)
+			if timedOut(ctx, results[i]) {
+				results[i] = errorResult(db.ErrRequestTimeout)
+			}
+		}
+
+		// A batch answers 200 since each operation reports its own errors
+		status := http.StatusOK
+		if !batch {
+			status = resultStatus(results[0])
+		}
+		if !debug {
+			for _, result := range results {
+				result.Errors = SanitizeErrors(result.Errors)
+			}
+		}
+
+		w.WriteHeader(status)
+		if batch {
+			json.NewEncoder(w).Encode(results)
+		} else {
+			json.NewEncoder(w).Encode(results[0])
+		}
 	})
 }
 
-func executeQuery(schema graphql.Schema, query string, variables map[string]interface{}) *graphql.Result {
+// executeQuery runs the operation of req picked by its operationName against
+// schema. Unless introspection is true, documents selecting __schema or
+// __type are rejected without running. A panic outside the resolvers, which
+// RecoverResolvers does not cover, fails the operation with ErrInternal.
+//
+// Each operation gets a span, named after its type and name once the
+// document is parsed, under which the spans of its resolvers nest. It is
+// marked failed when the result carries errors.
+func executeQuery(ctx context.Context, schema graphql.Schema, req GraphQLRequest, introspection bool) (result *graphql.Result) {
+	ctx, span := tracing.Start(ctx, "GraphQL Operation")
+	defer func() {
+		if len(result.Errors) > 0 {
+			span.SetStatus(codes.Error, result.Errors[0].Message)
+		}
+		span.End()
+	}()
+	defer func() {
+		if r := recover(); r != nil {
+			logPanic(ctx, r)
+			result = errorResult(db.ErrInternal)
+		}
+	}()
+
+	if !introspection && hasIntrospection(req.Query) {
+		return errorResult(db.ErrIntrospectionDisabled)
+	}
+	op, err := checkOperation(req.Query, req.OperationName)
+	if err != nil {
+		return errorResult(err)
+	}
+	if op != nil {
+		span.SetName(op.Operation)
+		span.SetAttributes(attribute.String("graphql.operation.type", op.Operation))
+		if op.Name != nil {
+			span.SetName(op.Operation + " " + op.Name.Value)
+			span.SetAttributes(attribute.String("graphql.operation.name", op.Name.Value))
+		}
+	}
+
 	return graphql.Do(graphql.Params{
 		Schema:         schema,
-		RequestString:  query,
-		VariableValues: variables,
+		RequestString:  req.Query,
+		VariableValues: req.Variables,
+		OperationName:  req.OperationName,
+		Context:        ctx,
 	})
 }
 
-func createSchema() (graphql.Schema, error) {
-	resolver := &graph.Resolver{}
-
+func createSchema(resolver *graph.Resolver) (graphql.Schema, error) {
 	walletType := graphql.NewObject(graphql.ObjectConfig{
 		Name: "Wallet",
 		Fields: graphql.Fields{
 			"address": &graphql.Field{
 				Type: graphql.String,
 			},
-			"balance": &graphql.Field{
+			"balance":      amountField(resolver),
+			"reserved":     amountField(resolver),
+			"held_balance": amountField(resolver),
+			"status": &graphql.Field{
+				Type: walletStatusEnum,
+			},
+			"frozen": &graphql.Field{
+				Type: graphql.Boolean,
+			},
+			"nonce": &graphql.Field{
+				Type: graphql.Int,
+			},
+			"last_activity_at": &graphql.Field{
+				Type: graphql.DateTime,
+			},
+		},
+	})
+
+	walletOrderEnum := graphql.NewEnum(graphql.EnumConfig{
+		Name: "WalletOrder",
+		Values: graphql.EnumValueConfigMap{
+			"BALANCE_DESC": &graphql.EnumValueConfig{Value: "BALANCE_DESC"},
+			"BALANCE_ASC":  &graphql.EnumValueConfig{Value: "BALANCE_ASC"},
+			"ADDRESS_ASC":  &graphql.EnumValueConfig{Value: "ADDRESS_ASC"},
+		},
+	})
+
+	neighborType := graphql.NewObject(graphql.ObjectConfig{
+		Name: "Neighbor",
+		Fields: graphql.Fields{
+			"address": &graphql.Field{
+				Type: graphql.String,
+			},
+			"total": amountField(resolver),
+		},
+	})
+
+	holderType := graphql.NewObject(graphql.ObjectConfig{
+		Name: "Holder",
+		Fields: graphql.Fields{
+			"address": &graphql.Field{
+				Type: graphql.String,
+			},
+			"balance": amountField(resolver),
+			"share_bps": &graphql.Field{
+				Type: graphql.Int,
+			},
+		},
+	})
+
+	walletStatsType := graphql.NewObject(graphql.ObjectConfig{
+		Name: "WalletStats",
+		Fields: graphql.Fields{
+			"address": &graphql.Field{
+				Type: graphql.String,
+			},
+			"total_sent":     amountField(resolver),
+			"total_received": amountField(resolver),
+			"transfer_count_out": &graphql.Field{
+				Type: graphql.Int,
+			},
+			"transfer_count_in": &graphql.Field{
+				Type: graphql.Int,
+			},
+		},
+	})
+
+	intervalEnum := graphql.NewEnum(graphql.EnumConfig{
+		Name: "Interval",
+		Values: graphql.EnumValueConfigMap{
+			"HOUR": &graphql.EnumValueConfig{Value: "HOUR"},
+			"DAY":  &graphql.EnumValueConfig{Value: "DAY"},
+			"WEEK": &graphql.EnumValueConfig{Value: "WEEK"},
+		},
+	})
+
+	transferVolumeType := graphql.NewObject(graphql.ObjectConfig{
+		Name: "TransferVolume",
+		Fields: graphql.Fields{
+			"bucket": &graphql.Field{
+				Type: graphql.DateTime,
+			},
+			"total_amount": amountField(resolver),
+			"count": &graphql.Field{
+				Type: graphql.Int,
+			},
+		},
+	})
+
+	balanceSnapshotType := graphql.NewObject(graphql.ObjectConfig{
+		Name: "BalanceSnapshot",
+		Fields: graphql.Fields{
+			"address": &graphql.Field{
 				Type: graphql.String,
 			},
+			"balance": amountField(resolver),
+			"recorded_at": &graphql.Field{
+				Type: graphql.DateTime,
+			},
 		},
 	})
 
 	transferResultType := graphql.NewObject(graphql.ObjectConfig{
 		Name: "TransferResult",
 		Fields: graphql.Fields{
-			"balance": &graphql.Field{
+			"balance": amountField(resolver),
+			"fee":     amountField(resolver),
+			"would_succeed": &graphql.Field{
+				Type: graphql.Boolean,
+			},
+			"failure_code": &graphql.Field{
+				Type:    graphql.String,
+				Resolve: resolveOptional(func(r *model.TransferResult) string { return r.FailureCode }),
+			},
+			"failure_message": &graphql.Field{
+				Type:    graphql.String,
+				Resolve: resolveOptional(func(r *model.TransferResult) string { return r.FailureMessage }),
+			},
+			"client_request_id": &graphql.Field{
+				Type:    graphql.String,
+				Resolve: resolveOptional(func(r *model.TransferResult) string { return r.ClientRequestID }),
+			},
+		},
+	})
+
+	transferType := graphql.NewObject(graphql.ObjectConfig{
+		Name: "Transfer",
+		Fields: graphql.Fields{
+			"id": &graphql.Field{
+				Type: graphql.Int,
+			},
+			"from_address": &graphql.Field{
+				Type: graphql.String,
+			},
+			"to_address": &graphql.Field{
 				Type: graphql.String,
 			},
+			"amount": amountField(resolver),
+			"refund_of": &graphql.Field{
+				Type: graphql.Int,
+			},
+			"swap_of": &graphql.Field{
+				Type: graphql.Int,
+			},
+			"from_balance_after": amountField(resolver),
+			"to_balance_after":   amountField(resolver),
+			"memo": &graphql.Field{
+				Type: graphql.String,
+			},
+			"token": &graphql.Field{
+				Type:    graphql.String,
+				Resolve: resolveTransferToken,
+			},
+			"sender": &graphql.Field{
+				Type:    walletType,
+				Resolve: resolveTransferWallet(func(t *model.Transfer) string { return t.FromAddress }),
+			},
+			"receiver": &graphql.Field{
+				Type:    walletType,
+				Resolve: resolveTransferWallet(func(t *model.Transfer) string { return t.ToAddress }),
+			},
+			"created_at": &graphql.Field{
+				Type: graphql.DateTime,
+			},
 		},
 	})
 
+	// The transfers of a wallet are only loaded when selected, so wallet
+	// lookups without them cost no extra query.
+	walletType.AddFieldConfig("transfers", &graphql.Field{
+		Type: graphql.NewNonNull(graphql.NewList(graphql.NewNonNull(transferType))),
+		Args: graphql.FieldConfigArgument{
+			"limit": &graphql.ArgumentConfig{
+				Type:         graphql.Int,
+				DefaultValue: db.DefaultTransferPageSize,
+			},
+		},
+		Resolve: resolveWalletTransfers(resolver),
+	})
+
+	scheduledTransferType := graphql.NewObject(graphql.ObjectConfig{
+		Name: "ScheduledTransfer",
+		Fields: graphql.Fields{
+			"id": &graphql.Field{
+				Type: graphql.Int,
+			},
+			"from_address": &graphql.Field{
+				Type: graphql.String,
+			},
+			"to_address": &graphql.Field{
+				Type: graphql.String,
+			},
+			"amount": amountField(resolver),
+			"execute_at": &graphql.Field{
+				Type: graphql.DateTime,
+			},
+			"status": &graphql.Field{
+				Type: graphql.String,
+			},
+			"failure_code": &graphql.Field{
+				Type: graphql.String,
+			},
+			"failure_message": &graphql.Field{
+				Type: graphql.String,
+			},
+			"executed_at": &graphql.Field{
+				Type: graphql.DateTime,
+			},
+			"created_at": &graphql.Field{
+				Type: graphql.DateTime,
+			},
+		},
+	})
+
+	ledgerIntegrityType := graphql.NewObject(graphql.ObjectConfig{
+		Name: "LedgerIntegrity",
+		Fields: graphql.Fields{
+			"consistent": &graphql.Field{
+				Type: graphql.Boolean,
+			},
+			"wallet_sum": amountField(resolver),
+			"expected":   amountField(resolver),
+		},
+	})
+
+	transferEdgeType := graphql.NewObject(graphql.ObjectConfig{
+		Name: "TransferEdge",
+		Fields: graphql.Fields{
+			"cursor": &graphql.Field{
+				Type: graphql.NewNonNull(graphql.String),
+			},
+			"node": &graphql.Field{
+				Type: transferType,
+			},
+		},
+	})
+
+	pageInfoType := graphql.NewObject(graphql.ObjectConfig{
+		Name: "PageInfo",
+		Fields: graphql.Fields{
+			"hasNextPage": &graphql.Field{
+				Type: graphql.NewNonNull(graphql.Boolean),
+			},
+			"endCursor": &graphql.Field{
+				Type: graphql.String,
+				Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+					pageInfo := p.Source.(model.PageInfo)
+					if pageInfo.EndCursor == "" {
+						return nil, nil
+					}
+					return pageInfo.EndCursor, nil
+				},
+			},
+		},
+	})
+
+	transferConnectionType := graphql.NewObject(graphql.ObjectConfig{
+		Name: "TransferConnection",
+		Fields: graphql.Fields{
+			"edges": &graphql.Field{
+				Type: graphql.NewList(transferEdgeType),
+			},
+			"pageInfo": &graphql.Field{
+				Type: graphql.NewNonNull(pageInfoType),
+			},
+		},
+	})
+
+	walletConnectionType := graphql.NewObject(graphql.ObjectConfig{
+		Name: "WalletConnection",
+		Fields: graphql.Fields{
+			"nodes": &graphql.Field{
+				Type: graphql.NewList(walletType),
+			},
+			"totalCount": &graphql.Field{
+				Type: graphql.NewNonNull(graphql.Int),
+			},
+			"pageInfo": &graphql.Field{
+				Type: graphql.NewNonNull(pageInfoType),
+			},
+		},
+	})
+
+	refundResultType := graphql.NewObject(graphql.ObjectConfig{
+		Name: "RefundResult",
+		Fields: graphql.Fields{
+			"transfer": &graphql.Field{
+				Type: transferType,
+			},
+			"from_balance": amountField(resolver),
+			"to_balance":   amountField(resolver),
+		},
+	})
+
+	swapResultType := graphql.NewObject(graphql.ObjectConfig{
+		Name: "SwapResult",
+		Fields: graphql.Fields{
+			"transfer_a": &graphql.Field{
+				Type: transferType,
+			},
+			"transfer_b": &graphql.Field{
+				Type: transferType,
+			},
+			"balance_a": amountField(resolver),
+			"balance_b": amountField(resolver),
+		},
+	})
+
+	sweepResultType := graphql.NewObject(graphql.ObjectConfig{
+		Name: "SweepResult",
+		Fields: graphql.Fields{
+			"transfer": &graphql.Field{
+				Type: transferType,
+			},
+			"from_balance": amountField(resolver),
+			"to_balance":   amountField(resolver),
+		},
+	})
+
+	holdType := graphql.NewObject(graphql.ObjectConfig{
+		Name: "Hold",
+		Fields: graphql.Fields{
+			"id": &graphql.Field{
+				Type: graphql.Int,
+			},
+			"from_address": &graphql.Field{
+				Type: graphql.String,
+			},
+			"amount": amountField(resolver),
+			"status": &graphql.Field{
+				Type: graphql.String,
+			},
+			"to_address": &graphql.Field{
+				Type: graphql.String,
+			},
+			"transfer_id": &graphql.Field{
+				Type: graphql.Int,
+			},
+			"settled_at": &graphql.Field{
+				Type: graphql.DateTime,
+			},
+			"created_at": &graphql.Field{
+				Type: graphql.DateTime,
+			},
+		},
+	})
+
+	// The supply is not a field of a source, so it is formatted here
+	totalSupplyField := amountField(resolver)
+	totalSupplyField.Resolve = func(p graphql.ResolveParams) (interface{}, error) {
+		supply, err := resolver.GetTotalSupply(p.Context)
+		if err != nil {
+			return nil, err
+		}
+		raw, _ := p.Args["raw"].(bool)
+		return resolver.FormatBalance(supply, raw), nil
+	}
+
 	queryType := graphql.NewObject(graphql.ObjectConfig{
 		Name: "Query",
 		Fields: graphql.Fields{
 			"wallet": &graphql.Field{
 				Type: walletType,
+				Args: graphql.FieldConfigArgument{
+					"address": &graphql.ArgumentConfig{
+						Type: graphql.NewNonNull(Address),
+					},
+					"token": &graphql.ArgumentConfig{
+						Type: graphql.String,
+					},
+				},
+				Resolve: resolveWallet(resolver),
+			},
+			"walletOrZero": &graphql.Field{
+				Type: graphql.NewNonNull(walletType),
+				Args: graphql.FieldConfigArgument{
+					"address": &graphql.ArgumentConfig{
+						Type: graphql.NewNonNull(Address),
+					},
+				},
+				Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+					address := p.Args["address"].(string)
+					return resolver.GetWalletOrZero(p.Context, address)
+				},
+			},
+			"balances": &graphql.Field{
+				Type: graphql.NewList(graphql.NewNonNull(walletType)),
+				Args: graphql.FieldConfigArgument{
+					"addresses": &graphql.ArgumentConfig{
+						Type: graphql.NewNonNull(graphql.NewList(graphql.NewNonNull(graphql.String))),
+					},
+					"include_unknown": &graphql.ArgumentConfig{
+						Type:         graphql.Boolean,
+						DefaultValue: true,
+					},
+				},
+				Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+					var addresses []string
+					for _, address := range p.Args["addresses"].([]interface{}) {
+						addresses = append(addresses, address.(string))
+					}
+					includeUnknown, _ := p.Args["include_unknown"].(bool)
+					return resolver.GetBalances(p.Context, addresses, includeUnknown)
+				},
+			},
+			"wallets": &graphql.Field{
+				Type: graphql.NewList(walletType),
+				Args: graphql.FieldConfigArgument{
+					"limit": &graphql.ArgumentConfig{
+						Type:         graphql.Int,
+						DefaultValue: db.DefaultWalletPageSize,
+					},
+					"offset": &graphql.ArgumentConfig{
+						Type:         graphql.Int,
+						DefaultValue: 0,
+					},
+					"orderBy": &graphql.ArgumentConfig{
+						Type:         walletOrderEnum,
+						DefaultValue: "ADDRESS_ASC",
+					},
+				},
+				Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+					limit, _ := p.Args["limit"].(int)
+					offset, _ := p.Args["offset"].(int)
+					order, _ := p.Args["orderBy"].(string)
+					return resolver.ListWallets(p.Context, limit, offset, order)
+				},
+			},
+			"walletsConnection": &graphql.Field{
+				Type: walletConnectionType,
+				Args: graphql.FieldConfigArgument{
+					"limit": &graphql.ArgumentConfig{
+						Type:         graphql.Int,
+						DefaultValue: db.DefaultWalletPageSize,
+					},
+					"offset": &graphql.ArgumentConfig{
+						Type:         graphql.Int,
+						DefaultValue: 0,
+					},
+					"orderBy": &graphql.ArgumentConfig{
+						Type:         walletOrderEnum,
+						DefaultValue: "ADDRESS_ASC",
+					},
+				},
+				Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+					limit, _ := p.Args["limit"].(int)
+					offset, _ := p.Args["offset"].(int)
+					order, _ := p.Args["orderBy"].(string)
+					return resolver.ListWalletsWithCount(p.Context, limit, offset, order)
+				},
+			},
+			"dormantWallets": &graphql.Field{
+				Type: graphql.NewList(walletType),
+				Args: graphql.FieldConfigArgument{
+					"inactive_since": &graphql.ArgumentConfig{
+						Type: graphql.NewNonNull(graphql.DateTime),
+					},
+					"limit": &graphql.ArgumentConfig{
+						Type:         graphql.Int,
+						DefaultValue: db.DefaultWalletPageSize,
+					},
+					"offset": &graphql.ArgumentConfig{
+						Type:         graphql.Int,
+						DefaultValue: 0,
+					},
+				},
+				Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+					inactiveSince := p.Args["inactive_since"].(time.Time)
+					limit, _ := p.Args["limit"].(int)
+					offset, _ := p.Args["offset"].(int)
+					return resolver.ListDormantWallets(p.Context, inactiveSince, limit, offset)
+				},
+			},
+			"neighbors": &graphql.Field{
+				Type: graphql.NewList(neighborType),
 				Args: graphql.FieldConfigArgument{
 					"address": &graphql.ArgumentConfig{
 						Type: graphql.NewNonNull(graphql.String),
 					},
+					"limit": &graphql.ArgumentConfig{
+						Type:         graphql.Int,
+						DefaultValue: db.DefaultNeighborLimit,
+					},
 				},
 				Resolve: func(p graphql.ResolveParams) (interface{}, error) {
 					address := p.Args["address"].(string)
-					return resolver.GetWallet(address)
+					limit, _ := p.Args["limit"].(int)
+					return resolver.GetNeighbors(p.Context, address, limit)
+				},
+			},
+			"topHolders": &graphql.Field{
+				Type: graphql.NewList(holderType),
+				Args: graphql.FieldConfigArgument{
+					"limit": &graphql.ArgumentConfig{
+						Type:         graphql.Int,
+						DefaultValue: db.DefaultHolderLimit,
+					},
+					"exclude_zero_address": &graphql.ArgumentConfig{
+						Type:         graphql.Boolean,
+						DefaultValue: false,
+					},
+				},
+				Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+					limit, _ := p.Args["limit"].(int)
+					exclude, _ := p.Args["exclude_zero_address"].(bool)
+					return resolver.GetTopHolders(p.Context, limit, exclude)
+				},
+			},
+			"walletStats": &graphql.Field{
+				Type: walletStatsType,
+				Args: graphql.FieldConfigArgument{
+					"address": &graphql.ArgumentConfig{
+						Type: graphql.NewNonNull(graphql.String),
+					},
+				},
+				Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+					address := p.Args["address"].(string)
+					return resolver.GetWalletStats(p.Context, address)
+				},
+			},
+			"transferVolume": &graphql.Field{
+				Type: graphql.NewNonNull(graphql.NewList(graphql.NewNonNull(transferVolumeType))),
+				Args: graphql.FieldConfigArgument{
+					"interval": &graphql.ArgumentConfig{
+						Type: graphql.NewNonNull(intervalEnum),
+					},
+					"since": &graphql.ArgumentConfig{
+						Type: graphql.DateTime,
+					},
+					"until": &graphql.ArgumentConfig{
+						Type: graphql.DateTime,
+					},
+				},
+				Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+					interval := p.Args["interval"].(string)
+					var since, until *time.Time
+					if t, ok := p.Args["since"].(time.Time); ok {
+						since = &t
+					}
+					if t, ok := p.Args["until"].(time.Time); ok {
+						until = &t
+					}
+					return resolver.GetTransferVolume(p.Context, interval, since, until)
+				},
+			},
+			"balanceHistory": &graphql.Field{
+				Type: graphql.NewNonNull(graphql.NewList(graphql.NewNonNull(balanceSnapshotType))),
+				Args: graphql.FieldConfigArgument{
+					"address": &graphql.ArgumentConfig{
+						Type: graphql.NewNonNull(graphql.String),
+					},
+					"since": &graphql.ArgumentConfig{
+						Type: graphql.DateTime,
+					},
+					"until": &graphql.ArgumentConfig{
+						Type: graphql.DateTime,
+					},
+				},
+				Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+					address := p.Args["address"].(string)
+					var since, until *time.Time
+					if t, ok := p.Args["since"].(time.Time); ok {
+						since = &t
+					}
+					if t, ok := p.Args["until"].(time.Time); ok {
+						until = &t
+					}
+					return resolver.GetBalanceHistory(p.Context, address, since, until)
+				},
+			},
+			"transfers": &graphql.Field{
+				Type: transferConnectionType,
+				Args: graphql.FieldConfigArgument{
+					"first": &graphql.ArgumentConfig{
+						Type:         graphql.Int,
+						DefaultValue: db.DefaultTransferPageSize,
+					},
+					"after": &graphql.ArgumentConfig{
+						Type: graphql.String,
+					},
+				},
+				Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+					first, _ := p.Args["first"].(int)
+					after, _ := p.Args["after"].(string)
+					return resolver.ListTransfers(p.Context, first, after)
+				},
+			},
+			"transfer": &graphql.Field{
+				Type: transferType,
+				Args: graphql.FieldConfigArgument{
+					"id": &graphql.ArgumentConfig{
+						Type: graphql.NewNonNull(graphql.Int),
+					},
+				},
+				Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+					id := p.Args["id"].(int)
+					return resolver.GetTransfer(p.Context, int64(id))
+				},
+			},
+			"scheduledTransfer": &graphql.Field{
+				Type: scheduledTransferType,
+				Args: graphql.FieldConfigArgument{
+					"id": &graphql.ArgumentConfig{
+						Type: graphql.NewNonNull(graphql.Int),
+					},
+				},
+				Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+					id := p.Args["id"].(int)
+					return resolver.GetScheduledTransfer(p.Context, int64(id))
+				},
+			},
+			"totalSupply": totalSupplyField,
+			"ledgerIntegrity": &graphql.Field{
+				Type: ledgerIntegrityType,
+				Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+					return resolver.LedgerIntegrity(p.Context)
+				},
+			},
+			"walletCount": &graphql.Field{
+				Type: graphql.Int,
+				Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+					return resolver.GetWalletCount(p.Context)
+				},
+			},
+			"readOnly": &graphql.Field{
+				Type: graphql.Boolean,
+				Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+					return graph.ReadOnly(), nil
 				},
 			},
 		},
@@ -107,22 +874,297 @@ func createSchema() (graphql.Schema, error) {
 				Type: transferResultType,
 				Args: graphql.FieldConfigArgument{
 					"from_address": &graphql.ArgumentConfig{
-						Type: graphql.NewNonNull(graphql.String),
+						Type: graphql.NewNonNull(Address),
 					},
 					"to_address": &graphql.ArgumentConfig{
-						Type: graphql.NewNonNull(graphql.String),
+						Type: graphql.NewNonNull(Address),
+					},
+					"amount": &graphql.ArgumentConfig{
+						Type: graphql.NewNonNull(Amount),
+					},
+					"client_request_id": &graphql.ArgumentConfig{
+						Type: graphql.String,
+					},
+					"dry_run": &graphql.ArgumentConfig{
+						Type:         graphql.Boolean,
+						DefaultValue: false,
+					},
+					"memo": &graphql.ArgumentConfig{
+						Type: graphql.String,
+					},
+					"expected_nonce": &graphql.ArgumentConfig{
+						Type: graphql.Int,
+					},
+					"signature": &graphql.ArgumentConfig{
+						Type: graphql.String,
+					},
+					"require_min_balance": &graphql.ArgumentConfig{
+						Type: graphql.String,
+					},
+					"token": &graphql.ArgumentConfig{
+						Type: graphql.String,
+					},
+				},
+				Resolve: resolveTransfer(resolver),
+			},
+			"scheduleTransfer": &graphql.Field{
+				Type: scheduledTransferType,
+				Args: graphql.FieldConfigArgument{
+					"from_address": &graphql.ArgumentConfig{
+						Type: graphql.NewNonNull(Address),
+					},
+					"to_address": &graphql.ArgumentConfig{
+						Type: graphql.NewNonNull(Address),
+					},
+					"amount": &graphql.ArgumentConfig{
+						Type: graphql.NewNonNull(Amount),
+					},
+					"execute_at": &graphql.ArgumentConfig{
+						Type: graphql.NewNonNull(graphql.DateTime),
+					},
+				},
+				Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+					fromAddress := p.Args["from_address"].(string)
+					toAddress := p.Args["to_address"].(string)
+					amount := p.Args["amount"].(string)
+					executeAt := p.Args["execute_at"].(time.Time)
+					return resolver.ScheduleTransfer(p.Context, fromAddress, toAddress, amount, executeAt)
+				},
+			},
+			"mint": &graphql.Field{
+				Type: walletType,
+				Args: graphql.FieldConfigArgument{
+					"to_address": &graphql.ArgumentConfig{
+						Type: graphql.NewNonNull(Address),
 					},
 					"amount": &graphql.ArgumentConfig{
+						Type: graphql.NewNonNull(Amount),
+					},
+					"token": &graphql.ArgumentConfig{
+						Type: graphql.String,
+					},
+				},
+				Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+					toAddress := p.Args["to_address"].(string)
+					amount := p.Args["amount"].(string)
+					token, _ := p.Args["token"].(string)
+					return resolver.Mint(p.Context, toAddress, amount, token)
+				},
+			},
+			"burn": &graphql.Field{
+				Type: graphql.String,
+				Args: graphql.FieldConfigArgument{
+					"from_address": &graphql.ArgumentConfig{
+						Type: graphql.NewNonNull(Address),
+					},
+					"amount": &graphql.ArgumentConfig{
+						Type: graphql.NewNonNull(Amount),
+					},
+				},
+				Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+					fromAddress := p.Args["from_address"].(string)
+					amount := p.Args["amount"].(string)
+					return resolver.Burn(p.Context, fromAddress, amount)
+				},
+			},
+			"addAddressToBlocklist": &graphql.Field{
+				Type: graphql.Boolean,
+				Args: graphql.FieldConfigArgument{
+					"address": &graphql.ArgumentConfig{
 						Type: graphql.NewNonNull(graphql.String),
 					},
 				},
 				Resolve: func(p graphql.ResolveParams) (interface{}, error) {
-					args := graph.TransferArgs{
-						FromAddress: p.Args["from_address"].(string),
-						ToAddress:   p.Args["to_address"].(string),
-						Amount:      p.Args["amount"].(string),
+					address := p.Args["address"].(string)
+					return resolver.BlockAddress(p.Context, address)
+				},
+			},
+			"removeAddressFromBlocklist": &graphql.Field{
+				Type: graphql.Boolean,
+				Args: graphql.FieldConfigArgument{
+					"address": &graphql.ArgumentConfig{
+						Type: graphql.NewNonNull(graphql.String),
+					},
+				},
+				Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+					address := p.Args["address"].(string)
+					return resolver.UnblockAddress(p.Context, address)
+				},
+			},
+			"setWalletStatus": &graphql.Field{
+				Type: walletType,
+				Args: graphql.FieldConfigArgument{
+					"address": &graphql.ArgumentConfig{
+						Type: graphql.NewNonNull(Address),
+					},
+					"status": &graphql.ArgumentConfig{
+						Type: graphql.NewNonNull(walletStatusEnum),
+					},
+				},
+				Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+					address := p.Args["address"].(string)
+					status := p.Args["status"].(string)
+					return resolver.SetWalletStatus(p.Context, address, status)
+				},
+			},
+			"freezeWallet": &graphql.Field{
+				Type: walletType,
+				Args: graphql.FieldConfigArgument{
+					"address": &graphql.ArgumentConfig{
+						Type: graphql.NewNonNull(Address),
+					},
+				},
+				Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+					address := p.Args["address"].(string)
+					return resolver.FreezeWallet(p.Context, address)
+				},
+			},
+			"unfreezeWallet": &graphql.Field{
+				Type: walletType,
+				Args: graphql.FieldConfigArgument{
+					"address": &graphql.ArgumentConfig{
+						Type: graphql.NewNonNull(Address),
+					},
+				},
+				Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+					address := p.Args["address"].(string)
+					return resolver.UnfreezeWallet(p.Context, address)
+				},
+			},
+			"setReserve": &graphql.Field{
+				Type: walletType,
+				Args: graphql.FieldConfigArgument{
+					"address": &graphql.ArgumentConfig{
+						Type: graphql.NewNonNull(Address),
+					},
+					"amount": &graphql.ArgumentConfig{
+						Type: graphql.NewNonNull(Amount),
+					},
+				},
+				Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+					address := p.Args["address"].(string)
+					amount := p.Args["amount"].(string)
+					return resolver.SetReserve(p.Context, address, amount)
+				},
+			},
+			"refundTransfer": &graphql.Field{
+				Type: refundResultType,
+				Args: graphql.FieldConfigArgument{
+					"transfer_id": &graphql.ArgumentConfig{
+						Type: graphql.NewNonNull(graphql.Int),
+					},
+				},
+				Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+					transferID := p.Args["transfer_id"].(int)
+					return resolver.RefundTransfer(p.Context, int64(transferID))
+				},
+			},
+			"swap": &graphql.Field{
+				Type: swapResultType,
+				Args: graphql.FieldConfigArgument{
+					"wallet_a": &graphql.ArgumentConfig{
+						Type: graphql.NewNonNull(Address),
+					},
+					"wallet_b": &graphql.ArgumentConfig{
+						Type: graphql.NewNonNull(Address),
+					},
+					"amount_a": &graphql.ArgumentConfig{
+						Type: graphql.NewNonNull(Amount),
+					},
+					"amount_b": &graphql.ArgumentConfig{
+						Type: graphql.NewNonNull(Amount),
+					},
+				},
+				Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+					walletA := p.Args["wallet_a"].(string)
+					walletB := p.Args["wallet_b"].(string)
+					amountA := p.Args["amount_a"].(string)
+					amountB := p.Args["amount_b"].(string)
+					return resolver.Swap(p.Context, walletA, walletB, amountA, amountB)
+				},
+			},
+			"sweep": &graphql.Field{
+				Type: sweepResultType,
+				Args: graphql.FieldConfigArgument{
+					"from_address": &graphql.ArgumentConfig{
+						Type: graphql.NewNonNull(Address),
+					},
+					"to_address": &graphql.ArgumentConfig{
+						Type: graphql.NewNonNull(Address),
+					},
+					"expected_nonce": &graphql.ArgumentConfig{
+						Type: graphql.Int,
+					},
+					"signature": &graphql.ArgumentConfig{
+						Type: graphql.String,
+					},
+				},
+				Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+					fromAddress := p.Args["from_address"].(string)
+					toAddress := p.Args["to_address"].(string)
+					var expectedNonce *int64
+					if nonce, ok := p.Args["expected_nonce"].(int); ok {
+						expected := int64(nonce)
+						expectedNonce = &expected
 					}
-					return resolver.Transfer(args)
+					signature, _ := p.Args["signature"].(string)
+					return resolver.Sweep(p.Context, fromAddress, toAddress, expectedNonce, signature)
+				},
+			},
+			"createHold": &graphql.Field{
+				Type: holdType,
+				Args: graphql.FieldConfigArgument{
+					"from": &graphql.ArgumentConfig{
+						Type: graphql.NewNonNull(Address),
+					},
+					"amount": &graphql.ArgumentConfig{
+						Type: graphql.NewNonNull(Amount),
+					},
+				},
+				Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+					from := p.Args["from"].(string)
+					amount := p.Args["amount"].(string)
+					return resolver.CreateHold(p.Context, from, amount)
+				},
+			},
+			"releaseHold": &graphql.Field{
+				Type: holdType,
+				Args: graphql.FieldConfigArgument{
+					"hold_id": &graphql.ArgumentConfig{
+						Type: graphql.NewNonNull(graphql.Int),
+					},
+					"to": &graphql.ArgumentConfig{
+						Type: graphql.NewNonNull(Address),
+					},
+				},
+				Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+					holdID := p.Args["hold_id"].(int)
+					to := p.Args["to"].(string)
+					return resolver.ReleaseHold(p.Context, int64(holdID), to)
+				},
+			},
+			"cancelHold": &graphql.Field{
+				Type: holdType,
+				Args: graphql.FieldConfigArgument{
+					"hold_id": &graphql.ArgumentConfig{
+						Type: graphql.NewNonNull(graphql.Int),
+					},
+				},
+				Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+					holdID := p.Args["hold_id"].(int)
+					return resolver.CancelHold(p.Context, int64(holdID))
+				},
+			},
+			"setReadOnly": &graphql.Field{
+				Type: graphql.Boolean,
+				Args: graphql.FieldConfigArgument{
+					"enabled": &graphql.ArgumentConfig{
+						Type: graphql.NewNonNull(graphql.Boolean),
+					},
+				},
+				Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+					enabled := p.Args["enabled"].(bool)
+					return resolver.SetReadOnlyMode(p.Context, enabled)
 				},
 			},
 		},
@@ -133,3 +1175,150 @@ func createSchema() (graphql.Schema, error) {
 		Mutation: mutationType,
 	})
 }
+
+// walletStatusEnum exposes the wallet statuses under their GraphQL names.
+var walletStatusEnum = graphql.NewEnum(graphql.EnumConfig{
+	Name: "WalletStatus",
+	Values: graphql.EnumValueConfigMap{
+		"ACTIVE": &graphql.EnumValueConfig{Value: db.WalletActive},
+		"FROZEN": &graphql.EnumValueConfig{Value: db.WalletFrozen},
+		"CLOSED": &graphql.EnumValueConfig{Value: db.WalletClosed},
+	},
+})
+
+// resolveWalletTransfers resolves the transfers field of a wallet from the
+// address of the parent wallet, which lookups return by pointer and lists by
+// value.
+func resolveWalletTransfers(resolver *graph.Resolver) graphql.FieldResolveFn {
+	return func(p graphql.ResolveParams) (interface{}, error) {
+		var address string
+		switch wallet := p.Source.(type) {
+		case *model.Wallet:
+			address = wallet.Address
+		case model.Wallet:
+			address = wallet.Address
+		}
+		limit, _ := p.Args["limit"].(int)
+		transfers, err := resolver.GetWalletTransfers(p.Context, address, limit)
+		if err != nil {
+			return nil, err
+		}
+
+		// Transfer fields resolve from a *model.Transfer.
+		out := make([]*model.Transfer, len(transfers))
+		for i := range transfers {
+			out[i] = &transfers[i]
+		}
+		return out, nil
+	}
+}
+
+// resolveTransferToken resolves the token of a transfer, naming the primary
+// token for transfers that moved it.
+func resolveTransferToken(p graphql.ResolveParams) (interface{}, error) {
+	if token := p.Source.(*model.Transfer).Token; token != "" {
+		return token, nil
+	}
+	return db.Settings.PrimaryToken, nil
+}
+
+// resolveTransferWallet resolves the sender or receiver wallet of a
+// transfer, which is null when there is none, through the request's
+// WalletLoader so a list of transfers looks its wallets up in one query.
+func resolveTransferWallet(address func(*model.Transfer) string) graphql.FieldResolveFn {
+	return func(p graphql.ResolveParams) (interface{}, error) {
+		loader := walletLoaderFromContext(p.Context)
+		if loader == nil {
+			loader = NewWalletLoader(db.GetWalletsContext)
+		}
+		return loader.Load(p.Context, address(p.Source.(*model.Transfer))), nil
+	}
+}
+
+// resolveWallet resolves Query.wallet, which is null for unknown addresses.
+func resolveWallet(resolver *graph.Resolver) graphql.FieldResolveFn {
+	return func(p graphql.ResolveParams) (interface{}, error) {
+		address := p.Args["address"].(string)
+		token, _ := p.Args["token"].(string)
+		wallet, err := resolver.GetTokenWallet(p.Context, address, token)
+		if err != nil || wallet == nil {
+			// A nil *model.Wallet in an interface is not nil; return a
+			// plain nil so the field is null.
+			return nil, err
+		}
+		return wallet, nil
+	}
+}
+
+// resolveTransfer resolves Mutation.transfer.
+func resolveTransfer(resolver *graph.Resolver) graphql.FieldResolveFn {
+	return func(p graphql.ResolveParams) (interface{}, error) {
+		args := graph.TransferArgs{
+			FromAddress: p.Args["from_address"].(string),
+			ToAddress:   p.Args["to_address"].(string),
+			Amount:      p.Args["amount"].(string),
+		}
+		args.ClientRequestID, _ = p.Args["client_request_id"].(string)
+		args.DryRun, _ = p.Args["dry_run"].(bool)
+		args.Memo, _ = p.Args["memo"].(string)
+		args.Signature, _ = p.Args["signature"].(string)
+		args.RequireMinBalance, _ = p.Args["require_min_balance"].(string)
+		args.Token, _ = p.Args["token"].(string)
+		if nonce, ok := p.Args["expected_nonce"].(int); ok {
+			expected := int64(nonce)
+			args.ExpectedNonce = &expected
+		}
+		return resolver.Transfer(p.Context, args)
+	}
+}
+
+// amountField is a field holding an amount or balance in base units, shown
+// in human units unless raw is set.
+func amountField(resolver *graph.Resolver) *graphql.Field {
+	return &graphql.Field{
+		Type: Amount,
+		Args: graphql.FieldConfigArgument{
+			"raw": &graphql.ArgumentConfig{
+				Type:         graphql.Boolean,
+				DefaultValue: false,
+			},
+		},
+		Resolve: resolveAmount(resolver),
+	}
+}
+
+// resolveAmount resolves an amount or balance field of the source like the
+// default resolver, then formats it. An empty or missing value is null.
+func resolveAmount(resolver *graph.Resolver) graphql.FieldResolveFn {
+	return func(p graphql.ResolveParams) (interface{}, error) {
+		value, err := graphql.DefaultResolveFn(p)
+		if err != nil {
+			return nil, err
+		}
+		var base string
+		switch v := value.(type) {
+		case string:
+			base = v
+		case *string:
+			if v != nil {
+				base = *v
+			}
+		}
+		if base == "" {
+			return nil, nil
+		}
+		raw, _ := p.Args["raw"].(bool)
+		return resolver.FormatBalance(base, raw), nil
+	}
+}
+
+// resolveOptional resolves a TransferResult string that is null when empty.
+func resolveOptional(field func(*model.TransferResult) string) graphql.FieldResolveFn {
+	return func(p graphql.ResolveParams) (interface{}, error) {
+		value := field(p.Source.(*model.TransferResult))
+		if value == "" {
+			return nil, nil
+		}
+		return value, nil
+	}
+}
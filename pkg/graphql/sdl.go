@@ -0,0 +1,456 @@
+package graphql
+
+import (
+	_ "embed"
+	"fmt"
+	"sort"
+	"strings"
+	"token-transfer-api/internal/graph"
+
+	"github.com/graphql-go/graphql"
+	"github.com/graphql-go/graphql/language/ast"
+	"github.com/graphql-go/graphql/language/parser"
+)
+
+// SDL is the schema NewHandler serves, written out in schema.graphql by
+// SchemaSDL. Regenerate it with make schema after changing the schema; a
+// unit test fails while it is out of date.
+//
+//go:embed schema.graphql
+var SDL string
+
+// sdlHeader starts schema.graphql.
+const sdlHeader = `# Code generated by make schema from the schema NewHandler serves. DO NOT EDIT.
+# Scalars and enums are implemented in Go and only declared here.
+`
+
+// SchemaSDL returns the schema NewHandler serves in SDL, as schema.graphql
+// holds it. Types and fields are sorted by name, since graphql-go keeps them
+// in maps.
+func SchemaSDL() (string, error) {
+	resolver, err := graph.NewResolver()
+	if err != nil {
+		return "", err
+	}
+	schema, err := createSchema(resolver)
+	if err != nil {
+		return "", err
+	}
+	return sdlHeader + printSchema(schema), nil
+}
+
+// NewSDLSchema builds the schema defined in SDL and wires its fields to the
+// resolvers, scalars and enums of the schema NewHandler serves.
+func NewSDLSchema() (graphql.Schema, error) {
+	resolver, err := graph.NewResolver()
+	if err != nil {
+		return graphql.Schema{}, err
+	}
+	served, err := createSchema(resolver)
+	if err != nil {
+		return graphql.Schema{}, err
+	}
+
+	types := map[string]graphql.Type{}
+	resolvers := map[string]graphql.FieldResolveFn{}
+	for name, t := range served.TypeMap() {
+		if !declared(name) {
+			continue
+		}
+		switch t := t.(type) {
+		case *graphql.Scalar, *graphql.Enum:
+			types[name] = t
+		case *graphql.Object:
+			for fieldName, field := range t.Fields() {
+				if field.Resolve != nil {
+					resolvers[name+"."+fieldName] = field.Resolve
+				}
+			}
+		}
+	}
+	return BuildSchema(SDL, types, resolvers)
+}
+
+// BuildSchema builds a schema from the type definitions in sdl. The root
+// types are the ones named Query and Mutation.
+//
+// Every scalar the SDL declares must be given in types, keyed by its name;
+// an enum given there replaces the SDL one so its values can map to Go
+// values other than their names. The built-in scalars and DateTime need no
+// declaration. Resolvers are keyed by "Type.field"; fields without one
+// resolve from the source's JSON tags or map keys. A resolver for a field the
+// SDL does not define is an error, so renames cannot leave one behind.
+func BuildSchema(sdl string, types map[string]graphql.Type, resolvers map[string]graphql.FieldResolveFn) (graphql.Schema, error) {
+	doc, err := parser.Parse(parser.ParseParams{Source: sdl})
+	if err != nil {
+		return graphql.Schema{}, err
+	}
+
+	b := &sdlBuilder{
+		defs:      map[string]ast.Node{},
+		types:     map[string]graphql.Type{},
+		given:     types,
+		resolvers: resolvers,
+		used:      map[string]bool{},
+	}
+	var names []string
+	for _, def := range doc.Definitions {
+		name, ok := definitionName(def)
+		if !ok {
+			return graphql.Schema{}, fmt.Errorf("unsupported SDL definition %s", def.GetKind())
+		}
+		if _, dup := b.defs[name]; dup {
+			return graphql.Schema{}, fmt.Errorf("type %s is defined twice", name)
+		}
+		b.defs[name] = def
+		names = append(names, name)
+	}
+
+	all := make([]graphql.Type, 0, len(names))
+	for _, name := range names {
+		all = append(all, b.named(name))
+	}
+
+	config := graphql.SchemaConfig{Types: all}
+	if query, ok := b.named("Query").(*graphql.Object); ok {
+		config.Query = query
+	}
+	if _, ok := b.defs["Mutation"]; ok {
+		mutation, ok := b.named("Mutation").(*graphql.Object)
+		if !ok {
+			b.fail(fmt.Errorf("Mutation must be an object type"))
+		}
+		config.Mutation = mutation
+	}
+	if b.err != nil {
+		return graphql.Schema{}, b.err
+	}
+
+	// The fields are built while the schema collects its types.
+	schema, err := graphql.NewSchema(config)
+	if err != nil {
+		return graphql.Schema{}, err
+	}
+	if b.err != nil {
+		return graphql.Schema{}, b.err
+	}
+	for key := range resolvers {
+		if !b.used[key] {
+			return graphql.Schema{}, fmt.Errorf("resolver %s has no field in the SDL", key)
+		}
+	}
+	return schema, nil
+}
+
+// builtinTypes are the types an SDL may use without declaring them.
+var builtinTypes = map[string]graphql.Type{
+	"String":   graphql.String,
+	"Int":      graphql.Int,
+	"Float":    graphql.Float,
+	"Boolean":  graphql.Boolean,
+	"ID":       graphql.ID,
+	"DateTime": graphql.DateTime,
+}
+
+type sdlBuilder struct {
+	defs      map[string]ast.Node
+	types     map[string]graphql.Type
+	given     map[string]graphql.Type
+	resolvers map[string]graphql.FieldResolveFn
+	used      map[string]bool
+	err       error
+}
+
+// fail records the first error met while building; later ones are usually
+// caused by it.
+func (b *sdlBuilder) fail(err error) {
+	if b.err == nil {
+		b.err = err
+	}
+}
+
+// named returns the type called name, building it on first use. Objects get
+// their fields lazily, so types may refer to each other in any order.
+func (b *sdlBuilder) named(name string) graphql.Type {
+	if t, ok := b.types[name]; ok {
+		return t
+	}
+	if t, ok := builtinTypes[name]; ok {
+		return t
+	}
+
+	def, ok := b.defs[name]
+	if !ok {
+		b.fail(fmt.Errorf("unknown type %s", name))
+		return graphql.String
+	}
+
+	var t graphql.Type
+	switch def := def.(type) {
+	case *ast.ScalarDefinition:
+		if t, ok = b.given[name]; !ok {
+			b.fail(fmt.Errorf("scalar %s has no Go implementation", name))
+			t = graphql.String
+		}
+	case *ast.EnumDefinition:
+		if t, ok = b.given[name]; !ok {
+			values := graphql.EnumValueConfigMap{}
+			for _, v := range def.Values {
+				values[v.Name.Value] = &graphql.EnumValueConfig{Value: v.Name.Value, Description: description(v.Description)}
+			}
+			t = graphql.NewEnum(graphql.EnumConfig{Name: name, Description: description(def.Description), Values: values})
+		}
+	case *ast.ObjectDefinition:
+		t = graphql.NewObject(graphql.ObjectConfig{
+			Name:        name,
+			Description: description(def.Description),
+			Fields:      graphql.FieldsThunk(func() graphql.Fields { return b.fields(name, def.Fields) }),
+		})
+	case *ast.InputObjectDefinition:
+		t = graphql.NewInputObject(graphql.InputObjectConfig{
+			Name:        name,
+			Description: description(def.Description),
+			Fields:      graphql.InputObjectConfigFieldMapThunk(func() graphql.InputObjectConfigFieldMap { return b.inputFields(def.Fields) }),
+		})
+	}
+	b.types[name] = t
+	return t
+}
+
+// fields builds the fields of the object type typeName.
+func (b *sdlBuilder) fields(typeName string, defs []*ast.FieldDefinition) graphql.Fields {
+	fields := graphql.Fields{}
+	for _, def := range defs {
+		key := typeName + "." + def.Name.Value
+		output, ok := b.typeOf(def.Type).(graphql.Output)
+		if !ok {
+			b.fail(fmt.Errorf("field %s must have an output type", key))
+			continue
+		}
+
+		args := graphql.FieldConfigArgument{}
+		for _, arg := range def.Arguments {
+			input, ok := b.typeOf(arg.Type).(graphql.Input)
+			if !ok {
+				b.fail(fmt.Errorf("argument %s(%s) must have an input type", key, arg.Name.Value))
+				continue
+			}
+			args[arg.Name.Value] = &graphql.ArgumentConfig{
+				Type:         input,
+				DefaultValue: b.defaultValue(arg.DefaultValue, input),
+				Description:  description(arg.Description),
+			}
+		}
+
+		fields[def.Name.Value] = &graphql.Field{
+			Type:        output,
+			Args:        args,
+			Resolve:     b.resolvers[key],
+			Description: description(def.Description),
+		}
+		b.used[key] = true
+	}
+	return fields
+}
+
+// inputFields builds the fields of an input object type.
+func (b *sdlBuilder) inputFields(defs []*ast.InputValueDefinition) graphql.InputObjectConfigFieldMap {
+	fields := graphql.InputObjectConfigFieldMap{}
+	for _, def := range defs {
+		input, ok := b.typeOf(def.Type).(graphql.Input)
+		if !ok {
+			b.fail(fmt.Errorf("input field %s must have an input type", def.Name.Value))
+			continue
+		}
+		fields[def.Name.Value] = &graphql.InputObjectFieldConfig{
+			Type:         input,
+			DefaultValue: b.defaultValue(def.DefaultValue, input),
+			Description:  description(def.Description),
+		}
+	}
+	return fields
+}
+
+// typeOf resolves a type reference such as [String!]!.
+func (b *sdlBuilder) typeOf(t ast.Type) graphql.Type {
+	switch t := t.(type) {
+	case *ast.NonNull:
+		return graphql.NewNonNull(b.typeOf(t.Type))
+	case *ast.List:
+		return graphql.NewList(b.typeOf(t.Type))
+	case *ast.Named:
+		return b.named(t.Name.Value)
+	}
+	b.fail(fmt.Errorf("unsupported type reference %v", t))
+	return graphql.String
+}
+
+// defaultValue converts a default written in the SDL. Only scalar and enum
+// defaults are supported.
+func (b *sdlBuilder) defaultValue(value ast.Value, t graphql.Input) interface{} {
+	if value == nil {
+		return nil
+	}
+	if nonNull, ok := t.(*graphql.NonNull); ok {
+		t = nonNull.OfType.(graphql.Input)
+	}
+	var parsed interface{}
+	switch t := t.(type) {
+	case *graphql.Scalar:
+		parsed = t.ParseLiteral(value)
+	case *graphql.Enum:
+		parsed = t.ParseLiteral(value)
+	default:
+		b.fail(fmt.Errorf("default values of type %s are not supported", t.Name()))
+		return nil
+	}
+	if parsed == nil {
+		b.fail(fmt.Errorf("invalid default value for type %s", t.Name()))
+	}
+	return parsed
+}
+
+// definitionName returns the name of a type definition, or false for
+// definitions BuildSchema does not support.
+func definitionName(def ast.Node) (string, bool) {
+	switch def := def.(type) {
+	case *ast.ScalarDefinition:
+		return def.Name.Value, true
+	case *ast.EnumDefinition:
+		return def.Name.Value, true
+	case *ast.ObjectDefinition:
+		return def.Name.Value, true
+	case *ast.InputObjectDefinition:
+		return def.Name.Value, true
+	}
+	return "", false
+}
+
+func description(s *ast.StringValue) string {
+	if s == nil {
+		return ""
+	}
+	return s.Value
+}
+
+// declared reports whether an SDL declares the type called name, which
+// built-in and introspection types it may use without.
+func declared(name string) bool {
+	_, builtin := builtinTypes[name]
+	return !builtin && !strings.HasPrefix(name, "__")
+}
+
+// printSchema writes the types of schema in SDL that BuildSchema reads back,
+// sorted by name.
+func printSchema(schema graphql.Schema) string {
+	var names []string
+	for name := range schema.TypeMap() {
+		if declared(name) {
+			names = append(names, name)
+		}
+	}
+	sort.Strings(names)
+
+	var b strings.Builder
+	for _, name := range names {
+		b.WriteString("\n")
+		switch t := schema.Type(name).(type) {
+		case *graphql.Scalar:
+			printDescription(&b, "", t.Description())
+			fmt.Fprintf(&b, "scalar %s\n", name)
+		case *graphql.Enum:
+			printDescription(&b, "", t.Description())
+			fmt.Fprintf(&b, "enum %s {\n", name)
+			values := t.Values()
+			sort.Slice(values, func(i, j int) bool { return values[i].Name < values[j].Name })
+			for _, v := range values {
+				printDescription(&b, "  ", v.Description)
+				fmt.Fprintf(&b, "  %s\n", v.Name)
+			}
+			b.WriteString("}\n")
+		case *graphql.Object:
+			printDescription(&b, "", t.Description())
+			fmt.Fprintf(&b, "type %s {\n", name)
+			fields := t.Fields()
+			for _, fieldName := range sortedKeys(fields) {
+				field := fields[fieldName]
+				printDescription(&b, "  ", field.Description)
+				fmt.Fprintf(&b, "  %s%s: %s\n", fieldName, printArgs(field.Args), field.Type)
+			}
+			b.WriteString("}\n")
+		case *graphql.InputObject:
+			printDescription(&b, "", t.Description())
+			fmt.Fprintf(&b, "input %s {\n", name)
+			fields := t.Fields()
+			for _, fieldName := range sortedKeys(fields) {
+				field := fields[fieldName]
+				printDescription(&b, "  ", field.Description())
+				fmt.Fprintf(&b, "  %s: %s%s\n", fieldName, field.Type, printDefault(field.DefaultValue, field.Type))
+			}
+			b.WriteString("}\n")
+		}
+	}
+	return b.String()
+}
+
+// printArgs writes the arguments of a field, one per line once there are
+// more than two.
+func printArgs(args []*graphql.Argument) string {
+	if len(args) == 0 {
+		return ""
+	}
+	sort.Slice(args, func(i, j int) bool { return args[i].Name() < args[j].Name() })
+	printed := make([]string, len(args))
+	for i, arg := range args {
+		printed[i] = fmt.Sprintf("%s: %s%s", arg.Name(), arg.Type, printDefault(arg.DefaultValue, arg.Type))
+	}
+	if len(printed) <= 2 {
+		return "(" + strings.Join(printed, ", ") + ")"
+	}
+	return "(\n    " + strings.Join(printed, "\n    ") + "\n  )"
+}
+
+// printDefault writes " = value" for a default of a scalar or enum input, and
+// nothing without one.
+func printDefault(value interface{}, t graphql.Input) string {
+	if value == nil {
+		return ""
+	}
+	if nonNull, ok := t.(*graphql.NonNull); ok {
+		t = nonNull.OfType.(graphql.Input)
+	}
+	if enum, ok := t.(*graphql.Enum); ok {
+		for _, v := range enum.Values() {
+			if v.Value == value {
+				return " = " + v.Name
+			}
+		}
+	}
+	if s, ok := value.(string); ok {
+		return " = " + quote(s)
+	}
+	return fmt.Sprintf(" = %v", value)
+}
+
+// printDescription writes a description as a string before the definition
+// it belongs to.
+func printDescription(b *strings.Builder, indent, desc string) {
+	if desc != "" {
+		fmt.Fprintf(b, "%s%s\n", indent, quote(desc))
+	}
+}
+
+// quote writes s as a GraphQL string.
+func quote(s string) string {
+	return `"` + strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`).Replace(s) + `"`
+}
+
+// sortedKeys returns the keys of m in order.
+func sortedKeys[V any](m map[string]V) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
@@ -0,0 +1,39 @@
+package graphql
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"time"
+
+	"github.com/graphql-go/graphql"
+)
+
+// DefaultRequestTimeout is how long a GraphQL request may run when
+// HTTP_REQUEST_TIMEOUT is not set.
+const DefaultRequestTimeout = 10 * time.Second
+
+// RequestTimeoutFromEnv reads from HTTP_REQUEST_TIMEOUT how long a GraphQL
+// request may run in total. When it is up the request's context is
+// canceled, which aborts the SQL it has in flight. 0 disables the deadline.
+func RequestTimeoutFromEnv() (time.Duration, error) {
+	v := os.Getenv("HTTP_REQUEST_TIMEOUT")
+	if v == "" {
+		return DefaultRequestTimeout, nil
+	}
+
+	d, err := time.ParseDuration(v)
+	if err != nil || d < 0 {
+		return 0, fmt.Errorf("invalid HTTP_REQUEST_TIMEOUT %q", v)
+	}
+	return d, nil
+}
+
+// timedOut reports whether the operation that produced result failed
+// because the request's deadline passed while it ran. Its errors are then
+// only the symptoms, such as a canceled query, or the bare context error
+// graphql.Do returns when it stops waiting for the resolvers.
+func timedOut(ctx context.Context, result *graphql.Result) bool {
+	return len(result.Errors) > 0 && errors.Is(ctx.Err(), context.DeadlineExceeded)
+}
@@ -0,0 +1,138 @@
+// Package grpcserver serves the WalletService defined in proto/wallet.proto
+// for internal service-to-service calls. Like the REST endpoints it calls
+// the resolver the GraphQL API is served by, so validation, rate limits and
+// duplicate detection are shared.
+package grpcserver
+
+import (
+	"context"
+	"errors"
+	"log"
+	"token-transfer-api/internal/db"
+	"token-transfer-api/internal/graph"
+	"token-transfer-api/pkg/graphql"
+	"token-transfer-api/pkg/grpcserver/walletpb"
+
+	"google.golang.org/genproto/googleapis/rpc/errdetails"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+)
+
+// ErrorDomain is the domain of the ErrorInfo detail attached to every error
+// status. Its reason is the app error code.
+const ErrorDomain = "token-transfer-api"
+
+// codeByAppCode maps app error codes to gRPC codes. Codes not listed are
+// reported as InvalidArgument.
+var codeByAppCode = map[string]codes.Code{
+	db.ErrInsufficientBalance.Code:  codes.FailedPrecondition,
+	db.ErrReserveViolation.Code:     codes.FailedPrecondition,
+	db.ErrNothingToSweep.Code:       codes.FailedPrecondition,
+	db.ErrConditionNotMet.Code:      codes.FailedPrecondition,
+	db.ErrSenderNotFound.Code:       codes.NotFound,
+	db.ErrReceiverNotFound.Code:     codes.NotFound,
+	db.ErrWalletNotFound.Code:       codes.NotFound,
+	db.ErrBlockedAddress.Code:       codes.PermissionDenied,
+	db.ErrUnauthorized.Code:         codes.PermissionDenied,
+	db.ErrUnauthenticated.Code:      codes.Unauthenticated,
+	db.ErrRateLimited.Code:          codes.ResourceExhausted,
+	db.ErrConflict.Code:             codes.Aborted,
+	db.ErrSerializationFailure.Code: codes.Aborted,
+	db.ErrDeadlock.Code:             codes.Aborted,
+	db.ErrLockTimeout.Code:          codes.Unavailable,
+	db.ErrNotInitialized.Code:       codes.Unavailable,
+	db.ErrRetryable.Code:            codes.Unavailable,
+	db.ErrReadOnly.Code:             codes.Unavailable,
+	db.ErrInternal.Code:             codes.Internal,
+	db.ErrCorruptBalance.Code:       codes.DataLoss,
+}
+
+type walletService struct {
+	walletpb.UnimplementedWalletServiceServer
+	resolver *graph.Resolver
+}
+
+// New returns a gRPC server with the WalletService registered on resolver.
+// The API keys in auth apply as for GraphQL, read from the "authorization"
+// metadata.
+func New(resolver *graph.Resolver, auth graphql.AuthConfig) *grpc.Server {
+	server := grpc.NewServer(grpc.UnaryInterceptor(authInterceptor(auth)))
+	walletpb.RegisterWalletServiceServer(server, &walletService{resolver: resolver})
+	return server
+}
+
+func (s *walletService) Transfer(ctx context.Context, req *walletpb.TransferRequest) (*walletpb.TransferResponse, error) {
+	result, err := s.resolver.Transfer(ctx, graph.TransferArgs{
+		FromAddress: req.GetFromAddress(),
+		ToAddress:   req.GetToAddress(),
+		Amount:      req.GetAmount(),
+	})
+	if err != nil {
+		return nil, toStatus(err)
+	}
+	return &walletpb.TransferResponse{Balance: result.Balance, Fee: result.Fee}, nil
+}
+
+func (s *walletService) GetWallet(ctx context.Context, req *walletpb.GetWalletRequest) (*walletpb.Wallet, error) {
+	wallet, err := s.resolver.GetWallet(ctx, req.GetAddress())
+	if err != nil {
+		return nil, toStatus(err)
+	}
+	if wallet == nil {
+		return nil, toStatus(db.ErrWalletNotFound)
+	}
+	return &walletpb.Wallet{
+		Address:  wallet.Address,
+		Balance:  wallet.Balance,
+		Reserved: wallet.Reserved,
+	}, nil
+}
+
+// authInterceptor requires a valid key for transfers, and for lookups when
+// queries are not public. Calls with a key bound to an address act on
+// behalf of it.
+func authInterceptor(cfg graphql.AuthConfig) grpc.UnaryServerInterceptor {
+	return func(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+		if len(cfg.APIKeys) == 0 {
+			return handler(ctx, req)
+		}
+		if cfg.PublicQueries && info.FullMethod == walletpb.WalletService_GetWallet_FullMethodName {
+			return handler(ctx, req)
+		}
+
+		md, _ := metadata.FromIncomingContext(ctx)
+		for _, v := range md.Get("authorization") {
+			if graphql.ValidAuthorization(v, cfg.APIKeys) {
+				if caller := cfg.CallerFor(v); caller != "" {
+					ctx = graph.WithCaller(ctx, caller)
+				}
+				return handler(ctx, req)
+			}
+		}
+		return nil, toStatus(db.ErrUnauthenticated)
+	}
+}
+
+// toStatus converts an error into a gRPC status carrying the app error code
+// as ErrorInfo. Errors that are not app errors are logged and reported as
+// ErrInternal, as in the GraphQL API.
+func toStatus(err error) error {
+	var appErr *db.AppError
+	if !errors.As(err, &appErr) {
+		log.Printf("Internal error: %v", err)
+		appErr = db.ErrInternal
+	}
+
+	code, ok := codeByAppCode[appErr.Code]
+	if !ok {
+		code = codes.InvalidArgument
+	}
+
+	st := status.New(code, appErr.Message)
+	if detailed, err := st.WithDetails(&errdetails.ErrorInfo{Reason: appErr.Code, Domain: ErrorDomain}); err == nil {
+		st = detailed
+	}
+	return st.Err()
+}
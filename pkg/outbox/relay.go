@@ -0,0 +1,116 @@
+package outbox
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"log"
+	"net/http"
+	"os"
+	"strconv"
+	"time"
+	"token-transfer-api/internal/db"
+	"token-transfer-api/internal/model"
+)
+
+// EventIDHeader carries the id of the delivered event, so a receiver can
+// drop an event it already processed when a delivery is repeated.
+const EventIDHeader = "X-Event-ID"
+
+// Config controls where and how often transfer events are delivered.
+type Config struct {
+	// URL receives each event as a JSON POST. The relay does not run when
+	// it is empty.
+	URL string
+	// PollInterval is how long the relay waits between looking for
+	// unpublished events.
+	PollInterval time.Duration
+	// Timeout bounds a single delivery.
+	Timeout time.Duration
+}
+
+// ConfigFromEnv reads the relay configuration from TRANSFER_EVENTS_URL,
+// TRANSFER_EVENTS_POLL_INTERVAL and TRANSFER_EVENTS_TIMEOUT.
+func ConfigFromEnv() (Config, error) {
+	cfg := Config{URL: os.Getenv("TRANSFER_EVENTS_URL"), PollInterval: time.Second, Timeout: 10 * time.Second}
+
+	if v := os.Getenv("TRANSFER_EVENTS_POLL_INTERVAL"); v != "" {
+		d, err := time.ParseDuration(v)
+		if err != nil || d <= 0 {
+			return Config{}, fmt.Errorf("invalid TRANSFER_EVENTS_POLL_INTERVAL %q", v)
+		}
+		cfg.PollInterval = d
+	}
+
+	if v := os.Getenv("TRANSFER_EVENTS_TIMEOUT"); v != "" {
+		d, err := time.ParseDuration(v)
+		if err != nil || d <= 0 {
+			return Config{}, fmt.Errorf("invalid TRANSFER_EVENTS_TIMEOUT %q", v)
+		}
+		cfg.Timeout = d
+	}
+
+	return cfg, nil
+}
+
+// Run delivers transfer events to cfg.URL until ctx is done. Each poll
+// drains every event that is due. Several relays may run against the same
+// database; db.PublishNextEvent hands each event to one of them at a time.
+func Run(ctx context.Context, cfg Config) {
+	client := &http.Client{Timeout: cfg.Timeout}
+	deliver := func(ctx context.Context, event *model.TransferEvent) error {
+		return Deliver(ctx, client, cfg.URL, event)
+	}
+
+	ticker := time.NewTicker(cfg.PollInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			drain(ctx, deliver)
+		}
+	}
+}
+
+// drain publishes due events until none are left or the database returns an
+// error, which is retried on the next poll.
+func drain(ctx context.Context, deliver func(context.Context, *model.TransferEvent) error) {
+	for ctx.Err() == nil {
+		event, err := db.PublishNextEvent(ctx, deliver)
+		if err != nil {
+			log.Printf("outbox: publishing transfer event: %v", err)
+			return
+		}
+		if event == nil {
+			return
+		}
+		if event.PublishedAt == nil {
+			log.Printf("transfer event id=%d transfer_id=%d delivery failed attempts=%d: %s", event.ID, event.TransferID, event.Attempts, *event.LastError)
+		}
+	}
+}
+
+// Deliver POSTs the payload of event to url. Any status other than 2xx is a
+// failed delivery.
+func Deliver(ctx context.Context, client *http.Client, url string, event *model.TransferEvent) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, url, bytes.NewReader(event.Payload))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set(EventIDHeader, strconv.FormatInt(event.ID, 10))
+
+	resp, err := client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return fmt.Errorf("webhook answered %s", resp.Status)
+	}
+	return nil
+}
@@ -0,0 +1,97 @@
+package ramp
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+// Ramp describes a slow start: the allowed concurrency grows linearly from
+// Initial to Max over Period.
+type Ramp struct {
+	Initial int
+	Max     int
+	Period  time.Duration
+}
+
+// Limit returns the concurrency allowed once elapsed has passed since the
+// start of the ramp.
+func (r Ramp) Limit(elapsed time.Duration) int {
+	if r.Initial < 1 {
+		r.Initial = 1
+	}
+	if r.Max < r.Initial {
+		r.Max = r.Initial
+	}
+	if r.Period <= 0 || elapsed >= r.Period {
+		return r.Max
+	}
+	if elapsed <= 0 {
+		return r.Initial
+	}
+
+	step := int(int64(r.Max-r.Initial) * int64(elapsed) / int64(r.Period))
+	return r.Initial + step
+}
+
+// pollInterval bounds how long a waiter sleeps before re-checking the limit
+// when no slot is released in the meantime.
+const pollInterval = 10 * time.Millisecond
+
+// Gate is a semaphore whose capacity follows a Ramp from the moment it is
+// created.
+type Gate struct {
+	ramp     Ramp
+	start    time.Time
+	mu       sync.Mutex
+	inFlight int
+	released chan struct{}
+}
+
+func NewGate(r Ramp) *Gate {
+	return &Gate{
+		ramp:     r,
+		start:    time.Now(),
+		released: make(chan struct{}, 1),
+	}
+}
+
+// Acquire blocks until a slot is available under the current limit or ctx
+// is done.
+func (g *Gate) Acquire(ctx context.Context) error {
+	for {
+		g.mu.Lock()
+		if g.inFlight < g.ramp.Limit(time.Since(g.start)) {
+			g.inFlight++
+			g.mu.Unlock()
+			return nil
+		}
+		g.mu.Unlock()
+
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-g.released:
+		case <-time.After(pollInterval):
+		}
+	}
+}
+
+// Release frees a slot taken by Acquire.
+func (g *Gate) Release() {
+	g.mu.Lock()
+	g.inFlight--
+	g.mu.Unlock()
+
+	select {
+	case g.released <- struct{}{}:
+	default:
+	}
+}
+
+// InFlight reports how many slots are currently taken.
+func (g *Gate) InFlight() int {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	return g.inFlight
+}
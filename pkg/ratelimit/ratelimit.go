@@ -0,0 +1,95 @@
+package ratelimit
+
+import (
+	"sync"
+	"time"
+)
+
+// Limiter is a token-bucket rate limiter keyed by an arbitrary string. Each
+// key may make Burst calls at once and regains one call every Interval.
+type Limiter struct {
+	burst    float64
+	interval time.Duration
+
+	mu        sync.Mutex
+	buckets   map[string]*bucket
+	lastSweep time.Time
+}
+
+type bucket struct {
+	tokens float64
+	last   time.Time
+}
+
+// PerMinute creates a limiter allowing n calls per minute per key, with
+// bursts of up to n calls.
+func PerMinute(n int) *Limiter {
+	return New(n, time.Minute/time.Duration(n))
+}
+
+// New creates a limiter with the given burst size and refill interval.
+func New(burst int, interval time.Duration) *Limiter {
+	return &Limiter{
+		burst:    float64(burst),
+		interval: interval,
+		buckets:  make(map[string]*bucket),
+	}
+}
+
+// Allow takes a token from key's bucket. When the bucket is empty it reports
+// false and how long the caller should wait before the next token is
+// available.
+func (l *Limiter) Allow(key string) (bool, time.Duration) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	now := time.Now()
+	l.sweep(now)
+
+	b, ok := l.buckets[key]
+	if !ok {
+		b = &bucket{tokens: l.burst, last: now}
+		l.buckets[key] = b
+	}
+	b.refill(now, l.burst, l.interval)
+
+	if b.tokens < 1 {
+		wait := time.Duration((1 - b.tokens) * float64(l.interval))
+		return false, wait
+	}
+	b.tokens--
+	return true, 0
+}
+
+// Len returns the number of keys currently tracked.
+func (l *Limiter) Len() int {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.sweep(time.Now())
+	return len(l.buckets)
+}
+
+// sweep drops buckets that have refilled completely, since a new bucket for
+// the key would be identical. It runs at most once per refill period so the
+// map cannot grow without bound. l.mu must be held.
+func (l *Limiter) sweep(now time.Time) {
+	full := time.Duration(l.burst * float64(l.interval))
+	if now.Sub(l.lastSweep) < full {
+		return
+	}
+	l.lastSweep = now
+
+	for key, b := range l.buckets {
+		if now.Sub(b.last) >= full {
+			delete(l.buckets, key)
+		}
+	}
+}
+
+func (b *bucket) refill(now time.Time, burst float64, interval time.Duration) {
+	b.tokens += float64(now.Sub(b.last)) / float64(interval)
+	if b.tokens > burst {
+		b.tokens = burst
+	}
+	b.last = now
+}
@@ -0,0 +1,250 @@
+// Package rest exposes transfers and wallet lookups as plain JSON endpoints
+// for clients that do not speak GraphQL. It calls the same resolver methods
+// as the GraphQL API, so validation and error codes match. Given the
+// resolver the GraphQL handler serves, it also shares its rate limits and
+// duplicate detection.
+package rest
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"io"
+	"log"
+	"mime"
+	"net/http"
+	"strconv"
+	"strings"
+	"token-transfer-api/internal/db"
+	"token-transfer-api/internal/graph"
+	"token-transfer-api/internal/model"
+	"token-transfer-api/pkg/graphql"
+)
+
+// statusByCode maps app error codes to HTTP statuses. Codes not listed are
+// client errors and answered with 400.
+var statusByCode = map[string]int{
+	db.ErrInsufficientBalance.Code:  http.StatusConflict,
+	db.ErrReserveViolation.Code:     http.StatusConflict,
+	db.ErrNothingToSweep.Code:       http.StatusConflict,
+	db.ErrConditionNotMet.Code:      http.StatusConflict,
+	db.ErrConflict.Code:             http.StatusConflict,
+	db.ErrSenderNotFound.Code:       http.StatusNotFound,
+	db.ErrReceiverNotFound.Code:     http.StatusNotFound,
+	db.ErrWalletNotFound.Code:       http.StatusNotFound,
+	db.ErrBlockedAddress.Code:       http.StatusForbidden,
+	db.ErrUnauthorized.Code:         http.StatusForbidden,
+	db.ErrUnauthenticated.Code:      http.StatusUnauthorized,
+	db.ErrRateLimited.Code:          http.StatusTooManyRequests,
+	db.ErrSerializationFailure.Code: http.StatusServiceUnavailable,
+	db.ErrDeadlock.Code:             http.StatusServiceUnavailable,
+	db.ErrLockTimeout.Code:          http.StatusServiceUnavailable,
+	db.ErrNotInitialized.Code:       http.StatusServiceUnavailable,
+	db.ErrRetryable.Code:            http.StatusServiceUnavailable,
+	db.ErrReadOnly.Code:             http.StatusServiceUnavailable,
+	db.ErrRequestTooLarge.Code:      http.StatusRequestEntityTooLarge,
+	db.ErrImportRejected.Code:       http.StatusUnprocessableEntity,
+	db.ErrInternal.Code:             http.StatusInternalServerError,
+	db.ErrCorruptBalance.Code:       http.StatusInternalServerError,
+}
+
+type transferRequest struct {
+	FromAddress string `json:"from_address"`
+	ToAddress   string `json:"to_address"`
+	Amount      string `json:"amount"`
+}
+
+type transferResponse struct {
+	Balance string `json:"balance"`
+	Fee     string `json:"fee"`
+}
+
+type importResponse struct {
+	Inserted int                        `json:"inserted"`
+	Updated  int                        `json:"updated"`
+	Rejected int                        `json:"rejected"`
+	Results  []model.WalletImportResult `json:"results"`
+}
+
+// NewHandler returns the REST handler with a resolver of its own, configured
+// from the environment.
+func NewHandler() http.Handler {
+	resolver, err := graph.NewResolver()
+	if err != nil {
+		panic(err)
+	}
+	return NewHandlerFor(resolver)
+}
+
+// NewHandlerFor returns a handler serving POST /api/transfer,
+// GET /api/wallet/{address} and POST /api/wallets/import through resolver.
+// Request bodies are limited to MAX_REQUEST_BYTES as in the GraphQL API.
+func NewHandlerFor(resolver *graph.Resolver) http.Handler {
+	maxBytes, err := graphql.MaxRequestBytesFromEnv()
+	if err != nil {
+		panic(err)
+	}
+
+	mux := http.NewServeMux()
+
+	mux.HandleFunc("POST /api/transfer", func(w http.ResponseWriter, r *http.Request) {
+		var req transferRequest
+		if err := json.NewDecoder(http.MaxBytesReader(w, r.Body, maxBytes)).Decode(&req); err != nil {
+			if appErr, ok := graphql.RequestTooLarge(err); ok {
+				writeError(w, appErr)
+				return
+			}
+			http.Error(w, "Error parsing request body", http.StatusBadRequest)
+			return
+		}
+
+		result, err := resolver.Transfer(r.Context(), graph.TransferArgs{
+			FromAddress: req.FromAddress,
+			ToAddress:   req.ToAddress,
+			Amount:      req.Amount,
+		})
+		if err != nil {
+			writeError(w, err)
+			return
+		}
+		writeJSON(w, http.StatusOK, transferResponse{Balance: result.Balance, Fee: result.Fee})
+	})
+
+	mux.HandleFunc("GET /api/wallet/{address}", func(w http.ResponseWriter, r *http.Request) {
+		wallet, err := resolver.GetWallet(r.Context(), r.PathValue("address"))
+		if err != nil {
+			writeError(w, err)
+			return
+		}
+		if wallet == nil {
+			writeError(w, db.ErrWalletNotFound)
+			return
+		}
+		writeJSON(w, http.StatusOK, wallet)
+	})
+
+	mux.HandleFunc("POST /api/wallets/import", func(w http.ResponseWriter, r *http.Request) {
+		var skipInvalid bool
+		switch r.URL.Query().Get("on_error") {
+		case "", "abort":
+		case "skip":
+			skipInvalid = true
+		default:
+			writeError(w, db.ErrInvalidImportPolicy)
+			return
+		}
+
+		rows, err := decodeImport(r.Header.Get("Content-Type"), http.MaxBytesReader(w, r.Body, maxBytes))
+		if err != nil {
+			if appErr, ok := graphql.RequestTooLarge(err); ok {
+				writeError(w, appErr)
+				return
+			}
+			writeError(w, db.ErrMalformedRequest.WithDetails(map[string]interface{}{"reason": err.Error()}))
+			return
+		}
+
+		results, err := resolver.ImportWallets(r.Context(), rows, skipInvalid)
+		if err != nil {
+			writeError(w, err)
+			return
+		}
+
+		resp := importResponse{Results: results}
+		for _, result := range results {
+			switch result.Status {
+			case db.ImportInserted:
+				resp.Inserted++
+			case db.ImportUpdated:
+				resp.Updated++
+			case db.ImportRejected:
+				resp.Rejected++
+			}
+		}
+		writeJSON(w, http.StatusOK, resp)
+	})
+
+	return mux
+}
+
+// decodeImport reads the rows of a wallet import: a JSON array of
+// {"address", "balance"} objects, or with a text/csv content type, CSV
+// records of an address and a balance under an optional
+// "address,balance" header.
+func decodeImport(contentType string, body io.Reader) ([]model.WalletImportRow, error) {
+	mediaType, _, _ := mime.ParseMediaType(contentType)
+	if mediaType != "text/csv" {
+		var rows []model.WalletImportRow
+		if err := json.NewDecoder(body).Decode(&rows); err != nil {
+			return nil, err
+		}
+		return rows, nil
+	}
+
+	reader := csv.NewReader(body)
+	reader.FieldsPerRecord = 2
+	reader.TrimLeadingSpace = true
+	records, err := reader.ReadAll()
+	if err != nil {
+		return nil, err
+	}
+	if len(records) > 0 && strings.EqualFold(records[0][0], "address") {
+		records = records[1:]
+	}
+
+	rows := make([]model.WalletImportRow, len(records))
+	for i, record := range records {
+		rows[i] = model.WalletImportRow{Address: record[0], Balance: record[1]}
+	}
+	return rows, nil
+}
+
+// WithAuth applies the API's key rules to the REST endpoints: transfers
+// always need a valid key, wallet lookups only when queries are not public.
+// Requests with a key bound to an address act on behalf of it.
+func WithAuth(next http.Handler, cfg graphql.AuthConfig) http.Handler {
+	if len(cfg.APIKeys) == 0 {
+		return next
+	}
+
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if graphql.ValidKey(r, cfg.APIKeys) || (cfg.PublicQueries && r.Method == http.MethodGet) {
+			next.ServeHTTP(w, cfg.Authenticate(r))
+			return
+		}
+		writeError(w, db.ErrUnauthenticated)
+	})
+}
+
+// StatusFor returns the HTTP status reported for an app error code.
+func StatusFor(code string) int {
+	if status, ok := statusByCode[code]; ok {
+		return status
+	}
+	return http.StatusBadRequest
+}
+
+func writeJSON(w http.ResponseWriter, status int, v interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(v)
+}
+
+// writeError answers with the error's code, message and details. Errors that
+// are not app errors are logged and reported as ErrInternal, as in the
+// GraphQL API.
+func writeError(w http.ResponseWriter, err error) {
+	var appErr *db.AppError
+	if !errors.As(err, &appErr) {
+		log.Printf("Internal error: %v", err)
+		appErr = db.ErrInternal
+	}
+
+	if retryAfter, ok := appErr.Details["retry_after"].(int); ok {
+		w.Header().Set("Retry-After", strconv.Itoa(retryAfter))
+	}
+
+	body := appErr.Extensions()
+	body["message"] = appErr.Message
+	writeJSON(w, StatusFor(appErr.Code), body)
+}
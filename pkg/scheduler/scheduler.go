@@ -0,0 +1,71 @@
+package scheduler
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"os"
+	"time"
+	"token-transfer-api/internal/db"
+	"token-transfer-api/internal/graph"
+)
+
+// Config controls how the scheduler looks for due transfers.
+type Config struct {
+	// PollInterval is how long the scheduler waits between looking for due
+	// transfers.
+	PollInterval time.Duration
+}
+
+// ConfigFromEnv reads the poll interval from SCHEDULER_POLL_INTERVAL.
+func ConfigFromEnv() (Config, error) {
+	cfg := Config{PollInterval: time.Second}
+
+	if v := os.Getenv("SCHEDULER_POLL_INTERVAL"); v != "" {
+		d, err := time.ParseDuration(v)
+		if err != nil || d <= 0 {
+			return Config{}, fmt.Errorf("invalid SCHEDULER_POLL_INTERVAL %q", v)
+		}
+		cfg.PollInterval = d
+	}
+
+	return cfg, nil
+}
+
+// Run executes scheduled transfers as they fall due until ctx is done. Each
+// poll drains every transfer that is due. Several schedulers may run against
+// the same database; db.ExecuteDueTransfer makes sure each transfer is only
+// executed once.
+func Run(ctx context.Context, cfg Config) {
+	ticker := time.NewTicker(cfg.PollInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			drain(ctx)
+		}
+	}
+}
+
+// drain executes due transfers until none are left or one returns an error,
+// which is retried on the next poll. In read-only mode due transfers are left
+// pending until writes resume.
+func drain(ctx context.Context) {
+	if graph.ReadOnly() {
+		return
+	}
+	for ctx.Err() == nil {
+		scheduled, err := db.ExecuteDueTransfer(ctx)
+		if err != nil {
+			log.Printf("scheduler: executing due transfer: %v", err)
+			return
+		}
+		if scheduled == nil {
+			return
+		}
+		log.Printf("scheduled transfer id=%d from=%s to=%s status=%s", scheduled.ID, scheduled.FromAddress, scheduled.ToAddress, scheduled.Status)
+	}
+}
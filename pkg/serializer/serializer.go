@@ -0,0 +1,77 @@
+package serializer
+
+import (
+	"context"
+	"sync"
+)
+
+// Serializer lets one caller at a time hold each key, queueing the others.
+// Funnelling a hot wallet's transfers through it keeps them from fighting
+// over the wallet's row lock in the database. It is per process, so it
+// smooths contention but does not replace the database's locking.
+type Serializer struct {
+	mu      sync.Mutex
+	entries map[string]*entry
+}
+
+// entry is the lock of one key. refs counts the holder and the waiters, so
+// the entry can be dropped once nobody needs it.
+type entry struct {
+	lock chan struct{}
+	refs int
+}
+
+func New() *Serializer {
+	return &Serializer{entries: make(map[string]*entry)}
+}
+
+// Acquire blocks until the caller holds key or ctx is done. The returned
+// function releases key and must be called exactly once.
+func (s *Serializer) Acquire(ctx context.Context, key string) (func(), error) {
+	s.mu.Lock()
+	e, ok := s.entries[key]
+	if !ok {
+		e = &entry{lock: make(chan struct{}, 1)}
+		s.entries[key] = e
+	}
+	e.refs++
+	s.mu.Unlock()
+
+	select {
+	case e.lock <- struct{}{}:
+		return func() {
+			<-e.lock
+			s.done(key, e)
+		}, nil
+	case <-ctx.Done():
+		s.done(key, e)
+		return nil, ctx.Err()
+	}
+}
+
+// Do runs fn while holding key.
+func (s *Serializer) Do(ctx context.Context, key string, fn func() error) error {
+	release, err := s.Acquire(ctx, key)
+	if err != nil {
+		return err
+	}
+	defer release()
+	return fn()
+}
+
+// Len returns the number of keys held or waited for.
+func (s *Serializer) Len() int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return len(s.entries)
+}
+
+// done drops a reference to e and frees it when it was the last one.
+func (s *Serializer) done(key string, e *entry) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	e.refs--
+	if e.refs == 0 {
+		delete(s.entries, key)
+	}
+}
@@ -0,0 +1,186 @@
+package webhook
+
+import (
+	"fmt"
+	"log"
+	"os"
+	"strconv"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// Job is a single webhook delivery.
+type Job func()
+
+// Config bounds the resources used for webhook deliveries and says where
+// transfer notifications go.
+type Config struct {
+	// Workers is the number of goroutines delivering webhooks.
+	Workers int
+	// QueueSize is the number of deliveries that may wait for a worker.
+	QueueSize int
+	// EnqueueTimeout is how long Submit blocks on a full queue before
+	// dropping the delivery. Zero drops immediately.
+	EnqueueTimeout time.Duration
+
+	// URL receives a notification for every completed transfer. None are
+	// sent when it is empty.
+	URL string
+	// Secret is the key notifications are signed with.
+	Secret string
+	// MaxAttempts is how often a notification is tried before it is given
+	// up.
+	MaxAttempts int
+	// RetryDelay is the pause before the first retry. It doubles with
+	// every further retry.
+	RetryDelay time.Duration
+}
+
+// ConfigFromEnv reads the dispatcher limits from WEBHOOK_WORKERS,
+// WEBHOOK_QUEUE_SIZE and WEBHOOK_ENQUEUE_TIMEOUT, and the notification
+// target from WEBHOOK_URL, WEBHOOK_SECRET, WEBHOOK_MAX_ATTEMPTS and
+// WEBHOOK_RETRY_DELAY.
+func ConfigFromEnv() (Config, error) {
+	cfg := Config{
+		Workers:     4,
+		QueueSize:   100,
+		URL:         os.Getenv("WEBHOOK_URL"),
+		Secret:      os.Getenv("WEBHOOK_SECRET"),
+		MaxAttempts: 5,
+		RetryDelay:  500 * time.Millisecond,
+	}
+
+	if v := os.Getenv("WEBHOOK_WORKERS"); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil || n < 1 {
+			return Config{}, fmt.Errorf("invalid WEBHOOK_WORKERS %q", v)
+		}
+		cfg.Workers = n
+	}
+
+	if v := os.Getenv("WEBHOOK_QUEUE_SIZE"); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil || n < 0 {
+			return Config{}, fmt.Errorf("invalid WEBHOOK_QUEUE_SIZE %q", v)
+		}
+		cfg.QueueSize = n
+	}
+
+	if v := os.Getenv("WEBHOOK_ENQUEUE_TIMEOUT"); v != "" {
+		d, err := time.ParseDuration(v)
+		if err != nil || d < 0 {
+			return Config{}, fmt.Errorf("invalid WEBHOOK_ENQUEUE_TIMEOUT %q", v)
+		}
+		cfg.EnqueueTimeout = d
+	}
+
+	if v := os.Getenv("WEBHOOK_MAX_ATTEMPTS"); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil || n < 1 {
+			return Config{}, fmt.Errorf("invalid WEBHOOK_MAX_ATTEMPTS %q", v)
+		}
+		cfg.MaxAttempts = n
+	}
+
+	if v := os.Getenv("WEBHOOK_RETRY_DELAY"); v != "" {
+		d, err := time.ParseDuration(v)
+		if err != nil || d < 0 {
+			return Config{}, fmt.Errorf("invalid WEBHOOK_RETRY_DELAY %q", v)
+		}
+		cfg.RetryDelay = d
+	}
+
+	if cfg.URL != "" && cfg.Secret == "" {
+		return Config{}, fmt.Errorf("WEBHOOK_SECRET is required when WEBHOOK_URL is set")
+	}
+
+	return cfg, nil
+}
+
+// Dispatcher runs webhook deliveries on a fixed pool of workers fed by a
+// bounded queue, so a burst of transfers cannot spawn unbounded goroutines.
+type Dispatcher struct {
+	cfg     Config
+	queue   chan Job
+	wg      sync.WaitGroup
+	active  atomic.Int64
+	dropped atomic.Int64
+}
+
+func NewDispatcher(cfg Config) *Dispatcher {
+	if cfg.Workers < 1 {
+		cfg.Workers = 1
+	}
+
+	d := &Dispatcher{
+		cfg:   cfg,
+		queue: make(chan Job, cfg.QueueSize),
+	}
+
+	d.wg.Add(cfg.Workers)
+	for i := 0; i < cfg.Workers; i++ {
+		go d.work()
+	}
+	return d
+}
+
+func (d *Dispatcher) work() {
+	defer d.wg.Done()
+	for job := range d.queue {
+		d.active.Add(1)
+		job()
+		d.active.Add(-1)
+	}
+}
+
+// Submit queues a delivery. It reports false and counts a drop when the
+// queue stays full for longer than the configured enqueue timeout.
+func (d *Dispatcher) Submit(job Job) bool {
+	select {
+	case d.queue <- job:
+		return true
+	default:
+	}
+
+	if d.cfg.EnqueueTimeout > 0 {
+		timer := time.NewTimer(d.cfg.EnqueueTimeout)
+		defer timer.Stop()
+
+		select {
+		case d.queue <- job:
+			return true
+		case <-timer.C:
+		}
+	}
+
+	dropped := d.dropped.Add(1)
+	log.Printf("Webhook queue full, dropped delivery (webhook_dropped_total=%d)", dropped)
+	return false
+}
+
+// Close stops accepting deliveries and waits for the queued ones to finish.
+func (d *Dispatcher) Close() {
+	close(d.queue)
+	d.wg.Wait()
+}
+
+// Workers returns the size of the worker pool.
+func (d *Dispatcher) Workers() int {
+	return d.cfg.Workers
+}
+
+// Active returns the number of deliveries currently running.
+func (d *Dispatcher) Active() int {
+	return int(d.active.Load())
+}
+
+// Queued returns the number of deliveries waiting for a worker.
+func (d *Dispatcher) Queued() int {
+	return len(d.queue)
+}
+
+// Dropped returns the number of deliveries dropped because the queue was full.
+func (d *Dispatcher) Dropped() int64 {
+	return d.dropped.Load()
+}
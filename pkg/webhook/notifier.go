@@ -0,0 +1,107 @@
+package webhook
+
+import (
+	"bytes"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"time"
+)
+
+// SignatureHeader carries the signature of a notification's body, as
+// "sha256=" followed by the hex HMAC-SHA256 of the body keyed with the
+// shared secret.
+const SignatureHeader = "X-Webhook-Signature"
+
+// maxRetryDelay caps the doubling pause between attempts.
+const maxRetryDelay = 30 * time.Second
+
+// Notifier posts signed JSON notifications to a URL in the background,
+// retrying failed deliveries with exponential backoff. Deliveries run on a
+// Dispatcher, so a slow or unreachable receiver never holds up the caller.
+type Notifier struct {
+	cfg        Config
+	client     *http.Client
+	dispatcher *Dispatcher
+}
+
+func NewNotifier(cfg Config) *Notifier {
+	if cfg.MaxAttempts < 1 {
+		cfg.MaxAttempts = 1
+	}
+	return &Notifier{
+		cfg:        cfg,
+		client:     &http.Client{Timeout: 10 * time.Second},
+		dispatcher: NewDispatcher(cfg),
+	}
+}
+
+// Notify queues payload for delivery as JSON. It reports false when the
+// payload cannot be encoded or the queue is full.
+func (n *Notifier) Notify(payload interface{}) bool {
+	body, err := json.Marshal(payload)
+	if err != nil {
+		log.Printf("Webhook payload not sent: %v", err)
+		return false
+	}
+	return n.dispatcher.Submit(func() { n.deliver(body) })
+}
+
+// Close waits for the queued notifications to be delivered or given up.
+func (n *Notifier) Close() {
+	n.dispatcher.Close()
+}
+
+// deliver posts body until the receiver accepts it or the attempts run out.
+func (n *Notifier) deliver(body []byte) {
+	delay := n.cfg.RetryDelay
+	for attempt := 1; ; attempt++ {
+		err := n.post(body)
+		if err == nil {
+			return
+		}
+		if attempt >= n.cfg.MaxAttempts {
+			log.Printf("Webhook delivery to %s given up after %d attempts: %v", n.cfg.URL, attempt, err)
+			return
+		}
+
+		time.Sleep(delay)
+		delay = min(2*delay, maxRetryDelay)
+	}
+}
+
+func (n *Notifier) post(body []byte) error {
+	req, err := http.NewRequest(http.MethodPost, n.cfg.URL, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set(SignatureHeader, Sign(n.cfg.Secret, body))
+
+	resp, err := n.client.Do(req)
+	if err != nil {
+		return err
+	}
+	resp.Body.Close()
+
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return fmt.Errorf("receiver answered %s", resp.Status)
+	}
+	return nil
+}
+
+// Sign returns the SignatureHeader value for body.
+func Sign(secret string, body []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write(body)
+	return "sha256=" + hex.EncodeToString(mac.Sum(nil))
+}
+
+// Verify reports whether signature is the SignatureHeader value for body.
+func Verify(secret string, body []byte, signature string) bool {
+	return hmac.Equal([]byte(Sign(secret, body)), []byte(signature))
+}
@@ -0,0 +1,124 @@
+package integration
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+	"token-transfer-api/internal/db"
+	"token-transfer-api/pkg/graphql"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/suite"
+)
+
+const (
+	activitySender   = "0x1700000000000000000000000000000000000001"
+	activityReceiver = "0x1700000000000000000000000000000000000002"
+	activityDormant  = "0x1700000000000000000000000000000000000003"
+)
+
+var longAgo = time.Date(2000, 1, 1, 0, 0, 0, 0, time.UTC)
+
+type ActivitySuite struct {
+	suite.Suite
+	server *httptest.Server
+}
+
+// SetupSuite initializes the test environment
+func (s *ActivitySuite) SetupSuite() {
+	setupDB(s.T())
+
+	// Setup GraphQL handler
+	handler := graphql.NewHandler()
+	s.server = httptest.NewServer(handler)
+}
+
+// TearDownSuite cleans up the test environment
+func (s *ActivitySuite) TearDownSuite() {
+	s.server.Close()
+	db.CloseDB()
+}
+
+// SetupTest resets the wallets so all of them were last active long ago
+func (s *ActivitySuite) SetupTest() {
+	s.createWallet(activitySender, "1000")
+	s.createWallet(activityReceiver, "0")
+	s.createWallet(activityDormant, "500")
+}
+
+// createWallet creates a wallet with the specified balance, last active in 2000
+func (s *ActivitySuite) createWallet(address, balance string) {
+	_, err := db.DB.Exec("INSERT INTO wallets (address, balance, last_activity_at) VALUES ($1, $2, $3) "+
+		"ON CONFLICT (address) DO UPDATE SET balance = $2, last_activity_at = $3",
+		address, balance, longAgo)
+	assert.NoError(s.T(), err)
+}
+
+// execute sends a GraphQL request and decodes the response
+func (s *ActivitySuite) execute(query string, variables map[string]interface{}) *graphQLResponse {
+	reqBody, _ := json.Marshal(graphQLRequest{Query: query, Variables: variables})
+	resp, err := http.Post(s.server.URL, "application/json", bytes.NewBuffer(reqBody))
+	assert.NoError(s.T(), err)
+	defer resp.Body.Close()
+
+	var result graphQLResponse
+	assert.NoError(s.T(), json.NewDecoder(resp.Body).Decode(&result))
+	assert.Nil(s.T(), result.Errors)
+	return &result
+}
+
+// lastActivity reads last_activity_at through the wallet query
+func (s *ActivitySuite) lastActivity(address string) time.Time {
+	result := s.execute(`query($address: Address!) { wallet(address: $address) { last_activity_at } }`,
+		map[string]interface{}{"address": address})
+
+	wallet := result.Data["wallet"].(map[string]interface{})
+	at, err := time.Parse(time.RFC3339Nano, wallet["last_activity_at"].(string))
+	assert.NoError(s.T(), err)
+	return at
+}
+
+// TestTransferUpdatesBothParties tests that sender and receiver activity is recorded
+func (s *ActivitySuite) TestTransferUpdatesBothParties() {
+	s.execute(`mutation { transfer(from_address: "`+activitySender+`", to_address: "`+activityReceiver+`", amount: "100") { balance } }`, nil)
+
+	assert.True(s.T(), s.lastActivity(activitySender).After(longAgo))
+	assert.True(s.T(), s.lastActivity(activityReceiver).After(longAgo))
+	assert.True(s.T(), s.lastActivity(activityDormant).Equal(longAgo))
+}
+
+// TestDormantWallets tests that only wallets without recent activity are listed
+func (s *ActivitySuite) TestDormantWallets() {
+	s.execute(`mutation { transfer(from_address: "`+activitySender+`", to_address: "`+activityReceiver+`", amount: "100") { balance } }`, nil)
+
+	cutoff := time.Now().Add(-time.Minute)
+
+	dormant := map[string]bool{}
+	for offset := 0; ; offset += db.MaxWalletPageSize {
+		wallets, err := db.ListDormantWallets(cutoff, db.MaxWalletPageSize, offset)
+		assert.NoError(s.T(), err)
+		for _, w := range wallets {
+			dormant[w.Address] = true
+		}
+		if len(wallets) < db.MaxWalletPageSize {
+			break
+		}
+	}
+
+	assert.True(s.T(), dormant[activityDormant])
+	assert.False(s.T(), dormant[activitySender])
+	assert.False(s.T(), dormant[activityReceiver])
+
+	// Also reachable through the GraphQL query
+	result := s.execute(`query($since: DateTime!) { dormantWallets(inactive_since: $since, limit: 1) { address } }`,
+		map[string]interface{}{"since": cutoff.Format(time.RFC3339)})
+	assert.Len(s.T(), result.Data["dormantWallets"], 1)
+}
+
+// Run the activity test suite
+func TestActivitySuite(t *testing.T) {
+	suite.Run(t, new(ActivitySuite))
+}
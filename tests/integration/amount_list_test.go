@@ -0,0 +1,102 @@
+package integration
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"token-transfer-api/internal/db"
+	"token-transfer-api/pkg/graphql"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/suite"
+)
+
+const (
+	amountListSender   = "0x2400000000000000000000000000000000000001"
+	amountListReceiver = "0x2400000000000000000000000000000000000002"
+)
+
+type AmountListSuite struct {
+	suite.Suite
+	server *httptest.Server
+}
+
+// SetupSuite initializes the test environment
+func (s *AmountListSuite) SetupSuite() {
+	setupDB(s.T())
+
+	// Setup GraphQL handler
+	handler := graphql.NewHandler()
+	s.server = httptest.NewServer(handler)
+	restoreSettings(s.T())
+}
+
+// TearDownSuite cleans up the test environment
+func (s *AmountListSuite) TearDownSuite() {
+	s.server.Close()
+	db.CloseDB()
+}
+
+// SetupTest resets the wallets and the amount lists
+func (s *AmountListSuite) SetupTest() {
+	restoreSettings(s.T())
+	s.createWallet(amountListSender, "1000")
+	s.createWallet(amountListReceiver, "0")
+}
+
+// createWallet creates a wallet with the specified balance
+func (s *AmountListSuite) createWallet(address, balance string) {
+	_, err := db.DB.Exec("INSERT INTO wallets (address, balance) VALUES ($1, $2) ON CONFLICT (address) DO UPDATE SET balance = $2",
+		address, balance)
+	assert.NoError(s.T(), err)
+}
+
+// transfer moves amount from the sender to the receiver
+func (s *AmountListSuite) transfer(amount string) *graphQLResponse {
+	reqBody, _ := json.Marshal(graphQLRequest{
+		Query: `mutation($from: Address!, $to: Address!, $amount: Amount!) { transfer(from_address: $from, to_address: $to, amount: $amount) { balance } }`,
+		Variables: map[string]interface{}{
+			"from":   amountListSender,
+			"to":     amountListReceiver,
+			"amount": amount,
+		},
+	})
+	resp, err := http.Post(s.server.URL, "application/json", bytes.NewBuffer(reqBody))
+	assert.NoError(s.T(), err)
+	defer resp.Body.Close()
+
+	var result graphQLResponse
+	assert.NoError(s.T(), json.NewDecoder(resp.Body).Decode(&result))
+	return &result
+}
+
+// TestAllowlistedAmountSucceeds tests that a listed denomination is transferred
+func (s *AmountListSuite) TestAllowlistedAmountSucceeds() {
+	db.Settings.AllowedAmounts = map[string]bool{"100": true, "250": true}
+
+	result := s.transfer("250")
+	assert.Nil(s.T(), result.Errors)
+	assert.Equal(s.T(), "750", result.Data["transfer"].(map[string]interface{})["balance"])
+}
+
+// TestAmountOutsideAllowlistRejected tests that other amounts get a coded error
+func (s *AmountListSuite) TestAmountOutsideAllowlistRejected() {
+	db.Settings.AllowedAmounts = map[string]bool{"100": true, "250": true}
+
+	result := s.transfer("99")
+	assert.NotNil(s.T(), result.Errors)
+	assert.Equal(s.T(), "AMOUNT_NOT_ALLOWED", result.Errors[0]["extensions"].(map[string]interface{})["code"])
+}
+
+// TestUnrestrictedByDefault tests that any amount works without lists
+func (s *AmountListSuite) TestUnrestrictedByDefault() {
+	result := s.transfer("37")
+	assert.Nil(s.T(), result.Errors)
+}
+
+// Run the amount list test suite
+func TestAmountListSuite(t *testing.T) {
+	suite.Run(t, new(AmountListSuite))
+}
@@ -0,0 +1,113 @@
+package integration
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"token-transfer-api/internal/db"
+	"token-transfer-api/pkg/graphql"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/suite"
+)
+
+const (
+	authSender   = "0x1800000000000000000000000000000000000001"
+	authReceiver = "0x1800000000000000000000000000000000000002"
+	authKey      = "test-api-key"
+)
+
+type AuthSuite struct {
+	suite.Suite
+	server *httptest.Server
+}
+
+// SetupSuite initializes the test environment behind the auth middleware
+func (s *AuthSuite) SetupSuite() {
+	setupDB(s.T())
+
+	handler := graphql.WithAuth(graphql.NewHandler(), graphql.AuthConfig{
+		APIKeys:       []string{authKey},
+		PublicQueries: true,
+	})
+	s.server = httptest.NewServer(handler)
+}
+
+// TearDownSuite cleans up the test environment
+func (s *AuthSuite) TearDownSuite() {
+	s.server.Close()
+	db.CloseDB()
+}
+
+// SetupTest resets the wallets used by the auth tests
+func (s *AuthSuite) SetupTest() {
+	s.createWallet(authSender, "1000")
+	s.createWallet(authReceiver, "0")
+}
+
+// createWallet creates a wallet with the specified balance
+func (s *AuthSuite) createWallet(address, balance string) {
+	_, err := db.DB.Exec("INSERT INTO wallets (address, balance) VALUES ($1, $2) ON CONFLICT (address) DO UPDATE SET balance = $2",
+		address, balance)
+	assert.NoError(s.T(), err)
+}
+
+// getBalance gets a wallet's balance
+func (s *AuthSuite) getBalance(address string) string {
+	var balance string
+	err := db.DB.QueryRow("SELECT balance FROM wallets WHERE address = $1", address).Scan(&balance)
+	assert.NoError(s.T(), err)
+	return balance
+}
+
+// execute sends a GraphQL request with an optional bearer token
+func (s *AuthSuite) execute(query, token string) (int, *graphQLResponse) {
+	reqBody, _ := json.Marshal(graphQLRequest{Query: query})
+	req, err := http.NewRequest(http.MethodPost, s.server.URL, bytes.NewBuffer(reqBody))
+	assert.NoError(s.T(), err)
+	req.Header.Set("Content-Type", "application/json")
+	if token != "" {
+		req.Header.Set("Authorization", "Bearer "+token)
+	}
+
+	resp, err := http.DefaultClient.Do(req)
+	assert.NoError(s.T(), err)
+	defer resp.Body.Close()
+
+	var result graphQLResponse
+	assert.NoError(s.T(), json.NewDecoder(resp.Body).Decode(&result))
+	return resp.StatusCode, &result
+}
+
+const authTransfer = `mutation { transfer(from_address: "` + authSender + `", to_address: "` + authReceiver + `", amount: "100") { balance } }`
+
+// TestAuthorizedTransfer tests that a transfer with a valid key goes through
+func (s *AuthSuite) TestAuthorizedTransfer() {
+	status, result := s.execute(authTransfer, authKey)
+	assert.Equal(s.T(), http.StatusOK, status)
+	assert.Nil(s.T(), result.Errors)
+	assert.Equal(s.T(), "900", s.getBalance(authSender))
+}
+
+// TestUnauthenticatedTransferRejected tests that a transfer without a key is refused
+func (s *AuthSuite) TestUnauthenticatedTransferRejected() {
+	status, result := s.execute(authTransfer, "")
+	assert.Equal(s.T(), http.StatusUnauthorized, status)
+	assert.NotNil(s.T(), result.Errors)
+	assert.Equal(s.T(), "UNAUTHENTICATED", result.Errors[0]["extensions"].(map[string]interface{})["code"])
+	assert.Equal(s.T(), "1000", s.getBalance(authSender))
+}
+
+// TestPublicQuery tests that queries do not need a key
+func (s *AuthSuite) TestPublicQuery() {
+	status, result := s.execute(`{ wallet(address: "`+authSender+`") { balance } }`, "")
+	assert.Equal(s.T(), http.StatusOK, status)
+	assert.Nil(s.T(), result.Errors)
+}
+
+// Run the auth test suite
+func TestAuthSuite(t *testing.T) {
+	suite.Run(t, new(AuthSuite))
+}
@@ -0,0 +1,117 @@
+package integration
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"token-transfer-api/internal/db"
+	"token-transfer-api/pkg/graphql"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/suite"
+)
+
+type BalanceAfterSuite struct {
+	suite.Suite
+	server *httptest.Server
+}
+
+// SetupSuite initializes the test environment
+func (s *BalanceAfterSuite) SetupSuite() {
+	setupDB(s.T())
+
+	// Without fees every transfer writes exactly one row
+	restoreSettings(s.T())
+	db.Settings.FeeWallet = ""
+	db.Settings.FeeFlat = new(big.Int)
+	db.Settings.FeeBPS = 0
+
+	s.server = httptest.NewServer(graphql.NewHandler())
+}
+
+// TearDownSuite cleans up the test environment
+func (s *BalanceAfterSuite) TearDownSuite() {
+	s.cleanup()
+	s.server.Close()
+	db.CloseDB()
+}
+
+// SetupTest funds the first wallet of the suite
+func (s *BalanceAfterSuite) SetupTest() {
+	s.cleanup()
+	_, err := db.DB.Exec("INSERT INTO wallets (address, balance) VALUES ($1, 1000)", s.wallet(1))
+	assert.NoError(s.T(), err)
+}
+
+func (s *BalanceAfterSuite) cleanup() {
+	_, err := db.DB.Exec("DELETE FROM transfers WHERE from_address LIKE '0x30%' OR to_address LIKE '0x30%'")
+	assert.NoError(s.T(), err)
+	_, err = db.DB.Exec("DELETE FROM wallets WHERE address LIKE '0x30%'")
+	assert.NoError(s.T(), err)
+}
+
+func (s *BalanceAfterSuite) wallet(n int) string {
+	return fmt.Sprintf("0x30%038d", n)
+}
+
+func (s *BalanceAfterSuite) getBalance(address string) string {
+	var balance string
+	err := db.DB.QueryRow("SELECT balance FROM wallets WHERE address = $1", address).Scan(&balance)
+	assert.NoError(s.T(), err)
+	return balance
+}
+
+// graphQL posts a query and returns its data, failing on errors
+func (s *BalanceAfterSuite) graphQL(query string) map[string]interface{} {
+	reqBody, _ := json.Marshal(graphQLRequest{Query: query})
+	resp, err := http.Post(s.server.URL, "application/json", bytes.NewBuffer(reqBody))
+	if err != nil {
+		s.T().Fatalf("Request failed: %v", err)
+	}
+	defer resp.Body.Close()
+
+	var result graphQLResponse
+	assert.NoError(s.T(), json.NewDecoder(resp.Body).Decode(&result))
+	assert.Nil(s.T(), result.Errors)
+	return result.Data
+}
+
+// TestPostBalancesMatchWallets tests that each transfer records the balances the wallets actually had afterwards
+func (s *BalanceAfterSuite) TestPostBalancesMatchWallets() {
+	steps := []struct {
+		from, to int
+		amount   string
+	}{
+		{1, 2, "100"},
+		{2, 3, "40"},
+		{1, 3, "10"},
+		{3, 1, "5"},
+		{2, 1, "60"},
+	}
+
+	for _, step := range steps {
+		from, to := s.wallet(step.from), s.wallet(step.to)
+		s.graphQL(fmt.Sprintf(`mutation { transfer(from_address: "%s", to_address: "%s", amount: "%s") { balance } }`,
+			from, to, step.amount))
+
+		data := s.graphQL(`{ transfers(first: 1) { edges { node { from_address to_address from_balance_after to_balance_after } } } }`)
+		node := data["transfers"].(map[string]interface{})["edges"].([]interface{})[0].(map[string]interface{})["node"].(map[string]interface{})
+
+		assert.Equal(s.T(), from, node["from_address"])
+		assert.Equal(s.T(), to, node["to_address"])
+		assert.Equal(s.T(), s.getBalance(from), node["from_balance_after"])
+		assert.Equal(s.T(), s.getBalance(to), node["to_balance_after"])
+	}
+
+	assert.Equal(s.T(), "955", s.getBalance(s.wallet(1)))
+	assert.Equal(s.T(), "0", s.getBalance(s.wallet(2)))
+	assert.Equal(s.T(), "45", s.getBalance(s.wallet(3)))
+}
+
+func TestBalanceAfterSuite(t *testing.T) {
+	suite.Run(t, new(BalanceAfterSuite))
+}
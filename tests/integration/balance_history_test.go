@@ -0,0 +1,174 @@
+package integration
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+	"token-transfer-api/internal/db"
+	"token-transfer-api/internal/model"
+	"token-transfer-api/pkg/graphql"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/suite"
+)
+
+const (
+	historyWalletA = "0x4d00000000000000000000000000000000000001"
+	historyWalletB = "0x4d00000000000000000000000000000000000002"
+)
+
+type BalanceHistorySuite struct {
+	suite.Suite
+	server *httptest.Server
+}
+
+// SetupSuite initializes the database connection and the GraphQL server
+func (s *BalanceHistorySuite) SetupSuite() {
+	setupDB(s.T())
+
+	s.server = httptest.NewServer(graphql.NewHandler())
+	restoreSettings(s.T())
+}
+
+// TearDownSuite closes the server and the database connection
+func (s *BalanceHistorySuite) TearDownSuite() {
+	s.cleanup()
+	s.server.Close()
+	db.CloseDB()
+}
+
+// SetupTest funds wallet A and turns the history on without fees
+func (s *BalanceHistorySuite) SetupTest() {
+	restoreSettings(s.T())
+	db.Settings.BalanceHistory = true
+	db.Settings.BalanceHistoryInterval = 0
+	db.Settings.FeeWallet = ""
+
+	s.cleanup()
+	_, err := db.DB.Exec("INSERT INTO wallets (address, balance) VALUES ($1, 1000)", historyWalletA)
+	assert.NoError(s.T(), err)
+}
+
+func (s *BalanceHistorySuite) cleanup() {
+	_, err := db.DB.Exec("DELETE FROM balance_history WHERE address LIKE '0x4d%'")
+	assert.NoError(s.T(), err)
+	_, err = db.DB.Exec("DELETE FROM transfers WHERE from_address LIKE '0x4d%' OR to_address LIKE '0x4d%'")
+	assert.NoError(s.T(), err)
+	_, err = db.DB.Exec("DELETE FROM wallets WHERE address LIKE '0x4d%'")
+	assert.NoError(s.T(), err)
+}
+
+// transfers moves tokens A→B 100, B→A 30 and A→B 50
+func (s *BalanceHistorySuite) transfers() {
+	for _, t := range []struct{ from, to, amount string }{
+		{historyWalletA, historyWalletB, "100"},
+		{historyWalletB, historyWalletA, "30"},
+		{historyWalletA, historyWalletB, "50"},
+	} {
+		_, err := db.TransferTokens(t.from, t.to, t.amount)
+		assert.NoError(s.T(), err)
+	}
+}
+
+func balances(history []model.BalanceSnapshot) []string {
+	out := []string{}
+	for _, snapshot := range history {
+		out = append(out, snapshot.Balance)
+	}
+	return out
+}
+
+// TestHistoryFollowsTransfers tests that each transfer records both wallets' new balances in order
+func (s *BalanceHistorySuite) TestHistoryFollowsTransfers() {
+	s.transfers()
+
+	history, err := db.GetBalanceHistory(historyWalletA, nil, nil)
+	assert.NoError(s.T(), err)
+	assert.Equal(s.T(), []string{"900", "930", "880"}, balances(history))
+
+	history, err = db.GetBalanceHistory(historyWalletB, nil, nil)
+	assert.NoError(s.T(), err)
+	assert.Equal(s.T(), []string{"100", "70", "120"}, balances(history))
+
+	// The last entry is the current balance
+	wallet, err := db.GetWallet(historyWalletB)
+	assert.NoError(s.T(), err)
+	assert.Equal(s.T(), wallet.Balance, history[len(history)-1].Balance)
+}
+
+// TestHistoryRange tests that since is inclusive and until exclusive
+func (s *BalanceHistorySuite) TestHistoryRange() {
+	s.transfers()
+
+	all, err := db.GetBalanceHistory(historyWalletA, nil, nil)
+	if !assert.NoError(s.T(), err) || !assert.Len(s.T(), all, 3) {
+		return
+	}
+	second := all[1].RecordedAt
+
+	history, err := db.GetBalanceHistory(historyWalletA, &second, nil)
+	assert.NoError(s.T(), err)
+	assert.Equal(s.T(), []string{"930", "880"}, balances(history))
+
+	history, err = db.GetBalanceHistory(historyWalletA, nil, &second)
+	assert.NoError(s.T(), err)
+	assert.Equal(s.T(), []string{"900"}, balances(history))
+}
+
+// TestHistoryQuery tests the balanceHistory query
+func (s *BalanceHistorySuite) TestHistoryQuery() {
+	s.transfers()
+
+	query := fmt.Sprintf(`{ balanceHistory(address: "%s") { address balance recorded_at } }`, historyWalletA)
+	reqBody, _ := json.Marshal(graphQLRequest{Query: query})
+	resp, err := http.Post(s.server.URL, "application/json", bytes.NewBuffer(reqBody))
+	if !assert.NoError(s.T(), err) {
+		return
+	}
+	defer resp.Body.Close()
+
+	var result graphQLResponse
+	assert.NoError(s.T(), json.NewDecoder(resp.Body).Decode(&result))
+	assert.Nil(s.T(), result.Errors)
+
+	history := result.Data["balanceHistory"].([]interface{})
+	if assert.Len(s.T(), history, 3) {
+		last := history[2].(map[string]interface{})
+		assert.Equal(s.T(), historyWalletA, last["address"])
+		assert.Equal(s.T(), "880", last["balance"])
+		assert.NotEmpty(s.T(), last["recorded_at"])
+	}
+}
+
+// TestIntervalKeepsLastBalance tests that with an interval each wallet keeps one row per interval holding its latest balance
+func (s *BalanceHistorySuite) TestIntervalKeepsLastBalance() {
+	// Long enough that the transfers cannot straddle two intervals
+	db.Settings.BalanceHistoryInterval = 100000 * time.Hour
+	s.transfers()
+
+	history, err := db.GetBalanceHistory(historyWalletA, nil, nil)
+	assert.NoError(s.T(), err)
+	assert.Equal(s.T(), []string{"880"}, balances(history))
+
+	history, err = db.GetBalanceHistory(historyWalletB, nil, nil)
+	assert.NoError(s.T(), err)
+	assert.Equal(s.T(), []string{"120"}, balances(history))
+}
+
+// TestHistoryOff tests that nothing is recorded while the history is off
+func (s *BalanceHistorySuite) TestHistoryOff() {
+	db.Settings.BalanceHistory = false
+	s.transfers()
+
+	history, err := db.GetBalanceHistory(historyWalletA, nil, nil)
+	assert.NoError(s.T(), err)
+	assert.Empty(s.T(), history)
+}
+
+func TestBalanceHistorySuite(t *testing.T) {
+	suite.Run(t, new(BalanceHistorySuite))
+}
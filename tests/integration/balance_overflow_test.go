@@ -0,0 +1,101 @@
+package integration
+
+import (
+	"strings"
+	"testing"
+	"token-transfer-api/internal/db"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/suite"
+)
+
+const (
+	overflowSender   = "0x4700000000000000000000000000000000000001"
+	overflowReceiver = "0x4700000000000000000000000000000000000002"
+)
+
+var (
+	// maxStoredBalance is the largest value DECIMAL(78, 0) holds
+	maxStoredBalance = strings.Repeat("9", 78)
+	// nearMaxBalance is 10 below maxStoredBalance
+	nearMaxBalance = strings.Repeat("9", 76) + "89"
+)
+
+type BalanceOverflowSuite struct {
+	suite.Suite
+}
+
+// SetupSuite initializes the database connection
+func (s *BalanceOverflowSuite) SetupSuite() {
+	setupDB(s.T())
+}
+
+// TearDownSuite closes the database connection
+func (s *BalanceOverflowSuite) TearDownSuite() {
+	s.cleanup()
+	db.CloseDB()
+}
+
+// SetupTest funds the sender and puts the receiver 10 below the maximum
+func (s *BalanceOverflowSuite) SetupTest() {
+	s.cleanup()
+	_, err := db.DB.Exec("INSERT INTO wallets (address, balance) VALUES ($1, 1000), ($2, $3)", overflowSender, overflowReceiver, nearMaxBalance)
+	assert.NoError(s.T(), err)
+}
+
+func (s *BalanceOverflowSuite) cleanup() {
+	_, err := db.DB.Exec("DELETE FROM transfers WHERE from_address LIKE '0x47%' OR to_address LIKE '0x47%'")
+	assert.NoError(s.T(), err)
+	_, err = db.DB.Exec("DELETE FROM wallets WHERE address LIKE '0x47%'")
+	assert.NoError(s.T(), err)
+}
+
+// assertUnchanged checks that neither wallet moved and nothing was recorded
+func (s *BalanceOverflowSuite) assertUnchanged() {
+	for address, balance := range map[string]string{overflowSender: "1000", overflowReceiver: nearMaxBalance} {
+		wallet, err := db.GetWallet(address)
+		assert.NoError(s.T(), err)
+		assert.Equal(s.T(), balance, wallet.Balance, address)
+	}
+
+	var count int
+	err := db.DB.QueryRow("SELECT COUNT(*) FROM transfers WHERE to_address = $1", overflowReceiver).Scan(&count)
+	assert.NoError(s.T(), err)
+	assert.Zero(s.T(), count)
+}
+
+// TestTransferOverflowRejected tests that a transfer pushing the receiver past the maximum fails cleanly on both paths
+func (s *BalanceOverflowSuite) TestTransferOverflowRejected() {
+	_, err := db.TransferTokens(overflowSender, overflowReceiver, "11")
+	assert.ErrorIs(s.T(), err, db.ErrBalanceOverflow)
+	s.assertUnchanged()
+
+	_, err = db.TransferTokensCTE(overflowSender, overflowReceiver, "11")
+	assert.ErrorIs(s.T(), err, db.ErrBalanceOverflow)
+	s.assertUnchanged()
+}
+
+// TestMintOverflowRejected tests that minting past the maximum fails cleanly, including amounts too long to store
+func (s *BalanceOverflowSuite) TestMintOverflowRejected() {
+	_, err := db.Mint(overflowReceiver, "11")
+	assert.ErrorIs(s.T(), err, db.ErrBalanceOverflow)
+
+	_, err = db.Mint("0x4700000000000000000000000000000000000003", "1"+strings.Repeat("0", 78))
+	assert.ErrorIs(s.T(), err, db.ErrBalanceOverflow)
+	s.assertUnchanged()
+}
+
+// TestCreditUpToMaximum tests that the receiver can be filled exactly to the maximum
+func (s *BalanceOverflowSuite) TestCreditUpToMaximum() {
+	_, err := db.TransferTokens(overflowSender, overflowReceiver, "10")
+	assert.NoError(s.T(), err)
+
+	wallet, err := db.GetWallet(overflowReceiver)
+	assert.NoError(s.T(), err)
+	assert.Equal(s.T(), maxStoredBalance, wallet.Balance)
+}
+
+// Run the balance overflow test suite
+func TestBalanceOverflowSuite(t *testing.T) {
+	suite.Run(t, new(BalanceOverflowSuite))
+}
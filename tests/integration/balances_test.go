@@ -0,0 +1,136 @@
+package integration
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"token-transfer-api/internal/db"
+	"token-transfer-api/pkg/graphql"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/suite"
+)
+
+const (
+	balancesAlice   = "0x3700000000000000000000000000000000000001"
+	balancesBob     = "0x3700000000000000000000000000000000000002"
+	balancesUnknown = "0x3700000000000000000000000000000000000003"
+)
+
+type BalancesSuite struct {
+	suite.Suite
+	server *httptest.Server
+}
+
+// SetupSuite initializes the test environment
+func (s *BalancesSuite) SetupSuite() {
+	setupDB(s.T())
+
+	s.server = httptest.NewServer(graphql.NewHandler())
+}
+
+// TearDownSuite cleans up the test environment
+func (s *BalancesSuite) TearDownSuite() {
+	s.server.Close()
+	db.CloseDB()
+}
+
+// SetupTest creates two known wallets
+func (s *BalancesSuite) SetupTest() {
+	_, err := db.DB.Exec("DELETE FROM wallets WHERE address = $1", balancesUnknown)
+	assert.NoError(s.T(), err)
+	for address, balance := range map[string]string{balancesAlice: "100", balancesBob: "250"} {
+		_, err = db.DB.Exec("INSERT INTO wallets (address, balance) VALUES ($1, $2) ON CONFLICT (address) DO UPDATE SET balance = $2",
+			address, balance)
+		assert.NoError(s.T(), err)
+	}
+}
+
+// graphQL posts a query with variables and returns the decoded response
+func (s *BalancesSuite) graphQL(query string, variables map[string]interface{}) *graphQLResponse {
+	reqBody, _ := json.Marshal(graphQLRequest{Query: query, Variables: variables})
+	resp, err := http.Post(s.server.URL, "application/json", bytes.NewBuffer(reqBody))
+	if err != nil {
+		s.T().Fatalf("Request failed: %v", err)
+	}
+	defer resp.Body.Close()
+
+	var result graphQLResponse
+	assert.NoError(s.T(), json.NewDecoder(resp.Body).Decode(&result))
+	return &result
+}
+
+// balances returns address and balance pairs from the balances query
+func (s *BalancesSuite) balances(addresses []string, includeUnknown bool) ([][2]string, *graphQLResponse) {
+	result := s.graphQL(`query($addresses: [String!]!, $includeUnknown: Boolean) {
+		balances(addresses: $addresses, include_unknown: $includeUnknown) { address balance }
+	}`, map[string]interface{}{"addresses": addresses, "includeUnknown": includeUnknown})
+	if result.Errors != nil {
+		return nil, result
+	}
+
+	var pairs [][2]string
+	for _, item := range result.Data["balances"].([]interface{}) {
+		wallet := item.(map[string]interface{})
+		pairs = append(pairs, [2]string{wallet["address"].(string), wallet["balance"].(string)})
+	}
+	return pairs, result
+}
+
+// TestKnownAndUnknown tests a mix of known and unknown addresses in request order
+func (s *BalancesSuite) TestKnownAndUnknown() {
+	addresses := []string{balancesBob, balancesUnknown, balancesAlice, balancesBob}
+
+	pairs, result := s.balances(addresses, true)
+	assert.Nil(s.T(), result.Errors)
+	assert.Equal(s.T(), [][2]string{
+		{balancesBob, "250"},
+		{balancesUnknown, "0"},
+		{balancesAlice, "100"},
+	}, pairs)
+
+	pairs, result = s.balances(addresses, false)
+	assert.Nil(s.T(), result.Errors)
+	assert.Equal(s.T(), [][2]string{
+		{balancesBob, "250"},
+		{balancesAlice, "100"},
+	}, pairs)
+}
+
+// TestGetWallets tests the db helper leaves unknown addresses out
+func (s *BalancesSuite) TestGetWallets() {
+	wallets, err := db.GetWallets([]string{balancesUnknown, balancesAlice})
+	assert.NoError(s.T(), err)
+	if assert.Len(s.T(), wallets, 1) {
+		assert.Equal(s.T(), balancesAlice, wallets[0].Address)
+		assert.Equal(s.T(), "100", wallets[0].Balance)
+	}
+
+	wallets, err = db.GetWallets(nil)
+	assert.NoError(s.T(), err)
+	assert.Empty(s.T(), wallets)
+}
+
+// TestLimits tests that too many or malformed addresses are rejected
+func (s *BalancesSuite) TestLimits() {
+	addresses := make([]string, db.MaxBulkAddresses+1)
+	for i := range addresses {
+		addresses[i] = fmt.Sprintf("0x37%038x", i)
+	}
+	_, result := s.balances(addresses, true)
+	if assert.NotNil(s.T(), result.Errors) {
+		assert.Equal(s.T(), "TOO_MANY_ADDRESSES", result.Errors[0]["extensions"].(map[string]interface{})["code"])
+	}
+
+	_, result = s.balances([]string{balancesAlice, "0x123"}, true)
+	if assert.NotNil(s.T(), result.Errors) {
+		assert.Equal(s.T(), "INVALID_ADDRESS", result.Errors[0]["extensions"].(map[string]interface{})["code"])
+	}
+}
+
+func TestBalancesSuite(t *testing.T) {
+	suite.Run(t, new(BalancesSuite))
+}
@@ -10,7 +10,6 @@ import (
 	"token-transfer-api/internal/db"
 	"token-transfer-api/pkg/graphql"
 
-	"github.com/joho/godotenv"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/suite"
 )
@@ -30,15 +29,33 @@ type graphQLResponse struct {
 	Errors []map[string]interface{} `json:"errors,omitempty"`
 }
 
-// SetupSuite initializes the test environment
-func (s *BasicTransferSuite) SetupSuite() {
-	if err := godotenv.Load("../../.env"); err != nil {
-		s.T().Logf("No .env file found")
+// anonymousKey is the API key of test requests made on behalf of no one
+const anonymousKey = "anonymous"
+
+// callerAuth returns API key rules under which each of callers is a key
+// bound to that address, next to anonymousKey, which is bound to none
+func callerAuth(callers ...string) graphql.AuthConfig {
+	cfg := graphql.AuthConfig{APIKeys: []string{anonymousKey}, Callers: map[string]string{}, PublicQueries: true}
+	for _, caller := range callers {
+		cfg.APIKeys = append(cfg.APIKeys, caller)
+		cfg.Callers[caller] = caller
 	}
+	return cfg
+}
 
-	if err := db.InitDB(); err != nil {
-		s.T().Fatalf("Failed to initialize database: %v", err)
+// authorize makes req act on behalf of caller under callerAuth, or of no
+// one when caller is empty
+func authorize(req *http.Request, caller string) {
+	key := anonymousKey
+	if caller != "" {
+		key = caller
 	}
+	req.Header.Set("Authorization", "Bearer "+key)
+}
+
+// SetupSuite initializes the test environment
+func (s *BasicTransferSuite) SetupSuite() {
+	setupDB(s.T())
 
 	// Setup GraphQL handler
 	handler := graphql.NewHandler()
@@ -0,0 +1,139 @@
+package integration
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"token-transfer-api/internal/db"
+	"token-transfer-api/pkg/graphql"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/suite"
+)
+
+const (
+	blocklistAdmin   = "0x2100000000000000000000000000000000000000"
+	blocklistBlocked = "0x2100000000000000000000000000000000000001"
+	blocklistAlice   = "0x2100000000000000000000000000000000000002"
+	blocklistBob     = "0x2100000000000000000000000000000000000003"
+)
+
+type BlocklistSuite struct {
+	suite.Suite
+	server *httptest.Server
+}
+
+// SetupSuite initializes the test environment with a configured admin
+func (s *BlocklistSuite) SetupSuite() {
+	setupDB(s.T())
+
+	s.T().Setenv("ADMIN_ADDRESS", blocklistAdmin)
+	handler := graphql.WithAuth(graphql.NewHandler(), callerAuth(blocklistAdmin, blocklistAlice))
+	s.server = httptest.NewServer(handler)
+}
+
+// TearDownSuite cleans up the test environment
+func (s *BlocklistSuite) TearDownSuite() {
+	s.server.Close()
+	db.CloseDB()
+}
+
+// SetupTest funds the wallets and blocks one of them
+func (s *BlocklistSuite) SetupTest() {
+	s.createWallet(blocklistBlocked, "1000")
+	s.createWallet(blocklistAlice, "1000")
+	s.createWallet(blocklistBob, "1000")
+
+	result := s.execute(`mutation($a: String!) { addAddressToBlocklist(address: $a) }`,
+		map[string]interface{}{"a": blocklistBlocked}, blocklistAdmin)
+	assert.Nil(s.T(), result.Errors)
+}
+
+// TearDownTest lifts the block so other suites can use the address space
+func (s *BlocklistSuite) TearDownTest() {
+	_, err := db.UnblockAddress(blocklistBlocked)
+	assert.NoError(s.T(), err)
+}
+
+// createWallet creates a wallet with the specified balance
+func (s *BlocklistSuite) createWallet(address, balance string) {
+	_, err := db.DB.Exec("INSERT INTO wallets (address, balance) VALUES ($1, $2) ON CONFLICT (address) DO UPDATE SET balance = $2",
+		address, balance)
+	assert.NoError(s.T(), err)
+}
+
+// getBalance gets a wallet's balance
+func (s *BlocklistSuite) getBalance(address string) string {
+	var balance string
+	err := db.DB.QueryRow("SELECT balance FROM wallets WHERE address = $1", address).Scan(&balance)
+	assert.NoError(s.T(), err)
+	return balance
+}
+
+// execute sends a GraphQL request on behalf of caller
+func (s *BlocklistSuite) execute(query string, variables map[string]interface{}, caller string) *graphQLResponse {
+	reqBody, _ := json.Marshal(graphQLRequest{Query: query, Variables: variables})
+	req, err := http.NewRequest(http.MethodPost, s.server.URL, bytes.NewBuffer(reqBody))
+	assert.NoError(s.T(), err)
+	req.Header.Set("Content-Type", "application/json")
+	authorize(req, caller)
+
+	resp, err := http.DefaultClient.Do(req)
+	assert.NoError(s.T(), err)
+	defer resp.Body.Close()
+
+	var result graphQLResponse
+	assert.NoError(s.T(), json.NewDecoder(resp.Body).Decode(&result))
+	return &result
+}
+
+// transfer moves 100 tokens between two wallets
+func (s *BlocklistSuite) transfer(from, to string) *graphQLResponse {
+	return s.execute(`mutation($from: Address!, $to: Address!) { transfer(from_address: $from, to_address: $to, amount: "100") { balance } }`,
+		map[string]interface{}{"from": from, "to": to}, "")
+}
+
+// TestTransfersInvolvingBlockedAddressFail tests both directions are refused
+func (s *BlocklistSuite) TestTransfersInvolvingBlockedAddressFail() {
+	for _, pair := range [][2]string{{blocklistBlocked, blocklistAlice}, {blocklistAlice, blocklistBlocked}} {
+		result := s.transfer(pair[0], pair[1])
+		assert.NotNil(s.T(), result.Errors)
+		assert.Equal(s.T(), "BLOCKED_ADDRESS", result.Errors[0]["extensions"].(map[string]interface{})["code"])
+	}
+
+	assert.Equal(s.T(), "1000", s.getBalance(blocklistBlocked))
+	assert.Equal(s.T(), "1000", s.getBalance(blocklistAlice))
+}
+
+// TestUnrelatedTransfersSucceed tests that other wallets are unaffected
+func (s *BlocklistSuite) TestUnrelatedTransfersSucceed() {
+	result := s.transfer(blocklistAlice, blocklistBob)
+	assert.Nil(s.T(), result.Errors)
+	assert.Equal(s.T(), "1100", s.getBalance(blocklistBob))
+}
+
+// TestUnblockRestoresTransfers tests that removing the block lets transfers through again
+func (s *BlocklistSuite) TestUnblockRestoresTransfers() {
+	result := s.execute(`mutation($a: String!) { removeAddressFromBlocklist(address: $a) }`,
+		map[string]interface{}{"a": blocklistBlocked}, blocklistAdmin)
+	assert.Nil(s.T(), result.Errors)
+	assert.Equal(s.T(), true, result.Data["removeAddressFromBlocklist"])
+
+	result = s.transfer(blocklistBlocked, blocklistAlice)
+	assert.Nil(s.T(), result.Errors)
+}
+
+// TestBlocklistRequiresAdmin tests that other callers cannot change the blocklist
+func (s *BlocklistSuite) TestBlocklistRequiresAdmin() {
+	result := s.execute(`mutation($a: String!) { removeAddressFromBlocklist(address: $a) }`,
+		map[string]interface{}{"a": blocklistBlocked}, blocklistAlice)
+	assert.NotNil(s.T(), result.Errors)
+	assert.Equal(s.T(), "UNAUTHORIZED", result.Errors[0]["extensions"].(map[string]interface{})["code"])
+}
+
+// Run the blocklist test suite
+func TestBlocklistSuite(t *testing.T) {
+	suite.Run(t, new(BlocklistSuite))
+}
@@ -0,0 +1,86 @@
+package integration
+
+import (
+	"context"
+	"testing"
+	"token-transfer-api/internal/db"
+	"token-transfer-api/internal/graph"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/suite"
+)
+
+const (
+	canonicalSender   = "0x3d00000000000000000000000000000000000001"
+	canonicalReceiver = "0x3d00000000000000000000000000000000000002"
+)
+
+type CanonicalAmountSuite struct {
+	suite.Suite
+}
+
+// SetupSuite initializes the database connection
+func (s *CanonicalAmountSuite) SetupSuite() {
+	setupDB(s.T())
+}
+
+// TearDownSuite closes the database connection
+func (s *CanonicalAmountSuite) TearDownSuite() {
+	db.CloseDB()
+}
+
+// SetupTest turns off fees and funds the sender
+func (s *CanonicalAmountSuite) SetupTest() {
+	restoreSettings(s.T())
+	db.Settings.FeeWallet = ""
+	s.cleanup()
+	_, err := db.DB.Exec("INSERT INTO wallets (address, balance) VALUES ($1, 100000)", canonicalSender)
+	assert.NoError(s.T(), err)
+}
+
+// TearDownTest removes the suite's rows
+func (s *CanonicalAmountSuite) TearDownTest() {
+	s.cleanup()
+}
+
+func (s *CanonicalAmountSuite) cleanup() {
+	_, err := db.DB.Exec("DELETE FROM transfers WHERE from_address LIKE '0x3d%' OR to_address LIKE '0x3d%'")
+	assert.NoError(s.T(), err)
+	_, err = db.DB.Exec("DELETE FROM wallets WHERE address LIKE '0x3d%'")
+	assert.NoError(s.T(), err)
+}
+
+// TestNonCanonicalAmountIsNormalized tests that amounts spelled with extra
+// zeros are credited and recorded in canonical base units
+func (s *CanonicalAmountSuite) TestNonCanonicalAmountIsNormalized() {
+	resolver := &graph.Resolver{Decimals: 2}
+
+	for _, amount := range []string{"12.50", "0.0700"} {
+		_, err := resolver.Transfer(context.Background(), graph.TransferArgs{
+			FromAddress: canonicalSender,
+			ToAddress:   canonicalReceiver,
+			Amount:      amount,
+		})
+		assert.NoError(s.T(), err, amount)
+	}
+
+	rows, err := db.DB.Query("SELECT amount::text, to_balance_after::text FROM transfers WHERE from_address = $1 ORDER BY id", canonicalSender)
+	assert.NoError(s.T(), err)
+	defer rows.Close()
+
+	var recorded [][2]string
+	for rows.Next() {
+		var r [2]string
+		assert.NoError(s.T(), rows.Scan(&r[0], &r[1]))
+		recorded = append(recorded, r)
+	}
+	assert.Equal(s.T(), [][2]string{{"1250", "1250"}, {"7", "1257"}}, recorded)
+
+	wallet, err := db.GetWallet(canonicalReceiver)
+	assert.NoError(s.T(), err)
+	assert.Equal(s.T(), "1257", wallet.Balance)
+}
+
+func TestCanonicalAmountSuite(t *testing.T) {
+	suite.Run(t, new(CanonicalAmountSuite))
+}
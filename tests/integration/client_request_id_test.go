@@ -0,0 +1,134 @@
+package integration
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"token-transfer-api/internal/db"
+	"token-transfer-api/pkg/graphql"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/suite"
+)
+
+const (
+	dedupSender   = "0x1600000000000000000000000000000000000001"
+	dedupReceiver = "0x1600000000000000000000000000000000000002"
+	dedupMixed    = "0x16000000000000000000000000000000000000ab"
+)
+
+type ClientRequestIDSuite struct {
+	suite.Suite
+	server *httptest.Server
+}
+
+// SetupSuite initializes the test environment
+func (s *ClientRequestIDSuite) SetupSuite() {
+	setupDB(s.T())
+
+	s.T().Setenv("TRANSFER_DEDUP_WINDOW", "1m")
+	handler := graphql.NewHandler()
+	s.server = httptest.NewServer(handler)
+}
+
+// TearDownSuite cleans up the test environment
+func (s *ClientRequestIDSuite) TearDownSuite() {
+	s.server.Close()
+	db.CloseDB()
+}
+
+// SetupTest resets the wallets used by the dedup tests
+func (s *ClientRequestIDSuite) SetupTest() {
+	s.createWallet(dedupSender, "1000")
+	s.createWallet(dedupReceiver, "0")
+	s.createWallet(dedupMixed, "1000")
+}
+
+// createWallet creates a wallet with the specified balance
+func (s *ClientRequestIDSuite) createWallet(address, balance string) {
+	_, err := db.DB.Exec("INSERT INTO wallets (address, balance) VALUES ($1, $2) ON CONFLICT (address) DO UPDATE SET balance = $2",
+		address, balance)
+	assert.NoError(s.T(), err)
+}
+
+// getBalance gets a wallet's balance
+func (s *ClientRequestIDSuite) getBalance(address string) string {
+	var balance string
+	err := db.DB.QueryRow("SELECT balance FROM wallets WHERE address = $1", address).Scan(&balance)
+	assert.NoError(s.T(), err)
+	return balance
+}
+
+// transfer executes a transfer from dedupSender with the given client request id
+func (s *ClientRequestIDSuite) transfer(amount, clientRequestID string) map[string]interface{} {
+	return s.transferFrom(dedupSender, amount, clientRequestID)
+}
+
+// transferFrom executes a transfer from sender with the given client request id
+func (s *ClientRequestIDSuite) transferFrom(sender, amount, clientRequestID string) map[string]interface{} {
+	reqBody, _ := json.Marshal(graphQLRequest{
+		Query: `mutation($from: Address!, $to: Address!, $amount: Amount!, $id: String) {
+			transfer(from_address: $from, to_address: $to, amount: $amount, client_request_id: $id) {
+				balance
+				client_request_id
+			}
+		}`,
+		Variables: map[string]interface{}{
+			"from":   sender,
+			"to":     dedupReceiver,
+			"amount": amount,
+			"id":     clientRequestID,
+		},
+	})
+	resp, err := http.Post(s.server.URL, "application/json", bytes.NewBuffer(reqBody))
+	assert.NoError(s.T(), err)
+	defer resp.Body.Close()
+
+	var result graphQLResponse
+	assert.NoError(s.T(), json.NewDecoder(resp.Body).Decode(&result))
+	assert.Nil(s.T(), result.Errors)
+	return result.Data["transfer"].(map[string]interface{})
+}
+
+// TestDoubleSubmitTransfersOnce tests that a resubmission within the window returns the first result
+func (s *ClientRequestIDSuite) TestDoubleSubmitTransfersOnce() {
+	first := s.transfer("100", "click-1")
+	second := s.transfer("100", "click-1")
+
+	assert.Equal(s.T(), "900", first["balance"])
+	assert.Equal(s.T(), "click-1", first["client_request_id"])
+	assert.Equal(s.T(), first, second)
+
+	assert.Equal(s.T(), "900", s.getBalance(dedupSender))
+	assert.Equal(s.T(), "100", s.getBalance(dedupReceiver))
+}
+
+// TestDistinctRequestIDsTransferTwice tests that different ids are separate transfers
+func (s *ClientRequestIDSuite) TestDistinctRequestIDsTransferTwice() {
+	s.transfer("100", "click-2")
+	s.transfer("100", "click-3")
+
+	assert.Equal(s.T(), "800", s.getBalance(dedupSender))
+	assert.Equal(s.T(), "200", s.getBalance(dedupReceiver))
+}
+
+// TestCaseVariantSenderDeduplicated tests that with case-insensitive
+// addresses a resubmission spelling the sender in another case is a duplicate
+func (s *ClientRequestIDSuite) TestCaseVariantSenderDeduplicated() {
+	restoreSettings(s.T())
+	db.Settings.CaseInsensitiveAddresses = true
+
+	first := s.transferFrom(dedupMixed, "100", "click-4")
+	second := s.transferFrom("0x16000000000000000000000000000000000000AB", "100", "click-4")
+
+	assert.Equal(s.T(), first, second)
+	assert.Equal(s.T(), "900", s.getBalance(dedupMixed))
+	assert.Equal(s.T(), "100", s.getBalance(dedupReceiver))
+}
+
+// Run the client request id test suite
+func TestClientRequestIDSuite(t *testing.T) {
+	suite.Run(t, new(ClientRequestIDSuite))
+}
@@ -0,0 +1,123 @@
+package integration
+
+import (
+	"database/sql"
+	"fmt"
+	"sync"
+	"testing"
+	"token-transfer-api/internal/db"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/suite"
+)
+
+const (
+	newReceiverSenderA = "0x5900000000000000000000000000000000000001"
+	newReceiverSenderB = "0x5900000000000000000000000000000000000002"
+)
+
+// newReceiverRounds is how many fresh receivers each test pays, to give the
+// two transfers many chances to create the same wallet at the same moment.
+const newReceiverRounds = 20
+
+type ConcurrentReceiverSuite struct {
+	suite.Suite
+}
+
+// SetupSuite initializes the database connection
+func (s *ConcurrentReceiverSuite) SetupSuite() {
+	setupDB(s.T())
+	restoreSettings(s.T())
+}
+
+// TearDownSuite closes the database connection
+func (s *ConcurrentReceiverSuite) TearDownSuite() {
+	s.cleanup()
+	db.CloseDB()
+}
+
+// SetupTest gives both senders 1000 tokens, without fees, and lets
+// transfers create their receivers
+func (s *ConcurrentReceiverSuite) SetupTest() {
+	restoreSettings(s.T())
+	db.Settings.FeeWallet = ""
+	db.Settings.ReceiverMustExist = false
+
+	s.cleanup()
+	_, err := db.DB.Exec("INSERT INTO wallets (address, balance) VALUES ($1, 1000), ($2, 1000)", newReceiverSenderA, newReceiverSenderB)
+	assert.NoError(s.T(), err)
+}
+
+// cleanup removes the suite's transfers and wallets
+func (s *ConcurrentReceiverSuite) cleanup() {
+	_, err := db.DB.Exec("DELETE FROM transfers WHERE from_address LIKE '0x59%' OR to_address LIKE '0x59%'")
+	assert.NoError(s.T(), err)
+	_, err = db.DB.Exec("DELETE FROM wallets WHERE address LIKE '0x59%'")
+	assert.NoError(s.T(), err)
+}
+
+// payFreshReceivers has both senders pay each of newReceiverRounds new
+// addresses at the same time, 10 from A and 7 from B, and checks that every
+// receiver ends up with both payments
+func (s *ConcurrentReceiverSuite) payFreshReceivers() {
+	for round := 1; round <= newReceiverRounds; round++ {
+		receiver := fmt.Sprintf("0x59%038x", 0x100+round)
+
+		start := make(chan struct{})
+		errs := make([]error, 2)
+		var wg sync.WaitGroup
+		for i, payment := range []struct{ from, amount string }{
+			{newReceiverSenderA, "10"},
+			{newReceiverSenderB, "7"},
+		} {
+			wg.Add(1)
+			go func(i int, from, amount string) {
+				defer wg.Done()
+				<-start
+				_, errs[i] = db.TransferTokens(from, receiver, amount)
+			}(i, payment.from, payment.amount)
+		}
+		close(start)
+		wg.Wait()
+
+		assert.NoError(s.T(), errs[0], receiver)
+		assert.NoError(s.T(), errs[1], receiver)
+
+		wallet, err := db.GetWallet(receiver)
+		assert.NoError(s.T(), err)
+		if assert.NotNil(s.T(), wallet, receiver) {
+			assert.Equal(s.T(), "17", wallet.Balance, receiver)
+		}
+	}
+
+	for address, balance := range map[string]string{
+		newReceiverSenderA: fmt.Sprint(1000 - 10*newReceiverRounds),
+		newReceiverSenderB: fmt.Sprint(1000 - 7*newReceiverRounds),
+	} {
+		wallet, err := db.GetWallet(address)
+		assert.NoError(s.T(), err)
+		if assert.NotNil(s.T(), wallet) {
+			assert.Equal(s.T(), balance, wallet.Balance, address)
+		}
+	}
+}
+
+// TestConcurrentFirstCredits tests that two transfers creating the same receiver at once both credit it
+func (s *ConcurrentReceiverSuite) TestConcurrentFirstCredits() {
+	s.payFreshReceivers()
+}
+
+// TestConcurrentFirstCreditsAtEachIsolation tests the same under every supported isolation level; under
+// REPEATABLE READ and SERIALIZABLE the losing insert is a serialization failure that the transfer retries
+func (s *ConcurrentReceiverSuite) TestConcurrentFirstCreditsAtEachIsolation() {
+	for _, level := range []sql.IsolationLevel{sql.LevelReadCommitted, sql.LevelRepeatableRead, sql.LevelSerializable} {
+		s.SetupTest()
+		db.Settings.Isolation = level
+		s.Run(level.String(), s.payFreshReceivers)
+	}
+}
+
+// Run the concurrent receiver test suite
+func TestConcurrentReceiverSuite(t *testing.T) {
+	suite.Run(t, new(ConcurrentReceiverSuite))
+}
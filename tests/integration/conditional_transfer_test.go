@@ -0,0 +1,134 @@
+package integration
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"token-transfer-api/internal/db"
+	"token-transfer-api/pkg/graphql"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/suite"
+)
+
+const (
+	conditionalSender   = "0x5300000000000000000000000000000000000001"
+	conditionalReceiver = "0x5300000000000000000000000000000000000002"
+)
+
+type ConditionalTransferSuite struct {
+	suite.Suite
+	server *httptest.Server
+}
+
+// SetupSuite initializes the database connection and the GraphQL server
+func (s *ConditionalTransferSuite) SetupSuite() {
+	setupDB(s.T())
+	restoreSettings(s.T())
+
+	s.T().Setenv("TOKEN_DECIMALS", "0")
+	s.server = httptest.NewServer(graphql.NewHandler())
+}
+
+// TearDownSuite closes the server and the database connection
+func (s *ConditionalTransferSuite) TearDownSuite() {
+	s.cleanup()
+	s.server.Close()
+	db.CloseDB()
+}
+
+// SetupTest gives the sender 800 tokens without fees
+func (s *ConditionalTransferSuite) SetupTest() {
+	restoreSettings(s.T())
+	db.Settings.FeeWallet = ""
+
+	s.cleanup()
+	_, err := db.DB.Exec("INSERT INTO wallets (address, balance) VALUES ($1, 800)", conditionalSender)
+	assert.NoError(s.T(), err)
+}
+
+func (s *ConditionalTransferSuite) cleanup() {
+	_, err := db.DB.Exec("DELETE FROM transfers WHERE from_address LIKE '0x53%' OR to_address LIKE '0x53%'")
+	assert.NoError(s.T(), err)
+	_, err = db.DB.Exec("DELETE FROM wallets WHERE address LIKE '0x53%'")
+	assert.NoError(s.T(), err)
+}
+
+// transfer sends 100 tokens through the transfer mutation on condition the sender holds minBalance
+func (s *ConditionalTransferSuite) transfer(minBalance string) *graphQLResponse {
+	query := fmt.Sprintf(`mutation { transfer(from_address: "%s", to_address: "%s", amount: "100", require_min_balance: "%s") { balance } }`,
+		conditionalSender, conditionalReceiver, minBalance)
+	reqBody, _ := json.Marshal(graphQLRequest{Query: query})
+	resp, err := http.Post(s.server.URL, "application/json", bytes.NewBuffer(reqBody))
+	if !assert.NoError(s.T(), err) {
+		return &graphQLResponse{}
+	}
+	defer resp.Body.Close()
+
+	var result graphQLResponse
+	assert.NoError(s.T(), json.NewDecoder(resp.Body).Decode(&result))
+	return &result
+}
+
+func (s *ConditionalTransferSuite) wallet() (balance string, nonce int64) {
+	wallet, err := db.GetWallet(conditionalSender)
+	if !assert.NoError(s.T(), err) || !assert.NotNil(s.T(), wallet) {
+		return "", -1
+	}
+	return wallet.Balance, wallet.Nonce
+}
+
+// TestConditionNotMet tests that a threshold above the balance skips the transfer even though the amount is covered
+func (s *ConditionalTransferSuite) TestConditionNotMet() {
+	result := s.transfer("1000")
+	if assert.NotEmpty(s.T(), result.Errors) {
+		extensions := result.Errors[0]["extensions"].(map[string]interface{})
+		assert.Equal(s.T(), "CONDITION_NOT_MET", extensions["code"])
+		assert.Equal(s.T(), "800", extensions["balance"])
+	}
+
+	balance, nonce := s.wallet()
+	assert.Equal(s.T(), "800", balance)
+	assert.Equal(s.T(), int64(0), nonce)
+
+	receiver, err := db.GetWallet(conditionalReceiver)
+	assert.NoError(s.T(), err)
+	assert.Nil(s.T(), receiver)
+}
+
+// TestConditionMet tests that a threshold the balance reaches lets the transfer go ahead
+func (s *ConditionalTransferSuite) TestConditionMet() {
+	result := s.transfer("800")
+	assert.Nil(s.T(), result.Errors)
+	assert.Equal(s.T(), "700", result.Data["transfer"].(map[string]interface{})["balance"])
+
+	// The condition is checked against the balance before each transfer
+	result = s.transfer("800")
+	if assert.NotEmpty(s.T(), result.Errors) {
+		assert.Equal(s.T(), "CONDITION_NOT_MET", result.Errors[0]["extensions"].(map[string]interface{})["code"])
+	}
+
+	balance, nonce := s.wallet()
+	assert.Equal(s.T(), "700", balance)
+	assert.Equal(s.T(), int64(1), nonce)
+}
+
+// TestDryRunReportsConditionNotMet tests that a dry run reports an unmet condition as its failure
+func (s *ConditionalTransferSuite) TestDryRunReportsConditionNotMet() {
+	result, err := db.SimulateTransfer(context.Background(), conditionalSender, conditionalReceiver, "100", "", nil, "801")
+	assert.NoError(s.T(), err)
+	assert.False(s.T(), result.WouldSucceed)
+	assert.Equal(s.T(), db.ErrConditionNotMet.Code, result.FailureCode)
+
+	result, err = db.SimulateTransfer(context.Background(), conditionalSender, conditionalReceiver, "100", "", nil, "800")
+	assert.NoError(s.T(), err)
+	assert.True(s.T(), result.WouldSucceed)
+}
+
+func TestConditionalTransferSuite(t *testing.T) {
+	suite.Run(t, new(ConditionalTransferSuite))
+}
@@ -0,0 +1,112 @@
+package integration
+
+import (
+	"errors"
+	"testing"
+	"token-transfer-api/internal/db"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/suite"
+)
+
+const (
+	corruptSender   = "0x5000000000000000000000000000000000000001"
+	corruptReceiver = "0x5000000000000000000000000000000000000002"
+)
+
+type CorruptBalanceSuite struct {
+	suite.Suite
+}
+
+// SetupSuite initializes the database connection
+func (s *CorruptBalanceSuite) SetupSuite() {
+	setupDB(s.T())
+	restoreSettings(s.T())
+}
+
+// TearDownSuite closes the database connection
+func (s *CorruptBalanceSuite) TearDownSuite() {
+	s.cleanup()
+	db.CloseDB()
+}
+
+// SetupTest creates a funded sender and a receiver without fees
+func (s *CorruptBalanceSuite) SetupTest() {
+	restoreSettings(s.T())
+	db.Settings.FeeWallet = ""
+
+	s.cleanup()
+	_, err := db.DB.Exec("INSERT INTO wallets (address, balance) VALUES ($1, 1000), ($2, 10)", corruptSender, corruptReceiver)
+	assert.NoError(s.T(), err)
+}
+
+func (s *CorruptBalanceSuite) cleanup() {
+	_, err := db.DB.Exec("DELETE FROM transfers WHERE from_address LIKE '0x50%' OR to_address LIKE '0x50%'")
+	assert.NoError(s.T(), err)
+	_, err = db.DB.Exec("DELETE FROM wallets WHERE address LIKE '0x50%'")
+	assert.NoError(s.T(), err)
+}
+
+// corrupt sets the balance of address to NaN, which the DECIMAL column and
+// its CHECK constraint accept
+func (s *CorruptBalanceSuite) corrupt(address string) {
+	_, err := db.DB.Exec("UPDATE wallets SET balance = 'NaN' WHERE address = $1", address)
+	assert.NoError(s.T(), err)
+}
+
+// assertCorrupt asserts that err is ErrCorruptBalance naming address
+func (s *CorruptBalanceSuite) assertCorrupt(err error, address string) {
+	var appErr *db.AppError
+	if assert.True(s.T(), errors.As(err, &appErr), "got %v", err) {
+		assert.Equal(s.T(), db.ErrCorruptBalance.Code, appErr.Code)
+		assert.Equal(s.T(), address, appErr.Details["address"])
+		assert.Contains(s.T(), appErr.Unwrap().Error(), address)
+	}
+}
+
+func (s *CorruptBalanceSuite) receiverBalance() string {
+	var balance string
+	assert.NoError(s.T(), db.DB.QueryRow("SELECT balance::text FROM wallets WHERE address = $1", corruptReceiver).Scan(&balance))
+	return balance
+}
+
+// TestCorruptSender tests that a transfer from a wallet with a corrupt balance fails cleanly
+func (s *CorruptBalanceSuite) TestCorruptSender() {
+	s.corrupt(corruptSender)
+
+	_, err := db.TransferTokens(corruptSender, corruptReceiver, "100")
+	s.assertCorrupt(err, corruptSender)
+
+	_, err = db.TransferTokensCTE(corruptSender, corruptReceiver, "100")
+	s.assertCorrupt(err, corruptSender)
+
+	assert.Equal(s.T(), "10", s.receiverBalance())
+}
+
+// TestCorruptReceiver tests that crediting a corrupt balance is not mistaken for an overflow
+func (s *CorruptBalanceSuite) TestCorruptReceiver() {
+	s.corrupt(corruptReceiver)
+
+	_, err := db.TransferTokens(corruptSender, corruptReceiver, "100")
+	s.assertCorrupt(err, corruptReceiver)
+
+	_, err = db.TransferTokensCTE(corruptSender, corruptReceiver, "100")
+	s.assertCorrupt(err, corruptReceiver)
+
+	wallet, err := db.GetWallet(corruptSender)
+	assert.NoError(s.T(), err)
+	assert.Equal(s.T(), "1000", wallet.Balance)
+}
+
+// TestCorruptWalletLookup tests that reading a corrupt wallet reports it instead of returning NaN
+func (s *CorruptBalanceSuite) TestCorruptWalletLookup() {
+	s.corrupt(corruptReceiver)
+
+	wallet, err := db.GetWallet(corruptReceiver)
+	assert.Nil(s.T(), wallet)
+	s.assertCorrupt(err, corruptReceiver)
+}
+
+func TestCorruptBalanceSuite(t *testing.T) {
+	suite.Run(t, new(CorruptBalanceSuite))
+}
@@ -0,0 +1,91 @@
+package integration
+
+import (
+	"testing"
+	"token-transfer-api/internal/db"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/suite"
+)
+
+const (
+	creditNew      = "0x4000000000000000000000000000000000000001"
+	creditExisting = "0x4000000000000000000000000000000000000002"
+)
+
+type CreditWalletSuite struct {
+	suite.Suite
+}
+
+// SetupSuite initializes the database connection
+func (s *CreditWalletSuite) SetupSuite() {
+	setupDB(s.T())
+}
+
+// TearDownSuite closes the database connection
+func (s *CreditWalletSuite) TearDownSuite() {
+	s.cleanup()
+	db.CloseDB()
+}
+
+// SetupTest leaves only creditExisting, with a balance of 100
+func (s *CreditWalletSuite) SetupTest() {
+	s.cleanup()
+	_, err := db.DB.Exec("INSERT INTO wallets (address, balance) VALUES ($1, 100)", creditExisting)
+	assert.NoError(s.T(), err)
+}
+
+func (s *CreditWalletSuite) cleanup() {
+	_, err := db.DB.Exec("DELETE FROM wallets WHERE address LIKE '0x40%'")
+	assert.NoError(s.T(), err)
+}
+
+// TestCreditNewAndExistingWallet tests that one helper creates a missing wallet and adds to an existing one
+func (s *CreditWalletSuite) TestCreditNewAndExistingWallet() {
+	balance, err := db.CreditWallet(creditNew, "25")
+	assert.NoError(s.T(), err)
+	assert.Equal(s.T(), "25", balance)
+
+	balance, err = db.CreditWallet(creditExisting, "25")
+	assert.NoError(s.T(), err)
+	assert.Equal(s.T(), "125", balance)
+
+	// Crediting again adds rather than overwrites
+	balance, err = db.CreditWallet(creditNew, "1000000000000000000000000000000")
+	assert.NoError(s.T(), err)
+	assert.Equal(s.T(), "1000000000000000000000000000025", balance)
+
+	for _, address := range []string{creditNew, creditExisting} {
+		wallet, err := db.GetWallet(address)
+		assert.NoError(s.T(), err)
+		if assert.NotNil(s.T(), wallet) {
+			assert.NotNil(s.T(), wallet.LastActivityAt, "crediting is wallet activity")
+		}
+	}
+
+	// Nothing is recorded as a transfer
+	var count int
+	err = db.DB.QueryRow("SELECT COUNT(*) FROM transfers WHERE to_address LIKE '0x40%'").Scan(&count)
+	assert.NoError(s.T(), err)
+	assert.Equal(s.T(), 0, count)
+}
+
+// TestCreditRejectsBadInput tests that invalid amounts and addresses change nothing
+func (s *CreditWalletSuite) TestCreditRejectsBadInput() {
+	for _, amount := range []string{"0", "-5", "1.5", "abc"} {
+		_, err := db.CreditWallet(creditExisting, amount)
+		assert.ErrorIs(s.T(), err, db.ErrInvalidAmount, amount)
+	}
+
+	_, err := db.CreditWallet("0x40", "5")
+	assert.ErrorIs(s.T(), err, db.ErrInvalidAddress)
+
+	wallet, err := db.GetWallet(creditExisting)
+	assert.NoError(s.T(), err)
+	assert.Equal(s.T(), "100", wallet.Balance)
+}
+
+// Run the credit wallet test suite
+func TestCreditWalletSuite(t *testing.T) {
+	suite.Run(t, new(CreditWalletSuite))
+}
@@ -0,0 +1,133 @@
+package integration
+
+import (
+	"context"
+	"errors"
+	"math/big"
+	"sync"
+	"testing"
+	"time"
+	"token-transfer-api/internal/db"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/suite"
+)
+
+const (
+	limitSender   = "0x3500000000000000000000000000000000000001"
+	limitReceiver = "0x3500000000000000000000000000000000000002"
+)
+
+type DailyLimitSuite struct {
+	suite.Suite
+}
+
+// SetupSuite initializes the database connection
+func (s *DailyLimitSuite) SetupSuite() {
+	setupDB(s.T())
+}
+
+// TearDownSuite closes the database connection
+func (s *DailyLimitSuite) TearDownSuite() {
+	db.CloseDB()
+}
+
+// SetupTest sets a daily limit of 500 and funds the sender well beyond it
+func (s *DailyLimitSuite) SetupTest() {
+	restoreSettings(s.T())
+	cfg := db.Settings
+	cfg.DailyLimit = big.NewInt(500)
+	db.Settings = cfg
+
+	s.cleanup()
+	_, err := db.DB.Exec("INSERT INTO wallets (address, balance) VALUES ($1, 10000)", limitSender)
+	assert.NoError(s.T(), err)
+}
+
+// TearDownTest removes the suite's rows
+func (s *DailyLimitSuite) TearDownTest() {
+	s.cleanup()
+}
+
+func (s *DailyLimitSuite) cleanup() {
+	_, err := db.DB.Exec("DELETE FROM transfers WHERE from_address LIKE '0x35%' OR to_address LIKE '0x35%'")
+	assert.NoError(s.T(), err)
+	_, err = db.DB.Exec("DELETE FROM wallets WHERE address LIKE '0x35%'")
+	assert.NoError(s.T(), err)
+}
+
+func (s *DailyLimitSuite) transfer(amount string) error {
+	_, err := db.ExecuteTransfer(context.Background(), limitSender, limitReceiver, amount, "", nil, "")
+	return err
+}
+
+// TestUpToAndPastLimit tests that transfers are accepted up to the limit and rejected beyond it
+func (s *DailyLimitSuite) TestUpToAndPastLimit() {
+	assert.NoError(s.T(), s.transfer("200"))
+	assert.NoError(s.T(), s.transfer("300"))
+
+	sent, err := db.GetSentInWindow(limitSender, time.Now().Add(-db.DailyLimitWindow))
+	assert.NoError(s.T(), err)
+	assert.Equal(s.T(), "500", sent)
+
+	err = s.transfer("1")
+	assert.ErrorIs(s.T(), err, db.ErrDailyLimitExceeded)
+	var appErr *db.AppError
+	if assert.True(s.T(), errors.As(err, &appErr)) {
+		assert.Equal(s.T(), "0", appErr.Details["remaining"])
+	}
+
+	// The rejected transfer moved nothing
+	var balance string
+	assert.NoError(s.T(), db.DB.QueryRow("SELECT balance FROM wallets WHERE address = $1", limitSender).Scan(&balance))
+	assert.Equal(s.T(), "9500", balance)
+}
+
+// TestSingleTransferOverLimit tests that a transfer larger than what is left is rejected whole
+func (s *DailyLimitSuite) TestSingleTransferOverLimit() {
+	assert.NoError(s.T(), s.transfer("400"))
+
+	err := s.transfer("101")
+	assert.ErrorIs(s.T(), err, db.ErrDailyLimitExceeded)
+	var appErr *db.AppError
+	if assert.True(s.T(), errors.As(err, &appErr)) {
+		assert.Equal(s.T(), "100", appErr.Details["remaining"])
+	}
+
+	assert.NoError(s.T(), s.transfer("100"))
+}
+
+// TestOldTransfersDoNotCount tests that transfers outside the window are ignored
+func (s *DailyLimitSuite) TestOldTransfersDoNotCount() {
+	_, err := db.DB.Exec("INSERT INTO wallets (address, balance) VALUES ($1, 0)", limitReceiver)
+	assert.NoError(s.T(), err)
+	_, err = db.DB.Exec("INSERT INTO transfers (from_address, to_address, amount, created_at) VALUES ($1, $2, 500, CURRENT_TIMESTAMP - INTERVAL '25 hours')",
+		limitSender, limitReceiver)
+	assert.NoError(s.T(), err)
+
+	assert.NoError(s.T(), s.transfer("500"))
+}
+
+// TestConcurrentTransfersRespectLimit tests that racing transfers cannot jointly exceed the limit
+func (s *DailyLimitSuite) TestConcurrentTransfersRespectLimit() {
+	var wg sync.WaitGroup
+	for i := 0; i < 10; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			s.transfer("100")
+		}()
+	}
+	wg.Wait()
+
+	// Transfers that lost a serialization conflict may have failed, so fewer
+	// than five can succeed, but never more
+	sent, err := db.GetSentInWindow(limitSender, time.Now().Add(-db.DailyLimitWindow))
+	assert.NoError(s.T(), err)
+	sentBig, _ := new(big.Int).SetString(sent, 10)
+	assert.LessOrEqual(s.T(), sentBig.Cmp(big.NewInt(500)), 0)
+}
+
+func TestDailyLimitSuite(t *testing.T) {
+	suite.Run(t, new(DailyLimitSuite))
+}
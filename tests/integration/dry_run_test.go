@@ -0,0 +1,129 @@
+package integration
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"token-transfer-api/internal/db"
+	"token-transfer-api/pkg/graphql"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/suite"
+)
+
+const (
+	dryRunSender   = "0x2500000000000000000000000000000000000001"
+	dryRunReceiver = "0x2500000000000000000000000000000000000002"
+)
+
+type DryRunSuite struct {
+	suite.Suite
+	server *httptest.Server
+}
+
+// SetupSuite initializes the test environment
+func (s *DryRunSuite) SetupSuite() {
+	setupDB(s.T())
+
+	// Setup GraphQL handler
+	handler := graphql.NewHandler()
+	s.server = httptest.NewServer(handler)
+}
+
+// TearDownSuite cleans up the test environment
+func (s *DryRunSuite) TearDownSuite() {
+	s.server.Close()
+	db.CloseDB()
+}
+
+// SetupTest resets the wallets and their history
+func (s *DryRunSuite) SetupTest() {
+	_, err := db.DB.Exec("DELETE FROM transfers WHERE from_address = $1", dryRunSender)
+	assert.NoError(s.T(), err)
+	s.createWallet(dryRunSender, "100")
+	s.createWallet(dryRunReceiver, "0")
+}
+
+// createWallet creates a wallet with the specified balance
+func (s *DryRunSuite) createWallet(address, balance string) {
+	_, err := db.DB.Exec("INSERT INTO wallets (address, balance) VALUES ($1, $2) ON CONFLICT (address) DO UPDATE SET balance = $2",
+		address, balance)
+	assert.NoError(s.T(), err)
+}
+
+// getBalance gets a wallet's balance
+func (s *DryRunSuite) getBalance(address string) string {
+	var balance string
+	err := db.DB.QueryRow("SELECT balance FROM wallets WHERE address = $1", address).Scan(&balance)
+	assert.NoError(s.T(), err)
+	return balance
+}
+
+// transferCount counts the transfers recorded for the sender
+func (s *DryRunSuite) transferCount() int {
+	var count int
+	err := db.DB.QueryRow("SELECT COUNT(*) FROM transfers WHERE from_address = $1", dryRunSender).Scan(&count)
+	assert.NoError(s.T(), err)
+	return count
+}
+
+// dryRun simulates a transfer of amount
+func (s *DryRunSuite) dryRun(amount string) map[string]interface{} {
+	reqBody, _ := json.Marshal(graphQLRequest{
+		Query: `mutation($from: Address!, $to: Address!, $amount: Amount!) {
+			transfer(from_address: $from, to_address: $to, amount: $amount, dry_run: true) {
+				balance
+				would_succeed
+				failure_code
+				failure_message
+			}
+		}`,
+		Variables: map[string]interface{}{
+			"from":   dryRunSender,
+			"to":     dryRunReceiver,
+			"amount": amount,
+		},
+	})
+	resp, err := http.Post(s.server.URL, "application/json", bytes.NewBuffer(reqBody))
+	assert.NoError(s.T(), err)
+	defer resp.Body.Close()
+
+	var result graphQLResponse
+	assert.NoError(s.T(), json.NewDecoder(resp.Body).Decode(&result))
+	assert.Nil(s.T(), result.Errors)
+	return result.Data["transfer"].(map[string]interface{})
+}
+
+// TestDryRunInsufficientBalance tests that a failing dry run reports failure without side effects
+func (s *DryRunSuite) TestDryRunInsufficientBalance() {
+	result := s.dryRun("500")
+
+	assert.Equal(s.T(), false, result["would_succeed"])
+	assert.Equal(s.T(), "INSUFFICIENT_BALANCE", result["failure_code"])
+	assert.Equal(s.T(), "insufficient balance", result["failure_message"])
+	assert.Nil(s.T(), result["balance"])
+
+	assert.Equal(s.T(), "100", s.getBalance(dryRunSender))
+	assert.Equal(s.T(), "0", s.getBalance(dryRunReceiver))
+	assert.Equal(s.T(), 0, s.transferCount())
+}
+
+// TestDryRunSuccess tests that a passing dry run previews the balance but changes nothing
+func (s *DryRunSuite) TestDryRunSuccess() {
+	result := s.dryRun("40")
+
+	assert.Equal(s.T(), true, result["would_succeed"])
+	assert.Equal(s.T(), "60", result["balance"])
+	assert.Nil(s.T(), result["failure_code"])
+
+	assert.Equal(s.T(), "100", s.getBalance(dryRunSender))
+	assert.Equal(s.T(), "0", s.getBalance(dryRunReceiver))
+	assert.Equal(s.T(), 0, s.transferCount())
+}
+
+// Run the dry run test suite
+func TestDryRunSuite(t *testing.T) {
+	suite.Run(t, new(DryRunSuite))
+}
@@ -0,0 +1,76 @@
+package integration
+
+import (
+	"bytes"
+	"encoding/json"
+	"log"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strings"
+	"testing"
+	"token-transfer-api/internal/db"
+	"token-transfer-api/pkg/graphql"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/suite"
+)
+
+const sanitizeAdmin = "0x1900000000000000000000000000000000000001"
+
+type ErrorSanitizationSuite struct {
+	suite.Suite
+	server *httptest.Server
+}
+
+// SetupSuite initializes the test environment outside debug mode
+func (s *ErrorSanitizationSuite) SetupSuite() {
+	setupDB(s.T())
+
+	s.T().Setenv("DEBUG", "false")
+	s.T().Setenv("ADMIN_ADDRESS", sanitizeAdmin)
+	handler := graphql.WithAuth(graphql.NewHandler(), callerAuth(sanitizeAdmin))
+	s.server = httptest.NewServer(handler)
+}
+
+// TearDownSuite cleans up the test environment
+func (s *ErrorSanitizationSuite) TearDownSuite() {
+	s.server.Close()
+	db.CloseDB()
+}
+
+// TestUnclassifiedErrorIsSanitized tests that a raw driver error is logged but not returned
+func (s *ErrorSanitizationSuite) TestUnclassifiedErrorIsSanitized() {
+	var logs bytes.Buffer
+	log.SetOutput(&logs)
+	defer log.SetOutput(os.Stderr)
+
+	// The address does not fit in VARCHAR(42), which the driver reports
+	// with the column type in the message. Transfers refuse it up front as
+	// an invalid Address, but the blocklist leaves the check to the table.
+	tooLong := "0x19" + strings.Repeat("0", 60)
+	reqBody, _ := json.Marshal(graphQLRequest{
+		Query:     `mutation($a: String!) { addAddressToBlocklist(address: $a) }`,
+		Variables: map[string]interface{}{"a": tooLong},
+	})
+	req, err := http.NewRequest(http.MethodPost, s.server.URL, bytes.NewBuffer(reqBody))
+	assert.NoError(s.T(), err)
+	req.Header.Set("Content-Type", "application/json")
+	authorize(req, sanitizeAdmin)
+	resp, err := http.DefaultClient.Do(req)
+	assert.NoError(s.T(), err)
+	defer resp.Body.Close()
+
+	var result graphQLResponse
+	assert.NoError(s.T(), json.NewDecoder(resp.Body).Decode(&result))
+	assert.NotNil(s.T(), result.Errors)
+	assert.Equal(s.T(), "internal server error", result.Errors[0]["message"])
+	assert.Equal(s.T(), "INTERNAL", result.Errors[0]["extensions"].(map[string]interface{})["code"])
+
+	assert.Contains(s.T(), logs.String(), "character varying")
+}
+
+// Run the error sanitization test suite
+func TestErrorSanitizationSuite(t *testing.T) {
+	suite.Run(t, new(ErrorSanitizationSuite))
+}
@@ -0,0 +1,101 @@
+package integration
+
+import (
+	"context"
+	"math/big"
+	"testing"
+	"token-transfer-api/internal/db"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/suite"
+)
+
+type FeeSuite struct {
+	suite.Suite
+	sender    string
+	receiver  string
+	feeWallet string
+}
+
+// SetupSuite initializes the database connection
+func (s *FeeSuite) SetupSuite() {
+	setupDB(s.T())
+
+	s.sender = "0xfe00000000000000000000000000000000000001"
+	s.receiver = "0xfe00000000000000000000000000000000000002"
+	s.feeWallet = "0xfe00000000000000000000000000000000000003"
+}
+
+// TearDownSuite closes the database connection
+func (s *FeeSuite) TearDownSuite() {
+	db.CloseDB()
+}
+
+// SetupTest enables fees and resets the wallets
+func (s *FeeSuite) SetupTest() {
+	restoreSettings(s.T())
+	db.Settings = db.Config{FeeFlat: big.NewInt(2), FeeBPS: 100, FeeWallet: s.feeWallet}
+
+	s.createWallet(s.sender, "1000")
+	s.createWallet(s.receiver, "0")
+	s.createWallet(s.feeWallet, "0")
+
+	_, err := db.DB.Exec("DELETE FROM transfers WHERE from_address = $1", s.sender)
+	assert.NoError(s.T(), err)
+}
+
+// createWallet creates a wallet with the specified balance
+func (s *FeeSuite) createWallet(address, balance string) {
+	_, err := db.DB.Exec("INSERT INTO wallets (address, balance) VALUES ($1, $2) ON CONFLICT (address) DO UPDATE SET balance = $2",
+		address, balance)
+	assert.NoError(s.T(), err)
+}
+
+// getBalance gets a wallet's balance
+func (s *FeeSuite) getBalance(address string) string {
+	var balance string
+	err := db.DB.QueryRow("SELECT balance FROM wallets WHERE address = $1", address).Scan(&balance)
+	assert.NoError(s.T(), err)
+	return balance
+}
+
+// TestTransferChargesFee tests that the fee is debited and credited atomically
+func (s *FeeSuite) TestTransferChargesFee() {
+	// 1% of 500 plus a flat 2
+	result, err := db.ExecuteTransfer(context.Background(), s.sender, s.receiver, "500", "", nil, "")
+	assert.NoError(s.T(), err)
+	assert.Equal(s.T(), "7", result.Fee)
+	assert.Equal(s.T(), "493", result.Balance)
+
+	assert.Equal(s.T(), "493", s.getBalance(s.sender))
+	assert.Equal(s.T(), "500", s.getBalance(s.receiver))
+	assert.Equal(s.T(), "7", s.getBalance(s.feeWallet))
+
+	rows, err := db.DB.Query("SELECT to_address, amount FROM transfers WHERE from_address = $1 ORDER BY id", s.sender)
+	assert.NoError(s.T(), err)
+	defer rows.Close()
+
+	var recorded [][2]string
+	for rows.Next() {
+		var to, amount string
+		assert.NoError(s.T(), rows.Scan(&to, &amount))
+		recorded = append(recorded, [2]string{to, amount})
+	}
+	assert.Equal(s.T(), [][2]string{{s.receiver, "500"}, {s.feeWallet, "7"}}, recorded)
+}
+
+// TestInsufficientBalanceForFee tests that the sender must cover amount plus fee
+func (s *FeeSuite) TestInsufficientBalanceForFee() {
+	// 995 + 9 + 2 exceeds the 1000 balance even though the amount alone fits
+	_, err := db.ExecuteTransfer(context.Background(), s.sender, s.receiver, "995", "", nil, "")
+	assert.ErrorIs(s.T(), err, db.ErrInsufficientBalance)
+
+	assert.Equal(s.T(), "1000", s.getBalance(s.sender))
+	assert.Equal(s.T(), "0", s.getBalance(s.receiver))
+	assert.Equal(s.T(), "0", s.getBalance(s.feeWallet))
+}
+
+// Run the fee test suite
+func TestFeeSuite(t *testing.T) {
+	suite.Run(t, new(FeeSuite))
+}
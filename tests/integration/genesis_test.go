@@ -0,0 +1,69 @@
+package integration
+
+import (
+	"context"
+	"math/big"
+	"testing"
+	"token-transfer-api/internal/db"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/suite"
+)
+
+const genesisTreasury = "0x3b00000000000000000000000000000000000001"
+
+type GenesisSuite struct {
+	suite.Suite
+}
+
+// SetupSuite initializes the database connection
+func (s *GenesisSuite) SetupSuite() {
+	setupDB(s.T())
+}
+
+// TearDownSuite closes the database connection
+func (s *GenesisSuite) TearDownSuite() {
+	db.CloseDB()
+}
+
+// TestSeedGenesisTwice tests that seeding again neither changes the balance
+// nor adds supply. It runs in a transaction that is rolled back.
+func (s *GenesisSuite) TestSeedGenesisTwice() {
+	tx, err := db.DB.Begin()
+	assert.NoError(s.T(), err)
+	defer tx.Rollback()
+	ctx := db.WithTx(context.Background(), tx)
+
+	before, err := db.CheckLedgerIntegrityContext(ctx)
+	assert.NoError(s.T(), err)
+
+	assert.NoError(s.T(), db.SeedGenesisContext(ctx, genesisTreasury, "5000"))
+	assert.NoError(s.T(), db.SeedGenesisContext(ctx, genesisTreasury, "5000"))
+	assert.NoError(s.T(), db.SeedGenesisContext(ctx, genesisTreasury, "7000"))
+
+	wallet, err := db.GetWalletContext(ctx, genesisTreasury)
+	assert.NoError(s.T(), err)
+	assert.Equal(s.T(), "5000", wallet.Balance)
+
+	after, err := db.CheckLedgerIntegrityContext(ctx)
+	assert.NoError(s.T(), err)
+	assert.Equal(s.T(), "5000", difference(before.WalletSum, after.WalletSum))
+	assert.Equal(s.T(), "5000", difference(before.Expected, after.Expected))
+}
+
+// TestSeedGenesisInvalid tests that bad input is rejected
+func (s *GenesisSuite) TestSeedGenesisInvalid() {
+	assert.ErrorIs(s.T(), db.SeedGenesis("treasury", "5000"), db.ErrInvalidAddress)
+	assert.ErrorIs(s.T(), db.SeedGenesis(genesisTreasury, "-1"), db.ErrInvalidAmount)
+}
+
+// difference returns to - from for two decimal strings
+func difference(from, to string) string {
+	a, _ := new(big.Int).SetString(from, 10)
+	b, _ := new(big.Int).SetString(to, 10)
+	return new(big.Int).Sub(b, a).String()
+}
+
+func TestGenesisSuite(t *testing.T) {
+	suite.Run(t, new(GenesisSuite))
+}
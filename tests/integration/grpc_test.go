@@ -0,0 +1,115 @@
+package integration
+
+import (
+	"context"
+	"net"
+	"testing"
+	"token-transfer-api/internal/db"
+	"token-transfer-api/internal/graph"
+	"token-transfer-api/pkg/graphql"
+	"token-transfer-api/pkg/grpcserver"
+	"token-transfer-api/pkg/grpcserver/walletpb"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/suite"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/status"
+	"google.golang.org/grpc/test/bufconn"
+)
+
+type GRPCSuite struct {
+	suite.Suite
+	server   *grpc.Server
+	conn     *grpc.ClientConn
+	client   walletpb.WalletServiceClient
+	sender   string
+	receiver string
+}
+
+// SetupSuite initializes the test environment with an in-process gRPC server
+func (s *GRPCSuite) SetupSuite() {
+	setupDB(s.T())
+
+	resolver, err := graph.NewResolver()
+	if err != nil {
+		s.T().Fatalf("Failed to create resolver: %v", err)
+	}
+	server := grpcserver.New(resolver, graphql.AuthConfig{})
+	lis := bufconn.Listen(1 << 20)
+	go server.Serve(lis)
+	s.server = server
+
+	s.conn, err = grpc.NewClient("passthrough:///bufnet",
+		grpc.WithContextDialer(func(ctx context.Context, _ string) (net.Conn, error) { return lis.DialContext(ctx) }),
+		grpc.WithTransportCredentials(insecure.NewCredentials()))
+	if err != nil {
+		s.T().Fatalf("Failed to dial gRPC server: %v", err)
+	}
+	s.client = walletpb.NewWalletServiceClient(s.conn)
+
+	s.sender = "0x2900000000000000000000000000000000000001"
+	s.receiver = "0x2900000000000000000000000000000000000002"
+}
+
+// TearDownSuite cleans up the test environment
+func (s *GRPCSuite) TearDownSuite() {
+	s.conn.Close()
+	s.server.Stop()
+	db.CloseDB()
+}
+
+// SetupTest creates a funded sender
+func (s *GRPCSuite) SetupTest() {
+	s.cleanup()
+	_, err := db.DB.Exec("INSERT INTO wallets (address, balance) VALUES ($1, 1000)", s.sender)
+	assert.NoError(s.T(), err)
+}
+
+// TearDownTest removes the suite's wallets and transfers
+func (s *GRPCSuite) TearDownTest() {
+	s.cleanup()
+}
+
+func (s *GRPCSuite) cleanup() {
+	_, err := db.DB.Exec("DELETE FROM transfers WHERE from_address LIKE '0x29%' OR to_address LIKE '0x29%'")
+	assert.NoError(s.T(), err)
+	_, err = db.DB.Exec("DELETE FROM wallets WHERE address LIKE '0x29%'")
+	assert.NoError(s.T(), err)
+}
+
+// TestTransfer tests a transfer and lookup through the in-process client
+func (s *GRPCSuite) TestTransfer() {
+	resp, err := s.client.Transfer(context.Background(), &walletpb.TransferRequest{
+		FromAddress: s.sender,
+		ToAddress:   s.receiver,
+		Amount:      "300",
+	})
+	assert.NoError(s.T(), err)
+	assert.Equal(s.T(), "700", resp.GetBalance())
+
+	wallet, err := s.client.GetWallet(context.Background(), &walletpb.GetWalletRequest{Address: s.receiver})
+	assert.NoError(s.T(), err)
+	assert.Equal(s.T(), "300", wallet.GetBalance())
+}
+
+// TestErrorCodes tests the gRPC codes of domain errors
+func (s *GRPCSuite) TestErrorCodes() {
+	_, err := s.client.Transfer(context.Background(), &walletpb.TransferRequest{
+		FromAddress: s.sender, ToAddress: s.receiver, Amount: "5000",
+	})
+	assert.Equal(s.T(), codes.FailedPrecondition, status.Code(err))
+
+	_, err = s.client.Transfer(context.Background(), &walletpb.TransferRequest{
+		FromAddress: "0x2900000000000000000000000000000000000009", ToAddress: s.receiver, Amount: "1",
+	})
+	assert.Equal(s.T(), codes.NotFound, status.Code(err))
+
+	_, err = s.client.GetWallet(context.Background(), &walletpb.GetWalletRequest{Address: "0x2900000000000000000000000000000000000009"})
+	assert.Equal(s.T(), codes.NotFound, status.Code(err))
+}
+
+func TestGRPCSuite(t *testing.T) {
+	suite.Run(t, new(GRPCSuite))
+}
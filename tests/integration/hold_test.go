@@ -0,0 +1,264 @@
+package integration
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"token-transfer-api/internal/db"
+	"token-transfer-api/pkg/graphql"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/suite"
+)
+
+const (
+	holdSender   = "0x4900000000000000000000000000000000000001"
+	holdReceiver = "0x4900000000000000000000000000000000000002"
+	holdAdmin    = "0x4900000000000000000000000000000000000003"
+	holdFees     = "0x4900000000000000000000000000000000000004"
+)
+
+type HoldSuite struct {
+	suite.Suite
+	server *httptest.Server
+}
+
+// SetupSuite initializes the database connection and the GraphQL server,
+// with keys acting on behalf of the sender, the receiver and the admin
+func (s *HoldSuite) SetupSuite() {
+	setupDB(s.T())
+
+	s.T().Setenv("ADMIN_ADDRESS", holdAdmin)
+	s.server = httptest.NewServer(graphql.WithAuth(graphql.NewHandler(), callerAuth(holdSender, holdReceiver, holdAdmin)))
+}
+
+// TearDownSuite closes the server and the database connection
+func (s *HoldSuite) TearDownSuite() {
+	s.cleanup()
+	s.server.Close()
+	db.CloseDB()
+}
+
+// SetupTest gives the sender 1000 tokens
+func (s *HoldSuite) SetupTest() {
+	s.cleanup()
+	_, err := db.DB.Exec("INSERT INTO wallets (address, balance) VALUES ($1, 1000)", holdSender)
+	assert.NoError(s.T(), err)
+}
+
+func (s *HoldSuite) cleanup() {
+	_, err := db.DB.Exec("DELETE FROM holds WHERE from_address LIKE '0x49%'")
+	assert.NoError(s.T(), err)
+	_, err = db.DB.Exec("DELETE FROM transfers WHERE from_address LIKE '0x49%' OR to_address LIKE '0x49%'")
+	assert.NoError(s.T(), err)
+	_, err = db.DB.Exec("DELETE FROM wallets WHERE address LIKE '0x49%'")
+	assert.NoError(s.T(), err)
+}
+
+// assertWallet checks a wallet's balance and held tokens
+func (s *HoldSuite) assertWallet(address, balance, held string) {
+	wallet, err := db.GetWallet(address)
+	if assert.NoError(s.T(), err) && assert.NotNil(s.T(), wallet, address) {
+		assert.Equal(s.T(), balance, wallet.Balance, address)
+		assert.Equal(s.T(), held, wallet.HeldBalance, address)
+	}
+}
+
+// execute posts a GraphQL document to the test server on behalf of caller,
+// or of no one when caller is empty
+func (s *HoldSuite) execute(query, caller string) *graphQLResponse {
+	reqBody, _ := json.Marshal(graphQLRequest{Query: query})
+	req, _ := http.NewRequest(http.MethodPost, s.server.URL, bytes.NewBuffer(reqBody))
+	req.Header.Set("Content-Type", "application/json")
+	authorize(req, caller)
+	resp, err := http.DefaultClient.Do(req)
+	if !assert.NoError(s.T(), err) {
+		return &graphQLResponse{}
+	}
+	defer resp.Body.Close()
+
+	var result graphQLResponse
+	assert.NoError(s.T(), json.NewDecoder(resp.Body).Decode(&result))
+	return &result
+}
+
+// TestCreateAndRelease tests that a released hold pays the receiver through a recorded transfer
+func (s *HoldSuite) TestCreateAndRelease() {
+	result := s.execute(`mutation { createHold(from: "`+holdSender+`", amount: "300") { id from_address amount status } }`, holdSender)
+	assert.Nil(s.T(), result.Errors)
+	created := result.Data["createHold"].(map[string]interface{})
+	assert.Equal(s.T(), holdSender, created["from_address"])
+	assert.Equal(s.T(), "300", created["amount"])
+	assert.Equal(s.T(), db.HoldHeld, created["status"])
+	holdID := int64(created["id"].(float64))
+
+	// The held tokens stay in the balance but cannot be spent
+	s.assertWallet(holdSender, "1000", "300")
+	result = s.execute(`{ wallet(address: "`+holdSender+`") { balance held_balance } }`, "")
+	assert.Nil(s.T(), result.Errors)
+	assert.Equal(s.T(), "300", result.Data["wallet"].(map[string]interface{})["held_balance"])
+
+	_, err := db.TransferTokens(holdSender, holdReceiver, "701")
+	assert.ErrorIs(s.T(), err, db.ErrReserveViolation)
+	_, err = db.TransferTokensCTE(holdSender, holdReceiver, "701")
+	assert.ErrorIs(s.T(), err, db.ErrReserveViolation)
+
+	result = s.execute(fmt.Sprintf(`mutation { releaseHold(hold_id: %d, to: "%s") { status to_address transfer_id settled_at } }`, holdID, holdReceiver), holdSender)
+	assert.Nil(s.T(), result.Errors)
+	released := result.Data["releaseHold"].(map[string]interface{})
+	assert.Equal(s.T(), db.HoldReleased, released["status"])
+	assert.Equal(s.T(), holdReceiver, released["to_address"])
+	assert.NotNil(s.T(), released["settled_at"])
+
+	s.assertWallet(holdSender, "700", "0")
+	s.assertWallet(holdReceiver, "300", "0")
+
+	transfer, err := db.GetTransferByID(int64(released["transfer_id"].(float64)))
+	if assert.NoError(s.T(), err) {
+		assert.Equal(s.T(), holdSender, transfer.FromAddress)
+		assert.Equal(s.T(), holdReceiver, transfer.ToAddress)
+		assert.Equal(s.T(), "300", transfer.Amount)
+	}
+}
+
+// TestCreateAndCancel tests that a cancelled hold frees the tokens without moving them
+func (s *HoldSuite) TestCreateAndCancel() {
+	hold, err := db.CreateHold(holdSender, "300")
+	assert.NoError(s.T(), err)
+
+	cancelled, err := db.CancelHold(hold.ID)
+	assert.NoError(s.T(), err)
+	assert.Equal(s.T(), db.HoldCancelled, cancelled.Status)
+	assert.Nil(s.T(), cancelled.ToAddress)
+	assert.Nil(s.T(), cancelled.TransferID)
+	assert.NotNil(s.T(), cancelled.SettledAt)
+
+	s.assertWallet(holdSender, "1000", "0")
+
+	var count int
+	err = db.DB.QueryRow("SELECT COUNT(*) FROM transfers WHERE from_address = $1", holdSender).Scan(&count)
+	assert.NoError(s.T(), err)
+	assert.Zero(s.T(), count)
+
+	_, err = db.TransferTokens(holdSender, holdReceiver, "1000")
+	assert.NoError(s.T(), err)
+}
+
+// TestSettleOnlyOnce tests that a hold cannot be released or cancelled after it was settled
+func (s *HoldSuite) TestSettleOnlyOnce() {
+	hold, err := db.CreateHold(holdSender, "300")
+	assert.NoError(s.T(), err)
+	_, err = db.ReleaseHold(hold.ID, holdReceiver)
+	assert.NoError(s.T(), err)
+
+	_, err = db.ReleaseHold(hold.ID, holdReceiver)
+	assert.ErrorIs(s.T(), err, db.ErrHoldSettled)
+	_, err = db.CancelHold(hold.ID)
+	assert.ErrorIs(s.T(), err, db.ErrHoldSettled)
+
+	s.assertWallet(holdSender, "700", "0")
+	s.assertWallet(holdReceiver, "300", "0")
+
+	cancelled, err := db.CreateHold(holdSender, "100")
+	assert.NoError(s.T(), err)
+	_, err = db.CancelHold(cancelled.ID)
+	assert.NoError(s.T(), err)
+	_, err = db.ReleaseHold(cancelled.ID, holdReceiver)
+	assert.ErrorIs(s.T(), err, db.ErrHoldSettled)
+
+	s.assertWallet(holdSender, "700", "0")
+	s.assertWallet(holdReceiver, "300", "0")
+}
+
+// TestCreateHoldNeedsSpendableBalance tests that a hold cannot take more than the balance left after the reserve and other holds
+func (s *HoldSuite) TestCreateHoldNeedsSpendableBalance() {
+	_, err := db.CreateHold(holdSender, "1001")
+	assert.ErrorIs(s.T(), err, db.ErrInsufficientBalance)
+
+	_, err = db.CreateHold(holdSender, "600")
+	assert.NoError(s.T(), err)
+	_, err = db.CreateHold(holdSender, "401")
+	assert.ErrorIs(s.T(), err, db.ErrReserveViolation)
+
+	_, err = db.SetReserve(holdSender, "401")
+	assert.ErrorIs(s.T(), err, db.ErrReserveExceedsBalance)
+
+	s.assertWallet(holdSender, "1000", "600")
+}
+
+// TestUnknownHold tests that settling a hold that does not exist fails
+func (s *HoldSuite) TestUnknownHold() {
+	_, err := db.ReleaseHold(-1, holdReceiver)
+	assert.ErrorIs(s.T(), err, db.ErrHoldNotFound)
+	_, err = db.CancelHold(-1)
+	assert.ErrorIs(s.T(), err, db.ErrHoldNotFound)
+}
+
+// TestSettleAuthorization tests that only the sender of a hold or the admin
+// can release or cancel it
+func (s *HoldSuite) TestSettleAuthorization() {
+	hold, err := db.CreateHold(holdSender, "300")
+	assert.NoError(s.T(), err)
+
+	for _, caller := range []string{holdReceiver, ""} {
+		result := s.execute(fmt.Sprintf(`mutation { releaseHold(hold_id: %d, to: "%s") { status } }`, hold.ID, holdReceiver), caller)
+		if assert.NotEmpty(s.T(), result.Errors, caller) {
+			assert.Equal(s.T(), "UNAUTHORIZED", result.Errors[0]["extensions"].(map[string]interface{})["code"], caller)
+		}
+		result = s.execute(fmt.Sprintf(`mutation { cancelHold(hold_id: %d) { status } }`, hold.ID), caller)
+		if assert.NotEmpty(s.T(), result.Errors, caller) {
+			assert.Equal(s.T(), "UNAUTHORIZED", result.Errors[0]["extensions"].(map[string]interface{})["code"], caller)
+		}
+	}
+	s.assertWallet(holdSender, "1000", "300")
+
+	result := s.execute(fmt.Sprintf(`mutation { cancelHold(hold_id: %d) { status } }`, hold.ID), holdAdmin)
+	assert.Nil(s.T(), result.Errors)
+	s.assertWallet(holdSender, "1000", "0")
+}
+
+// TestReleaseFollowsTransferRules tests that a release is charged the fee,
+// counts against the daily limit and the maximum amount, initializes a
+// receiver it creates and leaves the sender's nonce alone
+func (s *HoldSuite) TestReleaseFollowsTransferRules() {
+	restoreSettings(s.T())
+	db.Settings.FeeWallet = holdFees
+	db.Settings.FeeFlat = big.NewInt(5)
+	db.Settings.NewWalletGrant = big.NewInt(10)
+
+	hold, err := db.CreateHold(holdSender, "300")
+	assert.NoError(s.T(), err)
+	_, err = db.ReleaseHold(hold.ID, holdReceiver)
+	assert.NoError(s.T(), err)
+
+	s.assertWallet(holdSender, "695", "0")
+	s.assertWallet(holdReceiver, "310", "0")
+	s.assertWallet(holdFees, "5", "0")
+	var nonce int64
+	assert.NoError(s.T(), db.DB.QueryRow("SELECT nonce FROM wallets WHERE address = $1", holdSender).Scan(&nonce))
+	assert.Zero(s.T(), nonce)
+
+	db.Settings.MaxTransferAmount = big.NewInt(200)
+	tooLarge, err := db.CreateHold(holdSender, "201")
+	assert.NoError(s.T(), err)
+	_, err = db.ReleaseHold(tooLarge.ID, holdReceiver)
+	assert.ErrorIs(s.T(), err, db.ErrAmountTooLarge)
+
+	db.Settings.MaxTransferAmount = nil
+	db.Settings.DailyLimit = big.NewInt(400)
+	overLimit, err := db.CreateHold(holdSender, "100")
+	assert.NoError(s.T(), err)
+	_, err = db.ReleaseHold(overLimit.ID, holdReceiver)
+	assert.ErrorIs(s.T(), err, db.ErrDailyLimitExceeded)
+
+	// Both failed holds are still open
+	s.assertWallet(holdSender, "695", "301")
+}
+
+func TestHoldSuite(t *testing.T) {
+	suite.Run(t, new(HoldSuite))
+}
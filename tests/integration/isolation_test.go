@@ -0,0 +1,54 @@
+package integration
+
+import (
+	"context"
+	"database/sql"
+	"testing"
+	"token-transfer-api/internal/db"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/suite"
+)
+
+type IsolationSuite struct {
+	suite.Suite
+}
+
+// SetupSuite initializes the test environment
+func (s *IsolationSuite) SetupSuite() {
+	setupDB(s.T())
+}
+
+// TearDownSuite cleans up the test environment
+func (s *IsolationSuite) TearDownSuite() {
+	db.CloseDB()
+}
+
+// isolationInTx returns the isolation level Postgres reports inside a
+// transaction started with the given configured level.
+func (s *IsolationSuite) isolationInTx(level sql.IsolationLevel) string {
+	saved := db.Settings
+	defer func() { db.Settings = saved }()
+	db.Settings.Isolation = level
+
+	tx, err := db.BeginTx(context.Background())
+	if err != nil {
+		s.T().Fatalf("Failed to begin transaction: %v", err)
+	}
+	defer tx.Rollback()
+
+	var current string
+	assert.NoError(s.T(), tx.QueryRow("SELECT current_setting('transaction_isolation')").Scan(&current))
+	return current
+}
+
+// TestConfiguredIsolationIsUsed tests that transactions run at the configured isolation level
+func (s *IsolationSuite) TestConfiguredIsolationIsUsed() {
+	assert.Equal(s.T(), "repeatable read", s.isolationInTx(db.DefaultIsolation))
+	assert.Equal(s.T(), "read committed", s.isolationInTx(sql.LevelReadCommitted))
+	assert.Equal(s.T(), "serializable", s.isolationInTx(sql.LevelSerializable))
+}
+
+func TestIsolationSuite(t *testing.T) {
+	suite.Run(t, new(IsolationSuite))
+}
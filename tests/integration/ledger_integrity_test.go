@@ -0,0 +1,140 @@
+package integration
+
+import (
+	"bytes"
+	"context"
+	"database/sql"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"token-transfer-api/internal/db"
+	"token-transfer-api/pkg/graphql"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/suite"
+)
+
+const (
+	ledgerAdmin = "0x3600000000000000000000000000000000000000"
+	ledgerAlice = "0x3600000000000000000000000000000000000001"
+	ledgerBob   = "0x3600000000000000000000000000000000000002"
+)
+
+type LedgerIntegritySuite struct {
+	suite.Suite
+	server *httptest.Server
+}
+
+// SetupSuite initializes the test environment with a configured admin
+func (s *LedgerIntegritySuite) SetupSuite() {
+	setupDB(s.T())
+
+	s.T().Setenv("ADMIN_ADDRESS", ledgerAdmin)
+	s.server = httptest.NewServer(graphql.WithAuth(graphql.NewHandler(), callerAuth(ledgerAdmin, ledgerAlice)))
+}
+
+// TearDownSuite cleans up the test environment
+func (s *LedgerIntegritySuite) TearDownSuite() {
+	s.server.Close()
+	db.CloseDB()
+}
+
+// emptyLedger starts a transaction in which every wallet and transfer has
+// been deleted. Other suites leave wallets funded without a mint, so the
+// shared tables are not consistent themselves; the caller rolls back.
+func (s *LedgerIntegritySuite) emptyLedger() (*sql.Tx, context.Context) {
+	tx, err := db.DB.Begin()
+	assert.NoError(s.T(), err)
+
+	_, err = tx.Exec("DELETE FROM transfers")
+	assert.NoError(s.T(), err)
+	_, err = tx.Exec("DELETE FROM wallets")
+	assert.NoError(s.T(), err)
+
+	return tx, db.WithTx(context.Background(), tx)
+}
+
+// TestMintTransferBurnConsistent tests that mints, transfers, refunds and burns keep the ledger consistent
+func (s *LedgerIntegritySuite) TestMintTransferBurnConsistent() {
+	tx, ctx := s.emptyLedger()
+	defer tx.Rollback()
+
+	_, err := db.MintContext(ctx, ledgerAlice, "1000")
+	assert.NoError(s.T(), err)
+	_, err = db.MintContext(ctx, ledgerBob, "50")
+	assert.NoError(s.T(), err)
+
+	_, err = db.ExecuteTransfer(ctx, ledgerAlice, ledgerBob, "300", "", nil, "")
+	assert.NoError(s.T(), err)
+	var transferID int64
+	assert.NoError(s.T(), tx.QueryRow("SELECT MAX(id) FROM transfers").Scan(&transferID))
+	_, err = db.RefundTransferContext(ctx, transferID)
+	assert.NoError(s.T(), err)
+	_, err = db.ExecuteTransfer(ctx, ledgerAlice, ledgerBob, "200", "", nil, "")
+	assert.NoError(s.T(), err)
+
+	_, err = db.BurnContext(ctx, ledgerBob, "120")
+	assert.NoError(s.T(), err)
+
+	integrity, err := db.CheckLedgerIntegrityContext(ctx)
+	assert.NoError(s.T(), err)
+	assert.True(s.T(), integrity.Consistent)
+	assert.Equal(s.T(), "930", integrity.Expected)
+	assert.Equal(s.T(), "930", integrity.WalletSum)
+}
+
+// TestDiscrepancyDetected tests that a balance changed outside the ledger is reported
+func (s *LedgerIntegritySuite) TestDiscrepancyDetected() {
+	tx, ctx := s.emptyLedger()
+	defer tx.Rollback()
+
+	_, err := db.MintContext(ctx, ledgerAlice, "1000")
+	assert.NoError(s.T(), err)
+
+	_, err = tx.Exec("UPDATE wallets SET balance = balance + 1 WHERE address = $1", ledgerAlice)
+	assert.NoError(s.T(), err)
+
+	integrity, err := db.CheckLedgerIntegrityContext(ctx)
+	assert.NoError(s.T(), err)
+	assert.False(s.T(), integrity.Consistent)
+	assert.Equal(s.T(), "1000", integrity.Expected)
+	assert.Equal(s.T(), "1001", integrity.WalletSum)
+}
+
+// execute sends a GraphQL request on behalf of caller
+func (s *LedgerIntegritySuite) execute(query, caller string) *graphQLResponse {
+	reqBody, _ := json.Marshal(graphQLRequest{Query: query})
+	req, err := http.NewRequest(http.MethodPost, s.server.URL, bytes.NewBuffer(reqBody))
+	assert.NoError(s.T(), err)
+	req.Header.Set("Content-Type", "application/json")
+	authorize(req, caller)
+
+	resp, err := http.DefaultClient.Do(req)
+	assert.NoError(s.T(), err)
+	defer resp.Body.Close()
+
+	var result graphQLResponse
+	assert.NoError(s.T(), json.NewDecoder(resp.Body).Decode(&result))
+	return &result
+}
+
+// TestQueryRequiresAdmin tests that only the admin may run the ledgerIntegrity query
+func (s *LedgerIntegritySuite) TestQueryRequiresAdmin() {
+	query := `{ ledgerIntegrity { consistent wallet_sum expected } }`
+
+	result := s.execute(query, ledgerAlice)
+	assert.NotNil(s.T(), result.Errors)
+	assert.Equal(s.T(), "UNAUTHORIZED", result.Errors[0]["extensions"].(map[string]interface{})["code"])
+
+	result = s.execute(query, ledgerAdmin)
+	assert.Nil(s.T(), result.Errors)
+	integrity := result.Data["ledgerIntegrity"].(map[string]interface{})
+	assert.Contains(s.T(), integrity, "consistent")
+	assert.NotEmpty(s.T(), integrity["wallet_sum"])
+	assert.NotEmpty(s.T(), integrity["expected"])
+}
+
+func TestLedgerIntegritySuite(t *testing.T) {
+	suite.Run(t, new(LedgerIntegritySuite))
+}
@@ -0,0 +1,142 @@
+package integration
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"token-transfer-api/internal/db"
+	"token-transfer-api/pkg/graphql"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/suite"
+)
+
+type ListWalletsSuite struct {
+	suite.Suite
+	server *httptest.Server
+}
+
+// SetupSuite initializes the test environment
+func (s *ListWalletsSuite) SetupSuite() {
+	setupDB(s.T())
+
+	// Setup GraphQL handler
+	handler := graphql.NewHandler()
+	s.server = httptest.NewServer(handler)
+}
+
+// TearDownSuite cleans up the test environment
+func (s *ListWalletsSuite) TearDownSuite() {
+	s.server.Close()
+	db.CloseDB()
+}
+
+// SetupTest creates wallets whose balances have different digit counts
+func (s *ListWalletsSuite) SetupTest() {
+	s.createWallet("0x1100000000000000000000000000000000000001", "9")
+	s.createWallet("0x1100000000000000000000000000000000000002", "10")
+	s.createWallet("0x1100000000000000000000000000000000000003", "1000000000000000000000000000000000000000000000000000000000000")
+	s.createWallet("0x1100000000000000000000000000000000000004", "200000000000000000000000000000000000000000000000000000000000")
+}
+
+// createWallet creates a wallet with the specified balance
+func (s *ListWalletsSuite) createWallet(address, balance string) {
+	_, err := db.DB.Exec("INSERT INTO wallets (address, balance) VALUES ($1, $2) ON CONFLICT (address) DO UPDATE SET balance = $2",
+		address, balance)
+	assert.NoError(s.T(), err)
+}
+
+// queryWallets runs the wallets query with the given arguments
+func (s *ListWalletsSuite) queryWallets(args string) (*graphQLResponse, error) {
+	query := fmt.Sprintf(`{ wallets(%s) { address balance } }`, args)
+
+	reqBody, _ := json.Marshal(graphQLRequest{Query: query})
+	resp, err := http.Post(s.server.URL, "application/json", bytes.NewBuffer(reqBody))
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	var result graphQLResponse
+	err = json.NewDecoder(resp.Body).Decode(&result)
+	return &result, err
+}
+
+// TestBalanceDescSortsNumerically tests that the richest wallet comes first regardless of digit count
+func (s *ListWalletsSuite) TestBalanceDescSortsNumerically() {
+	result, err := s.queryWallets("limit: 10, orderBy: BALANCE_DESC")
+	assert.NoError(s.T(), err)
+	assert.Nil(s.T(), result.Errors)
+
+	wallets, ok := result.Data["wallets"].([]interface{})
+	assert.True(s.T(), ok)
+	assert.NotEmpty(s.T(), wallets)
+
+	first := wallets[0].(map[string]interface{})
+	assert.Equal(s.T(), "0x1100000000000000000000000000000000000003", first["address"])
+
+	var previous *big.Int
+	for _, w := range wallets {
+		balance, ok := new(big.Int).SetString(w.(map[string]interface{})["balance"].(string), 10)
+		assert.True(s.T(), ok)
+		if previous != nil {
+			assert.True(s.T(), previous.Cmp(balance) >= 0, "balances must be in descending numeric order")
+		}
+		previous = balance
+	}
+}
+
+// TestBalanceAscSortsNumerically tests that "9" sorts before "10"
+func (s *ListWalletsSuite) TestBalanceAscSortsNumerically() {
+	wallets, err := db.ListWallets(db.MaxWalletPageSize, 0, "BALANCE_ASC")
+	assert.NoError(s.T(), err)
+
+	position := map[string]int{}
+	for i, w := range wallets {
+		position[w.Address] = i
+	}
+
+	nine, hasNine := position["0x1100000000000000000000000000000000000001"]
+	ten, hasTen := position["0x1100000000000000000000000000000000000002"]
+	if hasNine && hasTen {
+		assert.Less(s.T(), nine, ten)
+	}
+}
+
+// TestLimitIsCapped tests that page sizes above the maximum are capped
+func (s *ListWalletsSuite) TestLimitIsCapped() {
+	for i := 0; i < db.MaxWalletPageSize+5; i++ {
+		s.createWallet(fmt.Sprintf("0x12%038x", i), "1")
+	}
+
+	wallets, err := db.ListWallets(db.MaxWalletPageSize*10, 0, "ADDRESS_ASC")
+	assert.NoError(s.T(), err)
+	assert.Len(s.T(), wallets, db.MaxWalletPageSize)
+
+	// Offset pages do not overlap
+	page1, err := db.ListWallets(5, 0, "ADDRESS_ASC")
+	assert.NoError(s.T(), err)
+	page2, err := db.ListWallets(5, 5, "ADDRESS_ASC")
+	assert.NoError(s.T(), err)
+	assert.Len(s.T(), page1, 5)
+	assert.Len(s.T(), page2, 5)
+	assert.True(s.T(), page1[4].Address < page2[0].Address)
+}
+
+// TestInvalidPagination tests that negative values are rejected
+func (s *ListWalletsSuite) TestInvalidPagination() {
+	_, err := db.ListWallets(10, -1, "ADDRESS_ASC")
+	assert.ErrorIs(s.T(), err, db.ErrInvalidPagination)
+
+	_, err = db.ListWallets(10, 0, "balance; DROP TABLE wallets")
+	assert.ErrorIs(s.T(), err, db.ErrInvalidOrder)
+}
+
+// Run the list wallets test suite
+func TestListWalletsSuite(t *testing.T) {
+	suite.Run(t, new(ListWalletsSuite))
+}
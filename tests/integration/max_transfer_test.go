@@ -0,0 +1,82 @@
+package integration
+
+import (
+	"math/big"
+	"testing"
+	"token-transfer-api/internal/db"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/suite"
+)
+
+const (
+	maxTransferSender   = "0x4100000000000000000000000000000000000001"
+	maxTransferReceiver = "0x4100000000000000000000000000000000000002"
+)
+
+type MaxTransferSuite struct {
+	suite.Suite
+}
+
+// SetupSuite initializes the database connection
+func (s *MaxTransferSuite) SetupSuite() {
+	setupDB(s.T())
+	restoreSettings(s.T())
+}
+
+// TearDownSuite closes the database connection
+func (s *MaxTransferSuite) TearDownSuite() {
+	s.cleanup()
+	db.CloseDB()
+}
+
+// SetupTest funds the sender and sets a ceiling of 100
+func (s *MaxTransferSuite) SetupTest() {
+	s.cleanup()
+	_, err := db.DB.Exec("INSERT INTO wallets (address, balance) VALUES ($1, 1000)", maxTransferSender)
+	assert.NoError(s.T(), err)
+
+	restoreSettings(s.T())
+	db.Settings.MaxTransferAmount = big.NewInt(100)
+}
+
+func (s *MaxTransferSuite) cleanup() {
+	_, err := db.DB.Exec("DELETE FROM transfers WHERE from_address LIKE '0x41%' OR to_address LIKE '0x41%'")
+	assert.NoError(s.T(), err)
+	_, err = db.DB.Exec("DELETE FROM wallets WHERE address LIKE '0x41%'")
+	assert.NoError(s.T(), err)
+}
+
+// TestAtLimitSucceeds tests that a transfer of exactly the ceiling goes through
+func (s *MaxTransferSuite) TestAtLimitSucceeds() {
+	balance, err := db.TransferTokens(maxTransferSender, maxTransferReceiver, "100")
+	assert.NoError(s.T(), err)
+	assert.Equal(s.T(), "900", balance)
+}
+
+// TestOverLimitRejected tests that a transfer above the ceiling fails and moves nothing
+func (s *MaxTransferSuite) TestOverLimitRejected() {
+	_, err := db.TransferTokens(maxTransferSender, maxTransferReceiver, "101")
+	assert.ErrorIs(s.T(), err, db.ErrAmountTooLarge)
+
+	wallet, err := db.GetWallet(maxTransferSender)
+	assert.NoError(s.T(), err)
+	assert.Equal(s.T(), "1000", wallet.Balance)
+
+	receiver, err := db.GetWallet(maxTransferReceiver)
+	assert.NoError(s.T(), err)
+	assert.Nil(s.T(), receiver)
+}
+
+// TestUnlimitedWithoutCeiling tests that clearing the ceiling permits any amount
+func (s *MaxTransferSuite) TestUnlimitedWithoutCeiling() {
+	db.Settings.MaxTransferAmount = nil
+
+	_, err := db.TransferTokens(maxTransferSender, maxTransferReceiver, "1000")
+	assert.NoError(s.T(), err)
+}
+
+// Run the max transfer test suite
+func TestMaxTransferSuite(t *testing.T) {
+	suite.Run(t, new(MaxTransferSuite))
+}
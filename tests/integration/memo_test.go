@@ -0,0 +1,135 @@
+package integration
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"token-transfer-api/internal/db"
+	"token-transfer-api/pkg/graphql"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/suite"
+)
+
+const (
+	memoSender   = "0x3300000000000000000000000000000000000001"
+	memoReceiver = "0x3300000000000000000000000000000000000002"
+)
+
+type MemoSuite struct {
+	suite.Suite
+	server *httptest.Server
+}
+
+// SetupSuite initializes the test environment
+func (s *MemoSuite) SetupSuite() {
+	setupDB(s.T())
+
+	s.server = httptest.NewServer(graphql.NewHandler())
+}
+
+// TearDownSuite cleans up the test environment
+func (s *MemoSuite) TearDownSuite() {
+	s.server.Close()
+	db.CloseDB()
+}
+
+// SetupTest funds the sender
+func (s *MemoSuite) SetupTest() {
+	s.cleanup()
+	_, err := db.DB.Exec("INSERT INTO wallets (address, balance) VALUES ($1, 1000)", memoSender)
+	assert.NoError(s.T(), err)
+}
+
+// TearDownTest removes the suite's wallets and transfers
+func (s *MemoSuite) TearDownTest() {
+	s.cleanup()
+}
+
+func (s *MemoSuite) cleanup() {
+	_, err := db.DB.Exec("DELETE FROM transfers WHERE from_address LIKE '0x33%' OR to_address LIKE '0x33%'")
+	assert.NoError(s.T(), err)
+	_, err = db.DB.Exec("DELETE FROM wallets WHERE address LIKE '0x33%'")
+	assert.NoError(s.T(), err)
+}
+
+// graphQL posts a query with variables and returns the decoded response
+func (s *MemoSuite) graphQL(query string, variables map[string]interface{}) *graphQLResponse {
+	reqBody, _ := json.Marshal(graphQLRequest{Query: query, Variables: variables})
+	resp, err := http.Post(s.server.URL, "application/json", bytes.NewBuffer(reqBody))
+	if err != nil {
+		s.T().Fatalf("Request failed: %v", err)
+	}
+	defer resp.Body.Close()
+
+	var result graphQLResponse
+	assert.NoError(s.T(), json.NewDecoder(resp.Body).Decode(&result))
+	return &result
+}
+
+// transferWithMemo sends 100 tokens from memoSender to memoReceiver with memo
+func (s *MemoSuite) transferWithMemo(memo string) *graphQLResponse {
+	return s.graphQL(`mutation($from: Address!, $to: Address!, $memo: String) {
+		transfer(from_address: $from, to_address: $to, amount: "100", memo: $memo) { balance }
+	}`, map[string]interface{}{"from": memoSender, "to": memoReceiver, "memo": memo})
+}
+
+// TestMemoIsReadBack tests that a memo is stored as sent and shown by the transfer queries
+func (s *MemoSuite) TestMemoIsReadBack() {
+	// Stored as sent, surrounding whitespace included
+	memo := "  Invoice #42 – März  "
+	result := s.transferWithMemo(memo)
+	assert.Nil(s.T(), result.Errors)
+
+	var id int64
+	assert.NoError(s.T(), db.DB.QueryRow("SELECT id FROM transfers WHERE from_address = $1", memoSender).Scan(&id))
+
+	result = s.graphQL(`query($id: Int!) { transfer(id: $id) { memo } }`, map[string]interface{}{"id": id})
+	assert.Nil(s.T(), result.Errors)
+	assert.Equal(s.T(), memo, result.Data["transfer"].(map[string]interface{})["memo"])
+
+	transfers, _, err := db.ListTransfers(db.MaxTransferPageSize, 0)
+	assert.NoError(s.T(), err)
+	for _, t := range transfers {
+		if t.ID == id {
+			assert.Equal(s.T(), memo, *t.Memo)
+			return
+		}
+	}
+	s.T().Fatal("transfer missing from history")
+}
+
+// TestNoMemo tests that transfers without a memo report null
+func (s *MemoSuite) TestNoMemo() {
+	result := s.graphQL(`mutation($from: Address!, $to: Address!) {
+		transfer(from_address: $from, to_address: $to, amount: "100") { balance }
+	}`, map[string]interface{}{"from": memoSender, "to": memoReceiver})
+	assert.Nil(s.T(), result.Errors)
+
+	var id int64
+	assert.NoError(s.T(), db.DB.QueryRow("SELECT id FROM transfers WHERE from_address = $1", memoSender).Scan(&id))
+	transfer, err := db.GetTransferByID(id)
+	assert.NoError(s.T(), err)
+	assert.Nil(s.T(), transfer.Memo)
+}
+
+// TestMemoLength tests that memos are limited to MaxMemoLength characters, not bytes
+func (s *MemoSuite) TestMemoLength() {
+	result := s.transferWithMemo(strings.Repeat("ü", db.MaxMemoLength))
+	assert.Nil(s.T(), result.Errors)
+
+	result = s.transferWithMemo(strings.Repeat("a", db.MaxMemoLength+1))
+	assert.NotNil(s.T(), result.Errors)
+	assert.Equal(s.T(), "MEMO_TOO_LONG", result.Errors[0]["extensions"].(map[string]interface{})["code"])
+
+	var balance string
+	assert.NoError(s.T(), db.DB.QueryRow("SELECT balance FROM wallets WHERE address = $1", memoSender).Scan(&balance))
+	assert.Equal(s.T(), "900", balance)
+}
+
+func TestMemoSuite(t *testing.T) {
+	suite.Run(t, new(MemoSuite))
+}
@@ -0,0 +1,127 @@
+package integration
+
+import (
+	"context"
+	"database/sql"
+	"sync"
+	"testing"
+	"token-transfer-api/internal/db"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/suite"
+)
+
+// migrateSchema is the empty schema the migrations are run against, so the
+// test does not touch the tables the other suites use.
+const migrateSchema = "migrate_test"
+
+type MigrateSuite struct {
+	suite.Suite
+	admin *sql.DB
+	pool  *sql.DB
+}
+
+// SetupTest creates an empty schema and a pool whose connections use it
+func (s *MigrateSuite) SetupTest() {
+	loadEnv(s.T())
+
+	params, err := db.ConnParamsFromEnv()
+	if err != nil {
+		s.T().Fatalf("Failed to read connection parameters: %v", err)
+	}
+	dsn := params.DSN()
+
+	s.admin, err = sql.Open("postgres", dsn)
+	if err != nil {
+		s.T().Fatalf("Failed to open database: %v", err)
+	}
+	_, err = s.admin.Exec("DROP SCHEMA IF EXISTS " + migrateSchema + " CASCADE")
+	assert.NoError(s.T(), err)
+	_, err = s.admin.Exec("CREATE SCHEMA " + migrateSchema)
+	assert.NoError(s.T(), err)
+
+	s.pool, err = sql.Open("postgres", dsn+" search_path="+migrateSchema)
+	if err != nil {
+		s.T().Fatalf("Failed to open database: %v", err)
+	}
+}
+
+// TearDownTest drops the schema and closes both pools
+func (s *MigrateSuite) TearDownTest() {
+	s.pool.Close()
+	_, err := s.admin.Exec("DROP SCHEMA IF EXISTS " + migrateSchema + " CASCADE")
+	assert.NoError(s.T(), err)
+	s.admin.Close()
+}
+
+func (s *MigrateSuite) appliedVersions() []int {
+	rows, err := s.pool.Query("SELECT version FROM schema_migrations ORDER BY version")
+	assert.NoError(s.T(), err)
+	defer rows.Close()
+
+	var versions []int
+	for rows.Next() {
+		var version int
+		assert.NoError(s.T(), rows.Scan(&version))
+		versions = append(versions, version)
+	}
+	return versions
+}
+
+// TestMigrateCleanDatabase tests that the migrations build the schema from nothing
+func (s *MigrateSuite) TestMigrateCleanDatabase() {
+	err := db.Migrate(context.Background(), s.pool)
+	assert.NoError(s.T(), err)
+	assert.Equal(s.T(), []int{1, 2, 3, 4, 5, 6, 7, 8, 9, 10, 11, 12}, s.appliedVersions())
+
+	for _, table := range []string{"wallets", "transfers", "blocked_addresses", "scheduled_transfers", "transfer_events", "token_balances"} {
+		var exists bool
+		err := s.pool.QueryRow("SELECT to_regclass($1) IS NOT NULL", migrateSchema+"."+table).Scan(&exists)
+		assert.NoError(s.T(), err)
+		assert.True(s.T(), exists, table)
+	}
+
+	var dataType string
+	var precision, scale int
+	err = s.pool.QueryRow(`SELECT data_type, numeric_precision, numeric_scale FROM information_schema.columns
+		WHERE table_schema = $1 AND table_name = 'wallets' AND column_name = 'balance'`, migrateSchema).Scan(&dataType, &precision, &scale)
+	assert.NoError(s.T(), err)
+	assert.Equal(s.T(), "numeric", dataType)
+	assert.Equal(s.T(), 78, precision)
+	assert.Equal(s.T(), 0, scale)
+}
+
+// TestMigrateIsIdempotent tests that a second run applies nothing
+func (s *MigrateSuite) TestMigrateIsIdempotent() {
+	assert.NoError(s.T(), db.Migrate(context.Background(), s.pool))
+	assert.NoError(s.T(), db.Migrate(context.Background(), s.pool))
+	assert.Equal(s.T(), []int{1, 2, 3, 4, 5, 6}, s.appliedVersions())
+
+	var wallets int
+	assert.NoError(s.T(), s.pool.QueryRow("SELECT COUNT(*) FROM wallets").Scan(&wallets))
+	assert.Equal(s.T(), 0, wallets)
+}
+
+// TestConcurrentMigrate tests that servers starting together apply each migration once
+func (s *MigrateSuite) TestConcurrentMigrate() {
+	var wg sync.WaitGroup
+	errs := make(chan error, 5)
+	for i := 0; i < 5; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			errs <- db.Migrate(context.Background(), s.pool)
+		}()
+	}
+	wg.Wait()
+	close(errs)
+
+	for err := range errs {
+		assert.NoError(s.T(), err)
+	}
+	assert.Equal(s.T(), []int{1, 2, 3, 4, 5, 6}, s.appliedVersions())
+}
+
+func TestMigrateSuite(t *testing.T) {
+	suite.Run(t, new(MigrateSuite))
+}
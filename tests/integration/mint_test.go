@@ -0,0 +1,222 @@
+package integration
+
+import (
+	"bytes"
+	"encoding/json"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"token-transfer-api/internal/db"
+	"token-transfer-api/pkg/graphql"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/suite"
+)
+
+const (
+	mintMinter   = "0x1500000000000000000000000000000000000001"
+	mintReceiver = "0x1500000000000000000000000000000000000002"
+	mintOther    = "0x1500000000000000000000000000000000000003"
+)
+
+type MintSuite struct {
+	suite.Suite
+	server *httptest.Server
+}
+
+// SetupSuite initializes the test environment with a configured minter
+func (s *MintSuite) SetupSuite() {
+	setupDB(s.T())
+
+	s.T().Setenv("MINTER_ADDRESS", mintMinter)
+	handler := graphql.WithAuth(graphql.NewHandler(), callerAuth(mintMinter, mintOther))
+	s.server = httptest.NewServer(handler)
+}
+
+// TearDownSuite cleans up the test environment
+func (s *MintSuite) TearDownSuite() {
+	s.server.Close()
+	db.CloseDB()
+}
+
+// SetupTest removes the receiver and its history so each test starts without it
+func (s *MintSuite) SetupTest() {
+	_, err := db.DB.Exec("DELETE FROM transfers WHERE from_address = $1 OR to_address = $1", mintReceiver)
+	assert.NoError(s.T(), err)
+	_, err = db.DB.Exec("DELETE FROM wallets WHERE address = $1", mintReceiver)
+	assert.NoError(s.T(), err)
+}
+
+// mint executes the mint mutation as the given caller
+func (s *MintSuite) mint(caller, amount string) (*graphQLResponse, error) {
+	reqBody, _ := json.Marshal(graphQLRequest{
+		Query: `mutation($to: Address!, $amount: Amount!) { mint(to_address: $to, amount: $amount) { address balance } }`,
+		Variables: map[string]interface{}{
+			"to":     mintReceiver,
+			"amount": amount,
+		},
+	})
+
+	req, err := http.NewRequest(http.MethodPost, s.server.URL, bytes.NewBuffer(reqBody))
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	authorize(req, caller)
+
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	var result graphQLResponse
+	err = json.NewDecoder(resp.Body).Decode(&result)
+	return &result, err
+}
+
+// TestAuthorizedMint tests that the minter can create tokens in a new wallet
+func (s *MintSuite) TestAuthorizedMint() {
+	result, err := s.mint(mintMinter, "500")
+	assert.NoError(s.T(), err)
+	assert.Nil(s.T(), result.Errors)
+
+	wallet := result.Data["mint"].(map[string]interface{})
+	assert.Equal(s.T(), mintReceiver, wallet["address"])
+	assert.Equal(s.T(), "500", wallet["balance"])
+
+	result, err = s.mint(mintMinter, "250")
+	assert.NoError(s.T(), err)
+	assert.Nil(s.T(), result.Errors)
+	assert.Equal(s.T(), "750", result.Data["mint"].(map[string]interface{})["balance"])
+
+	var count int
+	err = db.DB.QueryRow("SELECT COUNT(*) FROM transfers WHERE from_address = $1 AND to_address = $2",
+		db.ZeroAddress, mintReceiver).Scan(&count)
+	assert.NoError(s.T(), err)
+	assert.GreaterOrEqual(s.T(), count, 2)
+}
+
+// TestUnauthorizedMint tests that other callers cannot mint
+func (s *MintSuite) TestUnauthorizedMint() {
+	for _, caller := range []string{"", mintOther} {
+		result, err := s.mint(caller, "500")
+		assert.NoError(s.T(), err)
+		assert.NotNil(s.T(), result.Errors)
+		assert.Equal(s.T(), db.ErrUnauthorized.Message, result.Errors[0]["message"])
+		assert.Equal(s.T(), "UNAUTHORIZED", result.Errors[0]["extensions"].(map[string]interface{})["code"])
+	}
+
+	wallet, err := db.GetWallet(mintReceiver)
+	assert.NoError(s.T(), err)
+	assert.Nil(s.T(), wallet)
+}
+
+// TestForgedCallerHeaderRejected tests that naming the minter in a request header does not authorize a mint
+func (s *MintSuite) TestForgedCallerHeaderRejected() {
+	reqBody, _ := json.Marshal(graphQLRequest{
+		Query:     `mutation($to: Address!) { mint(to_address: $to, amount: "500") { balance } }`,
+		Variables: map[string]interface{}{"to": mintReceiver},
+	})
+	req, err := http.NewRequest(http.MethodPost, s.server.URL, bytes.NewBuffer(reqBody))
+	assert.NoError(s.T(), err)
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("X-Caller-Address", mintMinter)
+	authorize(req, mintOther)
+
+	resp, err := http.DefaultClient.Do(req)
+	if !assert.NoError(s.T(), err) {
+		return
+	}
+	defer resp.Body.Close()
+
+	var result graphQLResponse
+	assert.NoError(s.T(), json.NewDecoder(resp.Body).Decode(&result))
+	if assert.NotEmpty(s.T(), result.Errors) {
+		assert.Equal(s.T(), "UNAUTHORIZED", result.Errors[0]["extensions"].(map[string]interface{})["code"])
+	}
+
+	wallet, err := db.GetWallet(mintReceiver)
+	assert.NoError(s.T(), err)
+	assert.Nil(s.T(), wallet)
+}
+
+// TestMintInvalidAmount tests that non-positive amounts are rejected
+func (s *MintSuite) TestMintInvalidAmount() {
+	result, err := s.mint(mintMinter, "0")
+	assert.NoError(s.T(), err)
+	assert.NotNil(s.T(), result.Errors)
+	assert.Equal(s.T(), "invalid amount", result.Errors[0]["message"])
+}
+
+// burn executes the burn mutation as the minter
+func (s *MintSuite) burn(amount string) (*graphQLResponse, error) {
+	reqBody, _ := json.Marshal(graphQLRequest{
+		Query: `mutation($from: Address!, $amount: Amount!) { burn(from_address: $from, amount: $amount) }`,
+		Variables: map[string]interface{}{
+			"from":   mintReceiver,
+			"amount": amount,
+		},
+	})
+
+	req, err := http.NewRequest(http.MethodPost, s.server.URL, bytes.NewBuffer(reqBody))
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	authorize(req, mintMinter)
+
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	var result graphQLResponse
+	err = json.NewDecoder(resp.Body).Decode(&result)
+	return &result, err
+}
+
+// TestBurnReducesSupply tests that burning lowers both the wallet balance and the total supply
+func (s *MintSuite) TestBurnReducesSupply() {
+	result, err := s.mint(mintMinter, "1000")
+	assert.NoError(s.T(), err)
+	assert.Nil(s.T(), result.Errors)
+
+	supplyBefore, err := db.GetTotalSupply()
+	assert.NoError(s.T(), err)
+
+	result, err = s.burn("400")
+	assert.NoError(s.T(), err)
+	assert.Nil(s.T(), result.Errors)
+	assert.Equal(s.T(), "600", result.Data["burn"])
+
+	supplyAfter, err := db.GetTotalSupply()
+	assert.NoError(s.T(), err)
+
+	before, _ := new(big.Int).SetString(supplyBefore, 10)
+	after, _ := new(big.Int).SetString(supplyAfter, 10)
+	assert.Equal(s.T(), "400", new(big.Int).Sub(before, after).String())
+}
+
+// TestBurnInsufficientBalance tests that a wallet cannot burn more than it holds
+func (s *MintSuite) TestBurnInsufficientBalance() {
+	result, err := s.mint(mintMinter, "100")
+	assert.NoError(s.T(), err)
+	assert.Nil(s.T(), result.Errors)
+
+	result, err = s.burn("101")
+	assert.NoError(s.T(), err)
+	assert.NotNil(s.T(), result.Errors)
+	assert.Equal(s.T(), "insufficient balance", result.Errors[0]["message"])
+
+	wallet, err := db.GetWallet(mintReceiver)
+	assert.NoError(s.T(), err)
+	assert.Equal(s.T(), "100", wallet.Balance)
+}
+
+// Run the mint test suite
+func TestMintSuite(t *testing.T) {
+	suite.Run(t, new(MintSuite))
+}
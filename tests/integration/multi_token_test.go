@@ -0,0 +1,172 @@
+package integration
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"token-transfer-api/internal/db"
+	"token-transfer-api/pkg/graphql"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/suite"
+)
+
+const (
+	tokenWalletA = "0x5400000000000000000000000000000000000001"
+	tokenWalletB = "0x5400000000000000000000000000000000000002"
+)
+
+type MultiTokenSuite struct {
+	suite.Suite
+	server *httptest.Server
+}
+
+// SetupSuite initializes the database connection and the GraphQL server
+func (s *MultiTokenSuite) SetupSuite() {
+	setupDB(s.T())
+	restoreSettings(s.T())
+
+	s.T().Setenv("TOKEN_DECIMALS", "0")
+	s.server = httptest.NewServer(graphql.NewHandler())
+}
+
+// TearDownSuite closes the server and the database connection
+func (s *MultiTokenSuite) TearDownSuite() {
+	s.cleanup()
+	s.server.Close()
+	db.CloseDB()
+}
+
+// SetupTest configures USDC and GOLD next to the primary token, without
+// fees, and gives wallet A 1000 of the primary token and 500 USDC and
+// wallet B 50 of the primary token and 7 GOLD
+func (s *MultiTokenSuite) SetupTest() {
+	restoreSettings(s.T())
+	db.Settings.FeeWallet = ""
+	db.Settings.Tokens = map[string]bool{"USDC": true, "GOLD": true}
+
+	s.cleanup()
+	_, err := db.DB.Exec("INSERT INTO wallets (address, balance) VALUES ($1, 1000), ($2, 50)", tokenWalletA, tokenWalletB)
+	assert.NoError(s.T(), err)
+	_, err = db.MintToken("USDC", tokenWalletA, "500")
+	assert.NoError(s.T(), err)
+	_, err = db.MintToken("GOLD", tokenWalletB, "7")
+	assert.NoError(s.T(), err)
+}
+
+// cleanup removes the suite's transfers and wallets; their token balances go with the wallets
+func (s *MultiTokenSuite) cleanup() {
+	_, err := db.DB.Exec("DELETE FROM transfers WHERE from_address LIKE '0x54%' OR to_address LIKE '0x54%'")
+	assert.NoError(s.T(), err)
+	_, err = db.DB.Exec("DELETE FROM wallets WHERE address LIKE '0x54%'")
+	assert.NoError(s.T(), err)
+}
+
+// balances returns the balance of address in the primary token, USDC and GOLD
+func (s *MultiTokenSuite) balances(address string) [3]string {
+	var out [3]string
+	for i, token := range []string{"", "USDC", "GOLD"} {
+		wallet, err := db.GetTokenWallet(address, token)
+		if assert.NoError(s.T(), err) && assert.NotNil(s.T(), wallet) {
+			out[i] = wallet.Balance
+		}
+	}
+	return out
+}
+
+// TestTokensMoveIndependently tests that transfers of different tokens between the same pair leave each other's balances alone
+func (s *MultiTokenSuite) TestTokensMoveIndependently() {
+	balance, err := db.TransferToken("USDC", tokenWalletA, tokenWalletB, "200")
+	assert.NoError(s.T(), err)
+	assert.Equal(s.T(), "300", balance)
+
+	balance, err = db.TransferToken("GOLD", tokenWalletB, tokenWalletA, "3")
+	assert.NoError(s.T(), err)
+	assert.Equal(s.T(), "4", balance)
+
+	balance, err = db.TransferTokens(tokenWalletA, tokenWalletB, "100")
+	assert.NoError(s.T(), err)
+	assert.Equal(s.T(), "900", balance)
+
+	assert.Equal(s.T(), [3]string{"900", "300", "3"}, s.balances(tokenWalletA))
+	assert.Equal(s.T(), [3]string{"150", "200", "4"}, s.balances(tokenWalletB))
+
+	// A balance of one token does not cover a transfer of another
+	_, err = db.TransferToken("GOLD", tokenWalletB, tokenWalletA, "5")
+	assert.ErrorIs(s.T(), err, db.ErrInsufficientBalance)
+	assert.Equal(s.T(), [3]string{"150", "200", "4"}, s.balances(tokenWalletB))
+
+	// Totals of the primary token ignore the other tokens
+	stats, err := db.GetWalletStats(tokenWalletA)
+	assert.NoError(s.T(), err)
+	assert.Equal(s.T(), "100", stats.TotalSent)
+	assert.Equal(s.T(), int64(1), stats.TransferCountOut)
+}
+
+// TestTokenTransferMutation tests that the token argument of the transfer mutation and the wallet query picks the token
+func (s *MultiTokenSuite) TestTokenTransferMutation() {
+	reqBody, _ := json.Marshal(graphQLRequest{Query: `mutation {
+		usdc: transfer(from_address: "` + tokenWalletA + `", to_address: "` + tokenWalletB + `", amount: "120", token: "USDC") { balance fee }
+		primary: transfer(from_address: "` + tokenWalletA + `", to_address: "` + tokenWalletB + `", amount: "10") { balance }
+	}`})
+	resp, err := http.Post(s.server.URL, "application/json", bytes.NewBuffer(reqBody))
+	if !assert.NoError(s.T(), err) {
+		return
+	}
+	var result graphQLResponse
+	assert.NoError(s.T(), json.NewDecoder(resp.Body).Decode(&result))
+	resp.Body.Close()
+	assert.Nil(s.T(), result.Errors)
+	assert.Equal(s.T(), "380", result.Data["usdc"].(map[string]interface{})["balance"])
+	assert.Equal(s.T(), "0", result.Data["usdc"].(map[string]interface{})["fee"])
+	assert.Equal(s.T(), "990", result.Data["primary"].(map[string]interface{})["balance"])
+
+	reqBody, _ = json.Marshal(graphQLRequest{Query: `{
+		usdc: wallet(address: "` + tokenWalletB + `", token: "USDC") { balance }
+		primary: wallet(address: "` + tokenWalletB + `") { balance }
+	}`})
+	resp, err = http.Post(s.server.URL, "application/json", bytes.NewBuffer(reqBody))
+	if !assert.NoError(s.T(), err) {
+		return
+	}
+	result = graphQLResponse{}
+	assert.NoError(s.T(), json.NewDecoder(resp.Body).Decode(&result))
+	resp.Body.Close()
+	assert.Nil(s.T(), result.Errors)
+	assert.Equal(s.T(), "120", result.Data["usdc"].(map[string]interface{})["balance"])
+	assert.Equal(s.T(), "60", result.Data["primary"].(map[string]interface{})["balance"])
+}
+
+// TestUnknownToken tests that a token that is not configured is rejected
+func (s *MultiTokenSuite) TestUnknownToken() {
+	_, err := db.TransferToken("DOGE", tokenWalletA, tokenWalletB, "1")
+	assert.ErrorIs(s.T(), err, db.ErrUnknownToken)
+
+	_, err = db.GetTokenWallet(tokenWalletA, "DOGE")
+	assert.ErrorIs(s.T(), err, db.ErrUnknownToken)
+}
+
+// TestRefundRejectsOtherTokens tests that only transfers of the primary token can be refunded
+func (s *MultiTokenSuite) TestRefundRejectsOtherTokens() {
+	_, err := db.TransferToken("USDC", tokenWalletA, tokenWalletB, "200")
+	assert.NoError(s.T(), err)
+
+	var id int64
+	err = db.DB.QueryRow("SELECT id FROM transfers WHERE from_address = $1 AND token = 'USDC'", tokenWalletA).Scan(&id)
+	assert.NoError(s.T(), err)
+
+	transfer, err := db.GetTransferByID(id)
+	if assert.NoError(s.T(), err) {
+		assert.Equal(s.T(), "USDC", transfer.Token)
+	}
+
+	_, err = db.RefundTransfer(id)
+	assert.ErrorIs(s.T(), err, db.ErrUnsupportedToken)
+	assert.Equal(s.T(), [3]string{"1000", "300", "0"}, s.balances(tokenWalletA))
+}
+
+func TestMultiTokenSuite(t *testing.T) {
+	suite.Run(t, new(MultiTokenSuite))
+}
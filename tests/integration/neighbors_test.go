@@ -0,0 +1,122 @@
+package integration
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"token-transfer-api/internal/db"
+	"token-transfer-api/pkg/graphql"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/suite"
+)
+
+const (
+	neighborCenter = "0x1300000000000000000000000000000000000000"
+	neighborA      = "0x1300000000000000000000000000000000000001"
+	neighborB      = "0x1300000000000000000000000000000000000002"
+	neighborC      = "0x1300000000000000000000000000000000000003"
+	neighborFar    = "0x1300000000000000000000000000000000000004"
+)
+
+type NeighborsSuite struct {
+	suite.Suite
+	server *httptest.Server
+}
+
+// SetupSuite initializes the test environment
+func (s *NeighborsSuite) SetupSuite() {
+	setupDB(s.T())
+
+	// Setup GraphQL handler
+	handler := graphql.NewHandler()
+	s.server = httptest.NewServer(handler)
+}
+
+// TearDownSuite cleans up the test environment
+func (s *NeighborsSuite) TearDownSuite() {
+	s.server.Close()
+	db.CloseDB()
+}
+
+// SetupTest seeds a small transfer graph around neighborCenter
+func (s *NeighborsSuite) SetupTest() {
+	_, err := db.DB.Exec("DELETE FROM transfers WHERE from_address LIKE '0x13%' OR to_address LIKE '0x13%'")
+	assert.NoError(s.T(), err)
+
+	for _, address := range []string{neighborCenter, neighborA, neighborB, neighborC, neighborFar} {
+		_, err = db.DB.Exec("INSERT INTO wallets (address, balance) VALUES ($1, 0) ON CONFLICT (address) DO NOTHING", address)
+		assert.NoError(s.T(), err)
+	}
+
+	s.recordTransfer(neighborCenter, neighborA, "100")
+	s.recordTransfer(neighborA, neighborCenter, "50")
+	s.recordTransfer(neighborCenter, neighborB, "10")
+	s.recordTransfer(neighborC, neighborCenter, "1000000000000000000000000000000")
+	s.recordTransfer(neighborCenter, neighborCenter, "7")
+	// Not connected to neighborCenter
+	s.recordTransfer(neighborA, neighborFar, "999")
+}
+
+// recordTransfer inserts a transfer row without touching balances
+func (s *NeighborsSuite) recordTransfer(from, to, amount string) {
+	_, err := db.DB.Exec("INSERT INTO transfers (from_address, to_address, amount) VALUES ($1, $2, $3)", from, to, amount)
+	assert.NoError(s.T(), err)
+}
+
+// TestNeighborTotals tests the neighbor set and the per-neighbor totals
+func (s *NeighborsSuite) TestNeighborTotals() {
+	neighbors, err := db.GetNeighbors(neighborCenter, 0)
+	assert.NoError(s.T(), err)
+
+	totals := map[string]string{}
+	for _, n := range neighbors {
+		totals[n.Address] = n.Total
+	}
+
+	assert.Equal(s.T(), map[string]string{
+		neighborA: "150",
+		neighborB: "10",
+		neighborC: "1000000000000000000000000000000",
+	}, totals)
+
+	// Largest total first
+	assert.Equal(s.T(), neighborC, neighbors[0].Address)
+}
+
+// TestNeighborsQuery tests the neighbors query over HTTP with a limit
+func (s *NeighborsSuite) TestNeighborsQuery() {
+	reqBody, _ := json.Marshal(graphQLRequest{
+		Query: `query($address: String!) { neighbors(address: $address, limit: 2) { address total } }`,
+		Variables: map[string]interface{}{
+			"address": neighborCenter,
+		},
+	})
+	resp, err := http.Post(s.server.URL, "application/json", bytes.NewBuffer(reqBody))
+	assert.NoError(s.T(), err)
+	defer resp.Body.Close()
+
+	var result graphQLResponse
+	assert.NoError(s.T(), json.NewDecoder(resp.Body).Decode(&result))
+	assert.Nil(s.T(), result.Errors)
+
+	neighbors := result.Data["neighbors"].([]interface{})
+	assert.Len(s.T(), neighbors, 2)
+	assert.Equal(s.T(), neighborC, neighbors[0].(map[string]interface{})["address"])
+	assert.Equal(s.T(), neighborA, neighbors[1].(map[string]interface{})["address"])
+	assert.Equal(s.T(), "150", neighbors[1].(map[string]interface{})["total"])
+}
+
+// TestNoNeighbors tests that an address without transfers has no neighbors
+func (s *NeighborsSuite) TestNoNeighbors() {
+	neighbors, err := db.GetNeighbors("0x1300000000000000000000000000000000000099", 0)
+	assert.NoError(s.T(), err)
+	assert.Empty(s.T(), neighbors)
+}
+
+// Run the neighbors test suite
+func TestNeighborsSuite(t *testing.T) {
+	suite.Run(t, new(NeighborsSuite))
+}
@@ -0,0 +1,153 @@
+package integration
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"token-transfer-api/internal/db"
+	"token-transfer-api/pkg/graphql"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/suite"
+)
+
+const (
+	nonceSender   = "0x4a00000000000000000000000000000000000001"
+	nonceReceiver = "0x4a00000000000000000000000000000000000002"
+)
+
+type NonceSuite struct {
+	suite.Suite
+	server *httptest.Server
+}
+
+// SetupSuite initializes the database connection and the GraphQL server
+func (s *NonceSuite) SetupSuite() {
+	setupDB(s.T())
+
+	s.server = httptest.NewServer(graphql.NewHandler())
+}
+
+// TearDownSuite closes the server and the database connection
+func (s *NonceSuite) TearDownSuite() {
+	s.cleanup()
+	s.server.Close()
+	db.CloseDB()
+}
+
+// SetupTest funds the sender
+func (s *NonceSuite) SetupTest() {
+	s.cleanup()
+	_, err := db.DB.Exec("INSERT INTO wallets (address, balance) VALUES ($1, 1000)", nonceSender)
+	assert.NoError(s.T(), err)
+}
+
+func (s *NonceSuite) cleanup() {
+	_, err := db.DB.Exec("DELETE FROM transfers WHERE from_address LIKE '0x4a%' OR to_address LIKE '0x4a%'")
+	assert.NoError(s.T(), err)
+	_, err = db.DB.Exec("DELETE FROM wallets WHERE address LIKE '0x4a%'")
+	assert.NoError(s.T(), err)
+}
+
+func (s *NonceSuite) nonce(address string) int64 {
+	wallet, err := db.GetWallet(address)
+	if !assert.NoError(s.T(), err) || !assert.NotNil(s.T(), wallet) {
+		return -1
+	}
+	return wallet.Nonce
+}
+
+// transfer sends 100 tokens through the transfer mutation pinned to the given nonce
+func (s *NonceSuite) transfer(expectedNonce int) *graphQLResponse {
+	query := fmt.Sprintf(`mutation { transfer(from_address: "%s", to_address: "%s", amount: "100", expected_nonce: %d) { balance } }`,
+		nonceSender, nonceReceiver, expectedNonce)
+	reqBody, _ := json.Marshal(graphQLRequest{Query: query})
+	resp, err := http.Post(s.server.URL, "application/json", bytes.NewBuffer(reqBody))
+	if !assert.NoError(s.T(), err) {
+		return &graphQLResponse{}
+	}
+	defer resp.Body.Close()
+
+	var result graphQLResponse
+	assert.NoError(s.T(), json.NewDecoder(resp.Body).Decode(&result))
+	return &result
+}
+
+// TestExpectedNonce tests that a stale expected_nonce is rejected and the current one succeeds and increments it
+func (s *NonceSuite) TestExpectedNonce() {
+	assert.Equal(s.T(), int64(0), s.nonce(nonceSender))
+
+	result := s.transfer(0)
+	assert.Nil(s.T(), result.Errors)
+	assert.Equal(s.T(), "900", result.Data["transfer"].(map[string]interface{})["balance"])
+	assert.Equal(s.T(), int64(1), s.nonce(nonceSender))
+
+	// Replaying the same transfer is refused and changes nothing
+	result = s.transfer(0)
+	if assert.NotEmpty(s.T(), result.Errors) {
+		extensions := result.Errors[0]["extensions"].(map[string]interface{})
+		assert.Equal(s.T(), "NONCE_MISMATCH", extensions["code"])
+		assert.Equal(s.T(), float64(1), extensions["nonce"])
+	}
+	assert.Equal(s.T(), int64(1), s.nonce(nonceSender))
+
+	wallet, err := db.GetWallet(nonceSender)
+	assert.NoError(s.T(), err)
+	assert.Equal(s.T(), "900", wallet.Balance)
+
+	result = s.transfer(1)
+	assert.Nil(s.T(), result.Errors)
+	assert.Equal(s.T(), int64(2), s.nonce(nonceSender))
+
+	// The receiver has sent nothing
+	assert.Equal(s.T(), int64(0), s.nonce(nonceReceiver))
+}
+
+// TestNonceWithoutExpectation tests that transfers without expected_nonce still increment the nonce
+func (s *NonceSuite) TestNonceWithoutExpectation() {
+	_, err := db.TransferTokens(nonceSender, nonceReceiver, "1")
+	assert.NoError(s.T(), err)
+	_, err = db.TransferTokensCTE(nonceSender, nonceReceiver, "1")
+	assert.NoError(s.T(), err)
+	assert.Equal(s.T(), int64(2), s.nonce(nonceSender))
+
+	// A failed transfer does not use up a nonce
+	_, err = db.TransferTokens(nonceSender, nonceReceiver, "5000")
+	assert.ErrorIs(s.T(), err, db.ErrInsufficientBalance)
+	assert.Equal(s.T(), int64(2), s.nonce(nonceSender))
+}
+
+// TestDryRunReportsNonceMismatch tests that a dry run reports a stale nonce without incrementing it
+func (s *NonceSuite) TestDryRunReportsNonceMismatch() {
+	stale := int64(3)
+	result, err := db.SimulateTransfer(context.Background(), nonceSender, nonceReceiver, "100", "", &stale, "")
+	assert.NoError(s.T(), err)
+	assert.False(s.T(), result.WouldSucceed)
+	assert.Equal(s.T(), db.ErrNonceMismatch.Code, result.FailureCode)
+
+	current := int64(0)
+	result, err = db.SimulateTransfer(context.Background(), nonceSender, nonceReceiver, "100", "", &current, "")
+	assert.NoError(s.T(), err)
+	assert.True(s.T(), result.WouldSucceed)
+	assert.Equal(s.T(), int64(0), s.nonce(nonceSender))
+}
+
+// TestBurnKeepsNonce tests that burning from a wallet leaves its nonce
+// alone, so a transfer its owner signed before still goes through
+func (s *NonceSuite) TestBurnKeepsNonce() {
+	_, err := db.Burn(nonceSender, "50")
+	assert.NoError(s.T(), err)
+	assert.Equal(s.T(), int64(0), s.nonce(nonceSender))
+
+	result := s.transfer(0)
+	assert.Nil(s.T(), result.Errors)
+	assert.Equal(s.T(), int64(1), s.nonce(nonceSender))
+}
+
+func TestNonceSuite(t *testing.T) {
+	suite.Run(t, new(NonceSuite))
+}
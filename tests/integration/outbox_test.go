@@ -0,0 +1,182 @@
+package integration
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"sync"
+	"testing"
+	"token-transfer-api/internal/db"
+	"token-transfer-api/internal/model"
+	"token-transfer-api/pkg/outbox"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/suite"
+)
+
+const (
+	outboxSender   = "0x3e00000000000000000000000000000000000001"
+	outboxReceiver = "0x3e00000000000000000000000000000000000002"
+)
+
+type OutboxSuite struct {
+	suite.Suite
+
+	mu       sync.Mutex
+	received map[string]int
+	payloads map[string][]byte
+	failNext bool
+	webhook  *httptest.Server
+	client   *http.Client
+}
+
+// SetupSuite initializes the database connection and the receiving webhook
+func (s *OutboxSuite) SetupSuite() {
+	setupDB(s.T())
+
+	s.webhook = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		s.mu.Lock()
+		defer s.mu.Unlock()
+		if s.failNext {
+			s.failNext = false
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+		body, _ := io.ReadAll(r.Body)
+		id := r.Header.Get(outbox.EventIDHeader)
+		s.received[id]++
+		s.payloads[id] = body
+	}))
+	s.client = s.webhook.Client()
+}
+
+// TearDownSuite closes the webhook and the database connection
+func (s *OutboxSuite) TearDownSuite() {
+	s.webhook.Close()
+	db.CloseDB()
+}
+
+// SetupTest turns on events and funds the sender
+func (s *OutboxSuite) SetupTest() {
+	restoreSettings(s.T())
+	db.Settings.TransferEvents = true
+	db.Settings.FeeWallet = ""
+	s.received = map[string]int{}
+	s.payloads = map[string][]byte{}
+	s.failNext = false
+	s.cleanup()
+
+	_, err := db.DB.Exec("INSERT INTO wallets (address, balance) VALUES ($1, 1000)", outboxSender)
+	assert.NoError(s.T(), err)
+}
+
+// TearDownTest removes the suite's rows
+func (s *OutboxSuite) TearDownTest() {
+	s.cleanup()
+}
+
+func (s *OutboxSuite) cleanup() {
+	_, err := db.DB.Exec("DELETE FROM transfer_events WHERE transfer_id IN (SELECT id FROM transfers WHERE from_address LIKE '0x3e%' OR to_address LIKE '0x3e%')")
+	assert.NoError(s.T(), err)
+	_, err = db.DB.Exec("DELETE FROM transfers WHERE from_address LIKE '0x3e%' OR to_address LIKE '0x3e%'")
+	assert.NoError(s.T(), err)
+	_, err = db.DB.Exec("DELETE FROM wallets WHERE address LIKE '0x3e%'")
+	assert.NoError(s.T(), err)
+}
+
+func (s *OutboxSuite) transferID() int64 {
+	var id int64
+	err := db.DB.QueryRow("SELECT id FROM transfers WHERE from_address = $1", outboxSender).Scan(&id)
+	assert.NoError(s.T(), err)
+	return id
+}
+
+// drain publishes every due event through the test webhook
+func (s *OutboxSuite) drain() {
+	for {
+		event, err := db.PublishNextEvent(context.Background(), func(ctx context.Context, event *model.TransferEvent) error {
+			return outbox.Deliver(ctx, s.client, s.webhook.URL, event)
+		})
+		assert.NoError(s.T(), err)
+		if event == nil {
+			return
+		}
+	}
+}
+
+// TestEventWrittenWithTransfer tests that the event commits or rolls back together with its transfer
+func (s *OutboxSuite) TestEventWrittenWithTransfer() {
+	tx, err := db.DB.Begin()
+	assert.NoError(s.T(), err)
+	_, err = db.TransferTokensContext(db.WithTx(context.Background(), tx), outboxSender, outboxReceiver, "100")
+	assert.NoError(s.T(), err)
+
+	var events int
+	assert.NoError(s.T(), tx.QueryRow("SELECT COUNT(*) FROM transfer_events e JOIN transfers t ON t.id = e.transfer_id WHERE t.from_address = $1", outboxSender).Scan(&events))
+	assert.Equal(s.T(), 1, events)
+	assert.NoError(s.T(), tx.Rollback())
+
+	assert.NoError(s.T(), db.DB.QueryRow("SELECT COUNT(*) FROM transfer_events e JOIN transfers t ON t.id = e.transfer_id WHERE t.from_address = $1", outboxSender).Scan(&events))
+	assert.Equal(s.T(), 0, events)
+
+	// A rejected transfer writes no event either
+	_, err = db.TransferTokens(outboxSender, outboxReceiver, "5000")
+	assert.ErrorIs(s.T(), err, db.ErrInsufficientBalance)
+
+	_, err = db.TransferTokens(outboxSender, outboxReceiver, "100")
+	assert.NoError(s.T(), err)
+
+	stored, err := db.GetTransferEvents(s.transferID())
+	assert.NoError(s.T(), err)
+	assert.Len(s.T(), stored, 1)
+	assert.Nil(s.T(), stored[0].PublishedAt)
+
+	var payload map[string]interface{}
+	assert.NoError(s.T(), json.Unmarshal(stored[0].Payload, &payload))
+	assert.Equal(s.T(), outboxSender, payload["from_address"])
+	assert.Equal(s.T(), outboxReceiver, payload["to_address"])
+	assert.Equal(s.T(), "100", payload["amount"])
+	assert.Equal(s.T(), "0", payload["fee"])
+}
+
+// TestEventDeliveredOnce tests that a failed delivery is retried and a published event is not sent again
+func (s *OutboxSuite) TestEventDeliveredOnce() {
+	_, err := db.TransferTokens(outboxSender, outboxReceiver, "100")
+	assert.NoError(s.T(), err)
+	transferID := s.transferID()
+
+	s.failNext = true
+	s.drain()
+
+	stored, err := db.GetTransferEvents(transferID)
+	assert.NoError(s.T(), err)
+	assert.Len(s.T(), stored, 1)
+	event := stored[0]
+	id := strconv.FormatInt(event.ID, 10)
+	assert.Nil(s.T(), event.PublishedAt)
+	assert.Equal(s.T(), 1, event.Attempts)
+	assert.NotNil(s.T(), event.LastError)
+	assert.Zero(s.T(), s.received[id])
+
+	// Skip the backoff
+	_, err = db.DB.Exec("UPDATE transfer_events SET next_attempt_at = NOW() WHERE id = $1", event.ID)
+	assert.NoError(s.T(), err)
+	s.drain()
+	s.drain()
+
+	assert.Equal(s.T(), 1, s.received[id])
+	assert.JSONEq(s.T(), string(event.Payload), string(s.payloads[id]))
+
+	stored, err = db.GetTransferEvents(transferID)
+	assert.NoError(s.T(), err)
+	assert.NotNil(s.T(), stored[0].PublishedAt)
+	assert.Equal(s.T(), 2, stored[0].Attempts)
+	assert.Nil(s.T(), stored[0].LastError)
+}
+
+func TestOutboxSuite(t *testing.T) {
+	suite.Run(t, new(OutboxSuite))
+}
@@ -13,7 +13,6 @@ import (
 	"token-transfer-api/internal/db"
 	"token-transfer-api/pkg/graphql"
 
-	"github.com/joho/godotenv"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/suite"
 )
@@ -25,13 +24,7 @@ type RaceConditionSuite struct {
 
 // SetupSuite initializes the test environment
 func (s *RaceConditionSuite) SetupSuite() {
-	if err := godotenv.Load("../../.env"); err != nil {
-		s.T().Logf("No .env file found")
-	}
-
-	if err := db.InitDB(); err != nil {
-		s.T().Fatalf("Failed to initialize database: %v", err)
-	}
+	setupDB(s.T())
 
 	// Setup GraphQL handler
 	handler := graphql.NewHandler()
@@ -0,0 +1,163 @@
+package integration
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"token-transfer-api/internal/db"
+	"token-transfer-api/pkg/graphql"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/suite"
+)
+
+const (
+	rateFlooder   = "0x2000000000000000000000000000000000000001"
+	rateBystander = "0x2000000000000000000000000000000000000002"
+	rateReceiver  = "0x2000000000000000000000000000000000000003"
+	rateProber    = "0x2000000000000000000000000000000000000004"
+	rateScheduler = "0x2000000000000000000000000000000000000005"
+)
+
+type RateLimitSuite struct {
+	suite.Suite
+	server *httptest.Server
+}
+
+// SetupSuite initializes the test environment with a low transfer rate limit
+func (s *RateLimitSuite) SetupSuite() {
+	setupDB(s.T())
+
+	s.T().Setenv("TRANSFER_RATE_LIMIT", "3")
+	handler := graphql.NewHandler()
+	s.server = httptest.NewServer(handler)
+}
+
+// TearDownSuite cleans up the test environment
+func (s *RateLimitSuite) TearDownSuite() {
+	s.server.Close()
+	db.CloseDB()
+}
+
+// createWallet creates a wallet with the specified balance
+func (s *RateLimitSuite) createWallet(address, balance string) {
+	_, err := db.DB.Exec("INSERT INTO wallets (address, balance) VALUES ($1, $2) ON CONFLICT (address) DO UPDATE SET balance = $2",
+		address, balance)
+	assert.NoError(s.T(), err)
+}
+
+// transfer sends one token from the given sender to the receiver
+func (s *RateLimitSuite) transfer(from string) *graphQLResponse {
+	reqBody, _ := json.Marshal(graphQLRequest{
+		Query: `mutation($from: Address!, $to: Address!) { transfer(from_address: $from, to_address: $to, amount: "1") { balance } }`,
+		Variables: map[string]interface{}{
+			"from": from,
+			"to":   rateReceiver,
+		},
+	})
+	resp, err := http.Post(s.server.URL, "application/json", bytes.NewBuffer(reqBody))
+	assert.NoError(s.T(), err)
+	defer resp.Body.Close()
+
+	var result graphQLResponse
+	assert.NoError(s.T(), json.NewDecoder(resp.Body).Decode(&result))
+	return &result
+}
+
+// execute posts a GraphQL document to the test server
+func (s *RateLimitSuite) execute(query string, variables map[string]interface{}) *graphQLResponse {
+	reqBody, _ := json.Marshal(graphQLRequest{Query: query, Variables: variables})
+	resp, err := http.Post(s.server.URL, "application/json", bytes.NewBuffer(reqBody))
+	assert.NoError(s.T(), err)
+	defer resp.Body.Close()
+
+	var result graphQLResponse
+	assert.NoError(s.T(), json.NewDecoder(resp.Body).Decode(&result))
+	return &result
+}
+
+// TestFloodingSenderIsLimited tests that one sender is cut off without affecting another
+func (s *RateLimitSuite) TestFloodingSenderIsLimited() {
+	s.createWallet(rateFlooder, "1000")
+	s.createWallet(rateBystander, "1000")
+
+	var rejected []*graphQLResponse
+	for i := 0; i < 10; i++ {
+		result := s.transfer(rateFlooder)
+		if i < 3 {
+			assert.Nil(s.T(), result.Errors, "transfer %d should be allowed", i)
+		} else if assert.NotNil(s.T(), result.Errors, "transfer %d should be limited", i) {
+			rejected = append(rejected, result)
+		}
+	}
+
+	for _, result := range rejected {
+		extensions := result.Errors[0]["extensions"].(map[string]interface{})
+		assert.Equal(s.T(), "RATE_LIMITED", extensions["code"])
+		assert.Greater(s.T(), extensions["retry_after"], float64(0))
+	}
+
+	// Only the allowed transfers moved funds
+	wallet, err := db.GetWallet(rateFlooder)
+	assert.NoError(s.T(), err)
+	assert.Equal(s.T(), "997", wallet.Balance)
+
+	result := s.transfer(rateBystander)
+	assert.Nil(s.T(), result.Errors)
+}
+
+// TestOnlyRealTransfersAreLimited tests that dry runs and transfers failing
+// validation do not use up the sender's rate limit
+func (s *RateLimitSuite) TestOnlyRealTransfersAreLimited() {
+	s.createWallet(rateProber, "1000")
+	vars := map[string]interface{}{"from": rateProber, "to": rateReceiver}
+
+	for i := 0; i < 5; i++ {
+		result := s.execute(`mutation($from: Address!, $to: Address!) { transfer(from_address: $from, to_address: $to, amount: "1", dry_run: true) { failure_code } }`, vars)
+		assert.Nil(s.T(), result.Errors)
+
+		result = s.execute(`mutation($from: Address!, $to: Address!) { transfer(from_address: $from, to_address: $to, amount: "0") { balance } }`, vars)
+		if assert.NotNil(s.T(), result.Errors) {
+			assert.Equal(s.T(), "INVALID_AMOUNT", result.Errors[0]["extensions"].(map[string]interface{})["code"])
+		}
+
+		result = s.execute(`mutation($from: Address!, $to: Address!) { transfer(from_address: $from, to_address: $to, amount: "1", expected_nonce: 0, signature: "0xdead") { balance } }`, vars)
+		if assert.NotNil(s.T(), result.Errors) {
+			assert.Equal(s.T(), "BAD_SIGNATURE", result.Errors[0]["extensions"].(map[string]interface{})["code"])
+		}
+	}
+
+	for i := 0; i < 3; i++ {
+		assert.Nil(s.T(), s.transfer(rateProber).Errors, "transfer %d should be allowed", i)
+	}
+}
+
+// TestInvalidSchedulesAndSwapsAreNotLimited tests that scheduled transfers
+// and swaps failing validation do not use up the rate limit either
+func (s *RateLimitSuite) TestInvalidSchedulesAndSwapsAreNotLimited() {
+	s.createWallet(rateScheduler, "1000")
+	vars := map[string]interface{}{"from": rateScheduler, "to": rateReceiver}
+
+	for i := 0; i < 5; i++ {
+		result := s.execute(`mutation($from: Address!) { scheduleTransfer(from_address: $from, to_address: $from, amount: "1", execute_at: "2030-01-01T00:00:00Z") { id } }`, vars)
+		if assert.NotNil(s.T(), result.Errors) {
+			assert.Equal(s.T(), "SELF_TRANSFER", result.Errors[0]["extensions"].(map[string]interface{})["code"])
+		}
+
+		result = s.execute(`mutation($from: Address!, $to: Address!) { swap(wallet_a: $from, wallet_b: $to, amount_a: "1", amount_b: "0") { __typename } }`, vars)
+		if assert.NotNil(s.T(), result.Errors) {
+			assert.Equal(s.T(), "INVALID_AMOUNT", result.Errors[0]["extensions"].(map[string]interface{})["code"])
+		}
+	}
+
+	for i := 0; i < 3; i++ {
+		assert.Nil(s.T(), s.transfer(rateScheduler).Errors, "transfer %d should be allowed", i)
+	}
+}
+
+// Run the rate limit test suite
+func TestRateLimitSuite(t *testing.T) {
+	suite.Run(t, new(RateLimitSuite))
+}
@@ -0,0 +1,132 @@
+package integration
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"token-transfer-api/internal/db"
+	"token-transfer-api/internal/graph"
+	"token-transfer-api/pkg/graphql"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/suite"
+)
+
+const (
+	readOnlyAdmin   = "0x5800000000000000000000000000000000000009"
+	readOnlyWalletA = "0x5800000000000000000000000000000000000001"
+	readOnlyWalletB = "0x5800000000000000000000000000000000000002"
+)
+
+type ReadOnlySuite struct {
+	suite.Suite
+	server *httptest.Server
+}
+
+// SetupSuite initializes the database connection and the GraphQL server
+func (s *ReadOnlySuite) SetupSuite() {
+	setupDB(s.T())
+	restoreSettings(s.T())
+
+	s.T().Setenv("TOKEN_DECIMALS", "0")
+	s.T().Setenv("ADMIN_ADDRESS", readOnlyAdmin)
+	s.T().Setenv("READ_ONLY", "false")
+	s.server = httptest.NewServer(graphql.WithAuth(graphql.NewHandler(), callerAuth(readOnlyAdmin, readOnlyWalletA)))
+}
+
+// TearDownSuite leaves read-only mode and closes the server and the database connection
+func (s *ReadOnlySuite) TearDownSuite() {
+	graph.SetReadOnly(false)
+	s.cleanup()
+	s.server.Close()
+	db.CloseDB()
+}
+
+// SetupTest gives wallet A 1000 tokens, without fees, with read-only mode off
+func (s *ReadOnlySuite) SetupTest() {
+	graph.SetReadOnly(false)
+	restoreSettings(s.T())
+	db.Settings.FeeWallet = ""
+
+	s.cleanup()
+	_, err := db.DB.Exec("INSERT INTO wallets (address, balance) VALUES ($1, 1000)", readOnlyWalletA)
+	assert.NoError(s.T(), err)
+}
+
+// cleanup removes the suite's transfers and wallets
+func (s *ReadOnlySuite) cleanup() {
+	_, err := db.DB.Exec("DELETE FROM transfers WHERE from_address LIKE '0x58%' OR to_address LIKE '0x58%'")
+	assert.NoError(s.T(), err)
+	_, err = db.DB.Exec("DELETE FROM wallets WHERE address LIKE '0x58%'")
+	assert.NoError(s.T(), err)
+}
+
+// query runs a GraphQL operation on behalf of caller and returns the response
+func (s *ReadOnlySuite) query(caller, query string) *graphQLResponse {
+	reqBody, _ := json.Marshal(graphQLRequest{Query: query})
+	req, _ := http.NewRequest(http.MethodPost, s.server.URL, bytes.NewBuffer(reqBody))
+	req.Header.Set("Content-Type", "application/json")
+	authorize(req, caller)
+	resp, err := http.DefaultClient.Do(req)
+	if !assert.NoError(s.T(), err) {
+		return &graphQLResponse{}
+	}
+	defer resp.Body.Close()
+
+	var result graphQLResponse
+	assert.NoError(s.T(), json.NewDecoder(resp.Body).Decode(&result))
+	return &result
+}
+
+// transfer sends 100 tokens from wallet A to wallet B
+func (s *ReadOnlySuite) transfer() *graphQLResponse {
+	return s.query("", `mutation { transfer(from_address: "`+readOnlyWalletA+`", to_address: "`+readOnlyWalletB+`", amount: "100") { balance } }`)
+}
+
+// TestReadOnlyToggle tests that read-only mode rejects transfers while the wallet query keeps answering
+func (s *ReadOnlySuite) TestReadOnlyToggle() {
+	result := s.query(readOnlyAdmin, `mutation { setReadOnly(enabled: true) }`)
+	assert.Nil(s.T(), result.Errors)
+	assert.Equal(s.T(), true, result.Data["setReadOnly"])
+
+	result = s.query("", `{ readOnly }`)
+	assert.Nil(s.T(), result.Errors)
+	assert.Equal(s.T(), true, result.Data["readOnly"])
+
+	result = s.transfer()
+	if assert.NotEmpty(s.T(), result.Errors) {
+		assert.Equal(s.T(), "READ_ONLY", result.Errors[0]["extensions"].(map[string]interface{})["code"])
+	}
+
+	result = s.query("", `{ wallet(address: "`+readOnlyWalletA+`") { balance } }`)
+	assert.Nil(s.T(), result.Errors)
+	assert.Equal(s.T(), "1000", result.Data["wallet"].(map[string]interface{})["balance"])
+
+	var transfers int
+	err := db.DB.QueryRow("SELECT COUNT(*) FROM transfers WHERE from_address = $1", readOnlyWalletA).Scan(&transfers)
+	assert.NoError(s.T(), err)
+	assert.Equal(s.T(), 0, transfers)
+
+	result = s.query(readOnlyAdmin, `mutation { setReadOnly(enabled: false) }`)
+	assert.Nil(s.T(), result.Errors)
+
+	result = s.transfer()
+	assert.Nil(s.T(), result.Errors)
+	assert.Equal(s.T(), "900", result.Data["transfer"].(map[string]interface{})["balance"])
+}
+
+// TestSetReadOnlyRequiresAdmin tests that other callers cannot switch read-only mode
+func (s *ReadOnlySuite) TestSetReadOnlyRequiresAdmin() {
+	result := s.query(readOnlyWalletA, `mutation { setReadOnly(enabled: true) }`)
+	if assert.NotEmpty(s.T(), result.Errors) {
+		assert.Equal(s.T(), "UNAUTHORIZED", result.Errors[0]["extensions"].(map[string]interface{})["code"])
+	}
+	assert.False(s.T(), graph.ReadOnly())
+}
+
+// Run the read-only test suite
+func TestReadOnlySuite(t *testing.T) {
+	suite.Run(t, new(ReadOnlySuite))
+}
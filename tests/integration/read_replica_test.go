@@ -0,0 +1,182 @@
+package integration
+
+import (
+	"context"
+	"database/sql"
+	"net"
+	"net/url"
+	"testing"
+	"time"
+	"token-transfer-api/internal/db"
+
+	"github.com/lib/pq"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/suite"
+)
+
+// replicaDatabase stands in for the read replica. It is a separate database
+// on the same server that is never written by the API, so a wallet holding
+// a different balance there shows which pool a read went to.
+const replicaDatabase = "token_transfer_read"
+
+const replicaWallet = "0x4200000000000000000000000000000000000001"
+
+type ReadReplicaSuite struct {
+	suite.Suite
+	replica *sql.DB
+}
+
+// SetupSuite creates and migrates the replica database and opens the pools
+// with DATABASE_READ_URL pointing at it
+func (s *ReadReplicaSuite) SetupSuite() {
+	loadEnv(s.T())
+
+	params, err := db.ConnParamsFromEnv()
+	if err != nil {
+		s.T().Fatalf("Failed to read connection parameters: %v", err)
+	}
+	admin, err := sql.Open("postgres", params.DSN())
+	if err != nil {
+		s.T().Fatalf("Failed to open database: %v", err)
+	}
+	defer admin.Close()
+	if _, err := admin.Exec("CREATE DATABASE " + replicaDatabase); err != nil {
+		if pqErr, ok := err.(*pq.Error); !ok || pqErr.Code != "42P04" {
+			s.T().Fatalf("Failed to create replica database: %v", err)
+		}
+	}
+
+	readParams := params
+	readParams.DBName = replicaDatabase
+	s.replica, err = sql.Open("postgres", readParams.DSN())
+	if err != nil {
+		s.T().Fatalf("Failed to open replica database: %v", err)
+	}
+	if err := db.Migrate(context.Background(), s.replica); err != nil {
+		s.T().Fatalf("Failed to migrate replica database: %v", err)
+	}
+
+	s.T().Setenv("DATABASE_READ_URL", replicaURL(params))
+	if err := db.InitDB(); err != nil {
+		s.T().Fatalf("Failed to initialize database: %v", err)
+	}
+	restoreSettings(s.T())
+}
+
+// replicaURL renders params as a DATABASE_READ_URL for replicaDatabase
+func replicaURL(params db.ConnParams) string {
+	u := url.URL{Scheme: "postgres", Host: params.Host, Path: "/" + replicaDatabase}
+	if params.Port != "" {
+		u.Host = net.JoinHostPort(params.Host, params.Port)
+	}
+	if params.User != "" {
+		u.User = url.UserPassword(params.User, params.Password)
+	}
+	if params.SSLMode != "" {
+		u.RawQuery = url.Values{"sslmode": {params.SSLMode}}.Encode()
+	}
+	return u.String()
+}
+
+// TearDownSuite closes the pools; DATABASE_READ_URL is unset again once the suite ends
+func (s *ReadReplicaSuite) TearDownSuite() {
+	s.cleanup()
+	db.CloseDB()
+	s.replica.Close()
+}
+
+// SetupTest gives replicaWallet 5 tokens on the primary and 7 on the replica
+func (s *ReadReplicaSuite) SetupTest() {
+	s.cleanup()
+	restoreSettings(s.T())
+	db.Settings.ReadAfterWrite = 0
+
+	_, err := db.DB.Exec("INSERT INTO wallets (address, balance) VALUES ($1, 5)", replicaWallet)
+	assert.NoError(s.T(), err)
+	_, err = s.replica.Exec("INSERT INTO wallets (address, balance) VALUES ($1, 7)", replicaWallet)
+	assert.NoError(s.T(), err)
+}
+
+func (s *ReadReplicaSuite) cleanup() {
+	_, err := db.DB.Exec("DELETE FROM wallets WHERE address LIKE '0x42%'")
+	assert.NoError(s.T(), err)
+	_, err = s.replica.Exec("DELETE FROM wallets WHERE address LIKE '0x42%'")
+	assert.NoError(s.T(), err)
+}
+
+// balance returns replicaWallet's balance as GetWallet reads it
+func (s *ReadReplicaSuite) balance(ctx context.Context) string {
+	wallet, err := db.GetWalletContext(ctx, replicaWallet)
+	assert.NoError(s.T(), err)
+	if wallet == nil {
+		return ""
+	}
+	return wallet.Balance
+}
+
+// TestReadsUseReplica tests that wallet, stats and history reads go to the read pool
+func (s *ReadReplicaSuite) TestReadsUseReplica() {
+	assert.NotSame(s.T(), db.DB, db.DBRead)
+	assert.Equal(s.T(), "7", s.balance(context.Background()))
+
+	wallets, err := db.GetWallets([]string{replicaWallet})
+	assert.NoError(s.T(), err)
+	if assert.Len(s.T(), wallets, 1) {
+		assert.Equal(s.T(), "7", wallets[0].Balance)
+	}
+
+	// The replica database has no transfers at all
+	transfers, _, err := db.ListTransfers(0, 0)
+	assert.NoError(s.T(), err)
+	assert.Empty(s.T(), transfers)
+
+	// replicaWallet was never active, so it is listed among the dormant ones
+	dormant, err := db.ListDormantWallets(time.Now(), db.MaxWalletPageSize, 0)
+	assert.NoError(s.T(), err)
+	balances := map[string]string{}
+	for _, wallet := range dormant {
+		balances[wallet.Address] = wallet.Balance
+	}
+	assert.Equal(s.T(), "7", balances[replicaWallet])
+}
+
+// TestWritesUsePrimary tests that mutations change the primary only
+func (s *ReadReplicaSuite) TestWritesUsePrimary() {
+	balance, err := db.CreditWallet(replicaWallet, "1")
+	assert.NoError(s.T(), err)
+	assert.Equal(s.T(), "6", balance)
+
+	var replicaBalance string
+	err = s.replica.QueryRow("SELECT balance FROM wallets WHERE address = $1", replicaWallet).Scan(&replicaBalance)
+	assert.NoError(s.T(), err)
+	assert.Equal(s.T(), "7", replicaBalance)
+}
+
+// TestReadAfterWriteUsesPrimary tests that reads right after a write see it on the primary
+func (s *ReadReplicaSuite) TestReadAfterWriteUsesPrimary() {
+	db.Settings.ReadAfterWrite = time.Minute
+
+	_, err := db.CreditWallet(replicaWallet, "1")
+	assert.NoError(s.T(), err)
+	assert.Equal(s.T(), "6", s.balance(context.Background()))
+
+	// Once the window has passed the replica serves reads again
+	db.Settings.ReadAfterWrite = 0
+	assert.Equal(s.T(), "7", s.balance(context.Background()))
+}
+
+// TestTransactionsReadPrimary tests that reads inside a caller's transaction stay in it
+func (s *ReadReplicaSuite) TestTransactionsReadPrimary() {
+	tx, err := db.BeginTx(context.Background())
+	if err != nil {
+		s.T().Fatalf("Failed to begin transaction: %v", err)
+	}
+	defer tx.Rollback()
+
+	assert.Equal(s.T(), "5", s.balance(db.WithTx(context.Background(), tx)))
+}
+
+// Run the read replica test suite
+func TestReadReplicaSuite(t *testing.T) {
+	suite.Run(t, new(ReadReplicaSuite))
+}
@@ -0,0 +1,201 @@
+package integration
+
+import (
+	"context"
+	"math/big"
+	"testing"
+	"token-transfer-api/internal/db"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/suite"
+)
+
+const (
+	policySender   = "0x5100000000000000000000000000000000000001"
+	policyReceiver = "0x5100000000000000000000000000000000000002"
+	policyNew      = "0x5100000000000000000000000000000000000003"
+)
+
+type ReceiverPolicySuite struct {
+	suite.Suite
+}
+
+// SetupSuite initializes the database connection
+func (s *ReceiverPolicySuite) SetupSuite() {
+	setupDB(s.T())
+	restoreSettings(s.T())
+}
+
+// TearDownSuite closes the database connection
+func (s *ReceiverPolicySuite) TearDownSuite() {
+	s.cleanup()
+	db.CloseDB()
+}
+
+// SetupTest funds the sender, creates an empty receiver and requires receivers to exist
+func (s *ReceiverPolicySuite) SetupTest() {
+	restoreSettings(s.T())
+	db.Settings.FeeWallet = ""
+	db.Settings.ReceiverMustExist = true
+
+	s.cleanup()
+	_, err := db.DB.Exec("INSERT INTO wallets (address, balance) VALUES ($1, 1000), ($2, 0)", policySender, policyReceiver)
+	assert.NoError(s.T(), err)
+}
+
+func (s *ReceiverPolicySuite) cleanup() {
+	_, err := db.DB.Exec("DELETE FROM holds WHERE from_address LIKE '0x51%'")
+	assert.NoError(s.T(), err)
+	_, err = db.DB.Exec("DELETE FROM transfers WHERE from_address LIKE '0x51%' OR to_address LIKE '0x51%'")
+	assert.NoError(s.T(), err)
+	_, err = db.DB.Exec("DELETE FROM wallets WHERE address LIKE '0x51%'")
+	assert.NoError(s.T(), err)
+}
+
+func (s *ReceiverPolicySuite) assertNoWallet(address string) {
+	wallet, err := db.GetWallet(address)
+	assert.NoError(s.T(), err)
+	assert.Nil(s.T(), wallet)
+}
+
+func (s *ReceiverPolicySuite) balance(address string) string {
+	wallet, err := db.GetWallet(address)
+	if !assert.NoError(s.T(), err) || !assert.NotNil(s.T(), wallet) {
+		return ""
+	}
+	return wallet.Balance
+}
+
+// TestUnknownReceiverRejected tests that transfers to an address without a wallet fail and create nothing
+func (s *ReceiverPolicySuite) TestUnknownReceiverRejected() {
+	_, err := db.TransferTokens(policySender, policyNew, "100")
+	assert.ErrorIs(s.T(), err, db.ErrReceiverNotFound)
+
+	_, err = db.TransferTokensCTE(policySender, policyNew, "100")
+	assert.ErrorIs(s.T(), err, db.ErrReceiverNotFound)
+
+	result, err := db.SimulateTransfer(context.Background(), policySender, policyNew, "100", "", nil, "")
+	assert.NoError(s.T(), err)
+	assert.False(s.T(), result.WouldSucceed)
+	assert.Equal(s.T(), db.ErrReceiverNotFound.Code, result.FailureCode)
+
+	s.assertNoWallet(policyNew)
+	assert.Equal(s.T(), "1000", s.balance(policySender))
+}
+
+// TestExistingReceiverCredited tests that receivers that exist are paid as usual
+func (s *ReceiverPolicySuite) TestExistingReceiverCredited() {
+	_, err := db.TransferTokens(policySender, policyReceiver, "100")
+	assert.NoError(s.T(), err)
+	assert.Equal(s.T(), "100", s.balance(policyReceiver))
+}
+
+// TestHoldReleaseToUnknownReceiver tests that a hold cannot be released to an address without a wallet
+func (s *ReceiverPolicySuite) TestHoldReleaseToUnknownReceiver() {
+	hold, err := db.CreateHold(policySender, "100")
+	if !assert.NoError(s.T(), err) {
+		return
+	}
+
+	_, err = db.ReleaseHold(hold.ID, policyNew)
+	assert.ErrorIs(s.T(), err, db.ErrReceiverNotFound)
+	s.assertNoWallet(policyNew)
+}
+
+// TestMintCreatesWallet tests that mints still create the receiving wallet
+func (s *ReceiverPolicySuite) TestMintCreatesWallet() {
+	wallet, err := db.Mint(policyNew, "50")
+	assert.NoError(s.T(), err)
+	if assert.NotNil(s.T(), wallet) {
+		assert.Equal(s.T(), "50", wallet.Balance)
+	}
+}
+
+// TestDefaultCreatesReceiver tests that by default transfers create the receiver
+func (s *ReceiverPolicySuite) TestDefaultCreatesReceiver() {
+	db.Settings.ReceiverMustExist = false
+
+	_, err := db.TransferTokens(policySender, policyNew, "100")
+	assert.NoError(s.T(), err)
+	assert.Equal(s.T(), "100", s.balance(policyNew))
+}
+
+// TestNewWalletGrant tests that a receiver wallet a transfer creates starts with the amount plus the grant, recorded as a mint
+func (s *ReceiverPolicySuite) TestNewWalletGrant() {
+	db.Settings.ReceiverMustExist = false
+	db.Settings.NewWalletGrant = big.NewInt(25)
+
+	_, err := db.TransferTokens(policySender, policyNew, "100")
+	assert.NoError(s.T(), err)
+	assert.Equal(s.T(), "125", s.balance(policyNew))
+	assert.Equal(s.T(), "900", s.balance(policySender))
+
+	var grant, balanceAfter string
+	err = db.DB.QueryRow("SELECT amount, to_balance_after FROM transfers WHERE from_address = $1 AND to_address = $2", db.ZeroAddress, policyNew).Scan(&grant, &balanceAfter)
+	assert.NoError(s.T(), err)
+	assert.Equal(s.T(), "25", grant)
+	assert.Equal(s.T(), "25", balanceAfter)
+
+	// Only the first transfer creates the wallet, so only it is granted
+	_, err = db.TransferTokensCTE(policySender, policyNew, "10")
+	assert.NoError(s.T(), err)
+	assert.Equal(s.T(), "135", s.balance(policyNew))
+
+	// Existing receivers are not granted anything
+	_, err = db.TransferTokens(policySender, policyReceiver, "100")
+	assert.NoError(s.T(), err)
+	assert.Equal(s.T(), "100", s.balance(policyReceiver))
+}
+
+// TestNewWalletStatus tests that a receiver wallet a transfer creates takes the configured status after that transfer
+func (s *ReceiverPolicySuite) TestNewWalletStatus() {
+	db.Settings.ReceiverMustExist = false
+	db.Settings.NewWalletStatus = db.WalletFrozen
+
+	_, err := db.TransferTokens(policySender, policyNew, "100")
+	assert.NoError(s.T(), err)
+
+	wallet, err := db.GetWallet(policyNew)
+	assert.NoError(s.T(), err)
+	if assert.NotNil(s.T(), wallet) {
+		assert.Equal(s.T(), "100", wallet.Balance)
+		assert.Equal(s.T(), db.WalletFrozen, wallet.Status)
+	}
+
+	_, err = db.TransferTokens(policySender, policyNew, "10")
+	assert.ErrorIs(s.T(), err, db.ErrWalletFrozen)
+	assert.Equal(s.T(), "900", s.balance(policySender))
+}
+
+// TestNewWalletTokenTransfer tests that a receiver wallet a transfer of another token creates is initialized like one the primary token creates
+func (s *ReceiverPolicySuite) TestNewWalletTokenTransfer() {
+	db.Settings.ReceiverMustExist = false
+	db.Settings.NewWalletGrant = big.NewInt(25)
+	db.Settings.NewWalletStatus = db.WalletFrozen
+	db.Settings.Tokens = map[string]bool{"USDC": true}
+	_, err := db.MintToken("USDC", policySender, "500")
+	assert.NoError(s.T(), err)
+
+	_, err = db.TransferToken("USDC", policySender, policyNew, "100")
+	assert.NoError(s.T(), err)
+
+	wallet, err := db.GetTokenWallet(policyNew, "USDC")
+	assert.NoError(s.T(), err)
+	if assert.NotNil(s.T(), wallet) {
+		assert.Equal(s.T(), "100", wallet.Balance)
+		assert.Equal(s.T(), db.WalletFrozen, wallet.Status)
+	}
+	assert.Equal(s.T(), "25", s.balance(policyNew))
+
+	var grant string
+	err = db.DB.QueryRow("SELECT amount FROM transfers WHERE from_address = $1 AND to_address = $2 AND token IS NULL", db.ZeroAddress, policyNew).Scan(&grant)
+	assert.NoError(s.T(), err)
+	assert.Equal(s.T(), "25", grant)
+
+	_, err = db.TransferToken("USDC", policySender, policyNew, "10")
+	assert.ErrorIs(s.T(), err, db.ErrWalletFrozen)
+}
+
+func TestReceiverPolicySuite(t *testing.T) {
+	suite.Run(t, new(ReceiverPolicySuite))
+}
@@ -0,0 +1,290 @@
+package integration
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"token-transfer-api/internal/db"
+	"token-transfer-api/pkg/graphql"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/suite"
+)
+
+const (
+	refundAdmin    = "0xd000000000000000000000000000000000000000"
+	refundSender   = "0xd000000000000000000000000000000000000001"
+	refundReceiver = "0xd000000000000000000000000000000000000002"
+)
+
+type RefundSuite struct {
+	suite.Suite
+	server *httptest.Server
+}
+
+// SetupSuite initializes the test environment
+func (s *RefundSuite) SetupSuite() {
+	setupDB(s.T())
+
+	s.T().Setenv("ADMIN_ADDRESS", refundAdmin)
+	handler := graphql.WithAuth(graphql.NewHandler(), callerAuth(refundAdmin, refundReceiver))
+	s.server = httptest.NewServer(handler)
+}
+
+// TearDownSuite cleans up the test environment
+func (s *RefundSuite) TearDownSuite() {
+	s.server.Close()
+	db.CloseDB()
+}
+
+// SetupTest resets the wallets used by the refund tests
+func (s *RefundSuite) SetupTest() {
+	_, err := db.DB.Exec(`TRUNCATE TABLE transfers`)
+	assert.NoError(s.T(), err)
+
+	s.createWallet(refundSender, "1000")
+	s.createWallet(refundReceiver, "0")
+	_, err = db.DB.Exec("UPDATE wallets SET nonce = 0 WHERE address LIKE '0xd0%'")
+	assert.NoError(s.T(), err)
+}
+
+// createWallet creates a wallet with the specified balance
+func (s *RefundSuite) createWallet(address, balance string) {
+	_, err := db.DB.Exec("INSERT INTO wallets (address, balance) VALUES ($1, $2) ON CONFLICT (address) DO UPDATE SET balance = $2",
+		address, balance)
+	assert.NoError(s.T(), err)
+}
+
+// getBalance gets a wallet's balance
+func (s *RefundSuite) getBalance(address string) string {
+	var balance string
+	err := db.DB.QueryRow("SELECT balance FROM wallets WHERE address = $1", address).Scan(&balance)
+	assert.NoError(s.T(), err)
+	return balance
+}
+
+// lastTransferID returns the id of the most recently recorded transfer
+func (s *RefundSuite) lastTransferID() int64 {
+	var id int64
+	err := db.DB.QueryRow("SELECT id FROM transfers ORDER BY id DESC LIMIT 1").Scan(&id)
+	assert.NoError(s.T(), err)
+	return id
+}
+
+// execute posts a GraphQL document to the test server on behalf of caller
+func (s *RefundSuite) execute(query, caller string) (*graphQLResponse, error) {
+	reqBody, _ := json.Marshal(graphQLRequest{Query: query})
+	req, err := http.NewRequest(http.MethodPost, s.server.URL, bytes.NewBuffer(reqBody))
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	authorize(req, caller)
+
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	var result graphQLResponse
+	err = json.NewDecoder(resp.Body).Decode(&result)
+	return &result, err
+}
+
+// executeRefund makes a GraphQL request to refund a transfer as the admin
+func (s *RefundSuite) executeRefund(transferID int64) (*graphQLResponse, error) {
+	return s.executeRefundAs(transferID, refundAdmin)
+}
+
+// executeRefundAs makes a GraphQL request to refund a transfer as caller
+func (s *RefundSuite) executeRefundAs(transferID int64, caller string) (*graphQLResponse, error) {
+	return s.execute(fmt.Sprintf(`mutation {
+		refundTransfer(transfer_id: %d) {
+			transfer { id from_address to_address amount refund_of }
+			from_balance
+			to_balance
+		}
+	}`, transferID), caller)
+}
+
+// TestRefund tests that a refund moves the amount back and links to the original
+func (s *RefundSuite) TestRefund() {
+	sender := refundSender
+	receiver := refundReceiver
+
+	_, err := db.TransferTokens(sender, receiver, "300")
+	assert.NoError(s.T(), err)
+	originalID := s.lastTransferID()
+
+	result, err := s.executeRefund(originalID)
+	assert.NoError(s.T(), err)
+	assert.Nil(s.T(), result.Errors)
+
+	refund, ok := result.Data["refundTransfer"].(map[string]interface{})
+	assert.True(s.T(), ok)
+	assert.Equal(s.T(), "0", refund["from_balance"])
+	assert.Equal(s.T(), "1000", refund["to_balance"])
+
+	transfer := refund["transfer"].(map[string]interface{})
+	assert.Equal(s.T(), receiver, transfer["from_address"])
+	assert.Equal(s.T(), sender, transfer["to_address"])
+	assert.Equal(s.T(), "300", transfer["amount"])
+	assert.Equal(s.T(), float64(originalID), transfer["refund_of"])
+
+	assert.Equal(s.T(), "1000", s.getBalance(sender))
+	assert.Equal(s.T(), "0", s.getBalance(receiver))
+}
+
+// TestAlreadyRefunded tests that a transfer cannot be refunded twice
+func (s *RefundSuite) TestAlreadyRefunded() {
+	sender := refundSender
+	receiver := refundReceiver
+
+	_, err := db.TransferTokens(sender, receiver, "300")
+	assert.NoError(s.T(), err)
+	originalID := s.lastTransferID()
+
+	_, err = db.RefundTransfer(originalID)
+	assert.NoError(s.T(), err)
+
+	_, err = db.RefundTransfer(originalID)
+	assert.ErrorIs(s.T(), err, db.ErrAlreadyRefunded)
+
+	result, err := s.executeRefund(originalID)
+	assert.NoError(s.T(), err)
+	assert.NotNil(s.T(), result.Errors)
+	assert.Contains(s.T(), result.Errors[0]["message"], "already been refunded")
+
+	// Balances reflect a single refund only
+	assert.Equal(s.T(), "1000", s.getBalance(sender))
+	assert.Equal(s.T(), "0", s.getBalance(receiver))
+}
+
+// TestRefundInsufficientBalance tests refunding when the receiver already spent the tokens
+func (s *RefundSuite) TestRefundInsufficientBalance() {
+	sender := refundSender
+	receiver := refundReceiver
+	other := "0xd000000000000000000000000000000000000003"
+
+	_, err := db.TransferTokens(sender, receiver, "300")
+	assert.NoError(s.T(), err)
+	originalID := s.lastTransferID()
+
+	_, err = db.TransferTokens(receiver, other, "200")
+	assert.NoError(s.T(), err)
+
+	_, err = db.RefundTransfer(originalID)
+	assert.ErrorIs(s.T(), err, db.ErrInsufficientBalance)
+
+	// Balances are unchanged by the failed refund
+	assert.Equal(s.T(), "700", s.getBalance(sender))
+	assert.Equal(s.T(), "100", s.getBalance(receiver))
+}
+
+// TestRefundNotFound tests refunding a transfer that does not exist
+func (s *RefundSuite) TestRefundNotFound() {
+	_, err := db.RefundTransfer(999999999)
+	assert.ErrorIs(s.T(), err, db.ErrTransferNotFound)
+}
+
+// TestRefundRequiresAdmin tests that only the admin can refund, even the
+// original receiver giving the tokens back
+func (s *RefundSuite) TestRefundRequiresAdmin() {
+	_, err := db.TransferTokens(refundSender, refundReceiver, "300")
+	assert.NoError(s.T(), err)
+	originalID := s.lastTransferID()
+
+	for _, caller := range []string{refundReceiver, ""} {
+		result, err := s.executeRefundAs(originalID, caller)
+		assert.NoError(s.T(), err)
+		assert.NotNil(s.T(), result.Errors)
+		assert.Equal(s.T(), "UNAUTHORIZED", result.Errors[0]["extensions"].(map[string]interface{})["code"])
+	}
+
+	assert.Equal(s.T(), "700", s.getBalance(refundSender))
+	assert.Equal(s.T(), "300", s.getBalance(refundReceiver))
+}
+
+// TestRefundKeepsReceiverNonce tests that a refund leaves the original
+// receiver's nonce alone, so transfers it signed before stay valid
+func (s *RefundSuite) TestRefundKeepsReceiverNonce() {
+	_, err := db.TransferTokens(refundSender, refundReceiver, "300")
+	assert.NoError(s.T(), err)
+
+	_, err = db.RefundTransfer(s.lastTransferID())
+	assert.NoError(s.T(), err)
+
+	var nonce int64
+	err = db.DB.QueryRow("SELECT nonce FROM wallets WHERE address = $1", refundReceiver).Scan(&nonce)
+	assert.NoError(s.T(), err)
+	assert.Equal(s.T(), int64(0), nonce)
+}
+
+// TestRefundBlocked tests that a refund to or from a blocked address fails
+func (s *RefundSuite) TestRefundBlocked() {
+	_, err := db.TransferTokens(refundSender, refundReceiver, "300")
+	assert.NoError(s.T(), err)
+	originalID := s.lastTransferID()
+
+	assert.NoError(s.T(), db.BlockAddress(refundSender))
+	defer func() {
+		_, err := db.UnblockAddress(refundSender)
+		assert.NoError(s.T(), err)
+	}()
+
+	_, err = db.RefundTransfer(originalID)
+	assert.ErrorIs(s.T(), err, db.ErrBlockedAddress)
+	assert.Equal(s.T(), "300", s.getBalance(refundReceiver))
+}
+
+// TestRefundOfRefund tests that a refund cannot itself be refunded
+func (s *RefundSuite) TestRefundOfRefund() {
+	_, err := db.TransferTokens(refundSender, refundReceiver, "300")
+	assert.NoError(s.T(), err)
+
+	_, err = db.RefundTransfer(s.lastTransferID())
+	assert.NoError(s.T(), err)
+	refundID := s.lastTransferID()
+
+	_, err = db.RefundTransfer(refundID)
+	assert.ErrorIs(s.T(), err, db.ErrRefundOfRefund)
+
+	assert.Equal(s.T(), "1000", s.getBalance(refundSender))
+	assert.Equal(s.T(), "0", s.getBalance(refundReceiver))
+}
+
+// TestRefundRecordsEvent tests that a refund writes an outbox event naming
+// the original transfer while transfer events are on
+func (s *RefundSuite) TestRefundRecordsEvent() {
+	restoreSettings(s.T())
+	db.Settings.TransferEvents = true
+
+	_, err := db.TransferTokens(refundSender, refundReceiver, "300")
+	assert.NoError(s.T(), err)
+	originalID := s.lastTransferID()
+
+	_, err = db.RefundTransfer(originalID)
+	assert.NoError(s.T(), err)
+	refundID := s.lastTransferID()
+
+	events, err := db.GetTransferEvents(refundID)
+	assert.NoError(s.T(), err)
+	if assert.Len(s.T(), events, 1) {
+		var payload map[string]interface{}
+		assert.NoError(s.T(), json.Unmarshal(events[0].Payload, &payload))
+		assert.Equal(s.T(), float64(originalID), payload["refund_of"])
+		assert.Equal(s.T(), refundReceiver, payload["from_address"])
+	}
+
+	_, err = db.DB.Exec("DELETE FROM transfer_events WHERE transfer_id IN ($1, $2)", originalID, refundID)
+	assert.NoError(s.T(), err)
+}
+
+// Run the refund test suite
+func TestRefundSuite(t *testing.T) {
+	suite.Run(t, new(RefundSuite))
+}
@@ -0,0 +1,124 @@
+package integration
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+	"token-transfer-api/internal/db"
+	"token-transfer-api/pkg/graphql"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/suite"
+)
+
+const (
+	timeoutSender   = "0x4c00000000000000000000000000000000000001"
+	timeoutReceiver = "0x4c00000000000000000000000000000000000002"
+
+	requestTimeout = 300 * time.Millisecond
+)
+
+type RequestTimeoutSuite struct {
+	suite.Suite
+	server *httptest.Server
+}
+
+// SetupSuite initializes the database connection and a GraphQL server with a short request timeout
+func (s *RequestTimeoutSuite) SetupSuite() {
+	setupDB(s.T())
+
+	s.T().Setenv("HTTP_REQUEST_TIMEOUT", requestTimeout.String())
+	s.server = httptest.NewServer(graphql.NewHandler())
+}
+
+// TearDownSuite closes the server and the database connection
+func (s *RequestTimeoutSuite) TearDownSuite() {
+	s.cleanup()
+	s.server.Close()
+	db.CloseDB()
+}
+
+// SetupTest funds the sender
+func (s *RequestTimeoutSuite) SetupTest() {
+	s.cleanup()
+	_, err := db.DB.Exec("INSERT INTO wallets (address, balance) VALUES ($1, 1000)", timeoutSender)
+	assert.NoError(s.T(), err)
+}
+
+func (s *RequestTimeoutSuite) cleanup() {
+	_, err := db.DB.Exec("DELETE FROM transfers WHERE from_address LIKE '0x4c%' OR to_address LIKE '0x4c%'")
+	assert.NoError(s.T(), err)
+	_, err = db.DB.Exec("DELETE FROM wallets WHERE address LIKE '0x4c%'")
+	assert.NoError(s.T(), err)
+}
+
+// TestSlowQueryIsCutOff tests that a transfer stuck behind a row lock is answered at the deadline and rolled back
+func (s *RequestTimeoutSuite) TestSlowQueryIsCutOff() {
+	// Hold the sender's row so the transfer waits for it
+	tx, err := db.DB.Begin()
+	if !assert.NoError(s.T(), err) {
+		return
+	}
+	defer tx.Rollback()
+	_, err = tx.Exec("SELECT 1 FROM wallets WHERE address = $1 FOR UPDATE", timeoutSender)
+	assert.NoError(s.T(), err)
+
+	query := fmt.Sprintf(`mutation { transfer(from_address: "%s", to_address: "%s", amount: "100") { balance } }`,
+		timeoutSender, timeoutReceiver)
+	reqBody, _ := json.Marshal(graphQLRequest{Query: query})
+
+	start := time.Now()
+	resp, err := http.Post(s.server.URL, "application/json", bytes.NewBuffer(reqBody))
+	if !assert.NoError(s.T(), err) {
+		return
+	}
+	defer resp.Body.Close()
+	elapsed := time.Since(start)
+
+	assert.Equal(s.T(), http.StatusGatewayTimeout, resp.StatusCode)
+	assert.GreaterOrEqual(s.T(), elapsed, requestTimeout)
+	assert.Less(s.T(), elapsed, requestTimeout+time.Second)
+
+	var result graphQLResponse
+	assert.NoError(s.T(), json.NewDecoder(resp.Body).Decode(&result))
+	if assert.NotEmpty(s.T(), result.Errors) {
+		assert.Equal(s.T(), "REQUEST_TIMEOUT", result.Errors[0]["extensions"].(map[string]interface{})["code"])
+	}
+
+	// The waiting statement is canceled rather than left to run once the lock is free
+	assert.Eventually(s.T(), func() bool {
+		var waiting int
+		err := db.DB.QueryRow("SELECT COUNT(*) FROM pg_stat_activity WHERE wait_event_type = 'Lock' AND query LIKE '%wallets%'").Scan(&waiting)
+		return err == nil && waiting == 0
+	}, 5*time.Second, 20*time.Millisecond)
+
+	assert.NoError(s.T(), tx.Rollback())
+	wallet, err := db.GetWallet(timeoutSender)
+	assert.NoError(s.T(), err)
+	assert.Equal(s.T(), "1000", wallet.Balance)
+}
+
+// TestFastRequestUnaffected tests that requests finishing before the deadline are answered normally
+func (s *RequestTimeoutSuite) TestFastRequestUnaffected() {
+	query := fmt.Sprintf(`{ wallet(address: "%s") { balance } }`, timeoutSender)
+	reqBody, _ := json.Marshal(graphQLRequest{Query: query})
+	resp, err := http.Post(s.server.URL, "application/json", bytes.NewBuffer(reqBody))
+	if !assert.NoError(s.T(), err) {
+		return
+	}
+	defer resp.Body.Close()
+
+	assert.Equal(s.T(), http.StatusOK, resp.StatusCode)
+	var result graphQLResponse
+	assert.NoError(s.T(), json.NewDecoder(resp.Body).Decode(&result))
+	assert.Nil(s.T(), result.Errors)
+	assert.Equal(s.T(), "1000", result.Data["wallet"].(map[string]interface{})["balance"])
+}
+
+func TestRequestTimeoutSuite(t *testing.T) {
+	suite.Run(t, new(RequestTimeoutSuite))
+}
@@ -0,0 +1,177 @@
+package integration
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"token-transfer-api/internal/db"
+	"token-transfer-api/pkg/graphql"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/suite"
+)
+
+const reserveAdmin = "0xe000000000000000000000000000000000000000"
+
+type ReserveSuite struct {
+	suite.Suite
+	server   *httptest.Server
+	treasury string
+	receiver string
+}
+
+// SetupSuite initializes the test environment
+func (s *ReserveSuite) SetupSuite() {
+	setupDB(s.T())
+
+	s.treasury = "0xe000000000000000000000000000000000000001"
+	s.receiver = "0xe000000000000000000000000000000000000002"
+
+	s.T().Setenv("ADMIN_ADDRESS", reserveAdmin)
+	handler := graphql.WithAuth(graphql.NewHandler(), callerAuth(reserveAdmin, s.treasury, s.receiver))
+	s.server = httptest.NewServer(handler)
+}
+
+// TearDownSuite cleans up the test environment
+func (s *ReserveSuite) TearDownSuite() {
+	s.server.Close()
+	db.CloseDB()
+}
+
+// SetupTest resets the wallets and their reserves before each test
+func (s *ReserveSuite) SetupTest() {
+	s.createWallet(s.treasury, "1000")
+	s.createWallet(s.receiver, "0")
+}
+
+// createWallet creates a wallet with the specified balance and no reserve
+func (s *ReserveSuite) createWallet(address, balance string) {
+	_, err := db.DB.Exec("INSERT INTO wallets (address, balance) VALUES ($1, $2) ON CONFLICT (address) DO UPDATE SET balance = $2, reserved = 0",
+		address, balance)
+	assert.NoError(s.T(), err)
+}
+
+// getBalance gets a wallet's balance
+func (s *ReserveSuite) getBalance(address string) string {
+	var balance string
+	err := db.DB.QueryRow("SELECT balance FROM wallets WHERE address = $1", address).Scan(&balance)
+	assert.NoError(s.T(), err)
+	return balance
+}
+
+// executeSetReserve makes a GraphQL request to set a wallet's reserve on
+// behalf of the wallet itself
+func (s *ReserveSuite) executeSetReserve(address, amount string) (*graphQLResponse, error) {
+	return s.executeSetReserveAs(address, amount, address)
+}
+
+// executeSetReserveAs makes a GraphQL request to set a wallet's reserve on
+// behalf of caller
+func (s *ReserveSuite) executeSetReserveAs(address, amount, caller string) (*graphQLResponse, error) {
+	mutation := fmt.Sprintf(`mutation {
+		setReserve(address: "%s", amount: "%s") {
+			address
+			balance
+			reserved
+		}
+	}`, address, amount)
+
+	reqBody, _ := json.Marshal(graphQLRequest{Query: mutation})
+	req, err := http.NewRequest(http.MethodPost, s.server.URL, bytes.NewBuffer(reqBody))
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	authorize(req, caller)
+
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	var result graphQLResponse
+	err = json.NewDecoder(resp.Body).Decode(&result)
+	return &result, err
+}
+
+// TestSetReserve tests setting a reserve through the API
+func (s *ReserveSuite) TestSetReserve() {
+	result, err := s.executeSetReserve(s.treasury, "600")
+	assert.NoError(s.T(), err)
+	assert.Nil(s.T(), result.Errors)
+
+	wallet, ok := result.Data["setReserve"].(map[string]interface{})
+	assert.True(s.T(), ok)
+	assert.Equal(s.T(), "1000", wallet["balance"])
+	assert.Equal(s.T(), "600", wallet["reserved"])
+
+	stored, err := db.GetWallet(s.treasury)
+	assert.NoError(s.T(), err)
+	assert.Equal(s.T(), "600", stored.Reserved)
+}
+
+// TestTransferBreachingReserve tests that a debit cannot dip into the reserve
+func (s *ReserveSuite) TestTransferBreachingReserve() {
+	_, err := db.SetReserve(s.treasury, "600")
+	assert.NoError(s.T(), err)
+
+	// 1000 - 500 = 500 would drop below the 600 reserve
+	_, err = db.TransferTokens(s.treasury, s.receiver, "500")
+	assert.ErrorIs(s.T(), err, db.ErrReserveViolation)
+	assert.Equal(s.T(), "1000", s.getBalance(s.treasury))
+	assert.Equal(s.T(), "0", s.getBalance(s.receiver))
+
+	// Spending exactly down to the reserve is allowed
+	balance, err := db.TransferTokens(s.treasury, s.receiver, "400")
+	assert.NoError(s.T(), err)
+	assert.Equal(s.T(), "600", balance)
+
+	_, err = db.TransferTokens(s.treasury, s.receiver, "1")
+	assert.ErrorIs(s.T(), err, db.ErrReserveViolation)
+}
+
+// TestInvalidReserve tests the reserve validation rules
+func (s *ReserveSuite) TestInvalidReserve() {
+	_, err := db.SetReserve(s.treasury, "-1")
+	assert.ErrorIs(s.T(), err, db.ErrInvalidReserve)
+
+	_, err = db.SetReserve(s.treasury, "1001")
+	assert.ErrorIs(s.T(), err, db.ErrReserveExceedsBalance)
+
+	_, err = db.SetReserve("0xe0000000000000000000000000000000000000ff", "0")
+	assert.ErrorIs(s.T(), err, db.ErrWalletNotFound)
+
+	result, err := s.executeSetReserve(s.treasury, "abc")
+	assert.NoError(s.T(), err)
+	assert.NotNil(s.T(), result.Errors)
+	assert.Contains(s.T(), result.Errors[0]["message"], "non-negative")
+}
+
+// TestSetReserveAuthorization tests that only the wallet itself or the admin
+// can set its reserve
+func (s *ReserveSuite) TestSetReserveAuthorization() {
+	for _, caller := range []string{s.receiver, ""} {
+		result, err := s.executeSetReserveAs(s.treasury, "600", caller)
+		assert.NoError(s.T(), err)
+		assert.NotNil(s.T(), result.Errors)
+		assert.Equal(s.T(), "UNAUTHORIZED", result.Errors[0]["extensions"].(map[string]interface{})["code"])
+	}
+
+	var reserved string
+	err := db.DB.QueryRow("SELECT reserved FROM wallets WHERE address = $1", s.treasury).Scan(&reserved)
+	assert.NoError(s.T(), err)
+	assert.Equal(s.T(), "0", reserved)
+
+	result, err := s.executeSetReserveAs(s.treasury, "600", reserveAdmin)
+	assert.NoError(s.T(), err)
+	assert.Nil(s.T(), result.Errors)
+}
+
+// Run the reserve test suite
+func TestReserveSuite(t *testing.T) {
+	suite.Run(t, new(ReserveSuite))
+}
@@ -0,0 +1,129 @@
+package integration
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"token-transfer-api/internal/db"
+	"token-transfer-api/pkg/rest"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/suite"
+)
+
+type RESTSuite struct {
+	suite.Suite
+	server   *httptest.Server
+	sender   string
+	receiver string
+}
+
+// SetupSuite initializes the test environment
+func (s *RESTSuite) SetupSuite() {
+	setupDB(s.T())
+
+	s.server = httptest.NewServer(rest.NewHandler())
+	s.sender = "0x2800000000000000000000000000000000000001"
+	s.receiver = "0x2800000000000000000000000000000000000002"
+}
+
+// TearDownSuite cleans up the test environment
+func (s *RESTSuite) TearDownSuite() {
+	s.server.Close()
+	db.CloseDB()
+}
+
+// SetupTest creates a funded sender
+func (s *RESTSuite) SetupTest() {
+	s.cleanup()
+	_, err := db.DB.Exec("INSERT INTO wallets (address, balance) VALUES ($1, 1000)", s.sender)
+	assert.NoError(s.T(), err)
+}
+
+// TearDownTest removes the suite's wallets and transfers
+func (s *RESTSuite) TearDownTest() {
+	s.cleanup()
+}
+
+func (s *RESTSuite) cleanup() {
+	_, err := db.DB.Exec("DELETE FROM transfers WHERE from_address LIKE '0x28%' OR to_address LIKE '0x28%'")
+	assert.NoError(s.T(), err)
+	_, err = db.DB.Exec("DELETE FROM wallets WHERE address LIKE '0x28%'")
+	assert.NoError(s.T(), err)
+}
+
+// postTransfer calls POST /api/transfer and returns the status and decoded body
+func (s *RESTSuite) postTransfer(from, to, amount string) (int, map[string]interface{}) {
+	reqBody, _ := json.Marshal(map[string]string{"from_address": from, "to_address": to, "amount": amount})
+	resp, err := http.Post(s.server.URL+"/api/transfer", "application/json", bytes.NewBuffer(reqBody))
+	if err != nil {
+		s.T().Fatalf("Request failed: %v", err)
+	}
+	defer resp.Body.Close()
+
+	var body map[string]interface{}
+	assert.NoError(s.T(), json.NewDecoder(resp.Body).Decode(&body))
+	return resp.StatusCode, body
+}
+
+// getWallet calls GET /api/wallet/{address} and returns the status and decoded body
+func (s *RESTSuite) getWallet(address string) (int, map[string]interface{}) {
+	resp, err := http.Get(s.server.URL + "/api/wallet/" + address)
+	if err != nil {
+		s.T().Fatalf("Request failed: %v", err)
+	}
+	defer resp.Body.Close()
+
+	var body map[string]interface{}
+	assert.NoError(s.T(), json.NewDecoder(resp.Body).Decode(&body))
+	return resp.StatusCode, body
+}
+
+// TestTransferAndLookup tests a successful transfer and the wallets it leaves behind
+func (s *RESTSuite) TestTransferAndLookup() {
+	status, body := s.postTransfer(s.sender, s.receiver, "250")
+	assert.Equal(s.T(), http.StatusOK, status)
+	assert.Equal(s.T(), "750", body["balance"])
+
+	status, body = s.getWallet(s.receiver)
+	assert.Equal(s.T(), http.StatusOK, status)
+	assert.Equal(s.T(), s.receiver, body["address"])
+	assert.Equal(s.T(), "250", body["balance"])
+}
+
+// TestInvalidAmount tests that a malformed amount answers 400
+func (s *RESTSuite) TestInvalidAmount() {
+	status, body := s.postTransfer(s.sender, s.receiver, "-5")
+	assert.Equal(s.T(), http.StatusBadRequest, status)
+	assert.Equal(s.T(), "INVALID_AMOUNT", body["code"])
+}
+
+// TestInsufficientBalance tests that overspending answers 409 and moves nothing
+func (s *RESTSuite) TestInsufficientBalance() {
+	status, body := s.postTransfer(s.sender, s.receiver, "5000")
+	assert.Equal(s.T(), http.StatusConflict, status)
+	assert.Equal(s.T(), "INSUFFICIENT_BALANCE", body["code"])
+
+	_, body = s.getWallet(s.sender)
+	assert.Equal(s.T(), "1000", body["balance"])
+}
+
+// TestMissingSender tests that a transfer from an unknown wallet answers 404
+func (s *RESTSuite) TestMissingSender() {
+	status, body := s.postTransfer("0x2800000000000000000000000000000000000009", s.receiver, "1")
+	assert.Equal(s.T(), http.StatusNotFound, status)
+	assert.Equal(s.T(), "SENDER_NOT_FOUND", body["code"])
+}
+
+// TestWalletNotFound tests that looking up an unknown wallet answers 404
+func (s *RESTSuite) TestWalletNotFound() {
+	status, body := s.getWallet("0x2800000000000000000000000000000000000009")
+	assert.Equal(s.T(), http.StatusNotFound, status)
+	assert.Equal(s.T(), "WALLET_NOT_FOUND", body["code"])
+}
+
+func TestRESTSuite(t *testing.T) {
+	suite.Run(t, new(RESTSuite))
+}
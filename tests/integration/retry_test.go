@@ -0,0 +1,119 @@
+package integration
+
+import (
+	"testing"
+	"time"
+	"token-transfer-api/internal/db"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/suite"
+)
+
+type RetrySuite struct {
+	suite.Suite
+	sender   string
+	receiver string
+}
+
+// SetupSuite initializes the test environment
+func (s *RetrySuite) SetupSuite() {
+	setupDB(s.T())
+
+	s.sender = "0x2700000000000000000000000000000000000001"
+	s.receiver = "0x2700000000000000000000000000000000000002"
+}
+
+// TearDownSuite cleans up the test environment
+func (s *RetrySuite) TearDownSuite() {
+	db.CloseDB()
+}
+
+// SetupTest creates a funded sender and an empty receiver
+func (s *RetrySuite) SetupTest() {
+	s.cleanup()
+	_, err := db.DB.Exec("INSERT INTO wallets (address, balance) VALUES ($1, 1000), ($2, 0)", s.sender, s.receiver)
+	assert.NoError(s.T(), err)
+}
+
+// TearDownTest removes the suite's wallets and transfers
+func (s *RetrySuite) TearDownTest() {
+	s.cleanup()
+}
+
+func (s *RetrySuite) cleanup() {
+	_, err := db.DB.Exec("DELETE FROM transfers WHERE from_address LIKE '0x27%' OR to_address LIKE '0x27%'")
+	assert.NoError(s.T(), err)
+	_, err = db.DB.Exec("DELETE FROM wallets WHERE address LIKE '0x27%'")
+	assert.NoError(s.T(), err)
+}
+
+// transferIntoDeadlock starts a transfer from sender to receiver while
+// another transaction holds the receiver's row and then asks for the
+// sender's, so the two deadlock. The transfer waits first, so Postgres
+// aborts it rather than the blocker. It returns the transfer's error once the
+// blocker has rolled back.
+func (s *RetrySuite) transferIntoDeadlock() error {
+	blocker, err := db.DB.Begin()
+	assert.NoError(s.T(), err)
+	defer blocker.Rollback()
+
+	_, err = blocker.Exec("UPDATE wallets SET balance = balance WHERE address = $1", s.receiver)
+	assert.NoError(s.T(), err)
+
+	done := make(chan error, 1)
+	go func() {
+		_, err := db.TransferTokens(s.sender, s.receiver, "100")
+		done <- err
+	}()
+
+	// Let the transfer lock the sender and start waiting on the receiver
+	time.Sleep(200 * time.Millisecond)
+
+	_, err = blocker.Exec("UPDATE wallets SET balance = balance WHERE address = $1", s.sender)
+	assert.NoError(s.T(), err)
+	assert.NoError(s.T(), blocker.Rollback())
+
+	select {
+	case err := <-done:
+		return err
+	case <-time.After(10 * time.Second):
+		s.T().Fatal("transfer did not finish")
+		return nil
+	}
+}
+
+// TestDeadlockIsRetried tests that a transfer aborted by a deadlock is retried and succeeds
+func (s *RetrySuite) TestDeadlockIsRetried() {
+	restoreSettings(s.T())
+	db.Settings.MaxRetries = 3
+
+	assert.NoError(s.T(), s.transferIntoDeadlock())
+
+	var senderBalance, receiverBalance string
+	assert.NoError(s.T(), db.DB.QueryRow("SELECT balance FROM wallets WHERE address = $1", s.sender).Scan(&senderBalance))
+	assert.NoError(s.T(), db.DB.QueryRow("SELECT balance FROM wallets WHERE address = $1", s.receiver).Scan(&receiverBalance))
+	assert.Equal(s.T(), "900", senderBalance)
+	assert.Equal(s.T(), "100", receiverBalance)
+
+	var count int
+	assert.NoError(s.T(), db.DB.QueryRow("SELECT COUNT(*) FROM transfers WHERE from_address = $1", s.sender).Scan(&count))
+	assert.Equal(s.T(), 1, count)
+}
+
+// TestConflictWithoutRetries tests that a deadlocked transfer fails with CONFLICT when retries are disabled
+func (s *RetrySuite) TestConflictWithoutRetries() {
+	restoreSettings(s.T())
+	db.Settings.MaxRetries = 0
+
+	err := s.transferIntoDeadlock()
+	assert.ErrorIs(s.T(), err, db.ErrConflict)
+	assert.ErrorIs(s.T(), err, db.ErrDeadlock)
+
+	var senderBalance string
+	assert.NoError(s.T(), db.DB.QueryRow("SELECT balance FROM wallets WHERE address = $1", s.sender).Scan(&senderBalance))
+	assert.Equal(s.T(), "1000", senderBalance)
+}
+
+func TestRetrySuite(t *testing.T) {
+	suite.Run(t, new(RetrySuite))
+}
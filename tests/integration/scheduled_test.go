@@ -0,0 +1,201 @@
+package integration
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+	"token-transfer-api/internal/db"
+	"token-transfer-api/pkg/graphql"
+	"token-transfer-api/pkg/scheduler"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/suite"
+)
+
+const (
+	scheduledSender   = "0x3400000000000000000000000000000000000001"
+	scheduledReceiver = "0x3400000000000000000000000000000000000002"
+)
+
+type ScheduledTransferSuite struct {
+	suite.Suite
+	server *httptest.Server
+}
+
+// SetupSuite initializes the test environment
+func (s *ScheduledTransferSuite) SetupSuite() {
+	setupDB(s.T())
+
+	s.server = httptest.NewServer(graphql.NewHandler())
+}
+
+// TearDownSuite cleans up the test environment
+func (s *ScheduledTransferSuite) TearDownSuite() {
+	s.server.Close()
+	db.CloseDB()
+}
+
+// SetupTest funds the sender
+func (s *ScheduledTransferSuite) SetupTest() {
+	s.cleanup()
+	_, err := db.DB.Exec("INSERT INTO wallets (address, balance) VALUES ($1, 1000)", scheduledSender)
+	assert.NoError(s.T(), err)
+}
+
+// TearDownTest removes the suite's wallets, transfers and scheduled transfers
+func (s *ScheduledTransferSuite) TearDownTest() {
+	s.cleanup()
+}
+
+func (s *ScheduledTransferSuite) cleanup() {
+	_, err := db.DB.Exec("DELETE FROM scheduled_transfers WHERE from_address LIKE '0x34%' OR to_address LIKE '0x34%'")
+	assert.NoError(s.T(), err)
+	_, err = db.DB.Exec("DELETE FROM transfers WHERE from_address LIKE '0x34%' OR to_address LIKE '0x34%'")
+	assert.NoError(s.T(), err)
+	_, err = db.DB.Exec("DELETE FROM wallets WHERE address LIKE '0x34%'")
+	assert.NoError(s.T(), err)
+}
+
+// graphQL posts a query with variables and returns the decoded response
+func (s *ScheduledTransferSuite) graphQL(query string, variables map[string]interface{}) *graphQLResponse {
+	reqBody, _ := json.Marshal(graphQLRequest{Query: query, Variables: variables})
+	resp, err := http.Post(s.server.URL, "application/json", bytes.NewBuffer(reqBody))
+	if err != nil {
+		s.T().Fatalf("Request failed: %v", err)
+	}
+	defer resp.Body.Close()
+
+	var result graphQLResponse
+	assert.NoError(s.T(), json.NewDecoder(resp.Body).Decode(&result))
+	return &result
+}
+
+func (s *ScheduledTransferSuite) balance(address string) string {
+	var balance string
+	err := db.DB.QueryRow("SELECT balance FROM wallets WHERE address = $1", address).Scan(&balance)
+	assert.NoError(s.T(), err)
+	return balance
+}
+
+// TestExecutesWhenDue tests that a transfer scheduled a moment ahead is executed by the scheduler
+func (s *ScheduledTransferSuite) TestExecutesWhenDue() {
+	executeAt := time.Now().Add(500 * time.Millisecond)
+	result := s.graphQL(`mutation($from: Address!, $to: Address!, $at: DateTime!) {
+		scheduleTransfer(from_address: $from, to_address: $to, amount: "100", execute_at: $at) { id status }
+	}`, map[string]interface{}{"from": scheduledSender, "to": scheduledReceiver, "at": executeAt.Format(time.RFC3339Nano)})
+	assert.Nil(s.T(), result.Errors)
+
+	scheduled := result.Data["scheduleTransfer"].(map[string]interface{})
+	assert.Equal(s.T(), db.ScheduledPending, scheduled["status"])
+	id := int64(scheduled["id"].(float64))
+
+	// Nothing moves before the transfer is due
+	assert.Equal(s.T(), "1000", s.balance(scheduledSender))
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go scheduler.Run(ctx, scheduler.Config{PollInterval: 50 * time.Millisecond})
+
+	assert.Eventually(s.T(), func() bool {
+		st, err := db.GetScheduledTransfer(id)
+		return err == nil && st.Status != db.ScheduledPending
+	}, 5*time.Second, 50*time.Millisecond)
+
+	result = s.graphQL(`query($id: Int!) { scheduledTransfer(id: $id) { status failure_code executed_at } }`,
+		map[string]interface{}{"id": id})
+	assert.Nil(s.T(), result.Errors)
+	scheduled = result.Data["scheduledTransfer"].(map[string]interface{})
+	assert.Equal(s.T(), db.ScheduledExecuted, scheduled["status"])
+	assert.Nil(s.T(), scheduled["failure_code"])
+	assert.NotNil(s.T(), scheduled["executed_at"])
+
+	assert.Equal(s.T(), "900", s.balance(scheduledSender))
+	assert.Equal(s.T(), "100", s.balance(scheduledReceiver))
+}
+
+// TestFailureIsRecorded tests that a transfer rejected when it runs is marked failed with the reason
+func (s *ScheduledTransferSuite) TestFailureIsRecorded() {
+	scheduled, err := db.ScheduleTransfer(scheduledSender, scheduledReceiver, "5000", time.Now())
+	assert.NoError(s.T(), err)
+
+	executed, err := db.ExecuteDueTransfer(context.Background())
+	assert.NoError(s.T(), err)
+	if assert.NotNil(s.T(), executed) {
+		assert.Equal(s.T(), scheduled.ID, executed.ID)
+		assert.Equal(s.T(), db.ScheduledFailed, executed.Status)
+		assert.Equal(s.T(), db.ErrInsufficientBalance.Code, *executed.FailureCode)
+		assert.NotNil(s.T(), executed.ExecutedAt)
+	}
+
+	// A failed transfer is not picked up again
+	executed, err = db.ExecuteDueTransfer(context.Background())
+	assert.NoError(s.T(), err)
+	assert.Nil(s.T(), executed)
+	assert.Equal(s.T(), "1000", s.balance(scheduledSender))
+}
+
+// TestNotDueIsSkipped tests that transfers scheduled for later are left alone
+func (s *ScheduledTransferSuite) TestNotDueIsSkipped() {
+	scheduled, err := db.ScheduleTransfer(scheduledSender, scheduledReceiver, "100", time.Now().Add(time.Hour))
+	assert.NoError(s.T(), err)
+
+	executed, err := db.ExecuteDueTransfer(context.Background())
+	assert.NoError(s.T(), err)
+	assert.Nil(s.T(), executed)
+
+	scheduled, err = db.GetScheduledTransfer(scheduled.ID)
+	assert.NoError(s.T(), err)
+	assert.Equal(s.T(), db.ScheduledPending, scheduled.Status)
+}
+
+// TestExecutedOnceWithManyWorkers tests that concurrent workers execute a due transfer only once
+func (s *ScheduledTransferSuite) TestExecutedOnceWithManyWorkers() {
+	_, err := db.ScheduleTransfer(scheduledSender, scheduledReceiver, "100", time.Now())
+	assert.NoError(s.T(), err)
+
+	const workers = 8
+	var wg sync.WaitGroup
+	results := make(chan bool, workers)
+	for i := 0; i < workers; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			executed, err := db.ExecuteDueTransfer(context.Background())
+			assert.NoError(s.T(), err)
+			results <- executed != nil
+		}()
+	}
+	wg.Wait()
+	close(results)
+
+	count := 0
+	for executed := range results {
+		if executed {
+			count++
+		}
+	}
+	assert.Equal(s.T(), 1, count)
+	assert.Equal(s.T(), "900", s.balance(scheduledSender))
+	assert.Equal(s.T(), "100", s.balance(scheduledReceiver))
+}
+
+// TestValidation tests that malformed schedules are rejected up front
+func (s *ScheduledTransferSuite) TestValidation() {
+	_, err := db.ScheduleTransfer(scheduledSender, scheduledSender, "100", time.Now())
+	assert.ErrorIs(s.T(), err, db.ErrSelfTransfer)
+
+	_, err = db.ScheduleTransfer(scheduledSender, scheduledReceiver, "-1", time.Now())
+	assert.ErrorIs(s.T(), err, db.ErrInvalidAmount)
+
+	_, err = db.GetScheduledTransfer(-1)
+	assert.ErrorIs(s.T(), err, db.ErrScheduledNotFound)
+}
+
+func TestScheduledTransferSuite(t *testing.T) {
+	suite.Run(t, new(ScheduledTransferSuite))
+}
@@ -10,7 +10,6 @@ import (
 
 	"net/http/httptest"
 
-	"github.com/joho/godotenv"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/suite"
 )
@@ -22,13 +21,10 @@ type SchemaTestSuite struct {
 
 // SetupSuite initializes the test environment
 func (s *SchemaTestSuite) SetupSuite() {
-	if err := godotenv.Load("../../.env"); err != nil {
-		s.T().Logf("No .env file found")
-	}
+	setupDB(s.T())
 
-	if err := db.InitDB(); err != nil {
-		s.T().Fatalf("Failed to initialize database: %v", err)
-	}
+	// The schema is inspected through introspection, whatever .env says
+	s.T().Setenv("GRAPHQL_INTROSPECTION", "true")
 
 	// Setup GraphQL handler
 	handler := graphql.NewHandler()
@@ -142,7 +138,7 @@ func (s *SchemaTestSuite) TestGraphQLSchemaValidation() {
 	// Validate transfer mutation arguments
 	args, hasArgs := transferField["args"].([]interface{})
 	assert.True(s.T(), hasArgs, "transfer mutation should have arguments")
-	assert.Equal(s.T(), 3, len(args), "transfer should have exactly 3 arguments")
+	assert.Equal(s.T(), 5, len(args), "transfer should have exactly 5 arguments")
 
 	// Map to check if all required arguments exist
 	requiredArgs := map[string]bool{
@@ -151,6 +147,13 @@ func (s *SchemaTestSuite) TestGraphQLSchemaValidation() {
 		"amount":       false,
 	}
 
+	// Arguments that may be omitted, with their nullable types
+	optionalArgs := map[string]string{
+		"client_request_id": "String",
+		"dry_run":           "Boolean",
+	}
+	foundOptional := map[string]bool{}
+
 	// Check each argument
 	for _, a := range args {
 		arg, isObj := a.(map[string]interface{})
@@ -168,13 +171,21 @@ func (s *SchemaTestSuite) TestGraphQLSchemaValidation() {
 			requiredArgs[name] = true
 		}
 
-		// Check that the argument is non-nullable
 		argType, hasType := arg["type"].(map[string]interface{})
 		assert.True(s.T(), hasType, "Argument should have a type")
 
 		kind, hasKind := argType["kind"].(string)
 		assert.True(s.T(), hasKind, "Type should have a kind")
 
+		// Optional arguments are nullable
+		if typeName, exists := optionalArgs[name]; exists {
+			foundOptional[name] = true
+			assert.Equal(s.T(), "SCALAR", kind, "Argument %s should be nullable", name)
+			assert.Equal(s.T(), typeName, argType["name"], "Argument %s should be of %s type", name, typeName)
+			continue
+		}
+
+		// Check that the argument is non-nullable
 		// Either the type itself is NON_NULL or its ofType should be
 		if kind == "NON_NULL" {
 			ofType, hasOfType := argType["ofType"].(map[string]interface{})
@@ -194,6 +205,9 @@ func (s *SchemaTestSuite) TestGraphQLSchemaValidation() {
 	for arg, found := range requiredArgs {
 		assert.True(s.T(), found, "Required argument %s not found in schema", arg)
 	}
+	for arg := range optionalArgs {
+		assert.True(s.T(), foundOptional[arg], "Optional argument %s not found in schema", arg)
+	}
 
 	// Validate transfer return type
 	returnType, hasType := transferField["type"].(map[string]interface{})
@@ -0,0 +1,90 @@
+package integration
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"testing"
+	"token-transfer-api/internal/db"
+	"token-transfer-api/internal/graph"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/suite"
+)
+
+const serializedSender = "0x3c00000000000000000000000000000000000001"
+
+type SerializerSuite struct {
+	suite.Suite
+}
+
+// SetupSuite initializes the database connection
+func (s *SerializerSuite) SetupSuite() {
+	setupDB(s.T())
+}
+
+// TearDownSuite closes the database connection
+func (s *SerializerSuite) TearDownSuite() {
+	s.cleanup()
+	db.CloseDB()
+}
+
+// SetupTest gives the sender a fresh balance
+func (s *SerializerSuite) SetupTest() {
+	s.cleanup()
+	_, err := db.DB.Exec("INSERT INTO wallets (address, balance) VALUES ($1, 1000000)", serializedSender)
+	assert.NoError(s.T(), err)
+}
+
+func (s *SerializerSuite) cleanup() {
+	_, err := db.DB.Exec("DELETE FROM transfers WHERE from_address LIKE '0x3c%' OR to_address LIKE '0x3c%'")
+	assert.NoError(s.T(), err)
+	_, err = db.DB.Exec("DELETE FROM wallets WHERE address LIKE '0x3c%'")
+	assert.NoError(s.T(), err)
+}
+
+// hammer sends concurrent transfers from one sender through a resolver and
+// returns how many conflict retries they caused
+func (s *SerializerSuite) hammer(serialize string) int64 {
+	s.T().Setenv("TRANSFER_SERIALIZE_SENDERS", serialize)
+	s.T().Setenv("TRANSFER_RATE_LIMIT", "0")
+	resolver, err := graph.NewResolver()
+	assert.NoError(s.T(), err)
+
+	before := db.ConflictRetries()
+	var wg sync.WaitGroup
+	for i := 0; i < 50; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			_, err := resolver.Transfer(context.Background(), graph.TransferArgs{
+				FromAddress: serializedSender,
+				ToAddress:   fmt.Sprintf("0x3c%038x", i+2),
+				Amount:      "1",
+			})
+			assert.NoError(s.T(), err)
+		}(i)
+	}
+	wg.Wait()
+	return db.ConflictRetries() - before
+}
+
+// TestSerializerReducesRetries tests that queueing a hot sender's transfers
+// in the process avoids the conflict retries they cause otherwise
+func (s *SerializerSuite) TestSerializerReducesRetries() {
+	concurrent := s.hammer("false")
+	s.SetupTest()
+	serialized := s.hammer("true")
+
+	s.T().Logf("conflict retries: %d concurrent, %d serialized", concurrent, serialized)
+	assert.Equal(s.T(), int64(0), serialized)
+	assert.LessOrEqual(s.T(), serialized, concurrent)
+
+	var balance string
+	assert.NoError(s.T(), db.DB.QueryRow("SELECT balance FROM wallets WHERE address = $1", serializedSender).Scan(&balance))
+	assert.Equal(s.T(), "999950", balance)
+}
+
+func TestSerializerSuite(t *testing.T) {
+	suite.Run(t, new(SerializerSuite))
+}
@@ -0,0 +1,31 @@
+package integration
+
+import (
+	"testing"
+	"token-transfer-api/internal/db"
+
+	"github.com/joho/godotenv"
+)
+
+// loadEnv loads ../../.env into the environment when there is one.
+func loadEnv(tb testing.TB) {
+	if err := godotenv.Load("../../.env"); err != nil {
+		tb.Logf("No .env file found")
+	}
+}
+
+// setupDB loads the environment and opens the database, failing when it
+// cannot be reached.
+func setupDB(tb testing.TB) {
+	loadEnv(tb)
+	if err := db.InitDB(); err != nil {
+		tb.Fatalf("Failed to initialize database: %v", err)
+	}
+}
+
+// restoreSettings puts db.Settings back as they are now once tb and its
+// subtests have finished, so they can be changed freely in between.
+func restoreSettings(tb testing.TB) {
+	saved := db.Settings
+	tb.Cleanup(func() { db.Settings = saved })
+}
@@ -0,0 +1,126 @@
+package integration
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"token-transfer-api/internal/auth"
+	"token-transfer-api/internal/db"
+	"token-transfer-api/pkg/graphql"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/suite"
+)
+
+const signedReceiver = "0x4b00000000000000000000000000000000000002"
+
+// signedSenderKey is the private key of the sender; its address is derived
+// from it, so it has no 0x4b prefix.
+var signedSenderKey = big.NewInt(0x4b01)
+
+type SignedTransferSuite struct {
+	suite.Suite
+	server *httptest.Server
+	sender string
+}
+
+// SetupSuite initializes the database connection and a GraphQL server that requires signatures
+func (s *SignedTransferSuite) SetupSuite() {
+	setupDB(s.T())
+
+	s.T().Setenv("SIGNED_TRANSFERS", "required")
+	s.server = httptest.NewServer(graphql.NewHandler())
+
+	s.sender = auth.Address(signedSenderKey)
+}
+
+// TearDownSuite closes the server and the database connection
+func (s *SignedTransferSuite) TearDownSuite() {
+	s.cleanup()
+	s.server.Close()
+	db.CloseDB()
+}
+
+// SetupTest funds the sender
+func (s *SignedTransferSuite) SetupTest() {
+	s.cleanup()
+	_, err := db.DB.Exec("INSERT INTO wallets (address, balance) VALUES ($1, 1000)", s.sender)
+	assert.NoError(s.T(), err)
+}
+
+func (s *SignedTransferSuite) cleanup() {
+	_, err := db.DB.Exec("DELETE FROM transfers WHERE from_address IN ($1, $2) OR to_address IN ($1, $2)", s.sender, signedReceiver)
+	assert.NoError(s.T(), err)
+	_, err = db.DB.Exec("DELETE FROM wallets WHERE address IN ($1, $2)", s.sender, signedReceiver)
+	assert.NoError(s.T(), err)
+}
+
+// sign signs a transfer of amount at nonce with key
+func (s *SignedTransferSuite) sign(key *big.Int, amount string, nonce int64) string {
+	signature, err := auth.Sign(auth.TransferMessage(s.sender, signedReceiver, amount, nonce), key)
+	assert.NoError(s.T(), err)
+	return signature
+}
+
+// transfer sends amount through the transfer mutation with the given nonce and signature
+func (s *SignedTransferSuite) transfer(amount string, nonce int64, signature string) *graphQLResponse {
+	query := fmt.Sprintf(`mutation { transfer(from_address: "%s", to_address: "%s", amount: "%s", expected_nonce: %d, signature: "%s") { balance } }`,
+		s.sender, signedReceiver, amount, nonce, signature)
+	reqBody, _ := json.Marshal(graphQLRequest{Query: query})
+	resp, err := http.Post(s.server.URL, "application/json", bytes.NewBuffer(reqBody))
+	if !assert.NoError(s.T(), err) {
+		return &graphQLResponse{}
+	}
+	defer resp.Body.Close()
+
+	var result graphQLResponse
+	assert.NoError(s.T(), json.NewDecoder(resp.Body).Decode(&result))
+	return &result
+}
+
+func (s *SignedTransferSuite) assertCode(result *graphQLResponse, code string) {
+	if assert.NotEmpty(s.T(), result.Errors) {
+		assert.Equal(s.T(), code, result.Errors[0]["extensions"].(map[string]interface{})["code"])
+	}
+}
+
+func (s *SignedTransferSuite) balance() string {
+	wallet, err := db.GetWallet(s.sender)
+	if !assert.NoError(s.T(), err) || !assert.NotNil(s.T(), wallet) {
+		return ""
+	}
+	return wallet.Balance
+}
+
+// TestValidSignature tests that a transfer signed by the sender goes through
+func (s *SignedTransferSuite) TestValidSignature() {
+	result := s.transfer("100", 0, s.sign(signedSenderKey, "100", 0))
+	assert.Nil(s.T(), result.Errors)
+	assert.Equal(s.T(), "900", result.Data["transfer"].(map[string]interface{})["balance"])
+}
+
+// TestWrongSigner tests that a transfer signed by another key is rejected and moves nothing
+func (s *SignedTransferSuite) TestWrongSigner() {
+	s.assertCode(s.transfer("100", 0, s.sign(big.NewInt(0x4b02), "100", 0)), db.ErrBadSignature.Code)
+
+	// A valid signature of a different amount does not authorize this one
+	s.assertCode(s.transfer("500", 0, s.sign(signedSenderKey, "100", 0)), db.ErrBadSignature.Code)
+	assert.Equal(s.T(), "1000", s.balance())
+}
+
+// TestReplayedSignature tests that resending a signed transfer fails once its nonce is used
+func (s *SignedTransferSuite) TestReplayedSignature() {
+	signature := s.sign(signedSenderKey, "100", 0)
+	assert.Nil(s.T(), s.transfer("100", 0, signature).Errors)
+
+	s.assertCode(s.transfer("100", 0, signature), db.ErrNonceMismatch.Code)
+	assert.Equal(s.T(), "900", s.balance())
+}
+
+func TestSignedTransferSuite(t *testing.T) {
+	suite.Run(t, new(SignedTransferSuite))
+}
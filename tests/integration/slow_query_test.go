@@ -0,0 +1,94 @@
+package integration
+
+import (
+	"bytes"
+	"context"
+	"log"
+	"os"
+	"testing"
+	"token-transfer-api/internal/db"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/suite"
+)
+
+type SlowQuerySuite struct {
+	suite.Suite
+	logs bytes.Buffer
+}
+
+// SetupSuite opens the pool with a 50ms slow query threshold and captures the log
+func (s *SlowQuerySuite) SetupSuite() {
+	s.T().Setenv("DB_SLOW_QUERY_MS", "50")
+	setupDB(s.T())
+	log.SetOutput(&s.logs)
+}
+
+// TearDownSuite restores the log and closes the database connection
+func (s *SlowQuerySuite) TearDownSuite() {
+	log.SetOutput(os.Stderr)
+	db.CloseDB()
+}
+
+// SetupTest starts each test with an empty log
+func (s *SlowQuerySuite) SetupTest() {
+	s.logs.Reset()
+}
+
+// TestSlowStatementsLogged tests that slow Exec, QueryRow and transaction statements are logged
+func (s *SlowQuerySuite) TestSlowStatementsLogged() {
+	_, err := db.DB.Exec("SELECT pg_sleep(0.1) /* exec */")
+	assert.NoError(s.T(), err)
+
+	var one int
+	err = db.DB.QueryRow("SELECT 1 FROM pg_sleep(0.1) /* query row */").Scan(&one)
+	assert.NoError(s.T(), err)
+
+	tx, err := db.BeginTx(context.Background())
+	if assert.NoError(s.T(), err) {
+		_, err = tx.Exec("SELECT pg_sleep(0.1) /* in tx */")
+		assert.NoError(s.T(), err)
+		assert.NoError(s.T(), tx.Rollback())
+	}
+
+	stmt, err := db.DB.Prepare("SELECT pg_sleep(0.1) /* prepared */")
+	if assert.NoError(s.T(), err) {
+		_, err = stmt.Exec()
+		assert.NoError(s.T(), err)
+		stmt.Close()
+	}
+
+	logged := s.logs.String()
+	for _, marker := range []string{"/* exec */", "/* query row */", "/* in tx */", "/* prepared */"} {
+		assert.Contains(s.T(), logged, marker)
+	}
+	assert.Contains(s.T(), logged, "Slow query (")
+}
+
+// TestFastStatementsNotLogged tests that statements under the threshold stay out of the log
+func (s *SlowQuerySuite) TestFastStatementsNotLogged() {
+	var one int
+	err := db.DB.QueryRow("SELECT 1").Scan(&one)
+	assert.NoError(s.T(), err)
+	_, err = db.GetWallet("0x4800000000000000000000000000000000000001")
+	assert.NoError(s.T(), err)
+
+	assert.NotContains(s.T(), s.logs.String(), "Slow query")
+}
+
+// TestArgumentsNotLogged tests that only the parameterized SQL is logged, never the values
+func (s *SlowQuerySuite) TestArgumentsNotLogged() {
+	var echoed string
+	err := db.DB.QueryRow("SELECT $2::text FROM pg_sleep($1)", 0.1, "secret-argument").Scan(&echoed)
+	assert.NoError(s.T(), err)
+	assert.Equal(s.T(), "secret-argument", echoed)
+
+	logged := s.logs.String()
+	assert.Contains(s.T(), logged, "SELECT $2::text FROM pg_sleep($1)")
+	assert.NotContains(s.T(), logged, "secret-argument")
+}
+
+// Run the slow query test suite
+func TestSlowQuerySuite(t *testing.T) {
+	suite.Run(t, new(SlowQuerySuite))
+}
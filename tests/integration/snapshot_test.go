@@ -0,0 +1,118 @@
+package integration
+
+import (
+	"bytes"
+	"context"
+	"strings"
+	"testing"
+	"token-transfer-api/internal/db"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/suite"
+)
+
+type SnapshotSuite struct {
+	suite.Suite
+}
+
+// SetupSuite initializes the test environment
+func (s *SnapshotSuite) SetupSuite() {
+	setupDB(s.T())
+}
+
+// TearDownSuite cleans up the test environment
+func (s *SnapshotSuite) TearDownSuite() {
+	db.CloseDB()
+}
+
+// TestRoundTrip tests that exporting, wiping and importing restores the exact state
+func (s *SnapshotSuite) TestRoundTrip() {
+	// Work inside a transaction that is rolled back, so wiping the tables
+	// does not affect other suites
+	tx, err := db.DB.Begin()
+	assert.NoError(s.T(), err)
+	defer tx.Rollback()
+	ctx := db.WithTx(context.Background(), tx)
+
+	// Known state including a refund and a memo, so refund_of links and
+	// memos are covered
+	_, err = tx.Exec("INSERT INTO wallets (address, balance) VALUES ('0x2600000000000000000000000000000000000001', 1000) ON CONFLICT (address) DO UPDATE SET balance = 1000")
+	assert.NoError(s.T(), err)
+	_, err = db.ExecuteTransfer(ctx, "0x2600000000000000000000000000000000000001", "0x2600000000000000000000000000000000000002", "300", "invoice 7", nil, "")
+	assert.NoError(s.T(), err)
+	var transferID int64
+	assert.NoError(s.T(), tx.QueryRow("SELECT MAX(id) FROM transfers").Scan(&transferID))
+	_, err = db.RefundTransferContext(ctx, transferID)
+	assert.NoError(s.T(), err)
+
+	var exported bytes.Buffer
+	assert.NoError(s.T(), db.ExportSnapshotContext(ctx, &exported))
+	supplyBefore, err := db.GetTotalSupplyContext(ctx)
+	assert.NoError(s.T(), err)
+
+	// Wipe to a clean database
+	_, err = tx.Exec("DELETE FROM transfers")
+	assert.NoError(s.T(), err)
+	_, err = tx.Exec("DELETE FROM wallets")
+	assert.NoError(s.T(), err)
+
+	assert.NoError(s.T(), db.ImportSnapshotContext(ctx, bytes.NewReader(exported.Bytes())))
+
+	var reexported bytes.Buffer
+	assert.NoError(s.T(), db.ExportSnapshotContext(ctx, &reexported))
+	assert.Equal(s.T(), exported.String(), reexported.String())
+
+	supplyAfter, err := db.GetTotalSupplyContext(ctx)
+	assert.NoError(s.T(), err)
+	assert.Equal(s.T(), supplyBefore, supplyAfter)
+
+	// New transfers continue after the imported ids
+	_, err = db.ExecuteTransfer(ctx, "0x2600000000000000000000000000000000000001", "0x2600000000000000000000000000000000000002", "1", "", nil, "")
+	assert.NoError(s.T(), err)
+}
+
+// TestImportRejectsSupplyMismatch tests that a tampered snapshot is rolled back
+func (s *SnapshotSuite) TestImportRejectsSupplyMismatch() {
+	tx, err := db.DB.Begin()
+	assert.NoError(s.T(), err)
+	defer tx.Rollback()
+	ctx := db.WithTx(context.Background(), tx)
+
+	var exported bytes.Buffer
+	assert.NoError(s.T(), db.ExportSnapshotContext(ctx, &exported))
+
+	_, err = tx.Exec("DELETE FROM transfers")
+	assert.NoError(s.T(), err)
+	_, err = tx.Exec("DELETE FROM wallets")
+	assert.NoError(s.T(), err)
+
+	lines := strings.Split(strings.TrimSpace(exported.String()), "\n")
+	lines[len(lines)-1] = strings.Replace(lines[len(lines)-1], `"supply":"`, `"supply":"9`, 1)
+	err = db.ImportSnapshotContext(ctx, strings.NewReader(strings.Join(lines, "\n")))
+	assert.ErrorIs(s.T(), err, db.ErrSupplyMismatch)
+
+	var wallets int
+	assert.NoError(s.T(), tx.QueryRow("SELECT COUNT(*) FROM wallets").Scan(&wallets))
+	assert.Equal(s.T(), 0, wallets)
+}
+
+// TestImportRequiresEmptyTarget tests that a database with transfers is refused
+func (s *SnapshotSuite) TestImportRequiresEmptyTarget() {
+	tx, err := db.DB.Begin()
+	assert.NoError(s.T(), err)
+	defer tx.Rollback()
+	ctx := db.WithTx(context.Background(), tx)
+
+	_, err = tx.Exec("INSERT INTO wallets (address, balance) VALUES ('0x2600000000000000000000000000000000000003', 10) ON CONFLICT (address) DO UPDATE SET balance = 10")
+	assert.NoError(s.T(), err)
+	_, err = db.ExecuteTransfer(ctx, "0x2600000000000000000000000000000000000003", "0x2600000000000000000000000000000000000004", "1", "", nil, "")
+	assert.NoError(s.T(), err)
+
+	err = db.ImportSnapshotContext(ctx, strings.NewReader(`{"type":"header","version":1}`))
+	assert.ErrorIs(s.T(), err, db.ErrSnapshotNotEmpty)
+}
+
+// Run the snapshot test suite
+func TestSnapshotSuite(t *testing.T) {
+	suite.Run(t, new(SnapshotSuite))
+}
@@ -0,0 +1,116 @@
+package integration
+
+import (
+	"bytes"
+	"context"
+	"database/sql"
+	"encoding/json"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"token-transfer-api/internal/db"
+	"token-transfer-api/pkg/graphql"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/suite"
+)
+
+type SupplySuite struct {
+	suite.Suite
+	server *httptest.Server
+}
+
+// SetupSuite initializes the test environment
+func (s *SupplySuite) SetupSuite() {
+	setupDB(s.T())
+
+	// Setup GraphQL handler
+	handler := graphql.NewHandler()
+	s.server = httptest.NewServer(handler)
+}
+
+// TearDownSuite cleans up the test environment
+func (s *SupplySuite) TearDownSuite() {
+	s.server.Close()
+	db.CloseDB()
+}
+
+// createWallet creates a wallet with the specified balance
+func (s *SupplySuite) createWallet(address, balance string) {
+	_, err := db.DB.Exec("INSERT INTO wallets (address, balance) VALUES ($1, $2) ON CONFLICT (address) DO UPDATE SET balance = $2",
+		address, balance)
+	assert.NoError(s.T(), err)
+}
+
+// sumBalances adds up every wallet balance with big.Int math
+func (s *SupplySuite) sumBalances(q interface {
+	Query(string, ...interface{}) (*sql.Rows, error)
+}) (*big.Int, int64) {
+	rows, err := q.Query("SELECT balance FROM wallets")
+	assert.NoError(s.T(), err)
+	defer rows.Close()
+
+	sum := new(big.Int)
+	var count int64
+	for rows.Next() {
+		var balance string
+		assert.NoError(s.T(), rows.Scan(&balance))
+		balanceBig, ok := new(big.Int).SetString(balance, 10)
+		assert.True(s.T(), ok)
+		sum.Add(sum, balanceBig)
+		count++
+	}
+	return sum, count
+}
+
+// TestTotalSupplyMatchesSum tests that the supply equals the big.Int sum of all balances
+func (s *SupplySuite) TestTotalSupplyMatchesSum() {
+	// Balances well beyond int64 so any precision loss would show
+	s.createWallet("0x5000000000000000000000000000000000000001", "123456789012345678901234567890123456789")
+	s.createWallet("0x5000000000000000000000000000000000000002", "987654321098765432109876543210987654321")
+	s.createWallet("0x5000000000000000000000000000000000000003", "1")
+
+	// Read the supply and the individual balances from the same snapshot
+	tx, err := db.DB.BeginTx(context.Background(), &sql.TxOptions{Isolation: sql.LevelRepeatableRead, ReadOnly: true})
+	assert.NoError(s.T(), err)
+	defer tx.Rollback()
+	ctx := db.WithTx(context.Background(), tx)
+
+	supply, err := db.GetTotalSupplyContext(ctx)
+	assert.NoError(s.T(), err)
+	count, err := db.GetWalletCountContext(ctx)
+	assert.NoError(s.T(), err)
+
+	expectedSupply, expectedCount := s.sumBalances(tx)
+	assert.Equal(s.T(), expectedSupply.String(), supply)
+	assert.Equal(s.T(), expectedCount, count)
+}
+
+// TestTotalSupplyQuery tests the totalSupply and walletCount GraphQL queries
+func (s *SupplySuite) TestTotalSupplyQuery() {
+	s.createWallet("0x5000000000000000000000000000000000000001", "123456789012345678901234567890123456789")
+
+	reqBody, _ := json.Marshal(graphQLRequest{Query: `{ totalSupply walletCount }`})
+	resp, err := http.Post(s.server.URL, "application/json", bytes.NewBuffer(reqBody))
+	assert.NoError(s.T(), err)
+	defer resp.Body.Close()
+
+	var result graphQLResponse
+	assert.NoError(s.T(), json.NewDecoder(resp.Body).Decode(&result))
+	assert.Nil(s.T(), result.Errors)
+
+	supply, ok := result.Data["totalSupply"].(string)
+	assert.True(s.T(), ok, "totalSupply should be returned as a string")
+	supplyBig, ok := new(big.Int).SetString(supply, 10)
+	assert.True(s.T(), ok)
+
+	minimum, _ := new(big.Int).SetString("123456789012345678901234567890123456789", 10)
+	assert.True(s.T(), supplyBig.Cmp(minimum) >= 0)
+	assert.Greater(s.T(), result.Data["walletCount"], float64(0))
+}
+
+// Run the supply test suite
+func TestSupplySuite(t *testing.T) {
+	suite.Run(t, new(SupplySuite))
+}
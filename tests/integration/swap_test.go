@@ -0,0 +1,175 @@
+package integration
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"token-transfer-api/internal/db"
+	"token-transfer-api/pkg/graphql"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/suite"
+)
+
+const (
+	swapWalletA = "0x4800000000000000000000000000000000000001"
+	swapWalletB = "0x4800000000000000000000000000000000000002"
+)
+
+type SwapSuite struct {
+	suite.Suite
+	server *httptest.Server
+}
+
+// SetupSuite initializes the database connection and the GraphQL server
+func (s *SwapSuite) SetupSuite() {
+	setupDB(s.T())
+
+	s.server = httptest.NewServer(graphql.NewHandler())
+}
+
+// TearDownSuite closes the server and the database connection
+func (s *SwapSuite) TearDownSuite() {
+	s.cleanup()
+	s.server.Close()
+	db.CloseDB()
+}
+
+// SetupTest gives wallet A 1000 tokens and wallet B 50
+func (s *SwapSuite) SetupTest() {
+	s.cleanup()
+	_, err := db.DB.Exec("INSERT INTO wallets (address, balance) VALUES ($1, 1000), ($2, 50)", swapWalletA, swapWalletB)
+	assert.NoError(s.T(), err)
+}
+
+func (s *SwapSuite) cleanup() {
+	_, err := db.DB.Exec("DELETE FROM transfers WHERE from_address LIKE '0x48%' OR to_address LIKE '0x48%'")
+	assert.NoError(s.T(), err)
+	_, err = db.DB.Exec("DELETE FROM wallets WHERE address LIKE '0x48%'")
+	assert.NoError(s.T(), err)
+}
+
+func (s *SwapSuite) balance(address string) string {
+	wallet, err := db.GetWallet(address)
+	assert.NoError(s.T(), err)
+	return wallet.Balance
+}
+
+func (s *SwapSuite) transferCount() int {
+	var count int
+	err := db.DB.QueryRow("SELECT COUNT(*) FROM transfers WHERE from_address LIKE '0x48%'").Scan(&count)
+	assert.NoError(s.T(), err)
+	return count
+}
+
+// TestSwap tests that the swap mutation moves both amounts and records two linked transfers
+func (s *SwapSuite) TestSwap() {
+	reqBody, _ := json.Marshal(graphQLRequest{Query: `mutation {
+		swap(wallet_a: "` + swapWalletA + `", wallet_b: "` + swapWalletB + `", amount_a: "300", amount_b: "20") {
+			transfer_a { id from_address to_address amount swap_of from_balance_after to_balance_after }
+			transfer_b { id from_address to_address amount swap_of from_balance_after to_balance_after }
+			balance_a
+			balance_b
+		}
+	}`})
+	resp, err := http.Post(s.server.URL, "application/json", bytes.NewBuffer(reqBody))
+	if !assert.NoError(s.T(), err) {
+		return
+	}
+	defer resp.Body.Close()
+
+	var result graphQLResponse
+	assert.NoError(s.T(), json.NewDecoder(resp.Body).Decode(&result))
+	assert.Nil(s.T(), result.Errors)
+
+	swap := result.Data["swap"].(map[string]interface{})
+	assert.Equal(s.T(), "720", swap["balance_a"])
+	assert.Equal(s.T(), "330", swap["balance_b"])
+
+	legA := swap["transfer_a"].(map[string]interface{})
+	assert.Equal(s.T(), swapWalletA, legA["from_address"])
+	assert.Equal(s.T(), swapWalletB, legA["to_address"])
+	assert.Equal(s.T(), "300", legA["amount"])
+	assert.Nil(s.T(), legA["swap_of"])
+	assert.Equal(s.T(), "700", legA["from_balance_after"])
+	assert.Equal(s.T(), "350", legA["to_balance_after"])
+
+	legB := swap["transfer_b"].(map[string]interface{})
+	assert.Equal(s.T(), swapWalletB, legB["from_address"])
+	assert.Equal(s.T(), swapWalletA, legB["to_address"])
+	assert.Equal(s.T(), "20", legB["amount"])
+	assert.Equal(s.T(), legA["id"], legB["swap_of"])
+	assert.Equal(s.T(), "330", legB["from_balance_after"])
+	assert.Equal(s.T(), "720", legB["to_balance_after"])
+
+	assert.Equal(s.T(), "720", s.balance(swapWalletA))
+	assert.Equal(s.T(), "330", s.balance(swapWalletB))
+
+	stored, err := db.GetTransferByID(int64(legB["id"].(float64)))
+	assert.NoError(s.T(), err)
+	if assert.NotNil(s.T(), stored.SwapOf) {
+		assert.Equal(s.T(), int64(legA["id"].(float64)), *stored.SwapOf)
+	}
+}
+
+// TestSwapInsufficientBalanceRollsBack tests that when B cannot pay, A's already debited side is rolled back too
+func (s *SwapSuite) TestSwapInsufficientBalanceRollsBack() {
+	_, err := db.Swap(swapWalletA, swapWalletB, "300", "51")
+	assert.ErrorIs(s.T(), err, db.ErrInsufficientBalance)
+
+	assert.Equal(s.T(), "1000", s.balance(swapWalletA))
+	assert.Equal(s.T(), "50", s.balance(swapWalletB))
+	assert.Zero(s.T(), s.transferCount())
+
+	// Naming the wallets the other way round debits B first
+	_, err = db.Swap(swapWalletB, swapWalletA, "51", "300")
+	assert.ErrorIs(s.T(), err, db.ErrInsufficientBalance)
+
+	assert.Equal(s.T(), "1000", s.balance(swapWalletA))
+	assert.Equal(s.T(), "50", s.balance(swapWalletB))
+	assert.Zero(s.T(), s.transferCount())
+}
+
+// TestSwapRejectsSameWallet tests that a wallet cannot swap with itself
+func (s *SwapSuite) TestSwapRejectsSameWallet() {
+	_, err := db.Swap(swapWalletA, swapWalletA, "1", "1")
+	assert.ErrorIs(s.T(), err, db.ErrSelfTransfer)
+	assert.Zero(s.T(), s.transferCount())
+}
+
+// TestOpposingSwapsDoNotDeadlock tests that swaps naming the pair in opposite orders all complete
+func (s *SwapSuite) TestOpposingSwapsDoNotDeadlock() {
+	const rounds = 20
+
+	var wg sync.WaitGroup
+	errs := make(chan error, 2*rounds)
+	for i := 0; i < rounds; i++ {
+		wg.Add(2)
+		go func() {
+			defer wg.Done()
+			_, err := db.Swap(swapWalletA, swapWalletB, "2", "1")
+			errs <- err
+		}()
+		go func() {
+			defer wg.Done()
+			_, err := db.Swap(swapWalletB, swapWalletA, "1", "2")
+			errs <- err
+		}()
+	}
+	wg.Wait()
+	close(errs)
+
+	for err := range errs {
+		assert.NoError(s.T(), err)
+	}
+	assert.Equal(s.T(), "1000", s.balance(swapWalletA))
+	assert.Equal(s.T(), "50", s.balance(swapWalletB))
+	assert.Equal(s.T(), 4*rounds, s.transferCount())
+}
+
+func TestSwapSuite(t *testing.T) {
+	suite.Run(t, new(SwapSuite))
+}
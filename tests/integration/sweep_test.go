@@ -0,0 +1,209 @@
+package integration
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"token-transfer-api/internal/auth"
+	"token-transfer-api/internal/db"
+	"token-transfer-api/pkg/graphql"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/suite"
+)
+
+const (
+	sweepFrom = "0x5200000000000000000000000000000000000001"
+	sweepTo   = "0x5200000000000000000000000000000000000002"
+)
+
+// sweepSignerKey is the private key of the wallet swept through the
+// mutation, which must be signed; its address is derived from it, so it has
+// no 0x52 prefix.
+var sweepSignerKey = big.NewInt(0x5201)
+
+type SweepSuite struct {
+	suite.Suite
+	server *httptest.Server
+	signer string
+}
+
+// SetupSuite initializes the database connection and the GraphQL server
+func (s *SweepSuite) SetupSuite() {
+	setupDB(s.T())
+
+	s.T().Setenv("TOKEN_DECIMALS", "0")
+	s.server = httptest.NewServer(graphql.NewHandler())
+
+	s.signer = auth.Address(sweepSignerKey)
+}
+
+// TearDownSuite closes the server and the database connection
+func (s *SweepSuite) TearDownSuite() {
+	s.cleanup()
+	s.server.Close()
+	db.CloseDB()
+}
+
+// SetupTest gives both senders 1234 tokens and the receiver 66
+func (s *SweepSuite) SetupTest() {
+	s.cleanup()
+	_, err := db.DB.Exec("INSERT INTO wallets (address, balance) VALUES ($1, 1234), ($2, 66), ($3, 1234)", sweepFrom, sweepTo, s.signer)
+	assert.NoError(s.T(), err)
+}
+
+func (s *SweepSuite) cleanup() {
+	_, err := db.DB.Exec("DELETE FROM transfers WHERE from_address LIKE '0x52%' OR to_address LIKE '0x52%' OR from_address = $1", s.signer)
+	assert.NoError(s.T(), err)
+	_, err = db.DB.Exec("DELETE FROM wallets WHERE address LIKE '0x52%' OR address = $1", s.signer)
+	assert.NoError(s.T(), err)
+}
+
+// sign signs a sweep of the signer's wallet to sweepTo at nonce with key
+func (s *SweepSuite) sign(key *big.Int, nonce int64) string {
+	signature, err := auth.Sign(auth.SweepMessage(s.signer, sweepTo, nonce), key)
+	assert.NoError(s.T(), err)
+	return signature
+}
+
+// sweep sweeps the signer's wallet to sweepTo through the mutation with the given arguments
+func (s *SweepSuite) sweep(arguments string) *graphQLResponse {
+	reqBody, _ := json.Marshal(graphQLRequest{Query: fmt.Sprintf(`mutation {
+		sweep(from_address: "%s", to_address: "%s"%s) {
+			transfer { id from_address to_address amount from_balance_after to_balance_after }
+			from_balance
+			to_balance
+		}
+	}`, s.signer, sweepTo, arguments)})
+	resp, err := http.Post(s.server.URL, "application/json", bytes.NewBuffer(reqBody))
+	if !assert.NoError(s.T(), err) {
+		return &graphQLResponse{}
+	}
+	defer resp.Body.Close()
+
+	var result graphQLResponse
+	assert.NoError(s.T(), json.NewDecoder(resp.Body).Decode(&result))
+	return &result
+}
+
+// errorCode returns the code of the first error of result, or nil without errors
+func (s *SweepSuite) errorCode(result *graphQLResponse) interface{} {
+	if len(result.Errors) == 0 {
+		return nil
+	}
+	return result.Errors[0]["extensions"].(map[string]interface{})["code"]
+}
+
+func (s *SweepSuite) balance(address string) string {
+	wallet, err := db.GetWallet(address)
+	assert.NoError(s.T(), err)
+	return wallet.Balance
+}
+
+func (s *SweepSuite) transferCount() int {
+	var count int
+	err := db.DB.QueryRow("SELECT COUNT(*) FROM transfers WHERE from_address LIKE '0x52%'").Scan(&count)
+	assert.NoError(s.T(), err)
+	return count
+}
+
+// TestSweep tests that the sweep mutation moves the whole balance as one transfer
+func (s *SweepSuite) TestSweep() {
+	result := s.sweep(`, expected_nonce: 0, signature: "` + s.sign(sweepSignerKey, 0) + `"`)
+	assert.Nil(s.T(), result.Errors)
+
+	sweep := result.Data["sweep"].(map[string]interface{})
+	assert.Equal(s.T(), "0", sweep["from_balance"])
+	assert.Equal(s.T(), "1300", sweep["to_balance"])
+
+	transfer := sweep["transfer"].(map[string]interface{})
+	assert.Equal(s.T(), s.signer, transfer["from_address"])
+	assert.Equal(s.T(), sweepTo, transfer["to_address"])
+	assert.Equal(s.T(), "1234", transfer["amount"])
+	assert.Equal(s.T(), "0", transfer["from_balance_after"])
+	assert.Equal(s.T(), "1300", transfer["to_balance_after"])
+
+	assert.Equal(s.T(), "0", s.balance(s.signer))
+	assert.Equal(s.T(), "1300", s.balance(sweepTo))
+
+	var count int
+	assert.NoError(s.T(), db.DB.QueryRow("SELECT COUNT(*) FROM transfers WHERE from_address = $1", s.signer).Scan(&count))
+	assert.Equal(s.T(), 1, count)
+}
+
+// TestSweepRequiresSignature tests that the mutation only sweeps with the sender's signature at its nonce, and only once
+func (s *SweepSuite) TestSweepRequiresSignature() {
+	assert.Equal(s.T(), "BAD_SIGNATURE", s.errorCode(s.sweep("")))
+	assert.Equal(s.T(), "BAD_SIGNATURE", s.errorCode(s.sweep(`, expected_nonce: 0, signature: "`+s.sign(big.NewInt(0x5202), 0)+`"`)))
+	assert.Equal(s.T(), "NONCE_MISMATCH", s.errorCode(s.sweep(`, expected_nonce: 1, signature: "`+s.sign(sweepSignerKey, 1)+`"`)))
+	assert.Equal(s.T(), "1234", s.balance(s.signer))
+
+	signed := `, expected_nonce: 0, signature: "` + s.sign(sweepSignerKey, 0) + `"`
+	assert.Nil(s.T(), s.sweep(signed).Errors)
+	_, err := db.DB.Exec("UPDATE wallets SET balance = 10 WHERE address = $1", s.signer)
+	assert.NoError(s.T(), err)
+	assert.Equal(s.T(), "NONCE_MISMATCH", s.errorCode(s.sweep(signed)), "a signature only authorizes one sweep")
+	assert.Equal(s.T(), "10", s.balance(s.signer))
+}
+
+// TestSweepEmptyWallet tests that sweeping a wallet at zero fails without recording a transfer
+func (s *SweepSuite) TestSweepEmptyWallet() {
+	_, err := db.Sweep(sweepFrom, sweepTo)
+	assert.NoError(s.T(), err)
+
+	_, err = db.Sweep(sweepFrom, sweepTo)
+	assert.ErrorIs(s.T(), err, db.ErrNothingToSweep)
+	assert.Equal(s.T(), "1300", s.balance(sweepTo))
+	assert.Equal(s.T(), 1, s.transferCount())
+}
+
+// TestSweepLeavesReserve tests that reserved tokens stay with the sender
+func (s *SweepSuite) TestSweepLeavesReserve() {
+	_, err := db.DB.Exec("UPDATE wallets SET reserved = 234 WHERE address = $1", sweepFrom)
+	assert.NoError(s.T(), err)
+
+	result, err := db.Sweep(sweepFrom, sweepTo)
+	if assert.NoError(s.T(), err) {
+		assert.Equal(s.T(), "1000", result.Transfer.Amount)
+		assert.Equal(s.T(), "234", result.FromBalance)
+	}
+
+	_, err = db.Sweep(sweepFrom, sweepTo)
+	assert.ErrorIs(s.T(), err, db.ErrNothingToSweep)
+}
+
+// TestSweepMissingSender tests that sweeping a wallet that does not exist is reported as such
+func (s *SweepSuite) TestSweepMissingSender() {
+	_, err := db.Sweep("0x5200000000000000000000000000000000000003", sweepTo)
+	assert.ErrorIs(s.T(), err, db.ErrSenderNotFound)
+}
+
+// TestSweepInitializesNewReceiver tests that a receiver a sweep creates gets
+// NEW_WALLET_GRANT and NEW_WALLET_DEFAULT_STATUS like one a transfer creates
+func (s *SweepSuite) TestSweepInitializesNewReceiver() {
+	restoreSettings(s.T())
+	db.Settings.NewWalletGrant = big.NewInt(100)
+	db.Settings.NewWalletStatus = db.WalletFrozen
+
+	receiver := "0x5200000000000000000000000000000000000004"
+	result, err := db.Sweep(sweepFrom, receiver)
+	assert.NoError(s.T(), err)
+	assert.Equal(s.T(), "1334", result.ToBalance)
+	assert.Equal(s.T(), "1334", s.balance(receiver))
+
+	var status string
+	assert.NoError(s.T(), db.DB.QueryRow("SELECT status FROM wallets WHERE address = $1", receiver).Scan(&status))
+	assert.Equal(s.T(), db.WalletFrozen, status)
+
+	var grants int
+	assert.NoError(s.T(), db.DB.QueryRow("SELECT COUNT(*) FROM transfers WHERE from_address = $1 AND to_address = $2 AND amount = 100", db.ZeroAddress, receiver).Scan(&grants))
+	assert.Equal(s.T(), 1, grants)
+}
+
+func TestSweepSuite(t *testing.T) {
+	suite.Run(t, new(SweepSuite))
+}
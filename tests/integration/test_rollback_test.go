@@ -0,0 +1,156 @@
+package integration
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"token-transfer-api/internal/db"
+	"token-transfer-api/pkg/graphql"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/suite"
+)
+
+type TestRollbackSuite struct {
+	suite.Suite
+	server *httptest.Server
+}
+
+// SetupSuite initializes the test environment with ENV=test
+func (s *TestRollbackSuite) SetupSuite() {
+	setupDB(s.T())
+
+	// The rollback header is only honoured in test mode
+	s.T().Setenv("ENV", "test")
+	handler := graphql.NewHandler()
+	s.server = httptest.NewServer(handler)
+}
+
+// TearDownSuite cleans up the test environment
+func (s *TestRollbackSuite) TearDownSuite() {
+	s.server.Close()
+	db.CloseDB()
+}
+
+// SetupTest resets the wallets used by the rollback tests
+func (s *TestRollbackSuite) SetupTest() {
+	s.createWallet("0xf000000000000000000000000000000000000001", "1000")
+	s.createWallet("0xf000000000000000000000000000000000000002", "0")
+}
+
+// createWallet creates a wallet with the specified balance
+func (s *TestRollbackSuite) createWallet(address, balance string) {
+	_, err := db.DB.Exec("INSERT INTO wallets (address, balance) VALUES ($1, $2) ON CONFLICT (address) DO UPDATE SET balance = $2",
+		address, balance)
+	assert.NoError(s.T(), err)
+}
+
+// getBalance gets a wallet's balance
+func (s *TestRollbackSuite) getBalance(address string) string {
+	var balance string
+	err := db.DB.QueryRow("SELECT balance FROM wallets WHERE address = $1", address).Scan(&balance)
+	assert.NoError(s.T(), err)
+	return balance
+}
+
+// countTransfers counts the transfers sent by an address
+func (s *TestRollbackSuite) countTransfers(address string) int {
+	var count int
+	err := db.DB.QueryRow("SELECT COUNT(*) FROM transfers WHERE from_address = $1", address).Scan(&count)
+	assert.NoError(s.T(), err)
+	return count
+}
+
+// executeTransfer makes a GraphQL transfer request with the given rollback header value
+func (s *TestRollbackSuite) executeTransfer(fromAddress, toAddress, amount, rollback string) (*graphQLResponse, error) {
+	mutation := fmt.Sprintf(`mutation {
+		transfer(
+			from_address: "%s", 
+			to_address: "%s", 
+			amount: "%s"
+		) {
+			balance
+		}
+	}`, fromAddress, toAddress, amount)
+
+	reqBody, _ := json.Marshal(graphQLRequest{Query: mutation})
+	req, err := http.NewRequest(http.MethodPost, s.server.URL, bytes.NewBuffer(reqBody))
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	if rollback != "" {
+		req.Header.Set(graphql.TestRollbackHeader, rollback)
+	}
+
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	var result graphQLResponse
+	err = json.NewDecoder(resp.Body).Decode(&result)
+	return &result, err
+}
+
+// TestTransferIsRolledBack tests that a transfer with the header reports results without persisting them
+func (s *TestRollbackSuite) TestTransferIsRolledBack() {
+	fromAddr := "0xf000000000000000000000000000000000000001"
+	toAddr := "0xf000000000000000000000000000000000000002"
+	transfersBefore := s.countTransfers(fromAddr)
+
+	result, err := s.executeTransfer(fromAddr, toAddr, "250", "true")
+	assert.NoError(s.T(), err)
+	assert.Nil(s.T(), result.Errors)
+
+	transferData, ok := result.Data["transfer"].(map[string]interface{})
+	assert.True(s.T(), ok)
+	assert.Equal(s.T(), "750", transferData["balance"])
+
+	// Nothing was persisted
+	assert.Equal(s.T(), "1000", s.getBalance(fromAddr))
+	assert.Equal(s.T(), "0", s.getBalance(toAddr))
+	assert.Equal(s.T(), transfersBefore, s.countTransfers(fromAddr))
+}
+
+// TestTransferWithoutHeaderPersists tests that normal requests are unaffected
+func (s *TestRollbackSuite) TestTransferWithoutHeaderPersists() {
+	fromAddr := "0xf000000000000000000000000000000000000001"
+	toAddr := "0xf000000000000000000000000000000000000002"
+
+	result, err := s.executeTransfer(fromAddr, toAddr, "250", "")
+	assert.NoError(s.T(), err)
+	assert.Nil(s.T(), result.Errors)
+
+	assert.Equal(s.T(), "750", s.getBalance(fromAddr))
+	assert.Equal(s.T(), "250", s.getBalance(toAddr))
+}
+
+// TestHeaderIgnoredOutsideTestMode tests that the header has no effect unless ENV=test
+func (s *TestRollbackSuite) TestHeaderIgnoredOutsideTestMode() {
+	fromAddr := "0xf000000000000000000000000000000000000001"
+	toAddr := "0xf000000000000000000000000000000000000002"
+
+	s.T().Setenv("ENV", "production")
+	server := httptest.NewServer(graphql.NewHandler())
+	defer server.Close()
+
+	original := s.server
+	s.server = server
+	defer func() { s.server = original }()
+
+	result, err := s.executeTransfer(fromAddr, toAddr, "250", "true")
+	assert.NoError(s.T(), err)
+	assert.Nil(s.T(), result.Errors)
+
+	assert.Equal(s.T(), "750", s.getBalance(fromAddr))
+}
+
+// Run the test rollback suite
+func TestTestRollbackSuite(t *testing.T) {
+	suite.Run(t, new(TestRollbackSuite))
+}
@@ -0,0 +1,121 @@
+package integration
+
+import (
+	"context"
+	"database/sql"
+	"testing"
+	"token-transfer-api/internal/db"
+	"token-transfer-api/internal/model"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/suite"
+)
+
+type TopHoldersSuite struct {
+	suite.Suite
+}
+
+// SetupSuite initializes the database connection
+func (s *TopHoldersSuite) SetupSuite() {
+	setupDB(s.T())
+}
+
+// TearDownSuite closes the database connection
+func (s *TopHoldersSuite) TearDownSuite() {
+	db.CloseDB()
+}
+
+// holders starts a transaction holding only the zero address with 5000 and
+// four wallets with 3000, 1500, 450 and 50, whose balances sort differently
+// as text. Shares depend on every wallet, so the other suites' wallets are
+// deleted inside the transaction; the caller rolls back.
+func (s *TopHoldersSuite) holders() (*sql.Tx, context.Context) {
+	tx, err := db.DB.Begin()
+	assert.NoError(s.T(), err)
+
+	_, err = tx.Exec("DELETE FROM transfers")
+	assert.NoError(s.T(), err)
+	_, err = tx.Exec("DELETE FROM wallets")
+	assert.NoError(s.T(), err)
+
+	_, err = tx.Exec(`INSERT INTO wallets (address, balance) VALUES
+		($1, 5000),
+		('0x4f00000000000000000000000000000000000004', 50),
+		('0x4f00000000000000000000000000000000000002', 1500),
+		('0x4f00000000000000000000000000000000000003', 450),
+		('0x4f00000000000000000000000000000000000001', 3000)`, db.ZeroAddress)
+	assert.NoError(s.T(), err)
+
+	return tx, db.WithTx(context.Background(), tx)
+}
+
+func sumBps(holders []model.Holder) int64 {
+	var sum int64
+	for _, holder := range holders {
+		sum += holder.ShareBps
+	}
+	return sum
+}
+
+// TestTopHoldersOrder tests that the largest balances come first with their share of the whole supply
+func (s *TopHoldersSuite) TestTopHoldersOrder() {
+	tx, ctx := s.holders()
+	defer tx.Rollback()
+
+	holders, err := db.GetTopHoldersContext(ctx, 3, false)
+	assert.NoError(s.T(), err)
+	assert.Equal(s.T(), []model.Holder{
+		{Address: db.ZeroAddress, Balance: "5000", ShareBps: 5000},
+		{Address: "0x4f00000000000000000000000000000000000001", Balance: "3000", ShareBps: 3000},
+		{Address: "0x4f00000000000000000000000000000000000002", Balance: "1500", ShareBps: 1500},
+	}, holders)
+
+	all, err := db.GetTopHoldersContext(ctx, 0, false)
+	assert.NoError(s.T(), err)
+	assert.Len(s.T(), all, 5)
+	assert.Equal(s.T(), int64(10000), sumBps(all))
+}
+
+// TestTopHoldersExcludeZeroAddress tests that the zero address can be left out of both the list and the supply
+func (s *TopHoldersSuite) TestTopHoldersExcludeZeroAddress() {
+	tx, ctx := s.holders()
+	defer tx.Rollback()
+
+	holders, err := db.GetTopHoldersContext(ctx, db.MaxHolderLimit+1, true)
+	assert.NoError(s.T(), err)
+	assert.Equal(s.T(), []model.Holder{
+		{Address: "0x4f00000000000000000000000000000000000001", Balance: "3000", ShareBps: 6000},
+		{Address: "0x4f00000000000000000000000000000000000002", Balance: "1500", ShareBps: 3000},
+		{Address: "0x4f00000000000000000000000000000000000003", Balance: "450", ShareBps: 900},
+		{Address: "0x4f00000000000000000000000000000000000004", Balance: "50", ShareBps: 100},
+	}, holders)
+	assert.Equal(s.T(), int64(10000), sumBps(holders))
+}
+
+// TestTopHoldersRoundsDown tests that shares are rounded down so they never add up to more than the supply
+func (s *TopHoldersSuite) TestTopHoldersRoundsDown() {
+	tx, ctx := s.holders()
+	defer tx.Rollback()
+
+	_, err := tx.Exec("INSERT INTO wallets (address, balance) VALUES ('0x4f00000000000000000000000000000000000005', 7)")
+	assert.NoError(s.T(), err)
+
+	holders, err := db.GetTopHoldersContext(ctx, 0, false)
+	assert.NoError(s.T(), err)
+	if assert.Len(s.T(), holders, 6) {
+		assert.Equal(s.T(), int64(4996), holders[0].ShareBps)
+		assert.Equal(s.T(), int64(6), holders[5].ShareBps)
+	}
+	assert.LessOrEqual(s.T(), sumBps(holders), int64(10000))
+	assert.Greater(s.T(), sumBps(holders), int64(10000-len(holders)))
+}
+
+// TestTopHoldersNegativeLimit tests that a negative limit is rejected
+func (s *TopHoldersSuite) TestTopHoldersNegativeLimit() {
+	_, err := db.GetTopHolders(-1, false)
+	assert.ErrorIs(s.T(), err, db.ErrInvalidPagination)
+}
+
+func TestTopHoldersSuite(t *testing.T) {
+	suite.Run(t, new(TopHoldersSuite))
+}
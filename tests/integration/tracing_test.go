@@ -0,0 +1,174 @@
+package integration
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"token-transfer-api/internal/db"
+	"token-transfer-api/internal/tracing"
+	"token-transfer-api/pkg/graphql"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/suite"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+	"go.opentelemetry.io/otel/trace/noop"
+)
+
+const (
+	tracedSender   = "0x5a00000000000000000000000000000000000001"
+	tracedReceiver = "0x5a00000000000000000000000000000000000002"
+
+	// The trace and the span of the caller the test acts as
+	tracedTraceID  = "4bf92f3577b34da6a3ce929d0e0e4736"
+	tracedParentID = "00f067aa0ba902b7"
+)
+
+type TracingSuite struct {
+	suite.Suite
+	server   *httptest.Server
+	exporter *tracetest.InMemoryExporter
+}
+
+// SetupSuite initializes the database connection, a GraphQL server and a
+// tracer provider that keeps the spans in memory
+func (s *TracingSuite) SetupSuite() {
+	setupDB(s.T())
+
+	s.T().Setenv("TOKEN_DECIMALS", "0")
+	s.server = httptest.NewServer(graphql.NewHandler())
+
+	s.exporter = tracetest.NewInMemoryExporter()
+	otel.SetTracerProvider(sdktrace.NewTracerProvider(sdktrace.WithSyncer(s.exporter)))
+}
+
+// TearDownSuite drops the tracer provider and closes the server and the database connection
+func (s *TracingSuite) TearDownSuite() {
+	otel.SetTracerProvider(noop.NewTracerProvider())
+	tracing.SetSensitiveAmounts(false)
+	s.cleanup()
+	s.server.Close()
+	db.CloseDB()
+}
+
+// SetupTest funds the sender and forgets the spans of earlier tests
+func (s *TracingSuite) SetupTest() {
+	s.cleanup()
+	_, err := db.DB.Exec("INSERT INTO wallets (address, balance) VALUES ($1, 100)", tracedSender)
+	assert.NoError(s.T(), err)
+	s.exporter.Reset()
+	tracing.SetSensitiveAmounts(false)
+}
+
+func (s *TracingSuite) cleanup() {
+	_, err := db.DB.Exec("DELETE FROM transfers WHERE from_address LIKE '0x5a%' OR to_address LIKE '0x5a%'")
+	assert.NoError(s.T(), err)
+	_, err = db.DB.Exec("DELETE FROM wallets WHERE address LIKE '0x5a%'")
+	assert.NoError(s.T(), err)
+}
+
+// transfer sends 5 tokens through a named mutation made in the caller's span
+func (s *TracingSuite) transfer() {
+	reqBody, _ := json.Marshal(graphQLRequest{Query: `mutation Send {
+		transfer(from_address: "` + tracedSender + `", to_address: "` + tracedReceiver + `", amount: "5") { balance }
+	}`})
+	req, _ := http.NewRequest(http.MethodPost, s.server.URL, bytes.NewBuffer(reqBody))
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("traceparent", "00-"+tracedTraceID+"-"+tracedParentID+"-01")
+	resp, err := http.DefaultClient.Do(req)
+	if !assert.NoError(s.T(), err) {
+		return
+	}
+	defer resp.Body.Close()
+
+	var result graphQLResponse
+	assert.NoError(s.T(), json.NewDecoder(resp.Body).Decode(&result))
+	assert.Nil(s.T(), result.Errors)
+}
+
+// span returns the one recorded span called name
+func (s *TracingSuite) span(name string) tracetest.SpanStub {
+	var found []tracetest.SpanStub
+	for _, span := range s.exporter.GetSpans() {
+		if span.Name == name {
+			found = append(found, span)
+		}
+	}
+	if !assert.Len(s.T(), found, 1, name) {
+		return tracetest.SpanStub{}
+	}
+	return found[0]
+}
+
+// attributeValue returns the value of span's attribute key, if it has one
+func attributeValue(span tracetest.SpanStub, key string) (string, bool) {
+	for _, attr := range span.Attributes {
+		if string(attr.Key) == key {
+			return attr.Value.Emit(), true
+		}
+	}
+	return "", false
+}
+
+// TestTransferSpanHierarchy tests that a transfer is traced as the
+// operation, the transfer under it and its SQL statements under that, all
+// in the caller's trace
+func (s *TracingSuite) TestTransferSpanHierarchy() {
+	s.transfer()
+
+	operation := s.span("mutation Send")
+	assert.Equal(s.T(), tracedTraceID, operation.SpanContext.TraceID().String())
+	assert.Equal(s.T(), tracedParentID, operation.Parent.SpanID().String())
+	assert.True(s.T(), operation.Parent.IsRemote())
+	assert.Contains(s.T(), operation.Attributes, attribute.String("graphql.operation.name", "Send"))
+	assert.Contains(s.T(), operation.Attributes, attribute.String("graphql.operation.type", "mutation"))
+
+	transfer := s.span("db.transfer")
+	assert.Equal(s.T(), operation.SpanContext.SpanID(), transfer.Parent.SpanID())
+	from := sha256.Sum256([]byte(tracedSender))
+	to := sha256.Sum256([]byte(tracedReceiver))
+	assert.Contains(s.T(), transfer.Attributes, attribute.String("transfer.from", hex.EncodeToString(from[:])))
+	assert.Contains(s.T(), transfer.Attributes, attribute.String("transfer.to", hex.EncodeToString(to[:])))
+	assert.Contains(s.T(), transfer.Attributes, attribute.String("transfer.amount", "5"))
+
+	statements := map[string]int{}
+	for _, span := range s.exporter.GetSpans() {
+		if span.Parent.SpanID() != transfer.SpanContext.SpanID() {
+			continue
+		}
+		statements[span.Name]++
+		statement, ok := attributeValue(span, "db.statement")
+		assert.True(s.T(), ok, span.Name)
+		assert.True(s.T(), strings.HasPrefix(strings.ToLower(statement), strings.TrimPrefix(span.Name, "db.")), statement)
+	}
+	assert.NotZero(s.T(), statements["db.select"], "the sender is locked")
+	assert.NotZero(s.T(), statements["db.update"], "the sender is debited")
+	assert.NotZero(s.T(), statements["db.insert"], "the receiver is credited and the transfer recorded")
+
+	// No span names the wallets or holds the arguments of a statement
+	for _, span := range s.exporter.GetSpans() {
+		for _, attr := range span.Attributes {
+			assert.NotContains(s.T(), attr.Value.Emit(), "0x5a", "%s %s", span.Name, attr.Key)
+		}
+	}
+}
+
+// TestSensitiveAmountsOmitted tests that amounts stay out of spans once they are configured sensitive
+func (s *TracingSuite) TestSensitiveAmountsOmitted() {
+	tracing.SetSensitiveAmounts(true)
+	s.transfer()
+
+	_, ok := attributeValue(s.span("db.transfer"), "transfer.amount")
+	assert.False(s.T(), ok)
+}
+
+func TestTracingSuite(t *testing.T) {
+	suite.Run(t, new(TracingSuite))
+}
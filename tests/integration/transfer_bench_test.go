@@ -0,0 +1,58 @@
+package integration
+
+import (
+	"testing"
+	"token-transfer-api/internal/db"
+)
+
+const (
+	benchSender   = "0x3800000000000000000000000000000000000001"
+	benchReceiver = "0x3800000000000000000000000000000000000002"
+)
+
+// BenchmarkTransferTokens compares transfers with the hot statements prepared
+// by InitDB against transfers that send the SQL text on every call:
+//
+//	go test ./tests/integration/ -run '^$' -bench BenchmarkTransferTokens
+func BenchmarkTransferTokens(b *testing.B) {
+	b.Run("prepared", func(b *testing.B) { benchmarkTransfers(b, "true", db.TransferTokens) })
+	b.Run("unprepared", func(b *testing.B) { benchmarkTransfers(b, "false", db.TransferTokens) })
+}
+
+// BenchmarkTransferTokensCTE compares the single-statement transfer with the
+// classic one, both with prepared statements.
+func BenchmarkTransferTokensCTE(b *testing.B) {
+	b.Run("cte", func(b *testing.B) { benchmarkTransfers(b, "true", db.TransferTokensCTE) })
+	b.Run("classic", func(b *testing.B) { benchmarkTransfers(b, "true", db.TransferTokens) })
+}
+
+// benchmarkTransfers times b.N transfers of one token made with transfer.
+// Fees, the daily limit and events are turned off so every variant does the
+// same work.
+func benchmarkTransfers(b *testing.B, prepared string, transfer transferFunc) {
+	b.Setenv("DB_PREPARED_STATEMENTS", prepared)
+	b.Setenv("TRANSFER_DAILY_LIMIT", "")
+	b.Setenv("FEE_WALLET_ADDRESS", "")
+	b.Setenv("TRANSFER_FEE_FLAT", "")
+	b.Setenv("TRANSFER_FEE_BPS", "")
+	b.Setenv("TRANSFER_EVENTS_URL", "")
+	setupDB(b)
+	defer db.CloseDB()
+
+	_, err := db.DB.Exec("DELETE FROM transfers WHERE from_address LIKE '0x38%' OR to_address LIKE '0x38%'")
+	if err != nil {
+		b.Fatal(err)
+	}
+	_, err = db.DB.Exec("INSERT INTO wallets (address, balance) VALUES ($1, $2) ON CONFLICT (address) DO UPDATE SET balance = $2",
+		benchSender, "1000000000000000000")
+	if err != nil {
+		b.Fatal(err)
+	}
+
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		if _, err := transfer(benchSender, benchReceiver, "1"); err != nil {
+			b.Fatalf("transfer %d failed: %v", i, err)
+		}
+	}
+}
@@ -0,0 +1,118 @@
+package integration
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"token-transfer-api/internal/db"
+	"token-transfer-api/pkg/graphql"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/suite"
+)
+
+const (
+	byIDSender   = "0x3100000000000000000000000000000000000001"
+	byIDReceiver = "0x3100000000000000000000000000000000000002"
+)
+
+type TransferByIDSuite struct {
+	suite.Suite
+	server *httptest.Server
+}
+
+// SetupSuite initializes the test environment
+func (s *TransferByIDSuite) SetupSuite() {
+	setupDB(s.T())
+
+	s.server = httptest.NewServer(graphql.NewHandler())
+}
+
+// TearDownSuite cleans up the test environment
+func (s *TransferByIDSuite) TearDownSuite() {
+	s.server.Close()
+	db.CloseDB()
+}
+
+// SetupTest funds the sender
+func (s *TransferByIDSuite) SetupTest() {
+	s.cleanup()
+	_, err := db.DB.Exec("INSERT INTO wallets (address, balance) VALUES ($1, 1000)", byIDSender)
+	assert.NoError(s.T(), err)
+}
+
+// TearDownTest removes the suite's wallets and transfers
+func (s *TransferByIDSuite) TearDownTest() {
+	s.cleanup()
+}
+
+func (s *TransferByIDSuite) cleanup() {
+	_, err := db.DB.Exec("DELETE FROM transfers WHERE from_address LIKE '0x31%' OR to_address LIKE '0x31%'")
+	assert.NoError(s.T(), err)
+	_, err = db.DB.Exec("DELETE FROM wallets WHERE address LIKE '0x31%'")
+	assert.NoError(s.T(), err)
+}
+
+// graphQL posts a query and returns the decoded response
+func (s *TransferByIDSuite) graphQL(query string) *graphQLResponse {
+	reqBody, _ := json.Marshal(graphQLRequest{Query: query})
+	resp, err := http.Post(s.server.URL, "application/json", bytes.NewBuffer(reqBody))
+	if err != nil {
+		s.T().Fatalf("Request failed: %v", err)
+	}
+	defer resp.Body.Close()
+
+	var result graphQLResponse
+	assert.NoError(s.T(), json.NewDecoder(resp.Body).Decode(&result))
+	return &result
+}
+
+// TestFetchByID tests fetching a transfer by the id found in the history
+func (s *TransferByIDSuite) TestFetchByID() {
+	result := s.graphQL(fmt.Sprintf(`mutation { transfer(from_address: "%s", to_address: "%s", amount: "250") { balance } }`,
+		byIDSender, byIDReceiver))
+	assert.Nil(s.T(), result.Errors)
+
+	var id int64
+	err := db.DB.QueryRow("SELECT id FROM transfers WHERE from_address = $1 AND to_address = $2", byIDSender, byIDReceiver).Scan(&id)
+	assert.NoError(s.T(), err)
+
+	result = s.graphQL(`{ transfers(first: 100) { edges { node { id from_address } } } }`)
+	assert.Nil(s.T(), result.Errors)
+	found := false
+	for _, edge := range result.Data["transfers"].(map[string]interface{})["edges"].([]interface{}) {
+		node := edge.(map[string]interface{})["node"].(map[string]interface{})
+		if int64(node["id"].(float64)) == id {
+			found = true
+		}
+	}
+	assert.True(s.T(), found, "transfer should appear in the history")
+
+	result = s.graphQL(fmt.Sprintf(`{ transfer(id: %d) { id from_address to_address amount from_balance_after to_balance_after created_at } }`, id))
+	assert.Nil(s.T(), result.Errors)
+
+	transfer := result.Data["transfer"].(map[string]interface{})
+	assert.Equal(s.T(), float64(id), transfer["id"])
+	assert.Equal(s.T(), byIDSender, transfer["from_address"])
+	assert.Equal(s.T(), byIDReceiver, transfer["to_address"])
+	assert.Equal(s.T(), "250", transfer["amount"])
+	assert.Equal(s.T(), "250", transfer["to_balance_after"])
+	assert.NotEmpty(s.T(), transfer["created_at"])
+}
+
+// TestNotFound tests that an unknown id is reported with TRANSFER_NOT_FOUND
+func (s *TransferByIDSuite) TestNotFound() {
+	var maxID int64
+	assert.NoError(s.T(), db.DB.QueryRow("SELECT COALESCE(MAX(id), 0) FROM transfers").Scan(&maxID))
+
+	result := s.graphQL(fmt.Sprintf(`{ transfer(id: %d) { id } }`, maxID+1000))
+	assert.NotNil(s.T(), result.Errors)
+	assert.Equal(s.T(), "TRANSFER_NOT_FOUND", result.Errors[0]["extensions"].(map[string]interface{})["code"])
+}
+
+func TestTransferByIDSuite(t *testing.T) {
+	suite.Run(t, new(TransferByIDSuite))
+}
@@ -0,0 +1,208 @@
+package integration
+
+import (
+	"math/big"
+	"testing"
+	"token-transfer-api/internal/db"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/suite"
+)
+
+// transferFunc is the signature shared by TransferTokens and TransferTokensCTE.
+type transferFunc func(from, to, amount string) (string, error)
+
+// cteWorld is one set of wallets. Each scenario runs once against the
+// classic path and once against the CTE, each in its own world, and the
+// outcomes are compared.
+type cteWorld struct {
+	sender, receiver string
+	transfer         transferFunc
+}
+
+var (
+	classicWorld = cteWorld{
+		sender:   "0x3900000000000000000000000000000000000001",
+		receiver: "0x3900000000000000000000000000000000000002",
+		transfer: db.TransferTokens,
+	}
+	cteTransferWorld = cteWorld{
+		sender:   "0x3900000000000000000000000000000000000011",
+		receiver: "0x3900000000000000000000000000000000000012",
+		transfer: db.TransferTokensCTE,
+	}
+)
+
+type TransferCTESuite struct {
+	suite.Suite
+}
+
+// SetupSuite initializes the database connection
+func (s *TransferCTESuite) SetupSuite() {
+	setupDB(s.T())
+}
+
+// TearDownSuite closes the database connection
+func (s *TransferCTESuite) TearDownSuite() {
+	db.CloseDB()
+}
+
+// SetupTest turns off fees, the daily limit and events, which make the CTE fall back
+func (s *TransferCTESuite) SetupTest() {
+	restoreSettings(s.T())
+	cfg := db.Settings
+	cfg.FeeWallet = ""
+	cfg.DailyLimit = nil
+	cfg.TransferEvents = false
+	db.Settings = cfg
+	s.cleanup()
+}
+
+// TearDownTest removes the suite's rows
+func (s *TransferCTESuite) TearDownTest() {
+	s.cleanup()
+}
+
+func (s *TransferCTESuite) cleanup() {
+	for _, w := range []cteWorld{classicWorld, cteTransferWorld} {
+		_, err := db.UnblockAddress(w.receiver)
+		assert.NoError(s.T(), err)
+	}
+	_, err := db.DB.Exec("DELETE FROM transfers WHERE from_address LIKE '0x39%' OR to_address LIKE '0x39%'")
+	assert.NoError(s.T(), err)
+	_, err = db.DB.Exec("DELETE FROM wallets WHERE address LIKE '0x39%'")
+	assert.NoError(s.T(), err)
+}
+
+func (s *TransferCTESuite) createWallet(address, balance, reserved string) {
+	_, err := db.DB.Exec("INSERT INTO wallets (address, balance, reserved) VALUES ($1, $2, $3)", address, balance, reserved)
+	assert.NoError(s.T(), err)
+}
+
+// outcome is what a transfer left behind, without the addresses of its world.
+type outcome struct {
+	Balance       string
+	Err           error
+	SenderWallet  []string
+	ReceiverFound bool
+	Receiver      []string
+	Transfers     [][3]string
+}
+
+func (s *TransferCTESuite) outcome(w cteWorld, balance string, err error) outcome {
+	o := outcome{Balance: balance, Err: err}
+
+	var b, r string
+	if db.DB.QueryRow("SELECT balance, reserved FROM wallets WHERE address = $1", w.sender).Scan(&b, &r) == nil {
+		o.SenderWallet = []string{b, r}
+	}
+	var activity bool
+	if db.DB.QueryRow("SELECT balance, reserved, last_activity_at IS NOT NULL FROM wallets WHERE address = $1", w.receiver).Scan(&b, &r, &activity) == nil {
+		o.ReceiverFound = true
+		o.Receiver = []string{b, r}
+		assert.True(s.T(), activity)
+	}
+
+	rows, qerr := db.DB.Query("SELECT amount, COALESCE(from_balance_after::text, ''), COALESCE(to_balance_after::text, '') FROM transfers WHERE from_address = $1 ORDER BY id", w.sender)
+	assert.NoError(s.T(), qerr)
+	defer rows.Close()
+	for rows.Next() {
+		var t [3]string
+		assert.NoError(s.T(), rows.Scan(&t[0], &t[1], &t[2]))
+		o.Transfers = append(o.Transfers, t)
+	}
+	return o
+}
+
+// assertEquivalent runs setup and then the transfers of amounts in both
+// worlds and compares the outcomes.
+func (s *TransferCTESuite) assertEquivalent(setup func(w cteWorld), amounts ...string) outcome {
+	var outcomes []outcome
+	for _, w := range []cteWorld{classicWorld, cteTransferWorld} {
+		setup(w)
+		var balance string
+		var err error
+		for _, amount := range amounts {
+			balance, err = w.transfer(w.sender, w.receiver, amount)
+		}
+		outcomes = append(outcomes, s.outcome(w, balance, err))
+	}
+
+	assert.Equal(s.T(), outcomes[0], outcomes[1])
+	return outcomes[1]
+}
+
+// TestSuccessfulTransfers tests transfers to an existing and to a new receiver
+func (s *TransferCTESuite) TestSuccessfulTransfers() {
+	o := s.assertEquivalent(func(w cteWorld) {
+		s.createWallet(w.sender, "1000", "0")
+		s.createWallet(w.receiver, "5", "0")
+	}, "300", "200")
+	assert.NoError(s.T(), o.Err)
+	assert.Equal(s.T(), "500", o.Balance)
+	s.cleanup()
+
+	o = s.assertEquivalent(func(w cteWorld) {
+		s.createWallet(w.sender, "1000", "0")
+	}, "1000")
+	assert.NoError(s.T(), o.Err)
+	assert.Equal(s.T(), []string{"1000", "0"}, o.Receiver)
+}
+
+// TestRejectedTransfers tests that rejections return the same typed errors and change nothing
+func (s *TransferCTESuite) TestRejectedTransfers() {
+	cases := []struct {
+		name  string
+		setup func(w cteWorld)
+		want  error
+	}{
+		{"insufficient balance", func(w cteWorld) { s.createWallet(w.sender, "100", "0") }, db.ErrInsufficientBalance},
+		{"reserve", func(w cteWorld) { s.createWallet(w.sender, "200", "150") }, db.ErrReserveViolation},
+		{"unknown sender", func(w cteWorld) {}, db.ErrSenderNotFound},
+		{"blocked receiver", func(w cteWorld) {
+			s.createWallet(w.sender, "1000", "0")
+			assert.NoError(s.T(), db.BlockAddress(w.receiver))
+		}, db.ErrBlockedAddress},
+	}
+
+	for _, c := range cases {
+		s.Run(c.name, func() {
+			o := s.assertEquivalent(c.setup, "101")
+			assert.ErrorIs(s.T(), o.Err, c.want)
+			assert.Empty(s.T(), o.Transfers)
+			assert.False(s.T(), o.ReceiverFound)
+			s.cleanup()
+		})
+	}
+}
+
+// TestValidation tests the checks made before the database is asked
+func (s *TransferCTESuite) TestValidation() {
+	_, err := db.TransferTokensCTE(cteTransferWorld.sender, cteTransferWorld.sender, "1")
+	assert.ErrorIs(s.T(), err, db.ErrSelfTransfer)
+
+	_, err = db.TransferTokensCTE(cteTransferWorld.sender, cteTransferWorld.receiver, "1.5")
+	assert.ErrorIs(s.T(), err, db.ErrInvalidAmount)
+}
+
+// TestFeesFallBack tests that a configured fee is still charged through the CTE entry point
+func (s *TransferCTESuite) TestFeesFallBack() {
+	feeWallet := "0x3900000000000000000000000000000000000099"
+	s.createWallet(feeWallet, "0", "0")
+	db.Settings.FeeWallet = feeWallet
+	db.Settings.FeeFlat = big.NewInt(3)
+	db.Settings.FeeBPS = 0
+
+	s.createWallet(cteTransferWorld.sender, "100", "0")
+	balance, err := db.TransferTokensCTE(cteTransferWorld.sender, cteTransferWorld.receiver, "10")
+	assert.NoError(s.T(), err)
+	assert.Equal(s.T(), "87", balance)
+
+	var feeBalance string
+	assert.NoError(s.T(), db.DB.QueryRow("SELECT balance FROM wallets WHERE address = $1", feeWallet).Scan(&feeBalance))
+	assert.Equal(s.T(), "3", feeBalance)
+}
+
+func TestTransferCTESuite(t *testing.T) {
+	suite.Run(t, new(TransferCTESuite))
+}
@@ -0,0 +1,99 @@
+package integration
+
+import (
+	"context"
+	"database/sql"
+	"strings"
+	"testing"
+	"time"
+	"token-transfer-api/internal/db"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/suite"
+)
+
+const (
+	indexWallet = "0x3a00000000000000000000000000000000000001"
+	indexPeer   = "0x3a00000000000000000000000000000000000002"
+	indexNoiseA = "0x3a00000000000000000000000000000000000003"
+	indexNoiseB = "0x3a00000000000000000000000000000000000004"
+)
+
+type TransferIndexSuite struct {
+	suite.Suite
+}
+
+// SetupSuite initializes the database connection
+func (s *TransferIndexSuite) SetupSuite() {
+	setupDB(s.T())
+}
+
+// TearDownSuite closes the database connection
+func (s *TransferIndexSuite) TearDownSuite() {
+	db.CloseDB()
+}
+
+// TestAddressLookupsUseIndexes tests that per-address queries stay fast and
+// avoid scanning a large transfers table. The rows live in a transaction that
+// is rolled back.
+func (s *TransferIndexSuite) TestAddressLookupsUseIndexes() {
+	tx, err := db.DB.Begin()
+	assert.NoError(s.T(), err)
+	defer tx.Rollback()
+	ctx := db.WithTx(context.Background(), tx)
+
+	for _, address := range []string{indexWallet, indexPeer, indexNoiseA, indexNoiseB} {
+		_, err = tx.Exec("INSERT INTO wallets (address, balance) VALUES ($1, 0) ON CONFLICT (address) DO NOTHING", address)
+		assert.NoError(s.T(), err)
+	}
+
+	// 100,000 transfers between other wallets and a handful for indexWallet
+	_, err = tx.Exec("INSERT INTO transfers (from_address, to_address, amount) SELECT $1, $2, n FROM generate_series(1, 100000) n",
+		indexNoiseA, indexNoiseB)
+	assert.NoError(s.T(), err)
+	_, err = tx.Exec("INSERT INTO transfers (from_address, to_address, amount) SELECT $1, $2, n FROM generate_series(1, 5) n",
+		indexWallet, indexPeer)
+	assert.NoError(s.T(), err)
+	_, err = tx.Exec("INSERT INTO transfers (from_address, to_address, amount) SELECT $1, $2, n FROM generate_series(1, 3) n",
+		indexPeer, indexWallet)
+	assert.NoError(s.T(), err)
+	_, err = tx.Exec("ANALYZE transfers")
+	assert.NoError(s.T(), err)
+
+	for _, query := range []string{
+		"SELECT * FROM transfers WHERE from_address = $1 OR to_address = $1",
+		"SELECT * FROM transfers WHERE from_address = $1 ORDER BY id DESC LIMIT 20",
+		"SELECT * FROM transfers WHERE to_address = $1 ORDER BY id DESC LIMIT 20",
+	} {
+		plan := s.explain(tx, query, indexWallet)
+		assert.NotContains(s.T(), plan, "Seq Scan on transfers", query)
+		assert.Contains(s.T(), plan, "idx_transfers_", query)
+	}
+
+	start := time.Now()
+	stats, err := db.GetWalletStatsContext(ctx, indexWallet)
+	elapsed := time.Since(start)
+	assert.NoError(s.T(), err)
+	assert.Equal(s.T(), int64(5), stats.TransferCountOut)
+	assert.Equal(s.T(), int64(3), stats.TransferCountIn)
+	assert.Less(s.T(), elapsed, 100*time.Millisecond)
+}
+
+// explain returns the plan Postgres picks for query
+func (s *TransferIndexSuite) explain(tx *sql.Tx, query string, args ...interface{}) string {
+	rows, err := tx.Query("EXPLAIN "+query, args...)
+	assert.NoError(s.T(), err)
+	defer rows.Close()
+
+	var lines []string
+	for rows.Next() {
+		var line string
+		assert.NoError(s.T(), rows.Scan(&line))
+		lines = append(lines, line)
+	}
+	return strings.Join(lines, "\n")
+}
+
+func TestTransferIndexSuite(t *testing.T) {
+	suite.Run(t, new(TransferIndexSuite))
+}
@@ -0,0 +1,138 @@
+package integration
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"token-transfer-api/internal/db"
+	"token-transfer-api/pkg/graphql"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/suite"
+)
+
+const (
+	paginationSender   = "0x1400000000000000000000000000000000000001"
+	paginationReceiver = "0x1400000000000000000000000000000000000002"
+)
+
+type TransferPaginationSuite struct {
+	suite.Suite
+	server *httptest.Server
+}
+
+// SetupSuite initializes the test environment
+func (s *TransferPaginationSuite) SetupSuite() {
+	setupDB(s.T())
+
+	// Setup GraphQL handler
+	handler := graphql.NewHandler()
+	s.server = httptest.NewServer(handler)
+}
+
+// SetupTest creates the wallets the seeded transfers reference
+func (s *TransferPaginationSuite) SetupTest() {
+	for _, address := range []string{paginationSender, paginationReceiver} {
+		_, err := db.DB.Exec("INSERT INTO wallets (address, balance) VALUES ($1, 0) ON CONFLICT (address) DO NOTHING", address)
+		assert.NoError(s.T(), err)
+	}
+}
+
+// TearDownSuite cleans up the test environment
+func (s *TransferPaginationSuite) TearDownSuite() {
+	s.server.Close()
+	db.CloseDB()
+}
+
+// recordTransfer inserts a transfer row without touching balances
+func (s *TransferPaginationSuite) recordTransfer(amount int) {
+	_, err := db.DB.Exec("INSERT INTO transfers (from_address, to_address, amount) VALUES ($1, $2, $3)",
+		paginationSender, paginationReceiver, fmt.Sprint(amount))
+	assert.NoError(s.T(), err)
+}
+
+// fetchPage requests one page of the transfers connection
+func (s *TransferPaginationSuite) fetchPage(first int, after interface{}) map[string]interface{} {
+	reqBody, _ := json.Marshal(graphQLRequest{
+		Query: `query($first: Int, $after: String) {
+			transfers(first: $first, after: $after) {
+				edges { cursor node { id amount } }
+				pageInfo { hasNextPage endCursor }
+			}
+		}`,
+		Variables: map[string]interface{}{
+			"first": first,
+			"after": after,
+		},
+	})
+	resp, err := http.Post(s.server.URL, "application/json", bytes.NewBuffer(reqBody))
+	assert.NoError(s.T(), err)
+	defer resp.Body.Close()
+
+	var result graphQLResponse
+	assert.NoError(s.T(), json.NewDecoder(resp.Body).Decode(&result))
+	assert.Nil(s.T(), result.Errors)
+	return result.Data["transfers"].(map[string]interface{})
+}
+
+// TestPaginationWithConcurrentInserts tests that inserts during a scroll cause no duplicates
+func (s *TransferPaginationSuite) TestPaginationWithConcurrentInserts() {
+	for i := 0; i < 25; i++ {
+		s.recordTransfer(i + 1)
+	}
+
+	seen := map[int]bool{}
+	var after interface{}
+	for pages := 0; pages < 1000; pages++ {
+		conn := s.fetchPage(10, after)
+
+		lastID := 0
+		for _, e := range conn["edges"].([]interface{}) {
+			node := e.(map[string]interface{})["node"].(map[string]interface{})
+			id := int(node["id"].(float64))
+			assert.False(s.T(), seen[id], "transfer %d returned twice", id)
+			seen[id] = true
+			if lastID != 0 {
+				assert.Less(s.T(), id, lastID, "transfers must be ordered newest first")
+			}
+			lastID = id
+		}
+
+		// New transfers arriving mid-scroll must not shift later pages
+		s.recordTransfer(1000 + pages)
+
+		pageInfo := conn["pageInfo"].(map[string]interface{})
+		if !pageInfo["hasNextPage"].(bool) {
+			break
+		}
+		after = pageInfo["endCursor"]
+	}
+
+	assert.GreaterOrEqual(s.T(), len(seen), 25)
+}
+
+// TestInvalidCursor tests that a malformed cursor is rejected
+func (s *TransferPaginationSuite) TestInvalidCursor() {
+	_, _, err := db.ListTransfers(10, -1)
+	assert.ErrorIs(s.T(), err, db.ErrInvalidPagination)
+
+	reqBody, _ := json.Marshal(graphQLRequest{
+		Query: `{ transfers(first: 5, after: "garbage") { pageInfo { hasNextPage } } }`,
+	})
+	resp, err := http.Post(s.server.URL, "application/json", bytes.NewBuffer(reqBody))
+	assert.NoError(s.T(), err)
+	defer resp.Body.Close()
+
+	var result graphQLResponse
+	assert.NoError(s.T(), json.NewDecoder(resp.Body).Decode(&result))
+	assert.NotNil(s.T(), result.Errors)
+	assert.Contains(s.T(), result.Errors[0]["message"], "invalid pagination cursor")
+}
+
+// Run the transfer pagination test suite
+func TestTransferPaginationSuite(t *testing.T) {
+	suite.Run(t, new(TransferPaginationSuite))
+}
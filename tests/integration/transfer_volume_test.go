@@ -0,0 +1,152 @@
+package integration
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+	"token-transfer-api/internal/db"
+	"token-transfer-api/internal/model"
+	"token-transfer-api/pkg/graphql"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/suite"
+)
+
+const (
+	volumeSender   = "0x3f00000000000000000000000000000000000001"
+	volumeReceiver = "0x3f00000000000000000000000000000000000002"
+)
+
+// The transfers are dated long before any other suite runs, so bounding the
+// queries to volumeSince and volumeUntil keeps other suites' transfers out.
+var (
+	volumeSince = time.Date(2001, 3, 4, 0, 0, 0, 0, time.UTC)
+	volumeUntil = time.Date(2001, 3, 6, 0, 0, 0, 0, time.UTC)
+)
+
+type TransferVolumeSuite struct {
+	suite.Suite
+	server *httptest.Server
+}
+
+// SetupSuite initializes the test environment
+func (s *TransferVolumeSuite) SetupSuite() {
+	setupDB(s.T())
+
+	s.server = httptest.NewServer(graphql.NewHandler())
+}
+
+// TearDownSuite cleans up the test environment
+func (s *TransferVolumeSuite) TearDownSuite() {
+	s.server.Close()
+	s.cleanup()
+	db.CloseDB()
+}
+
+// SetupTest records transfers on both sides of a day boundary and just
+// outside the queried range
+func (s *TransferVolumeSuite) SetupTest() {
+	s.cleanup()
+	for _, address := range []string{volumeSender, volumeReceiver} {
+		_, err := db.DB.Exec("INSERT INTO wallets (address, balance) VALUES ($1, 0)", address)
+		assert.NoError(s.T(), err)
+	}
+
+	s.recordTransfer("999", "2001-03-03T23:59:59Z")
+	s.recordTransfer("100", "2001-03-04T09:00:00Z")
+	s.recordTransfer("1000000000000000000000000000000", "2001-03-04T23:30:00Z")
+	s.recordTransfer("7", "2001-03-05T00:15:00Z")
+	s.recordTransfer("999", "2001-03-06T00:00:00Z")
+}
+
+func (s *TransferVolumeSuite) cleanup() {
+	_, err := db.DB.Exec("DELETE FROM transfers WHERE from_address LIKE '0x3f%' OR to_address LIKE '0x3f%'")
+	assert.NoError(s.T(), err)
+	_, err = db.DB.Exec("DELETE FROM wallets WHERE address LIKE '0x3f%'")
+	assert.NoError(s.T(), err)
+}
+
+// recordTransfer inserts a transfer row made at the given time without touching balances
+func (s *TransferVolumeSuite) recordTransfer(amount, at string) {
+	_, err := db.DB.Exec("INSERT INTO transfers (from_address, to_address, amount, created_at) VALUES ($1, $2, $3, $4::timestamptz)",
+		volumeSender, volumeReceiver, amount, at)
+	assert.NoError(s.T(), err)
+}
+
+// TestDayBuckets tests that transfers are summed and counted per UTC day
+func (s *TransferVolumeSuite) TestDayBuckets() {
+	volume, err := db.GetTransferVolume("DAY", &volumeSince, &volumeUntil)
+	assert.NoError(s.T(), err)
+	assert.Equal(s.T(), []model.TransferVolume{
+		{Bucket: volumeSince, TotalAmount: "1000000000000000000000000000100", Count: 2},
+		{Bucket: volumeSince.AddDate(0, 0, 1), TotalAmount: "7", Count: 1},
+	}, volume)
+}
+
+// TestHourAndWeekBuckets tests the other bucket sizes, with weeks starting on Monday
+func (s *TransferVolumeSuite) TestHourAndWeekBuckets() {
+	volume, err := db.GetTransferVolume("HOUR", &volumeSince, &volumeUntil)
+	assert.NoError(s.T(), err)
+	assert.Len(s.T(), volume, 3)
+	assert.Equal(s.T(), time.Date(2001, 3, 4, 23, 0, 0, 0, time.UTC), volume[1].Bucket)
+
+	// 2001-03-04 is a Sunday, so the day boundary is also a week boundary
+	volume, err = db.GetTransferVolume("WEEK", &volumeSince, &volumeUntil)
+	assert.NoError(s.T(), err)
+	assert.Equal(s.T(), []model.TransferVolume{
+		{Bucket: time.Date(2001, 2, 26, 0, 0, 0, 0, time.UTC), TotalAmount: "1000000000000000000000000000100", Count: 2},
+		{Bucket: time.Date(2001, 3, 5, 0, 0, 0, 0, time.UTC), TotalAmount: "7", Count: 1},
+	}, volume)
+}
+
+// TestEmptyRanges tests that ranges without transfers give an empty list
+func (s *TransferVolumeSuite) TestEmptyRanges() {
+	since := time.Date(2001, 3, 1, 0, 0, 0, 0, time.UTC)
+	until := time.Date(2001, 3, 2, 0, 0, 0, 0, time.UTC)
+	volume, err := db.GetTransferVolume("DAY", &since, &until)
+	assert.NoError(s.T(), err)
+	assert.Equal(s.T(), []model.TransferVolume{}, volume)
+
+	// A reversed range is empty rather than an error
+	volume, err = db.GetTransferVolume("DAY", &volumeUntil, &volumeSince)
+	assert.NoError(s.T(), err)
+	assert.Empty(s.T(), volume)
+}
+
+// TestUnknownInterval tests that only HOUR, DAY and WEEK are accepted
+func (s *TransferVolumeSuite) TestUnknownInterval() {
+	_, err := db.GetTransferVolume("MONTH", nil, nil)
+	assert.ErrorIs(s.T(), err, db.ErrInvalidInterval)
+}
+
+// TestTransferVolumeQuery tests the GraphQL query end to end
+func (s *TransferVolumeSuite) TestTransferVolumeQuery() {
+	reqBody, _ := json.Marshal(graphQLRequest{
+		Query: `query($since: DateTime, $until: DateTime) {
+			transferVolume(interval: DAY, since: $since, until: $until) { bucket total_amount count }
+		}`,
+		Variables: map[string]interface{}{
+			"since": volumeSince.Format(time.RFC3339),
+			"until": volumeUntil.Format(time.RFC3339),
+		},
+	})
+	resp, err := http.Post(s.server.URL, "application/json", bytes.NewBuffer(reqBody))
+	assert.NoError(s.T(), err)
+	defer resp.Body.Close()
+
+	var result graphQLResponse
+	assert.NoError(s.T(), json.NewDecoder(resp.Body).Decode(&result))
+	assert.Nil(s.T(), result.Errors)
+	assert.Equal(s.T(), []interface{}{
+		map[string]interface{}{"bucket": "2001-03-04T00:00:00Z", "total_amount": "1000000000000000000000000000100", "count": float64(2)},
+		map[string]interface{}{"bucket": "2001-03-05T00:00:00Z", "total_amount": "7", "count": float64(1)},
+	}, result.Data["transferVolume"])
+}
+
+// Run the transfer volume test suite
+func TestTransferVolumeSuite(t *testing.T) {
+	suite.Run(t, new(TransferVolumeSuite))
+}
@@ -0,0 +1,211 @@
+package integration
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+	"token-transfer-api/internal/db"
+	"token-transfer-api/pkg/graphql"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/suite"
+)
+
+const (
+	freezeAdmin = "0x4400000000000000000000000000000000000000"
+	freezeAlice = "0x4400000000000000000000000000000000000001"
+	freezeBob   = "0x4400000000000000000000000000000000000002"
+	freezeEmpty = "0x4400000000000000000000000000000000000003"
+)
+
+type WalletFreezeSuite struct {
+	suite.Suite
+	server *httptest.Server
+}
+
+// SetupSuite initializes the test environment with a configured admin
+func (s *WalletFreezeSuite) SetupSuite() {
+	setupDB(s.T())
+
+	s.T().Setenv("ADMIN_ADDRESS", freezeAdmin)
+	s.server = httptest.NewServer(graphql.WithAuth(graphql.NewHandler(), callerAuth(freezeAdmin, freezeAlice)))
+}
+
+// TearDownSuite cleans up the test environment
+func (s *WalletFreezeSuite) TearDownSuite() {
+	s.server.Close()
+	s.cleanup()
+	db.CloseDB()
+}
+
+// SetupTest gives Alice 100000 tokens, Bob 1000 and leaves a third wallet empty
+func (s *WalletFreezeSuite) SetupTest() {
+	s.cleanup()
+	for address, balance := range map[string]string{freezeAlice: "100000", freezeBob: "1000", freezeEmpty: "0"} {
+		_, err := db.DB.Exec("INSERT INTO wallets (address, balance) VALUES ($1, $2)", address, balance)
+		assert.NoError(s.T(), err)
+	}
+}
+
+func (s *WalletFreezeSuite) cleanup() {
+	_, err := db.DB.Exec("DELETE FROM transfers WHERE from_address LIKE '0x44%' OR to_address LIKE '0x44%'")
+	assert.NoError(s.T(), err)
+	_, err = db.DB.Exec("DELETE FROM wallets WHERE address LIKE '0x44%'")
+	assert.NoError(s.T(), err)
+}
+
+// sentBy counts the transfers committed from address
+func (s *WalletFreezeSuite) sentBy(address string) int {
+	var count int
+	err := db.DB.QueryRow("SELECT COUNT(*) FROM transfers WHERE from_address = $1", address).Scan(&count)
+	assert.NoError(s.T(), err)
+	return count
+}
+
+// execute sends a GraphQL request on behalf of caller
+func (s *WalletFreezeSuite) execute(query string, variables map[string]interface{}, caller string) *graphQLResponse {
+	reqBody, _ := json.Marshal(graphQLRequest{Query: query, Variables: variables})
+	req, err := http.NewRequest(http.MethodPost, s.server.URL, bytes.NewBuffer(reqBody))
+	assert.NoError(s.T(), err)
+	req.Header.Set("Content-Type", "application/json")
+	authorize(req, caller)
+
+	resp, err := http.DefaultClient.Do(req)
+	assert.NoError(s.T(), err)
+	defer resp.Body.Close()
+
+	var result graphQLResponse
+	assert.NoError(s.T(), json.NewDecoder(resp.Body).Decode(&result))
+	return &result
+}
+
+// TestFrozenWalletCannotSendOrReceive tests both directions on both transfer paths, and that unfreezing lifts the hold
+func (s *WalletFreezeSuite) TestFrozenWalletCannotSendOrReceive() {
+	wallet, err := db.SetWalletFrozen(freezeBob, true)
+	assert.NoError(s.T(), err)
+	assert.True(s.T(), wallet.Frozen)
+
+	_, err = db.TransferTokens(freezeAlice, freezeBob, "10")
+	assert.ErrorIs(s.T(), err, db.ErrWalletFrozen)
+	_, err = db.TransferTokens(freezeBob, freezeAlice, "10")
+	assert.ErrorIs(s.T(), err, db.ErrWalletFrozen)
+	_, err = db.TransferTokensCTE(freezeAlice, freezeBob, "10")
+	assert.ErrorIs(s.T(), err, db.ErrWalletFrozen)
+	_, err = db.TransferTokensCTE(freezeBob, freezeAlice, "10")
+	assert.ErrorIs(s.T(), err, db.ErrWalletFrozen)
+	assert.Zero(s.T(), s.sentBy(freezeAlice))
+	assert.Zero(s.T(), s.sentBy(freezeBob))
+
+	wallet, err = db.SetWalletFrozen(freezeBob, false)
+	assert.NoError(s.T(), err)
+	assert.False(s.T(), wallet.Frozen)
+	balance, err := db.TransferTokens(freezeBob, freezeAlice, "10")
+	assert.NoError(s.T(), err)
+	assert.Equal(s.T(), "990", balance)
+}
+
+// TestFreezeIsSeparateFromStatus tests that lifting a hold does not reopen a closed wallet
+func (s *WalletFreezeSuite) TestFreezeIsSeparateFromStatus() {
+	_, err := db.SetWalletStatus(freezeEmpty, db.WalletClosed)
+	assert.NoError(s.T(), err)
+	_, err = db.SetWalletFrozen(freezeEmpty, true)
+	assert.NoError(s.T(), err)
+
+	wallet, err := db.SetWalletFrozen(freezeEmpty, false)
+	assert.NoError(s.T(), err)
+	assert.Equal(s.T(), db.WalletClosed, wallet.Status)
+	_, err = db.TransferTokens(freezeAlice, freezeEmpty, "10")
+	assert.ErrorIs(s.T(), err, db.ErrWalletClosed)
+}
+
+// TestFreezeUnknownWallet tests that only existing wallets can be frozen
+func (s *WalletFreezeSuite) TestFreezeUnknownWallet() {
+	_, err := db.SetWalletFrozen("0x44000000000000000000000000000000000000ff", true)
+	assert.ErrorIs(s.T(), err, db.ErrWalletNotFound)
+}
+
+// TestFreezeDuringTransfers freezes the sender while transfers are in flight
+// and checks that none commits once the freeze has
+func (s *WalletFreezeSuite) TestFreezeDuringTransfers() {
+	const workers, perWorker = 10, 200
+	var succeeded atomic.Int64
+	unexpected := make(chan error, workers)
+
+	var wg sync.WaitGroup
+	for i := 0; i < workers; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for j := 0; j < perWorker; j++ {
+				_, err := db.TransferTokens(freezeAlice, freezeBob, "1")
+				switch {
+				case err == nil:
+					succeeded.Add(1)
+				case errors.Is(err, db.ErrWalletFrozen):
+					return
+				case !errors.Is(err, db.ErrConflict):
+					unexpected <- err
+					return
+				}
+			}
+		}()
+	}
+
+	// Freeze once transfers are flowing
+	deadline := time.Now().Add(10 * time.Second)
+	for succeeded.Load() < workers && time.Now().Before(deadline) {
+		time.Sleep(time.Millisecond)
+	}
+	_, err := db.SetWalletFrozen(freezeAlice, true)
+	assert.NoError(s.T(), err)
+	sentAtFreeze := s.sentBy(freezeAlice)
+
+	wg.Wait()
+	close(unexpected)
+	for err := range unexpected {
+		s.T().Errorf("unexpected transfer error: %v", err)
+	}
+
+	assert.Positive(s.T(), sentAtFreeze)
+	assert.Less(s.T(), sentAtFreeze, workers*perWorker)
+	assert.Equal(s.T(), sentAtFreeze, s.sentBy(freezeAlice), "a transfer committed after the freeze")
+	assert.Equal(s.T(), int64(sentAtFreeze), succeeded.Load())
+
+	wallet, err := db.GetWallet(freezeAlice)
+	assert.NoError(s.T(), err)
+	assert.Equal(s.T(), strconv.Itoa(100000-sentAtFreeze), wallet.Balance)
+}
+
+// TestFreezeMutations tests that the mutations are admin-only and report the hold
+func (s *WalletFreezeSuite) TestFreezeMutations() {
+	vars := map[string]interface{}{"a": freezeBob}
+
+	result := s.execute(`mutation($a: Address!) { freezeWallet(address: $a) { frozen } }`, vars, freezeAlice)
+	if assert.NotNil(s.T(), result.Errors) {
+		assert.Equal(s.T(), "UNAUTHORIZED", result.Errors[0]["extensions"].(map[string]interface{})["code"])
+	}
+
+	result = s.execute(`mutation($a: Address!) { freezeWallet(address: $a) { address frozen status } }`, vars, freezeAdmin)
+	assert.Nil(s.T(), result.Errors)
+	assert.Equal(s.T(), map[string]interface{}{"address": freezeBob, "frozen": true, "status": "ACTIVE"}, result.Data["freezeWallet"])
+
+	result = s.execute(`query($a: Address!) { wallet(address: $a) { frozen } }`, vars, "")
+	assert.Nil(s.T(), result.Errors)
+	assert.Equal(s.T(), map[string]interface{}{"frozen": true}, result.Data["wallet"])
+
+	result = s.execute(`mutation($a: Address!) { unfreezeWallet(address: $a) { frozen } }`, vars, freezeAdmin)
+	assert.Nil(s.T(), result.Errors)
+	assert.Equal(s.T(), map[string]interface{}{"frozen": false}, result.Data["unfreezeWallet"])
+}
+
+// Run the wallet freeze test suite
+func TestWalletFreezeSuite(t *testing.T) {
+	suite.Run(t, new(WalletFreezeSuite))
+}
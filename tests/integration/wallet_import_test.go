@@ -0,0 +1,203 @@
+package integration
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"token-transfer-api/internal/db"
+	"token-transfer-api/pkg/rest"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/suite"
+)
+
+const (
+	importAdmin    = "0x4e00000000000000000000000000000000000009"
+	importExisting = "0x4e00000000000000000000000000000000000001"
+	importReserved = "0x4e00000000000000000000000000000000000002"
+	importNew      = "0x4e00000000000000000000000000000000000003"
+	importEmpty    = "0x4e00000000000000000000000000000000000004"
+)
+
+type WalletImportSuite struct {
+	suite.Suite
+	server *httptest.Server
+}
+
+// SetupSuite initializes the database connection and a REST server with an admin
+func (s *WalletImportSuite) SetupSuite() {
+	setupDB(s.T())
+
+	s.T().Setenv("ADMIN_ADDRESS", importAdmin)
+	s.T().Setenv("TOKEN_DECIMALS", "0")
+	s.server = httptest.NewServer(rest.WithAuth(rest.NewHandler(), callerAuth(importAdmin)))
+}
+
+// TearDownSuite closes the server and the database connection
+func (s *WalletImportSuite) TearDownSuite() {
+	s.cleanup()
+	s.server.Close()
+	db.CloseDB()
+}
+
+// SetupTest creates a wallet holding 1000 and one with 400 of its 500 reserved
+func (s *WalletImportSuite) SetupTest() {
+	s.cleanup()
+	_, err := db.DB.Exec("INSERT INTO wallets (address, balance) VALUES ($1, 1000)", importExisting)
+	assert.NoError(s.T(), err)
+	_, err = db.DB.Exec("INSERT INTO wallets (address, balance, reserved) VALUES ($1, 500, 400)", importReserved)
+	assert.NoError(s.T(), err)
+}
+
+func (s *WalletImportSuite) cleanup() {
+	_, err := db.DB.Exec("DELETE FROM balance_history WHERE address LIKE '0x4e%'")
+	assert.NoError(s.T(), err)
+	_, err = db.DB.Exec("DELETE FROM transfers WHERE from_address LIKE '0x4e%' OR to_address LIKE '0x4e%'")
+	assert.NoError(s.T(), err)
+	_, err = db.DB.Exec("DELETE FROM wallets WHERE address LIKE '0x4e%'")
+	assert.NoError(s.T(), err)
+}
+
+// mixedRows are three valid rows and four rejected ones, as CSV
+const mixedRows = "address,balance\n" +
+	importExisting + ",250\n" +
+	importNew + ",75\n" +
+	"0x4e0000000000000000000000000000000000zzzz,10\n" +
+	importEmpty + ",0\n" +
+	importReserved + ",100\n" +
+	importNew + ",80\n" +
+	importEmpty + ",-5\n"
+
+// importRows posts body to the import endpoint as the admin and returns the status and decoded body
+func (s *WalletImportSuite) importRows(policy, contentType, body string) (int, map[string]interface{}) {
+	req, _ := http.NewRequest(http.MethodPost, s.server.URL+"/api/wallets/import?on_error="+policy, bytes.NewBufferString(body))
+	req.Header.Set("Content-Type", contentType)
+	authorize(req, importAdmin)
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		s.T().Fatalf("Request failed: %v", err)
+	}
+	defer resp.Body.Close()
+
+	var decoded map[string]interface{}
+	assert.NoError(s.T(), json.NewDecoder(resp.Body).Decode(&decoded))
+	return resp.StatusCode, decoded
+}
+
+// rowStatuses returns the status and code of each row of results
+func rowStatuses(results interface{}) []string {
+	out := []string{}
+	for _, result := range results.([]interface{}) {
+		row := result.(map[string]interface{})
+		status := row["status"].(string)
+		if code, ok := row["code"].(string); ok {
+			status += " " + code
+		}
+		out = append(out, status)
+	}
+	return out
+}
+
+func (s *WalletImportSuite) balance(address string) string {
+	wallet, err := db.GetWallet(address)
+	if !assert.NoError(s.T(), err) || wallet == nil {
+		return ""
+	}
+	return wallet.Balance
+}
+
+// TestSkipImportsValidRows tests that with on_error=skip the valid rows are written and the others reported
+func (s *WalletImportSuite) TestSkipImportsValidRows() {
+	status, body := s.importRows("skip", "text/csv", mixedRows)
+	assert.Equal(s.T(), http.StatusOK, status)
+	assert.Equal(s.T(), []string{
+		"UPDATED",
+		"INSERTED",
+		"REJECTED INVALID_ADDRESS",
+		"INSERTED",
+		"REJECTED RESERVE_EXCEEDS_BALANCE",
+		"REJECTED DUPLICATE_IMPORT_ROW",
+		"REJECTED INVALID_AMOUNT",
+	}, rowStatuses(body["results"]))
+	assert.Equal(s.T(), float64(2), body["inserted"])
+	assert.Equal(s.T(), float64(1), body["updated"])
+	assert.Equal(s.T(), float64(4), body["rejected"])
+
+	assert.Equal(s.T(), "250", s.balance(importExisting))
+	assert.Equal(s.T(), "75", s.balance(importNew))
+	assert.Equal(s.T(), "0", s.balance(importEmpty))
+	assert.Equal(s.T(), "500", s.balance(importReserved))
+
+	// The changes are recorded as a burn and a mint, so the ledger still adds up
+	rows, err := db.DB.Query("SELECT from_address, to_address, amount::text FROM transfers WHERE from_address LIKE '0x4e%' OR to_address LIKE '0x4e%' ORDER BY id")
+	if !assert.NoError(s.T(), err) {
+		return
+	}
+	defer rows.Close()
+	var ledger [][3]string
+	for rows.Next() {
+		var entry [3]string
+		assert.NoError(s.T(), rows.Scan(&entry[0], &entry[1], &entry[2]))
+		ledger = append(ledger, entry)
+	}
+	assert.Equal(s.T(), [][3]string{
+		{importExisting, db.ZeroAddress, "750"},
+		{db.ZeroAddress, importNew, "75"},
+	}, ledger)
+}
+
+// TestAbortWritesNothing tests that with on_error=abort a single bad row keeps every row out
+func (s *WalletImportSuite) TestAbortWritesNothing() {
+	status, body := s.importRows("abort", "text/csv", mixedRows)
+	assert.Equal(s.T(), http.StatusUnprocessableEntity, status)
+	assert.Equal(s.T(), "IMPORT_REJECTED", body["code"])
+	assert.Equal(s.T(), []string{
+		"ABORTED",
+		"ABORTED",
+		"REJECTED INVALID_ADDRESS",
+		"ABORTED",
+		"ABORTED",
+		"REJECTED DUPLICATE_IMPORT_ROW",
+		"REJECTED INVALID_AMOUNT",
+	}, rowStatuses(body["results"]))
+
+	assert.Equal(s.T(), "1000", s.balance(importExisting))
+	wallet, err := db.GetWallet(importNew)
+	assert.NoError(s.T(), err)
+	assert.Nil(s.T(), wallet)
+}
+
+// TestAbortOnLockedBalance tests that a row only found invalid against the stored wallet also aborts the import
+func (s *WalletImportSuite) TestAbortOnLockedBalance() {
+	rows, _ := json.Marshal([]map[string]string{
+		{"address": importNew, "balance": "75"},
+		{"address": importReserved, "balance": "100"},
+	})
+	status, body := s.importRows("abort", "application/json", string(rows))
+	assert.Equal(s.T(), http.StatusUnprocessableEntity, status)
+	assert.Equal(s.T(), []string{"ABORTED", "REJECTED RESERVE_EXCEEDS_BALANCE"}, rowStatuses(body["results"]))
+
+	wallet, err := db.GetWallet(importNew)
+	assert.NoError(s.T(), err)
+	assert.Nil(s.T(), wallet)
+}
+
+// TestAbortImportsCleanRows tests that with on_error=abort an import without bad rows goes through
+func (s *WalletImportSuite) TestAbortImportsCleanRows() {
+	rows, _ := json.Marshal([]map[string]string{
+		{"address": importExisting, "balance": "1500"},
+		{"address": importNew, "balance": "75"},
+	})
+	status, body := s.importRows("abort", "application/json", string(rows))
+	assert.Equal(s.T(), http.StatusOK, status)
+	assert.Equal(s.T(), []string{"UPDATED", "INSERTED"}, rowStatuses(body["results"]))
+
+	assert.Equal(s.T(), "1500", s.balance(importExisting))
+	assert.Equal(s.T(), "75", s.balance(importNew))
+}
+
+func TestWalletImportSuite(t *testing.T) {
+	suite.Run(t, new(WalletImportSuite))
+}
@@ -0,0 +1,133 @@
+package integration
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"token-transfer-api/internal/db"
+	"token-transfer-api/internal/model"
+	"token-transfer-api/pkg/graphql"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/suite"
+)
+
+const (
+	loaderWalletA = "0x5700000000000000000000000000000000000001"
+	loaderWalletB = "0x5700000000000000000000000000000000000002"
+	loaderWalletC = "0x5700000000000000000000000000000000000003"
+)
+
+type WalletLoaderSuite struct {
+	suite.Suite
+	server  *httptest.Server
+	fetches atomic.Int64
+}
+
+// SetupSuite initializes the database connection and a GraphQL server whose
+// requests batch wallet lookups through a loader counting its queries
+func (s *WalletLoaderSuite) SetupSuite() {
+	setupDB(s.T())
+	restoreSettings(s.T())
+
+	s.T().Setenv("TOKEN_DECIMALS", "0")
+	handler := graphql.NewHandler()
+	s.server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		loader := graphql.NewWalletLoader(func(ctx context.Context, addresses []string) ([]model.Wallet, error) {
+			s.fetches.Add(1)
+			return db.GetWalletsContext(ctx, addresses)
+		})
+		handler.ServeHTTP(w, r.WithContext(graphql.WithWalletLoader(r.Context(), loader)))
+	}))
+}
+
+// TearDownSuite closes the server and the database connection
+func (s *WalletLoaderSuite) TearDownSuite() {
+	s.cleanup()
+	s.server.Close()
+	db.CloseDB()
+}
+
+// SetupTest gives wallet A 1000 tokens, without fees, and sends four
+// transfers between wallets A, B and C
+func (s *WalletLoaderSuite) SetupTest() {
+	restoreSettings(s.T())
+	db.Settings.FeeWallet = ""
+
+	s.cleanup()
+	_, err := db.DB.Exec("INSERT INTO wallets (address, balance) VALUES ($1, 1000)", loaderWalletA)
+	assert.NoError(s.T(), err)
+
+	for _, transfer := range [][3]string{
+		{loaderWalletA, loaderWalletB, "300"},
+		{loaderWalletA, loaderWalletC, "200"},
+		{loaderWalletB, loaderWalletC, "100"},
+		{loaderWalletC, loaderWalletA, "50"},
+	} {
+		_, err := db.TransferTokens(transfer[0], transfer[1], transfer[2])
+		assert.NoError(s.T(), err)
+	}
+	s.fetches.Store(0)
+}
+
+// cleanup removes the suite's transfers and wallets
+func (s *WalletLoaderSuite) cleanup() {
+	_, err := db.DB.Exec("DELETE FROM transfers WHERE from_address LIKE '0x57%' OR to_address LIKE '0x57%'")
+	assert.NoError(s.T(), err)
+	_, err = db.DB.Exec("DELETE FROM wallets WHERE address LIKE '0x57%'")
+	assert.NoError(s.T(), err)
+}
+
+// query runs a GraphQL query and returns the response
+func (s *WalletLoaderSuite) query(query string) *graphQLResponse {
+	reqBody, _ := json.Marshal(graphQLRequest{Query: query})
+	resp, err := http.Post(s.server.URL, "application/json", bytes.NewBuffer(reqBody))
+	if !assert.NoError(s.T(), err) {
+		return &graphQLResponse{}
+	}
+	defer resp.Body.Close()
+
+	var result graphQLResponse
+	assert.NoError(s.T(), json.NewDecoder(resp.Body).Decode(&result))
+	return &result
+}
+
+// TestTransferWalletsBatched tests that the sender and receiver wallets of a list of transfers are fetched with one query
+func (s *WalletLoaderSuite) TestTransferWalletsBatched() {
+	result := s.query(`{ wallet(address: "` + loaderWalletA + `") {
+		transfers(limit: 10) { amount sender { address balance } receiver { address balance } }
+	} }`)
+	assert.Nil(s.T(), result.Errors)
+	assert.Equal(s.T(), int64(1), s.fetches.Load())
+
+	balances := map[string]string{loaderWalletA: "550", loaderWalletB: "200", loaderWalletC: "250"}
+	transfers := result.Data["wallet"].(map[string]interface{})["transfers"].([]interface{})
+	if assert.Len(s.T(), transfers, 3) {
+		for _, item := range transfers {
+			transfer := item.(map[string]interface{})
+			for _, side := range []string{"sender", "receiver"} {
+				wallet := transfer[side].(map[string]interface{})
+				assert.Equal(s.T(), balances[wallet["address"].(string)], wallet["balance"], side)
+			}
+		}
+		latest := transfers[0].(map[string]interface{})
+		assert.Equal(s.T(), loaderWalletC, latest["sender"].(map[string]interface{})["address"])
+		assert.Equal(s.T(), loaderWalletA, latest["receiver"].(map[string]interface{})["address"])
+	}
+}
+
+// TestTransferWithoutWalletsNotFetched tests that transfers not selecting their wallets cost no wallet query
+func (s *WalletLoaderSuite) TestTransferWithoutWalletsNotFetched() {
+	result := s.query(`{ wallet(address: "` + loaderWalletA + `") { transfers(limit: 10) { amount } } }`)
+	assert.Nil(s.T(), result.Errors)
+	assert.Equal(s.T(), int64(0), s.fetches.Load())
+}
+
+// Run the wallet loader test suite
+func TestWalletLoaderSuite(t *testing.T) {
+	suite.Run(t, new(WalletLoaderSuite))
+}
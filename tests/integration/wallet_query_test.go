@@ -0,0 +1,191 @@
+package integration
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"token-transfer-api/internal/db"
+	"token-transfer-api/internal/graph"
+	"token-transfer-api/pkg/graphql"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/suite"
+)
+
+const (
+	walletQueryKnown   = "0x4600000000000000000000000000000000000001"
+	walletQueryUnknown = "0x4600000000000000000000000000000000000002"
+)
+
+type WalletQuerySuite struct {
+	suite.Suite
+	server *httptest.Server
+}
+
+// SetupSuite initializes the test environment
+func (s *WalletQuerySuite) SetupSuite() {
+	setupDB(s.T())
+
+	s.server = httptest.NewServer(graphql.NewHandler())
+}
+
+// TearDownSuite cleans up the test environment
+func (s *WalletQuerySuite) TearDownSuite() {
+	s.server.Close()
+	s.cleanup()
+	db.CloseDB()
+}
+
+// SetupTest creates the known wallet with 1234 tokens, 34 of them reserved
+func (s *WalletQuerySuite) SetupTest() {
+	s.cleanup()
+	_, err := db.DB.Exec("INSERT INTO wallets (address, balance, reserved) VALUES ($1, 1234, 34)", walletQueryKnown)
+	assert.NoError(s.T(), err)
+}
+
+func (s *WalletQuerySuite) cleanup() {
+	_, err := db.DB.Exec("DELETE FROM transfers WHERE from_address LIKE '0x46%' OR to_address LIKE '0x46%'")
+	assert.NoError(s.T(), err)
+	_, err = db.DB.Exec("DELETE FROM wallets WHERE address LIKE '0x46%'")
+	assert.NoError(s.T(), err)
+}
+
+// execute posts a GraphQL request and returns the status and response
+func (s *WalletQuerySuite) execute(query string, variables map[string]interface{}) (int, *graphQLResponse) {
+	reqBody, _ := json.Marshal(graphQLRequest{Query: query, Variables: variables})
+	resp, err := http.Post(s.server.URL, "application/json", bytes.NewBuffer(reqBody))
+	assert.NoError(s.T(), err)
+	defer resp.Body.Close()
+
+	var result graphQLResponse
+	assert.NoError(s.T(), json.NewDecoder(resp.Body).Decode(&result))
+	return resp.StatusCode, &result
+}
+
+// TestKnownAddress tests that an existing wallet is returned with its stored balance
+func (s *WalletQuerySuite) TestKnownAddress() {
+	status, result := s.execute(`query($a: Address!) { wallet(address: $a) { address balance reserved status frozen } }`,
+		map[string]interface{}{"a": walletQueryKnown})
+	assert.Equal(s.T(), http.StatusOK, status)
+	assert.Nil(s.T(), result.Errors)
+	assert.Equal(s.T(), map[string]interface{}{
+		"address":  walletQueryKnown,
+		"balance":  "1234",
+		"reserved": "34",
+		"status":   "ACTIVE",
+		"frozen":   false,
+	}, result.Data["wallet"])
+}
+
+// TestUnknownAddress tests that an address never seen is null without an error, as a variable and as a literal
+func (s *WalletQuerySuite) TestUnknownAddress() {
+	status, result := s.execute(`query($a: Address!) { wallet(address: $a) { address balance } }`,
+		map[string]interface{}{"a": walletQueryUnknown})
+	assert.Equal(s.T(), http.StatusOK, status)
+	assert.Nil(s.T(), result.Errors)
+	assert.Contains(s.T(), result.Data, "wallet")
+	assert.Nil(s.T(), result.Data["wallet"])
+
+	status, result = s.execute(`{ wallet(address: "`+walletQueryUnknown+`") { balance } known: wallet(address: "`+walletQueryKnown+`") { balance } }`, nil)
+	assert.Equal(s.T(), http.StatusOK, status)
+	assert.Nil(s.T(), result.Errors)
+	assert.Nil(s.T(), result.Data["wallet"])
+	assert.Equal(s.T(), map[string]interface{}{"balance": "1234"}, result.Data["known"])
+}
+
+// TestMalformedAddress tests that malformed addresses fail validation instead of returning null
+func (s *WalletQuerySuite) TestMalformedAddress() {
+	status, result := s.execute(`{ wallet(address: "0x123") { balance } }`, nil)
+	assert.Equal(s.T(), http.StatusBadRequest, status)
+	assert.NotEmpty(s.T(), result.Errors)
+	assert.Nil(s.T(), result.Data)
+
+	status, result = s.execute(`query($a: Address!) { wallet(address: $a) { balance } }`,
+		map[string]interface{}{"a": "0xnonexistent"})
+	assert.Equal(s.T(), http.StatusBadRequest, status)
+	assert.NotEmpty(s.T(), result.Errors)
+	assert.Nil(s.T(), result.Data)
+}
+
+// TestHumanUnits tests that with TOKEN_DECIMALS wallet balances are shown in
+// the same human units as transfer results, or in base units when raw
+func (s *WalletQuerySuite) TestHumanUnits() {
+	s.T().Setenv("TOKEN_DECIMALS", "2")
+	server := httptest.NewServer(graphql.NewHandler())
+	defer server.Close()
+
+	reqBody, _ := json.Marshal(graphQLRequest{
+		Query:     `mutation($from: Address!, $to: Address!) { transfer(from_address: $from, to_address: $to, amount: "0.34") { balance raw_balance: balance(raw: true) } }`,
+		Variables: map[string]interface{}{"from": walletQueryKnown, "to": walletQueryUnknown},
+	})
+	resp, err := http.Post(server.URL, "application/json", bytes.NewBuffer(reqBody))
+	assert.NoError(s.T(), err)
+	var result graphQLResponse
+	assert.NoError(s.T(), json.NewDecoder(resp.Body).Decode(&result))
+	resp.Body.Close()
+	assert.Nil(s.T(), result.Errors)
+	assert.Equal(s.T(), map[string]interface{}{"balance": "12", "raw_balance": "1200"}, result.Data["transfer"])
+
+	reqBody, _ = json.Marshal(graphQLRequest{
+		Query:     `query($a: Address!) { wallet(address: $a) { balance reserved held_balance raw_balance: balance(raw: true) raw_reserved: reserved(raw: true) } }`,
+		Variables: map[string]interface{}{"a": walletQueryKnown},
+	})
+	resp, err = http.Post(server.URL, "application/json", bytes.NewBuffer(reqBody))
+	assert.NoError(s.T(), err)
+	result = graphQLResponse{}
+	assert.NoError(s.T(), json.NewDecoder(resp.Body).Decode(&result))
+	resp.Body.Close()
+	assert.Nil(s.T(), result.Errors)
+	assert.Equal(s.T(), map[string]interface{}{
+		"balance":      "12",
+		"reserved":     "0.34",
+		"held_balance": "0",
+		"raw_balance":  "1200",
+		"raw_reserved": "34",
+	}, result.Data["wallet"])
+}
+
+// TestHumanUnitTotals tests that with TOKEN_DECIMALS totals are shown in
+// the same human units as balances, or in base units when raw
+func (s *WalletQuerySuite) TestHumanUnitTotals() {
+	s.T().Setenv("TOKEN_DECIMALS", "2")
+	server := httptest.NewServer(graphql.NewHandler())
+	defer server.Close()
+
+	post := func(query string) graphQLResponse {
+		reqBody, _ := json.Marshal(graphQLRequest{
+			Query:     query,
+			Variables: map[string]interface{}{"from": walletQueryKnown, "to": walletQueryUnknown},
+		})
+		resp, err := http.Post(server.URL, "application/json", bytes.NewBuffer(reqBody))
+		assert.NoError(s.T(), err)
+		defer resp.Body.Close()
+		var result graphQLResponse
+		assert.NoError(s.T(), json.NewDecoder(resp.Body).Decode(&result))
+		assert.Nil(s.T(), result.Errors)
+		return result
+	}
+
+	post(`mutation($from: Address!, $to: Address!) { transfer(from_address: $from, to_address: $to, amount: "0.34") { balance } }`)
+	result := post(`query($from: String!, $to: String!) {
+		walletStats(address: $from) { total_sent raw_sent: total_sent(raw: true) }
+		neighbors(address: $to) { total raw_total: total(raw: true) }
+		totalSupply
+		raw_supply: totalSupply(raw: true)
+	}`)
+
+	assert.Equal(s.T(), map[string]interface{}{"total_sent": "0.34", "raw_sent": "34"}, result.Data["walletStats"])
+	assert.Equal(s.T(), []interface{}{map[string]interface{}{"total": "0.34", "raw_total": "34"}}, result.Data["neighbors"])
+
+	supply, err := db.GetTotalSupply()
+	assert.NoError(s.T(), err)
+	assert.Equal(s.T(), supply, result.Data["raw_supply"])
+	assert.Equal(s.T(), (&graph.Resolver{Decimals: 2}).FormatBalance(supply, false), result.Data["totalSupply"])
+}
+
+// Run the wallet query test suite
+func TestWalletQuerySuite(t *testing.T) {
+	suite.Run(t, new(WalletQuerySuite))
+}
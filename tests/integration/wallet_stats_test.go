@@ -0,0 +1,113 @@
+package integration
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"token-transfer-api/internal/db"
+	"token-transfer-api/pkg/graphql"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/suite"
+)
+
+const (
+	statsWallet = "0x3200000000000000000000000000000000000000"
+	statsPeerA  = "0x3200000000000000000000000000000000000001"
+	statsPeerB  = "0x3200000000000000000000000000000000000002"
+)
+
+type WalletStatsSuite struct {
+	suite.Suite
+	server *httptest.Server
+}
+
+// SetupSuite initializes the test environment
+func (s *WalletStatsSuite) SetupSuite() {
+	setupDB(s.T())
+
+	s.server = httptest.NewServer(graphql.NewHandler())
+}
+
+// TearDownSuite cleans up the test environment
+func (s *WalletStatsSuite) TearDownSuite() {
+	s.server.Close()
+	db.CloseDB()
+}
+
+// SetupTest seeds transfers in both directions around statsWallet
+func (s *WalletStatsSuite) SetupTest() {
+	_, err := db.DB.Exec("DELETE FROM transfers WHERE from_address LIKE '0x32%' OR to_address LIKE '0x32%'")
+	assert.NoError(s.T(), err)
+
+	for _, address := range []string{statsWallet, statsPeerA, statsPeerB} {
+		_, err = db.DB.Exec("INSERT INTO wallets (address, balance) VALUES ($1, 0) ON CONFLICT (address) DO NOTHING", address)
+		assert.NoError(s.T(), err)
+	}
+
+	s.recordTransfer(statsWallet, statsPeerA, "100")
+	s.recordTransfer(statsWallet, statsPeerB, "25")
+	s.recordTransfer(statsPeerA, statsWallet, "1000000000000000000000000000000")
+	s.recordTransfer(statsPeerB, statsWallet, "5")
+	s.recordTransfer(statsPeerB, statsWallet, "5")
+	// Does not involve statsWallet
+	s.recordTransfer(statsPeerA, statsPeerB, "999")
+}
+
+// recordTransfer inserts a transfer row without touching balances
+func (s *WalletStatsSuite) recordTransfer(from, to, amount string) {
+	_, err := db.DB.Exec("INSERT INTO transfers (from_address, to_address, amount) VALUES ($1, $2, $3)", from, to, amount)
+	assert.NoError(s.T(), err)
+}
+
+// TestSentAndReceived tests totals and counts for an address with transfers both ways
+func (s *WalletStatsSuite) TestSentAndReceived() {
+	stats, err := db.GetWalletStats(statsWallet)
+	assert.NoError(s.T(), err)
+
+	assert.Equal(s.T(), "125", stats.TotalSent)
+	assert.Equal(s.T(), "1000000000000000000000000000010", stats.TotalReceived)
+	assert.Equal(s.T(), int64(2), stats.TransferCountOut)
+	assert.Equal(s.T(), int64(3), stats.TransferCountIn)
+}
+
+// TestWalletStatsQuery tests the walletStats query over HTTP
+func (s *WalletStatsSuite) TestWalletStatsQuery() {
+	reqBody, _ := json.Marshal(graphQLRequest{
+		Query: `query($address: String!) { walletStats(address: $address) { total_sent total_received transfer_count_out transfer_count_in } }`,
+		Variables: map[string]interface{}{
+			"address": statsWallet,
+		},
+	})
+	resp, err := http.Post(s.server.URL, "application/json", bytes.NewBuffer(reqBody))
+	assert.NoError(s.T(), err)
+	defer resp.Body.Close()
+
+	var result graphQLResponse
+	assert.NoError(s.T(), json.NewDecoder(resp.Body).Decode(&result))
+	assert.Nil(s.T(), result.Errors)
+
+	stats := result.Data["walletStats"].(map[string]interface{})
+	assert.Equal(s.T(), "125", stats["total_sent"])
+	assert.Equal(s.T(), "1000000000000000000000000000010", stats["total_received"])
+	assert.Equal(s.T(), float64(2), stats["transfer_count_out"])
+	assert.Equal(s.T(), float64(3), stats["transfer_count_in"])
+}
+
+// TestNoTransfers tests that an address without transfers gets zeros
+func (s *WalletStatsSuite) TestNoTransfers() {
+	stats, err := db.GetWalletStats("0x3200000000000000000000000000000000000099")
+	assert.NoError(s.T(), err)
+
+	assert.Equal(s.T(), "0", stats.TotalSent)
+	assert.Equal(s.T(), "0", stats.TotalReceived)
+	assert.Equal(s.T(), int64(0), stats.TransferCountOut)
+	assert.Equal(s.T(), int64(0), stats.TransferCountIn)
+}
+
+// Run the wallet stats test suite
+func TestWalletStatsSuite(t *testing.T) {
+	suite.Run(t, new(WalletStatsSuite))
+}
@@ -0,0 +1,177 @@
+package integration
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"token-transfer-api/internal/db"
+	"token-transfer-api/pkg/graphql"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/suite"
+)
+
+const (
+	statusAdmin = "0x4300000000000000000000000000000000000000"
+	statusAlice = "0x4300000000000000000000000000000000000001"
+	statusBob   = "0x4300000000000000000000000000000000000002"
+	statusEmpty = "0x4300000000000000000000000000000000000003"
+)
+
+type WalletStatusSuite struct {
+	suite.Suite
+	server *httptest.Server
+}
+
+// SetupSuite initializes the test environment with a configured admin
+func (s *WalletStatusSuite) SetupSuite() {
+	setupDB(s.T())
+
+	s.T().Setenv("ADMIN_ADDRESS", statusAdmin)
+	s.server = httptest.NewServer(graphql.WithAuth(graphql.NewHandler(), callerAuth(statusAdmin, statusAlice)))
+}
+
+// TearDownSuite cleans up the test environment
+func (s *WalletStatusSuite) TearDownSuite() {
+	s.server.Close()
+	s.cleanup()
+	db.CloseDB()
+}
+
+// SetupTest leaves Alice and Bob active with 1000 tokens and an empty wallet
+func (s *WalletStatusSuite) SetupTest() {
+	s.cleanup()
+	for address, balance := range map[string]string{statusAlice: "1000", statusBob: "1000", statusEmpty: "0"} {
+		_, err := db.DB.Exec("INSERT INTO wallets (address, balance) VALUES ($1, $2)", address, balance)
+		assert.NoError(s.T(), err)
+	}
+}
+
+func (s *WalletStatusSuite) cleanup() {
+	_, err := db.DB.Exec("DELETE FROM transfers WHERE from_address LIKE '0x43%' OR to_address LIKE '0x43%'")
+	assert.NoError(s.T(), err)
+	_, err = db.DB.Exec("DELETE FROM wallets WHERE address LIKE '0x43%'")
+	assert.NoError(s.T(), err)
+}
+
+// setStatus changes a wallet's status directly
+func (s *WalletStatusSuite) setStatus(address, status string) {
+	_, err := db.SetWalletStatus(address, status)
+	assert.NoError(s.T(), err)
+}
+
+// execute sends a GraphQL request on behalf of caller
+func (s *WalletStatusSuite) execute(query string, variables map[string]interface{}, caller string) *graphQLResponse {
+	reqBody, _ := json.Marshal(graphQLRequest{Query: query, Variables: variables})
+	req, err := http.NewRequest(http.MethodPost, s.server.URL, bytes.NewBuffer(reqBody))
+	assert.NoError(s.T(), err)
+	req.Header.Set("Content-Type", "application/json")
+	authorize(req, caller)
+
+	resp, err := http.DefaultClient.Do(req)
+	assert.NoError(s.T(), err)
+	defer resp.Body.Close()
+
+	var result graphQLResponse
+	assert.NoError(s.T(), json.NewDecoder(resp.Body).Decode(&result))
+	return &result
+}
+
+// TestFrozenWalletCannotSendOrReceive tests both directions for a frozen wallet, on both transfer paths
+func (s *WalletStatusSuite) TestFrozenWalletCannotSendOrReceive() {
+	s.setStatus(statusAlice, db.WalletFrozen)
+
+	_, err := db.TransferTokens(statusAlice, statusBob, "100")
+	assert.ErrorIs(s.T(), err, db.ErrWalletFrozen)
+	_, err = db.TransferTokens(statusBob, statusAlice, "100")
+	assert.ErrorIs(s.T(), err, db.ErrWalletFrozen)
+	_, err = db.TransferTokensCTE(statusAlice, statusBob, "100")
+	assert.ErrorIs(s.T(), err, db.ErrWalletFrozen)
+	_, err = db.TransferTokensCTE(statusBob, statusAlice, "100")
+	assert.ErrorIs(s.T(), err, db.ErrWalletFrozen)
+	_, err = db.Mint(statusAlice, "100")
+	assert.ErrorIs(s.T(), err, db.ErrWalletFrozen)
+
+	for _, address := range []string{statusAlice, statusBob} {
+		wallet, err := db.GetWallet(address)
+		assert.NoError(s.T(), err)
+		assert.Equal(s.T(), "1000", wallet.Balance, address)
+	}
+
+	// Unfreezing lets the wallet transfer again
+	s.setStatus(statusAlice, db.WalletActive)
+	balance, err := db.TransferTokens(statusAlice, statusBob, "100")
+	assert.NoError(s.T(), err)
+	assert.Equal(s.T(), "900", balance)
+}
+
+// TestClosedWalletCannotSendOrReceive tests that a closed wallet is refused as sender and receiver
+func (s *WalletStatusSuite) TestClosedWalletCannotSendOrReceive() {
+	s.setStatus(statusEmpty, db.WalletClosed)
+
+	_, err := db.TransferTokens(statusAlice, statusEmpty, "100")
+	assert.ErrorIs(s.T(), err, db.ErrWalletClosed)
+	_, err = db.TransferTokensCTE(statusAlice, statusEmpty, "100")
+	assert.ErrorIs(s.T(), err, db.ErrWalletClosed)
+	_, err = db.TransferTokens(statusEmpty, statusAlice, "1")
+	assert.ErrorIs(s.T(), err, db.ErrWalletClosed)
+
+	wallet, err := db.GetWallet(statusEmpty)
+	assert.NoError(s.T(), err)
+	assert.Equal(s.T(), "0", wallet.Balance)
+	assert.Equal(s.T(), db.WalletClosed, wallet.Status)
+}
+
+// TestCloseRequiresZeroBalance tests that a wallet holding tokens cannot be closed until it is emptied
+func (s *WalletStatusSuite) TestCloseRequiresZeroBalance() {
+	_, err := db.SetWalletStatus(statusAlice, db.WalletClosed)
+	assert.ErrorIs(s.T(), err, db.ErrWalletNotEmpty)
+
+	wallet, err := db.GetWallet(statusAlice)
+	assert.NoError(s.T(), err)
+	assert.Equal(s.T(), db.WalletActive, wallet.Status)
+
+	_, err = db.TransferTokens(statusAlice, statusBob, "1000")
+	assert.NoError(s.T(), err)
+	wallet, err = db.SetWalletStatus(statusAlice, db.WalletClosed)
+	assert.NoError(s.T(), err)
+	assert.Equal(s.T(), db.WalletClosed, wallet.Status)
+}
+
+// TestSetStatusOfUnknownWallet tests that only existing wallets get a status
+func (s *WalletStatusSuite) TestSetStatusOfUnknownWallet() {
+	_, err := db.SetWalletStatus("0x43000000000000000000000000000000000000ff", db.WalletFrozen)
+	assert.ErrorIs(s.T(), err, db.ErrWalletNotFound)
+}
+
+// TestSetWalletStatusMutation tests the admin-only mutation and the status field
+func (s *WalletStatusSuite) TestSetWalletStatusMutation() {
+	const mutation = `mutation($a: Address!) { setWalletStatus(address: $a, status: FROZEN) { address status } }`
+	vars := map[string]interface{}{"a": statusBob}
+
+	result := s.execute(mutation, vars, statusAlice)
+	if assert.NotNil(s.T(), result.Errors) {
+		assert.Equal(s.T(), "UNAUTHORIZED", result.Errors[0]["extensions"].(map[string]interface{})["code"])
+	}
+
+	result = s.execute(mutation, vars, statusAdmin)
+	assert.Nil(s.T(), result.Errors)
+	assert.Equal(s.T(), map[string]interface{}{"address": statusBob, "status": "FROZEN"}, result.Data["setWalletStatus"])
+
+	result = s.execute(`mutation($from: Address!, $to: Address!) { transfer(from_address: $from, to_address: $to, amount: "1") { balance } }`,
+		map[string]interface{}{"from": statusAlice, "to": statusBob}, "")
+	if assert.NotNil(s.T(), result.Errors) {
+		assert.Equal(s.T(), "WALLET_FROZEN", result.Errors[0]["extensions"].(map[string]interface{})["code"])
+	}
+
+	result = s.execute(`query($a: Address!) { wallet(address: $a) { status } }`, vars, "")
+	assert.Nil(s.T(), result.Errors)
+	assert.Equal(s.T(), map[string]interface{}{"status": "FROZEN"}, result.Data["wallet"])
+}
+
+// Run the wallet status test suite
+func TestWalletStatusSuite(t *testing.T) {
+	suite.Run(t, new(WalletStatusSuite))
+}
@@ -0,0 +1,131 @@
+package integration
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"token-transfer-api/internal/db"
+	"token-transfer-api/pkg/graphql"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/suite"
+)
+
+const (
+	nestedWalletA = "0x5500000000000000000000000000000000000001"
+	nestedWalletB = "0x5500000000000000000000000000000000000002"
+	nestedWalletC = "0x5500000000000000000000000000000000000003"
+)
+
+type WalletTransfersSuite struct {
+	suite.Suite
+	server *httptest.Server
+}
+
+// SetupSuite initializes the database connection and the GraphQL server
+func (s *WalletTransfersSuite) SetupSuite() {
+	setupDB(s.T())
+	restoreSettings(s.T())
+
+	s.T().Setenv("TOKEN_DECIMALS", "0")
+	s.server = httptest.NewServer(graphql.NewHandler())
+}
+
+// TearDownSuite closes the server and the database connection
+func (s *WalletTransfersSuite) TearDownSuite() {
+	s.cleanup()
+	s.server.Close()
+	db.CloseDB()
+}
+
+// SetupTest gives wallet A 1000 tokens, without fees, and sends three
+// transfers: A to B, B to A and C to B, which does not involve A
+func (s *WalletTransfersSuite) SetupTest() {
+	restoreSettings(s.T())
+	db.Settings.FeeWallet = ""
+
+	s.cleanup()
+	_, err := db.DB.Exec("INSERT INTO wallets (address, balance) VALUES ($1, 1000), ($2, 0), ($3, 100)",
+		nestedWalletA, nestedWalletB, nestedWalletC)
+	assert.NoError(s.T(), err)
+
+	for _, transfer := range [][3]string{
+		{nestedWalletA, nestedWalletB, "300"},
+		{nestedWalletB, nestedWalletA, "50"},
+		{nestedWalletC, nestedWalletB, "10"},
+	} {
+		_, err := db.TransferTokens(transfer[0], transfer[1], transfer[2])
+		assert.NoError(s.T(), err)
+	}
+}
+
+// cleanup removes the suite's transfers and wallets
+func (s *WalletTransfersSuite) cleanup() {
+	_, err := db.DB.Exec("DELETE FROM transfers WHERE from_address LIKE '0x55%' OR to_address LIKE '0x55%'")
+	assert.NoError(s.T(), err)
+	_, err = db.DB.Exec("DELETE FROM wallets WHERE address LIKE '0x55%'")
+	assert.NoError(s.T(), err)
+}
+
+// query runs a GraphQL query and returns the response
+func (s *WalletTransfersSuite) query(query string) *graphQLResponse {
+	reqBody, _ := json.Marshal(graphQLRequest{Query: query})
+	resp, err := http.Post(s.server.URL, "application/json", bytes.NewBuffer(reqBody))
+	if !assert.NoError(s.T(), err) {
+		return &graphQLResponse{}
+	}
+	defer resp.Body.Close()
+
+	var result graphQLResponse
+	assert.NoError(s.T(), json.NewDecoder(resp.Body).Decode(&result))
+	return &result
+}
+
+// TestWalletWithTransfers tests that one query returns the wallet's balance and its latest transfers, newest first
+func (s *WalletTransfersSuite) TestWalletWithTransfers() {
+	result := s.query(`{ wallet(address: "` + nestedWalletA + `") {
+		balance
+		transfers(limit: 10) { from_address to_address amount token }
+	} }`)
+	assert.Nil(s.T(), result.Errors)
+
+	wallet := result.Data["wallet"].(map[string]interface{})
+	assert.Equal(s.T(), "750", wallet["balance"])
+
+	transfers := wallet["transfers"].([]interface{})
+	if assert.Len(s.T(), transfers, 2) {
+		latest := transfers[0].(map[string]interface{})
+		assert.Equal(s.T(), nestedWalletB, latest["from_address"])
+		assert.Equal(s.T(), nestedWalletA, latest["to_address"])
+		assert.Equal(s.T(), "50", latest["amount"])
+		assert.Equal(s.T(), db.Settings.PrimaryToken, latest["token"])
+
+		first := transfers[1].(map[string]interface{})
+		assert.Equal(s.T(), nestedWalletA, first["from_address"])
+		assert.Equal(s.T(), "300", first["amount"])
+	}
+}
+
+// TestWalletTransfersLimit tests that limit caps the number of transfers returned
+func (s *WalletTransfersSuite) TestWalletTransfersLimit() {
+	result := s.query(`{ wallet(address: "` + nestedWalletB + `") { transfers(limit: 2) { from_address amount } } }`)
+	assert.Nil(s.T(), result.Errors)
+
+	transfers := result.Data["wallet"].(map[string]interface{})["transfers"].([]interface{})
+	if assert.Len(s.T(), transfers, 2) {
+		assert.Equal(s.T(), nestedWalletC, transfers[0].(map[string]interface{})["from_address"])
+		assert.Equal(s.T(), nestedWalletB, transfers[1].(map[string]interface{})["from_address"])
+	}
+
+	result = s.query(`{ wallet(address: "` + nestedWalletB + `") { transfers(limit: -1) { id } } }`)
+	if assert.NotEmpty(s.T(), result.Errors) {
+		assert.Equal(s.T(), "INVALID_PAGINATION", result.Errors[0]["extensions"].(map[string]interface{})["code"])
+	}
+}
+
+// Run the wallet transfers test suite
+func TestWalletTransfersSuite(t *testing.T) {
+	suite.Run(t, new(WalletTransfersSuite))
+}
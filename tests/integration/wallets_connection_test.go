@@ -0,0 +1,98 @@
+package integration
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"token-transfer-api/internal/db"
+	"token-transfer-api/pkg/graphql"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/suite"
+)
+
+type WalletsConnectionSuite struct {
+	suite.Suite
+	server *httptest.Server
+}
+
+// SetupSuite initializes the test environment
+func (s *WalletsConnectionSuite) SetupSuite() {
+	setupDB(s.T())
+
+	// Setup GraphQL handler
+	handler := graphql.NewHandler()
+	s.server = httptest.NewServer(handler)
+}
+
+// TearDownSuite cleans up the test environment
+func (s *WalletsConnectionSuite) TearDownSuite() {
+	s.server.Close()
+	db.CloseDB()
+}
+
+// SetupTest makes sure there are more wallets than fit on one page
+func (s *WalletsConnectionSuite) SetupTest() {
+	for i := 0; i < 12; i++ {
+		_, err := db.DB.Exec("INSERT INTO wallets (address, balance) VALUES ($1, 1) ON CONFLICT (address) DO NOTHING",
+			fmt.Sprintf("0x22%038x", i))
+		assert.NoError(s.T(), err)
+	}
+}
+
+// queryConnection runs walletsConnection with the given page
+func (s *WalletsConnectionSuite) queryConnection(limit, offset int) map[string]interface{} {
+	reqBody, _ := json.Marshal(graphQLRequest{
+		Query: `query($limit: Int, $offset: Int) {
+			walletsConnection(limit: $limit, offset: $offset) {
+				nodes { address }
+				totalCount
+				pageInfo { hasNextPage }
+			}
+		}`,
+		Variables: map[string]interface{}{"limit": limit, "offset": offset},
+	})
+	resp, err := http.Post(s.server.URL, "application/json", bytes.NewBuffer(reqBody))
+	assert.NoError(s.T(), err)
+	defer resp.Body.Close()
+
+	var result graphQLResponse
+	assert.NoError(s.T(), json.NewDecoder(resp.Body).Decode(&result))
+	assert.Nil(s.T(), result.Errors)
+	return result.Data["walletsConnection"].(map[string]interface{})
+}
+
+// TestTotalCountCoversAllWallets tests that totalCount is the full set while nodes honor the limit
+func (s *WalletsConnectionSuite) TestTotalCountCoversAllWallets() {
+	count, err := db.GetWalletCount()
+	assert.NoError(s.T(), err)
+
+	page := s.queryConnection(5, 0)
+	assert.Len(s.T(), page["nodes"], 5)
+	assert.Equal(s.T(), float64(count), page["totalCount"])
+	assert.Equal(s.T(), true, page["pageInfo"].(map[string]interface{})["hasNextPage"])
+}
+
+// TestLastPage tests that the final page reports no next page and keeps the count
+func (s *WalletsConnectionSuite) TestLastPage() {
+	count, err := db.GetWalletCount()
+	assert.NoError(s.T(), err)
+
+	page := s.queryConnection(5, int(count)-2)
+	assert.Len(s.T(), page["nodes"], 2)
+	assert.Equal(s.T(), float64(count), page["totalCount"])
+	assert.Equal(s.T(), false, page["pageInfo"].(map[string]interface{})["hasNextPage"])
+
+	// Past the end there are no rows, but the count is still reported
+	page = s.queryConnection(5, int(count)+10)
+	assert.Len(s.T(), page["nodes"], 0)
+	assert.Equal(s.T(), float64(count), page["totalCount"])
+}
+
+// Run the wallets connection test suite
+func TestWalletsConnectionSuite(t *testing.T) {
+	suite.Run(t, new(WalletsConnectionSuite))
+}
@@ -0,0 +1,95 @@
+package integration
+
+import (
+	"testing"
+	"token-transfer-api/internal/db"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/suite"
+)
+
+const zeroAddressReceiver = "0x4500000000000000000000000000000000000001"
+
+type ZeroAddressSuite struct {
+	suite.Suite
+	zeroBalance string
+}
+
+// SetupSuite initializes the database connection
+func (s *ZeroAddressSuite) SetupSuite() {
+	setupDB(s.T())
+	restoreSettings(s.T())
+}
+
+// TearDownSuite closes the database connection
+func (s *ZeroAddressSuite) TearDownSuite() {
+	s.cleanup()
+	db.CloseDB()
+}
+
+// SetupTest makes sure the zero address holds tokens and remembers its balance
+func (s *ZeroAddressSuite) SetupTest() {
+	s.cleanup()
+	restoreSettings(s.T())
+
+	_, err := db.DB.Exec("INSERT INTO wallets (address, balance) VALUES ($1, 1000) ON CONFLICT (address) DO NOTHING", db.ZeroAddress)
+	assert.NoError(s.T(), err)
+	err = db.DB.QueryRow("SELECT balance::text FROM wallets WHERE address = $1", db.ZeroAddress).Scan(&s.zeroBalance)
+	assert.NoError(s.T(), err)
+}
+
+// TearDownTest gives the zero address back what the test sent
+func (s *ZeroAddressSuite) TearDownTest() {
+	_, err := db.DB.Exec("UPDATE wallets SET balance = $1 WHERE address = $2", s.zeroBalance, db.ZeroAddress)
+	assert.NoError(s.T(), err)
+}
+
+func (s *ZeroAddressSuite) cleanup() {
+	_, err := db.DB.Exec("DELETE FROM transfers WHERE from_address LIKE '0x45%' OR to_address LIKE '0x45%'")
+	assert.NoError(s.T(), err)
+	_, err = db.DB.Exec("DELETE FROM wallets WHERE address LIKE '0x45%'")
+	assert.NoError(s.T(), err)
+}
+
+// TestSpendableByDefault tests that the zero address sends like any wallet unless configured otherwise
+func (s *ZeroAddressSuite) TestSpendableByDefault() {
+	db.Settings.ZeroAddressReserved = false
+
+	_, err := db.TransferTokens(db.ZeroAddress, zeroAddressReceiver, "10")
+	assert.NoError(s.T(), err)
+	_, err = db.TransferTokensCTE(db.ZeroAddress, zeroAddressReceiver, "10")
+	assert.NoError(s.T(), err)
+
+	wallet, err := db.GetWallet(zeroAddressReceiver)
+	assert.NoError(s.T(), err)
+	assert.Equal(s.T(), "20", wallet.Balance)
+}
+
+// TestReservedRejectsTransfers tests that a reserved zero address can only issue tokens through Mint
+func (s *ZeroAddressSuite) TestReservedRejectsTransfers() {
+	db.Settings.ZeroAddressReserved = true
+
+	_, err := db.TransferTokens(db.ZeroAddress, zeroAddressReceiver, "10")
+	assert.ErrorIs(s.T(), err, db.ErrReservedAddress)
+	_, err = db.TransferTokensCTE(db.ZeroAddress, zeroAddressReceiver, "10")
+	assert.ErrorIs(s.T(), err, db.ErrReservedAddress)
+
+	var balance string
+	err = db.DB.QueryRow("SELECT balance::text FROM wallets WHERE address = $1", db.ZeroAddress).Scan(&balance)
+	assert.NoError(s.T(), err)
+	assert.Equal(s.T(), s.zeroBalance, balance)
+
+	minted, err := db.Mint(zeroAddressReceiver, "10")
+	if assert.NoError(s.T(), err) {
+		assert.Equal(s.T(), "10", minted.Balance)
+	}
+
+	// Only sending is refused; the zero address still receives
+	_, err = db.TransferTokens(zeroAddressReceiver, db.ZeroAddress, "5")
+	assert.NoError(s.T(), err)
+}
+
+// Run the zero address test suite
+func TestZeroAddressSuite(t *testing.T) {
+	suite.Run(t, new(ZeroAddressSuite))
+}
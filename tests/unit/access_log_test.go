@@ -0,0 +1,98 @@
+package unit
+
+import (
+	"bytes"
+	"log"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strings"
+	"testing"
+	"token-transfer-api/pkg/graphql"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// logRequest serves one GraphQL request through WithAccessLog and returns the response and the log
+func logRequest(t *testing.T, cfg graphql.AccessLogConfig, body string) (*httptest.ResponseRecorder, string) {
+	var logs bytes.Buffer
+	log.SetOutput(&logs)
+	defer log.SetOutput(os.Stderr)
+
+	req := httptest.NewRequest(http.MethodPost, "/", strings.NewReader(body))
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("Authorization", "Bearer very-secret")
+	rec := httptest.NewRecorder()
+	graphql.WithAccessLog(graphql.NewHandler(), cfg).ServeHTTP(rec, req)
+	return rec, logs.String()
+}
+
+// TestAccessLogFromEnv tests the ACCESS_LOG settings
+func TestAccessLogFromEnv(t *testing.T) {
+	cfg, err := graphql.AccessLogConfigFromEnv()
+	assert.NoError(t, err)
+	assert.Equal(t, graphql.AccessLogConfig{Enabled: true, MaxBodyBytes: graphql.DefaultAccessLogBodyBytes}, cfg)
+
+	t.Setenv("ACCESS_LOG", "false")
+	t.Setenv("ACCESS_LOG_BODIES", "true")
+	t.Setenv("ACCESS_LOG_BODY_BYTES", "100")
+	cfg, err = graphql.AccessLogConfigFromEnv()
+	assert.NoError(t, err)
+	assert.Equal(t, graphql.AccessLogConfig{Bodies: true, MaxBodyBytes: 100}, cfg)
+
+	for name, v := range map[string]string{"ACCESS_LOG": "maybe", "ACCESS_LOG_BODIES": "all", "ACCESS_LOG_BODY_BYTES": "0"} {
+		t.Setenv(name, v)
+		_, err = graphql.AccessLogConfigFromEnv()
+		assert.Error(t, err, name)
+		t.Setenv(name, "")
+	}
+}
+
+// TestAccessLogLine tests that each request is logged with its operation and status, without bodies by default
+func TestAccessLogLine(t *testing.T) {
+	rec, logs := logRequest(t, graphql.AccessLogConfig{Enabled: true, MaxBodyBytes: 1024},
+		`{"query": "query Ping { __typename }"}`)
+	assert.Equal(t, http.StatusOK, rec.Code)
+
+	assert.Contains(t, logs, "Access POST / operation=Ping status=200")
+	assert.Contains(t, logs, "request_id="+rec.Header().Get(graphql.RequestIDHeader))
+	assert.NotContains(t, logs, "__typename")
+	assert.NotContains(t, logs, "very-secret")
+
+	// Failed requests are logged with their status too
+	rec, logs = logRequest(t, graphql.AccessLogConfig{Enabled: true, MaxBodyBytes: 1024}, `{not json`)
+	assert.Equal(t, http.StatusBadRequest, rec.Code)
+	assert.Contains(t, logs, "operation=- status=400")
+}
+
+// TestAccessLogBodies tests that bodies are captured only when asked for, cut to the limit and without credentials
+func TestAccessLogBodies(t *testing.T) {
+	_, logs := logRequest(t, graphql.AccessLogConfig{Enabled: true, Bodies: true, MaxBodyBytes: 1024},
+		`{"query": "query Ping { __typename }", "operationName": "Ping"}`)
+	assert.Contains(t, logs, "operation=Ping status=200")
+	assert.Contains(t, logs, `__typename`)
+	assert.Contains(t, logs, `Query`)
+	assert.Contains(t, logs, "[REDACTED]")
+	assert.NotContains(t, logs, "very-secret")
+
+	_, logs = logRequest(t, graphql.AccessLogConfig{Enabled: true, Bodies: true, MaxBodyBytes: 10},
+		`{"query": "query Ping { __typename }"}`)
+	assert.Contains(t, logs, "...(truncated)")
+	assert.NotContains(t, logs, "__typename }")
+}
+
+// TestAccessLogDisabled tests that nothing is logged when the access log is off
+func TestAccessLogDisabled(t *testing.T) {
+	rec, logs := logRequest(t, graphql.AccessLogConfig{}, `{"query": "{ __typename }"}`)
+	assert.Equal(t, http.StatusOK, rec.Code)
+	assert.Empty(t, logs)
+}
+
+// TestAccessLogBodyLimit tests that a body over MAX_REQUEST_BYTES is still refused by the handler and logged
+func TestAccessLogBodyLimit(t *testing.T) {
+	t.Setenv("MAX_REQUEST_BYTES", "64")
+	rec, logs := logRequest(t, graphql.AccessLogConfig{Enabled: true, MaxBodyBytes: 1024},
+		`{"query": "query Ping { __typename }", "variables": {"padding": "`+strings.Repeat("x", 100)+`"}}`)
+	assert.Equal(t, http.StatusRequestEntityTooLarge, rec.Code)
+	assert.Contains(t, logs, "operation=- status=413")
+}
@@ -0,0 +1,44 @@
+package unit
+
+import (
+	"context"
+	"testing"
+	"token-transfer-api/internal/db"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// TestNormalizeAddressCaseInsensitive tests that addresses are lowercased only in case-insensitive mode
+func TestNormalizeAddressCaseInsensitive(t *testing.T) {
+	sensitive := db.Config{}
+	assert.Equal(t, "0xAbC", sensitive.NormalizeAddress("0xAbC"))
+
+	insensitive := db.Config{CaseInsensitiveAddresses: true}
+	assert.Equal(t, "0xabc", insensitive.NormalizeAddress("0xAbC"))
+}
+
+// TestCaseVariantSelfTransferRejected tests that the self-transfer check runs after normalization
+func TestCaseVariantSelfTransferRejected(t *testing.T) {
+	saved := db.Settings
+	defer func() { db.Settings = saved }()
+	db.Settings = db.Config{CaseInsensitiveAddresses: true}
+
+	_, err := db.ExecuteTransfer(context.Background(),
+		"0xAbCdEf0000000000000000000000000000000001",
+		"0xabcdef0000000000000000000000000000000001",
+		"100", "", nil, "")
+	assert.ErrorIs(t, err, db.ErrSelfTransfer)
+}
+
+// TestSelfTransferRejected tests that a transfer to the sender's own address is rejected
+func TestSelfTransferRejected(t *testing.T) {
+	saved := db.Settings
+	defer func() { db.Settings = saved }()
+	db.Settings = db.Config{}
+
+	_, err := db.ExecuteTransfer(context.Background(),
+		"0xabcdef0000000000000000000000000000000001",
+		"0xabcdef0000000000000000000000000000000001",
+		"100", "", nil, "")
+	assert.ErrorIs(t, err, db.ErrSelfTransfer)
+}
@@ -0,0 +1,42 @@
+package unit
+
+import (
+	"context"
+	"testing"
+	"token-transfer-api/internal/db"
+	"token-transfer-api/internal/graph"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// TestValidAddress tests the accepted address format
+func TestValidAddress(t *testing.T) {
+	assert.True(t, db.ValidAddress("0x0000000000000000000000000000000000000000"))
+	assert.True(t, db.ValidAddress("0xAbCdEf0123456789abcdef0123456789ABCDEF01"))
+
+	assert.False(t, db.ValidAddress(""))
+	assert.False(t, db.ValidAddress("0xnonexistent"))
+	assert.False(t, db.ValidAddress("0000000000000000000000000000000000000000"))
+	assert.False(t, db.ValidAddress("0x00000000000000000000000000000000000000000"))
+	assert.False(t, db.ValidAddress("0x000000000000000000000000000000000000000g"))
+}
+
+// TestWalletOrZeroRejectsJunk tests that malformed addresses error before any lookup
+func TestWalletOrZeroRejectsJunk(t *testing.T) {
+	resolver := &graph.Resolver{}
+
+	wallet, err := resolver.GetWalletOrZero(context.Background(), "not-an-address")
+	assert.Nil(t, wallet)
+	assert.ErrorIs(t, err, db.ErrInvalidAddress)
+}
+
+// TestGetWalletRejectsJunk tests that the wallet lookup validates the address before the database is asked
+func TestGetWalletRejectsJunk(t *testing.T) {
+	resolver := &graph.Resolver{}
+
+	for _, address := range []string{"", "0x123", "not-an-address"} {
+		wallet, err := resolver.GetWallet(context.Background(), address)
+		assert.Nil(t, wallet, address)
+		assert.ErrorIs(t, err, db.ErrInvalidAddress, address)
+	}
+}
@@ -0,0 +1,76 @@
+package unit
+
+import (
+	"context"
+	"math/big"
+	"testing"
+	"token-transfer-api/internal/db"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// TestAmountAllowedUnrestrictedByDefault tests that an empty configuration permits any amount
+func TestAmountAllowedUnrestrictedByDefault(t *testing.T) {
+	cfg := db.Config{}
+
+	assert.True(t, cfg.AmountAllowed(big.NewInt(1)))
+	assert.True(t, cfg.AmountAllowed(big.NewInt(123456789)))
+}
+
+// TestAmountAllowlist tests that only listed amounts pass an allowlist
+func TestAmountAllowlist(t *testing.T) {
+	t.Setenv("TRANSFER_AMOUNT_ALLOWLIST", "100, 250,0500")
+	cfg, err := db.LoadConfig()
+	assert.NoError(t, err)
+
+	assert.True(t, cfg.AmountAllowed(big.NewInt(100)))
+	assert.True(t, cfg.AmountAllowed(big.NewInt(500)))
+	assert.False(t, cfg.AmountAllowed(big.NewInt(101)))
+}
+
+// TestAmountDenylist tests that listed amounts are refused
+func TestAmountDenylist(t *testing.T) {
+	t.Setenv("TRANSFER_AMOUNT_DENYLIST", "13")
+	cfg, err := db.LoadConfig()
+	assert.NoError(t, err)
+
+	assert.False(t, cfg.AmountAllowed(big.NewInt(13)))
+	assert.True(t, cfg.AmountAllowed(big.NewInt(14)))
+}
+
+// TestAmountListRejectsInvalidEntries tests that malformed lists fail to load
+func TestAmountListRejectsInvalidEntries(t *testing.T) {
+	t.Setenv("TRANSFER_AMOUNT_ALLOWLIST", "100,abc")
+	_, err := db.LoadConfig()
+	assert.Error(t, err)
+}
+
+// TestTransferOutsideAllowlistRejected tests that TransferTokens refuses unlisted amounts
+func TestTransferOutsideAllowlistRejected(t *testing.T) {
+	saved := db.Settings
+	defer func() { db.Settings = saved }()
+	db.Settings = db.Config{AllowedAmounts: map[string]bool{"100": true}}
+
+	_, err := db.ExecuteTransfer(context.Background(),
+		"0x2400000000000000000000000000000000000001",
+		"0x2400000000000000000000000000000000000002",
+		"99", "", nil, "")
+	assert.ErrorIs(t, err, db.ErrAmountNotAllowed)
+}
+
+// TestDailyLimitConfig tests that TRANSFER_DAILY_LIMIT is parsed and 0 disables it
+func TestDailyLimitConfig(t *testing.T) {
+	t.Setenv("TRANSFER_DAILY_LIMIT", "1000")
+	cfg, err := db.LoadConfig()
+	assert.NoError(t, err)
+	assert.Equal(t, "1000", cfg.DailyLimit.String())
+
+	t.Setenv("TRANSFER_DAILY_LIMIT", "0")
+	cfg, err = db.LoadConfig()
+	assert.NoError(t, err)
+	assert.Nil(t, cfg.DailyLimit)
+
+	t.Setenv("TRANSFER_DAILY_LIMIT", "-5")
+	_, err = db.LoadConfig()
+	assert.Error(t, err)
+}
@@ -0,0 +1,99 @@
+package unit
+
+import (
+	"testing"
+	"token-transfer-api/internal/amount"
+	"token-transfer-api/internal/db"
+	"token-transfer-api/internal/graph"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// TestParseAmount tests converting human amounts to base units
+func TestParseAmount(t *testing.T) {
+	cases := []struct {
+		human    string
+		decimals int
+		base     string
+	}{
+		{"1.5", 18, "1500000000000000000"},
+		{"0.000000000000000001", 18, "1"},
+		{"100", 18, "100000000000000000000"},
+		{"100", 0, "100"},
+		{"1.50", 1, "15"},
+		{"2.0", 0, "2"},
+		{"0.07", 2, "7"},
+		{"0", 6, "0"},
+	}
+	for _, c := range cases {
+		base, err := amount.Parse(c.human, c.decimals)
+		assert.NoError(t, err, c.human)
+		assert.Equal(t, c.base, base, c.human)
+	}
+}
+
+// TestParseAmountSyntax tests that anything but a plain decimal number is rejected
+func TestParseAmountSyntax(t *testing.T) {
+	for _, human := range []string{"", ".", ".5", "1.", "-1", "+1", "1e3", "1.2.3", "abc", " 1", "007", "00.5"} {
+		_, err := amount.Parse(human, 18)
+		assert.ErrorIs(t, err, amount.ErrSyntax, human)
+	}
+}
+
+// TestParseAmountTooPrecise tests that extra fractional digits are rejected rather than rounded
+func TestParseAmountTooPrecise(t *testing.T) {
+	_, err := amount.Parse("1.05", 1)
+	assert.ErrorIs(t, err, amount.ErrTooPrecise)
+
+	_, err = amount.Parse("0.5", 0)
+	assert.ErrorIs(t, err, amount.ErrTooPrecise)
+
+	_, err = amount.Parse("0.0000000000000000001", 18)
+	assert.ErrorIs(t, err, amount.ErrTooPrecise)
+}
+
+// TestParseBaseCanonical tests that base-unit amounts must be canonical positive integers
+func TestParseBaseCanonical(t *testing.T) {
+	v, err := amount.ParseBase("123456789012345678901234567890")
+	assert.NoError(t, err)
+	assert.Equal(t, "123456789012345678901234567890", v.String())
+
+	for _, s := range []string{"007", "+5", " 5", "5 ", "1e3", "0", "", "-5", "5.0"} {
+		_, err := amount.ParseBase(s)
+		assert.ErrorIs(t, err, amount.ErrSyntax, s)
+	}
+}
+
+// TestTransferRejectsNonCanonicalAmount tests that db transfers reject loose amount spellings before touching the database
+func TestTransferRejectsNonCanonicalAmount(t *testing.T) {
+	for _, s := range []string{"007", "+5", " 5", "1e3"} {
+		_, err := db.TransferTokens(
+			"0xabcdef0000000000000000000000000000000001",
+			"0xabcdef0000000000000000000000000000000002",
+			s)
+		assert.ErrorIs(t, err, db.ErrInvalidAmount, s)
+	}
+}
+
+// TestFormatAmount tests rendering base units in human units
+func TestFormatAmount(t *testing.T) {
+	assert.Equal(t, "1.5", amount.Format("1500000000000000000", 18))
+	assert.Equal(t, "0.01", amount.Format("1", 2))
+	assert.Equal(t, "42", amount.Format("42", 0))
+	assert.Equal(t, "3", amount.Format("300", 2))
+}
+
+// TestResolverParseAmountErrors tests that parse failures map to coded app errors
+func TestResolverParseAmountErrors(t *testing.T) {
+	resolver := &graph.Resolver{Decimals: 2}
+
+	base, err := resolver.ParseAmount("1.25")
+	assert.NoError(t, err)
+	assert.Equal(t, "125", base)
+
+	_, err = resolver.ParseAmount("1.255")
+	assert.ErrorIs(t, err, db.ErrAmountTooPrecise)
+
+	_, err = resolver.ParseAmount("-1")
+	assert.ErrorIs(t, err, db.ErrInvalidAmount)
+}
@@ -0,0 +1,124 @@
+package unit
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"token-transfer-api/pkg/graphql"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// authRequest sends query through WithAuth and reports the status and whether the wrapped handler ran
+func authRequest(t *testing.T, cfg graphql.AuthConfig, query, token string) (int, bool) {
+	reached := false
+	next := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		// The body must still be readable after the middleware inspected it
+		var req graphql.GraphQLRequest
+		assert.NoError(t, json.NewDecoder(r.Body).Decode(&req))
+		assert.Equal(t, query, req.Query)
+		reached = true
+	})
+
+	body, _ := json.Marshal(graphql.GraphQLRequest{Query: query})
+	req := httptest.NewRequest(http.MethodPost, "/", bytes.NewBuffer(body))
+	if token != "" {
+		req.Header.Set("Authorization", "Bearer "+token)
+	}
+
+	rec := httptest.NewRecorder()
+	graphql.WithAuth(next, cfg).ServeHTTP(rec, req)
+	return rec.Code, reached
+}
+
+// TestAuthRejectsAnonymousMutation tests that mutations without a valid key get a 401
+func TestAuthRejectsAnonymousMutation(t *testing.T) {
+	cfg := graphql.AuthConfig{APIKeys: []string{"k1", "k2"}, PublicQueries: true}
+	mutation := `mutation { transfer(from_address: "a", to_address: "b", amount: "1") { balance } }`
+
+	status, reached := authRequest(t, cfg, mutation, "")
+	assert.Equal(t, http.StatusUnauthorized, status)
+	assert.False(t, reached)
+
+	status, reached = authRequest(t, cfg, mutation, "wrong")
+	assert.Equal(t, http.StatusUnauthorized, status)
+	assert.False(t, reached)
+
+	// A mutation hidden behind a query in the same document still needs a key
+	status, reached = authRequest(t, cfg, `query A { totalSupply } `+mutation, "")
+	assert.Equal(t, http.StatusUnauthorized, status)
+	assert.False(t, reached)
+}
+
+// TestAuthAcceptsValidKey tests that any configured key authorizes a mutation
+func TestAuthAcceptsValidKey(t *testing.T) {
+	cfg := graphql.AuthConfig{APIKeys: []string{"k1", "k2"}, PublicQueries: true}
+
+	_, reached := authRequest(t, cfg, `mutation { burn(from_address: "a", amount: "1") }`, "k2")
+	assert.True(t, reached)
+}
+
+// TestAuthPublicQueries tests that queries only skip authentication when configured
+func TestAuthPublicQueries(t *testing.T) {
+	query := `{ totalSupply }`
+
+	_, reached := authRequest(t, graphql.AuthConfig{APIKeys: []string{"k1"}, PublicQueries: true}, query, "")
+	assert.True(t, reached)
+
+	status, reached := authRequest(t, graphql.AuthConfig{APIKeys: []string{"k1"}}, query, "")
+	assert.Equal(t, http.StatusUnauthorized, status)
+	assert.False(t, reached)
+}
+
+// TestAuthDisabledWithoutKeys tests that no keys means no authentication
+func TestAuthDisabledWithoutKeys(t *testing.T) {
+	_, reached := authRequest(t, graphql.AuthConfig{}, `mutation { burn(from_address: "a", amount: "1") }`, "")
+	assert.True(t, reached)
+}
+
+// TestAuthConfigCallers tests that API_KEYS entries written as key=address are bound to the address
+func TestAuthConfigCallers(t *testing.T) {
+	minter := "0x7a00000000000000000000000000000000000001"
+	t.Setenv("API_KEYS", "k1, k2="+minter)
+	cfg, err := graphql.AuthConfigFromEnv()
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"k1", "k2"}, cfg.APIKeys)
+	assert.Equal(t, minter, cfg.CallerFor("Bearer k2"))
+	assert.Empty(t, cfg.CallerFor("Bearer k1"))
+	assert.Empty(t, cfg.CallerFor("Bearer "+minter))
+
+	for _, v := range []string{"k1=minter", "=" + minter} {
+		t.Setenv("API_KEYS", v)
+		_, err := graphql.AuthConfigFromEnv()
+		assert.Error(t, err, v)
+	}
+}
+
+// TestForgedCallerCannotMint tests that naming the minter in a header does not authorize a mint, while the minter's key does
+func TestForgedCallerCannotMint(t *testing.T) {
+	minter := "0x7a00000000000000000000000000000000000001"
+	t.Setenv("MINTER_ADDRESS", minter)
+	cfg := graphql.AuthConfig{APIKeys: []string{"client", "minter"}, Callers: map[string]string{"minter": minter}}
+	handler := graphql.WithAuth(graphql.NewHandler(), cfg)
+
+	// An amount of zero is rejected once the caller is authorized, before
+	// the database is asked
+	mint := func(key string) interface{} {
+		body, _ := json.Marshal(graphql.GraphQLRequest{Query: `mutation { mint(to_address: "` + minter + `", amount: "0") { balance } }`})
+		req := httptest.NewRequest(http.MethodPost, "/", strings.NewReader(string(body)))
+		req.Header.Set("Content-Type", "application/json")
+		req.Header.Set("Authorization", "Bearer "+key)
+		req.Header.Set("X-Caller-Address", minter)
+		rec := httptest.NewRecorder()
+		handler.ServeHTTP(rec, req)
+
+		var resp persistedResponse
+		assert.NoError(t, json.NewDecoder(rec.Body).Decode(&resp))
+		return errorCode(resp)
+	}
+	assert.Equal(t, "UNAUTHORIZED", mint("client"))
+	assert.Equal(t, "INVALID_AMOUNT", mint("minter"))
+}
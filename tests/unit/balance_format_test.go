@@ -0,0 +1,32 @@
+package unit
+
+import (
+	"testing"
+	"token-transfer-api/internal/graph"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// TestFormatBalanceHumanUnits tests that base units are rendered with the configured decimals
+func TestFormatBalanceHumanUnits(t *testing.T) {
+	resolver := &graph.Resolver{Decimals: 18}
+
+	assert.Equal(t, "1.5", resolver.FormatBalance("1500000000000000000", false))
+	assert.Equal(t, "0.000000000000000001", resolver.FormatBalance("1", false))
+	assert.Equal(t, "2", resolver.FormatBalance("2000000000000000000", false))
+	assert.Equal(t, "0", resolver.FormatBalance("0", false))
+}
+
+// TestFormatBalanceRaw tests that raw mode returns the stored base units
+func TestFormatBalanceRaw(t *testing.T) {
+	resolver := &graph.Resolver{Decimals: 18}
+
+	assert.Equal(t, "1500000000000000000", resolver.FormatBalance("1500000000000000000", true))
+}
+
+// TestFormatBalanceNoDecimals tests that balances are untouched without decimals
+func TestFormatBalanceNoDecimals(t *testing.T) {
+	resolver := &graph.Resolver{}
+
+	assert.Equal(t, "999900", resolver.FormatBalance("999900", false))
+}
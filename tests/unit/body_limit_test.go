@@ -0,0 +1,91 @@
+package unit
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"token-transfer-api/pkg/graphql"
+	"token-transfer-api/pkg/rest"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// paddedQuery returns a valid GraphQL request body of exactly size bytes
+func paddedQuery(size int) string {
+	body := `{"query":"{ __typename }"}`
+	return body[:len(body)-1] + strings.Repeat(" ", size-len(body)) + "}"
+}
+
+// assertTooLarge checks for a 413 carrying REQUEST_TOO_LARGE and the limit,
+// either as a GraphQL error or as a REST error body
+func assertTooLarge(t *testing.T, rec *httptest.ResponseRecorder, limit int) {
+	assert.Equal(t, http.StatusRequestEntityTooLarge, rec.Code)
+
+	var fields map[string]interface{}
+	assert.NoError(t, json.NewDecoder(rec.Body).Decode(&fields))
+	if errs, ok := fields["errors"].([]interface{}); ok {
+		fields = errs[0].(map[string]interface{})["extensions"].(map[string]interface{})
+	}
+	assert.Equal(t, "REQUEST_TOO_LARGE", fields["code"])
+	assert.Equal(t, float64(limit), fields["max_bytes"])
+}
+
+// TestGraphQLBodyLimit tests that bodies over MAX_REQUEST_BYTES get a 413 and smaller ones run
+func TestGraphQLBodyLimit(t *testing.T) {
+	t.Setenv("MAX_REQUEST_BYTES", "1024")
+	handler := graphql.NewHandler()
+
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, httptest.NewRequest(http.MethodPost, "/", strings.NewReader(paddedQuery(1024))))
+	assert.Equal(t, http.StatusOK, rec.Code)
+	assert.Contains(t, rec.Body.String(), `"__typename":"Query"`)
+
+	rec = httptest.NewRecorder()
+	handler.ServeHTTP(rec, httptest.NewRequest(http.MethodPost, "/", strings.NewReader(paddedQuery(1025))))
+	assertTooLarge(t, rec, 1024)
+}
+
+// TestAuthBodyLimit tests that the auth middleware does not read past the limit either
+func TestAuthBodyLimit(t *testing.T) {
+	t.Setenv("MAX_REQUEST_BYTES", "1024")
+
+	reached := false
+	next := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) { reached = true })
+	handler := graphql.WithAuth(next, graphql.AuthConfig{APIKeys: []string{"k1"}, PublicQueries: true})
+
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, httptest.NewRequest(http.MethodPost, "/", strings.NewReader(paddedQuery(4096))))
+	assertTooLarge(t, rec, 1024)
+	assert.False(t, reached)
+}
+
+// TestRESTBodyLimit tests that the REST transfer endpoint applies the same limit
+func TestRESTBodyLimit(t *testing.T) {
+	t.Setenv("MAX_REQUEST_BYTES", "1024")
+
+	body := `{"from_address":"` + strings.Repeat("0", 2048) + `"}`
+	rec := httptest.NewRecorder()
+	rest.NewHandler().ServeHTTP(rec, httptest.NewRequest(http.MethodPost, "/api/transfer", strings.NewReader(body)))
+	assertTooLarge(t, rec, 1024)
+}
+
+// TestMaxRequestBytesFromEnv tests the default and rejects non-positive limits
+func TestMaxRequestBytesFromEnv(t *testing.T) {
+	t.Setenv("MAX_REQUEST_BYTES", "")
+	limit, err := graphql.MaxRequestBytesFromEnv()
+	assert.NoError(t, err)
+	assert.Equal(t, int64(graphql.DefaultMaxRequestBytes), limit)
+
+	t.Setenv("MAX_REQUEST_BYTES", "5242880")
+	limit, err = graphql.MaxRequestBytesFromEnv()
+	assert.NoError(t, err)
+	assert.Equal(t, int64(5<<20), limit)
+
+	for _, v := range []string{"0", "-1", "1MB"} {
+		t.Setenv("MAX_REQUEST_BYTES", v)
+		_, err = graphql.MaxRequestBytesFromEnv()
+		assert.Error(t, err, v)
+	}
+}
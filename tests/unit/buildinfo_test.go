@@ -0,0 +1,33 @@
+package unit
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"token-transfer-api/internal/buildinfo"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// TestVersionEndpointDefaults tests that a binary built without ldflags reports "dev"
+func TestVersionEndpointDefaults(t *testing.T) {
+	rec := httptest.NewRecorder()
+	buildinfo.Handler().ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/version", nil))
+
+	assert.Equal(t, http.StatusOK, rec.Code)
+	assert.Equal(t, "application/json", rec.Header().Get("Content-Type"))
+
+	var info map[string]string
+	assert.NoError(t, json.NewDecoder(rec.Body).Decode(&info))
+	assert.Equal(t, map[string]string{"version": "dev", "commit": "dev", "build_time": "dev"}, info)
+}
+
+// TestVersionEndpointRejectsPost tests that only GET and HEAD are served
+func TestVersionEndpointRejectsPost(t *testing.T) {
+	rec := httptest.NewRecorder()
+	buildinfo.Handler().ServeHTTP(rec, httptest.NewRequest(http.MethodPost, "/version", nil))
+
+	assert.Equal(t, http.StatusMethodNotAllowed, rec.Code)
+	assert.Equal(t, "GET, HEAD", rec.Header().Get("Allow"))
+}
@@ -0,0 +1,148 @@
+package unit
+
+import (
+	"database/sql/driver"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"syscall"
+	"testing"
+	"token-transfer-api/internal/db"
+
+	"github.com/lib/pq"
+	"github.com/stretchr/testify/assert"
+)
+
+// TestClassifyKnownSQLStates tests that each known SQLSTATE maps to its app error and code
+func TestClassifyKnownSQLStates(t *testing.T) {
+	cases := []struct {
+		sqlState pq.ErrorCode
+		expected *db.AppError
+		code     string
+	}{
+		{"23505", db.ErrDuplicate, "DUPLICATE"},
+		{"23514", db.ErrConstraintViolation, "CONSTRAINT_VIOLATION"},
+		{"22003", db.ErrNumericOverflow, "NUMERIC_OVERFLOW"},
+		{"40001", db.ErrSerializationFailure, "SERIALIZATION_FAILURE"},
+		{"40P01", db.ErrDeadlock, "DEADLOCK_DETECTED"},
+		{"55P03", db.ErrLockTimeout, "LOCK_TIMEOUT"},
+		{"57014", db.ErrQueryCanceled, "QUERY_CANCELED"},
+	}
+
+	for _, c := range cases {
+		t.Run(string(c.sqlState), func(t *testing.T) {
+			driverErr := &pq.Error{Code: c.sqlState, Message: "raw driver message", Constraint: "wallets_balance_check"}
+
+			err := db.ClassifyError(driverErr)
+			assert.ErrorIs(t, err, c.expected)
+
+			var appErr *db.AppError
+			assert.True(t, errors.As(err, &appErr))
+			assert.Equal(t, c.code, appErr.Code)
+			assert.Equal(t, c.code, appErr.Extensions()["code"])
+
+			// The client-facing message never contains the driver detail
+			assert.Equal(t, c.expected.Message, err.Error())
+			assert.NotContains(t, err.Error(), "wallets_balance_check")
+
+			// The cause is preserved for logging
+			var cause *pq.Error
+			assert.True(t, errors.As(err, &cause))
+			assert.Equal(t, c.sqlState, cause.Code)
+		})
+	}
+}
+
+// TestClassifyWrappedDriverError tests that wrapped driver errors are classified
+func TestClassifyWrappedDriverError(t *testing.T) {
+	err := db.ClassifyError(fmt.Errorf("update sender: %w", &pq.Error{Code: "40P01"}))
+	assert.ErrorIs(t, err, db.ErrDeadlock)
+}
+
+// TestClassifyPassThrough tests that app errors and unknown errors are returned unchanged
+func TestClassifyPassThrough(t *testing.T) {
+	assert.Nil(t, db.ClassifyError(nil))
+	assert.Same(t, db.ErrInsufficientBalance, db.ClassifyError(db.ErrInsufficientBalance))
+
+	unknown := &pq.Error{Code: "42P01", Message: "relation does not exist"}
+	assert.Same(t, unknown, db.ClassifyError(unknown))
+
+	plain := errors.New("connection refused")
+	assert.Same(t, plain, db.ClassifyError(plain))
+}
+
+// TestSentinelErrorsKeepMessages tests that the typed errors keep their client messages
+func TestSentinelErrorsKeepMessages(t *testing.T) {
+	assert.Equal(t, "insufficient balance", db.ErrInsufficientBalance.Error())
+	assert.Equal(t, "invalid amount", db.ErrInvalidAmount.Error())
+	assert.Equal(t, "sender wallet does not exist", db.ErrSenderNotFound.Error())
+	assert.False(t, errors.Is(db.ErrInsufficientBalance, db.ErrInvalidAmount))
+}
+
+// TestErrorDetailsInExtensions tests that details are reported next to the code
+func TestErrorDetailsInExtensions(t *testing.T) {
+	err := db.ErrRateLimited.WithDetails(map[string]interface{}{"retry_after": 12})
+
+	assert.True(t, errors.Is(err, db.ErrRateLimited))
+	assert.Equal(t, map[string]interface{}{"code": "RATE_LIMITED", "retryable": true, "retry_after": 12}, err.Extensions())
+	assert.Equal(t, map[string]interface{}{"code": "RATE_LIMITED", "retryable": true}, db.ErrRateLimited.Extensions())
+}
+
+// TestClassifyTransientErrors tests that connection failures and an unavailable server are retryable
+func TestClassifyTransientErrors(t *testing.T) {
+	for _, driverErr := range []error{
+		&pq.Error{Code: "08006", Message: "connection failure"},
+		&pq.Error{Code: "08P01", Message: "protocol violation"},
+		&pq.Error{Code: "57P01", Message: "terminating connection due to administrator command"},
+		&pq.Error{Code: "57P03", Message: "the database system is starting up"},
+		&pq.Error{Code: "53300", Message: "sorry, too many clients already"},
+		driver.ErrBadConn,
+		io.ErrUnexpectedEOF,
+		fmt.Errorf("read: %w", syscall.ECONNRESET),
+		&net.OpError{Op: "dial", Net: "tcp", Err: syscall.ECONNREFUSED},
+	} {
+		err := db.ClassifyError(driverErr)
+		assert.ErrorIs(t, err, db.ErrRetryable, driverErr.Error())
+		assert.ErrorIs(t, err, driverErr, "the cause is preserved for logging")
+
+		var appErr *db.AppError
+		if assert.True(t, errors.As(err, &appErr)) {
+			assert.True(t, appErr.Retryable())
+			assert.Equal(t, true, appErr.Extensions()["retryable"])
+		}
+	}
+}
+
+// TestClassifyPermanentErrors tests that data and constraint errors without their own code are permanent
+func TestClassifyPermanentErrors(t *testing.T) {
+	for _, driverErr := range []*pq.Error{
+		{Code: "22P02", Message: "invalid input syntax for type numeric"},
+		{Code: "22001", Message: "value too long for type character varying(42)"},
+		{Code: "23503", Message: "violates foreign key constraint"},
+	} {
+		err := db.ClassifyError(driverErr)
+		assert.ErrorIs(t, err, db.ErrPermanent, driverErr.Message)
+		assert.NotContains(t, err.Error(), driverErr.Message)
+
+		var appErr *db.AppError
+		if assert.True(t, errors.As(err, &appErr)) {
+			assert.False(t, appErr.Retryable())
+			assert.Equal(t, false, appErr.Extensions()["retryable"])
+		}
+	}
+
+	// Codes with their own error keep them
+	assert.ErrorIs(t, db.ClassifyError(&pq.Error{Code: "22003"}), db.ErrNumericOverflow)
+	assert.ErrorIs(t, db.ClassifyError(&pq.Error{Code: "23505"}), db.ErrDuplicate)
+}
+
+// TestRetryableErrors tests which app errors tell clients to retry
+func TestRetryableErrors(t *testing.T) {
+	for _, err := range []*db.AppError{db.ErrRetryable, db.ErrSerializationFailure, db.ErrDeadlock, db.ErrLockTimeout, db.ErrConflict, db.ErrRateLimited, db.ErrNotInitialized} {
+		assert.True(t, err.Retryable(), err.Code)
+	}
+	for _, err := range []*db.AppError{db.ErrPermanent, db.ErrInsufficientBalance, db.ErrInvalidAmount, db.ErrDuplicate, db.ErrInternal, db.ErrQueryCanceled} {
+		assert.False(t, err.Retryable(), err.Code)
+	}
+}
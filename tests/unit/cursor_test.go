@@ -0,0 +1,26 @@
+package unit
+
+import (
+	"testing"
+	"token-transfer-api/internal/db"
+	"token-transfer-api/internal/graph"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// TestCursorRoundTrip tests that a cursor decodes back to the id it was built from
+func TestCursorRoundTrip(t *testing.T) {
+	for _, id := range []int64{1, 42, 9223372036854775807} {
+		got, err := graph.DecodeCursor(graph.EncodeCursor(id))
+		assert.NoError(t, err)
+		assert.Equal(t, id, got)
+	}
+}
+
+// TestDecodeInvalidCursor tests that malformed cursors are rejected
+func TestDecodeInvalidCursor(t *testing.T) {
+	for _, cursor := range []string{"not base64!", "YWJj", "MA==", "LTE="} {
+		_, err := graph.DecodeCursor(cursor)
+		assert.ErrorIs(t, err, db.ErrInvalidCursor, cursor)
+	}
+}
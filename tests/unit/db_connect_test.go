@@ -0,0 +1,124 @@
+package unit
+
+import (
+	"encoding/binary"
+	"io"
+	"net"
+	"strconv"
+	"sync/atomic"
+	"testing"
+	"time"
+	"token-transfer-api/internal/db"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// startingPostgres listens like a Postgres server that is still starting up:
+// every connection is answered with FATAL 57P03 after the startup message.
+// It returns the port and the number of connections seen.
+func startingPostgres(t *testing.T) (string, *atomic.Int64) {
+	lis, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("Failed to listen: %v", err)
+	}
+	t.Cleanup(func() { lis.Close() })
+
+	var fields []byte
+	for _, f := range []string{"SFATAL", "C57P03", "Mthe database system is starting up"} {
+		fields = append(fields, f...)
+		fields = append(fields, 0)
+	}
+	fields = append(fields, 0)
+	reply := append([]byte{'E'}, binary.BigEndian.AppendUint32(nil, uint32(4+len(fields)))...)
+	reply = append(reply, fields...)
+
+	var connections atomic.Int64
+	go func() {
+		for {
+			c, err := lis.Accept()
+			if err != nil {
+				return
+			}
+			connections.Add(1)
+			go func() {
+				defer c.Close()
+				var length [4]byte
+				if _, err := io.ReadFull(c, length[:]); err != nil {
+					return
+				}
+				if _, err := io.CopyN(io.Discard, c, int64(binary.BigEndian.Uint32(length[:]))-4); err != nil {
+					return
+				}
+				c.Write(reply)
+			}()
+		}
+	}()
+
+	return strconv.Itoa(lis.Addr().(*net.TCPAddr).Port), &connections
+}
+
+// pointAt directs InitDB at the given local port
+func pointAt(t *testing.T, port string) {
+	t.Setenv("DATABASE_URL", "")
+	t.Setenv("DB_HOST", "127.0.0.1")
+	t.Setenv("DB_PORT", port)
+	t.Setenv("DB_USER", "postgres")
+	t.Setenv("DB_PASSWORD", "postgres")
+	t.Setenv("DB_NAME", "token_transfer")
+	t.Setenv("DB_SSLMODE", "disable")
+}
+
+// TestInitDBRetriesUnreachableDatabase tests that InitDB pings DB_CONNECT_RETRIES more times before failing
+func TestInitDBRetriesUnreachableDatabase(t *testing.T) {
+	port, connections := startingPostgres(t)
+	pointAt(t, port)
+	t.Setenv("DB_CONNECT_RETRIES", "3")
+	t.Setenv("DB_CONNECT_BACKOFF", "1ms")
+
+	err := db.InitDB()
+	if assert.Error(t, err) {
+		assert.Contains(t, err.Error(), "failed to ping database after 4 attempts")
+		assert.Contains(t, err.Error(), "starting up")
+	}
+	assert.Equal(t, int64(4), connections.Load())
+	assert.Nil(t, db.DB)
+}
+
+// TestInitDBNoRetriesByDefault tests that without DB_CONNECT_RETRIES InitDB gives up after one attempt
+func TestInitDBNoRetriesByDefault(t *testing.T) {
+	port, connections := startingPostgres(t)
+	pointAt(t, port)
+	t.Setenv("DB_CONNECT_RETRIES", "")
+
+	start := time.Now()
+	err := db.InitDB()
+	assert.Error(t, err)
+	assert.Equal(t, int64(1), connections.Load())
+	assert.Less(t, time.Since(start), db.DefaultConnectBackoff)
+}
+
+// TestConnectRetriesFromEnv tests the DB_CONNECT_RETRIES and DB_CONNECT_BACKOFF settings
+func TestConnectRetriesFromEnv(t *testing.T) {
+	t.Setenv("DB_CONNECT_RETRIES", "")
+	t.Setenv("DB_CONNECT_BACKOFF", "")
+	cfg, err := db.LoadConfig()
+	assert.NoError(t, err)
+	assert.Equal(t, 0, cfg.ConnectRetries)
+	assert.Equal(t, db.DefaultConnectBackoff, cfg.ConnectBackoff)
+
+	t.Setenv("DB_CONNECT_RETRIES", "10")
+	t.Setenv("DB_CONNECT_BACKOFF", "250ms")
+	cfg, err = db.LoadConfig()
+	assert.NoError(t, err)
+	assert.Equal(t, 10, cfg.ConnectRetries)
+	assert.Equal(t, 250*time.Millisecond, cfg.ConnectBackoff)
+
+	t.Setenv("DB_CONNECT_RETRIES", "-1")
+	_, err = db.LoadConfig()
+	assert.Error(t, err)
+
+	t.Setenv("DB_CONNECT_RETRIES", "")
+	t.Setenv("DB_CONNECT_BACKOFF", "soon")
+	_, err = db.LoadConfig()
+	assert.Error(t, err)
+}
@@ -0,0 +1,52 @@
+package unit
+
+import (
+	"context"
+	"testing"
+	"token-transfer-api/internal/db"
+
+	"github.com/joho/godotenv"
+	"github.com/stretchr/testify/assert"
+)
+
+// TestUseAfterCloseDB tests that closing twice is harmless and later calls fail with ErrNotInitialized
+func TestUseAfterCloseDB(t *testing.T) {
+	assert.NoError(t, db.CloseDB())
+	assert.NoError(t, db.CloseDB())
+	assert.Nil(t, db.DB)
+
+	_, err := db.GetWallet("0xabcdef0000000000000000000000000000000001")
+	assert.ErrorIs(t, err, db.ErrNotInitialized)
+
+	_, err = db.TransferTokens(
+		"0xabcdef0000000000000000000000000000000001",
+		"0xabcdef0000000000000000000000000000000002",
+		"100")
+	assert.ErrorIs(t, err, db.ErrNotInitialized)
+
+	_, _, err = db.ListTransfersContext(context.Background(), 10, 0)
+	assert.ErrorIs(t, err, db.ErrNotInitialized)
+}
+
+// TestReopenAfterCloseDB tests that InitDB reopens the pool after it was closed twice
+func TestReopenAfterCloseDB(t *testing.T) {
+	if err := godotenv.Load("../../.env"); err != nil {
+		t.Logf("No .env file found")
+	}
+
+	if err := db.InitDB(); err != nil {
+		t.Fatalf("Failed to initialize database: %v", err)
+	}
+
+	assert.NoError(t, db.CloseDB())
+	assert.NoError(t, db.CloseDB())
+
+	if err := db.InitDB(); err != nil {
+		t.Fatalf("Failed to reopen database: %v", err)
+	}
+	defer db.CloseDB()
+
+	assert.NoError(t, db.DB.Ping())
+	_, err := db.GetWalletCount()
+	assert.NoError(t, err)
+}
@@ -0,0 +1,87 @@
+package unit
+
+import (
+	"errors"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+	"token-transfer-api/pkg/dedup"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// TestDedupReusesResultWithinWindow tests that a repeated key returns the first result
+func TestDedupReusesResultWithinWindow(t *testing.T) {
+	cache := dedup.New(time.Minute)
+	var calls int32
+
+	fn := func() (interface{}, error) {
+		return atomic.AddInt32(&calls, 1), nil
+	}
+
+	first, reused, err := cache.Do("a", fn)
+	assert.NoError(t, err)
+	assert.False(t, reused)
+
+	second, reused, err := cache.Do("a", fn)
+	assert.NoError(t, err)
+	assert.True(t, reused)
+	assert.Equal(t, first, second)
+
+	_, reused, _ = cache.Do("b", fn)
+	assert.False(t, reused)
+	assert.Equal(t, int32(2), atomic.LoadInt32(&calls))
+}
+
+// TestDedupConcurrentCallsRunOnce tests that simultaneous submissions share one call
+func TestDedupConcurrentCallsRunOnce(t *testing.T) {
+	cache := dedup.New(time.Minute)
+	var calls int32
+
+	var wg sync.WaitGroup
+	for i := 0; i < 10; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			cache.Do("a", func() (interface{}, error) {
+				atomic.AddInt32(&calls, 1)
+				time.Sleep(20 * time.Millisecond)
+				return "ok", nil
+			})
+		}()
+	}
+	wg.Wait()
+
+	assert.Equal(t, int32(1), atomic.LoadInt32(&calls))
+}
+
+// TestDedupForgetsFailures tests that a failed call can be retried
+func TestDedupForgetsFailures(t *testing.T) {
+	cache := dedup.New(time.Minute)
+
+	_, _, err := cache.Do("a", func() (interface{}, error) {
+		return nil, errors.New("boom")
+	})
+	assert.Error(t, err)
+
+	value, reused, err := cache.Do("a", func() (interface{}, error) {
+		return "ok", nil
+	})
+	assert.NoError(t, err)
+	assert.False(t, reused)
+	assert.Equal(t, "ok", value)
+}
+
+// TestDedupExpires tests that keys are forgotten after the window
+func TestDedupExpires(t *testing.T) {
+	cache := dedup.New(20 * time.Millisecond)
+	fn := func() (interface{}, error) { return "ok", nil }
+
+	cache.Do("a", fn)
+	time.Sleep(40 * time.Millisecond)
+
+	_, reused, _ := cache.Do("a", fn)
+	assert.False(t, reused)
+	assert.Equal(t, 1, cache.Len())
+}
@@ -0,0 +1,56 @@
+package unit
+
+import (
+	"math/big"
+	"testing"
+	"token-transfer-api/internal/db"
+
+	"github.com/stretchr/testify/assert"
+)
+
+const feeWallet = "0xfee0000000000000000000000000000000000000"
+
+// TestFeeBasisPointsRounding tests that the percentage fee is rounded down
+func TestFeeBasisPointsRounding(t *testing.T) {
+	cfg := db.Config{FeeBPS: 25, FeeWallet: feeWallet}
+
+	cases := []struct {
+		amount int64
+		fee    int64
+	}{
+		{amount: 10000, fee: 25},
+		{amount: 399, fee: 0},    // 0.9975 rounds down
+		{amount: 400, fee: 1},    // exactly 1
+		{amount: 799, fee: 1},    // 1.9975 rounds down
+		{amount: 12345, fee: 30}, // 30.8625 rounds down
+		{amount: 1, fee: 0},
+	}
+
+	for _, c := range cases {
+		assert.Equal(t, big.NewInt(c.fee).String(), cfg.Fee(big.NewInt(c.amount)).String(), "amount %d", c.amount)
+	}
+}
+
+// TestFeeFlatAndBasisPoints tests that the flat fee is added to the percentage fee
+func TestFeeFlatAndBasisPoints(t *testing.T) {
+	cfg := db.Config{FeeFlat: big.NewInt(5), FeeBPS: 100, FeeWallet: feeWallet}
+
+	assert.Equal(t, "15", cfg.Fee(big.NewInt(1000)).String())
+	assert.Equal(t, "5", cfg.Fee(big.NewInt(99)).String())
+}
+
+// TestFeeLargeAmounts tests that fees are computed without overflow
+func TestFeeLargeAmounts(t *testing.T) {
+	cfg := db.Config{FeeBPS: 1, FeeWallet: feeWallet}
+
+	amount, _ := new(big.Int).SetString("100000000000000000000000000000000000000000000000", 10) // 10^47
+	expected, _ := new(big.Int).SetString("10000000000000000000000000000000000000000000", 10)   // 10^43
+	assert.Equal(t, expected, cfg.Fee(amount))
+}
+
+// TestFeeDisabledWithoutWallet tests that no fee is charged without a fee wallet
+func TestFeeDisabledWithoutWallet(t *testing.T) {
+	cfg := db.Config{FeeFlat: big.NewInt(5), FeeBPS: 100}
+
+	assert.Equal(t, 0, cfg.Fee(big.NewInt(1000)).Sign())
+}
@@ -0,0 +1,95 @@
+package unit
+
+import (
+	"encoding/json"
+	"net/http"
+	"strings"
+	"testing"
+	"token-transfer-api/pkg/graphql"
+
+	gql "github.com/graphql-go/graphql"
+	"github.com/stretchr/testify/assert"
+)
+
+const mixedCaseAddress = "0xAbCdEf0000000000000000000000000000000001"
+
+// addressEchoSchema has a single field returning its Address argument
+func addressEchoSchema(t *testing.T) gql.Schema {
+	schema, err := gql.NewSchema(gql.SchemaConfig{
+		Query: gql.NewObject(gql.ObjectConfig{
+			Name: "Query",
+			Fields: gql.Fields{
+				"echo": &gql.Field{
+					Type: graphql.Address,
+					Args: gql.FieldConfigArgument{
+						"address": &gql.ArgumentConfig{Type: gql.NewNonNull(graphql.Address)},
+					},
+					Resolve: func(p gql.ResolveParams) (interface{}, error) {
+						return p.Args["address"], nil
+					},
+				},
+			},
+		}),
+	})
+	assert.NoError(t, err)
+	return schema
+}
+
+// TestAddressLiterals tests which query literals Address accepts
+func TestAddressLiterals(t *testing.T) {
+	schema := addressEchoSchema(t)
+
+	result := gql.Do(gql.Params{Schema: schema, RequestString: `{ echo(address: "` + mixedCaseAddress + `") }`})
+	assert.Empty(t, result.Errors)
+	assert.Equal(t, map[string]interface{}{"echo": mixedCaseAddress}, result.Data, "case is kept as sent")
+
+	for _, literal := range []string{`"0x123"`, `"0xnonexistent"`, `"` + strings.Repeat("0", 42) + `"`,
+		`"0x000000000000000000000000000000000000000g"`, `"0x00000000000000000000000000000000000000000"`,
+		`" 0x0000000000000000000000000000000000000001"`, `""`, `1`} {
+		result := gql.Do(gql.Params{Schema: schema, RequestString: `{ echo(address: ` + literal + `) }`})
+		if assert.NotEmpty(t, result.Errors, literal) {
+			assert.Contains(t, result.Errors[0].Message, `Argument "address" has invalid value`, literal)
+			assert.Contains(t, result.Errors[0].Message, `Expected type "Address"`, literal)
+		}
+	}
+}
+
+// TestAddressVariables tests which variable values Address accepts
+func TestAddressVariables(t *testing.T) {
+	schema := addressEchoSchema(t)
+	query := `query Echo($address: Address!) { echo(address: $address) }`
+
+	result := gql.Do(gql.Params{Schema: schema, RequestString: query, VariableValues: map[string]interface{}{"address": mixedCaseAddress}})
+	assert.Empty(t, result.Errors)
+	assert.Equal(t, map[string]interface{}{"echo": mixedCaseAddress}, result.Data)
+
+	for _, value := range []interface{}{"0x123", "nope", float64(1)} {
+		result := gql.Do(gql.Params{Schema: schema, RequestString: query, VariableValues: map[string]interface{}{"address": value}})
+		if assert.NotEmpty(t, result.Errors, value) {
+			assert.Contains(t, result.Errors[0].Message, `Variable "$address" got invalid value`)
+		}
+	}
+}
+
+// TestTransferRejectsMalformedAddress tests that a bad address fails validation before any resolver runs
+func TestTransferRejectsMalformedAddress(t *testing.T) {
+	handler := graphql.NewHandler()
+
+	for _, query := range []string{
+		`mutation { transfer(from_address: "0x123", to_address: "0x0000000000000000000000000000000000000001", amount: "1") { balance } }`,
+		`mutation { transfer(from_address: "0x0000000000000000000000000000000000000001", to_address: "0x123", amount: "1") { balance } }`,
+		`{ wallet(address: "0x123") { balance } }`,
+		`{ walletOrZero(address: "0x123") { balance } }`,
+		`mutation { setReserve(address: "0x123", amount: "1") { balance } }`,
+	} {
+		body, _ := json.Marshal(graphql.GraphQLRequest{Query: query})
+		rec := post(handler, "application/json", string(body))
+		var resp persistedResponse
+		assert.NoError(t, json.NewDecoder(rec.Body).Decode(&resp))
+		assert.Equal(t, http.StatusBadRequest, rec.Code, query)
+		assert.Nil(t, resp.Data, query)
+		if assert.NotEmpty(t, resp.Errors, query) {
+			assert.Contains(t, resp.Errors[0]["message"], `Expected type "Address", found "0x123"`, query)
+		}
+	}
+}
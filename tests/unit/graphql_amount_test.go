@@ -0,0 +1,82 @@
+package unit
+
+import (
+	"encoding/json"
+	"net/http"
+	"strings"
+	"testing"
+	"token-transfer-api/pkg/graphql"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// TestTransferRejectsInvalidAmountLiteral tests that a bad amount fails validation before any transfer runs
+func TestTransferRejectsInvalidAmountLiteral(t *testing.T) {
+	t.Setenv("TOKEN_DECIMALS", "")
+	handler := graphql.NewHandler()
+
+	body, _ := json.Marshal(graphql.GraphQLRequest{Query: `mutation { transfer(from_address: "0x00000000000000000000000000000000000000aa", to_address: "0x00000000000000000000000000000000000000bb", amount: "-5") { balance } }`})
+	rec := post(handler, "application/json", string(body))
+	var resp persistedResponse
+	assert.NoError(t, json.NewDecoder(rec.Body).Decode(&resp))
+	assert.Equal(t, http.StatusBadRequest, rec.Code)
+	assert.Nil(t, resp.Data)
+	if assert.NotEmpty(t, resp.Errors) {
+		assert.Contains(t, resp.Errors[0]["message"], `Expected type "Amount"`)
+	}
+
+	body, _ = json.Marshal(graphql.GraphQLRequest{
+		Query:     `mutation Send($amount: Amount!) { transfer(from_address: "0x00000000000000000000000000000000000000aa", to_address: "0x00000000000000000000000000000000000000bb", amount: $amount) { balance } }`,
+		Variables: map[string]interface{}{"amount": "01.5"},
+	})
+	rec = post(handler, "application/json", string(body))
+	resp = persistedResponse{}
+	assert.NoError(t, json.NewDecoder(rec.Body).Decode(&resp))
+	assert.Equal(t, http.StatusBadRequest, rec.Code)
+	assert.Nil(t, resp.Data)
+	assert.NotEmpty(t, resp.Errors)
+}
+
+// TestAmountTypeIgnoresDecimals tests that amounts keep the Amount type
+// whatever TOKEN_DECIMALS is
+func TestAmountTypeIgnoresDecimals(t *testing.T) {
+	amountType := func() string {
+		body, _ := json.Marshal(graphql.GraphQLRequest{Query: `{ __type(name: "Mutation") { fields { name args { name type { ofType { name } } } } } }`})
+		rec := post(graphql.NewHandler(), "application/json", string(body))
+		var resp persistedResponse
+		assert.NoError(t, json.NewDecoder(rec.Body).Decode(&resp))
+		for _, field := range resp.Data["__type"].(map[string]interface{})["fields"].([]interface{}) {
+			field := field.(map[string]interface{})
+			if field["name"] != "transfer" {
+				continue
+			}
+			for _, arg := range field["args"].([]interface{}) {
+				arg := arg.(map[string]interface{})
+				if arg["name"] == "amount" {
+					return arg["type"].(map[string]interface{})["ofType"].(map[string]interface{})["name"].(string)
+				}
+			}
+		}
+		return ""
+	}
+
+	t.Setenv("GRAPHQL_INTROSPECTION", "true")
+	t.Setenv("TOKEN_DECIMALS", "")
+	assert.Equal(t, "Amount", amountType())
+
+	t.Setenv("TOKEN_DECIMALS", "2")
+	assert.Equal(t, "Amount", amountType())
+}
+
+// TestAmountScalar tests which spellings Amount accepts and writes
+func TestAmountScalar(t *testing.T) {
+	for _, valid := range []string{"0", "100", "1.5", "0.05", "1.50"} {
+		assert.Equal(t, valid, graphql.Amount.ParseValue(valid), valid)
+		assert.Equal(t, valid, graphql.Amount.Serialize(valid), valid)
+	}
+	for _, invalid := range []string{"", "-1", "+1", "01", "1.", ".5", "1e3", " 1", "1,5", strings.Repeat("9", 79)} {
+		assert.Nil(t, graphql.Amount.ParseValue(invalid), invalid)
+	}
+	assert.Equal(t, strings.Repeat("9", 78)+".5", graphql.Amount.ParseValue(strings.Repeat("9", 78)+".5"))
+	assert.Nil(t, graphql.Amount.ParseValue(100))
+}
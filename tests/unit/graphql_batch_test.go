@@ -0,0 +1,103 @@
+package unit
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"token-transfer-api/pkg/graphql"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// post sends body with the given content type through handler
+func post(handler http.Handler, contentType, body string) *httptest.ResponseRecorder {
+	req := httptest.NewRequest(http.MethodPost, "/", strings.NewReader(body))
+	req.Header.Set("Content-Type", contentType)
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, req)
+	return rec
+}
+
+// TestRawGraphQLBody tests that application/graphql bodies are read as the query itself
+func TestRawGraphQLBody(t *testing.T) {
+	for _, contentType := range []string{"application/graphql", "application/graphql; charset=utf-8"} {
+		rec := post(graphql.NewHandler(), contentType, `{ raw: __typename }`)
+		assert.Equal(t, http.StatusOK, rec.Code)
+
+		var resp persistedResponse
+		assert.NoError(t, json.NewDecoder(rec.Body).Decode(&resp))
+		assert.Nil(t, resp.Errors, contentType)
+		assert.Equal(t, "Query", resp.Data["raw"], contentType)
+	}
+}
+
+// TestBatchedOperations tests that an array of operations gets an array of results in the same order
+func TestBatchedOperations(t *testing.T) {
+	body := `[
+		{"query": "{ first: __typename }"},
+		{"query": "query B($skip: Boolean!) { second: __typename @skip(if: $skip) }", "variables": {"skip": false}}
+	]`
+	rec := post(graphql.NewHandler(), "application/json", body)
+	assert.Equal(t, http.StatusOK, rec.Code)
+
+	var resp []persistedResponse
+	assert.NoError(t, json.NewDecoder(rec.Body).Decode(&resp))
+	assert.Len(t, resp, 2)
+	assert.Equal(t, map[string]interface{}{"first": "Query"}, resp[0].Data)
+	assert.Equal(t, map[string]interface{}{"second": "Query"}, resp[1].Data)
+}
+
+// TestBatchErrorsStayPerOperation tests that one failing operation does not fail the others
+func TestBatchErrorsStayPerOperation(t *testing.T) {
+	body := `[
+		{"extensions": {"persistedQuery": {"version": 1, "sha256Hash": "` + queryHash(`{ batchMiss: __typename }`) + `"}}},
+		{"query": "{ ok: __typename }"}
+	]`
+	rec := post(graphql.NewHandler(), "application/json", body)
+	assert.Equal(t, http.StatusOK, rec.Code)
+
+	var resp []persistedResponse
+	assert.NoError(t, json.NewDecoder(rec.Body).Decode(&resp))
+	assert.Len(t, resp, 2)
+	assert.Equal(t, "PERSISTED_QUERY_NOT_FOUND", errorCode(resp[0]))
+	assert.Nil(t, resp[1].Errors)
+	assert.Equal(t, "Query", resp[1].Data["ok"])
+}
+
+// TestBatchSize tests that empty and oversized batches are bad requests
+func TestBatchSize(t *testing.T) {
+	handler := graphql.NewHandler()
+
+	assert.Equal(t, http.StatusBadRequest, post(handler, "application/json", `[]`).Code)
+
+	ops := make([]string, graphql.MaxBatchOperations+1)
+	for i := range ops {
+		ops[i] = `{"query": "{ __typename }"}`
+	}
+	assert.Equal(t, http.StatusBadRequest, post(handler, "application/json", "["+strings.Join(ops, ",")+"]").Code)
+}
+
+// TestAuthSeesBatchesAndRawBodies tests that mutations need a key however the request is encoded
+func TestAuthSeesBatchesAndRawBodies(t *testing.T) {
+	cfg := graphql.AuthConfig{APIKeys: []string{"k1"}, PublicQueries: true}
+	reached := false
+	handler := graphql.WithAuth(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) { reached = true }), cfg)
+
+	mutation := `mutation { burn(from_address: \"a\", amount: \"1\") }`
+	rec := post(handler, "application/json", `[{"query": "{ __typename }"}, {"query": "`+mutation+`"}]`)
+	assert.Equal(t, http.StatusUnauthorized, rec.Code)
+	assert.False(t, reached)
+
+	rec = post(handler, "application/graphql", `mutation { burn(from_address: "a", amount: "1") }`)
+	assert.Equal(t, http.StatusUnauthorized, rec.Code)
+	assert.False(t, reached)
+
+	// Queries stay public in either encoding
+	post(handler, "application/json", `[{"query": "{ __typename }"}]`)
+	assert.True(t, reached)
+	reached = false
+	post(handler, "application/graphql", `{ __typename }`)
+	assert.True(t, reached)
+}
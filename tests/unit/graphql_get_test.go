@@ -0,0 +1,79 @@
+package unit
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strconv"
+	"testing"
+	"token-transfer-api/pkg/graphql"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// getRequest sends params to the GraphQL handler as a GET request
+func getRequest(params url.Values) *httptest.ResponseRecorder {
+	rec := httptest.NewRecorder()
+	graphql.NewHandler().ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/?"+params.Encode(), nil))
+	return rec
+}
+
+// TestGETQuery tests that queries can be sent as URL parameters
+func TestGETQuery(t *testing.T) {
+	rec := getRequest(url.Values{"query": {`{ __typename }`}})
+	assert.Equal(t, http.StatusOK, rec.Code)
+
+	var resp persistedResponse
+	assert.NoError(t, json.NewDecoder(rec.Body).Decode(&resp))
+	assert.Nil(t, resp.Errors)
+	assert.Equal(t, "Query", resp.Data["__typename"])
+}
+
+// TestGETOperationNameAndVariables tests that operationName picks the operation and variables are decoded
+func TestGETOperationNameAndVariables(t *testing.T) {
+	rec := getRequest(url.Values{
+		"query":         {`query A { a: __typename } query B($skip: Boolean!) { b: __typename @skip(if: $skip) }`},
+		"operationName": {"B"},
+		"variables":     {`{"skip": false}`},
+	})
+	assert.Equal(t, http.StatusOK, rec.Code)
+
+	var resp persistedResponse
+	assert.NoError(t, json.NewDecoder(rec.Body).Decode(&resp))
+	assert.Nil(t, resp.Errors)
+	assert.Equal(t, map[string]interface{}{"b": "Query"}, resp.Data)
+}
+
+// TestGETRejectsMutation tests that mutations over GET are refused before running
+func TestGETRejectsMutation(t *testing.T) {
+	rec := getRequest(url.Values{"query": {`mutation { burn(from_address: "0x000000000000000000000000000000000000000a", amount: "1") }`}})
+	assert.Equal(t, http.StatusMethodNotAllowed, rec.Code)
+	assert.Equal(t, http.MethodPost, rec.Header().Get("Allow"))
+
+	var resp persistedResponse
+	assert.NoError(t, json.NewDecoder(rec.Body).Decode(&resp))
+	assert.Nil(t, resp.Data)
+	assert.Equal(t, "MUTATION_OVER_GET", errorCode(resp))
+}
+
+// TestGETRejectsPersistedMutation tests that a mutation registered by POST cannot be replayed by hash over GET
+func TestGETRejectsPersistedMutation(t *testing.T) {
+	mutation := `mutation { burn(from_address: "0x00000000000000000000000000000000000000ee", amount: "1") }`
+	hash := queryHash(mutation)
+	extensions := `{"persistedQuery": {"version": 1, "sha256Hash": "` + hash + `"}}`
+
+	// Registering runs it, and execution fails on authorization, which does
+	// not matter here
+	body := `{"query": ` + strconv.Quote(mutation) + `, "extensions": ` + extensions + `}`
+	assert.Equal(t, http.StatusForbidden, post(graphql.NewHandler(), "application/json", body).Code)
+
+	rec := getRequest(url.Values{"extensions": {extensions}})
+	assert.Equal(t, http.StatusMethodNotAllowed, rec.Code)
+}
+
+// TestGETInvalidVariables tests that malformed variables are a bad request
+func TestGETInvalidVariables(t *testing.T) {
+	rec := getRequest(url.Values{"query": {`{ __typename }`}, "variables": {`{not json`}})
+	assert.Equal(t, http.StatusBadRequest, rec.Code)
+}
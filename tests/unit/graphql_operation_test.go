@@ -0,0 +1,63 @@
+package unit
+
+import (
+	"encoding/json"
+	"net/http"
+	"testing"
+	"token-transfer-api/pkg/graphql"
+
+	"github.com/stretchr/testify/assert"
+)
+
+const twoOperations = `query First { first: __typename } query Second { second: __typename }`
+
+// runOperation posts query with operationName and decodes the response
+func runOperation(t *testing.T, query, operationName string) (int, persistedResponse) {
+	body, _ := json.Marshal(graphql.GraphQLRequest{Query: query, OperationName: operationName})
+	rec := post(graphql.NewHandler(), "application/json", string(body))
+
+	var resp persistedResponse
+	assert.NoError(t, json.NewDecoder(rec.Body).Decode(&resp))
+	return rec.Code, resp
+}
+
+// TestOperationNameSelectsOperation tests that each operation of a document can be picked by name
+func TestOperationNameSelectsOperation(t *testing.T) {
+	status, resp := runOperation(t, twoOperations, "First")
+	assert.Equal(t, http.StatusOK, status)
+	assert.Nil(t, resp.Errors)
+	assert.Equal(t, map[string]interface{}{"first": "Query"}, resp.Data)
+
+	status, resp = runOperation(t, twoOperations, "Second")
+	assert.Equal(t, http.StatusOK, status)
+	assert.Nil(t, resp.Errors)
+	assert.Equal(t, map[string]interface{}{"second": "Query"}, resp.Data)
+}
+
+// TestOperationNameRequired tests that a document with several operations needs a name
+func TestOperationNameRequired(t *testing.T) {
+	status, resp := runOperation(t, twoOperations, "")
+	assert.Equal(t, http.StatusBadRequest, status)
+	assert.Nil(t, resp.Data)
+	assert.Equal(t, "OPERATION_NAME_REQUIRED", errorCode(resp))
+}
+
+// TestUnknownOperationName tests that a name matching no operation is rejected
+func TestUnknownOperationName(t *testing.T) {
+	status, resp := runOperation(t, twoOperations, "Third")
+	assert.Equal(t, http.StatusBadRequest, status)
+	assert.Equal(t, "UNKNOWN_OPERATION", errorCode(resp))
+	assert.Equal(t, "Third", resp.Errors[0]["extensions"].(map[string]interface{})["operationName"])
+
+	// A single anonymous operation cannot be picked by name either
+	status, resp = runOperation(t, `{ __typename }`, "First")
+	assert.Equal(t, http.StatusBadRequest, status)
+	assert.Equal(t, "UNKNOWN_OPERATION", errorCode(resp))
+}
+
+// TestSingleOperationNeedsNoName tests that a lone operation runs without operationName
+func TestSingleOperationNeedsNoName(t *testing.T) {
+	status, resp := runOperation(t, `query Only { __typename }`, "")
+	assert.Equal(t, http.StatusOK, status)
+	assert.Nil(t, resp.Errors)
+}
@@ -0,0 +1,125 @@
+package unit
+
+import (
+	"bytes"
+	"context"
+	"log"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strings"
+	"testing"
+	"token-transfer-api/internal/db"
+	"token-transfer-api/internal/model"
+	"token-transfer-api/pkg/graphql"
+
+	gql "github.com/graphql-go/graphql"
+	"github.com/stretchr/testify/assert"
+)
+
+// panickingSchema has a field that panics with a message a client must not
+// see and one that dereferences a nil pointer
+func panickingSchema(t *testing.T) gql.Schema {
+	schema, err := gql.NewSchema(gql.SchemaConfig{
+		Query: gql.NewObject(gql.ObjectConfig{
+			Name: "Query",
+			Fields: gql.Fields{
+				"boom": &gql.Field{
+					Type: gql.String,
+					Resolve: func(p gql.ResolveParams) (interface{}, error) {
+						panic("connection string postgres://app:hunter2@db")
+					},
+				},
+				"balance": &gql.Field{
+					Type: gql.NewNonNull(gql.String),
+					Resolve: func(p gql.ResolveParams) (interface{}, error) {
+						var wallet *model.Wallet
+						return wallet.Balance, nil
+					},
+				},
+				"ok": &gql.Field{
+					Type: gql.String,
+					Resolve: func(p gql.ResolveParams) (interface{}, error) {
+						return "fine", nil
+					},
+				},
+			},
+		}),
+	})
+	if err != nil {
+		t.Fatalf("Failed to create schema: %v", err)
+	}
+	graphql.RecoverResolvers(schema)
+	return schema
+}
+
+// TestPanickingResolverReportsInternalError tests that a panic becomes a generic coded error and is logged with the request ID
+func TestPanickingResolverReportsInternalError(t *testing.T) {
+	var logs bytes.Buffer
+	log.SetOutput(&logs)
+	defer log.SetOutput(os.Stderr)
+
+	result := gql.Do(gql.Params{
+		Schema:        panickingSchema(t),
+		RequestString: `{ boom ok }`,
+		Context:       graphql.WithRequestID(context.Background(), "req-42"),
+	})
+	errs := graphql.SanitizeErrors(result.Errors)
+
+	if assert.Len(t, errs, 1) {
+		assert.Equal(t, db.ErrInternal.Message, errs[0].Message)
+		assert.Equal(t, db.ErrInternal.Code, errs[0].Extensions["code"])
+		assert.NotContains(t, errs[0].Message, "hunter2")
+	}
+	assert.Equal(t, map[string]interface{}{"boom": nil, "ok": "fine"}, result.Data)
+
+	assert.Contains(t, logs.String(), "Panic serving request req-42")
+	assert.Contains(t, logs.String(), "hunter2")
+	assert.Contains(t, logs.String(), "graphql_recover_test.go", "the stack is logged")
+}
+
+// TestNilDereferenceInNonNullResolver tests that a runtime panic under a non-null field fails the operation cleanly
+func TestNilDereferenceInNonNullResolver(t *testing.T) {
+	log.SetOutput(&bytes.Buffer{})
+	defer log.SetOutput(os.Stderr)
+
+	result := gql.Do(gql.Params{
+		Schema:        panickingSchema(t),
+		RequestString: `{ balance }`,
+		Context:       context.Background(),
+	})
+	errs := graphql.SanitizeErrors(result.Errors)
+
+	if assert.Len(t, errs, 1) {
+		assert.Equal(t, db.ErrInternal.Code, errs[0].Extensions["code"])
+		assert.NotContains(t, errs[0].Message, "nil pointer")
+	}
+	assert.Nil(t, result.Data)
+}
+
+// TestRequestIDHeader tests that a client's request ID is echoed and a missing or unsafe one is replaced
+func TestRequestIDHeader(t *testing.T) {
+	handler := graphql.NewHandler()
+
+	send := func(id string) string {
+		req := httptest.NewRequest(http.MethodPost, "/", strings.NewReader(`{"query": "{ __typename }"}`))
+		req.Header.Set("Content-Type", "application/json")
+		if id != "" {
+			req.Header.Set(graphql.RequestIDHeader, id)
+		}
+		rec := httptest.NewRecorder()
+		handler.ServeHTTP(rec, req)
+		assert.Equal(t, http.StatusOK, rec.Code)
+		return rec.Header().Get(graphql.RequestIDHeader)
+	}
+
+	assert.Equal(t, "client-req.7", send("client-req.7"))
+
+	generated := send("")
+	assert.Len(t, generated, 16)
+	assert.NotEqual(t, generated, send(""))
+
+	replaced := send("evil\nlog line")
+	assert.NotContains(t, replaced, "evil")
+	assert.Len(t, replaced, 16)
+}
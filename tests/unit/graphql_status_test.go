@@ -0,0 +1,77 @@
+package unit
+
+import (
+	"encoding/json"
+	"net/http"
+	"strings"
+	"testing"
+	"token-transfer-api/pkg/graphql"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// assertStatus posts body to the handler and checks the status and the code
+// of the first GraphQL error, if any is expected
+func assertStatus(t *testing.T, body string, status int, code interface{}) {
+	t.Helper()
+	rec := post(graphql.NewHandler(), "application/json", body)
+	assert.Equal(t, status, rec.Code, body)
+	assert.Equal(t, "application/json", rec.Header().Get("Content-Type"), body)
+
+	var resp persistedResponse
+	assert.NoError(t, json.NewDecoder(rec.Body).Decode(&resp), body)
+	assert.Equal(t, code, errorCode(resp), body)
+	if code != nil {
+		assert.NotEmpty(t, resp.Errors[0]["message"], body)
+	}
+}
+
+// TestStatusSuccess tests that a successful operation is a 200
+func TestStatusSuccess(t *testing.T) {
+	assertStatus(t, `{"query": "{ __typename }"}`, http.StatusOK, nil)
+}
+
+// TestStatusMalformedBody tests that bodies that are not GraphQL requests are a 400 with an errors array
+func TestStatusMalformedBody(t *testing.T) {
+	for _, body := range []string{`{not json`, `{"query": 5}`, `[{"query": "{ __typename }"}`} {
+		assertStatus(t, body, http.StatusBadRequest, "BAD_REQUEST")
+	}
+}
+
+// TestStatusInvalidDocument tests that syntax and validation errors are a 400
+func TestStatusInvalidDocument(t *testing.T) {
+	rec := post(graphql.NewHandler(), "application/json", `{"query": "{ __typename"}`)
+	assert.Equal(t, http.StatusBadRequest, rec.Code)
+	assert.Contains(t, rec.Body.String(), `"errors"`)
+
+	rec = post(graphql.NewHandler(), "application/json", `{"query": "{ noSuchField }"}`)
+	assert.Equal(t, http.StatusBadRequest, rec.Code)
+	assert.Contains(t, rec.Body.String(), "noSuchField")
+
+	rec = post(graphql.NewHandler(), "application/json", `{"query": "query A { __typename }", "operationName": "B"}`)
+	assert.Equal(t, http.StatusBadRequest, rec.Code)
+}
+
+// TestStatusQueryLimits tests that documents refused by the query limits are a 400
+func TestStatusQueryLimits(t *testing.T) {
+	t.Setenv("GRAPHQL_MAX_DEPTH", "1")
+	assertStatus(t, `{"query": "{ wallet(address: \"0x1\") { balance } }"}`, http.StatusBadRequest, "QUERY_TOO_DEEP")
+}
+
+// TestStatusFieldErrors tests that errors raised while executing keep a 200
+func TestStatusFieldErrors(t *testing.T) {
+	assertStatus(t, `{"query": "{ balances(addresses: [\"nope\"]) { balance } }"}`, http.StatusOK, "INVALID_ADDRESS")
+}
+
+// TestStatusForbidden tests that an operation refused only for lack of permission is a 403
+func TestStatusForbidden(t *testing.T) {
+	assertStatus(t, `{"query": "mutation { burn(from_address: \"0x000000000000000000000000000000000000000a\", amount: \"1\") }"}`, http.StatusForbidden, "UNAUTHORIZED")
+}
+
+// TestStatusUnauthenticated tests that a mutation without an API key is a 401
+func TestStatusUnauthenticated(t *testing.T) {
+	handler := graphql.WithAuth(graphql.NewHandler(), graphql.AuthConfig{APIKeys: []string{"k1"}, PublicQueries: true})
+	rec := post(handler, "application/json", `{"query": "mutation { burn(from_address: \"0x000000000000000000000000000000000000000a\", amount: \"1\") }"}`)
+	assert.Equal(t, http.StatusUnauthorized, rec.Code)
+	assert.True(t, strings.Contains(rec.Body.String(), "UNAUTHENTICATED"))
+}
@@ -0,0 +1,101 @@
+package unit
+
+import (
+	"context"
+	"encoding/json"
+	"net"
+	"testing"
+	"token-transfer-api/internal/graph"
+	"token-transfer-api/pkg/graphql"
+	"token-transfer-api/pkg/grpcserver"
+	"token-transfer-api/pkg/grpcserver/walletpb"
+
+	"github.com/stretchr/testify/assert"
+	"google.golang.org/genproto/googleapis/rpc/errdetails"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/status"
+	"google.golang.org/grpc/test/bufconn"
+)
+
+// startGRPC serves the wallet service in-process and returns a client for it
+func startGRPC(t *testing.T, auth graphql.AuthConfig) walletpb.WalletServiceClient {
+	resolver, err := graph.NewResolver()
+	if err != nil {
+		t.Fatalf("Failed to create resolver: %v", err)
+	}
+	return startGRPCFor(t, resolver, auth)
+}
+
+// startGRPCFor serves the wallet service on resolver in-process and returns
+// a client for it
+func startGRPCFor(t *testing.T, resolver *graph.Resolver, auth graphql.AuthConfig) walletpb.WalletServiceClient {
+	server := grpcserver.New(resolver, auth)
+	lis := bufconn.Listen(1 << 20)
+	go server.Serve(lis)
+	t.Cleanup(server.Stop)
+
+	conn, err := grpc.NewClient("passthrough:///bufnet",
+		grpc.WithContextDialer(func(ctx context.Context, _ string) (net.Conn, error) { return lis.DialContext(ctx) }),
+		grpc.WithTransportCredentials(insecure.NewCredentials()))
+	if err != nil {
+		t.Fatalf("Failed to dial gRPC server: %v", err)
+	}
+	t.Cleanup(func() { conn.Close() })
+	return walletpb.NewWalletServiceClient(conn)
+}
+
+// TestGRPCInvalidAmount tests that a bad amount maps to InvalidArgument with the app error code attached
+func TestGRPCInvalidAmount(t *testing.T) {
+	client := startGRPC(t, graphql.AuthConfig{})
+
+	_, err := client.Transfer(context.Background(), &walletpb.TransferRequest{
+		FromAddress: "0xabcdef0000000000000000000000000000000001",
+		ToAddress:   "0xabcdef0000000000000000000000000000000002",
+		Amount:      "-1",
+	})
+	st := status.Convert(err)
+	assert.Equal(t, codes.InvalidArgument, st.Code())
+	assert.Equal(t, "invalid amount", st.Message())
+	if assert.Len(t, st.Details(), 1) {
+		info := st.Details()[0].(*errdetails.ErrorInfo)
+		assert.Equal(t, "INVALID_AMOUNT", info.Reason)
+		assert.Equal(t, grpcserver.ErrorDomain, info.Domain)
+	}
+}
+
+// TestGRPCAuth tests that transfers need a key in the authorization metadata when keys are configured
+func TestGRPCAuth(t *testing.T) {
+	client := startGRPC(t, graphql.AuthConfig{APIKeys: []string{"secret"}, PublicQueries: true})
+
+	_, err := client.Transfer(context.Background(), &walletpb.TransferRequest{Amount: "-1"})
+	assert.Equal(t, codes.Unauthenticated, status.Code(err))
+
+	// Public lookups pass the interceptor and fail only on the address
+	_, err = client.GetWallet(context.Background(), &walletpb.GetWalletRequest{Address: "nope"})
+	assert.Equal(t, codes.InvalidArgument, status.Code(err))
+}
+
+// TestGRPCSharesRateLimitWithGraphQL tests that gRPC and GraphQL served by
+// one resolver count a sender's transfers against the same rate limit
+func TestGRPCSharesRateLimitWithGraphQL(t *testing.T) {
+	t.Setenv("TRANSFER_RATE_LIMIT", "1")
+	resolver, err := graph.NewResolver()
+	if err != nil {
+		t.Fatalf("Failed to create resolver: %v", err)
+	}
+	sender := "0xabcdef0000000000000000000000000000000013"
+	receiver := "0xabcdef0000000000000000000000000000000014"
+
+	query, _ := json.Marshal(map[string]interface{}{
+		"query":     `mutation($from: Address!, $to: Address!) { transfer(from_address: $from, to_address: $to, amount: "1") { balance } }`,
+		"variables": map[string]string{"from": sender, "to": receiver},
+	})
+	rec := post(graphql.NewHandlerFor(resolver), "application/json", string(query))
+	assert.NotContains(t, rec.Body.String(), "RATE_LIMITED")
+
+	client := startGRPCFor(t, resolver, graphql.AuthConfig{})
+	_, err = client.Transfer(context.Background(), &walletpb.TransferRequest{FromAddress: sender, ToAddress: receiver, Amount: "1"})
+	assert.Equal(t, codes.ResourceExhausted, status.Code(err))
+}
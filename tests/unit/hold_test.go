@@ -0,0 +1,28 @@
+package unit
+
+import (
+	"testing"
+	"token-transfer-api/internal/db"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// TestCreateHoldValidatesBeforeDatabase tests that a hold's amount and sender are checked before the database is reached
+func TestCreateHoldValidatesBeforeDatabase(t *testing.T) {
+	saved := db.Settings
+	defer func() { db.Settings = saved }()
+	const sender = "0x4900000000000000000000000000000000000001"
+
+	db.Settings = db.Config{}
+	for _, amount := range []string{"0", "-5", "1.5", "abc"} {
+		_, err := db.CreateHold(sender, amount)
+		assert.ErrorIs(t, err, db.ErrInvalidAmount, amount)
+	}
+
+	db.Settings = db.Config{ZeroAddressReserved: true}
+	_, err := db.CreateHold(db.ZeroAddress, "1")
+	assert.ErrorIs(t, err, db.ErrReservedAddress)
+
+	_, err = db.CreateHold(sender, "1")
+	assert.ErrorIs(t, err, db.ErrNotInitialized)
+}
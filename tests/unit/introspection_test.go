@@ -0,0 +1,73 @@
+package unit
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"token-transfer-api/pkg/graphql"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// introspect posts query to a handler built with the current environment
+func introspect(t *testing.T, query string) persistedResponse {
+	body, _ := json.Marshal(graphql.GraphQLRequest{Query: query})
+	rec := httptest.NewRecorder()
+	graphql.NewHandler().ServeHTTP(rec, httptest.NewRequest(http.MethodPost, "/", bytes.NewBuffer(body)))
+
+	var resp persistedResponse
+	assert.NoError(t, json.NewDecoder(rec.Body).Decode(&resp))
+	return resp
+}
+
+// TestIntrospectionDisabled tests that __schema and __type are rejected when the flag is off
+func TestIntrospectionDisabled(t *testing.T) {
+	t.Setenv("GRAPHQL_INTROSPECTION", "false")
+
+	for _, query := range []string{
+		`{ __schema { queryType { name } } }`,
+		`{ __type(name: "Wallet") { name } }`,
+		`{ totalSupply ...Schema } fragment Schema on Query { __schema { types { name } } }`,
+		`{ ... on Query { __type(name: "Wallet") { name } } }`,
+	} {
+		resp := introspect(t, query)
+		assert.Nil(t, resp.Data, query)
+		assert.Equal(t, "INTROSPECTION_DISABLED", errorCode(resp), query)
+	}
+
+	// __typename is not introspection of the schema
+	resp := introspect(t, `{ __typename }`)
+	assert.Nil(t, resp.Errors)
+	assert.Equal(t, "Query", resp.Data["__typename"])
+}
+
+// TestIntrospectionEnabled tests that introspection works when the flag is on, even in production
+func TestIntrospectionEnabled(t *testing.T) {
+	t.Setenv("ENV", "production")
+	t.Setenv("GRAPHQL_INTROSPECTION", "true")
+
+	resp := introspect(t, `{ __schema { queryType { name } } }`)
+	assert.Nil(t, resp.Errors)
+	assert.Equal(t, "Query", resp.Data["__schema"].(map[string]interface{})["queryType"].(map[string]interface{})["name"])
+}
+
+// TestIntrospectionFromEnv tests the per-environment default and the override
+func TestIntrospectionFromEnv(t *testing.T) {
+	t.Setenv("GRAPHQL_INTROSPECTION", "")
+
+	t.Setenv("ENV", "development")
+	enabled, err := graphql.IntrospectionFromEnv()
+	assert.NoError(t, err)
+	assert.True(t, enabled)
+
+	t.Setenv("ENV", "production")
+	enabled, err = graphql.IntrospectionFromEnv()
+	assert.NoError(t, err)
+	assert.False(t, enabled)
+
+	t.Setenv("GRAPHQL_INTROSPECTION", "maybe")
+	_, err = graphql.IntrospectionFromEnv()
+	assert.Error(t, err)
+}
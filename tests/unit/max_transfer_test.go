@@ -0,0 +1,47 @@
+package unit
+
+import (
+	"context"
+	"math/big"
+	"testing"
+	"token-transfer-api/internal/db"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// TestMaxTransferAmountConfig tests that MAX_TRANSFER_AMOUNT is parsed and empty leaves transfers unlimited
+func TestMaxTransferAmountConfig(t *testing.T) {
+	t.Setenv("MAX_TRANSFER_AMOUNT", "")
+	cfg, err := db.LoadConfig()
+	assert.NoError(t, err)
+	assert.Nil(t, cfg.MaxTransferAmount)
+
+	t.Setenv("MAX_TRANSFER_AMOUNT", "100000000000000000000000000000000000000000000000")
+	cfg, err = db.LoadConfig()
+	assert.NoError(t, err)
+	assert.Equal(t, "100000000000000000000000000000000000000000000000", cfg.MaxTransferAmount.String())
+
+	for _, v := range []string{"0", "-5", "1.5", "lots"} {
+		t.Setenv("MAX_TRANSFER_AMOUNT", v)
+		_, err = db.LoadConfig()
+		assert.Error(t, err, v)
+	}
+}
+
+// TestTransferOverMaxAmountRejected tests that TransferTokens refuses amounts above the ceiling before touching the database
+func TestTransferOverMaxAmountRejected(t *testing.T) {
+	saved := db.Settings
+	defer func() { db.Settings = saved }()
+	db.Settings = db.Config{MaxTransferAmount: big.NewInt(100)}
+
+	_, err := db.ExecuteTransfer(context.Background(),
+		"0x4100000000000000000000000000000000000001",
+		"0x4100000000000000000000000000000000000002",
+		"101", "", nil, "")
+	assert.ErrorIs(t, err, db.ErrAmountTooLarge)
+
+	var appErr *db.AppError
+	if assert.ErrorAs(t, err, &appErr) {
+		assert.Equal(t, "100", appErr.Details["max"])
+	}
+}
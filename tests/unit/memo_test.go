@@ -0,0 +1,29 @@
+package unit
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"token-transfer-api/internal/db"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// TestMemoTooLongRejected tests that an overlong memo is refused before the database is touched
+func TestMemoTooLongRejected(t *testing.T) {
+	memo := strings.Repeat("a", db.MaxMemoLength+1)
+
+	_, err := db.ExecuteTransfer(context.Background(),
+		"0x3300000000000000000000000000000000000001",
+		"0x3300000000000000000000000000000000000002",
+		"100", memo, nil, "")
+	assert.ErrorIs(t, err, db.ErrMemoTooLong)
+
+	result, err := db.SimulateTransfer(context.Background(),
+		"0x3300000000000000000000000000000000000001",
+		"0x3300000000000000000000000000000000000002",
+		"100", memo, nil, "")
+	assert.NoError(t, err)
+	assert.False(t, result.WouldSucceed)
+	assert.Equal(t, db.ErrMemoTooLong.Code, result.FailureCode)
+}
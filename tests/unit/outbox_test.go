@@ -0,0 +1,57 @@
+package unit
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+	"token-transfer-api/internal/model"
+	"token-transfer-api/pkg/outbox"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// TestOutboxConfigFromEnv tests the defaults and rejects bad durations
+func TestOutboxConfigFromEnv(t *testing.T) {
+	t.Setenv("TRANSFER_EVENTS_URL", "")
+	t.Setenv("TRANSFER_EVENTS_POLL_INTERVAL", "")
+	t.Setenv("TRANSFER_EVENTS_TIMEOUT", "")
+	cfg, err := outbox.ConfigFromEnv()
+	assert.NoError(t, err)
+	assert.Equal(t, outbox.Config{PollInterval: time.Second, Timeout: 10 * time.Second}, cfg)
+
+	t.Setenv("TRANSFER_EVENTS_URL", "http://events.internal/transfers")
+	t.Setenv("TRANSFER_EVENTS_POLL_INTERVAL", "250ms")
+	cfg, err = outbox.ConfigFromEnv()
+	assert.NoError(t, err)
+	assert.Equal(t, "http://events.internal/transfers", cfg.URL)
+	assert.Equal(t, 250*time.Millisecond, cfg.PollInterval)
+
+	for _, v := range []string{"0s", "-1s", "soon"} {
+		t.Setenv("TRANSFER_EVENTS_POLL_INTERVAL", v)
+		_, err = outbox.ConfigFromEnv()
+		assert.Error(t, err, v)
+	}
+}
+
+// TestOutboxDeliver tests that the payload is posted with the event id and non-2xx answers fail
+func TestOutboxDeliver(t *testing.T) {
+	status := http.StatusNoContent
+	var gotID, gotBody string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		gotID, gotBody = r.Header.Get(outbox.EventIDHeader), string(body)
+		w.WriteHeader(status)
+	}))
+	defer server.Close()
+
+	event := &model.TransferEvent{ID: 42, Payload: []byte(`{"amount":"100"}`)}
+	assert.NoError(t, outbox.Deliver(context.Background(), server.Client(), server.URL, event))
+	assert.Equal(t, "42", gotID)
+	assert.Equal(t, `{"amount":"100"}`, gotBody)
+
+	status = http.StatusInternalServerError
+	assert.Error(t, outbox.Deliver(context.Background(), server.Client(), server.URL, event))
+}
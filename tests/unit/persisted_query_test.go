@@ -0,0 +1,178 @@
+package unit
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"token-transfer-api/pkg/graphql"
+
+	"github.com/stretchr/testify/assert"
+)
+
+type persistedResponse struct {
+	Data   map[string]interface{}   `json:"data"`
+	Errors []map[string]interface{} `json:"errors"`
+}
+
+func queryHash(query string) string {
+	sum := sha256.Sum256([]byte(query))
+	return hex.EncodeToString(sum[:])
+}
+
+// persistedRequest posts a request carrying hash as its persisted query
+func persistedRequest(t *testing.T, handler http.Handler, query, hash string) persistedResponse {
+	body, _ := json.Marshal(graphql.GraphQLRequest{
+		Query: query,
+		Extensions: &graphql.RequestExtensions{
+			PersistedQuery: &graphql.PersistedQuery{Version: 1, Sha256Hash: hash},
+		},
+	})
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, httptest.NewRequest(http.MethodPost, "/", bytes.NewBuffer(body)))
+	assert.Equal(t, http.StatusOK, rec.Code)
+
+	var resp persistedResponse
+	assert.NoError(t, json.NewDecoder(rec.Body).Decode(&resp))
+	return resp
+}
+
+func errorCode(resp persistedResponse) interface{} {
+	if len(resp.Errors) == 0 {
+		return nil
+	}
+	return resp.Errors[0]["extensions"].(map[string]interface{})["code"]
+}
+
+// TestPersistedQueryFlow tests the not-found, register and cache-hit steps of a client
+func TestPersistedQueryFlow(t *testing.T) {
+	handler := graphql.NewHandler()
+	query := `{ flow: __typename }`
+	hash := queryHash(query)
+
+	// Unknown hash: the client is asked to send the query
+	resp := persistedRequest(t, handler, "", hash)
+	assert.Equal(t, "PERSISTED_QUERY_NOT_FOUND", errorCode(resp))
+	assert.Equal(t, "PersistedQueryNotFound", resp.Errors[0]["message"])
+	assert.Nil(t, resp.Data)
+
+	// Query and hash: registered and executed
+	resp = persistedRequest(t, handler, query, hash)
+	assert.Nil(t, resp.Errors)
+	assert.Equal(t, "Query", resp.Data["flow"])
+
+	// Hash only: served from the cache
+	resp = persistedRequest(t, handler, "", hash)
+	assert.Nil(t, resp.Errors)
+	assert.Equal(t, "Query", resp.Data["flow"])
+}
+
+// TestPersistedQueryHashMismatch tests that a query is not registered under someone else's hash
+func TestPersistedQueryHashMismatch(t *testing.T) {
+	handler := graphql.NewHandler()
+	hash := queryHash(`{ expected: __typename }`)
+
+	resp := persistedRequest(t, handler, `{ other: __typename }`, hash)
+	assert.Equal(t, "PERSISTED_QUERY_HASH_MISMATCH", errorCode(resp))
+
+	resp = persistedRequest(t, handler, "", hash)
+	assert.Equal(t, "PERSISTED_QUERY_NOT_FOUND", errorCode(resp))
+}
+
+// TestPersistedQueryHashIsCaseInsensitive tests that an uppercase hex hash finds the registered query
+func TestPersistedQueryHashIsCaseInsensitive(t *testing.T) {
+	handler := graphql.NewHandler()
+	query := `{ upper: __typename }`
+
+	resp := persistedRequest(t, handler, query, queryHash(query))
+	assert.Nil(t, resp.Errors)
+
+	upper := []byte(queryHash(query))
+	for i, c := range upper {
+		if c >= 'a' && c <= 'f' {
+			upper[i] = c - 'a' + 'A'
+		}
+	}
+	resp = persistedRequest(t, handler, "", string(upper))
+	assert.Nil(t, resp.Errors)
+	assert.Equal(t, "Query", resp.Data["upper"])
+}
+
+// TestPersistedMutationNeedsKey tests that a mutation sent by hash alone
+// still needs an API key, and that a request refused for lack of one does
+// not register its query
+func TestPersistedMutationNeedsKey(t *testing.T) {
+	mutation := `mutation { burn(from_address: "0x00000000000000000000000000000000000000ff", amount: "1") }`
+	hash := queryHash(mutation)
+	cfg := graphql.AuthConfig{APIKeys: []string{"k1"}, PublicQueries: true}
+
+	reached := false
+	next := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) { reached = true })
+
+	send := func(query, token string) (int, persistedResponse) {
+		body, _ := json.Marshal(graphql.GraphQLRequest{
+			Query: query,
+			Extensions: &graphql.RequestExtensions{
+				PersistedQuery: &graphql.PersistedQuery{Version: 1, Sha256Hash: hash},
+			},
+		})
+		req := httptest.NewRequest(http.MethodPost, "/", bytes.NewBuffer(body))
+		if token != "" {
+			req.Header.Set("Authorization", "Bearer "+token)
+		}
+		rec := httptest.NewRecorder()
+		graphql.WithAuth(next, cfg).ServeHTTP(rec, req)
+
+		var resp persistedResponse
+		json.NewDecoder(rec.Body).Decode(&resp)
+		return rec.Code, resp
+	}
+
+	// Not registered yet: refused, since it could stand for a mutation
+	status, resp := send("", "")
+	assert.Equal(t, http.StatusOK, status)
+	assert.Equal(t, "PERSISTED_QUERY_NOT_FOUND", errorCode(resp))
+	assert.False(t, reached)
+
+	status, _ = send(mutation, "")
+	assert.Equal(t, http.StatusUnauthorized, status)
+	assert.False(t, reached)
+
+	// The refused request did not register the mutation
+	_, resp = send("", "")
+	assert.Equal(t, "PERSISTED_QUERY_NOT_FOUND", errorCode(resp))
+	assert.False(t, reached)
+
+	// With a key it is registered, and the hash alone is then recognized
+	// as a mutation
+	status, _ = send(mutation, "k1")
+	assert.Equal(t, http.StatusOK, status)
+	assert.True(t, reached)
+
+	reached = false
+	status, _ = send("", "")
+	assert.Equal(t, http.StatusUnauthorized, status)
+	assert.False(t, reached)
+}
+
+// TestPersistedQueryFlowBehindAuth tests that a public query registered
+// and run without a key goes through the handler as WithAuth resolved it
+func TestPersistedQueryFlowBehindAuth(t *testing.T) {
+	handler := graphql.WithAuth(graphql.NewHandler(), graphql.AuthConfig{APIKeys: []string{"k1"}, PublicQueries: true})
+	query := `{ behindAuth: __typename }`
+	hash := queryHash(query)
+
+	resp := persistedRequest(t, handler, "", hash)
+	assert.Equal(t, "PERSISTED_QUERY_NOT_FOUND", errorCode(resp))
+
+	resp = persistedRequest(t, handler, query, hash)
+	assert.Nil(t, resp.Errors)
+	assert.Equal(t, "Query", resp.Data["behindAuth"])
+
+	resp = persistedRequest(t, handler, "", hash)
+	assert.Nil(t, resp.Errors)
+	assert.Equal(t, "Query", resp.Data["behindAuth"])
+}
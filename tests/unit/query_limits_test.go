@@ -0,0 +1,189 @@
+package unit
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"token-transfer-api/internal/db"
+	"token-transfer-api/pkg/graphql"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// introspectionQuery is the shape of the query GraphQL tools send, with the
+// usual seven levels of ofType
+const introspectionQuery = `
+query IntrospectionQuery {
+	__schema {
+		queryType { name }
+		mutationType { name }
+		types { ...FullType }
+	}
+}
+fragment FullType on __Type {
+	kind
+	name
+	fields(includeDeprecated: true) {
+		name
+		args { ...InputValue }
+		type { ...TypeRef }
+	}
+	inputFields { ...InputValue }
+	enumValues(includeDeprecated: true) { name }
+	possibleTypes { ...TypeRef }
+}
+fragment InputValue on __InputValue {
+	name
+	type { ...TypeRef }
+	defaultValue
+}
+fragment TypeRef on __Type {
+	kind name
+	ofType { kind name ofType { kind name ofType { kind name ofType { kind name
+		ofType { kind name ofType { kind name ofType { kind name } } } } } } }
+}`
+
+// nested returns a query selecting ofType depth times below __type
+func nested(depth int) string {
+	return `{ __type(name: "Query") { ` + strings.Repeat("ofType { ", depth) + "name" + strings.Repeat(" }", depth) + " } }"
+}
+
+// TestQueryDepthLimit tests that queries deeper than MaxDepth are rejected and shallower ones pass
+func TestQueryDepthLimit(t *testing.T) {
+	limits := graphql.QueryLimits{MaxDepth: 3}
+
+	assert.Nil(t, limits.Check(nil, `{ transfers { pageInfo { hasNextPage } } }`, nil))
+
+	err := limits.Check(nil, `{ transfers { edges { node { id } } } }`, nil)
+	assert.ErrorIs(t, err, db.ErrQueryTooDeep)
+	assert.Equal(t, 4, err.Details["depth"])
+	assert.Equal(t, 3, err.Details["max_depth"])
+
+	// Fragments count at the depth they are spread
+	err = limits.Check(nil, `{ transfers { edges { ...Edge } } } fragment Edge on TransferEdge { node { id } }`, nil)
+	assert.ErrorIs(t, err, db.ErrQueryTooDeep)
+}
+
+// TestIntrospectionDepthLimit tests that introspection is allowed up to its own cap
+func TestIntrospectionDepthLimit(t *testing.T) {
+	limits := graphql.DefaultQueryLimits
+
+	assert.Nil(t, limits.Check(nil, introspectionQuery, nil))
+	assert.Nil(t, limits.Check(nil, nested(limits.MaxIntrospectionDepth-2), nil))
+	assert.ErrorIs(t, limits.Check(nil, nested(limits.MaxIntrospectionDepth), nil), db.ErrQueryTooDeep)
+
+	// Introspection next to data selections gets the normal limit
+	mixed := `{ totalSupply ` + strings.TrimPrefix(nested(limits.MaxDepth), "{")
+	assert.ErrorIs(t, limits.Check(nil, mixed, nil), db.ErrQueryTooDeep)
+}
+
+// TestQueryComplexityLimit tests that list sizes multiply the cost of the fields below them
+func TestQueryComplexityLimit(t *testing.T) {
+	limits := graphql.QueryLimits{MaxComplexity: 100}
+
+	// 1 + 20 * (1 + 1 + 1)
+	assert.Nil(t, limits.Check(nil, `{ walletsConnection(limit: 20) { nodes { address } } }`, nil))
+
+	err := limits.Check(nil, `{ walletsConnection(limit: 50) { nodes { address balance } } }`, nil)
+	assert.ErrorIs(t, err, db.ErrQueryTooComplex)
+	assert.Equal(t, 151, err.Details["complexity"])
+
+	// Sizes passed as variables count too
+	query := `query($n: Int) { walletsConnection(limit: $n) { nodes { address balance } } }`
+	assert.Nil(t, limits.Check(nil, query, map[string]interface{}{"n": float64(10)}))
+	assert.ErrorIs(t, limits.Check(nil, query, map[string]interface{}{"n": float64(1e12)}), db.ErrQueryTooComplex)
+}
+
+// TestRepeatedFragmentsStayCheap tests that a fragment spread many times is measured once
+func TestRepeatedFragmentsStayCheap(t *testing.T) {
+	// Each fragment spreads the next one twice: 2^40 paths if fragments
+	// were expanded, each of which doubles the estimated cost
+	var doc strings.Builder
+	doc.WriteString(`{ ...` + fragName(0) + ` }`)
+	for i := 0; i < 40; i++ {
+		next := fragName(i + 1)
+		doc.WriteString(" fragment " + fragName(i) + " on Query { ..." + next + " ..." + next + " }")
+	}
+	doc.WriteString(" fragment " + fragName(40) + " on Query { totalSupply }")
+
+	assert.ErrorIs(t, graphql.DefaultQueryLimits.Check(nil, doc.String(), nil), db.ErrQueryTooComplex)
+}
+
+// fragName returns a distinct fragment name for i
+func fragName(i int) string {
+	return "F" + strings.Repeat("x", i)
+}
+
+// TestQueryLimitsFromEnv tests the environment settings and their defaults
+func TestQueryLimitsFromEnv(t *testing.T) {
+	limits, err := graphql.QueryLimitsFromEnv()
+	assert.NoError(t, err)
+	assert.Equal(t, graphql.DefaultQueryLimits, limits)
+
+	t.Setenv("GRAPHQL_MAX_DEPTH", "5")
+	t.Setenv("GRAPHQL_MAX_COMPLEXITY", "0")
+	limits, err = graphql.QueryLimitsFromEnv()
+	assert.NoError(t, err)
+	assert.Equal(t, 5, limits.MaxDepth)
+	assert.Equal(t, 0, limits.MaxComplexity)
+	assert.Equal(t, graphql.DefaultQueryLimits.MaxIntrospectionDepth, limits.MaxIntrospectionDepth)
+
+	t.Setenv("GRAPHQL_MAX_INTROSPECTION_DEPTH", "-1")
+	_, err = graphql.QueryLimitsFromEnv()
+	assert.Error(t, err)
+}
+
+// TestHandlerRejectsDeepQuery tests that the handler answers an over-deep query with QUERY_TOO_DEEP before executing it
+func TestHandlerRejectsDeepQuery(t *testing.T) {
+	t.Setenv("GRAPHQL_MAX_DEPTH", "3")
+	handler := graphql.NewHandler()
+
+	body, _ := json.Marshal(graphql.GraphQLRequest{Query: `{ transfers { edges { node { id } } } }`})
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, httptest.NewRequest(http.MethodPost, "/", bytes.NewBuffer(body)))
+
+	var resp persistedResponse
+	assert.NoError(t, json.NewDecoder(rec.Body).Decode(&resp))
+	assert.Nil(t, resp.Data)
+	assert.Equal(t, "QUERY_TOO_DEEP", errorCode(resp))
+
+	// Within the limit the query runs
+	body, _ = json.Marshal(graphql.GraphQLRequest{Query: `{ __typename }`})
+	rec = httptest.NewRecorder()
+	handler.ServeHTTP(rec, httptest.NewRequest(http.MethodPost, "/", bytes.NewBuffer(body)))
+	resp = persistedResponse{}
+	assert.NoError(t, json.NewDecoder(rec.Body).Decode(&resp))
+	assert.Nil(t, resp.Errors)
+	assert.Equal(t, "Query", resp.Data["__typename"])
+}
+
+// TestHandlerCountsDefaultPageSizes tests that lists without a page size
+// argument count their default page size, and that a list argument counts
+// its elements
+func TestHandlerCountsDefaultPageSizes(t *testing.T) {
+	handler := graphql.NewHandler()
+	check := func(query string) persistedResponse {
+		body, _ := json.Marshal(graphql.GraphQLRequest{Query: query})
+		rec := httptest.NewRecorder()
+		handler.ServeHTTP(rec, httptest.NewRequest(http.MethodPost, "/", bytes.NewBuffer(body)))
+
+		var resp persistedResponse
+		assert.NoError(t, json.NewDecoder(rec.Body).Decode(&resp))
+		return resp
+	}
+
+	// 1 + 20 * (1 + 20 * (1 + 1 + 20 * 1))
+	resp := check(`{ wallets { transfers { sender { transfers { id } } } } }`)
+	assert.Equal(t, "QUERY_TOO_COMPLEX", errorCode(resp))
+	if assert.NotEmpty(t, resp.Errors) {
+		assert.Equal(t, float64(8821), resp.Errors[0]["extensions"].(map[string]interface{})["complexity"])
+	}
+
+	// 1 + 50 * (1 + 20 * 1)
+	addresses := strings.Repeat(`"0x0000000000000000000000000000000000000001", `, 50)
+	resp = check(`{ balances(addresses: [` + addresses + `]) { transfers { id } } }`)
+	assert.Equal(t, "QUERY_TOO_COMPLEX", errorCode(resp))
+}
@@ -0,0 +1,60 @@
+package unit
+
+import (
+	"context"
+	"testing"
+	"time"
+	"token-transfer-api/pkg/ramp"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// TestRampLimitIncreases tests that the allowed concurrency grows over the period
+func TestRampLimitIncreases(t *testing.T) {
+	r := ramp.Ramp{Initial: 2, Max: 10, Period: 10 * time.Second}
+
+	assert.Equal(t, 2, r.Limit(0))
+	assert.Equal(t, 6, r.Limit(5*time.Second))
+	assert.Equal(t, 10, r.Limit(10*time.Second))
+	assert.Equal(t, 10, r.Limit(time.Minute))
+
+	previous := r.Limit(0)
+	for elapsed := time.Duration(0); elapsed <= r.Period; elapsed += time.Second {
+		limit := r.Limit(elapsed)
+		assert.GreaterOrEqual(t, limit, previous, "limit must never decrease")
+		previous = limit
+	}
+}
+
+// TestGateHoldsInitialCap tests that early load stays below the initial cap
+func TestGateHoldsInitialCap(t *testing.T) {
+	gate := ramp.NewGate(ramp.Ramp{Initial: 3, Max: 50, Period: time.Hour})
+
+	for i := 0; i < 3; i++ {
+		assert.NoError(t, gate.Acquire(context.Background()))
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	assert.ErrorIs(t, gate.Acquire(ctx), context.DeadlineExceeded)
+	assert.Equal(t, 3, gate.InFlight())
+
+	// Releasing a slot lets the next caller in
+	gate.Release()
+	assert.NoError(t, gate.Acquire(context.Background()))
+	assert.Equal(t, 3, gate.InFlight())
+}
+
+// TestGateRampsUp tests that the gate admits more callers once the ramp has elapsed
+func TestGateRampsUp(t *testing.T) {
+	gate := ramp.NewGate(ramp.Ramp{Initial: 1, Max: 4, Period: 100 * time.Millisecond})
+
+	assert.NoError(t, gate.Acquire(context.Background()))
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+	for i := 0; i < 3; i++ {
+		assert.NoError(t, gate.Acquire(ctx))
+	}
+	assert.Equal(t, 4, gate.InFlight())
+}
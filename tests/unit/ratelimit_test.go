@@ -0,0 +1,54 @@
+package unit
+
+import (
+	"testing"
+	"time"
+	"token-transfer-api/pkg/ratelimit"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// TestRateLimitFloodRejected tests that a key is cut off once its burst is spent
+func TestRateLimitFloodRejected(t *testing.T) {
+	limiter := ratelimit.PerMinute(5)
+
+	for i := 0; i < 5; i++ {
+		ok, _ := limiter.Allow("flooder")
+		assert.True(t, ok, "call %d should be allowed", i)
+	}
+
+	ok, wait := limiter.Allow("flooder")
+	assert.False(t, ok)
+	assert.Greater(t, wait, time.Duration(0))
+	assert.LessOrEqual(t, wait, 12*time.Second)
+
+	// Other keys have their own bucket
+	ok, _ = limiter.Allow("bystander")
+	assert.True(t, ok)
+}
+
+// TestRateLimitRefills tests that tokens come back over time
+func TestRateLimitRefills(t *testing.T) {
+	limiter := ratelimit.New(1, 20*time.Millisecond)
+
+	ok, _ := limiter.Allow("a")
+	assert.True(t, ok)
+	ok, _ = limiter.Allow("a")
+	assert.False(t, ok)
+
+	time.Sleep(30 * time.Millisecond)
+	ok, _ = limiter.Allow("a")
+	assert.True(t, ok)
+}
+
+// TestRateLimitDropsIdleBuckets tests that fully refilled buckets are cleaned up
+func TestRateLimitDropsIdleBuckets(t *testing.T) {
+	limiter := ratelimit.New(2, 10*time.Millisecond)
+
+	limiter.Allow("a")
+	limiter.Allow("b")
+	assert.Equal(t, 2, limiter.Len())
+
+	time.Sleep(30 * time.Millisecond)
+	assert.Equal(t, 0, limiter.Len())
+}
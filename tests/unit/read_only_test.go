@@ -0,0 +1,105 @@
+package unit
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"token-transfer-api/internal/db"
+	"token-transfer-api/internal/graph"
+	"token-transfer-api/pkg/graphql"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// TestReadOnlyRejectsMutationsBeforeDB tests that every kind of mutation fails with READ_ONLY without a database
+func TestReadOnlyRejectsMutationsBeforeDB(t *testing.T) {
+	defer graph.SetReadOnly(false)
+	t.Setenv("READ_ONLY", "true")
+	t.Setenv("ADMIN_ADDRESS", "0x5800000000000000000000000000000000000009")
+	t.Setenv("MINTER_ADDRESS", "0x5800000000000000000000000000000000000009")
+	admin := "0x5800000000000000000000000000000000000009"
+	auth := graphql.AuthConfig{APIKeys: []string{"admin-key"}, Callers: map[string]string{"admin-key": admin}}
+	handler := graphql.WithAuth(graphql.NewHandler(), auth)
+	assert.True(t, graph.ReadOnly())
+
+	from := "0x5800000000000000000000000000000000000001"
+	to := "0x5800000000000000000000000000000000000002"
+	for _, mutation := range []string{
+		`transfer(from_address: "` + from + `", to_address: "` + to + `", amount: "1") { balance }`,
+		`transfer(from_address: "` + from + `", to_address: "` + to + `", amount: "1", dry_run: true) { balance }`,
+		`mint(to_address: "` + to + `", amount: "1") { balance }`,
+		`burn(from_address: "` + from + `", amount: "1")`,
+		`addAddressToBlocklist(address: "` + from + `")`,
+		`freezeWallet(address: "` + from + `") { address }`,
+		`refundTransfer(transfer_id: 1) { transfer { id } }`,
+		`sweep(from_address: "` + from + `", to_address: "` + to + `") { from_balance }`,
+		`cancelHold(hold_id: 1) { id }`,
+	} {
+		body, _ := json.Marshal(graphql.GraphQLRequest{Query: "mutation { " + mutation + " }"})
+		req := httptest.NewRequest(http.MethodPost, "/", strings.NewReader(string(body)))
+		req.Header.Set("Content-Type", "application/json")
+		req.Header.Set("Authorization", "Bearer admin-key")
+		rec := httptest.NewRecorder()
+		handler.ServeHTTP(rec, req)
+
+		var resp persistedResponse
+		assert.NoError(t, json.NewDecoder(rec.Body).Decode(&resp), mutation)
+		assert.Equal(t, "READ_ONLY", errorCode(resp), mutation)
+	}
+}
+
+// TestSetReadOnlyMode tests that only the admin switches read-only mode
+func TestSetReadOnlyMode(t *testing.T) {
+	defer graph.SetReadOnly(false)
+	resolver := &graph.Resolver{AdminAddress: "0x5800000000000000000000000000000000000009"}
+
+	_, err := resolver.SetReadOnlyMode(graph.WithCaller(context.Background(), "0x5800000000000000000000000000000000000001"), true)
+	assert.ErrorIs(t, err, db.ErrUnauthorized)
+	assert.False(t, graph.ReadOnly())
+
+	admin := graph.WithCaller(context.Background(), resolver.AdminAddress)
+	enabled, err := resolver.SetReadOnlyMode(admin, true)
+	assert.NoError(t, err)
+	assert.True(t, enabled)
+	assert.True(t, graph.ReadOnly())
+
+	_, err = resolver.Transfer(context.Background(), graph.TransferArgs{FromAddress: "0x5800000000000000000000000000000000000001", ToAddress: "0x5800000000000000000000000000000000000002", Amount: "1"})
+	assert.ErrorIs(t, err, db.ErrReadOnly)
+	assert.True(t, db.ErrReadOnly.Retryable())
+
+	// Switching back works while read-only
+	enabled, err = resolver.SetReadOnlyMode(admin, false)
+	assert.NoError(t, err)
+	assert.False(t, enabled)
+	assert.False(t, graph.ReadOnly())
+}
+
+// TestAdminCallerNormalized tests that the admin check compares normalized addresses, and refuses everyone without an admin
+func TestAdminCallerNormalized(t *testing.T) {
+	saved := db.Settings
+	defer func() { db.Settings = saved }()
+	defer graph.SetReadOnly(false)
+
+	resolver := &graph.Resolver{AdminAddress: "0x58000000000000000000000000000000000000Ab"}
+	caller := graph.WithCaller(context.Background(), "0x58000000000000000000000000000000000000aB")
+
+	_, err := resolver.SetReadOnlyMode(caller, false)
+	assert.ErrorIs(t, err, db.ErrUnauthorized)
+
+	db.Settings.CaseInsensitiveAddresses = true
+	_, err = resolver.SetReadOnlyMode(caller, false)
+	assert.NoError(t, err)
+
+	_, err = (&graph.Resolver{}).SetReadOnlyMode(graph.WithCaller(context.Background(), ""), false)
+	assert.ErrorIs(t, err, db.ErrUnauthorized)
+}
+
+// TestReadOnlyConfig tests that a malformed READ_ONLY is rejected
+func TestReadOnlyConfig(t *testing.T) {
+	t.Setenv("READ_ONLY", "sometimes")
+	_, err := graph.NewResolver()
+	assert.Error(t, err)
+}
@@ -0,0 +1,52 @@
+package unit
+
+import (
+	"testing"
+	"token-transfer-api/internal/db"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// TestAutoCreateReceiverConfig tests that receivers are created unless AUTO_CREATE_RECEIVER is false
+func TestAutoCreateReceiverConfig(t *testing.T) {
+	for v, mustExist := range map[string]bool{"": false, "true": false, "1": false, "false": true, "0": true} {
+		t.Setenv("AUTO_CREATE_RECEIVER", v)
+		cfg, err := db.LoadConfig()
+		assert.NoError(t, err, v)
+		assert.Equal(t, mustExist, cfg.ReceiverMustExist, v)
+	}
+
+	t.Setenv("AUTO_CREATE_RECEIVER", "never")
+	_, err := db.LoadConfig()
+	assert.Error(t, err)
+}
+
+// TestNewWalletConfig tests that NEW_WALLET_GRANT and NEW_WALLET_DEFAULT_STATUS are parsed, with nothing set by default
+func TestNewWalletConfig(t *testing.T) {
+	cfg, err := db.LoadConfig()
+	assert.NoError(t, err)
+	assert.Nil(t, cfg.NewWalletGrant)
+	assert.Empty(t, cfg.NewWalletStatus)
+
+	t.Setenv("NEW_WALLET_GRANT", "25")
+	t.Setenv("NEW_WALLET_DEFAULT_STATUS", "frozen")
+	cfg, err = db.LoadConfig()
+	assert.NoError(t, err)
+	assert.Equal(t, "25", cfg.NewWalletGrant.String())
+	assert.Equal(t, db.WalletFrozen, cfg.NewWalletStatus)
+
+	t.Setenv("NEW_WALLET_GRANT", "0")
+	t.Setenv("NEW_WALLET_DEFAULT_STATUS", "active")
+	cfg, err = db.LoadConfig()
+	assert.NoError(t, err)
+	assert.Nil(t, cfg.NewWalletGrant)
+	assert.Empty(t, cfg.NewWalletStatus)
+
+	for name, v := range map[string]string{"NEW_WALLET_GRANT": "-1", "NEW_WALLET_DEFAULT_STATUS": "pending"} {
+		t.Run(name, func(t *testing.T) {
+			t.Setenv(name, v)
+			_, err := db.LoadConfig()
+			assert.Error(t, err)
+		})
+	}
+}
@@ -0,0 +1,39 @@
+package unit
+
+import (
+	"testing"
+	"time"
+	"token-transfer-api/pkg/graphql"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// TestRequestTimeoutFromEnv tests the HTTP_REQUEST_TIMEOUT setting
+func TestRequestTimeoutFromEnv(t *testing.T) {
+	t.Setenv("HTTP_REQUEST_TIMEOUT", "")
+	timeout, err := graphql.RequestTimeoutFromEnv()
+	assert.NoError(t, err)
+	assert.Equal(t, graphql.DefaultRequestTimeout, timeout)
+
+	t.Setenv("HTTP_REQUEST_TIMEOUT", "250ms")
+	timeout, err = graphql.RequestTimeoutFromEnv()
+	assert.NoError(t, err)
+	assert.Equal(t, 250*time.Millisecond, timeout)
+
+	t.Setenv("HTTP_REQUEST_TIMEOUT", "0")
+	timeout, err = graphql.RequestTimeoutFromEnv()
+	assert.NoError(t, err)
+	assert.Zero(t, timeout)
+
+	for _, v := range []string{"10", "-1s", "soon"} {
+		t.Setenv("HTTP_REQUEST_TIMEOUT", v)
+		_, err = graphql.RequestTimeoutFromEnv()
+		assert.Error(t, err, v)
+	}
+}
+
+// TestRequestTimeoutLeavesFastRequests tests that requests well within the deadline are answered as usual
+func TestRequestTimeoutLeavesFastRequests(t *testing.T) {
+	t.Setenv("HTTP_REQUEST_TIMEOUT", "5s")
+	assertStatus(t, `{"query": "{ __typename }"}`, 200, nil)
+}
@@ -0,0 +1,141 @@
+package unit
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"token-transfer-api/internal/graph"
+	"token-transfer-api/pkg/graphql"
+	"token-transfer-api/pkg/rest"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// TestRESTStatusFor tests the HTTP status chosen for each kind of app error
+func TestRESTStatusFor(t *testing.T) {
+	assert.Equal(t, http.StatusBadRequest, rest.StatusFor("INVALID_AMOUNT"))
+	assert.Equal(t, http.StatusBadRequest, rest.StatusFor("INVALID_ADDRESS"))
+	assert.Equal(t, http.StatusConflict, rest.StatusFor("INSUFFICIENT_BALANCE"))
+	assert.Equal(t, http.StatusNotFound, rest.StatusFor("SENDER_NOT_FOUND"))
+	assert.Equal(t, http.StatusTooManyRequests, rest.StatusFor("RATE_LIMITED"))
+	assert.Equal(t, http.StatusUnprocessableEntity, rest.StatusFor("IMPORT_REJECTED"))
+	assert.Equal(t, http.StatusInternalServerError, rest.StatusFor("INTERNAL"))
+	assert.Equal(t, http.StatusInternalServerError, rest.StatusFor("CORRUPT_BALANCE"))
+	assert.Equal(t, http.StatusConflict, rest.StatusFor("NOTHING_TO_SWEEP"))
+	assert.Equal(t, http.StatusConflict, rest.StatusFor("CONDITION_NOT_MET"))
+	assert.Equal(t, http.StatusServiceUnavailable, rest.StatusFor("READ_ONLY"))
+}
+
+// TestRESTRejectsBadInputBeforeDB tests the 400 responses that need no database
+func TestRESTRejectsBadInputBeforeDB(t *testing.T) {
+	server := httptest.NewServer(rest.NewHandler())
+	defer server.Close()
+
+	resp, err := http.Post(server.URL+"/api/transfer", "application/json", bytes.NewBufferString("{not json"))
+	assert.NoError(t, err)
+	resp.Body.Close()
+	assert.Equal(t, http.StatusBadRequest, resp.StatusCode)
+
+	reqBody, _ := json.Marshal(map[string]string{
+		"from_address": "0xabcdef0000000000000000000000000000000001",
+		"to_address":   "0xabcdef0000000000000000000000000000000002",
+		"amount":       "abc",
+	})
+	resp, err = http.Post(server.URL+"/api/transfer", "application/json", bytes.NewBuffer(reqBody))
+	assert.NoError(t, err)
+	var body map[string]interface{}
+	assert.NoError(t, json.NewDecoder(resp.Body).Decode(&body))
+	resp.Body.Close()
+	assert.Equal(t, http.StatusBadRequest, resp.StatusCode)
+	assert.Equal(t, "INVALID_AMOUNT", body["code"])
+
+	resp, err = http.Get(server.URL + "/api/wallet/not-an-address")
+	assert.NoError(t, err)
+	resp.Body.Close()
+	assert.Equal(t, http.StatusBadRequest, resp.StatusCode)
+}
+
+// TestRESTAuth tests that transfers need a key while public wallet lookups do not
+func TestRESTAuth(t *testing.T) {
+	handler := rest.WithAuth(rest.NewHandler(), graphql.AuthConfig{APIKeys: []string{"secret"}, PublicQueries: true})
+	server := httptest.NewServer(handler)
+	defer server.Close()
+
+	resp, err := http.Post(server.URL+"/api/transfer", "application/json", bytes.NewBufferString("{}"))
+	assert.NoError(t, err)
+	resp.Body.Close()
+	assert.Equal(t, http.StatusUnauthorized, resp.StatusCode)
+
+	resp, err = http.Get(server.URL + "/api/wallet/not-an-address")
+	assert.NoError(t, err)
+	resp.Body.Close()
+	assert.Equal(t, http.StatusBadRequest, resp.StatusCode)
+}
+
+// TestRESTImportRejectsBadRequests tests the import answers that need no database
+func TestRESTImportRejectsBadRequests(t *testing.T) {
+	admin := "0xabcdef0000000000000000000000000000000009"
+	t.Setenv("ADMIN_ADDRESS", admin)
+	auth := graphql.AuthConfig{APIKeys: []string{"admin-key", "other-key"}, Callers: map[string]string{"admin-key": admin}}
+	server := httptest.NewServer(rest.WithAuth(rest.NewHandler(), auth))
+	defer server.Close()
+
+	importRows := func(query, contentType, body, key string) (int, map[string]interface{}) {
+		req, _ := http.NewRequest(http.MethodPost, server.URL+"/api/wallets/import"+query, bytes.NewBufferString(body))
+		req.Header.Set("Content-Type", contentType)
+		req.Header.Set("Authorization", "Bearer "+key)
+		// Naming the admin in a header authorizes nothing
+		req.Header.Set("X-Caller-Address", admin)
+		resp, err := http.DefaultClient.Do(req)
+		if !assert.NoError(t, err) {
+			return 0, nil
+		}
+		defer resp.Body.Close()
+		var decoded map[string]interface{}
+		assert.NoError(t, json.NewDecoder(resp.Body).Decode(&decoded))
+		return resp.StatusCode, decoded
+	}
+	rows := `[{"address": "0xabcdef0000000000000000000000000000000001", "balance": "10"}]`
+
+	status, body := importRows("?on_error=ignore", "application/json", rows, "admin-key")
+	assert.Equal(t, http.StatusBadRequest, status)
+	assert.Equal(t, "INVALID_IMPORT_POLICY", body["code"])
+
+	status, body = importRows("", "text/csv", "address,balance\n0xabcdef0000000000000000000000000000000001,10,extra\n", "admin-key")
+	assert.Equal(t, http.StatusBadRequest, status)
+	assert.Equal(t, "BAD_REQUEST", body["code"])
+
+	status, body = importRows("", "application/json", `{"address": "0x1"}`, "admin-key")
+	assert.Equal(t, http.StatusBadRequest, status)
+	assert.Equal(t, "BAD_REQUEST", body["code"])
+
+	status, body = importRows("", "application/json", rows, "other-key")
+	assert.Equal(t, http.StatusForbidden, status)
+	assert.Equal(t, "UNAUTHORIZED", body["code"])
+}
+
+// TestRESTSharesRateLimitWithGraphQL tests that REST and GraphQL served by
+// one resolver count a sender's transfers against the same rate limit
+func TestRESTSharesRateLimitWithGraphQL(t *testing.T) {
+	t.Setenv("TRANSFER_RATE_LIMIT", "1")
+	resolver, err := graph.NewResolver()
+	if err != nil {
+		t.Fatalf("Failed to create resolver: %v", err)
+	}
+	sender := "0xabcdef0000000000000000000000000000000011"
+	receiver := "0xabcdef0000000000000000000000000000000012"
+
+	body, _ := json.Marshal(map[string]string{"from_address": sender, "to_address": receiver, "amount": "1"})
+	rec := httptest.NewRecorder()
+	rest.NewHandlerFor(resolver).ServeHTTP(rec, httptest.NewRequest(http.MethodPost, "/api/transfer", bytes.NewBuffer(body)))
+	assert.NotEqual(t, http.StatusTooManyRequests, rec.Code)
+
+	query, _ := json.Marshal(map[string]interface{}{
+		"query":     `mutation($from: Address!, $to: Address!) { transfer(from_address: $from, to_address: $to, amount: "1") { balance } }`,
+		"variables": map[string]string{"from": sender, "to": receiver},
+	})
+	rec = post(graphql.NewHandlerFor(resolver), "application/json", string(query))
+	assert.Contains(t, rec.Body.String(), "RATE_LIMITED")
+}
@@ -0,0 +1,53 @@
+package unit
+
+import (
+	"bytes"
+	"errors"
+	"log"
+	"os"
+	"testing"
+	"token-transfer-api/internal/db"
+	"token-transfer-api/pkg/graphql"
+
+	"github.com/graphql-go/graphql/gqlerrors"
+	"github.com/stretchr/testify/assert"
+)
+
+// resolverError formats err the way graphql-go reports a failing resolver
+func resolverError(err error) gqlerrors.FormattedError {
+	return gqlerrors.FormatError(gqlerrors.NewLocatedError(err, nil))
+}
+
+// TestSanitizeHidesDriverErrors tests that unclassified errors reach the log but not the client
+func TestSanitizeHidesDriverErrors(t *testing.T) {
+	var logs bytes.Buffer
+	log.SetOutput(&logs)
+	defer log.SetOutput(os.Stderr)
+
+	raw := errors.New(`pq: insert or update on table "transfers" violates foreign key constraint "transfers_to_address_fkey"`)
+	errs := graphql.SanitizeErrors([]gqlerrors.FormattedError{resolverError(raw)})
+
+	assert.Equal(t, "internal server error", errs[0].Message)
+	assert.Equal(t, "INTERNAL", errs[0].Extensions["code"])
+	assert.Contains(t, logs.String(), "transfers_to_address_fkey")
+}
+
+// TestSanitizeKeepsAppErrors tests that coded errors are passed through
+func TestSanitizeKeepsAppErrors(t *testing.T) {
+	errs := graphql.SanitizeErrors([]gqlerrors.FormattedError{
+		resolverError(db.ErrInsufficientBalance),
+		resolverError(db.ClassifyError(db.ErrSerializationFailure)),
+	})
+
+	assert.Equal(t, "insufficient balance", errs[0].Message)
+	assert.Equal(t, "INSUFFICIENT_BALANCE", errs[0].Extensions["code"])
+	assert.Equal(t, "SERIALIZATION_FAILURE", errs[1].Extensions["code"])
+}
+
+// TestSanitizeKeepsValidationErrors tests that query syntax errors are not hidden
+func TestSanitizeKeepsValidationErrors(t *testing.T) {
+	validation := gqlerrors.FormatError(gqlerrors.NewError(`Cannot query field "nope" on type "Query".`, nil, "", nil, nil, nil))
+
+	errs := graphql.SanitizeErrors([]gqlerrors.FormattedError{validation})
+	assert.Equal(t, `Cannot query field "nope" on type "Query".`, errs[0].Message)
+}
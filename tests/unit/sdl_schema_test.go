@@ -0,0 +1,121 @@
+package unit
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"testing"
+	"token-transfer-api/pkg/graphql"
+
+	gql "github.com/graphql-go/graphql"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// typeQuery introspects the type called name down to three levels of wrapping
+func typeQuery(name string) string {
+	return fmt.Sprintf(`{ __type(name: %q) { kind name description
+		fields { name description args { name defaultValue type { ...Ref } } type { ...Ref } }
+		enumValues { name }
+	} }
+	fragment Ref on __Type { kind name ofType { kind name ofType { kind name ofType { kind name } } } }`, name)
+}
+
+// sdlType introspects the type called name in the SDL schema
+func sdlType(t *testing.T, schema gql.Schema, name string) map[string]interface{} {
+	result := gql.Do(gql.Params{Schema: schema, RequestString: typeQuery(name), Context: context.Background()})
+	require.Empty(t, result.Errors, name)
+
+	// Round-trip through JSON so the result compares with the handler's
+	body, err := json.Marshal(result.Data)
+	require.NoError(t, err)
+	var data map[string]map[string]interface{}
+	require.NoError(t, json.Unmarshal(body, &data))
+	return sorted(data["__type"]).(map[string]interface{})
+}
+
+// sorted orders the fields, arguments and enum values of an introspected
+// type by name, since graphql-go keeps them in maps
+func sorted(v interface{}) interface{} {
+	switch v := v.(type) {
+	case map[string]interface{}:
+		for k, item := range v {
+			v[k] = sorted(item)
+		}
+	case []interface{}:
+		for i, item := range v {
+			v[i] = sorted(item)
+		}
+		sort.Slice(v, func(i, j int) bool {
+			a, _ := v[i].(map[string]interface{})
+			b, _ := v[j].(map[string]interface{})
+			return fmt.Sprint(a["name"]) < fmt.Sprint(b["name"])
+		})
+	}
+	return v
+}
+
+// TestSchemaSDLUpToDate tests that schema.graphql is the schema the handler serves
+func TestSchemaSDLUpToDate(t *testing.T) {
+	sdl, err := graphql.SchemaSDL()
+	require.NoError(t, err)
+	assert.Equal(t, sdl, graphql.SDL, "pkg/graphql/schema.graphql is out of date, run make schema")
+}
+
+// TestSDLSchemaMatchesHandlerSchema tests that the SDL schema introspects like the schema the handler serves
+func TestSDLSchemaMatchesHandlerSchema(t *testing.T) {
+	t.Setenv("GRAPHQL_INTROSPECTION", "true")
+	for _, decimals := range []string{"", "6"} {
+		t.Setenv("TOKEN_DECIMALS", decimals)
+		schema, err := graphql.NewSDLSchema()
+		require.NoError(t, err)
+
+		served := introspect(t, `{ __schema { types { name } } }`)
+		require.Empty(t, served.Errors)
+		types := served.Data["__schema"].(map[string]interface{})["types"].([]interface{})
+		assert.Len(t, schema.TypeMap(), len(types))
+
+		for _, typ := range types {
+			name := typ.(map[string]interface{})["name"].(string)
+			served := introspect(t, typeQuery(name))
+			require.Empty(t, served.Errors, name)
+			assert.Equal(t, sorted(served.Data["__type"]), sdlType(t, schema, name), "%s with TOKEN_DECIMALS=%q", name, decimals)
+		}
+	}
+}
+
+// TestSDLSchemaResolvesTransfer tests that the SDL transfer field runs the resolver
+func TestSDLSchemaResolvesTransfer(t *testing.T) {
+	schema, err := graphql.NewSDLSchema()
+	require.NoError(t, err)
+
+	result := gql.Do(gql.Params{
+		Schema:        schema,
+		RequestString: `mutation { transfer(from_address: "0x0000000000000000000000000000000000000001", to_address: "0x0000000000000000000000000000000000000001", amount: "1") { balance } }`,
+		Context:       context.Background(),
+	})
+	require.Len(t, result.Errors, 1)
+	assert.Equal(t, "SELF_TRANSFER", result.Errors[0].Extensions["code"])
+}
+
+// TestBuildSchemaErrors tests that mistakes in the SDL or its wiring are reported
+func TestBuildSchemaErrors(t *testing.T) {
+	for sdl, want := range map[string]string{
+		`type Query { a: Missing }`:                      "unknown type Missing",
+		`scalar Money type Query { a: Money }`:           "scalar Money has no Go implementation",
+		`type Query { a: String } type Query { b: Int }`: "type Query is defined twice",
+		`type Query { a(x: Int = "one"): String }`:       "invalid default value for type Int",
+		`type Query { a: String`:                         "Syntax Error",
+	} {
+		_, err := graphql.BuildSchema(sdl, nil, nil)
+		if assert.Error(t, err, sdl) {
+			assert.Contains(t, err.Error(), want, sdl)
+		}
+	}
+
+	_, err := graphql.BuildSchema(`type Query { a: String }`, nil, map[string]gql.FieldResolveFn{
+		"Query.b": func(gql.ResolveParams) (interface{}, error) { return nil, nil },
+	})
+	assert.EqualError(t, err, "resolver Query.b has no field in the SDL")
+}
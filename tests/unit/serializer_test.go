@@ -0,0 +1,76 @@
+package unit
+
+import (
+	"context"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+	"token-transfer-api/pkg/serializer"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// TestSerializerOneHolderPerKey tests that callers with the same key never overlap
+func TestSerializerOneHolderPerKey(t *testing.T) {
+	s := serializer.New()
+	var inside, maxInside atomic.Int32
+	var wg sync.WaitGroup
+
+	for i := 0; i < 20; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			err := s.Do(context.Background(), "hot", func() error {
+				n := inside.Add(1)
+				for {
+					m := maxInside.Load()
+					if n <= m || maxInside.CompareAndSwap(m, n) {
+						break
+					}
+				}
+				time.Sleep(time.Millisecond)
+				inside.Add(-1)
+				return nil
+			})
+			assert.NoError(t, err)
+		}()
+	}
+	wg.Wait()
+
+	assert.Equal(t, int32(1), maxInside.Load())
+	assert.Equal(t, 0, s.Len())
+}
+
+// TestSerializerKeysIndependent tests that holding one key does not block another
+func TestSerializerKeysIndependent(t *testing.T) {
+	s := serializer.New()
+	release, err := s.Acquire(context.Background(), "a")
+	assert.NoError(t, err)
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+	other, err := s.Acquire(ctx, "b")
+	assert.NoError(t, err)
+	assert.Equal(t, 2, s.Len())
+
+	other()
+	release()
+	assert.Equal(t, 0, s.Len())
+}
+
+// TestSerializerWaitCanceled tests that a waiter gives up with its context and frees its reference
+func TestSerializerWaitCanceled(t *testing.T) {
+	s := serializer.New()
+	release, err := s.Acquire(context.Background(), "a")
+	assert.NoError(t, err)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+	_, err = s.Acquire(ctx, "a")
+	assert.ErrorIs(t, err, context.DeadlineExceeded)
+	assert.Equal(t, 1, s.Len())
+
+	release()
+	assert.Equal(t, 0, s.Len())
+}
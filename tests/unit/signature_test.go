@@ -0,0 +1,247 @@
+package unit
+
+import (
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"token-transfer-api/internal/auth"
+	"token-transfer-api/internal/graph"
+	"token-transfer-api/pkg/graphql"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// signerKey is the sample private key of the web3.js documentation
+var signerKey, _ = new(big.Int).SetString("4c0883a69102937d6231471b5dbb6204fe5129617082792ae468d01a3f362318", 16)
+
+const signerAddress = "0x2c7536e3605d9c16a7a3d7b1898e529396a65c23"
+
+// TestKeccak256 tests the hash against known Keccak-256 digests
+func TestKeccak256(t *testing.T) {
+	for input, digest := range map[string]string{
+		"":                       "c5d2460186f7233c927e7db2dcc703c0e500b653ca82273b7bfad8045d85a470",
+		"abc":                    "4e03657aea45a94fc7d47ba826c8d667c0d1e6e33a64a036ec44f58fa12d6c45",
+		strings.Repeat("a", 135): "34367dc248bbd832f4e3e69dfaac2f92638bd0bbd18f2912ba4ef454919cf446",
+	} {
+		assert.Equal(t, digest, hex.EncodeToString(auth.Keccak256([]byte(input))), "%d bytes", len(input))
+	}
+
+	// Hashing in pieces is hashing the concatenation
+	assert.Equal(t, auth.Keccak256([]byte("abc")), auth.Keccak256([]byte("a"), []byte("bc")))
+}
+
+// TestAddressOfKey tests that addresses derived from private keys match Ethereum's
+func TestAddressOfKey(t *testing.T) {
+	assert.Equal(t, "0x7e5f4552091a69125d5dfcb7b8c2659029395bdf", auth.Address(big.NewInt(1)))
+	assert.Equal(t, "0x2b5ad5c4795c026514f8317c7a215e218dccd6cf", auth.Address(big.NewInt(2)))
+	assert.Equal(t, signerAddress, auth.Address(signerKey))
+}
+
+// TestSignAndVerifySweep tests that a sweep signature only verifies as that sweep and never as a transfer
+func TestSignAndVerifySweep(t *testing.T) {
+	const receiver = "0x4b00000000000000000000000000000000000002"
+
+	signature, err := auth.Sign(auth.SweepMessage(signerAddress, receiver, 3), signerKey)
+	assert.NoError(t, err)
+	assert.NoError(t, auth.VerifySweep(signature, signerAddress, receiver, 3))
+	assert.ErrorIs(t, auth.VerifySweep(signature, signerAddress, "0x4b00000000000000000000000000000000000003", 3), auth.ErrWrongSigner)
+	assert.ErrorIs(t, auth.VerifySweep(signature, signerAddress, receiver, 4), auth.ErrWrongSigner)
+
+	transfer, err := auth.Sign(auth.TransferMessage(signerAddress, receiver, "100", 3), signerKey)
+	assert.NoError(t, err)
+	assert.ErrorIs(t, auth.VerifySweep(transfer, signerAddress, receiver, 3), auth.ErrWrongSigner)
+}
+
+// TestRecoverWalletSignature tests recovery of a personal_sign signature made by an Ethereum wallet
+func TestRecoverWalletSignature(t *testing.T) {
+	assert.Equal(t, "a1de988600a42c4b4ab089b619297c17d53cffae5d5120d82d8a92d0bb3b78f2",
+		hex.EncodeToString(auth.HashMessage([]byte("Hello World"))))
+
+	// web3.eth.accounts.sign("Some data", signerKey)
+	signature := "0xb91467e570a6466aa9e9876cbcd013baba02900b8979d43fe208a4a4f339f5fd6007e74cd82e037b800186422fc2da167c747ef045e5d18a5f5d4300f8e1a0291c"
+	signer, err := auth.RecoverAddress([]byte("Some data"), signature)
+	assert.NoError(t, err)
+	assert.Equal(t, signerAddress, signer)
+
+	// Another message recovers some other address
+	signer, err = auth.RecoverAddress([]byte("Other data"), signature)
+	if err == nil {
+		assert.NotEqual(t, signerAddress, signer)
+	}
+}
+
+// TestSignAndVerifyTransfer tests that only the sender's signature of the exact transfer verifies
+func TestSignAndVerifyTransfer(t *testing.T) {
+	const receiver = "0x4b00000000000000000000000000000000000002"
+
+	signature, err := auth.Sign(auth.TransferMessage(signerAddress, receiver, "100", 3), signerKey)
+	assert.NoError(t, err)
+	assert.NoError(t, auth.VerifyTransfer(signature, signerAddress, receiver, "100", 3))
+	assert.NoError(t, auth.VerifyTransfer(signature, strings.ToUpper(signerAddress[:2])+strings.ToUpper(signerAddress[2:]), receiver, "100", 3),
+		"address case does not matter")
+
+	for _, tc := range []struct {
+		to, amount string
+		nonce      int64
+	}{
+		{"0x4b00000000000000000000000000000000000003", "100", 3},
+		{receiver, "101", 3},
+		{receiver, "100", 4},
+	} {
+		assert.ErrorIs(t, auth.VerifyTransfer(signature, signerAddress, tc.to, tc.amount, tc.nonce), auth.ErrWrongSigner, tc)
+	}
+
+	// Signed by another key
+	other, err := auth.Sign(auth.TransferMessage(signerAddress, receiver, "100", 3), big.NewInt(2))
+	assert.NoError(t, err)
+	assert.ErrorIs(t, auth.VerifyTransfer(other, signerAddress, receiver, "100", 3), auth.ErrWrongSigner)
+
+	for _, malformed := range []string{"", "0x", "0x1234", signature[2:], signature + "00", "0x" + strings.Repeat("zz", 65)} {
+		assert.ErrorIs(t, auth.VerifyTransfer(malformed, signerAddress, receiver, "100", 3), auth.ErrMalformedSignature, malformed)
+	}
+}
+
+// TestHighSRejected tests that the malleable twin of a signature is refused
+func TestHighSRejected(t *testing.T) {
+	signature, err := auth.Sign([]byte("message"), signerKey)
+	assert.NoError(t, err)
+	raw, _ := hex.DecodeString(signature[2:])
+
+	n, _ := new(big.Int).SetString("fffffffffffffffffffffffffffffffebaaedce6af48a03bbfd25e8cd0364141", 16)
+	s := new(big.Int).SetBytes(raw[32:64])
+	new(big.Int).Sub(n, s).FillBytes(raw[32:64])
+	raw[64] ^= 1 // 27 <-> 28
+
+	_, err = auth.RecoverAddress([]byte("message"), "0x"+hex.EncodeToString(raw))
+	assert.ErrorIs(t, err, auth.ErrMalformedSignature)
+}
+
+// TestSignedTransfersFromEnv tests the SIGNED_TRANSFERS setting
+func TestSignedTransfersFromEnv(t *testing.T) {
+	for v, required := range map[string]bool{"": false, "optional": false, "required": true} {
+		t.Setenv("SIGNED_TRANSFERS", v)
+		r, err := graph.NewResolver()
+		assert.NoError(t, err, v)
+		assert.Equal(t, required, r.RequireSignatures, v)
+	}
+
+	t.Setenv("SIGNED_TRANSFERS", "always")
+	_, err := graph.NewResolver()
+	assert.Error(t, err)
+}
+
+// TestTransferSignatureChecked tests that bad signatures are rejected before the database is reached
+func TestTransferSignatureChecked(t *testing.T) {
+	const receiver = "0x4b00000000000000000000000000000000000002"
+	good, err := auth.Sign(auth.TransferMessage(signerAddress, receiver, "100", 0), signerKey)
+	assert.NoError(t, err)
+	wrong, err := auth.Sign(auth.TransferMessage(signerAddress, receiver, "100", 0), big.NewInt(2))
+	assert.NoError(t, err)
+
+	transfer := func(arguments string) persistedResponse {
+		query := fmt.Sprintf(`mutation { transfer(from_address: "%s", to_address: "%s", amount: "100"%s) { balance failure_code } }`,
+			signerAddress, receiver, arguments)
+		body, _ := json.Marshal(graphql.GraphQLRequest{Query: query})
+		rec := post(graphql.NewHandler(), "application/json", string(body))
+		var resp persistedResponse
+		assert.NoError(t, json.NewDecoder(rec.Body).Decode(&resp))
+		return resp
+	}
+
+	t.Setenv("SIGNED_TRANSFERS", "required")
+	assert.Equal(t, "BAD_SIGNATURE", errorCode(transfer("")))
+	assert.Equal(t, "BAD_SIGNATURE", errorCode(transfer(`, signature: "`+good+`"`)), "expected_nonce is required")
+	assert.Equal(t, "BAD_SIGNATURE", errorCode(transfer(`, expected_nonce: 0, signature: "`+wrong+`"`)))
+	assert.Equal(t, "BAD_SIGNATURE", errorCode(transfer(`, expected_nonce: 1, signature: "`+good+`"`)))
+
+	// A valid signature gets as far as the database, which is not open here
+	assert.Equal(t, "NOT_INITIALIZED", errorCode(transfer(`, expected_nonce: 0, signature: "`+good+`"`)))
+
+	// Without the requirement only signatures that are sent are checked
+	t.Setenv("SIGNED_TRANSFERS", "")
+	assert.Equal(t, "NOT_INITIALIZED", errorCode(transfer("")))
+	assert.Equal(t, "BAD_SIGNATURE", errorCode(transfer(`, expected_nonce: 0, signature: "`+wrong+`"`)))
+
+	resp := transfer(`, expected_nonce: 0, signature: "` + wrong + `", dry_run: true`)
+	assert.Empty(t, resp.Errors)
+	assert.Equal(t, "BAD_SIGNATURE", resp.Data["transfer"].(map[string]interface{})["failure_code"])
+}
+
+// TestRequiredSignaturesRejectUnsignedDebits tests that with SIGNED_TRANSFERS=required
+// no mutation that takes tokens from a wallet without a signature gets to the database
+func TestRequiredSignaturesRejectUnsignedDebits(t *testing.T) {
+	const admin = "0x4b00000000000000000000000000000000000009"
+	const from = "0x4b00000000000000000000000000000000000001"
+	const to = "0x4b00000000000000000000000000000000000002"
+	t.Setenv("ADMIN_ADDRESS", admin)
+	t.Setenv("MINTER_ADDRESS", admin)
+	auth := graphql.AuthConfig{APIKeys: []string{"admin-key"}, Callers: map[string]string{"admin-key": admin}}
+
+	execute := func(mutation string) persistedResponse {
+		body, _ := json.Marshal(graphql.GraphQLRequest{Query: "mutation { " + mutation + " }"})
+		req := httptest.NewRequest(http.MethodPost, "/", strings.NewReader(string(body)))
+		req.Header.Set("Content-Type", "application/json")
+		req.Header.Set("Authorization", "Bearer admin-key")
+		rec := httptest.NewRecorder()
+		graphql.WithAuth(graphql.NewHandler(), auth).ServeHTTP(rec, req)
+		var resp persistedResponse
+		assert.NoError(t, json.NewDecoder(rec.Body).Decode(&resp), mutation)
+		return resp
+	}
+
+	for name, mutation := range map[string]string{
+		"scheduleTransfer": `scheduleTransfer(from_address: "` + from + `", to_address: "` + to + `", amount: "1", execute_at: "2030-01-01T00:00:00Z") { id }`,
+		"swap":             `swap(wallet_a: "` + from + `", wallet_b: "` + to + `", amount_a: "1", amount_b: "1") { balance_a }`,
+		"createHold":       `createHold(from: "` + from + `", amount: "1") { id }`,
+		"releaseHold":      `releaseHold(hold_id: 1, to: "` + to + `") { id }`,
+		"refundTransfer":   `refundTransfer(transfer_id: 1) { transfer { id } }`,
+		"burn":             `burn(from_address: "` + from + `", amount: "1")`,
+	} {
+		t.Run(name, func(t *testing.T) {
+			t.Setenv("SIGNED_TRANSFERS", "required")
+			assert.Equal(t, "BAD_SIGNATURE", errorCode(execute(mutation)))
+
+			// Without the requirement the mutation gets as far as the database, which is not open here
+			t.Setenv("SIGNED_TRANSFERS", "optional")
+			assert.Equal(t, "NOT_INITIALIZED", errorCode(execute(mutation)))
+		})
+	}
+}
+
+// TestSweepSignatureChecked tests that a sweep needs the sender's signature whatever SIGNED_TRANSFERS is set to
+func TestSweepSignatureChecked(t *testing.T) {
+	const receiver = "0x4b00000000000000000000000000000000000002"
+	good, err := auth.Sign(auth.SweepMessage(signerAddress, receiver, 0), signerKey)
+	assert.NoError(t, err)
+	wrong, err := auth.Sign(auth.SweepMessage(signerAddress, receiver, 0), big.NewInt(2))
+	assert.NoError(t, err)
+	transfer, err := auth.Sign(auth.TransferMessage(signerAddress, receiver, "100", 0), signerKey)
+	assert.NoError(t, err)
+
+	sweep := func(arguments string) persistedResponse {
+		query := fmt.Sprintf(`mutation { sweep(from_address: "%s", to_address: "%s"%s) { from_balance } }`,
+			signerAddress, receiver, arguments)
+		body, _ := json.Marshal(graphql.GraphQLRequest{Query: query})
+		rec := post(graphql.NewHandler(), "application/json", string(body))
+		var resp persistedResponse
+		assert.NoError(t, json.NewDecoder(rec.Body).Decode(&resp))
+		return resp
+	}
+
+	for _, v := range []string{"optional", "required"} {
+		t.Setenv("SIGNED_TRANSFERS", v)
+		assert.Equal(t, "BAD_SIGNATURE", errorCode(sweep("")), v)
+		assert.Equal(t, "BAD_SIGNATURE", errorCode(sweep(`, signature: "`+good+`"`)), "%s: expected_nonce is required", v)
+		assert.Equal(t, "BAD_SIGNATURE", errorCode(sweep(`, expected_nonce: 0, signature: "`+wrong+`"`)), v)
+		assert.Equal(t, "BAD_SIGNATURE", errorCode(sweep(`, expected_nonce: 1, signature: "`+good+`"`)), v)
+		assert.Equal(t, "BAD_SIGNATURE", errorCode(sweep(`, expected_nonce: 0, signature: "`+transfer+`"`)), "%s: a transfer signature is no sweep signature", v)
+
+		// A valid signature gets as far as the database, which is not open here
+		assert.Equal(t, "NOT_INITIALIZED", errorCode(sweep(`, expected_nonce: 0, signature: "`+good+`"`)), v)
+	}
+}
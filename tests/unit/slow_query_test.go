@@ -0,0 +1,42 @@
+package unit
+
+import (
+	"testing"
+	"time"
+	"token-transfer-api/internal/db"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// TestSlowQueryConfig tests the DB_SLOW_QUERY_MS setting
+func TestSlowQueryConfig(t *testing.T) {
+	for v, want := range map[string]time.Duration{"": 0, "0": 0, "250": 250 * time.Millisecond} {
+		t.Setenv("DB_SLOW_QUERY_MS", v)
+		cfg, err := db.LoadConfig()
+		assert.NoError(t, err, v)
+		assert.Equal(t, want, cfg.SlowQuery, v)
+	}
+
+	for _, v := range []string{"-1", "1.5", "1s", "slow"} {
+		t.Setenv("DB_SLOW_QUERY_MS", v)
+		_, err := db.LoadConfig()
+		assert.Error(t, err, v)
+	}
+}
+
+// TestSlowQueryLogConnects tests that the timed pool reaches Postgres like the plain one, errors included
+func TestSlowQueryLogConnects(t *testing.T) {
+	port, connections := startingPostgres(t)
+	pointAt(t, port)
+	t.Setenv("DB_SLOW_QUERY_MS", "100")
+	t.Setenv("DB_CONNECT_RETRIES", "1")
+	t.Setenv("DB_CONNECT_BACKOFF", "1ms")
+
+	err := db.InitDB()
+	if assert.Error(t, err) {
+		assert.Contains(t, err.Error(), "failed to ping database after 2 attempts")
+		assert.Contains(t, err.Error(), "starting up")
+	}
+	assert.Equal(t, int64(2), connections.Load())
+	assert.Nil(t, db.DB)
+}
@@ -0,0 +1,32 @@
+package unit
+
+import (
+	"testing"
+	"token-transfer-api/internal/db"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// TestSwapValidatesBothLegs tests that either side of a swap is checked before the database is reached
+func TestSwapValidatesBothLegs(t *testing.T) {
+	saved := db.Settings
+	defer func() { db.Settings = saved }()
+	db.Settings = db.Config{}
+	const walletA = "0x4800000000000000000000000000000000000001"
+	const walletB = "0x4800000000000000000000000000000000000002"
+
+	_, err := db.Swap(walletA, walletB, "0", "1")
+	assert.ErrorIs(t, err, db.ErrInvalidAmount)
+	_, err = db.Swap(walletA, walletB, "1", "-1")
+	assert.ErrorIs(t, err, db.ErrInvalidAmount)
+	_, err = db.Swap(walletA, walletA, "1", "1")
+	assert.ErrorIs(t, err, db.ErrSelfTransfer)
+
+	db.Settings = db.Config{ZeroAddressReserved: true}
+	_, err = db.Swap(walletA, db.ZeroAddress, "1", "1")
+	assert.ErrorIs(t, err, db.ErrReservedAddress)
+
+	// Valid swaps get as far as the database, which is not open here
+	_, err = db.Swap(walletA, walletB, "1", "1")
+	assert.ErrorIs(t, err, db.ErrNotInitialized)
+}
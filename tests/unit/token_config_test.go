@@ -0,0 +1,56 @@
+package unit
+
+import (
+	"context"
+	"testing"
+	"token-transfer-api/internal/db"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// TestTokenConfig tests that PRIMARY_TOKEN and TOKENS are read and malformed symbols rejected
+func TestTokenConfig(t *testing.T) {
+	t.Setenv("PRIMARY_TOKEN", "")
+	t.Setenv("TOKENS", "")
+	cfg, err := db.LoadConfig()
+	assert.NoError(t, err)
+	assert.Equal(t, db.DefaultPrimaryToken, cfg.PrimaryToken)
+	assert.Empty(t, cfg.Tokens)
+
+	t.Setenv("PRIMARY_TOKEN", "GOLD")
+	t.Setenv("TOKENS", "USDC, EUR2")
+	cfg, err = db.LoadConfig()
+	assert.NoError(t, err)
+	assert.Equal(t, "GOLD", cfg.PrimaryToken)
+	assert.Equal(t, map[string]bool{"USDC": true, "EUR2": true}, cfg.Tokens)
+	assert.True(t, cfg.IsPrimaryToken(""))
+	assert.True(t, cfg.IsPrimaryToken("GOLD"))
+	assert.False(t, cfg.IsPrimaryToken("USDC"))
+
+	for _, tokens := range []string{"usdc", "USDC,", "GOLD", "2X", "ABCDEFGHIJKLMNOPQ"} {
+		t.Setenv("TOKENS", tokens)
+		_, err := db.LoadConfig()
+		assert.Error(t, err, tokens)
+	}
+
+	t.Setenv("TOKENS", "")
+	t.Setenv("PRIMARY_TOKEN", "gold")
+	_, err = db.LoadConfig()
+	assert.Error(t, err)
+}
+
+// TestUnknownTokenRejectedBeforeDB tests that a token that is not configured is refused without touching the database
+func TestUnknownTokenRejectedBeforeDB(t *testing.T) {
+	saved := db.Settings
+	defer func() { db.Settings = saved }()
+	db.Settings.Tokens = map[string]bool{"USDC": true}
+
+	_, err := db.ExecuteTokenTransfer(context.Background(), "DOGE",
+		"0x5400000000000000000000000000000000000001",
+		"0x5400000000000000000000000000000000000002",
+		"1", "", nil, "")
+	assert.ErrorIs(t, err, db.ErrUnknownToken)
+
+	_, err = db.MintToken("DOGE", "0x5400000000000000000000000000000000000001", "1")
+	assert.ErrorIs(t, err, db.ErrUnknownToken)
+}
@@ -0,0 +1,118 @@
+package unit
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"token-transfer-api/internal/tracing"
+	"token-transfer-api/pkg/graphql"
+
+	"github.com/stretchr/testify/assert"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+	"go.opentelemetry.io/otel/trace/noop"
+)
+
+// TestTracingConfigFromEnv tests when spans are exported and amounts kept out of them
+func TestTracingConfigFromEnv(t *testing.T) {
+	for _, tc := range []struct {
+		exporter, endpoint, disabled string
+		export                       bool
+	}{
+		{"", "", "", false},
+		{"", "http://collector:4318", "", true},
+		{"otlp", "", "", true},
+		{"none", "http://collector:4318", "", false},
+		{"otlp", "http://collector:4318", "true", false},
+	} {
+		t.Setenv("OTEL_TRACES_EXPORTER", tc.exporter)
+		t.Setenv("OTEL_EXPORTER_OTLP_ENDPOINT", tc.endpoint)
+		t.Setenv("OTEL_SDK_DISABLED", tc.disabled)
+		cfg, err := tracing.ConfigFromEnv()
+		assert.NoError(t, err, tc)
+		assert.Equal(t, tc.export, cfg.Export, tc)
+	}
+
+	t.Setenv("OTEL_TRACES_EXPORTER", "zipkin")
+	_, err := tracing.ConfigFromEnv()
+	assert.Error(t, err)
+
+	t.Setenv("OTEL_TRACES_EXPORTER", "")
+	t.Setenv("TRACE_SENSITIVE_AMOUNTS", "true")
+	cfg, err := tracing.ConfigFromEnv()
+	assert.NoError(t, err)
+	assert.True(t, cfg.SensitiveAmounts)
+
+	t.Setenv("TRACE_SENSITIVE_AMOUNTS", "sometimes")
+	_, err = tracing.ConfigFromEnv()
+	assert.Error(t, err)
+}
+
+// TestTracingAddressHashed tests that addresses are recorded as the SHA-256 of their lowercase form
+func TestTracingAddressHashed(t *testing.T) {
+	const address = "0x5a000000000000000000000000000000000000Ab"
+	sum := sha256.Sum256([]byte(strings.ToLower(address)))
+	assert.Equal(t, attribute.String("transfer.from", hex.EncodeToString(sum[:])), tracing.Address("transfer.from", address))
+	assert.Equal(t, tracing.Address("transfer.from", address), tracing.Address("transfer.from", strings.ToLower(address)))
+}
+
+// TestOperationSpanJoinsCallerTrace tests that an operation's span continues
+// the trace of the request's traceparent and the transfer's span nests under
+// it, failed here since no database is open
+func TestOperationSpanJoinsCallerTrace(t *testing.T) {
+	exporter := tracetest.NewInMemoryExporter()
+	otel.SetTracerProvider(sdktrace.NewTracerProvider(sdktrace.WithSyncer(exporter)))
+	defer otel.SetTracerProvider(noop.NewTracerProvider())
+
+	body, _ := json.Marshal(graphql.GraphQLRequest{Query: `mutation Send {
+		transfer(from_address: "0x5a00000000000000000000000000000000000001", to_address: "0x5a00000000000000000000000000000000000002", amount: "5") { balance }
+	}`})
+	req := httptest.NewRequest(http.MethodPost, "/", strings.NewReader(string(body)))
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("traceparent", "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01")
+	graphql.NewHandler().ServeHTTP(httptest.NewRecorder(), req)
+
+	spans := map[string]tracetest.SpanStub{}
+	for _, span := range exporter.GetSpans() {
+		spans[span.Name] = span
+	}
+	operation, ok := spans["mutation Send"]
+	if !assert.True(t, ok, "operation span") {
+		return
+	}
+	assert.Equal(t, "4bf92f3577b34da6a3ce929d0e0e4736", operation.SpanContext.TraceID().String())
+	assert.Equal(t, "00f067aa0ba902b7", operation.Parent.SpanID().String())
+	assert.Equal(t, codes.Error, operation.Status.Code)
+
+	transfer, ok := spans["db.transfer"]
+	if !assert.True(t, ok, "transfer span") {
+		return
+	}
+	assert.Equal(t, operation.SpanContext.SpanID(), transfer.Parent.SpanID())
+	assert.Equal(t, codes.Error, transfer.Status.Code)
+	assert.Contains(t, transfer.Status.Description, "not initialized")
+}
+
+// TestOperationSpanWithoutTraceparent tests that a request without traceparent starts a trace of its own
+func TestOperationSpanWithoutTraceparent(t *testing.T) {
+	exporter := tracetest.NewInMemoryExporter()
+	otel.SetTracerProvider(sdktrace.NewTracerProvider(sdktrace.WithSyncer(exporter)))
+	defer otel.SetTracerProvider(noop.NewTracerProvider())
+
+	body, _ := json.Marshal(graphql.GraphQLRequest{Query: `{ totalSupply }`})
+	graphql.NewHandler().ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodPost, "/", strings.NewReader(string(body))))
+
+	spans := exporter.GetSpans()
+	if assert.Len(t, spans, 1) {
+		assert.Equal(t, "query", spans[0].Name)
+		assert.False(t, spans[0].Parent.IsValid())
+		assert.Contains(t, spans[0].Attributes, attribute.String("graphql.operation.type", "query"))
+	}
+}
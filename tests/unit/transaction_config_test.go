@@ -0,0 +1,160 @@
+package unit
+
+import (
+	"database/sql"
+	"testing"
+	"time"
+	"token-transfer-api/internal/db"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// TestMaxRetriesDefault tests that conflicting transfers are retried by default
+func TestMaxRetriesDefault(t *testing.T) {
+	t.Setenv("TRANSFER_MAX_RETRIES", "")
+	cfg, err := db.LoadConfig()
+	assert.NoError(t, err)
+	assert.Equal(t, db.DefaultMaxRetries, cfg.MaxRetries)
+}
+
+// TestMaxRetriesFromEnv tests that TRANSFER_MAX_RETRIES sets the retry count, including zero
+func TestMaxRetriesFromEnv(t *testing.T) {
+	t.Setenv("TRANSFER_MAX_RETRIES", "0")
+	cfg, err := db.LoadConfig()
+	assert.NoError(t, err)
+	assert.Equal(t, 0, cfg.MaxRetries)
+
+	t.Setenv("TRANSFER_MAX_RETRIES", "7")
+	cfg, err = db.LoadConfig()
+	assert.NoError(t, err)
+	assert.Equal(t, 7, cfg.MaxRetries)
+}
+
+// TestMaxRetriesInvalid tests that a negative or malformed retry count is rejected
+func TestMaxRetriesInvalid(t *testing.T) {
+	for _, v := range []string{"-1", "three"} {
+		t.Setenv("TRANSFER_MAX_RETRIES", v)
+		_, err := db.LoadConfig()
+		assert.Error(t, err, v)
+	}
+}
+
+// TestIsolationDefault tests that transactions default to REPEATABLE READ
+func TestIsolationDefault(t *testing.T) {
+	t.Setenv("DB_ISOLATION", "")
+	cfg, err := db.LoadConfig()
+	assert.NoError(t, err)
+	assert.Equal(t, sql.LevelRepeatableRead, cfg.Isolation)
+}
+
+// TestIsolationFromEnv tests that DB_ISOLATION accepts the Postgres level names in any case
+func TestIsolationFromEnv(t *testing.T) {
+	levels := map[string]sql.IsolationLevel{
+		"READ COMMITTED":  sql.LevelReadCommitted,
+		"repeatable read": sql.LevelRepeatableRead,
+		"Serializable":    sql.LevelSerializable,
+	}
+	for v, want := range levels {
+		t.Setenv("DB_ISOLATION", v)
+		cfg, err := db.LoadConfig()
+		assert.NoError(t, err, v)
+		assert.Equal(t, want, cfg.Isolation, v)
+	}
+
+	t.Setenv("DB_ISOLATION", "READ UNCOMMITTED")
+	_, err := db.LoadConfig()
+	assert.Error(t, err)
+}
+
+// TestPreparedStatementsFromEnv tests that statements are prepared unless DB_PREPARED_STATEMENTS turns it off
+func TestPreparedStatementsFromEnv(t *testing.T) {
+	t.Setenv("DB_PREPARED_STATEMENTS", "")
+	cfg, err := db.LoadConfig()
+	assert.NoError(t, err)
+	assert.True(t, cfg.PreparedStatements)
+
+	t.Setenv("DB_PREPARED_STATEMENTS", "false")
+	cfg, err = db.LoadConfig()
+	assert.NoError(t, err)
+	assert.False(t, cfg.PreparedStatements)
+
+	t.Setenv("DB_PREPARED_STATEMENTS", "sometimes")
+	_, err = db.LoadConfig()
+	assert.Error(t, err)
+}
+
+// TestAutoMigrateFromEnv tests that InitDB migrates unless DB_AUTO_MIGRATE turns it off
+func TestAutoMigrateFromEnv(t *testing.T) {
+	t.Setenv("DB_AUTO_MIGRATE", "")
+	cfg, err := db.LoadConfig()
+	assert.NoError(t, err)
+	assert.True(t, cfg.AutoMigrate)
+
+	t.Setenv("DB_AUTO_MIGRATE", "false")
+	cfg, err = db.LoadConfig()
+	assert.NoError(t, err)
+	assert.False(t, cfg.AutoMigrate)
+
+	t.Setenv("DB_AUTO_MIGRATE", "later")
+	_, err = db.LoadConfig()
+	assert.Error(t, err)
+}
+
+// TestGenesisFromEnv tests the treasury defaults and overrides
+func TestGenesisFromEnv(t *testing.T) {
+	t.Setenv("GENESIS_ADDRESS", "")
+	t.Setenv("GENESIS_SUPPLY", "")
+	cfg, err := db.LoadConfig()
+	assert.NoError(t, err)
+	assert.Equal(t, db.ZeroAddress, cfg.GenesisAddress)
+	assert.Equal(t, "1000000", cfg.GenesisSupply.String())
+
+	t.Setenv("GENESIS_ADDRESS", "0x3b00000000000000000000000000000000000001")
+	t.Setenv("GENESIS_SUPPLY", "21000000")
+	cfg, err = db.LoadConfig()
+	assert.NoError(t, err)
+	assert.Equal(t, "0x3b00000000000000000000000000000000000001", cfg.GenesisAddress)
+	assert.Equal(t, "21000000", cfg.GenesisSupply.String())
+
+	t.Setenv("GENESIS_SUPPLY", "0")
+	cfg, err = db.LoadConfig()
+	assert.NoError(t, err)
+	assert.Nil(t, cfg.GenesisSupply)
+
+	t.Setenv("GENESIS_SUPPLY", "-5")
+	_, err = db.LoadConfig()
+	assert.Error(t, err)
+
+	t.Setenv("GENESIS_SUPPLY", "")
+	t.Setenv("GENESIS_ADDRESS", "treasury")
+	_, err = db.LoadConfig()
+	assert.Error(t, err)
+}
+
+// TestBalanceHistoryFromEnv tests the BALANCE_HISTORY and BALANCE_HISTORY_INTERVAL settings
+func TestBalanceHistoryFromEnv(t *testing.T) {
+	t.Setenv("BALANCE_HISTORY", "")
+	t.Setenv("BALANCE_HISTORY_INTERVAL", "")
+	cfg, err := db.LoadConfig()
+	assert.NoError(t, err)
+	assert.False(t, cfg.BalanceHistory)
+	assert.Zero(t, cfg.BalanceHistoryInterval)
+
+	t.Setenv("BALANCE_HISTORY", "true")
+	t.Setenv("BALANCE_HISTORY_INTERVAL", "1h")
+	cfg, err = db.LoadConfig()
+	assert.NoError(t, err)
+	assert.True(t, cfg.BalanceHistory)
+	assert.Equal(t, time.Hour, cfg.BalanceHistoryInterval)
+
+	t.Setenv("BALANCE_HISTORY", "sometimes")
+	_, err = db.LoadConfig()
+	assert.Error(t, err)
+
+	t.Setenv("BALANCE_HISTORY", "true")
+	for _, v := range []string{"-1h", "1500ms", "hourly"} {
+		t.Setenv("BALANCE_HISTORY_INTERVAL", v)
+		_, err = db.LoadConfig()
+		assert.Error(t, err, v)
+	}
+}
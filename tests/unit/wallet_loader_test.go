@@ -0,0 +1,98 @@
+package unit
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"testing"
+	"token-transfer-api/internal/db"
+	"token-transfer-api/internal/model"
+	"token-transfer-api/pkg/graphql"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// countingFetch returns a fetch function knowing every address but the zero
+// one, and the lists of addresses it was called with
+func countingFetch() (graphql.WalletFetchFunc, *[][]string) {
+	var calls [][]string
+	fetch := func(ctx context.Context, addresses []string) ([]model.Wallet, error) {
+		calls = append(calls, append([]string(nil), addresses...))
+		wallets := []model.Wallet{}
+		for _, address := range addresses {
+			if address != db.ZeroAddress {
+				wallets = append(wallets, model.Wallet{Address: address, Balance: "1"})
+			}
+		}
+		return wallets, nil
+	}
+	return fetch, &calls
+}
+
+// TestWalletLoaderBatches tests that queued addresses are fetched together, once each
+func TestWalletLoaderBatches(t *testing.T) {
+	fetch, calls := countingFetch()
+	loader := graphql.NewWalletLoader(fetch)
+	ctx := context.Background()
+
+	a := "0x5600000000000000000000000000000000000001"
+	b := "0x5600000000000000000000000000000000000002"
+	thunks := []func() (interface{}, error){
+		loader.Load(ctx, a),
+		loader.Load(ctx, b),
+		loader.Load(ctx, a),
+		loader.Load(ctx, db.ZeroAddress),
+	}
+	assert.Empty(t, *calls, "nothing is fetched before a thunk runs")
+
+	for i, thunk := range thunks {
+		wallet, err := thunk()
+		assert.NoError(t, err)
+		if i == 3 {
+			assert.Nil(t, wallet, "unknown wallets resolve to a plain nil")
+			continue
+		}
+		assert.IsType(t, &model.Wallet{}, wallet)
+	}
+	assert.Equal(t, [][]string{{a, b, db.ZeroAddress}}, *calls)
+
+	// A fetched batch is not reused
+	wallet, err := loader.Load(ctx, a)()
+	assert.NoError(t, err)
+	assert.Equal(t, a, wallet.(*model.Wallet).Address)
+	assert.Len(t, *calls, 2)
+}
+
+// TestWalletLoaderChunks tests that batches over the bulk limit are fetched in chunks
+func TestWalletLoaderChunks(t *testing.T) {
+	fetch, calls := countingFetch()
+	loader := graphql.NewWalletLoader(fetch)
+
+	var thunks []func() (interface{}, error)
+	for i := 1; i <= db.MaxBulkAddresses+1; i++ {
+		thunks = append(thunks, loader.Load(context.Background(), fmt.Sprintf("0x56%038x", i)))
+	}
+	for _, thunk := range thunks {
+		_, err := thunk()
+		assert.NoError(t, err)
+	}
+	if assert.Len(t, *calls, 2) {
+		assert.Len(t, (*calls)[0], db.MaxBulkAddresses)
+		assert.Len(t, (*calls)[1], 1)
+	}
+}
+
+// TestWalletLoaderError tests that a failed fetch fails every thunk of the batch
+func TestWalletLoaderError(t *testing.T) {
+	failure := errors.New("boom")
+	loader := graphql.NewWalletLoader(func(context.Context, []string) ([]model.Wallet, error) {
+		return nil, failure
+	})
+
+	first := loader.Load(context.Background(), "0x5600000000000000000000000000000000000001")
+	second := loader.Load(context.Background(), "0x5600000000000000000000000000000000000002")
+	_, err := first()
+	assert.Equal(t, failure, err)
+	_, err = second()
+	assert.Equal(t, failure, err)
+}
@@ -0,0 +1,31 @@
+package unit
+
+import (
+	"net/http"
+	"testing"
+	"token-transfer-api/internal/db"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// TestSetWalletStatusRejectsUnknownStatus tests that only active, frozen and closed are accepted
+func TestSetWalletStatusRejectsUnknownStatus(t *testing.T) {
+	for _, status := range []string{"", "deleted", "FROZEN"} {
+		_, err := db.SetWalletStatus("0x4300000000000000000000000000000000000001", status)
+		assert.ErrorIs(t, err, db.ErrInvalidWalletStatus, status)
+	}
+}
+
+// TestSetWalletStatusMutationRequiresAdmin tests that the mutation is a 403 without the admin caller
+func TestSetWalletStatusMutationRequiresAdmin(t *testing.T) {
+	t.Setenv("ADMIN_ADDRESS", "0x4300000000000000000000000000000000000000")
+	assertStatus(t, `{"query": "mutation { setWalletStatus(address: \"0x4300000000000000000000000000000000000001\", status: FROZEN) { status } }"}`, http.StatusForbidden, "UNAUTHORIZED")
+}
+
+// TestFreezeMutationsRequireAdmin tests that freezing and unfreezing are a 403 without the admin caller
+func TestFreezeMutationsRequireAdmin(t *testing.T) {
+	t.Setenv("ADMIN_ADDRESS", "0x4400000000000000000000000000000000000000")
+	for _, mutation := range []string{"freezeWallet", "unfreezeWallet"} {
+		assertStatus(t, `{"query": "mutation { `+mutation+`(address: \"0x4400000000000000000000000000000000000001\") { frozen } }"}`, http.StatusForbidden, "UNAUTHORIZED")
+	}
+}
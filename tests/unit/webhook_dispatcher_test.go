@@ -0,0 +1,84 @@
+package unit
+
+import (
+	"sync/atomic"
+	"testing"
+	"time"
+	"token-transfer-api/pkg/webhook"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// waitFor polls cond until it holds or the timeout expires
+func waitFor(t *testing.T, cond func() bool) {
+	t.Helper()
+	deadline := time.Now().Add(2 * time.Second)
+	for !cond() {
+		if time.Now().After(deadline) {
+			t.Fatal("condition not met before timeout")
+		}
+		time.Sleep(time.Millisecond)
+	}
+}
+
+// TestDispatcherStaysBounded tests that a flood of deliveries never exceeds the pool and queue
+func TestDispatcherStaysBounded(t *testing.T) {
+	d := webhook.NewDispatcher(webhook.Config{Workers: 2, QueueSize: 3})
+
+	release := make(chan struct{})
+	var running, maxRunning, completed atomic.Int64
+	job := func() {
+		n := running.Add(1)
+		for {
+			m := maxRunning.Load()
+			if n <= m || maxRunning.CompareAndSwap(m, n) {
+				break
+			}
+		}
+		<-release
+		running.Add(-1)
+		completed.Add(1)
+	}
+
+	// Occupy both workers first so the queue state is deterministic
+	assert.True(t, d.Submit(job))
+	assert.True(t, d.Submit(job))
+	waitFor(t, func() bool { return d.Active() == 2 })
+
+	accepted := 2
+	for i := 0; i < 100; i++ {
+		if d.Submit(job) {
+			accepted++
+		}
+	}
+
+	assert.Equal(t, 5, accepted, "only the workers and the queue may hold deliveries")
+	assert.Equal(t, int64(97), d.Dropped())
+	assert.Equal(t, 3, d.Queued())
+	assert.Equal(t, 2, d.Active())
+
+	close(release)
+	d.Close()
+
+	assert.Equal(t, int64(5), completed.Load())
+	assert.LessOrEqual(t, maxRunning.Load(), int64(d.Workers()))
+}
+
+// TestDispatcherBlocksBrieflyWhenConfigured tests that a full queue waits for a free slot before dropping
+func TestDispatcherBlocksBrieflyWhenConfigured(t *testing.T) {
+	d := webhook.NewDispatcher(webhook.Config{Workers: 1, QueueSize: 0, EnqueueTimeout: time.Second})
+
+	release := make(chan struct{})
+	assert.True(t, d.Submit(func() { <-release }))
+	waitFor(t, func() bool { return d.Active() == 1 })
+
+	go func() {
+		time.Sleep(20 * time.Millisecond)
+		close(release)
+	}()
+
+	// The worker frees up within the timeout, so this delivery is not dropped
+	assert.True(t, d.Submit(func() {}))
+	assert.Equal(t, int64(0), d.Dropped())
+	d.Close()
+}
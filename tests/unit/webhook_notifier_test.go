@@ -0,0 +1,158 @@
+package unit
+
+import (
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+	"token-transfer-api/internal/model"
+	"token-transfer-api/pkg/webhook"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// TestNotifierSignsAndRetries tests that a notification is signed with the
+// shared secret and retried until the receiver accepts it
+func TestNotifierSignsAndRetries(t *testing.T) {
+	var attempts atomic.Int64
+	var mu sync.Mutex
+	var body []byte
+	var signature string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		b, _ := io.ReadAll(r.Body)
+		if attempts.Add(1) < 3 {
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+		mu.Lock()
+		body, signature = b, r.Header.Get(webhook.SignatureHeader)
+		mu.Unlock()
+	}))
+	defer server.Close()
+
+	n := webhook.NewNotifier(webhook.Config{
+		Workers:     1,
+		QueueSize:   1,
+		URL:         server.URL,
+		Secret:      "shh",
+		MaxAttempts: 5,
+		RetryDelay:  time.Millisecond,
+	})
+	timestamp := time.Date(2026, 1, 1, 9, 0, 0, 0, time.UTC)
+	assert.True(t, n.Notify(model.TransferNotification{
+		From:             "0xaaa",
+		To:               "0xbbb",
+		Amount:           "100",
+		FromBalanceAfter: "900",
+		Timestamp:        timestamp,
+	}))
+	n.Close()
+
+	assert.Equal(t, int64(3), attempts.Load())
+	mu.Lock()
+	defer mu.Unlock()
+	assert.True(t, webhook.Verify("shh", body, signature), "signature must match the body")
+	assert.False(t, webhook.Verify("other", body, signature))
+
+	var got map[string]interface{}
+	assert.NoError(t, json.Unmarshal(body, &got))
+	assert.Equal(t, map[string]interface{}{
+		"from":               "0xaaa",
+		"to":                 "0xbbb",
+		"amount":             "100",
+		"from_balance_after": "900",
+		"timestamp":          "2026-01-01T09:00:00Z",
+	}, got)
+}
+
+// TestNotifierGivesUp tests that delivery stops after MaxAttempts
+func TestNotifierGivesUp(t *testing.T) {
+	var attempts atomic.Int64
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		attempts.Add(1)
+		w.WriteHeader(http.StatusInternalServerError)
+	}))
+	defer server.Close()
+
+	n := webhook.NewNotifier(webhook.Config{
+		Workers:     1,
+		QueueSize:   1,
+		URL:         server.URL,
+		Secret:      "shh",
+		MaxAttempts: 2,
+		RetryDelay:  time.Millisecond,
+	})
+	assert.True(t, n.Notify(model.TransferNotification{From: "0xaaa"}))
+	n.Close()
+
+	assert.Equal(t, int64(2), attempts.Load())
+}
+
+// TestNotifierDoesNotBlock tests that Notify returns while the receiver hangs
+func TestNotifierDoesNotBlock(t *testing.T) {
+	release := make(chan struct{})
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		<-release
+	}))
+	defer server.Close()
+	defer close(release)
+
+	n := webhook.NewNotifier(webhook.Config{
+		Workers:     1,
+		QueueSize:   1,
+		URL:         server.URL,
+		Secret:      "shh",
+		MaxAttempts: 1,
+	})
+
+	done := make(chan struct{})
+	go func() {
+		n.Notify(model.TransferNotification{From: "0xaaa"})
+		n.Notify(model.TransferNotification{From: "0xaaa"})
+		close(done)
+	}()
+	select {
+	case <-done:
+	case <-time.After(time.Second):
+		t.Fatal("Notify blocked on a slow receiver")
+	}
+}
+
+// TestWebhookNotificationConfigFromEnv tests the notification settings
+func TestWebhookNotificationConfigFromEnv(t *testing.T) {
+	t.Setenv("WEBHOOK_URL", "")
+	t.Setenv("WEBHOOK_SECRET", "")
+	cfg, err := webhook.ConfigFromEnv()
+	assert.NoError(t, err)
+	assert.Equal(t, "", cfg.URL)
+	assert.Equal(t, 5, cfg.MaxAttempts)
+	assert.Equal(t, 500*time.Millisecond, cfg.RetryDelay)
+
+	t.Setenv("WEBHOOK_URL", "http://example.com/hook")
+	_, err = webhook.ConfigFromEnv()
+	assert.Error(t, err, "a URL without a secret must be rejected")
+
+	t.Setenv("WEBHOOK_SECRET", "shh")
+	t.Setenv("WEBHOOK_MAX_ATTEMPTS", "3")
+	t.Setenv("WEBHOOK_RETRY_DELAY", "2s")
+	cfg, err = webhook.ConfigFromEnv()
+	assert.NoError(t, err)
+	assert.Equal(t, "http://example.com/hook", cfg.URL)
+	assert.Equal(t, "shh", cfg.Secret)
+	assert.Equal(t, 3, cfg.MaxAttempts)
+	assert.Equal(t, 2*time.Second, cfg.RetryDelay)
+
+	for _, bad := range []string{"0", "x"} {
+		t.Setenv("WEBHOOK_MAX_ATTEMPTS", bad)
+		_, err = webhook.ConfigFromEnv()
+		assert.Error(t, err)
+	}
+	t.Setenv("WEBHOOK_MAX_ATTEMPTS", "")
+	t.Setenv("WEBHOOK_RETRY_DELAY", "-1s")
+	_, err = webhook.ConfigFromEnv()
+	assert.Error(t, err)
+}
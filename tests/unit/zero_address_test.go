@@ -0,0 +1,43 @@
+package unit
+
+import (
+	"context"
+	"testing"
+	"token-transfer-api/internal/db"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// TestZeroAddressSpendableConfig tests that the zero address stays spendable unless ZERO_ADDRESS_SPENDABLE is false
+func TestZeroAddressSpendableConfig(t *testing.T) {
+	for v, reserved := range map[string]bool{"": false, "true": false, "1": false, "false": true, "0": true} {
+		t.Setenv("ZERO_ADDRESS_SPENDABLE", v)
+		cfg, err := db.LoadConfig()
+		assert.NoError(t, err, v)
+		assert.Equal(t, reserved, cfg.ZeroAddressReserved, v)
+	}
+
+	t.Setenv("ZERO_ADDRESS_SPENDABLE", "sometimes")
+	_, err := db.LoadConfig()
+	assert.Error(t, err)
+}
+
+// TestZeroAddressTransferRule tests that only a reserved zero address is refused before the database is reached
+func TestZeroAddressTransferRule(t *testing.T) {
+	saved := db.Settings
+	defer func() { db.Settings = saved }()
+	const receiver = "0x4500000000000000000000000000000000000001"
+
+	db.Settings = db.Config{ZeroAddressReserved: true}
+	_, err := db.ExecuteTransfer(context.Background(), db.ZeroAddress, receiver, "1", "", nil, "")
+	assert.ErrorIs(t, err, db.ErrReservedAddress)
+
+	// Other senders, and the zero address while spendable, get as far as
+	// the database, which is not open here
+	_, err = db.ExecuteTransfer(context.Background(), receiver, db.ZeroAddress, "1", "", nil, "")
+	assert.ErrorIs(t, err, db.ErrNotInitialized)
+
+	db.Settings = db.Config{}
+	_, err = db.ExecuteTransfer(context.Background(), db.ZeroAddress, receiver, "1", "", nil, "")
+	assert.ErrorIs(t, err, db.ErrNotInitialized)
+}